@@ -0,0 +1,133 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/alterclientquotas"
+)
+
+// AlterClientQuotasRequest represents a request sent to a kafka broker to
+// alter client quotas.
+type AlterClientQuotasRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// List of client quota alterations to apply.
+	Entries []AlterClientQuotaEntry
+
+	// When true, the broker only validates the alterations without applying
+	// them.
+	ValidateOnly bool
+}
+
+// AlterClientQuotaEntry represents the quota alterations for a single entity.
+type AlterClientQuotaEntry struct {
+	// The components of the entity to alter the quotas of.
+	Entity []ClientQuotaEntity
+
+	// List of operations to apply to the quotas of the entity.
+	Ops []AlterClientQuotaOp
+}
+
+// AlterClientQuotaOp represents a single quota alteration.
+type AlterClientQuotaOp struct {
+	// The quota to alter.
+	Key ClientQuotaKey
+
+	// The value to set the quota to, ignored if Remove is true.
+	Value float64
+
+	// When true, the quota is removed from the entity instead of being set.
+	Remove bool
+}
+
+// AlterClientQuotasResponse represents a response from a kafka broker to an
+// alter client quotas request.
+type AlterClientQuotasResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// List of results for each entity that the request altered quotas of.
+	Entries []AlterClientQuotaResult
+}
+
+// AlterClientQuotaResult represents the result of altering the quotas of an
+// entity.
+type AlterClientQuotaResult struct {
+	// The components of the entity that the quotas were altered for.
+	Entity []ClientQuotaEntity
+
+	// An error that may have occurred while attempting to alter the quotas of
+	// the entity.
+	//
+	// The error contains both the kafka error code, and an error message
+	// returned by the kafka broker. Programs may use the standard errors.Is
+	// function to test the error against kafka error codes.
+	Error error
+}
+
+// AlterClientQuotas sends an alter client quotas request to a kafka broker and
+// returns the response.
+func (c *Client) AlterClientQuotas(ctx context.Context, req *AlterClientQuotasRequest) (*AlterClientQuotasResponse, error) {
+	entries := make([]alterclientquotas.Entry, len(req.Entries))
+
+	for i, entry := range req.Entries {
+		entities := make([]alterclientquotas.Entity, len(entry.Entity))
+		ops := make([]alterclientquotas.Ops, len(entry.Ops))
+
+		for j, e := range entry.Entity {
+			entities[j] = alterclientquotas.Entity{
+				EntityType: string(e.Type),
+				EntityName: e.Name,
+			}
+		}
+
+		for j, op := range entry.Ops {
+			ops[j] = alterclientquotas.Ops{
+				Key:    string(op.Key),
+				Value:  op.Value,
+				Remove: op.Remove,
+			}
+		}
+
+		entries[i] = alterclientquotas.Entry{
+			Entities: entities,
+			Ops:      ops,
+		}
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &alterclientquotas.Request{
+		Entries:      entries,
+		ValidateOnly: req.ValidateOnly,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).AlterClientQuotas: %w", err)
+	}
+
+	res := m.(*alterclientquotas.Response)
+	ret := &AlterClientQuotasResponse{
+		Throttle: makeDuration(res.ThrottleTimeMs),
+		Entries:  make([]AlterClientQuotaResult, len(res.Results)),
+	}
+
+	for i, r := range res.Results {
+		entity := make([]ClientQuotaEntity, len(r.Entities))
+
+		for j, e := range r.Entities {
+			entity[j] = ClientQuotaEntity{
+				Type: ClientQuotaEntityType(e.EntityType),
+				Name: e.EntityName,
+			}
+		}
+
+		ret.Entries[i] = AlterClientQuotaResult{
+			Entity: entity,
+			Error:  makeError(r.ErrorCode, r.ErrorMessage),
+		}
+	}
+
+	return ret, nil
+}
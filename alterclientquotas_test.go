@@ -0,0 +1,67 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	ktesting "github.com/segmentio/kafka-go/testing"
+)
+
+func TestClientAlterClientQuotas(t *testing.T) {
+	if !ktesting.KafkaIsAtLeast("2.6.0") {
+		return
+	}
+
+	ctx := context.Background()
+	client, shutdown := newLocalClient()
+	defer shutdown()
+
+	entity := []ClientQuotaEntity{
+		{Type: ClientQuotaEntityClientID, Name: "kafka-go-test-" + makeTopic()},
+	}
+
+	alterResp, err := client.AlterClientQuotas(ctx, &AlterClientQuotasRequest{
+		Entries: []AlterClientQuotaEntry{
+			{
+				Entity: entity,
+				Ops: []AlterClientQuotaOp{
+					{Key: ClientQuotaProducerByteRate, Value: 500000},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range alterResp.Entries {
+		if entry.Error != nil {
+			t.Fatal(entry.Error)
+		}
+	}
+
+	describeResp, err := client.DescribeClientQuotas(ctx, &DescribeClientQuotasRequest{
+		Components: []ClientQuotaFilterComponent{
+			{
+				EntityType: ClientQuotaEntityClientID,
+				MatchType:  ClientQuotaMatchExact,
+				Match:      entity[0].Name,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if describeResp.Error != nil {
+		t.Fatal(describeResp.Error)
+	}
+
+	if len(describeResp.Entries) != 1 {
+		t.Fatalf("expected 1 entry; got %d", len(describeResp.Entries))
+	}
+
+	if v := describeResp.Entries[0].Values[ClientQuotaProducerByteRate]; v != 500000 {
+		t.Errorf("expected producer byte rate to be 500000; got %v", v)
+	}
+}
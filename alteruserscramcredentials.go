@@ -0,0 +1,169 @@
+package kafka
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/alteruserscramcredentials"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// AlterUserScramCredentialsRequest represents a request sent to a kafka broker
+// to alter user scram credentials.
+type AlterUserScramCredentialsRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// List of credentials to delete.
+	Deletions []UserScramCredentialsDeletion
+
+	// List of credentials to create or update.
+	Upsertions []UserScramCredentialsUpsertion
+}
+
+// UserScramCredentialsDeletion represents the deletion of the credentials of a
+// user for a SCRAM mechanism.
+type UserScramCredentialsDeletion struct {
+	Name      string
+	Mechanism ScramMechanism
+}
+
+// UserScramCredentialsUpsertion represents the creation or update of the
+// credentials of a user for a SCRAM mechanism.
+//
+// The password of the user is never sent to the broker, only the salted
+// password is. Programs usually construct values of this type by calling
+// NewUserScramCredentialsUpsertion.
+type UserScramCredentialsUpsertion struct {
+	Name           string
+	Mechanism      ScramMechanism
+	Iterations     int
+	Salt           []byte
+	SaltedPassword []byte
+}
+
+// Minimum number of iterations accepted by kafka brokers for SCRAM credentials.
+const minScramIterations = 4096
+
+// NewUserScramCredentialsUpsertion constructs an upsertion of the credentials
+// of a user, generating a random salt and deriving the salted password from
+// the password passed as argument.
+//
+// If iterations is zero, the minimum number of iterations supported by kafka
+// (4096) is used.
+func NewUserScramCredentialsUpsertion(name string, mechanism ScramMechanism, password string, iterations int) (UserScramCredentialsUpsertion, error) {
+	var newHash func() hash.Hash
+
+	switch mechanism {
+	case ScramMechanismSha256:
+		newHash = sha256.New
+	case ScramMechanismSha512:
+		newHash = sha512.New
+	default:
+		return UserScramCredentialsUpsertion{}, fmt.Errorf("unsupported scram mechanism: %d", mechanism)
+	}
+
+	if iterations == 0 {
+		iterations = minScramIterations
+	}
+
+	if iterations < minScramIterations {
+		return UserScramCredentialsUpsertion{}, fmt.Errorf("scram iterations must be at least %d: %d", minScramIterations, iterations)
+	}
+
+	if password == "" {
+		return UserScramCredentialsUpsertion{}, errors.New("scram password must not be empty")
+	}
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return UserScramCredentialsUpsertion{}, fmt.Errorf("generating scram salt: %w", err)
+	}
+
+	size := newHash().Size()
+	return UserScramCredentialsUpsertion{
+		Name:           name,
+		Mechanism:      mechanism,
+		Iterations:     iterations,
+		Salt:           salt,
+		SaltedPassword: pbkdf2.Key([]byte(password), salt, iterations, size, newHash),
+	}, nil
+}
+
+// AlterUserScramCredentialsResponse represents a response from a kafka broker
+// to an alter user credentials request.
+type AlterUserScramCredentialsResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// List of altered user scram credentials.
+	Results []AlterUserScramCredentialsResponseUser
+}
+
+// AlterUserScramCredentialsResponseUser represents the result of altering the
+// credentials of a single user.
+type AlterUserScramCredentialsResponseUser struct {
+	// Name of the user.
+	User string
+
+	// An error that may have occurred while attempting to alter the
+	// credentials of the user.
+	//
+	// The errors contain the kafka error code. Programs may use the standard
+	// errors.Is function to test the error against kafka error codes.
+	Error error
+}
+
+// AlterUserScramCredentials sends user scram credentials alteration request to
+// a kafka broker and returns the response.
+func (c *Client) AlterUserScramCredentials(ctx context.Context, req *AlterUserScramCredentialsRequest) (*AlterUserScramCredentialsResponse, error) {
+	deletions := make([]alteruserscramcredentials.RequestUserScramCredentialsDeletion, len(req.Deletions))
+	upsertions := make([]alteruserscramcredentials.RequestUserScramCredentialsUpsertion, len(req.Upsertions))
+
+	for i, d := range req.Deletions {
+		deletions[i] = alteruserscramcredentials.RequestUserScramCredentialsDeletion{
+			Name:      d.Name,
+			Mechanism: int8(d.Mechanism),
+		}
+	}
+
+	for i, u := range req.Upsertions {
+		upsertions[i] = alteruserscramcredentials.RequestUserScramCredentialsUpsertion{
+			Name:           u.Name,
+			Mechanism:      int8(u.Mechanism),
+			Iterations:     int32(u.Iterations),
+			Salt:           u.Salt,
+			SaltedPassword: u.SaltedPassword,
+		}
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &alteruserscramcredentials.Request{
+		Deletions:  deletions,
+		Upsertions: upsertions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).AlterUserScramCredentials: %w", err)
+	}
+
+	res := m.(*alteruserscramcredentials.Response)
+	ret := &AlterUserScramCredentialsResponse{
+		Throttle: makeDuration(res.ThrottleTimeMs),
+		Results:  make([]AlterUserScramCredentialsResponseUser, len(res.Results)),
+	}
+
+	for i, r := range res.Results {
+		ret.Results[i] = AlterUserScramCredentialsResponseUser{
+			User:  r.User,
+			Error: makeError(r.ErrorCode, r.ErrorMessage),
+		}
+	}
+
+	return ret, nil
+}
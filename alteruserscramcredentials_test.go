@@ -0,0 +1,152 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	ktesting "github.com/segmentio/kafka-go/testing"
+)
+
+func TestNewUserScramCredentialsUpsertion(t *testing.T) {
+	tests := []struct {
+		mechanism ScramMechanism
+		size      int
+	}{
+		{mechanism: ScramMechanismSha256, size: 32},
+		{mechanism: ScramMechanismSha512, size: 64},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mechanism.String(), func(t *testing.T) {
+			u1, err := NewUserScramCredentialsUpsertion("alice", test.mechanism, "alice-secret", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u2, err := NewUserScramCredentialsUpsertion("alice", test.mechanism, "alice-secret", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if u1.Iterations != minScramIterations {
+				t.Errorf("expected %d iterations; got %d", minScramIterations, u1.Iterations)
+			}
+			if len(u1.SaltedPassword) != test.size {
+				t.Errorf("expected salted password of %d bytes; got %d", test.size, len(u1.SaltedPassword))
+			}
+			if bytes.Equal(u1.Salt, u2.Salt) {
+				t.Error("expected salts to be randomized")
+			}
+			if bytes.Equal(u1.SaltedPassword, u2.SaltedPassword) {
+				t.Error("expected salted passwords to differ with different salts")
+			}
+		})
+	}
+
+	if _, err := NewUserScramCredentialsUpsertion("alice", ScramMechanismUnknown, "alice-secret", 0); err == nil {
+		t.Error("expected an error for unknown scram mechanisms")
+	}
+	if _, err := NewUserScramCredentialsUpsertion("alice", ScramMechanismSha256, "alice-secret", 100); err == nil {
+		t.Error("expected an error for low iteration counts")
+	}
+}
+
+func TestAlterAndDescribeUserScramCredentials(t *testing.T) {
+	// https://issues.apache.org/jira/browse/KAFKA-10259
+	if !ktesting.KafkaIsAtLeast("2.7.0") {
+		return
+	}
+
+	ctx := context.Background()
+	client, shutdown := newLocalClient()
+	defer shutdown()
+
+	name := makeTopic()
+
+	upsertion, err := NewUserScramCredentialsUpsertion(name, ScramMechanismSha512, "my-password", 15000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createRes, err := client.AlterUserScramCredentials(ctx, &AlterUserScramCredentialsRequest{
+		Upsertions: []UserScramCredentialsUpsertion{upsertion},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(createRes.Results) != 1 {
+		t.Fatalf("expected 1 createResult; got %d", len(createRes.Results))
+	}
+
+	if createRes.Results[0].User != name {
+		t.Fatalf("expected createResult with user: %s, got %s", name, createRes.Results[0].User)
+	}
+
+	if createRes.Results[0].Error != nil {
+		t.Fatalf("didn't expect an error in createResult, got %v", createRes.Results[0].Error)
+	}
+
+	describeCreationRes, err := client.DescribeUserScramCredentials(ctx, &DescribeUserScramCredentialsRequest{
+		Users: []string{name},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCreation := DescribeUserScramCredentialsResponseResult{
+		User: name,
+		CredentialInfos: []DescribeUserScramCredentialsCredentialInfo{
+			{
+				Mechanism:  ScramMechanismSha512,
+				Iterations: 15000,
+			},
+		},
+	}
+
+	if len(describeCreationRes.Results) != 1 {
+		t.Fatalf("expected 1 result; got %d", len(describeCreationRes.Results))
+	}
+
+	result := describeCreationRes.Results[0]
+	if result.User != expectedCreation.User || result.Error != nil ||
+		len(result.CredentialInfos) != 1 || result.CredentialInfos[0] != expectedCreation.CredentialInfos[0] {
+		t.Fatalf("expected describeCreationRes result %v, got %v", expectedCreation, result)
+	}
+
+	deleteRes, err := client.AlterUserScramCredentials(ctx, &AlterUserScramCredentialsRequest{
+		Deletions: []UserScramCredentialsDeletion{
+			{
+				Name:      name,
+				Mechanism: ScramMechanismSha512,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deleteRes.Results) != 1 {
+		t.Fatalf("expected 1 deleteResult; got %d", len(deleteRes.Results))
+	}
+
+	if deleteRes.Results[0].Error != nil {
+		t.Fatalf("didn't expect an error in deleteResult, got %v", deleteRes.Results[0].Error)
+	}
+
+	describeDeletionRes, err := client.DescribeUserScramCredentials(ctx, &DescribeUserScramCredentialsRequest{
+		Users: []string{name},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(describeDeletionRes.Results) != 1 {
+		t.Fatalf("expected 1 result; got %d", len(describeDeletionRes.Results))
+	}
+
+	if !errors.Is(describeDeletionRes.Results[0].Error, ResourceNotFound) {
+		t.Fatalf("expected describeDeletionRes error %v, got %v", ResourceNotFound, describeDeletionRes.Results[0].Error)
+	}
+}
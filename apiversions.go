@@ -3,6 +3,8 @@ package kafka
 import (
 	"context"
 	"net"
+	"regexp"
+	"runtime/debug"
 
 	"github.com/segmentio/kafka-go/protocol"
 	"github.com/segmentio/kafka-go/protocol/apiversions"
@@ -42,7 +44,10 @@ func (c *Client) ApiVersions(
 	ctx context.Context,
 	req *ApiVersionsRequest,
 ) (*ApiVersionsResponse, error) {
-	apiReq := &apiversions.Request{}
+	apiReq := &apiversions.Request{
+		ClientSoftwareName:    clientSoftwareName,
+		ClientSoftwareVersion: clientSoftwareVersion,
+	}
 	protoResp, err := c.roundTrip(
 		ctx,
 		req.Addr,
@@ -70,3 +75,39 @@ func (c *Client) ApiVersions(
 
 	return resp, err
 }
+
+// brokerApiVersions returns the matrix of API versions of the response.
+func (res *ApiVersionsResponse) brokerApiVersions() BrokerApiVersions {
+	versions := make([]ApiVersion, len(res.ApiKeys))
+	for i, k := range res.ApiKeys {
+		versions[i] = ApiVersion{
+			ApiKey:     int16(k.ApiKey),
+			MinVersion: int16(k.MinVersion),
+			MaxVersion: int16(k.MaxVersion),
+		}
+	}
+	return makeBrokerApiVersions(versions)
+}
+
+// The software name and version that clients identify with in ApiVersions v3+
+// requests, see KIP-511. Brokers reject the requests if they are not made of
+// alphanumerical characters, dots, and dashes.
+var (
+	clientSoftwareName    = "kafka-go"
+	clientSoftwareVersion = kafkaGoVersion()
+)
+
+var softwareVersionPattern = regexp.MustCompile(`^[a-zA-Z0-9](?:[a-zA-Z0-9\-.]*[a-zA-Z0-9])?$`)
+
+// kafkaGoVersion returns the version of kafka-go that the program was built
+// with, or "unknown" if it is not available.
+func kafkaGoVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, m := range append([]*debug.Module{&info.Main}, info.Deps...) {
+			if m.Path == "github.com/segmentio/kafka-go" && softwareVersionPattern.MatchString(m.Version) {
+				return m.Version
+			}
+		}
+	}
+	return "unknown"
+}
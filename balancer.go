@@ -1,6 +1,7 @@
 package kafka
 
 import (
+	"encoding/binary"
 	"hash"
 	"hash/crc32"
 	"hash/fnv"
@@ -8,6 +9,7 @@ import (
 	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // The Balancer interface provides an abstraction of the message distribution
@@ -277,15 +279,27 @@ func (b CRC32Balancer) Balance(msg Message, partitions ...int) (partition int) {
 // Java partitioner will use a round robin balancer instead of random on nil
 // keys.  We choose librdkafka's implementation because it arguably has a larger
 // install base.
+//
+// Set NilKeyBalancer to a StickyBalancer to replicate the default partitioner
+// of the Java client since version 2.4, which routes messages with nil keys to
+// the same partition until a batch is complete (see KIP-480).
 type Murmur2Balancer struct {
 	Consistent bool
-	random     randomBalancer
+
+	// An optional balancer used for messages with nil keys when Consistent
+	// is false, in place of routing them to random partitions.
+	NilKeyBalancer Balancer
+
+	random randomBalancer
 }
 
 func (b Murmur2Balancer) Balance(msg Message, partitions ...int) (partition int) {
 	// NOTE: the murmur2 balancers in java and librdkafka treat a nil key as
 	//       non-existent while treating an empty slice as a defined value.
 	if msg.Key == nil && !b.Consistent {
+		if b.NilKeyBalancer != nil {
+			return b.NilKeyBalancer.Balance(msg, partitions...)
+		}
 		return b.random.Balance(msg, partitions...)
 	}
 
@@ -293,6 +307,345 @@ func (b Murmur2Balancer) Balance(msg Message, partitions ...int) (partition int)
 	return partitions[idx]
 }
 
+// ObserveProduce satisfies the BalancerFeedback interface, it forwards the
+// feedback to NilKeyBalancer.
+func (b Murmur2Balancer) ObserveProduce(topic string, partition int, latency time.Duration, err error) {
+	if feedback, ok := b.NilKeyBalancer.(BalancerFeedback); ok {
+		feedback.ObserveProduce(topic, partition, latency, err)
+	}
+}
+
+// StickyBalancer is a Balancer that routes all messages to the same random
+// partition until a batch of messages was produced to it, then switches to
+// another random partition.  This is the behavior of the sticky partitioner of
+// the Java client for messages with nil keys (see KIP-480), which creates
+// larger batches than distributing messages across all partitions.
+//
+// The balancer switches partitions when the Writer reports that a batch was
+// produced to the current partition, so it must be used as the Balancer of a
+// Writer, directly or through Murmur2Balancer.NilKeyBalancer.  Like other
+// balancers, an instance must only be used with the partitions of a single
+// topic.
+type StickyBalancer struct {
+	mutex     sync.Mutex
+	partition int
+	sticky    bool
+	previous  int
+	switched  bool
+}
+
+// Balance satisfies the Balancer interface.
+func (b *StickyBalancer) Balance(msg Message, partitions ...int) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.sticky && containsPartition(partitions, b.partition) {
+		return b.partition
+	}
+
+	// like the Java client, avoid choosing the previous partition again when
+	// switching partitions.
+	p := partitions[rand.Intn(len(partitions))]
+	for b.switched && p == b.previous && len(partitions) > 1 {
+		p = partitions[rand.Intn(len(partitions))]
+	}
+
+	b.partition, b.sticky = p, true
+	return p
+}
+
+// ObserveProduce satisfies the BalancerFeedback interface.
+func (b *StickyBalancer) ObserveProduce(topic string, partition int, latency time.Duration, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.sticky && partition == b.partition {
+		b.previous, b.switched = b.partition, true
+		b.sticky = false
+	}
+}
+
+func containsPartition(partitions []int, partition int) bool {
+	for _, p := range partitions {
+		if p == partition {
+			return true
+		}
+	}
+	return false
+}
+
+// ConsistentHashBalancer is a Balancer that routes messages to partitions
+// using a consistent hash ring.  This ensures that messages with the same key
+// are routed to the same partition.
+//
+// Each partition owns VirtualNodes points on the ring, and messages are routed
+// to the partition owning the first point that follows the hash of their key.
+// Unlike the modulo-based balancers, which remap nearly all keys when the
+// number of partitions of a topic changes, adding a partition only remaps the
+// keys which hash to the points of the new partition, about 1/N of the keys
+// for N partitions.
+//
+// Messages with nil keys are distributed in a round robin fashion.
+type ConsistentHashBalancer struct {
+	// The number of points that each partition owns on the hash ring.  More
+	// points distribute the keys more evenly across partitions, at the cost
+	// of memory and of the time spent building the ring when partitions
+	// change.
+	//
+	// Default: 100
+	VirtualNodes int
+
+	rr    RoundRobin
+	mutex sync.Mutex
+	ring  hashRing
+}
+
+// Balance satisfies the Balancer interface.
+func (b *ConsistentHashBalancer) Balance(msg Message, partitions ...int) int {
+	if msg.Key == nil {
+		return b.rr.Balance(msg, partitions...)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// partitions change
+	if !b.ring.has(partitions) {
+		virtualNodes := b.VirtualNodes
+		if virtualNodes <= 0 {
+			virtualNodes = 100
+		}
+		b.ring = makeHashRing(virtualNodes, partitions)
+	}
+
+	return b.ring.lookup(fnv1a64(msg.Key))
+}
+
+// hashRing is a consistent hash ring, where each partition owns several
+// points.
+type hashRing struct {
+	partitions []int
+	points     []hashRingPoint
+}
+
+type hashRingPoint struct {
+	hash      uint64
+	partition int
+}
+
+func makeHashRing(virtualNodes int, partitions []int) hashRing {
+	ring := hashRing{
+		partitions: append([]int(nil), partitions...),
+		points:     make([]hashRingPoint, 0, virtualNodes*len(partitions)),
+	}
+
+	var b [16]byte
+	for _, p := range partitions {
+		for i := 0; i < virtualNodes; i++ {
+			binary.BigEndian.PutUint64(b[:8], uint64(p))
+			binary.BigEndian.PutUint64(b[8:], uint64(i))
+			ring.points = append(ring.points, hashRingPoint{
+				hash:      fnv1a64(b[:]),
+				partition: p,
+			})
+		}
+	}
+
+	// ties are broken by partition so the ring doesn't depend on the order
+	// of partitions.
+	sort.Slice(ring.points, func(i, j int) bool {
+		a, b := ring.points[i], ring.points[j]
+		return a.hash < b.hash || (a.hash == b.hash && a.partition < b.partition)
+	})
+	return ring
+}
+
+func (r *hashRing) has(partitions []int) bool {
+	if len(partitions) != len(r.partitions) {
+		return false
+	}
+	for i := range partitions {
+		if partitions[i] != r.partitions[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *hashRing) lookup(hash uint64) int {
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= hash
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].partition
+}
+
+// fnv1a64 returns the 64 bits FNV-1a hash of b, followed by the finalizer of
+// murmur3 to spread inputs which differ by a few bits across the ring.
+func fnv1a64(b []byte) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	h := uint64(offset64)
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= prime64
+	}
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// BalancerFeedback is implemented by balancers which adapt the distribution of
+// messages to the outcome of produce requests.  Writer calls ObserveProduce
+// after each attempt to produce a batch of messages to a partition, with the
+// latency of the request and the error it returned, if any.
+//
+// ObserveProduce is called concurrently from multiple goroutines.
+type BalancerFeedback interface {
+	ObserveProduce(topic string, partition int, latency time.Duration, err error)
+}
+
+// WeightedBalancer is a Balancer that routes messages to partitions at random,
+// with a probability proportional to the weight of partitions.  It allows
+// programs to steer traffic away from partitions which are hot, lagging, or
+// under-replicated.
+//
+// The weight of a partition is the product of its static weight in Weights,
+// the result of the Weight function, and of a health factor that the balancer
+// maintains from the feedback of the Writer: partitions whose produce requests
+// fail or are slower than the other partitions receive fewer messages, until
+// their requests succeed again.
+//
+// Keys are not taken into account, so messages with the same key may be routed
+// to different partitions.
+//
+// The balancer ignores the topic of messages: Weights, Weight, and the health
+// of partitions are indexed by partition number only, so a Writer producing to
+// several topics applies the same weights to the partitions with the same
+// number in each topic, and feedback from one topic affects the others.
+// Programs weighting the partitions of several topics should use one Writer,
+// each with its own WeightedBalancer, per topic.
+type WeightedBalancer struct {
+	// Static weights of partitions.  Partitions missing from the map have a
+	// weight of 1, and partitions with a weight of zero or less receive no
+	// messages unless all partitions do.
+	Weights map[int]float64
+
+	// An optional function returning the current weight of a partition, for
+	// example derived from the lag of consumers or the size of the ISR.  It
+	// is called for every partition on each message so it must be fast,
+	// programs should compute the weights in the background and cache them.
+	Weight func(partition int) float64
+
+	mutex  sync.Mutex
+	health map[int]*partitionHealth
+}
+
+// partitionHealth tracks the outcome of the produce requests to a partition.
+type partitionHealth struct {
+	// errors is a factor in (0,1] which halves on every error, and recovers
+	// on successes.
+	errors float64
+	// latency is the exponentially weighted moving average of the latency of
+	// successful requests.
+	latency time.Duration
+}
+
+const (
+	weightedBalancerMinHealth = 0.01
+	weightedBalancerRecovery  = 0.25
+	weightedBalancerSmoothing = 0.2
+)
+
+// Balance satisfies the Balancer interface.
+func (b *WeightedBalancer) Balance(msg Message, partitions ...int) int {
+	weights := make([]float64, len(partitions))
+
+	b.mutex.Lock()
+	var fastest time.Duration
+	for _, p := range partitions {
+		if h := b.health[p]; h != nil && h.latency > 0 && (fastest == 0 || h.latency < fastest) {
+			fastest = h.latency
+		}
+	}
+
+	total := 0.0
+	for i, p := range partitions {
+		w := 1.0
+		if weight, ok := b.Weights[p]; ok {
+			w = weight
+		}
+		if w > 0 && b.Weight != nil {
+			w *= b.Weight(p)
+		}
+		if h := b.health[p]; w > 0 && h != nil {
+			w *= h.errors
+			if h.latency > 0 {
+				w *= float64(fastest) / float64(h.latency)
+			}
+		}
+		if w > 0 {
+			weights[i] = w
+			total += w
+		}
+	}
+	b.mutex.Unlock()
+
+	if total == 0 {
+		return partitions[rand.Intn(len(partitions))]
+	}
+
+	r := rand.Float64() * total
+	for i, w := range weights {
+		if r -= w; r < 0 && w > 0 {
+			return partitions[i]
+		}
+	}
+	// rounding errors may leave a tiny remainder, use the last partition with
+	// a positive weight.
+	for i := len(weights) - 1; ; i-- {
+		if weights[i] > 0 {
+			return partitions[i]
+		}
+	}
+}
+
+// ObserveProduce satisfies the BalancerFeedback interface.
+func (b *WeightedBalancer) ObserveProduce(topic string, partition int, latency time.Duration, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.health == nil {
+		b.health = make(map[int]*partitionHealth)
+	}
+	h := b.health[partition]
+	if h == nil {
+		h = &partitionHealth{errors: 1}
+		b.health[partition] = h
+	}
+
+	if err != nil {
+		if h.errors /= 2; h.errors < weightedBalancerMinHealth {
+			h.errors = weightedBalancerMinHealth
+		}
+		return
+	}
+
+	h.errors += (1 - h.errors) * weightedBalancerRecovery
+	if h.latency == 0 {
+		h.latency = latency
+	} else {
+		h.latency += time.Duration(weightedBalancerSmoothing * float64(latency-h.latency))
+	}
+}
+
 // Go port of the Java library's murmur2 function.
 // https://github.com/apache/kafka/blob/1.0/clients/src/main/java/org/apache/kafka/common/utils/Utils.java#L353
 func murmur2(data []byte) uint32 {
@@ -5,6 +5,7 @@ import (
 	"hash"
 	"hash/crc32"
 	"testing"
+	"time"
 )
 
 func TestHashBalancer(t *testing.T) {
@@ -411,3 +412,232 @@ func TestLeastBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestConsistentHashBalancer(t *testing.T) {
+	makePartitions := func(n int) []int {
+		partitions := make([]int, n)
+		for i := range partitions {
+			partitions[i] = i
+		}
+		return partitions
+	}
+
+	keys := make([][]byte, 10000)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("key-%d", i))
+	}
+
+	t.Run("same key, same partition", func(t *testing.T) {
+		b1 := &ConsistentHashBalancer{}
+		b2 := &ConsistentHashBalancer{}
+		partitions := makePartitions(12)
+		for _, key := range keys[:100] {
+			p1 := b1.Balance(Message{Key: key}, partitions...)
+			p2 := b2.Balance(Message{Key: key}, partitions...)
+			if p1 != p2 {
+				t.Fatalf("key %s routed to partitions %d and %d", key, p1, p2)
+			}
+		}
+	})
+
+	t.Run("nil keys are distributed in round robin", func(t *testing.T) {
+		b := &ConsistentHashBalancer{}
+		for i := 0; i < 6; i++ {
+			if p := b.Balance(Message{}, 0, 1, 2); p != i%3 {
+				t.Fatalf("expected partition %d but got %d", i%3, p)
+			}
+		}
+	})
+
+	t.Run("keys are distributed evenly", func(t *testing.T) {
+		b := &ConsistentHashBalancer{}
+		partitions := makePartitions(10)
+		counts := make(map[int]int)
+		for _, key := range keys {
+			counts[b.Balance(Message{Key: key}, partitions...)]++
+		}
+		mean := len(keys) / len(partitions)
+		for _, p := range partitions {
+			if counts[p] < mean/2 || counts[p] > mean*3/2 {
+				t.Errorf("partition %d received %d keys, expected about %d", p, counts[p], mean)
+			}
+		}
+	})
+
+	t.Run("adding a partition remaps few keys", func(t *testing.T) {
+		b := &ConsistentHashBalancer{VirtualNodes: 200}
+		before := make([]int, len(keys))
+		for i, key := range keys {
+			before[i] = b.Balance(Message{Key: key}, makePartitions(10)...)
+		}
+
+		moved := 0
+		for i, key := range keys {
+			p := b.Balance(Message{Key: key}, makePartitions(11)...)
+			if p != before[i] {
+				if p != 10 {
+					t.Fatalf("key %s moved from partition %d to the existing partition %d", key, before[i], p)
+				}
+				moved++
+			}
+		}
+
+		// about 1/11 of the keys are expected to move.
+		if moved == 0 || moved > len(keys)/5 {
+			t.Errorf("%d keys out of %d were remapped", moved, len(keys))
+		}
+	})
+
+	t.Run("the order of partitions does not matter", func(t *testing.T) {
+		b1 := &ConsistentHashBalancer{}
+		b2 := &ConsistentHashBalancer{}
+		for _, key := range keys[:100] {
+			p1 := b1.Balance(Message{Key: key}, 0, 1, 2, 3)
+			p2 := b2.Balance(Message{Key: key}, 3, 1, 0, 2)
+			if p1 != p2 {
+				t.Fatalf("key %s routed to partitions %d and %d", key, p1, p2)
+			}
+		}
+	})
+}
+
+func TestWeightedBalancer(t *testing.T) {
+	distribution := func(b *WeightedBalancer, partitions ...int) map[int]int {
+		counts := make(map[int]int)
+		for i := 0; i < 10000; i++ {
+			counts[b.Balance(Message{}, partitions...)]++
+		}
+		return counts
+	}
+
+	t.Run("static weights", func(t *testing.T) {
+		b := &WeightedBalancer{Weights: map[int]float64{0: 3, 2: 0}}
+		counts := distribution(b, 0, 1, 2)
+		if counts[2] != 0 {
+			t.Errorf("partition with a zero weight received %d messages", counts[2])
+		}
+		if ratio := float64(counts[0]) / float64(counts[1]); ratio < 2.5 || ratio > 3.5 {
+			t.Errorf("expected partition 0 to receive 3 times more messages than partition 1: %v", counts)
+		}
+	})
+
+	t.Run("weight function", func(t *testing.T) {
+		b := &WeightedBalancer{
+			Weight: func(partition int) float64 {
+				if partition == 1 {
+					return 0
+				}
+				return 1
+			},
+		}
+		if counts := distribution(b, 0, 1); counts[1] != 0 {
+			t.Errorf("partition with a zero weight received %d messages", counts[1])
+		}
+	})
+
+	t.Run("all partitions with zero weights", func(t *testing.T) {
+		b := &WeightedBalancer{Weights: map[int]float64{0: 0, 1: 0}}
+		if counts := distribution(b, 0, 1); counts[0] == 0 || counts[1] == 0 {
+			t.Errorf("expected messages to be distributed across all partitions: %v", counts)
+		}
+	})
+
+	t.Run("errors reduce the share of partitions", func(t *testing.T) {
+		b := &WeightedBalancer{}
+		for i := 0; i < 3; i++ {
+			b.ObserveProduce("topic", 1, time.Millisecond, RequestTimedOut)
+		}
+		counts := distribution(b, 0, 1)
+		if counts[1]*4 > counts[0] {
+			t.Errorf("expected partition 1 to receive fewer messages after errors: %v", counts)
+		}
+
+		// the partition recovers after successful requests.
+		for i := 0; i < 20; i++ {
+			b.ObserveProduce("topic", 1, time.Millisecond, nil)
+		}
+		counts = distribution(b, 0, 1)
+		if ratio := float64(counts[0]) / float64(counts[1]); ratio < 0.8 || ratio > 1.25 {
+			t.Errorf("expected partition 1 to recover: %v", counts)
+		}
+	})
+
+	t.Run("slow partitions receive fewer messages", func(t *testing.T) {
+		b := &WeightedBalancer{}
+		b.ObserveProduce("topic", 0, 10*time.Millisecond, nil)
+		b.ObserveProduce("topic", 1, 40*time.Millisecond, nil)
+		counts := distribution(b, 0, 1, 2)
+		if ratio := float64(counts[0]) / float64(counts[1]); ratio < 3 || ratio > 5 {
+			t.Errorf("expected partition 0 to receive 4 times more messages than partition 1: %v", counts)
+		}
+		if ratio := float64(counts[2]) / float64(counts[0]); ratio < 0.8 || ratio > 1.25 {
+			t.Errorf("expected partitions without feedback to be unaffected: %v", counts)
+		}
+	})
+}
+
+func TestStickyBalancer(t *testing.T) {
+	b := &StickyBalancer{}
+	partitions := []int{0, 1, 2, 3}
+
+	p := b.Balance(Message{}, partitions...)
+	for i := 0; i < 100; i++ {
+		if q := b.Balance(Message{Value: []byte("hello")}, partitions...); q != p {
+			t.Fatalf("expected partition %d but got %d", p, q)
+		}
+	}
+
+	// feedback for other partitions does not affect the sticky partition.
+	b.ObserveProduce("topic", (p+1)%len(partitions), time.Millisecond, nil)
+	if q := b.Balance(Message{}, partitions...); q != p {
+		t.Fatalf("expected partition %d but got %d", p, q)
+	}
+
+	// the balancer switches to another partition once a batch was produced
+	// to the sticky partition.
+	for i := 0; i < 20; i++ {
+		b.ObserveProduce("topic", p, time.Millisecond, nil)
+		q := b.Balance(Message{}, partitions...)
+		if q == p {
+			t.Fatalf("the balancer did not switch from partition %d", p)
+		}
+		p = q
+	}
+
+	// the sticky partition is replaced when it is not available anymore.
+	if q := b.Balance(Message{}, 5); q != 5 {
+		t.Fatalf("expected partition 5 but got %d", q)
+	}
+}
+
+func TestMurmur2BalancerNilKeyBalancer(t *testing.T) {
+	sticky := &StickyBalancer{}
+	b := Murmur2Balancer{NilKeyBalancer: sticky}
+	partitions := []int{0, 1, 2, 3, 4, 5}
+
+	p := b.Balance(Message{}, partitions...)
+	for i := 0; i < 10; i++ {
+		if q := b.Balance(Message{}, partitions...); q != p {
+			t.Fatalf("expected partition %d but got %d", p, q)
+		}
+	}
+
+	// keyed messages are still hashed.
+	key := []byte("hello world")
+	if q, want := b.Balance(Message{Key: key}, partitions...), int((murmur2(key)&0x7fffffff)%6); q != want {
+		t.Fatalf("expected partition %d but got %d", want, q)
+	}
+
+	// the feedback is forwarded to the sticky balancer.
+	var feedback BalancerFeedback = b
+	feedback.ObserveProduce("topic", p, time.Millisecond, nil)
+	if q := b.Balance(Message{}, partitions...); q == p {
+		t.Fatalf("the balancer did not switch from partition %d", p)
+	}
+
+	// consistent balancers hash nil keys.
+	b.Consistent = true
+	if q, want := b.Balance(Message{}, partitions...), int((murmur2(nil)&0x7fffffff)%6); q != want {
+		t.Fatalf("expected partition %d but got %d", want, q)
+	}
+}
@@ -2,10 +2,13 @@ package kafka
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"io"
 	"sync"
 	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
 )
 
 // A Batch is an iterator over a sequence of messages fetched from a kafka
@@ -20,7 +23,7 @@ import (
 type Batch struct {
 	mutex         sync.Mutex
 	conn          *Conn
-	lock          *sync.Mutex
+	lock          *responseLock
 	msgs          *messageSetReader
 	deadline      time.Time
 	throttle      time.Duration
@@ -38,6 +41,39 @@ type Batch struct {
 	// we get an EOF we do not get the lastOffset. So there is a mismatch
 	// between when we receive it and need to use it.
 	lastOffset int64
+	// The buffers that the keys and values of messages are leased from, or
+	// nil if they are allocated for each message.
+	buffers *messageBuffers
+	// The context of batches created by ReadBatchContext, and the function
+	// which stops watching it, reporting whether it interrupted the reads.
+	ctx  context.Context
+	stop func() bool
+}
+
+// RecordBatchHeader describes the batch of records that a message read from a
+// Batch belongs to.
+type RecordBatchHeader struct {
+	// Version of the message format that the batch was encoded with. Message
+	// sets of versions 0 and 1 only carry Attributes, the other fields are
+	// specific to record batches of version 2.
+	Version int8
+
+	// The attributes of the batch, which report its compression codec, and
+	// whether it was written by a transaction, or is a control batch holding
+	// a transaction marker. The key and value of messages read from control
+	// batches encode a protocol.ControlRecord.
+	Attributes protocol.Attributes
+
+	PartitionLeaderEpoch int32
+	BaseOffset           int64
+	LastOffset           int64
+
+	// The idempotent or transactional producer which wrote the batch, and the
+	// sequence number of its first record. ProducerID is -1 when the batch was
+	// written by another producer.
+	ProducerID    int64
+	ProducerEpoch int16
+	BaseSequence  int32
 }
 
 // Throttle gives the throttling duration applied by the kafka server on the
@@ -77,6 +113,10 @@ func (batch *Batch) close() (err error) {
 	conn := batch.conn
 	lock := batch.lock
 
+	if batch.stop != nil {
+		batch.stop()
+	}
+
 	batch.conn = nil
 	batch.lock = nil
 	if batch.msgs != nil {
@@ -108,6 +148,41 @@ func (batch *Batch) close() (err error) {
 	return
 }
 
+// RecordBatchHeader returns the header of the record batch that the last
+// message read from the batch belongs to, or the zero value if no messages
+// were read.
+//
+// A Batch returned by Conn.ReadBatch may span multiple record batches, the
+// header can change after each call to Read or ReadMessage.
+func (batch *Batch) RecordBatchHeader() RecordBatchHeader {
+	batch.mutex.Lock()
+	defer batch.mutex.Unlock()
+
+	if batch.msgs == nil || batch.msgs.last.length == 0 {
+		return RecordBatchHeader{}
+	}
+
+	h := &batch.msgs.last
+	switch h.magic {
+	case 0, 1:
+		return RecordBatchHeader{
+			Version:    h.magic,
+			Attributes: protocol.Attributes(h.v1.attributes),
+		}
+	default:
+		return RecordBatchHeader{
+			Version:              h.magic,
+			Attributes:           protocol.Attributes(h.v2.attributes),
+			PartitionLeaderEpoch: h.v2.leaderEpoch,
+			BaseOffset:           h.firstOffset,
+			LastOffset:           h.firstOffset + int64(h.v2.lastOffsetDelta),
+			ProducerID:           h.v2.producerID,
+			ProducerEpoch:        h.v2.producerEpoch,
+			BaseSequence:         h.v2.baseSequence,
+		}
+	}
+}
+
 // Err returns a non-nil error if the batch is broken. This is the same error
 // that would be returned by Read, ReadMessage or Close (except in the case of
 // io.EOF which is never returned by Close).
@@ -193,11 +268,11 @@ func (batch *Batch) ReadMessage() (Message, error) {
 
 	offset, timestamp, headers, err = batch.readMessage(
 		func(r *bufio.Reader, size int, nbytes int) (remain int, err error) {
-			msg.Key, remain, err = readNewBytes(r, size, nbytes)
+			msg.Key, remain, err = readLeasedBytes(r, size, nbytes, batch.buffers, &msg.leases[0])
 			return
 		},
 		func(r *bufio.Reader, size int, nbytes int) (remain int, err error) {
-			msg.Value, remain, err = readNewBytes(r, size, nbytes)
+			msg.Value, remain, err = readLeasedBytes(r, size, nbytes, batch.buffers, &msg.leases[1])
 			return
 		},
 	)
@@ -207,13 +282,14 @@ func (batch *Batch) ReadMessage() (Message, error) {
 		if err != nil {
 			break
 		}
+		msg.Release()
 		offset, timestamp, headers, err = batch.readMessage(
 			func(r *bufio.Reader, size int, nbytes int) (remain int, err error) {
-				msg.Key, remain, err = readNewBytes(r, size, nbytes)
+				msg.Key, remain, err = readLeasedBytes(r, size, nbytes, batch.buffers, &msg.leases[0])
 				return
 			},
 			func(r *bufio.Reader, size int, nbytes int) (remain int, err error) {
-				msg.Value, remain, err = readNewBytes(r, size, nbytes)
+				msg.Value, remain, err = readLeasedBytes(r, size, nbytes, batch.buffers, &msg.leases[1])
 				return
 			},
 		)
@@ -257,6 +333,7 @@ func (batch *Batch) readMessage(
 			// caller can't tell the difference between a batch that was fully
 			// consumed or a batch whose connection is in an error state.
 			batch.err = dontExpectEOF(err)
+			err, batch.err = batch.contextErr(err), batch.contextErr(batch.err)
 		case batch.msgs.remaining() == 0:
 			// Because we use the adjusted deadline we could end up returning
 			// before the actual deadline occurred. This is necessary otherwise
@@ -292,11 +369,21 @@ func (batch *Batch) readMessage(
 		// caller can't tell the difference between a batch that was fully
 		// consumed or a batch whose connection is in an error state.
 		batch.err = dontExpectEOF(err)
+		err, batch.err = batch.contextErr(err), batch.contextErr(batch.err)
 	}
 
 	return
 }
 
+// contextErr replaces err with the error of the batch context when reading the
+// batch failed because the context was canceled.
+func (batch *Batch) contextErr(err error) error {
+	if err != nil && batch.ctx != nil && batch.ctx.Err() != nil {
+		return batch.ctx.Err()
+	}
+	return err
+}
+
 func checkTimeoutErr(deadline time.Time) (err error) {
 	if !deadline.IsZero() && time.Now().After(deadline) {
 		err = RequestTimedOut
@@ -1,12 +1,16 @@
 package kafka
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"io"
 	"net"
 	"strconv"
 	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
 )
 
 func TestBatchDontExpectEOF(t *testing.T) {
@@ -39,3 +43,59 @@ func TestBatchDontExpectEOF(t *testing.T) {
 		t.Error("bad error when closing the batch:", err)
 	}
 }
+
+func TestBatchRecordBatchHeader(t *testing.T) {
+	for _, codec := range []Compression{0, Gzip} {
+		t.Run(codec.String(), func(t *testing.T) {
+			buffer := new(bytes.Buffer)
+			rs := protocol.RecordSet{
+				Version:    2,
+				Attributes: protocol.Attributes(codec) | protocol.Transactional,
+				Producer:   &protocol.ProducerState{ID: 42, Epoch: 3, BaseSequence: 7},
+				Records: protocol.NewRecordReader(
+					protocol.Record{
+						Value:   protocol.NewBytes([]byte("A")),
+						Headers: []protocol.Header{{Key: "k", Value: []byte("v")}},
+					},
+					protocol.Record{Offset: 1, Value: protocol.NewBytes([]byte("B"))},
+				),
+			}
+			if _, err := rs.WriteTo(buffer); err != nil {
+				t.Fatal(err)
+			}
+			// Skip the size of the record set.
+			b := buffer.Bytes()[4:]
+
+			msgs, err := newMessageSetReader(bufio.NewReader(bytes.NewReader(b)), len(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			batch := &Batch{msgs: msgs}
+
+			if h := batch.RecordBatchHeader(); h != (RecordBatchHeader{}) {
+				t.Errorf("header returned before reading messages: %+v", h)
+			}
+
+			for i := 0; i < 2; i++ {
+				m, err := batch.ReadMessage()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if i == 0 && (len(m.Headers) != 1 || m.Headers[0].Key != "k") {
+					t.Errorf("wrong message headers: %+v", m.Headers)
+				}
+
+				h := batch.RecordBatchHeader()
+				if h.Version != 2 || h.Attributes.Compression() != codec || !h.Attributes.Transactional() || h.Attributes.Control() {
+					t.Errorf("wrong attributes: %+v", h)
+				}
+				if h.ProducerID != 42 || h.ProducerEpoch != 3 || h.BaseSequence != 7 {
+					t.Errorf("wrong producer: %+v", h)
+				}
+				if h.BaseOffset != 0 || h.LastOffset != 1 {
+					t.Errorf("wrong offsets: %+v", h)
+				}
+			}
+		})
+	}
+}
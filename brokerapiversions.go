@@ -0,0 +1,227 @@
+package kafka
+
+import (
+	"strconv"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+// BrokerApiVersions is the matrix of API versions supported by a kafka broker,
+// returned by Conn.BrokerApiVersions.
+type BrokerApiVersions struct {
+	// The range of versions of each API supported by the broker, by API key.
+	// The maximum versions are lowered to the ones configured in
+	// ConnConfig.MaxVersions.
+	Broker map[protocol.ApiKey]ApiVersion
+
+	// The range of versions of each API that both the broker and Conn support,
+	// which the connection sends requests with. APIs that Conn does not send
+	// requests for, or of which the broker supports none of the versions, are
+	// absent.
+	Negotiated map[protocol.ApiKey]ApiVersion
+
+	// The version of the software that the broker is compatible with. Brokers
+	// do not report the software that they run, ApiVersions responses carry no
+	// software name or version (KIP-511 only lets clients identify
+	// themselves), so it is detected from the API versions: SoftwareVersion is
+	// the earliest Apache Kafka release supporting the APIs that the broker
+	// supports, like "2.8".
+	SoftwareVersion string
+}
+
+// BrokerFeature is a capability of kafka brokers that programs can test with
+// BrokerApiVersions.SupportsFeature.
+type BrokerFeature int
+
+const (
+	// FeatureRecordHeaders is the support of message headers, and of the v2
+	// record batch format (Kafka 0.11).
+	FeatureRecordHeaders BrokerFeature = iota
+
+	// FeatureIdempotentProducer is the support of producer ids (Kafka 0.11).
+	FeatureIdempotentProducer
+
+	// FeatureTransactions is the support of transactional producers (Kafka
+	// 0.11).
+	FeatureTransactions
+
+	// FeatureIncrementalFetch is the support of fetch sessions, KIP-227 (Kafka
+	// 1.1).
+	FeatureIncrementalFetch
+
+	// FeatureZstdCompression is the support of the zstd compression codec
+	// (Kafka 2.1).
+	FeatureZstdCompression
+
+	// FeatureStaticMembership is the support of group instance ids in consumer
+	// groups, KIP-345 (Kafka 2.3).
+	FeatureStaticMembership
+
+	// FeatureIncrementalAlterConfigs is the support of the
+	// IncrementalAlterConfigs API (Kafka 2.3).
+	FeatureIncrementalAlterConfigs
+
+	// FeatureOffsetDelete is the support of the OffsetDelete API (Kafka 2.4).
+	FeatureOffsetDelete
+
+	// FeatureClientQuotas is the support of the DescribeClientQuotas and
+	// AlterClientQuotas APIs (Kafka 2.6).
+	FeatureClientQuotas
+
+	// FeatureTopicIDs is the support of topic ids in metadata responses,
+	// KIP-516 (Kafka 2.8).
+	FeatureTopicIDs
+
+	// FeatureMaxTimestampOffsets is the support of the MaxTimestamp offset
+	// lookups of the ListOffsets API, KIP-734 (Kafka 3.0).
+	FeatureMaxTimestampOffsets
+
+	// FeatureProducerEpochBump is the support of bumping the epoch of
+	// producer ids with InitProducerId, KIP-360 (Kafka 2.5).
+	FeatureProducerEpochBump
+)
+
+// brokerFeatures are the API versions that brokers must support to have each
+// of the features, indexed by feature.
+var brokerFeatures = [...][]ApiVersion{
+	FeatureRecordHeaders:           {{ApiKey: int16(protocol.Produce), MinVersion: 3}, {ApiKey: int16(protocol.Fetch), MinVersion: 4}},
+	FeatureIdempotentProducer:      {{ApiKey: int16(protocol.InitProducerId)}, {ApiKey: int16(protocol.Produce), MinVersion: 3}},
+	FeatureTransactions:            {{ApiKey: int16(protocol.AddPartitionsToTxn)}, {ApiKey: int16(protocol.EndTxn)}, {ApiKey: int16(protocol.TxnOffsetCommit)}},
+	FeatureIncrementalFetch:        {{ApiKey: int16(protocol.Fetch), MinVersion: 7}},
+	FeatureZstdCompression:         {{ApiKey: int16(protocol.Produce), MinVersion: 7}, {ApiKey: int16(protocol.Fetch), MinVersion: 10}},
+	FeatureStaticMembership:        {{ApiKey: int16(protocol.JoinGroup), MinVersion: 5}},
+	FeatureIncrementalAlterConfigs: {{ApiKey: int16(protocol.IncrementalAlterConfigs)}},
+	FeatureOffsetDelete:            {{ApiKey: int16(protocol.OffsetDelete)}},
+	FeatureClientQuotas:            {{ApiKey: int16(protocol.DescribeClientQuotas)}, {ApiKey: int16(protocol.AlterClientQuotas)}},
+	FeatureTopicIDs:                {{ApiKey: int16(protocol.Metadata), MinVersion: 10}},
+	FeatureMaxTimestampOffsets:     {{ApiKey: int16(protocol.ListOffsets), MinVersion: 7}},
+	FeatureProducerEpochBump:       {{ApiKey: int16(protocol.InitProducerId), MinVersion: 3}},
+}
+
+var brokerFeatureNames = [...]string{
+	FeatureRecordHeaders:           "RecordHeaders",
+	FeatureIdempotentProducer:      "IdempotentProducer",
+	FeatureTransactions:            "Transactions",
+	FeatureIncrementalFetch:        "IncrementalFetch",
+	FeatureZstdCompression:         "ZstdCompression",
+	FeatureStaticMembership:        "StaticMembership",
+	FeatureIncrementalAlterConfigs: "IncrementalAlterConfigs",
+	FeatureOffsetDelete:            "OffsetDelete",
+	FeatureClientQuotas:            "ClientQuotas",
+	FeatureTopicIDs:                "TopicIDs",
+	FeatureMaxTimestampOffsets:     "MaxTimestampOffsets",
+	FeatureProducerEpochBump:       "ProducerEpochBump",
+}
+
+func (f BrokerFeature) String() string {
+	if i := int(f); i >= 0 && i < len(brokerFeatureNames) {
+		return brokerFeatureNames[i]
+	}
+	return "BrokerFeature(" + strconv.Itoa(int(f)) + ")"
+}
+
+// kafkaReleases are the APIs introduced by Apache Kafka releases, used to
+// detect the version of brokers, ordered from the most recent release.
+var kafkaReleases = []struct {
+	version string
+	api     ApiVersion
+}{
+	{"3.0", ApiVersion{ApiKey: int16(protocol.ListOffsets), MinVersion: 7}},
+	{"2.8", ApiVersion{ApiKey: int16(protocol.DescribeCluster)}},
+	{"2.7", ApiVersion{ApiKey: int16(protocol.DescribeUserScramCredentials)}},
+	{"2.6", ApiVersion{ApiKey: int16(protocol.DescribeClientQuotas)}},
+	{"2.4", ApiVersion{ApiKey: int16(protocol.OffsetDelete)}},
+	{"2.3", ApiVersion{ApiKey: int16(protocol.IncrementalAlterConfigs)}},
+	{"2.2", ApiVersion{ApiKey: int16(protocol.ElectLeaders)}},
+	{"2.1", ApiVersion{ApiKey: int16(protocol.Fetch), MinVersion: 10}},
+	{"2.0", ApiVersion{ApiKey: int16(protocol.Fetch), MinVersion: 8}},
+	{"1.1", ApiVersion{ApiKey: int16(protocol.DeleteGroups)}},
+	{"1.0", ApiVersion{ApiKey: int16(protocol.CreatePartitions)}},
+	{"0.11", ApiVersion{ApiKey: int16(protocol.InitProducerId)}},
+	{"0.10.2", ApiVersion{ApiKey: int16(protocol.OffsetFetch), MinVersion: 2}},
+	{"0.10.1", ApiVersion{ApiKey: int16(protocol.CreateTopics)}},
+	{"0.10.0", ApiVersion{ApiKey: int16(protocol.ApiVersions)}},
+}
+
+// connApiVersions are the versions of the requests that Conn sends, by API
+// key, sorted in ascending order. Conn negotiates the highest version that the
+// broker supports.
+var connApiVersions = map[apiKey][]apiVersion{
+	produce:              {v2, v3, v7},
+	fetch:                {v2, v5, v10},
+	listOffsets:          {v1},
+	metadata:             {v1},
+	offsetCommit:         {v2},
+	offsetFetch:          {v1},
+	findCoordinator:      {v0},
+	joinGroup:            {v1},
+	heartbeat:            {v0},
+	leaveGroup:           {v0},
+	syncGroup:            {v0},
+	listGroups:           {v1},
+	saslHandshake:        {v0, v1},
+	apiVersions:          {v0},
+	createTopics:         {v0},
+	deleteTopics:         {v0},
+	offsetForLeaderEpoch: {v1},
+	saslAuthenticate:     {v0, v1},
+}
+
+func makeBrokerApiVersions(versions []ApiVersion) BrokerApiVersions {
+	v := BrokerApiVersions{
+		Broker:     make(map[protocol.ApiKey]ApiVersion, len(versions)),
+		Negotiated: make(map[protocol.ApiKey]ApiVersion, len(connApiVersions)),
+	}
+
+	for _, a := range versions {
+		v.Broker[protocol.ApiKey(a.ApiKey)] = a
+	}
+
+	for key, supported := range connApiVersions {
+		a, ok := v.Broker[protocol.ApiKey(key)]
+		if !ok {
+			continue
+		}
+		n := ApiVersion{ApiKey: int16(key), MinVersion: -1, MaxVersion: -1}
+		for _, s := range supported {
+			if int16(s) < a.MinVersion || int16(s) > a.MaxVersion {
+				continue
+			}
+			if n.MinVersion < 0 {
+				n.MinVersion = int16(s)
+			}
+			n.MaxVersion = int16(s)
+		}
+		if n.MinVersion >= 0 {
+			v.Negotiated[protocol.ApiKey(key)] = n
+		}
+	}
+
+	for _, r := range kafkaReleases {
+		if v.supports(r.api) {
+			v.SoftwareVersion = r.version
+			break
+		}
+	}
+	return v
+}
+
+// SupportsFeature reports whether the broker supports the feature f.
+func (v BrokerApiVersions) SupportsFeature(f BrokerFeature) bool {
+	if i := int(f); i < 0 || i >= len(brokerFeatures) {
+		return false
+	}
+	for _, api := range brokerFeatures[f] {
+		if !v.supports(api) {
+			return false
+		}
+	}
+	return true
+}
+
+// supports reports whether the broker supports the API key of api, in version
+// api.MinVersion or above.
+func (v BrokerApiVersions) supports(api ApiVersion) bool {
+	a, ok := v.Broker[protocol.ApiKey(api.ApiKey)]
+	return ok && a.MaxVersion >= api.MinVersion
+}
@@ -0,0 +1,63 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func TestBrokerApiVersions(t *testing.T) {
+	v := makeBrokerApiVersions([]ApiVersion{
+		{ApiKey: int16(protocol.Produce), MinVersion: 0, MaxVersion: 7},
+		{ApiKey: int16(protocol.Fetch), MinVersion: 0, MaxVersion: 10},
+		{ApiKey: int16(protocol.ApiVersions), MinVersion: 0, MaxVersion: 2},
+		{ApiKey: int16(protocol.InitProducerId), MinVersion: 0, MaxVersion: 1},
+		{ApiKey: int16(protocol.CreatePartitions), MinVersion: 0, MaxVersion: 1},
+		{ApiKey: int16(protocol.DeleteGroups), MinVersion: 0, MaxVersion: 1},
+		{ApiKey: int16(protocol.Metadata), MinVersion: 0, MaxVersion: 99},
+		// APIs which Conn does not send requests for are not negotiated.
+		{ApiKey: int16(protocol.LeaderAndIsr), MinVersion: 0, MaxVersion: 4},
+	})
+
+	if v.SoftwareVersion != "2.1" {
+		t.Errorf("wrong software version detected: %s", v.SoftwareVersion)
+	}
+
+	for key, want := range map[protocol.ApiKey]ApiVersion{
+		protocol.Produce:     {ApiKey: int16(protocol.Produce), MinVersion: 2, MaxVersion: 7},
+		protocol.Fetch:       {ApiKey: int16(protocol.Fetch), MinVersion: 2, MaxVersion: 10},
+		protocol.Metadata:    {ApiKey: int16(protocol.Metadata), MinVersion: 1, MaxVersion: 1},
+		protocol.ApiVersions: {ApiKey: int16(protocol.ApiVersions), MinVersion: 0, MaxVersion: 0},
+	} {
+		if a := v.Negotiated[key]; a != want {
+			t.Errorf("wrong negotiated %s versions: want %v, got %v", key, want, a)
+		}
+	}
+	for _, key := range []protocol.ApiKey{protocol.LeaderAndIsr, protocol.InitProducerId, protocol.JoinGroup} {
+		if a, ok := v.Negotiated[key]; ok {
+			t.Errorf("unexpected negotiated versions of %s: %v", key, a)
+		}
+	}
+	if a := v.Broker[protocol.Metadata]; a.MaxVersion != 99 {
+		t.Errorf("wrong broker metadata versions: %v", a)
+	}
+
+	for _, test := range []struct {
+		feature  BrokerFeature
+		supports bool
+	}{
+		{FeatureRecordHeaders, true},
+		{FeatureIdempotentProducer, true},
+		{FeatureTransactions, false},
+		{FeatureIncrementalFetch, true},
+		{FeatureZstdCompression, true},
+		{FeatureStaticMembership, false},
+		{FeatureTopicIDs, true},
+		{FeatureProducerEpochBump, false},
+		{BrokerFeature(-1), false},
+	} {
+		if supports := v.SupportsFeature(test.feature); supports != test.supports {
+			t.Errorf("wrong support of %s: want %t, got %t", test.feature, test.supports, supports)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package kafka
+
+import (
+	"math/rand"
+	"net"
+	"sort"
+	"sync/atomic"
+
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/findcoordinator"
+	meta "github.com/segmentio/kafka-go/protocol/metadata"
+)
+
+// BrokerSelector is an interface implemented by types that choose the broker
+// which a Transport sends the Metadata, FindCoordinator, and ApiVersions
+// requests to, since any broker of the cluster can serve them. Requests sent
+// to the address of a specific broker are not passed to the selector.
+type BrokerSelector interface {
+	// SelectBroker returns the index of the broker that req is sent to in the
+	// list of brokers, which is ordered by broker ID. Returning an index out
+	// of range sends the request to the bootstrap address instead.
+	//
+	// The method may be called concurrently from multiple goroutines.
+	SelectBroker(req Request, brokers []BrokerLoad) int
+}
+
+// BrokerSelectorFunc is an implementation of the BrokerSelector interface for
+// functions.
+type BrokerSelectorFunc func(Request, []BrokerLoad) int
+
+// SelectBroker satisfies the BrokerSelector interface.
+func (f BrokerSelectorFunc) SelectBroker(req Request, brokers []BrokerLoad) int {
+	return f(req, brokers)
+}
+
+// BrokerLoad describes the load of the connections that a Transport maintains
+// with a broker, it is passed to broker selectors.
+type BrokerLoad struct {
+	Broker Broker
+
+	// Number of requests in flight to the broker.
+	InFlight int
+
+	// Number of connections open to the broker, and the number of those that
+	// are idle.
+	OpenConns int
+	IdleConns int
+}
+
+// LeastLoadedBrokerSelector is the default BrokerSelector of transports, it
+// selects the broker with the fewest requests in flight, preferring brokers to
+// which idle connections are open, and chooses randomly between brokers with
+// equal loads to spread the requests across the cluster.
+type LeastLoadedBrokerSelector struct{}
+
+// SelectBroker satisfies the BrokerSelector interface.
+func (LeastLoadedBrokerSelector) SelectBroker(req Request, brokers []BrokerLoad) int {
+	selected, ties := -1, 0
+
+	for i := range brokers {
+		if selected < 0 {
+			selected, ties = i, 1
+			continue
+		}
+
+		switch compareBrokerLoads(&brokers[i], &brokers[selected]) {
+		case -1:
+			selected, ties = i, 1
+		case 0:
+			// Reservoir sampling, each of the brokers with the lowest load
+			// has the same probability of being selected.
+			if ties++; rand.Intn(ties) == 0 {
+				selected = i
+			}
+		}
+	}
+
+	return selected
+}
+
+func compareBrokerLoads(a, b *BrokerLoad) int {
+	switch {
+	case a.InFlight < b.InFlight:
+		return -1
+	case a.InFlight > b.InFlight:
+		return +1
+	case a.IdleConns > 0 && b.IdleConns == 0:
+		return -1
+	case a.IdleConns == 0 && b.IdleConns > 0:
+		return +1
+	default:
+		return 0
+	}
+}
+
+// selectBroker returns the connection group of the broker that the selector of
+// the pool chose to send req to, or nil if the bootstrap connection group must
+// be used.
+//
+// Only Metadata, FindCoordinator, and ApiVersions requests are sent to the
+// selected brokers, and only by pools created for a bootstrap address. Pools
+// created for the address of a broker send the requests to that broker, which
+// is what programs expect when they set the address of a request (e.g. the
+// Addr field of ApiVersionsRequest). Other requests, like those sent with
+// RoundTripRaw, always use the bootstrap connection group.
+func (p *connPool) selectBroker(req Request) *connGroup {
+	switch req.(type) {
+	case *meta.Request, *findcoordinator.Request, *apiversions.Request:
+	default:
+		return nil
+	}
+
+	p.mutex.RLock()
+	groups := make([]*connGroup, 0, len(p.conns))
+	for _, g := range p.conns {
+		groups = append(groups, g)
+	}
+	p.mutex.RUnlock()
+
+	if len(groups) == 0 {
+		return nil
+	}
+
+	for _, g := range groups {
+		if sameAddr(g.addr, p.ctrl.addr) {
+			return nil
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].broker.ID < groups[j].broker.ID
+	})
+
+	brokers := make([]BrokerLoad, len(groups))
+	for i, g := range groups {
+		brokers[i] = g.load()
+	}
+
+	selector := p.selector
+	if selector == nil {
+		selector = LeastLoadedBrokerSelector{}
+	}
+
+	if i := selector.SelectBroker(req, brokers); i >= 0 && i < len(groups) {
+		return groups[i]
+	}
+	return nil
+}
+
+func sameAddr(a, b net.Addr) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	return a.Network() == b.Network() && a.String() == b.String()
+}
+
+func (g *connGroup) load() BrokerLoad {
+	g.mutex.Lock()
+	openConns := g.openConns
+	idleConns := len(g.idleConns)
+	g.mutex.Unlock()
+
+	return BrokerLoad{
+		Broker:    g.broker,
+		InFlight:  int(atomic.LoadInt64(g.stats.inflight.ptr())),
+		OpenConns: openConns,
+		IdleConns: idleConns,
+	}
+}
@@ -0,0 +1,169 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/describecluster"
+)
+
+func TestLeastLoadedBrokerSelector(t *testing.T) {
+	tests := []struct {
+		scenario string
+		brokers  []BrokerLoad
+		selected []int
+	}{
+		{
+			scenario: "no brokers",
+			selected: []int{-1},
+		},
+		{
+			scenario: "the broker with the fewest requests in flight is selected",
+			brokers: []BrokerLoad{
+				{Broker: Broker{ID: 0}, InFlight: 3, IdleConns: 1},
+				{Broker: Broker{ID: 1}, InFlight: 1},
+				{Broker: Broker{ID: 2}, InFlight: 2, IdleConns: 1},
+			},
+			selected: []int{1},
+		},
+		{
+			scenario: "brokers with idle connections are preferred",
+			brokers: []BrokerLoad{
+				{Broker: Broker{ID: 0}},
+				{Broker: Broker{ID: 1}, OpenConns: 1, IdleConns: 1},
+				{Broker: Broker{ID: 2}},
+			},
+			selected: []int{1},
+		},
+		{
+			scenario: "brokers with equal loads are selected randomly",
+			brokers: []BrokerLoad{
+				{Broker: Broker{ID: 0}},
+				{Broker: Broker{ID: 1}},
+				{Broker: Broker{ID: 2}, InFlight: 1},
+			},
+			selected: []int{0, 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			seen := make(map[int]bool)
+			for i := 0; i < 100; i++ {
+				seen[LeastLoadedBrokerSelector{}.SelectBroker(&apiversions.Request{}, test.brokers)] = true
+			}
+			for _, i := range test.selected {
+				if !seen[i] {
+					t.Errorf("broker at index %d was never selected: %v", i, seen)
+				}
+			}
+			if len(seen) != len(test.selected) {
+				t.Errorf("wrong brokers selected: want=%v got=%v", test.selected, seen)
+			}
+		})
+	}
+}
+
+func TestTransportBrokerSelector(t *testing.T) {
+	requests := make(chan connRequest, 1)
+	pool := &connPool{conns: map[int32]*connGroup{}}
+	pool.ctrl = &connGroup{pool: pool, broker: Broker{ID: -1}, idleConns: []*conn{{reqs: requests}}}
+
+	for id := 0; id < 3; id++ {
+		pool.conns[int32(id)] = &connGroup{
+			pool:      pool,
+			broker:    Broker{ID: id},
+			openConns: 1,
+			idleConns: []*conn{{reqs: requests}},
+		}
+	}
+
+	var loads []BrokerLoad
+	pool.selector = BrokerSelectorFunc(func(req Request, brokers []BrokerLoad) int {
+		loads = brokers
+		return 2
+	})
+
+	selected := pool.conns[2].idleConns[0]
+
+	c, err := pool.grabAnyConn(context.Background(), &apiversions.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != selected {
+		t.Error("the connection to the selected broker was not used")
+	}
+	if len(loads) != 3 || loads[0].Broker.ID != 0 || loads[2].Broker.ID != 2 || loads[1].OpenConns != 1 || loads[1].IdleConns != 1 {
+		t.Errorf("wrong broker loads passed to the selector: %+v", loads)
+	}
+
+	pool.selector = BrokerSelectorFunc(func(Request, []BrokerLoad) int { return -1 })
+
+	if _, err := pool.grabAnyConn(context.Background(), &apiversions.Request{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(pool.ctrl.idleConns) != 0 {
+		t.Error("the bootstrap connection was not used")
+	}
+}
+
+func TestTransportBrokerSelectorScope(t *testing.T) {
+	newPool := func(addr net.Addr) *connPool {
+		requests := make(chan connRequest, 1)
+		pool := &connPool{conns: map[int32]*connGroup{}}
+		pool.ctrl = &connGroup{pool: pool, addr: addr, broker: Broker{ID: -1}, idleConns: []*conn{{reqs: requests}}}
+		for id := 0; id < 2; id++ {
+			pool.conns[int32(id)] = &connGroup{
+				pool:      pool,
+				addr:      TCP(fmt.Sprintf("broker-%d:9092", id)),
+				broker:    Broker{ID: id},
+				idleConns: []*conn{{reqs: requests}},
+			}
+		}
+		pool.selector = BrokerSelectorFunc(func(Request, []BrokerLoad) int { return 1 })
+		return pool
+	}
+
+	tests := []struct {
+		scenario  string
+		addr      net.Addr
+		request   Request
+		bootstrap bool
+	}{
+		{
+			scenario: "requests to the bootstrap address use the selector",
+			addr:     TCP("bootstrap:9092"),
+			request:  &apiversions.Request{},
+		},
+		{
+			scenario:  "requests to the address of a broker are sent to the broker",
+			addr:      TCP("broker-0:9092"),
+			request:   &apiversions.Request{},
+			bootstrap: true,
+		},
+		{
+			scenario:  "other requests are sent to the bootstrap address",
+			addr:      TCP("bootstrap:9092"),
+			request:   &describecluster.Request{},
+			bootstrap: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			pool := newPool(test.addr)
+			bootstrap := pool.ctrl.idleConns[0]
+
+			c, err := pool.grabAnyConn(context.Background(), test.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (c == bootstrap) != test.bootstrap {
+				t.Errorf("wrong connection: bootstrap want=%t got=%t", test.bootstrap, c == bootstrap)
+			}
+		})
+	}
+}
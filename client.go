@@ -42,6 +42,23 @@ type Client struct {
 	//
 	// If nil, DefaultTransport is used.
 	Transport RoundTripper
+
+	// When true, requests which fail because the broker they were sent to is
+	// not the controller of the cluster are retried wrapped in an Envelope
+	// request, sent to the controller reported by a DescribeCluster request
+	// (see KIP-590).
+	//
+	// Note that brokers only accept Envelope requests from principals allowed
+	// to perform cluster actions, and that DescribeCluster requires Kafka 2.8
+	// or above.
+	ForwardToController bool
+
+	// When true, the client allows sending requests that brokers normally
+	// only receive from other brokers, which may corrupt the state of the
+	// cluster when misused, like WriteTxnMarkers.
+	//
+	// Only enable this option in programs operating kafka clusters.
+	UnsafeAdmin bool
 }
 
 // A ConsumerGroup and Topic as these are both strings we define a type for
@@ -112,7 +129,11 @@ func (c *Client) roundTrip(ctx context.Context, addr net.Addr, msg protocol.Mess
 		}
 	}
 
-	return c.transport().RoundTrip(ctx, addr, msg)
+	res, err := c.transport().RoundTrip(ctx, addr, msg)
+	if err == nil && c.ForwardToController && notController(res) {
+		return c.forward(ctx, addr, msg)
+	}
+	return res, err
 }
 
 func (c *Client) transport() RoundTripper {
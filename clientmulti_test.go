@@ -0,0 +1,104 @@
+package kafka_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func TestClientProduceFetchMultiplePartitions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A", Partitions: 2}, kafkatest.Topic{Name: "topic-B"})
+	if _, err := b.Append("topic-A", 1, kafkatest.Record{Value: []byte("A1-0")}); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &kafka.Client{Addr: b.Addr()}
+
+	records := func(values ...string) kafka.RecordReader {
+		r := make([]kafka.Record, len(values))
+		for i, v := range values {
+			r[i] = kafka.Record{Value: kafka.NewBytes([]byte(v))}
+		}
+		return kafka.NewRecordReader(r...)
+	}
+
+	produced, err := client.Produce(ctx, &kafka.ProduceRequest{
+		RequiredAcks: kafka.RequireAll,
+		Topics: map[string][]kafka.ProducePartition{
+			"topic-A": {
+				{Partition: 0, Records: records("A0-0", "A0-1")},
+				{Partition: 1, Records: records("A1-1")},
+			},
+			"topic-B": {
+				{Partition: 0, Records: records("B0-0")},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baseOffsets := make(map[string]int64)
+	for topic, partitions := range produced.Topics {
+		for _, p := range partitions {
+			if p.Error != nil {
+				t.Fatalf("%s/%d: %v", topic, p.Partition, p.Error)
+			}
+			baseOffsets[fmt.Sprintf("%s/%d", topic, p.Partition)] = p.BaseOffset
+		}
+	}
+	if s := fmt.Sprint(baseOffsets); s != "map[topic-A/0:0 topic-A/1:1 topic-B/0:0]" {
+		t.Errorf("wrong base offsets: %s", s)
+	}
+
+	fetched, err := client.Fetch(ctx, &kafka.FetchRequest{
+		MaxBytes: 1 << 20,
+		Topics: map[string][]kafka.FetchPartition{
+			"topic-A": {
+				{Partition: 0, Offset: 1},
+				{Partition: 1, Offset: kafka.FirstOffset},
+			},
+			"topic-B": {
+				{Partition: 0, Offset: kafka.LastOffset},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values []string
+	for topic, partitions := range fetched.Topics {
+		for _, p := range partitions {
+			if p.Error != nil {
+				t.Fatalf("%s/%d: %v", topic, p.Partition, p.Error)
+			}
+			for {
+				r, err := p.Records.ReadRecord()
+				if err != nil {
+					break
+				}
+				v, err := kafka.ReadAll(r.Value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				values = append(values, fmt.Sprintf("%s/%d@%d=%s", topic, p.Partition, r.Offset, v))
+			}
+		}
+	}
+	sort.Strings(values)
+
+	// Offset 0 of topic-A/0 may be returned with the batch of offset 1.
+	want := []string{"topic-A/0@0=A0-0", "topic-A/0@1=A0-1", "topic-A/1@0=A1-0", "topic-A/1@1=A1-1"}
+	if fmt.Sprint(values) != fmt.Sprint(want) && fmt.Sprint(values) != fmt.Sprint(want[1:]) {
+		t.Errorf("wrong records:\nwant: %v\ngot:  %v", want, values)
+	}
+}
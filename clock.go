@@ -0,0 +1,130 @@
+package kafka
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is an interface used by readers, writers, transports, and consumer
+// groups to read the time and schedule timers.
+//
+// The default clock is SystemClock. Programs may substitute a fake clock in
+// tests to drive batch timeouts, commit and heartbeat intervals, backoffs, and
+// rebalances without waiting for the real time to elapse, see the Clock type
+// of the kafkatest package.
+//
+// The clock does not apply to network deadlines and idle connection timeouts,
+// which are always measured with the system clock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTimer creates a timer sending the current time on its channel after
+	// at least the duration d.
+	NewTimer(d time.Duration) Timer
+
+	// NewTicker creates a ticker sending the current time on its channel
+	// every period d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is the interface of timers created by a Clock, which behave like
+// time.Timer.
+type Timer interface {
+	// C returns the channel that the time is sent on when the timer fires.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing, it returns false if the timer
+	// already fired or was stopped.
+	Stop() bool
+
+	// Reset changes the timer to fire after the duration d, it returns false
+	// if the timer already fired or was stopped.
+	Reset(d time.Duration) bool
+}
+
+// Ticker is the interface of tickers created by a Clock, which behave like
+// time.Ticker.
+type Ticker interface {
+	// C returns the channel that the ticks are sent on.
+	C() <-chan time.Time
+
+	// Stop turns off the ticker, no more ticks are sent after it returns.
+	Stop()
+}
+
+// SystemClock is the Clock implemented by the time package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{time.NewTimer(d)} }
+
+func (systemClock) NewTicker(d time.Duration) Ticker { return systemTicker{time.NewTicker(d)} }
+
+type systemTimer struct{ *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+type systemTicker struct{ *time.Ticker }
+
+func (t systemTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// clockOrDefault returns c, or SystemClock if c is nil.
+func clockOrDefault(c Clock) Clock {
+	if c == nil {
+		return SystemClock
+	}
+	return c
+}
+
+// sleepClock is like sleep but waits for the duration to elapse on clock.
+func sleepClock(ctx context.Context, clock Clock, duration time.Duration) bool {
+	if duration == 0 {
+		select {
+		default:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	timer := clock.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// afterClock is like sleepClock but returns false when done is closed instead
+// of a context being cancelled.
+func afterClock(done <-chan struct{}, clock Clock, duration time.Duration) bool {
+	timer := clock.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// withClockTimeout is like context.WithTimeout but the timeout elapses on
+// clock.
+func withClockTimeout(parent context.Context, clock Clock, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	timer := clock.NewTimer(timeout)
+	go func() {
+		defer timer.Stop()
+		select {
+		case <-timer.C():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
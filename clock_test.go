@@ -0,0 +1,39 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSystemClock(t *testing.T) {
+	timer := SystemClock.NewTimer(time.Millisecond)
+	defer timer.Stop()
+
+	ticker := SystemClock.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+
+	start := SystemClock.Now()
+	<-timer.C()
+	<-ticker.C()
+
+	if elapsed := time.Since(start); elapsed < time.Millisecond {
+		t.Errorf("the timer fired too early: %s", elapsed)
+	}
+	if timer.Reset(time.Hour) {
+		t.Error("resetting a timer which fired returned true")
+	}
+}
+
+func TestSleepClock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if !sleepClock(ctx, SystemClock, time.Millisecond) {
+		t.Error("sleep was interrupted before the context was cancelled")
+	}
+
+	cancel()
+	if sleepClock(ctx, SystemClock, time.Hour) {
+		t.Error("sleep was not interrupted by the cancelled context")
+	}
+}
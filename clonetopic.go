@@ -0,0 +1,99 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// CloneTopicConfig creates the topic dst with the same number of partitions,
+// replication factor, and topic-level configuration overrides as the existing
+// topic src. Only the topic settings are copied, the records are not.
+//
+// Configuration entries which are read-only or sensitive (whose values are
+// not returned by the brokers) are not copied.
+//
+// The method is intended for building blue/green topic migrations, where a new
+// topic must match the settings of the one that it replaces.
+func (c *Client) CloneTopicConfig(ctx context.Context, src, dst string) error {
+	meta, err := c.Metadata(ctx, &MetadataRequest{Topics: []string{src}})
+	if err != nil {
+		return fmt.Errorf("kafka.(*Client).CloneTopicConfig: %w", err)
+	}
+	if len(meta.Topics) != 1 {
+		return fmt.Errorf("kafka.(*Client).CloneTopicConfig: %q: %w", src, UnknownTopicOrPartition)
+	}
+
+	topic := meta.Topics[0]
+	if topic.Error != nil {
+		return fmt.Errorf("kafka.(*Client).CloneTopicConfig: %q: %w", src, topic.Error)
+	}
+	if len(topic.Partitions) == 0 {
+		return fmt.Errorf("kafka.(*Client).CloneTopicConfig: %q: topic has no partitions", src)
+	}
+
+	configs, err := c.DescribeConfigs(ctx, &DescribeConfigsRequest{
+		Resources: []DescribeConfigRequestResource{{
+			ResourceType: ResourceTypeTopic,
+			ResourceName: src,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("kafka.(*Client).CloneTopicConfig: %w", err)
+	}
+	if len(configs.Resources) != 1 {
+		return fmt.Errorf("kafka.(*Client).CloneTopicConfig: %q: missing configuration in response", src)
+	}
+	if err := configs.Resources[0].Error; err != nil {
+		return fmt.Errorf("kafka.(*Client).CloneTopicConfig: %q: %w", src, err)
+	}
+
+	res, err := c.CreateTopics(ctx, &CreateTopicsRequest{
+		Topics: []TopicConfig{{
+			Topic:             dst,
+			NumPartitions:     len(topic.Partitions),
+			ReplicationFactor: len(topic.Partitions[0].Replicas),
+			ConfigEntries:     topicConfigOverrides(configs.Resources[0].ConfigEntries),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("kafka.(*Client).CloneTopicConfig: %w", err)
+	}
+	if err := res.Errors[dst]; err != nil {
+		return fmt.Errorf("kafka.(*Client).CloneTopicConfig: %q: %w", dst, err)
+	}
+
+	return nil
+}
+
+// topicConfigOverrides returns the configuration entries which were set on
+// the topic itself, rather than inherited from the broker defaults.
+func topicConfigOverrides(entries []DescribeConfigResponseConfigEntry) []ConfigEntry {
+	overrides := make([]ConfigEntry, 0, len(entries))
+
+	for _, e := range entries {
+		if e.ReadOnly || e.IsSensitive {
+			continue
+		}
+
+		switch e.Source() {
+		case ConfigSourceDynamicTopicConfig:
+		case ConfigSourceUnknown:
+			// Version 0 of the DescribeConfigs API only reports whether the
+			// entries are defaults, the source of the others is unknown.
+		default:
+			continue
+		}
+
+		overrides = append(overrides, ConfigEntry{
+			ConfigName:  e.ConfigName,
+			ConfigValue: e.ConfigValue,
+		})
+	}
+
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].ConfigName < overrides[j].ConfigName
+	})
+
+	return overrides
+}
@@ -0,0 +1,80 @@
+package kafka
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTopicConfigOverrides(t *testing.T) {
+	overrides := topicConfigOverrides([]DescribeConfigResponseConfigEntry{
+		{ConfigName: "retention.ms", ConfigValue: "3600000", ConfigSource: int8(ConfigSourceDynamicTopicConfig)},
+		{ConfigName: "cleanup.policy", ConfigValue: "delete", ConfigSource: 5},
+		{ConfigName: "compression.type", ConfigValue: "zstd", IsDefault: false},
+		{ConfigName: "segment.bytes", ConfigValue: "1073741824", IsDefault: true},
+		{ConfigName: "secret", ConfigSource: int8(ConfigSourceDynamicTopicConfig), IsSensitive: true},
+		{ConfigName: "read.only", ConfigValue: "x", ConfigSource: int8(ConfigSourceDynamicTopicConfig), ReadOnly: true},
+	})
+
+	expected := []ConfigEntry{
+		{ConfigName: "compression.type", ConfigValue: "zstd"},
+		{ConfigName: "retention.ms", ConfigValue: "3600000"},
+	}
+
+	if !reflect.DeepEqual(overrides, expected) {
+		t.Errorf("unexpected overrides\nwant: %+v\ngot:  %+v", expected, overrides)
+	}
+}
+
+func TestClientCloneTopicConfig(t *testing.T) {
+	client, shutdown := newLocalClient()
+	defer shutdown()
+
+	src := makeTopic()
+	dst := makeTopic()
+
+	res, err := client.CreateTopics(context.Background(), &CreateTopicsRequest{
+		Topics: []TopicConfig{{
+			Topic:             src,
+			NumPartitions:     3,
+			ReplicationFactor: 1,
+			ConfigEntries: []ConfigEntry{
+				{ConfigName: "retention.ms", ConfigValue: "3600000"},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := res.Errors[src]; err != nil {
+		t.Fatal(err)
+	}
+	defer deleteTopic(t, src)
+
+	if err := client.CloneTopicConfig(context.Background(), src, dst); err != nil {
+		t.Fatal(err)
+	}
+	defer deleteTopic(t, dst)
+
+	meta, err := client.Metadata(context.Background(), &MetadataRequest{Topics: []string{dst}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(meta.Topics[0].Partitions); n != 3 {
+		t.Errorf("expected 3 partitions, got %d", n)
+	}
+
+	configs, err := client.DescribeConfigs(context.Background(), &DescribeConfigsRequest{
+		Resources: []DescribeConfigRequestResource{{
+			ResourceType: ResourceTypeTopic,
+			ResourceName: dst,
+			ConfigNames:  []string{"retention.ms"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := configs.Resources[0].ConfigEntries; len(entries) != 1 || entries[0].ConfigValue != "3600000" {
+		t.Errorf("configuration was not cloned: %+v", entries)
+	}
+}
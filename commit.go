@@ -36,4 +36,7 @@ func makeCommits(msgs ...Message) []commit {
 type commitRequest struct {
 	commits []commit
 	errch   chan<- error
+	// metadata is committed with the offsets instead of the CommitMetadata
+	// of the reader configuration when it is not nil.
+	metadata *string
 }
@@ -0,0 +1,86 @@
+package kafka_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func TestReaderCommitMetadata(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+	if _, err := b.Append("topic-A", 0, kafkatest.Record{Value: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:           []string{b.Addr().String()},
+		GroupID:           "group-A",
+		Topic:             "topic-A",
+		MaxWait:           10 * time.Millisecond,
+		HeartbeatInterval: 100 * time.Millisecond,
+		CommitMetadata:    "host-A",
+	})
+	defer r.Close()
+
+	if _, err := r.ReadMessage(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &kafka.Client{Addr: b.Addr()}
+	res, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: "group-A",
+		Topics:  map[string][]int{"topic-A": {0}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := res.Topics["topic-A"][0]; p.CommittedOffset != 1 || p.Metadata != "host-A" {
+		t.Errorf("wrong committed offset: %+v", p)
+	}
+}
+
+func TestReaderCommitMessagesWithMetadata(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+	if _, err := b.Append("topic-A", 0, kafkatest.Record{Value: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:           []string{b.Addr().String()},
+		GroupID:           "group-A",
+		Topic:             "topic-A",
+		MaxWait:           10 * time.Millisecond,
+		HeartbeatInterval: 100 * time.Millisecond,
+		CommitMetadata:    "host-A",
+	})
+	defer r.Close()
+
+	msg, err := r.FetchMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CommitMessagesWithMetadata(ctx, "checkpoint-1", msg); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &kafka.Client{Addr: b.Addr()}
+	res, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: "group-A",
+		Topics:  map[string][]int{"topic-A": {0}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := res.Topics["topic-A"][0]; p.CommittedOffset != 1 || p.Metadata != "checkpoint-1" {
+		t.Errorf("wrong committed offset: %+v", p)
+	}
+}
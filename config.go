@@ -0,0 +1,51 @@
+package kafka
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConfigError is returned by the Validate methods of configuration types, it
+// lists all the problems found in a configuration so they can be fixed at
+// once.
+//
+// The problems can be inspected with errors.Is and errors.As on Go 1.20 and
+// later, or by iterating over the Errors field.
+type ConfigError struct {
+	Errors []error
+}
+
+func (e *ConfigError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	s := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		s[i] = err.Error()
+	}
+	return fmt.Sprintf("%d configuration problems: %s", len(e.Errors), strings.Join(s, "; "))
+}
+
+// Unwrap returns the list of problems of the configuration.
+func (e *ConfigError) Unwrap() []error {
+	return e.Errors
+}
+
+// configErrors accumulates the problems found when validating a
+// configuration.
+type configErrors []error
+
+func (c *configErrors) add(err error) {
+	*c = append(*c, err)
+}
+
+func (c *configErrors) addf(format string, args ...interface{}) {
+	c.add(fmt.Errorf(format, args...))
+}
+
+func (c configErrors) err() error {
+	if len(c) == 0 {
+		return nil
+	}
+	return &ConfigError{Errors: c}
+}
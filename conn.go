@@ -2,6 +2,7 @@ package kafka
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -9,10 +10,12 @@ import (
 	"net"
 	"os"
 	"path/filepath"
-	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/sasl"
 )
 
 var (
@@ -23,20 +26,28 @@ var (
 // Conn represents a connection to a kafka broker.
 //
 // Instances of Conn are safe to use concurrently from multiple goroutines.
+// Requests made concurrently are pipelined on the connection: each goroutine
+// writes its request without waiting for the responses to the previous ones,
+// and the responses are dispatched to the goroutines that wait for them based
+// on their correlation IDs.
 type Conn struct {
 	// base network connection
 	conn net.Conn
 
-	// number of inflight requests on the connection.
-	inflight int32
-
 	// offset management (synchronized on the mutex field)
 	mutex  sync.Mutex
 	offset int64
 
-	// read buffer (synchronized on rlock)
-	rlock sync.Mutex
-	rbuf  bufio.Reader
+	// SASL session of the connection, which is re-authenticated before it
+	// expires (synchronized on session)
+	session       sync.RWMutex
+	saslMechanism sasl.Mechanism
+	saslMetadata  *sasl.Metadata
+	saslExpiry    time.Time
+
+	// read buffer (synchronized on rdemux)
+	rdemux responseDemux
+	rbuf   bufio.Reader
 
 	// write buffer (synchronized on wlock)
 	wlock sync.Mutex
@@ -55,6 +66,7 @@ type Conn struct {
 	fetchMinSize  int32
 	broker        int32
 	rack          string
+	maxVersions   map[protocol.ApiKey]int16
 
 	// correlation ID generator (synchronized on wlock)
 	correlationID int32
@@ -97,6 +109,11 @@ type ConnConfig struct {
 	// For more details look at transactional.id description here: http://kafka.apache.org/documentation.html#producerconfigs
 	// Empty string means that this connection can't be transactional.
 	TransactionalID string
+
+	// Pins the maximum versions of the APIs used by the connection, the
+	// versions advertised by the broker are capped to the ones set in this
+	// map. See Transport.MaxVersions for details.
+	MaxVersions map[protocol.ApiKey]int16
 }
 
 // ReadBatchConfig is a configuration object used for reading batches of messages.
@@ -183,6 +200,7 @@ func NewConnWith(conn net.Conn, config ConnConfig) *Conn {
 		offset:          FirstOffset,
 		requiredAcks:    -1,
 		transactionalID: emptyToNullable(config.TransactionalID),
+		maxVersions:     config.MaxVersions,
 	}
 
 	c.wb.w = &c.wbuf
@@ -229,6 +247,9 @@ func (c *Conn) loadVersions() (apiVersionMap, error) {
 	v = make(apiVersionMap, len(brokerVersions))
 
 	for _, a := range brokerVersions {
+		if max, ok := c.maxVersions[protocol.ApiKey(a.ApiKey)]; ok && max < a.MaxVersion {
+			a.MaxVersion = max
+		}
 		v[apiKey(a.ApiKey)] = a
 	}
 
@@ -236,6 +257,33 @@ func (c *Conn) loadVersions() (apiVersionMap, error) {
 	return v, nil
 }
 
+// BrokerApiVersions returns the versions of the APIs supported by the broker
+// that the connection was established to, the versions that the connection
+// negotiates with it, and the broker software version detected from them.
+//
+// The versions are the ones that the connection negotiated requests with, the
+// ApiVersions request is only sent if the connection did not send one yet.
+func (c *Conn) BrokerApiVersions() (BrokerApiVersions, error) {
+	v, err := c.loadVersions()
+	if err != nil {
+		return BrokerApiVersions{}, err
+	}
+	versions := make([]ApiVersion, 0, len(v))
+	for _, a := range v {
+		versions = append(versions, a)
+	}
+	return makeBrokerApiVersions(versions), nil
+}
+
+// logArgs returns the keys and values identifying the broker and the last
+// request sent on the connection in log messages.
+func (c *Conn) logArgs() []interface{} {
+	c.wlock.Lock()
+	id := c.correlationID
+	c.wlock.Unlock()
+	return []interface{}{"broker", c.RemoteAddr().String(), "correlation_id", id}
+}
+
 // Broker returns a Broker value representing the kafka broker that this
 // connection was established to.
 func (c *Conn) Broker() Broker {
@@ -466,7 +514,7 @@ func (c *Conn) offsetCommit(request offsetCommitRequestV2) (offsetCommitResponse
 	for _, r := range response.Responses {
 		for _, pr := range r.PartitionResponses {
 			if pr.ErrorCode != 0 {
-				return offsetCommitResponseV2{}, Error(pr.ErrorCode)
+				return offsetCommitResponseV2{}, withErrorContext(Error(pr.ErrorCode), r.Topic, int(pr.Partition), request.GroupID)
 			}
 		}
 	}
@@ -773,12 +821,12 @@ func (c *Conn) ReadBatchWith(cfg ReadBatchConfig) *Batch {
 		return &Batch{err: dontExpectEOF(err)}
 	}
 
-	fetchVersion, err := c.negotiateVersion(fetch, v2, v5, v10)
+	fetchVersion, err := c.negotiateVersion(fetch, connApiVersions[fetch]...)
 	if err != nil {
 		return &Batch{err: dontExpectEOF(err)}
 	}
 
-	id, err := c.doRequest(&c.rdeadline, func(deadline time.Time, id int32) error {
+	id, err := c.doSessionRequest(&c.rdeadline, func(deadline time.Time, id int32) error {
 		now := time.Now()
 		var timeout time.Duration
 		if cfg.MaxWait > 0 {
@@ -887,6 +935,41 @@ func (c *Conn) ReadBatchWith(cfg ReadBatchConfig) *Batch {
 	}
 }
 
+// ReadBatchContext is like ReadBatchWith, but honors the cancellation and
+// deadline of ctx while fetching the batch and reading its messages, instead of
+// requiring the program to set deadlines on the connection.
+//
+// When ctx is canceled, the blocking operations of the connection are
+// interrupted, the batch fails with the error of the context, and the
+// connection is closed when the batch is, since its state is unknown.
+//
+// When cfg.MaxWait is zero and ctx has a deadline, the broker is asked to
+// respond before the deadline.
+func (c *Conn) ReadBatchContext(ctx context.Context, cfg ReadBatchConfig) *Batch {
+	if err := ctx.Err(); err != nil {
+		return &Batch{err: err}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && cfg.MaxWait == 0 {
+		now := time.Now()
+		if timeout := deadlineToTimeout(adjustDeadlineForRTT(deadline, now, defaultRTT), now); timeout > 0 {
+			cfg.MaxWait = timeout
+		}
+	}
+
+	stop := c.watchContext(ctx)
+	batch := c.ReadBatchWith(cfg)
+	batch.ctx, batch.stop = ctx, stop
+	batch.err = batch.contextErr(batch.err)
+
+	if batch.conn == nil {
+		// The batch failed before taking the lock on the connection, closing
+		// it would not stop watching the context.
+		stop()
+	}
+	return batch
+}
+
 // ReadOffset returns the offset of the first message with a timestamp equal or
 // greater to t.
 func (c *Conn) ReadOffset(t time.Time) (int64, error) {
@@ -919,6 +1002,59 @@ func (c *Conn) ReadOffsets() (first, last int64, err error) {
 	return
 }
 
+// ReadOffsetForLeaderEpoch returns the end offset of the given leader epoch on
+// the partition of the connection, along with the epoch that the offset belongs
+// to, which is lower than the requested epoch if the leader did not know about
+// it.
+//
+// Consumers use this method after a leader change to detect that the log was
+// truncated, in which case they must seek back to the returned offset.
+//
+// The method requires kafka 2.0 or above, and must be called on a connection to
+// the leader of the partition.
+func (c *Conn) ReadOffsetForLeaderEpoch(leaderEpoch int) (epoch int, offset int64, err error) {
+	if _, err = c.negotiateVersion(offsetForLeaderEpoch, connApiVersions[offsetForLeaderEpoch]...); err != nil {
+		return
+	}
+
+	err = c.readOperation(
+		func(deadline time.Time, id int32) error {
+			return c.writeRequest(offsetForLeaderEpoch, v1, id, offsetForLeaderEpochRequestV1{
+				Topics: []offsetForLeaderEpochRequestTopicV1{{
+					Topic: c.topic,
+					Partitions: []offsetForLeaderEpochRequestPartitionV1{{
+						Partition:   c.partition,
+						LeaderEpoch: int32(leaderEpoch),
+					}},
+				}},
+			})
+		},
+		func(deadline time.Time, size int) error {
+			var res offsetForLeaderEpochResponseV1
+
+			if err := c.readResponse(size, &res); err != nil {
+				return err
+			}
+
+			for _, t := range res.Topics {
+				for _, p := range t.Partitions {
+					if t.Topic != c.topic || p.Partition != c.partition {
+						continue
+					}
+					if p.ErrorCode != 0 {
+						return Error(p.ErrorCode)
+					}
+					epoch, offset = int(p.LeaderEpoch), p.EndOffset
+					return nil
+				}
+			}
+
+			return UnknownTopicOrPartition
+		},
+	)
+	return
+}
+
 func (c *Conn) readOffset(t int64) (offset int64, err error) {
 	err = c.readOperation(
 		func(deadline time.Time, id int32) error {
@@ -1045,6 +1181,27 @@ func (c *Conn) WriteMessages(msgs ...Message) (int, error) {
 	return c.WriteCompressedMessages(nil, msgs...)
 }
 
+// WriteMessagesContext is like WriteMessages, but honors the cancellation and
+// deadline of ctx, instead of requiring the program to set deadlines on the
+// connection.
+//
+// When ctx is canceled, the blocking operations of the connection are
+// interrupted and the method returns the error of the context. Whether the
+// messages were written is then unknown, and the connection is closed.
+func (c *Conn) WriteMessagesContext(ctx context.Context, msgs ...Message) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	stop := c.watchContext(ctx)
+	n, err := c.WriteMessages(msgs...)
+	if stop() && err != nil {
+		c.conn.Close()
+		err = ctx.Err()
+	}
+	return n, err
+}
+
 // WriteCompressedMessages writes a batch of messages to the connection's topic
 // and partition, returning the number of bytes written. The write is an atomic
 // operation, it either fully succeeds or fails.
@@ -1091,10 +1248,25 @@ func (c *Conn) writeCompressedMessages(codec CompressionCodec, msgs ...Message)
 	}
 
 	var produceVersion apiVersion
-	if produceVersion, err = c.negotiateVersion(produce, v2, v3, v7); err != nil {
+	if produceVersion, err = c.negotiateVersion(produce, connApiVersions[produce]...); err != nil {
 		return
 	}
 
+	if produceVersion < v3 {
+		// Produce requests older than v3 carry message sets, which cannot
+		// represent some of the features of records.
+		if codec != nil && protocol.Attributes(codec.Code()) == protocol.Zstd {
+			err = &protocol.UnsupportedFeatureError{Feature: "zstd compression", Version: 1}
+			return
+		}
+		for _, msg := range msgs {
+			if len(msg.Headers) != 0 {
+				err = &protocol.UnsupportedFeatureError{Feature: "record headers", Version: 1}
+				return
+			}
+		}
+	}
+
 	err = c.writeOperation(
 		func(deadline time.Time, id int32) error {
 			now := time.Now()
@@ -1269,24 +1441,25 @@ func (c *Conn) writeOperation(write func(time.Time, int32) error, read func(time
 	return c.do(&c.wdeadline, write, read)
 }
 
-func (c *Conn) enter() {
-	atomic.AddInt32(&c.inflight, +1)
-}
-
-func (c *Conn) leave() {
-	atomic.AddInt32(&c.inflight, -1)
-}
-
-func (c *Conn) concurrency() int {
-	return int(atomic.LoadInt32(&c.inflight))
+func (c *Conn) do(d *connDeadline, write func(time.Time, int32) error, read func(time.Time, int) error) error {
+	id, err := c.doSessionRequest(d, write)
+	if err != nil {
+		return err
+	}
+	return c.doResponse(d, id, read)
 }
 
-func (c *Conn) do(d *connDeadline, write func(time.Time, int32) error, read func(time.Time, int) error) error {
-	id, err := c.doRequest(d, write)
+// saslOperation is like writeOperation, but does not check the expiration of
+// the SASL session since it is used to establish it.
+func (c *Conn) saslOperation(write func(time.Time, int32) error, read func(time.Time, int) error) error {
+	id, err := c.doRequest(&c.wdeadline, write)
 	if err != nil {
 		return err
 	}
+	return c.doResponse(&c.wdeadline, id, read)
+}
 
+func (c *Conn) doResponse(d *connDeadline, id int32, read func(time.Time, int) error) error {
 	deadline, size, lock, err := c.waitResponse(d, id)
 	if err != nil {
 		return err
@@ -1304,11 +1477,25 @@ func (c *Conn) do(d *connDeadline, write func(time.Time, int32) error, read func
 	return err
 }
 
+// doSessionRequest writes a request after re-authenticating the SASL session of
+// the connection if it was about to expire.
+func (c *Conn) doSessionRequest(d *connDeadline, write func(time.Time, int32) error) (int32, error) {
+	if err := c.reauthenticateSASL(); err != nil {
+		return 0, err
+	}
+	c.session.RLock()
+	defer c.session.RUnlock()
+	return c.doRequest(d, write)
+}
+
 func (c *Conn) doRequest(d *connDeadline, write func(time.Time, int32) error) (id int32, err error) {
-	c.enter()
 	c.wlock.Lock()
 	c.correlationID++
 	id = c.correlationID
+	// The request is registered before being written, so the response is
+	// dispatched to this goroutine even if it is read by another one before
+	// the call to waitResponse.
+	c.rdemux.register(id)
 	err = write(d.setConnWriteDeadline(c.conn), id)
 	d.unsetConnWriteDeadline()
 
@@ -1317,56 +1504,159 @@ func (c *Conn) doRequest(d *connDeadline, write func(time.Time, int32) error) (i
 		// recoverable state so we're better off just giving up at this point to
 		// avoid any risk of corrupting the following operations.
 		c.conn.Close()
-		c.leave()
+		c.rdemux.unregister(id)
 	}
 
 	c.wlock.Unlock()
 	return
 }
 
-func (c *Conn) waitResponse(d *connDeadline, id int32) (deadline time.Time, size int, lock *sync.Mutex, err error) {
+func (c *Conn) waitResponse(d *connDeadline, id int32) (deadline time.Time, size int, lock *responseLock, err error) {
+	c.rdemux.acquire(id)
+
 	for {
 		var rsz int32
 		var rid int32
 
-		c.rlock.Lock()
 		deadline = d.setConnReadDeadline(c.conn)
 		rsz, rid, err = c.peekResponseSizeAndID()
 
 		if err != nil {
 			d.unsetConnReadDeadline()
 			c.conn.Close()
-			c.rlock.Unlock()
+			c.rdemux.release(id)
 			break
 		}
 
 		if id == rid {
 			c.skipResponseSizeAndID()
-			size, lock = int(rsz-4), &c.rlock
-			// Don't unlock the read mutex to yield ownership to the caller.
+			// Don't release the read side of the connection to yield ownership
+			// to the caller.
+			size, lock = int(rsz-4), &responseLock{demux: &c.rdemux, id: id}
 			break
 		}
 
-		if c.concurrency() == 1 {
-			// If the goroutine is the only one waiting on this connection it
-			// should be impossible to read a correlation id different from the
-			// one it expects. This is a sign that the data we are reading on
+		if !c.rdemux.handoff(id, rid) {
+			// None of the goroutines are waiting for a response with this
+			// correlation id, this is a sign that the data we are reading on
 			// the wire is corrupted and the connection needs to be closed.
+			d.unsetConnReadDeadline()
+			c.conn.Close()
+			c.rdemux.release(id)
 			err = io.ErrNoProgress
-			c.rlock.Unlock()
 			break
 		}
-
-		// Optimistically release the read lock if a response has already
-		// been received but the current operation is not the target for it.
-		c.rlock.Unlock()
-		runtime.Gosched()
 	}
 
-	c.leave()
 	return
 }
 
+// responseDemux dispatches the responses read on a connection to the goroutines
+// waiting for them.
+//
+// The goroutines waiting for responses pass each other the ownership of the
+// read side of the connection: the owner peeks at the correlation id of the
+// next response, and hands the ownership over to the goroutine that waits for
+// it. When a goroutine is done reading its response, it hands the ownership
+// over to any other waiting goroutine, which repeats the process.
+type responseDemux struct {
+	mutex   sync.Mutex
+	reading bool
+	waiting map[int32]chan struct{}
+}
+
+// register declares that the calling goroutine expects a response with the
+// given correlation id.
+func (m *responseDemux) register(id int32) {
+	m.mutex.Lock()
+	if m.waiting == nil {
+		m.waiting = make(map[int32]chan struct{})
+	}
+	m.waiting[id] = make(chan struct{}, 1)
+	m.mutex.Unlock()
+}
+
+// acquire blocks until the goroutine waiting for id owns the read side of the
+// connection.
+func (m *responseDemux) acquire(id int32) {
+	m.mutex.Lock()
+	ready := m.waiting[id]
+
+	if !m.reading {
+		m.reading = true
+		m.mutex.Unlock()
+		return
+	}
+
+	m.mutex.Unlock()
+	<-ready
+}
+
+// handoff passes the ownership of the read side of the connection from the
+// goroutine waiting for id to the one waiting for rid, then blocks until the
+// ownership is handed back. The method returns false if no goroutines are
+// waiting for rid, in which case the caller retains the ownership.
+func (m *responseDemux) handoff(id, rid int32) bool {
+	m.mutex.Lock()
+	ready, next := m.waiting[id], m.waiting[rid]
+	if next != nil {
+		next <- struct{}{}
+	}
+	m.mutex.Unlock()
+
+	if next == nil {
+		return false
+	}
+
+	<-ready
+	return true
+}
+
+// release unregisters the goroutine waiting for id, which owns the read side
+// of the connection, and passes the ownership to another waiting goroutine.
+func (m *responseDemux) release(id int32) {
+	m.mutex.Lock()
+	delete(m.waiting, id)
+	m.next()
+	m.mutex.Unlock()
+}
+
+// unregister unregisters the goroutine waiting for id, which did not acquire
+// the read side of the connection. The ownership may have been handed over to
+// the goroutine already, in which case it is passed to another one.
+func (m *responseDemux) unregister(id int32) {
+	m.mutex.Lock()
+	ready := m.waiting[id]
+	delete(m.waiting, id)
+
+	select {
+	case <-ready:
+		m.next()
+	default:
+	}
+
+	m.mutex.Unlock()
+}
+
+// next passes the ownership of the read side of the connection to any of the
+// waiting goroutines, the mutex must be held when calling the method.
+func (m *responseDemux) next() {
+	for _, ready := range m.waiting {
+		ready <- struct{}{}
+		return
+	}
+	m.reading = false
+}
+
+// responseLock represents the ownership of the read side of a connection by
+// the goroutine which reads the response to a request.
+type responseLock struct {
+	demux *responseDemux
+	id    int32
+}
+
+func (l *responseLock) Unlock() { l.demux.release(l.id) }
+
 func (c *Conn) requestHeader(apiKey apiKey, apiVersion apiVersion, correlationID int32) requestHeader {
 	return requestHeader{
 		ApiKey:        int16(apiKey),
@@ -1388,7 +1678,7 @@ func (c *Conn) ApiVersions() ([]ApiVersion, error) {
 		deadline = &c.wdeadline
 	}
 
-	id, err := c.doRequest(deadline, func(_ time.Time, id int32) error {
+	id, err := c.doSessionRequest(deadline, func(_ time.Time, id int32) error {
 		h := requestHeader{
 			ApiKey:        int16(apiVersions),
 			ApiVersion:    int16(v0),
@@ -1437,6 +1727,40 @@ func (c *Conn) ApiVersions() ([]ApiVersion, error) {
 	return r, nil
 }
 
+// watchContext interrupts the blocking operations of the connection when ctx
+// is canceled, until the returned function is called. The function reports
+// whether the operations were interrupted.
+func (c *Conn) watchContext(ctx context.Context) func() bool {
+	if ctx.Done() == nil {
+		return func() bool { return false }
+	}
+
+	done := make(chan struct{})
+	interrupted := make(chan bool, 1)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			// A deadline in the past unblocks the reads and writes on the
+			// socket immediately. The deadline is reset by the next operation.
+			c.conn.SetDeadline(time.Unix(1, 0))
+			interrupted <- true
+		case <-done:
+			interrupted <- false
+		}
+	}()
+
+	var once sync.Once
+	var result bool
+	return func() bool {
+		once.Do(func() {
+			close(done)
+			result = <-interrupted
+		})
+		return result
+	}
+}
+
 // connDeadline is a helper type to implement read/write deadline management on
 // the kafka connection.
 type connDeadline struct {
@@ -1515,12 +1839,12 @@ func (c *Conn) saslHandshake(mechanism string) error {
 	// challenge/responses are sent
 	var resp saslHandshakeResponseV0
 
-	version, err := c.negotiateVersion(saslHandshake, v0, v1)
+	version, err := c.negotiateVersion(saslHandshake, connApiVersions[saslHandshake]...)
 	if err != nil {
 		return err
 	}
 
-	err = c.writeOperation(
+	err = c.saslOperation(
 		func(deadline time.Time, id int32) error {
 			return c.writeRequest(saslHandshake, version, id, &saslHandshakeRequestV0{Mechanism: mechanism})
 		},
@@ -1539,25 +1863,39 @@ func (c *Conn) saslHandshake(mechanism string) error {
 // saslAuthenticate sends the SASL authenticate message.  This function must
 // be immediately preceded by a successful saslHandshake.
 //
+// The second return value is the lifetime of the SASL session in milliseconds,
+// which is zero if the session does not expire or the broker does not support
+// re-authentication (KIP-368).
+//
 // See http://kafka.apache.org/protocol.html#The_Messages_SaslAuthenticate
-func (c *Conn) saslAuthenticate(data []byte) ([]byte, error) {
+func (c *Conn) saslAuthenticate(data []byte) ([]byte, int64, error) {
 	// if we sent a v1 handshake, then we must encapsulate the authentication
 	// request in a saslAuthenticateRequest.  otherwise, we read and write raw
 	// bytes.
-	version, err := c.negotiateVersion(saslHandshake, v0, v1)
+	version, err := c.negotiateVersion(saslHandshake, connApiVersions[saslHandshake]...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if version == v1 {
+		// The session lifetime was added in v1 of SaslAuthenticate, the
+		// request format is the same in both versions.
+		authVersion, err := c.negotiateVersion(saslAuthenticate, connApiVersions[saslAuthenticate]...)
+		if err != nil {
+			return nil, 0, err
+		}
+
 		var request = saslAuthenticateRequestV0{Data: data}
-		var response saslAuthenticateResponseV0
+		var response saslAuthenticateResponseV1
 
-		err := c.writeOperation(
+		err = c.saslOperation(
 			func(deadline time.Time, id int32) error {
-				return c.writeRequest(saslAuthenticate, v0, id, request)
+				return c.writeRequest(saslAuthenticate, authVersion, id, request)
 			},
 			func(deadline time.Time, size int) error {
 				return expectZeroSize(func() (remain int, err error) {
+					if authVersion == v0 {
+						return (&response.saslAuthenticateResponseV0).readFrom(&c.rbuf, size)
+					}
 					return (&response).readFrom(&c.rbuf, size)
 				}())
 			},
@@ -1565,24 +1903,101 @@ func (c *Conn) saslAuthenticate(data []byte) ([]byte, error) {
 		if err == nil && response.ErrorCode != 0 {
 			err = Error(response.ErrorCode)
 		}
-		return response.Data, err
+		return response.Data, response.SessionLifetimeMs, err
 	}
 
 	// fall back to opaque bytes on the wire.  the broker is expecting these if
 	// it just processed a v0 sasl handshake.
 	c.wb.writeInt32(int32(len(data)))
 	if _, err := c.wb.Write(data); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if err := c.wb.Flush(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	var respLen int32
 	if _, err := readInt32(&c.rbuf, 4, &respLen); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	resp, _, err := readNewBytes(&c.rbuf, int(respLen), int(respLen))
-	return resp, err
+	return resp, 0, err
+}
+
+// authenticateSASL performs all of the required requests to authenticate the
+// connection with the SASL mechanism, and returns the lifetime of the session
+// in milliseconds.
+func (c *Conn) authenticateSASL(ctx context.Context, mechanism sasl.Mechanism) (int64, error) {
+	if err := c.saslHandshake(mechanism.Name()); err != nil {
+		return 0, fmt.Errorf("SASL handshake failed: %w", err)
+	}
+
+	sess, state, err := mechanism.Start(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("SASL authentication process could not be started: %w", err)
+	}
+
+	var lifetimeMs int64
+	for completed := false; !completed; {
+		var challenge []byte
+		challenge, lifetimeMs, err = c.saslAuthenticate(state)
+		switch {
+		case err == nil:
+		case errors.Is(err, io.EOF):
+			// the broker may communicate a failed exchange by closing the
+			// connection (esp. in the case where we're passing opaque sasl
+			// data over the wire since there's no protocol info).
+			return 0, SASLAuthenticationFailed
+		default:
+			return 0, err
+		}
+
+		completed, state, err = sess.Next(ctx, challenge)
+		if err != nil {
+			return 0, fmt.Errorf("SASL authentication process has failed: %w", err)
+		}
+	}
+
+	return lifetimeMs, nil
+}
+
+// setSASLSession records the SASL session established on the connection, which
+// is re-authenticated before it expires.
+func (c *Conn) setSASLSession(mechanism sasl.Mechanism, metadata *sasl.Metadata, lifetimeMs int64) {
+	c.session.Lock()
+	c.saslMechanism = mechanism
+	c.saslMetadata = metadata
+	c.saslExpiry = saslReauthenticationTime(time.Now(), lifetimeMs)
+	c.session.Unlock()
+}
+
+// reauthenticateSASL re-authenticates the connection if its SASL session is
+// about to expire (see KIP-368). New requests are blocked during the exchange,
+// while the responses to the requests in flight are still being read.
+func (c *Conn) reauthenticateSASL() error {
+	c.session.RLock()
+	expiry := c.saslExpiry
+	c.session.RUnlock()
+
+	if expiry.IsZero() || time.Now().Before(expiry) {
+		return nil
+	}
+
+	c.session.Lock()
+	defer c.session.Unlock()
+
+	if time.Now().Before(c.saslExpiry) {
+		return nil // re-authenticated by another goroutine
+	}
+
+	ctx := sasl.WithMetadata(context.Background(), c.saslMetadata)
+	lifetimeMs, err := c.authenticateSASL(ctx, c.saslMechanism)
+	if err != nil {
+		c.conn.Close()
+		return fmt.Errorf("SASL re-authentication failed: %w", err)
+	}
+
+	c.saslExpiry = saslReauthenticationTime(time.Now(), lifetimeMs)
+	return nil
 }
@@ -13,6 +13,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/sasl/plain"
 	ktesting "github.com/segmentio/kafka-go/testing"
 	"golang.org/x/net/nettest"
 )
@@ -1366,3 +1369,94 @@ func TestMakeBrokersOneMissing(t *testing.T) {
 		t.Errorf("Expected broker 3 at index 1, got %d", b[1].ID)
 	}
 }
+
+func TestConnPipelining(t *testing.T) {
+	const concurrency = 8
+
+	client, server := net.Pipe()
+	conn := NewConn(client, "", 0)
+	defer conn.Close()
+
+	go func() {
+		defer server.Close()
+
+		// Read all the requests before responding in the reverse order, so
+		// the responses are dispatched to goroutines which did not read them.
+		ids := make([]int32, 0, concurrency)
+		for len(ids) < concurrency {
+			_, correlationID, _, _, err := protocol.ReadRequest(server)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ids = append(ids, correlationID)
+		}
+
+		for i := len(ids) - 1; i >= 0; i-- {
+			protocol.WriteResponse(server, 0, ids[i], &apiversions.Response{
+				ApiKeys: []apiversions.ApiKeyResponse{{ApiKey: int16(ids[i])}},
+			})
+		}
+	}()
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	results := make(chan int16, concurrency)
+
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			versions, err := conn.ApiVersions()
+			if err != nil {
+				t.Error(err)
+				results <- -1
+				return
+			}
+			results <- versions[0].ApiKey
+		}()
+	}
+
+	seen := make(map[int16]bool)
+	for i := 0; i < concurrency; i++ {
+		seen[<-results] = true
+	}
+
+	for id := int16(1); id <= concurrency; id++ {
+		if !seen[id] {
+			t.Errorf("response with correlation id %d was not received", id)
+		}
+	}
+}
+
+func TestConnSASLReauthentication(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	authentications := make(chan struct{}, 10)
+	go serveSASLReauthentication(server, authentications)
+
+	dialer := &Dialer{
+		DialFunc: func(context.Context, string, string) (net.Conn, error) {
+			return client, nil
+		},
+		SASLMechanism: plain.Mechanism{Username: "user", Password: "pass"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := dialer.DialContext(ctx, "tcp", "localhost:9092")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	time.Sleep(2 * time.Millisecond)
+
+	if _, err := conn.ApiVersions(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(authentications); n != 2 {
+		t.Errorf("wrong number of SASL authentications: want=2 got=%d", n)
+	}
+}
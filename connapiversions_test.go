@@ -0,0 +1,38 @@
+package kafka_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func TestConnBrokerApiVersions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t)
+
+	conn, err := kafka.DialContext(ctx, "tcp", b.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	v, err := conn.BrokerApiVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.SoftwareVersion != "2.1" {
+		t.Errorf("wrong software version detected: %q", v.SoftwareVersion)
+	}
+	if !v.SupportsFeature(kafka.FeatureTransactions) {
+		t.Error("the broker supports transactions")
+	}
+	if v.SupportsFeature(kafka.FeatureTopicIDs) {
+		t.Error("the broker does not support topic ids")
+	}
+}
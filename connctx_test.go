@@ -0,0 +1,114 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func dialLeader(t *testing.T, b *kafkatest.Broker) *kafka.Conn {
+	t.Helper()
+	conn, err := kafka.DialLeader(context.Background(), "tcp", b.Addr().String(), "topic-A", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestConnContext(t *testing.T) {
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+
+	t.Run("write and read", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		conn := dialLeader(t, b)
+		if _, err := conn.WriteMessagesContext(ctx,
+			kafka.Message{Value: []byte("A")},
+			kafka.Message{Value: []byte("B")},
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		batch := conn.ReadBatchContext(ctx, kafka.ReadBatchConfig{MinBytes: 1, MaxBytes: 1 << 20})
+		var values []string
+		for {
+			m, err := batch.ReadMessage()
+			if err != nil {
+				break
+			}
+			values = append(values, string(m.Value))
+		}
+		if err := batch.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if len(values) != 2 || values[0] != "A" || values[1] != "B" {
+			t.Errorf("wrong messages: %q", values)
+		}
+	})
+
+	t.Run("cancel a blocked read", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		timer := time.AfterFunc(100*time.Millisecond, cancel)
+		defer timer.Stop()
+
+		conn := dialLeader(t, b)
+		if _, err := conn.Seek(0, kafka.SeekEnd); err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		batch := conn.ReadBatchContext(ctx, kafka.ReadBatchConfig{
+			MinBytes: 1,
+			MaxBytes: 1 << 20,
+			MaxWait:  10 * time.Second,
+		})
+		err := batch.Close()
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("the read was not interrupted (%s)", elapsed)
+		}
+	})
+
+	t.Run("context deadline bounds the fetch", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+
+		conn := dialLeader(t, b)
+		if _, err := conn.Seek(0, kafka.SeekEnd); err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		batch := conn.ReadBatchContext(ctx, kafka.ReadBatchConfig{MinBytes: 1, MaxBytes: 1 << 20})
+		batch.Close()
+
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("the read was not bounded by the context deadline (%s)", elapsed)
+		}
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		conn := dialLeader(t, b)
+		if _, err := conn.WriteMessagesContext(ctx, kafka.Message{Value: []byte("C")}); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		if err := conn.ReadBatchContext(ctx, kafka.ReadBatchConfig{MaxBytes: 1 << 20}).Close(); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		if n := len(b.Records("topic-A", 0)); n != 2 {
+			t.Errorf("%d records were written, want 2", n)
+		}
+	})
+}
@@ -10,7 +10,6 @@ import (
 	"math"
 	"net"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 )
@@ -23,6 +22,12 @@ var ErrGroupClosed = errors.New("consumer group is closed")
 // Generation's Start function when the context has been closed.
 var ErrGenerationEnded = errors.New("consumer group generation has ended")
 
+// ErrPollIntervalExceeded is returned by ConsumerGroup.Next after a Reader
+// left the group because the program did not fetch messages within
+// ReaderConfig.MaxPollInterval.  The group is joined again on the next call to
+// Next.
+var ErrPollIntervalExceeded = errors.New("consumer group member did not fetch messages within the maximum poll interval")
+
 const (
 	// defaultProtocolType holds the default protocol type documented in the
 	// kafka protocol
@@ -89,6 +94,27 @@ type ConsumerGroupConfig struct {
 	// Default: [Range, RoundRobin]
 	GroupBalancers []GroupBalancer
 
+	// GroupAssigner is an optional function called in place of the selected
+	// GroupBalancer when this member is elected leader of the group.  The
+	// selected balancer still determines the protocol and user data of the
+	// members.  The user data sent to members along with their assignments
+	// is nil when the function is used.
+	//
+	// Only used when GroupProtocol is GroupProtocolClassic.
+	GroupAssigner GroupAssigner
+
+	// GroupProtocol is the protocol used by the members of the group to
+	// coordinate partition assignments.
+	//
+	// Default: GroupProtocolClassic
+	GroupProtocol GroupProtocol
+
+	// ServerAssignor is the name of the assignor that the coordinator uses to
+	// compute partition assignments when GroupProtocol is
+	// GroupProtocolConsumer, for example "uniform" or "range".  When empty,
+	// the coordinator uses its default assignor.
+	ServerAssignor string
+
 	// HeartbeatInterval sets the optional frequency at which the reader sends the consumer
 	// group heartbeat update.
 	//
@@ -134,6 +160,37 @@ type ConsumerGroupConfig struct {
 	// Default: -1
 	RetentionTime time.Duration
 
+	// CommitInterval optionally batches the offsets committed with
+	// Generation.CommitOffsets.  When positive, CommitOffsets records the
+	// offsets and returns immediately, and the offsets of all partitions are
+	// committed in a single request once per interval, and when the
+	// generation ends.  When zero, each call to CommitOffsets commits the
+	// offsets synchronously.
+	//
+	// Default: 0
+	CommitInterval time.Duration
+
+	// CommitRetries is the number of times that a failed offset commit is
+	// retried, with an exponential backoff, before giving up.  Retries of the
+	// commit made when the generation ends stop after RebalanceTimeout, when
+	// the coordinator does not accept the commits of the generation anymore.
+	//
+	// Default: 0
+	CommitRetries int
+
+	// OnCommitError is an optional function called when committing offsets
+	// batched by CommitInterval failed after all retries, and the generation
+	// has ended so the offsets cannot be committed anymore.  Failed commits
+	// are otherwise retried on the next interval.
+	OnCommitError func(offsets map[string]map[int]int64, err error)
+
+	// CommitMetadata is an optional string committed with the offsets of the
+	// group, for example to record the host that committed them. The metadata
+	// committed with the offsets of assigned partitions is available in
+	// PartitionAssignment.Metadata.  Generation.CommitOffsetsWithMetadata
+	// commits other metadata with specific offsets.
+	CommitMetadata string
+
 	// StartOffset determines from whence the consumer group should begin
 	// consuming when it finds a partition without a committed offset.  If
 	// non-zero, it must be set to one of FirstOffset or LastOffset.
@@ -149,6 +206,17 @@ type ConsumerGroupConfig struct {
 	// back to using Logger instead.
 	ErrorLogger Logger
 
+	// If not nil, specifies a structured logger used to report internal
+	// changes and errors within the consumer group, with keys and values describing
+	// their context. It takes precedence over Logger and ErrorLogger.
+	StructuredLogger StructuredLogger
+
+	// OnEvent is an optional function called when the state of this member of
+	// the group changes, for example to measure the frequency and duration of
+	// rebalances.  It is called synchronously by the goroutine running the
+	// group, so it must not block.
+	OnEvent func(GroupEvent)
+
 	// Timeout is the network timeout used when communicating with the consumer
 	// group coordinator.  This value should not be too small since errors
 	// communicating with the broker will generally cause a consumer group
@@ -160,9 +228,21 @@ type ConsumerGroupConfig struct {
 	// Default: 5s
 	Timeout time.Duration
 
+	// Clock is an optional clock used to schedule heartbeats, commits,
+	// partition watches, and join group backoffs, so tests can drive
+	// rebalances with a fake clock. See Clock for details.
+	//
+	// Default: SystemClock
+	Clock Clock
+
 	// connect is a function for dialing the coordinator.  This is provided for
 	// unit testing to mock broker connections.
 	connect func(dialer *Dialer, brokers ...string) (coordinator, error)
+
+	// client is used to exchange messages with the coordinator when using
+	// GroupProtocolConsumer.  This is provided for unit testing to mock
+	// broker connections.
+	client *Client
 }
 
 // Validate method validates ConsumerGroupConfig properties and sets relevant
@@ -185,6 +265,10 @@ func (config *ConsumerGroupConfig) Validate() error {
 		config.Dialer = DefaultDialer
 	}
 
+	if config.Clock == nil {
+		config.Clock = SystemClock
+	}
+
 	if len(config.GroupBalancers) == 0 {
 		config.GroupBalancers = []GroupBalancer{
 			RangeGroupBalancer{},
@@ -216,6 +300,14 @@ func (config *ConsumerGroupConfig) Validate() error {
 		config.RetentionTime = defaultRetentionTime
 	}
 
+	if config.CommitInterval < 0 {
+		return fmt.Errorf("CommitInterval out of bounds: %d", config.CommitInterval)
+	}
+
+	if config.CommitRetries < 0 {
+		return fmt.Errorf("CommitRetries out of bounds: %d", config.CommitRetries)
+	}
+
 	if config.HeartbeatInterval < 0 || (config.HeartbeatInterval/time.Millisecond) >= math.MaxInt32 {
 		return fmt.Errorf("HeartbeatInterval out of bounds: %d", config.HeartbeatInterval)
 	}
@@ -236,6 +328,10 @@ func (config *ConsumerGroupConfig) Validate() error {
 		return fmt.Errorf("RetentionTime out of bounds: %d", config.RetentionTime)
 	}
 
+	if len(config.CommitMetadata) > math.MaxInt16 {
+		return fmt.Errorf("CommitMetadata out of bounds: %d bytes", len(config.CommitMetadata))
+	}
+
 	if config.PartitionWatchInterval < 0 || (config.PartitionWatchInterval/time.Millisecond) >= math.MaxInt32 {
 		return fmt.Errorf("PartitionWachInterval out of bounds %d", config.PartitionWatchInterval)
 	}
@@ -252,10 +348,22 @@ func (config *ConsumerGroupConfig) Validate() error {
 		config.Timeout = defaultTimeout
 	}
 
+	switch config.GroupProtocol {
+	case "":
+		config.GroupProtocol = GroupProtocolClassic
+	case GroupProtocolClassic, GroupProtocolConsumer:
+	default:
+		return fmt.Errorf("GroupProtocol is not valid %q", config.GroupProtocol)
+	}
+
 	if config.connect == nil {
 		config.connect = makeConnect(*config)
 	}
 
+	if config.client == nil && config.GroupProtocol == GroupProtocolConsumer {
+		config.client = makeClient(*config)
+	}
+
 	return nil
 }
 
@@ -271,6 +379,10 @@ type PartitionAssignment struct {
 	// is the first time the partition have been assigned to a member of the
 	// group.
 	Offset int64
+
+	// Metadata is the metadata committed with Offset, or an empty string if no
+	// offset was committed.
+	Metadata string
 }
 
 // genCtx adapts the done channel of the generation to a context.Context.  This
@@ -320,6 +432,10 @@ type Generation struct {
 	// assignments are grouped by topic.
 	Assignments map[string][]PartitionAssignment
 
+	// UserData is the data that the group leader sent along with the
+	// assignments of this member, see UserDataGroupBalancer.
+	UserData []byte
+
 	conn coordinator
 
 	// the following fields are used for process accounting to synchronize
@@ -334,6 +450,25 @@ type Generation struct {
 	routines int
 	joined   chan struct{}
 
+	// cause is the error which ended the generation, if any.  It is set by the
+	// heartbeat loop and partition watchers, and protected by lock.
+	cause error
+
+	// pending holds the offsets recorded by CommitOffsets which have not been
+	// committed yet when commits are batched, and flushed is set once the
+	// last batch was committed at the end of the generation.  commitLock
+	// protects both.
+	commitLock      sync.Mutex
+	pending         offsetStash
+	pendingMetadata metadataStash
+	flushed         bool
+	commitInterval time.Duration
+	commitRetries  int
+	commitTimeout  time.Duration
+	onCommitError  func(map[string]map[int]int64, error)
+	commitMetadata string
+
+	clock           Clock
 	retentionMillis int64
 	log             func(func(Logger))
 	logError        func(func(Logger))
@@ -412,14 +547,174 @@ func (g *Generation) Start(fn func(ctx context.Context)) {
 	}()
 }
 
+// Done returns a channel which is closed when the generation ends, either
+// because a function launched by Start exited, End was called, the group
+// rebalanced, the connection to the coordinator was lost, or the group was
+// closed.
+//
+// Applications which fetch messages without Start, for example on their own
+// Conn, must stop consuming the assigned partitions once the channel is closed
+// and call Next to obtain the assignments of the next generation.
+func (g *Generation) Done() <-chan struct{} {
+	return g.done
+}
+
+// End ends the generation, the consumer group rejoins the group and Next
+// returns the next generation once all functions launched by Start have
+// exited.  End does not wait for them so it can be called from one of these
+// functions.  Calling End more than once has no effect.
+func (g *Generation) End() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if !g.closed {
+		close(g.done)
+		g.closed = true
+	}
+}
+
 // CommitOffsets commits the provided topic+partition+offset combos to the
 // consumer group coordinator.  This can be used to reset the consumer to
 // explicit offsets.
+//
+// When the group is configured with a CommitInterval, the offsets are recorded
+// and committed asynchronously, in which case CommitOffsets always returns nil.
 func (g *Generation) CommitOffsets(offsets map[string]map[int]int64) error {
+	return g.commitOffsetsWithMetadata(offsets, nil)
+}
+
+// CommitOffsetsWithMetadata is like CommitOffsets but commits metadata with the
+// offsets instead of the CommitMetadata of the group configuration, for example
+// to record a checkpoint of the processing of the partitions.
+//
+// When commits are batched by CommitInterval, the offset of each partition is
+// committed with the metadata of the call which recorded it.
+func (g *Generation) CommitOffsetsWithMetadata(offsets map[string]map[int]int64, metadata string) error {
+	if len(metadata) > math.MaxInt16 {
+		return fmt.Errorf("commit metadata out of bounds: %d bytes", len(metadata))
+	}
+	m := metadataStash{}
+	m.set(offsets, &metadata)
+	return g.commitOffsetsWithMetadata(offsets, m)
+}
+
+// commitOffsetsWithMetadata commits the offsets, the partitions which have no
+// metadata in the stash are committed with the metadata of the group
+// configuration.
+func (g *Generation) commitOffsetsWithMetadata(offsets map[string]map[int]int64, metadata metadataStash) error {
 	if len(offsets) == 0 {
 		return nil
 	}
 
+	if g.commitInterval > 0 {
+		g.commitLock.Lock()
+		// offsets committed after the last batch, for example by functions
+		// launched with Start when the generation ends, are committed
+		// synchronously.
+		if !g.flushed {
+			if g.pending == nil {
+				g.pending, g.pendingMetadata = offsetStash{}, metadataStash{}
+			}
+			g.pending.set(offsets)
+			for topic, partitions := range offsets {
+				for partition := range partitions {
+					if m, ok := metadata[topic][partition]; ok {
+						g.pendingMetadata.put(topic, partition, &m)
+					} else {
+						g.pendingMetadata.put(topic, partition, nil)
+					}
+				}
+			}
+			g.commitLock.Unlock()
+			return nil
+		}
+		g.commitLock.Unlock()
+	}
+
+	return g.commitOffsetsWithRetry(context.Background(), offsets, metadata)
+}
+
+// commitOffsetsWithRetry commits the offsets, retrying up to the configured
+// number of times.  It gives up early if ctx is cancelled while waiting to
+// retry.
+func (g *Generation) commitOffsetsWithRetry(ctx context.Context, offsets map[string]map[int]int64, metadata metadataStash) error {
+	policy := &RetryPolicy{
+		MaxAttempts: g.commitRetries + 1,
+		MinBackoff:  100 * time.Millisecond,
+		MaxBackoff:  5 * time.Second,
+	}
+	return policy.do(ctx, clockOrDefault(g.clock), nil, nil, func() error {
+		return g.commitOffsets(offsets, metadata)
+	})
+}
+
+// commitLoop commits the offsets recorded by CommitOffsets at the configured
+// interval, and a last time when the generation ends.
+func (g *Generation) commitLoop() {
+	g.Start(func(ctx context.Context) {
+		ticker := clockOrDefault(g.clock).NewTicker(g.commitInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				// the generation ended, retries must not be interrupted by
+				// the context, but are pointless once the coordinator has
+				// completed the rebalance and rejects the commits of this
+				// generation.
+				flushCtx, cancel := withClockTimeout(context.Background(), clockOrDefault(g.clock), g.commitTimeout)
+				offsets, _, err := g.flush(flushCtx, true)
+				cancel()
+				if err != nil && g.onCommitError != nil {
+					g.onCommitError(offsets, err)
+				}
+				return
+
+			case <-ticker.C():
+				if offsets, metadata, err := g.flush(ctx, false); err != nil {
+					g.logError(func(l Logger) {
+						logKV(l, "failed to commit offsets", coordinatorLogArgs(g.conn, "error", err)...)
+					})
+					// retry on the next tick, unless more recent offsets were
+					// recorded in the meantime.
+					g.commitLock.Lock()
+					if g.pending == nil {
+						g.pending, g.pendingMetadata = offsetStash{}, metadataStash{}
+					}
+					for topic, partitions := range offsets {
+						for partition := range partitions {
+							if _, ok := g.pending[topic][partition]; !ok {
+								if m, ok := metadata[topic][partition]; ok {
+									g.pendingMetadata.put(topic, partition, &m)
+								}
+							}
+						}
+					}
+					g.pending.setMissing(offsets)
+					g.commitLock.Unlock()
+				}
+			}
+		}
+	})
+}
+
+// flush commits the pending offsets, and returns them with their metadata and
+// the error if the commit failed.  After the last flush, offsets are not
+// batched anymore.
+func (g *Generation) flush(ctx context.Context, last bool) (offsetStash, metadataStash, error) {
+	g.commitLock.Lock()
+	offsets, metadata := g.pending, g.pendingMetadata
+	g.pending, g.pendingMetadata = nil, nil
+	g.flushed = last
+	g.commitLock.Unlock()
+
+	if len(offsets) == 0 {
+		return nil, nil, nil
+	}
+	return offsets, metadata, g.commitOffsetsWithRetry(ctx, offsets, metadata)
+}
+
+// commitOffsets sends the offsets to the coordinator in a single request.
+func (g *Generation) commitOffsets(offsets map[string]map[int]int64, metadata metadataStash) error {
 	topics := make([]offsetCommitRequestV2Topic, 0, len(offsets))
 	for topic, partitions := range offsets {
 		t := offsetCommitRequestV2Topic{Topic: topic}
@@ -427,6 +722,7 @@ func (g *Generation) CommitOffsets(offsets map[string]map[int]int64) error {
 			t.Partitions = append(t.Partitions, offsetCommitRequestV2Partition{
 				Partition: int32(partition),
 				Offset:    offset,
+				Metadata:  metadata.get(topic, partition, g.commitMetadata),
 			})
 		}
 		topics = append(topics, t)
@@ -441,17 +737,11 @@ func (g *Generation) CommitOffsets(offsets map[string]map[int]int64) error {
 	}
 
 	_, err := g.conn.offsetCommit(request)
+	err = withErrorContext(err, "", -1, g.GroupID)
 	if err == nil {
 		// if logging is enabled, print out the partitions that were committed.
 		g.log(func(l Logger) {
-			var report []string
-			for _, t := range request.Topics {
-				report = append(report, fmt.Sprintf("\ttopic: %s", t.Topic))
-				for _, p := range t.Partitions {
-					report = append(report, fmt.Sprintf("\t\tpartition %d: %d", p.Partition, p.Offset))
-				}
-			}
-			l.Printf("committed offsets for group %s: \n%s", g.GroupID, strings.Join(report, "\n"))
+			logKV(l, "committed offsets", coordinatorLogArgs(g.conn, "offsets", offsets)...)
 		})
 	}
 
@@ -464,26 +754,27 @@ func (g *Generation) CommitOffsets(offsets map[string]map[int]int64) error {
 func (g *Generation) heartbeatLoop(interval time.Duration) {
 	g.Start(func(ctx context.Context) {
 		g.log(func(l Logger) {
-			l.Printf("started heartbeat for group, %v [%v]", g.GroupID, interval)
+			logKV(l, "started heartbeat", "interval", interval)
 		})
 		defer g.log(func(l Logger) {
-			l.Printf("stopped heartbeat for group %s\n", g.GroupID)
+			logKV(l, "stopped heartbeat")
 		})
 
-		ticker := time.NewTicker(interval)
+		ticker := clockOrDefault(g.clock).NewTicker(interval)
 		defer ticker.Stop()
 
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case <-ticker.C:
+			case <-ticker.C():
 				_, err := g.conn.heartbeat(heartbeatRequestV0{
 					GroupID:      g.GroupID,
 					GenerationID: g.ID,
 					MemberID:     g.MemberID,
 				})
 				if err != nil {
+					g.fail(err)
 					return
 				}
 			}
@@ -491,6 +782,22 @@ func (g *Generation) heartbeatLoop(interval time.Duration) {
 	})
 }
 
+// fail records the error which ended the generation.
+func (g *Generation) fail(err error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.cause == nil {
+		g.cause = err
+	}
+}
+
+// err returns the error which ended the generation, if any.
+func (g *Generation) err() error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.cause
+}
+
 // partitionWatcher queries kafka and watches for partition changes, triggering
 // a rebalance if changes are found. Similar to heartbeat it's okay to return on
 // error here as if you are unable to ask a broker for basic metadata you're in
@@ -500,19 +807,19 @@ func (g *Generation) heartbeatLoop(interval time.Duration) {
 func (g *Generation) partitionWatcher(interval time.Duration, topic string) {
 	g.Start(func(ctx context.Context) {
 		g.log(func(l Logger) {
-			l.Printf("started partition watcher for group, %v, topic %v [%v]", g.GroupID, topic, interval)
+			logKV(l, "started partition watcher", "topic", topic, "interval", interval)
 		})
 		defer g.log(func(l Logger) {
-			l.Printf("stopped partition watcher for group, %v, topic %v", g.GroupID, topic)
+			logKV(l, "stopped partition watcher", "topic", topic)
 		})
 
-		ticker := time.NewTicker(interval)
+		ticker := clockOrDefault(g.clock).NewTicker(interval)
 		defer ticker.Stop()
 
 		ops, err := g.conn.readPartitions(topic)
 		if err != nil {
 			g.logError(func(l Logger) {
-				l.Printf("Problem getting partitions during startup, %v\n, Returning and setting up nextGeneration", err)
+				logKV(l, "failed to read partitions during startup, setting up the next generation", coordinatorLogArgs(g.conn, "topic", topic, "error", err)...)
 			})
 			return
 		}
@@ -521,20 +828,20 @@ func (g *Generation) partitionWatcher(interval time.Duration, topic string) {
 			select {
 			case <-ctx.Done():
 				return
-			case <-ticker.C:
+			case <-ticker.C():
 				ops, err := g.conn.readPartitions(topic)
 				switch {
 				case err == nil, errors.Is(err, UnknownTopicOrPartition):
 					if len(ops) != oParts {
 						g.log(func(l Logger) {
-							l.Printf("Partition changes found, reblancing group: %v.", g.GroupID)
+							logKV(l, "partition changes found, rebalancing", "topic", topic)
 						})
 						return
 					}
 
 				default:
 					g.logError(func(l Logger) {
-						l.Printf("Problem getting partitions while checking for changes, %v", err)
+						logKV(l, "failed to read partitions while checking for changes", coordinatorLogArgs(g.conn, "topic", topic, "error", err)...)
 					})
 					var kafkaError Error
 					if errors.As(err, &kafkaError) {
@@ -542,6 +849,7 @@ func (g *Generation) partitionWatcher(interval time.Duration, topic string) {
 					}
 					// other errors imply that we lost the connection to the coordinator, so we
 					// should abort and reconnect.
+					g.fail(err)
 					return
 				}
 			}
@@ -564,6 +872,15 @@ type coordinator interface {
 	readPartitions(...string) ([]Partition, error)
 }
 
+// coordinatorLogArgs returns the keys and values identifying the connection
+// to the coordinator in log messages, followed by kv.
+func coordinatorLogArgs(c coordinator, kv ...interface{}) []interface{} {
+	if t, ok := c.(*timeoutCoordinator); ok {
+		return append(t.conn.logArgs(), kv...)
+	}
+	return kv
+}
+
 // timeoutCoordinator wraps the Conn to ensure that every operation has a
 // deadline.  Otherwise, it would be possible for requests to block indefinitely
 // if the remote server never responds.  There are many spots where the consumer
@@ -654,6 +971,7 @@ func NewConsumerGroup(config ConsumerGroupConfig) (*ConsumerGroup, error) {
 		config: config,
 		next:   make(chan *Generation),
 		errs:   make(chan error),
+		resume: make(chan struct{}),
 		done:   make(chan struct{}),
 	}
 	cg.wg.Add(1)
@@ -669,14 +987,45 @@ func NewConsumerGroup(config ConsumerGroupConfig) (*ConsumerGroup, error) {
 // member enters or exits the group, it results in a new Generation.  The
 // Generation is where partition assignments and offset management occur.
 // Callers will use Next to get a handle to the Generation.
+//
+// The group does not depend on Reader, so it can coordinate applications which
+// fetch messages by other means, like Conn or a custom batch reader.  The
+// lifecycle of a group is:
+//
+//   - NewConsumerGroup starts joining the group in the background.
+//   - Next blocks until this member is assigned partitions in a new generation,
+//     and returns it.  Generation.Assignments holds the offsets to resume
+//     consuming from, and Generation.CommitOffsets commits progress.  The
+//     group heartbeats with the coordinator for the lifetime of the
+//     generation.
+//   - The generation ends when Generation.Done is closed.  Functions launched
+//     with Generation.Start are cancelled, and Next returns the following
+//     generation once they have all exited.
+//   - Close leaves the group and ends the current generation, after which
+//     Next returns ErrGroupClosed.
 type ConsumerGroup struct {
 	config ConsumerGroupConfig
 	next   chan *Generation
 	errs   chan error
 
+	// resume is used by Next to let the group join again after leaving it
+	// because of ErrPollIntervalExceeded.
+	resume chan struct{}
+
 	closeOnce sync.Once
 	wg        sync.WaitGroup
 	done      chan struct{}
+
+	// The assignment received in the previous generation, which is passed to
+	// balancers implementing UserDataGroupBalancer when joining the group.
+	// Only accessed by the goroutine running the group.
+	assignment *GroupMemberAssignment
+
+	// The times at which the current rebalance started and the current
+	// generation became stable, used to compute the duration of events.
+	// Only accessed by the goroutine running the group.
+	rebalanceStart time.Time
+	stableStart    time.Time
 }
 
 // Close terminates the current generation by causing this member to leave and
@@ -699,19 +1048,30 @@ func (cg *ConsumerGroup) Close() error {
 //
 // If the ConsumerGroup has been closed, then Next will return ErrGroupClosed.
 func (cg *ConsumerGroup) Next(ctx context.Context) (*Generation, error) {
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case <-cg.done:
-		return nil, ErrGroupClosed
-	case err := <-cg.errs:
-		return nil, err
-	case next := <-cg.next:
-		return next, nil
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-cg.done:
+			return nil, ErrGroupClosed
+		case err := <-cg.errs:
+			return nil, err
+		case next := <-cg.next:
+			return next, nil
+		case cg.resume <- struct{}{}:
+			// the group had left after ErrPollIntervalExceeded, it now
+			// joins again and Next waits for the new generation.
+		}
 	}
 }
 
 func (cg *ConsumerGroup) run() {
+	if cg.config.GroupProtocol == GroupProtocolConsumer {
+		if fallback := cg.runConsumerProtocol(); !fallback {
+			return
+		}
+	}
+
 	// the memberID is the only piece of information that is maintained across
 	// generations.  it starts empty and will be assigned on the first nextGeneration
 	// when the joinGroup request is processed.  it may change again later if
@@ -727,6 +1087,10 @@ func (cg *ConsumerGroup) run() {
 		// joining or syncing the group.
 		var backoff <-chan time.Time
 
+		// paused will be set if this go routine should wait for the next call
+		// to Next before joining the group again.
+		var paused bool
+
 		switch {
 		case err == nil:
 			// no error...the previous generation finished normally.
@@ -734,9 +1098,22 @@ func (cg *ConsumerGroup) run() {
 
 		case errors.Is(err, ErrGroupClosed):
 			// the CG has been closed...leave the group and exit loop.
+			if memberID != "" {
+				cg.emit(GroupEvent{Type: GroupLeaving, MemberID: memberID})
+			}
 			_ = cg.leaveGroup(memberID)
 			return
 
+		case errors.Is(err, ErrPollIntervalExceeded):
+			// the member stopped consuming messages, leave the group so its
+			// partitions are reassigned to other members, and only join
+			// again once the program is ready to consume.
+			cg.emit(GroupEvent{Type: GroupLeaving, MemberID: memberID, Err: err})
+			_ = cg.leaveGroup(memberID)
+			memberID = ""
+			cg.assignment = nil
+			paused = true
+
 		case errors.Is(err, RebalanceInProgress):
 			// in case of a RebalanceInProgress, don't leave the group or
 			// change the member ID, but report the error.  the next attempt
@@ -750,16 +1127,28 @@ func (cg *ConsumerGroup) run() {
 			// so we don't attempt to use it again.  in order to avoid
 			// a tight error loop, backoff before the next attempt to join
 			// the group.
+			if memberID != "" {
+				cg.emit(GroupEvent{Type: GroupLeaving, MemberID: memberID, Err: err})
+			}
 			_ = cg.leaveGroup(memberID)
 			memberID = ""
-			backoff = time.After(cg.config.JoinGroupBackoff)
+			cg.assignment = nil
+			backoff = cg.clock().NewTimer(cg.config.JoinGroupBackoff).C()
 		}
 		// ensure that we exit cleanly in case the CG is done and no one is
 		// waiting to receive on the unbuffered error channel.
 		select {
 		case <-cg.done:
 			return
-		case cg.errs <- err:
+		case cg.errs <- withErrorContext(err, "", -1, cg.config.ID):
+		}
+		// wait for Next to be called again if the member left the group.
+		if paused {
+			select {
+			case <-cg.done:
+				return
+			case <-cg.resume:
+			}
 		}
 		// backoff if needed, being sure to exit cleanly if the CG is done.
 		if backoff != nil {
@@ -774,6 +1163,8 @@ func (cg *ConsumerGroup) run() {
 }
 
 func (cg *ConsumerGroup) nextGeneration(memberID string) (string, error) {
+	cg.emit(GroupEvent{Type: GroupJoining, MemberID: memberID})
+
 	// get a new connection to the coordinator on each loop.  the previous
 	// generation could have exited due to losing the connection, so this
 	// ensures that we always have a clean starting point.  it means we will
@@ -783,7 +1174,7 @@ func (cg *ConsumerGroup) nextGeneration(memberID string) (string, error) {
 	conn, err := cg.coordinator()
 	if err != nil {
 		cg.withErrorLogger(func(log Logger) {
-			log.Printf("Unable to establish connection to consumer group coordinator for group %s: %v", cg.config.ID, err)
+			logKV(log, "unable to establish connection to the consumer group coordinator", "error", err)
 		})
 		return memberID, err // a prior memberID may still be valid, so don't return ""
 	}
@@ -791,36 +1182,44 @@ func (cg *ConsumerGroup) nextGeneration(memberID string) (string, error) {
 
 	var generationID int32
 	var groupAssignments GroupMemberAssignments
+	var groupUserData map[string][]byte
 	var assignments map[string][]int32
+	var userData []byte
 
 	// join group.  this will join the group and prepare assignments if our
 	// consumer is elected leader.  it may also change or assign the member ID.
-	memberID, generationID, groupAssignments, err = cg.joinGroup(conn, memberID)
+	previousMemberID := memberID
+	memberID, generationID, groupAssignments, groupUserData, err = cg.joinGroup(conn, memberID)
 	if err != nil {
 		cg.withErrorLogger(func(log Logger) {
-			log.Printf("Failed to join group %s: %v", cg.config.ID, err)
+			logKV(log, "failed to join group", coordinatorLogArgs(conn, "error", err)...)
 		})
+		if isFenced(err) {
+			cg.emit(GroupEvent{Type: GroupFenced, MemberID: previousMemberID, Err: err})
+		}
 		return memberID, err
 	}
 	cg.withLogger(func(log Logger) {
-		log.Printf("Joined group %s as member %s in generation %d", cg.config.ID, memberID, generationID)
+		logKV(log, "joined group", "member", memberID, "generation", generationID)
 	})
+	cg.emit(GroupEvent{Type: GroupSyncing, MemberID: memberID, GenerationID: generationID})
 
 	// sync group
-	assignments, err = cg.syncGroup(conn, memberID, generationID, groupAssignments)
+	assignments, userData, err = cg.syncGroup(conn, memberID, generationID, groupAssignments, groupUserData)
 	if err != nil {
 		cg.withErrorLogger(func(log Logger) {
-			log.Printf("Failed to sync group %s: %v", cg.config.ID, err)
+			logKV(log, "failed to sync group", coordinatorLogArgs(conn, "member", memberID, "generation", generationID, "error", err)...)
 		})
 		return memberID, err
 	}
+	cg.assignment = makeGroupMemberAssignment(generationID, assignments, userData)
 
 	// fetch initial offsets.
-	var offsets map[string]map[int]int64
+	var offsets map[string]map[int]committedOffset
 	offsets, err = cg.fetchOffsets(conn, assignments)
 	if err != nil {
 		cg.withErrorLogger(func(log Logger) {
-			log.Printf("Failed to fetch offsets for group %s: %v", cg.config.ID, err)
+			logKV(log, "failed to fetch offsets", coordinatorLogArgs(conn, "member", memberID, "generation", generationID, "error", err)...)
 		})
 		return memberID, err
 	}
@@ -831,9 +1230,16 @@ func (cg *ConsumerGroup) nextGeneration(memberID string) (string, error) {
 		GroupID:         cg.config.ID,
 		MemberID:        memberID,
 		Assignments:     cg.makeAssignments(assignments, offsets),
+		UserData:        userData,
 		conn:            conn,
 		done:            make(chan struct{}),
 		joined:          make(chan struct{}),
+		commitInterval:  cg.config.CommitInterval,
+		commitRetries:   cg.config.CommitRetries,
+		commitTimeout:   cg.config.RebalanceTimeout,
+		onCommitError:   cg.config.OnCommitError,
+		commitMetadata:  cg.config.CommitMetadata,
+		clock:           cg.clock(),
 		retentionMillis: int64(cg.config.RetentionTime / time.Millisecond),
 		log:             cg.withLogger,
 		logError:        cg.withErrorLogger,
@@ -843,12 +1249,17 @@ func (cg *ConsumerGroup) nextGeneration(memberID string) (string, error) {
 	// any of these functions exit, then the generation is determined to be
 	// complete.
 	gen.heartbeatLoop(cg.config.HeartbeatInterval)
+	if cg.config.CommitInterval > 0 {
+		gen.commitLoop()
+	}
 	if cg.config.WatchPartitionChanges {
 		for _, topic := range cg.config.Topics {
 			gen.partitionWatcher(cg.config.PartitionWatchInterval, topic)
 		}
 	}
 
+	cg.emit(GroupEvent{Type: GroupStable, MemberID: memberID, GenerationID: generationID})
+
 	// make this generation available for retrieval.  if the CG is closed before
 	// we can send it on the channel, exit.  that case is required b/c the next
 	// channel is unbuffered.  if the caller to Next has already bailed because
@@ -870,6 +1281,10 @@ func (cg *ConsumerGroup) nextGeneration(memberID string) (string, error) {
 		// time for next generation!  make sure all the current go routines exit
 		// before continuing onward.
 		gen.close()
+		cg.emit(GroupEvent{Type: GroupRebalancing, MemberID: memberID, GenerationID: generationID, Err: gen.err()})
+		if err := gen.err(); errors.Is(err, ErrPollIntervalExceeded) {
+			return memberID, err
+		}
 		return memberID, nil
 	}
 }
@@ -931,10 +1346,10 @@ func (cg *ConsumerGroup) coordinator() (coordinator, error) {
 //  * InconsistentGroupProtocol:
 //  * InvalidSessionTimeout:
 //  * GroupAuthorizationFailed:
-func (cg *ConsumerGroup) joinGroup(conn coordinator, memberID string) (string, int32, GroupMemberAssignments, error) {
+func (cg *ConsumerGroup) joinGroup(conn coordinator, memberID string) (string, int32, GroupMemberAssignments, map[string][]byte, error) {
 	request, err := cg.makeJoinGroupRequestV1(memberID)
 	if err != nil {
-		return "", 0, nil, err
+		return "", 0, nil, nil, err
 	}
 
 	response, err := conn.joinGroup(request)
@@ -942,38 +1357,35 @@ func (cg *ConsumerGroup) joinGroup(conn coordinator, memberID string) (string, i
 		err = Error(response.ErrorCode)
 	}
 	if err != nil {
-		return "", 0, nil, err
+		return "", 0, nil, nil, err
 	}
 
 	memberID = response.MemberID
 	generationID := response.GenerationID
 
 	cg.withLogger(func(l Logger) {
-		l.Printf("joined group %s as member %s in generation %d", cg.config.ID, memberID, generationID)
+		logKV(l, "join group response received", coordinatorLogArgs(conn, "member", memberID, "generation", generationID, "leader", response.LeaderID)...)
 	})
 
 	var assignments GroupMemberAssignments
+	var userData map[string][]byte
 	if iAmLeader := response.MemberID == response.LeaderID; iAmLeader {
-		v, err := cg.assignTopicPartitions(conn, response)
+		v, u, err := cg.assignTopicPartitions(conn, response)
 		if err != nil {
-			return memberID, 0, nil, err
+			return memberID, 0, nil, nil, err
 		}
-		assignments = v
+		assignments, userData = v, u
 
 		cg.withLogger(func(l Logger) {
 			for memberID, assignment := range assignments {
 				for topic, partitions := range assignment {
-					l.Printf("assigned member/topic/partitions %v/%v/%v", memberID, topic, partitions)
+					logKV(l, "assigned partitions", "member", memberID, "topic", topic, "partitions", partitions)
 				}
 			}
 		})
 	}
 
-	cg.withLogger(func(l Logger) {
-		l.Printf("joinGroup succeeded for response, %v.  generationID=%v, memberID=%v", cg.config.ID, response.GenerationID, response.MemberID)
-	})
-
-	return memberID, generationID, assignments, nil
+	return memberID, generationID, assignments, userData, nil
 }
 
 // makeJoinGroupRequestV1 handles the logic of constructing a joinGroup
@@ -988,7 +1400,13 @@ func (cg *ConsumerGroup) makeJoinGroupRequestV1(memberID string) (joinGroupReque
 	}
 
 	for _, balancer := range cg.config.GroupBalancers {
-		userData, err := balancer.UserData()
+		var userData []byte
+		var err error
+		if b, ok := balancer.(UserDataGroupBalancer); ok {
+			userData, err = b.MemberUserData(cg.assignment)
+		} else {
+			userData, err = balancer.UserData()
+		}
 		if err != nil {
 			return joinGroupRequestV1{}, fmt.Errorf("unable to construct protocol metadata for member, %v: %w", balancer.ProtocolName(), err)
 		}
@@ -1005,11 +1423,12 @@ func (cg *ConsumerGroup) makeJoinGroupRequestV1(memberID string) (joinGroupReque
 	return request, nil
 }
 
-// assignTopicPartitions uses the selected GroupBalancer to assign members to
-// their various partitions.
-func (cg *ConsumerGroup) assignTopicPartitions(conn coordinator, group joinGroupResponseV1) (GroupMemberAssignments, error) {
+// assignTopicPartitions uses the GroupAssigner, or the selected GroupBalancer
+// to assign members to their various partitions. The user data sent to members is nil unless the
+// balancer implements UserDataGroupBalancer.
+func (cg *ConsumerGroup) assignTopicPartitions(conn coordinator, group joinGroupResponseV1) (GroupMemberAssignments, map[string][]byte, error) {
 	cg.withLogger(func(l Logger) {
-		l.Printf("selected as leader for group, %s\n", cg.config.ID)
+		logKV(l, "selected as leader", "generation", group.GenerationID)
 	})
 
 	balancer, ok := findGroupBalancer(group.GroupProtocol, cg.config.GroupBalancers)
@@ -1017,12 +1436,12 @@ func (cg *ConsumerGroup) assignTopicPartitions(conn coordinator, group joinGroup
 		// NOTE : this shouldn't happen in practice...the broker should not
 		//        return successfully from joinGroup unless all members support
 		//        at least one common protocol.
-		return nil, fmt.Errorf("unable to find selected balancer, %v, for group, %v", group.GroupProtocol, cg.config.ID)
+		return nil, nil, fmt.Errorf("unable to find selected balancer, %v, for group, %v", group.GroupProtocol, cg.config.ID)
 	}
 
 	members, err := cg.makeMemberProtocolMetadata(group.Members)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	topics := extractTopics(members)
@@ -1033,20 +1452,53 @@ func (cg *ConsumerGroup) assignTopicPartitions(conn coordinator, group joinGroup
 	// clients: java, python, and librdkafka.
 	// a topic watcher can trigger a rebalance when the topic comes into being.
 	if err != nil && !errors.Is(err, UnknownTopicOrPartition) {
-		return nil, err
+		return nil, nil, err
 	}
 
 	cg.withLogger(func(l Logger) {
-		l.Printf("using '%v' balancer to assign group, %v", group.GroupProtocol, cg.config.ID)
+		logKV(l, "assigning partitions", "protocol", group.GroupProtocol)
 		for _, member := range members {
-			l.Printf("found member: %v/%#v", member.ID, member.UserData)
+			logKV(l, "found member", "member", member.ID, "user_data", member.UserData)
 		}
 		for _, partition := range partitions {
-			l.Printf("found topic/partition: %v/%v", partition.Topic, partition.ID)
+			logKV(l, "found partition", "topic", partition.Topic, "partition", partition.ID)
 		}
 	})
 
-	return balancer.AssignGroups(members, partitions), nil
+	if cg.config.GroupAssigner != nil {
+		assignments, err := cg.config.GroupAssigner(GroupAssignmentRequest{
+			GroupID:      cg.config.ID,
+			GenerationID: group.GenerationID,
+			LeaderID:     group.LeaderID,
+			Protocol:     group.GroupProtocol,
+			Members:      members,
+			Partitions:   partitions,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to assign partitions of group %v: %w", cg.config.ID, err)
+		}
+		for memberID := range assignments {
+			if !hasGroupMember(members, memberID) {
+				return nil, nil, fmt.Errorf("partitions of group %v assigned to unknown member %v", cg.config.ID, memberID)
+			}
+		}
+		return assignments, nil, nil
+	}
+
+	if b, ok := balancer.(UserDataGroupBalancer); ok {
+		assignments, userData := b.AssignGroupsWithUserData(members, partitions)
+		return assignments, userData, nil
+	}
+	return balancer.AssignGroups(members, partitions), nil, nil
+}
+
+func hasGroupMember(members []GroupMember, memberID string) bool {
+	for _, member := range members {
+		if member.ID == memberID {
+			return true
+		}
+	}
+	return false
 }
 
 // makeMemberProtocolMetadata maps encoded member metadata ([]byte) into []GroupMember.
@@ -1078,36 +1530,36 @@ func (cg *ConsumerGroup) makeMemberProtocolMetadata(in []joinGroupResponseMember
 //  * IllegalGeneration:
 //  * RebalanceInProgress:
 //  * GroupAuthorizationFailed:
-func (cg *ConsumerGroup) syncGroup(conn coordinator, memberID string, generationID int32, memberAssignments GroupMemberAssignments) (map[string][]int32, error) {
-	request := cg.makeSyncGroupRequestV0(memberID, generationID, memberAssignments)
+func (cg *ConsumerGroup) syncGroup(conn coordinator, memberID string, generationID int32, memberAssignments GroupMemberAssignments, memberUserData map[string][]byte) (map[string][]int32, []byte, error) {
+	request := cg.makeSyncGroupRequestV0(memberID, generationID, memberAssignments, memberUserData)
 	response, err := conn.syncGroup(request)
 	if err == nil && response.ErrorCode != 0 {
 		err = Error(response.ErrorCode)
 	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	assignments := groupAssignment{}
 	reader := bufio.NewReader(bytes.NewReader(response.MemberAssignments))
 	if _, err := (&assignments).readFrom(reader, len(response.MemberAssignments)); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if len(assignments.Topics) == 0 {
 		cg.withLogger(func(l Logger) {
-			l.Printf("received empty assignments for group, %v as member %s for generation %d", cg.config.ID, memberID, generationID)
+			logKV(l, "received empty assignments", "member", memberID, "generation", generationID)
 		})
 	}
 
 	cg.withLogger(func(l Logger) {
-		l.Printf("sync group finished for group, %v", cg.config.ID)
+		logKV(l, "sync group finished", coordinatorLogArgs(conn, "member", memberID, "generation", generationID)...)
 	})
 
-	return assignments.Topics, nil
+	return assignments.Topics, assignments.UserData, nil
 }
 
-func (cg *ConsumerGroup) makeSyncGroupRequestV0(memberID string, generationID int32, memberAssignments GroupMemberAssignments) syncGroupRequestV0 {
+func (cg *ConsumerGroup) makeSyncGroupRequestV0(memberID string, generationID int32, memberAssignments GroupMemberAssignments, memberUserData map[string][]byte) syncGroupRequestV0 {
 	request := syncGroupRequestV0{
 		GroupID:      cg.config.ID,
 		GenerationID: generationID,
@@ -1129,21 +1581,46 @@ func (cg *ConsumerGroup) makeSyncGroupRequestV0(memberID string, generationID in
 			request.GroupAssignments = append(request.GroupAssignments, syncGroupRequestGroupAssignmentV0{
 				MemberID: memberID,
 				MemberAssignments: groupAssignment{
-					Version: 1,
-					Topics:  topics32,
+					Version:  1,
+					Topics:   topics32,
+					UserData: memberUserData[memberID],
 				}.bytes(),
 			})
 		}
 
 		cg.withLogger(func(logger Logger) {
-			logger.Printf("Syncing %d assignments for generation %d as member %s", len(request.GroupAssignments), generationID, memberID)
+			logKV(logger, "syncing assignments", "assignments", len(request.GroupAssignments), "member", memberID, "generation", generationID)
 		})
 	}
 
 	return request
 }
 
-func (cg *ConsumerGroup) fetchOffsets(conn coordinator, subs map[string][]int32) (map[string]map[int]int64, error) {
+// makeGroupMemberAssignment returns the assignment of the member in the
+// generation, which is passed to the balancers when joining the next one.
+func makeGroupMemberAssignment(generationID int32, assignments map[string][]int32, userData []byte) *GroupMemberAssignment {
+	topics := make(map[string][]int, len(assignments))
+	for topic, partitions := range assignments {
+		ids := make([]int, len(partitions))
+		for i, p := range partitions {
+			ids[i] = int(p)
+		}
+		topics[topic] = ids
+	}
+	return &GroupMemberAssignment{
+		GenerationID: generationID,
+		Topics:       topics,
+		UserData:     userData,
+	}
+}
+
+// committedOffset is an offset committed by the group, and its metadata.
+type committedOffset struct {
+	offset   int64
+	metadata string
+}
+
+func (cg *ConsumerGroup) fetchOffsets(conn coordinator, subs map[string][]int32) (map[string]map[int]committedOffset, error) {
 	req := offsetFetchRequestV1{
 		GroupID: cg.config.ID,
 		Topics:  make([]offsetFetchRequestV1Topic, 0, len(cg.config.Topics)),
@@ -1159,16 +1636,16 @@ func (cg *ConsumerGroup) fetchOffsets(conn coordinator, subs map[string][]int32)
 		return nil, err
 	}
 
-	offsetsByTopic := make(map[string]map[int]int64)
+	offsetsByTopic := make(map[string]map[int]committedOffset)
 	for _, res := range offsets.Responses {
-		offsetsByPartition := map[int]int64{}
+		offsetsByPartition := map[int]committedOffset{}
 		offsetsByTopic[res.Topic] = offsetsByPartition
 		for _, pr := range res.PartitionResponses {
 			for _, partition := range subs[res.Topic] {
 				if partition == pr.Partition {
-					offset := pr.Offset
-					if offset < 0 {
-						offset = cg.config.StartOffset
+					offset := committedOffset{offset: pr.Offset, metadata: pr.Metadata}
+					if offset.offset < 0 {
+						offset = committedOffset{offset: cg.config.StartOffset}
 					}
 					offsetsByPartition[int(partition)] = offset
 				}
@@ -1179,23 +1656,24 @@ func (cg *ConsumerGroup) fetchOffsets(conn coordinator, subs map[string][]int32)
 	return offsetsByTopic, nil
 }
 
-func (cg *ConsumerGroup) makeAssignments(assignments map[string][]int32, offsets map[string]map[int]int64) map[string][]PartitionAssignment {
+func (cg *ConsumerGroup) makeAssignments(assignments map[string][]int32, offsets map[string]map[int]committedOffset) map[string][]PartitionAssignment {
 	topicAssignments := make(map[string][]PartitionAssignment)
 	for _, topic := range cg.config.Topics {
 		topicPartitions := assignments[topic]
 		topicAssignments[topic] = make([]PartitionAssignment, 0, len(topicPartitions))
 		for _, partition := range topicPartitions {
-			var offset int64
+			var offset committedOffset
 			partitionOffsets, ok := offsets[topic]
 			if ok {
 				offset, ok = partitionOffsets[int(partition)]
 			}
 			if !ok {
-				offset = cg.config.StartOffset
+				offset = committedOffset{offset: cg.config.StartOffset}
 			}
 			topicAssignments[topic] = append(topicAssignments[topic], PartitionAssignment{
-				ID:     int(partition),
-				Offset: offset,
+				ID:       int(partition),
+				Offset:   offset.offset,
+				Metadata: offset.metadata,
 			})
 		}
 	}
@@ -1209,7 +1687,7 @@ func (cg *ConsumerGroup) leaveGroup(memberID string) error {
 	}
 
 	cg.withLogger(func(log Logger) {
-		log.Printf("Leaving group %s, member %s", cg.config.ID, memberID)
+		logKV(log, "leaving group", "member", memberID)
 	})
 
 	// IMPORTANT : leaveGroup establishes its own connection to the coordinator
@@ -1228,7 +1706,7 @@ func (cg *ConsumerGroup) leaveGroup(memberID string) error {
 	})
 	if err != nil {
 		cg.withErrorLogger(func(log Logger) {
-			log.Printf("leave group failed for group, %v, and member, %v: %v", cg.config.ID, memberID, err)
+			logKV(log, "failed to leave group", coordinatorLogArgs(coordinator, "member", memberID, "error", err)...)
 		})
 	}
 
@@ -1237,15 +1715,17 @@ func (cg *ConsumerGroup) leaveGroup(memberID string) error {
 	return err
 }
 
+func (cg *ConsumerGroup) clock() Clock { return clockOrDefault(cg.config.Clock) }
+
 func (cg *ConsumerGroup) withLogger(do func(Logger)) {
-	if cg.config.Logger != nil {
-		do(cg.config.Logger)
+	if l := newLogger(cg.config.StructuredLogger, cg.config.Logger, LogLevelInfo, "group", cg.config.ID); l != nil {
+		do(l)
 	}
 }
 
 func (cg *ConsumerGroup) withErrorLogger(do func(Logger)) {
-	if cg.config.ErrorLogger != nil {
-		do(cg.config.ErrorLogger)
+	if l := newLogger(cg.config.StructuredLogger, cg.config.ErrorLogger, LogLevelError, "group", cg.config.ID); l != nil {
+		do(l)
 	} else {
 		cg.withLogger(do)
 	}
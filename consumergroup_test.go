@@ -3,6 +3,7 @@ package kafka
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"strings"
 	"sync"
@@ -104,6 +105,10 @@ func TestValidateConsumerGroupConfig(t *testing.T) {
 		{config: ConsumerGroupConfig{Brokers: []string{"broker1"}, Topics: []string{"t1"}, ID: "group1", HeartbeatInterval: 2, SessionTimeout: 2, RebalanceTimeout: 2, RetentionTime: 1, PartitionWatchInterval: -1}, errorOccured: true},
 		{config: ConsumerGroupConfig{Brokers: []string{"broker1"}, Topics: []string{"t1"}, ID: "group1", HeartbeatInterval: 2, SessionTimeout: 2, RebalanceTimeout: 2, RetentionTime: 1, PartitionWatchInterval: 1, JoinGroupBackoff: -1}, errorOccured: true},
 		{config: ConsumerGroupConfig{Brokers: []string{"broker1"}, Topics: []string{"t1"}, ID: "group1", HeartbeatInterval: 2, SessionTimeout: 2, RebalanceTimeout: 2, RetentionTime: 1, PartitionWatchInterval: 1, JoinGroupBackoff: 1}, errorOccured: false},
+		{config: ConsumerGroupConfig{Brokers: []string{"broker1"}, Topics: []string{"t1"}, ID: "group1", GroupProtocol: "cooperative"}, errorOccured: true},
+		{config: ConsumerGroupConfig{Brokers: []string{"broker1"}, Topics: []string{"t1"}, ID: "group1", CommitInterval: -1}, errorOccured: true},
+		{config: ConsumerGroupConfig{Brokers: []string{"broker1"}, Topics: []string{"t1"}, ID: "group1", CommitRetries: -1}, errorOccured: true},
+		{config: ConsumerGroupConfig{Brokers: []string{"broker1"}, Topics: []string{"t1"}, ID: "group1", GroupProtocol: GroupProtocolConsumer}, errorOccured: false},
 	}
 	for _, test := range tests {
 		err := test.config.Validate()
@@ -223,7 +228,7 @@ func TestReaderAssignTopicPartitions(t *testing.T) {
 				RangeGroupBalancer{},
 				RoundRobinGroupBalancer{},
 			}
-			assignments, err := cg.assignTopicPartitions(conn, tc.Members)
+			assignments, _, err := cg.assignTopicPartitions(conn, tc.Members)
 			if err != nil {
 				t.Fatalf("bad err: %v", err)
 			}
@@ -234,6 +239,91 @@ func TestReaderAssignTopicPartitions(t *testing.T) {
 	}
 }
 
+func TestConsumerGroupAssigner(t *testing.T) {
+	leader := Broker{ID: 1, Host: "broker-1", Port: 9092, Rack: "rack-a"}
+	partitions := []Partition{
+		{Topic: "topic-1", ID: 0, Leader: leader, Replicas: []Broker{leader}},
+		{Topic: "topic-1", ID: 1, Leader: leader, Replicas: []Broker{leader}},
+	}
+	conn := &mockCoordinator{
+		readPartitionsFunc: func(...string) ([]Partition, error) {
+			return partitions, nil
+		},
+	}
+
+	group := joinGroupResponseV1{
+		GenerationID:  3,
+		GroupProtocol: RangeGroupBalancer{}.ProtocolName(),
+		LeaderID:      "member-1",
+		MemberID:      "member-1",
+		Members: []joinGroupResponseMemberV1{
+			{MemberID: "member-1", MemberMetadata: groupMetadata{Topics: []string{"topic-1"}, UserData: []byte("big")}.bytes()},
+			{MemberID: "member-2", MemberMetadata: groupMetadata{Topics: []string{"topic-1"}, UserData: []byte("small")}.bytes()},
+		},
+	}
+
+	t.Run("assigns partitions", func(t *testing.T) {
+		var request GroupAssignmentRequest
+		want := GroupMemberAssignments{"member-1": {"topic-1": {0, 1}}}
+
+		cg := ConsumerGroup{}
+		cg.config.ID = "group-1"
+		cg.config.GroupBalancers = []GroupBalancer{RangeGroupBalancer{}}
+		cg.config.GroupAssigner = func(r GroupAssignmentRequest) (GroupMemberAssignments, error) {
+			request = r
+			return want, nil
+		}
+
+		assignments, userData, err := cg.assignTopicPartitions(conn, group)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(assignments, want) {
+			t.Errorf("expected %v; got %v", want, assignments)
+		}
+		if userData != nil {
+			t.Errorf("expected no user data; got %v", userData)
+		}
+
+		expected := GroupAssignmentRequest{
+			GroupID:      "group-1",
+			GenerationID: 3,
+			LeaderID:     "member-1",
+			Protocol:     "range",
+			Members: []GroupMember{
+				{ID: "member-1", Topics: []string{"topic-1"}, UserData: []byte("big")},
+				{ID: "member-2", Topics: []string{"topic-1"}, UserData: []byte("small")},
+			},
+			Partitions: partitions,
+		}
+		if !reflect.DeepEqual(request, expected) {
+			t.Errorf("wrong request passed to the assigner:\nwant: %+v\ngot:  %+v", expected, request)
+		}
+	})
+
+	t.Run("fails on errors", func(t *testing.T) {
+		cg := ConsumerGroup{}
+		cg.config.GroupBalancers = []GroupBalancer{RangeGroupBalancer{}}
+		cg.config.GroupAssigner = func(GroupAssignmentRequest) (GroupMemberAssignments, error) {
+			return nil, errors.New("no capacity")
+		}
+		if _, _, err := cg.assignTopicPartitions(conn, group); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("fails on unknown members", func(t *testing.T) {
+		cg := ConsumerGroup{}
+		cg.config.GroupBalancers = []GroupBalancer{RangeGroupBalancer{}}
+		cg.config.GroupAssigner = func(GroupAssignmentRequest) (GroupMemberAssignments, error) {
+			return GroupMemberAssignments{"member-3": {"topic-1": {0}}}, nil
+		}
+		if _, _, err := cg.assignTopicPartitions(conn, group); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
 func TestConsumerGroup(t *testing.T) {
 	tests := []struct {
 		scenario string
@@ -677,3 +767,545 @@ func TestGenerationStartsFunctionAfterClosed(t *testing.T) {
 		}
 	}
 }
+
+// newGroupCoordinator returns a mock coordinator of a group which the consumer
+// is the only member and leader of. Each join starts a new generation.
+func newGroupCoordinator(partitions []Partition) mockCoordinator {
+	var mutex sync.Mutex
+	var generationID int32
+
+	return mockCoordinator{
+		findCoordinatorFunc: func(findCoordinatorRequestV0) (findCoordinatorResponseV0, error) {
+			return findCoordinatorResponseV0{
+				Coordinator: findCoordinatorResponseCoordinatorV0{NodeID: 1, Host: "foo.bar.com", Port: 12345},
+			}, nil
+		},
+		joinGroupFunc: func(req joinGroupRequestV1) (joinGroupResponseV1, error) {
+			mutex.Lock()
+			generationID++
+			id := generationID
+			mutex.Unlock()
+			return joinGroupResponseV1{
+				GenerationID:  id,
+				GroupProtocol: req.GroupProtocols[0].ProtocolName,
+				LeaderID:      "member-1",
+				MemberID:      "member-1",
+				Members: []joinGroupResponseMemberV1{
+					{MemberID: "member-1", MemberMetadata: req.GroupProtocols[0].ProtocolMetadata},
+				},
+			}, nil
+		},
+		syncGroupFunc: func(req syncGroupRequestV0) (syncGroupResponseV0, error) {
+			return syncGroupResponseV0{MemberAssignments: req.GroupAssignments[0].MemberAssignments}, nil
+		},
+		heartbeatFunc: func(heartbeatRequestV0) (heartbeatResponseV0, error) {
+			return heartbeatResponseV0{}, nil
+		},
+		leaveGroupFunc: func(leaveGroupRequestV0) (leaveGroupResponseV0, error) {
+			return leaveGroupResponseV0{}, nil
+		},
+		offsetFetchFunc: func(offsetFetchRequestV1) (offsetFetchResponseV1, error) {
+			return offsetFetchResponseV1{}, nil
+		},
+		offsetCommitFunc: func(offsetCommitRequestV2) (offsetCommitResponseV2, error) {
+			return offsetCommitResponseV2{}, nil
+		},
+		readPartitionsFunc: func(...string) ([]Partition, error) {
+			return partitions, nil
+		},
+	}
+}
+
+// userDataBalancer is a balancer which records the previous assignments that
+// it receives, and sends the generation count in its user data.
+type userDataBalancer struct {
+	RangeGroupBalancer
+	mutex    sync.Mutex
+	previous []*GroupMemberAssignment
+}
+
+func (b *userDataBalancer) MemberUserData(previous *GroupMemberAssignment) ([]byte, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.previous = append(b.previous, previous)
+	if previous == nil {
+		return []byte("first"), nil
+	}
+	return []byte("after-" + string(previous.UserData)), nil
+}
+
+func (b *userDataBalancer) AssignGroupsWithUserData(members []GroupMember, partitions []Partition) (GroupMemberAssignments, map[string][]byte) {
+	userData := make(map[string][]byte, len(members))
+	for _, m := range members {
+		userData[m.ID] = append([]byte("assigned-"), m.UserData...)
+	}
+	return b.AssignGroups(members, partitions), userData
+}
+
+func TestConsumerGroupUserDataBalancer(t *testing.T) {
+	balancer := &userDataBalancer{}
+
+	group, err := NewConsumerGroup(ConsumerGroupConfig{
+		ID:                makeGroupID(),
+		Topics:            []string{"topic-1"},
+		Brokers:           []string{"no-such-broker"},
+		GroupBalancers:    []GroupBalancer{balancer},
+		HeartbeatInterval: 2 * time.Second,
+		RebalanceTimeout:  time.Second,
+		RetentionTime:     time.Hour,
+		connect: func(*Dialer, ...string) (coordinator, error) {
+			return newGroupCoordinator([]Partition{{Topic: "topic-1", ID: 0}}), nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer group.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	gen1, err := group.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(gen1.UserData) != "assigned-first" {
+		t.Errorf("wrong user data in generation 1: %q", gen1.UserData)
+	}
+	gen1.Start(func(context.Context) {}) // end the generation
+
+	gen2, err := group.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(gen2.UserData) != "assigned-after-assigned-first" {
+		t.Errorf("wrong user data in generation 2: %q", gen2.UserData)
+	}
+
+	balancer.mutex.Lock()
+	defer balancer.mutex.Unlock()
+
+	want := []*GroupMemberAssignment{
+		nil,
+		{
+			GenerationID: gen1.ID,
+			Topics:       map[string][]int{"topic-1": {0}},
+			UserData:     []byte("assigned-first"),
+		},
+	}
+	if !reflect.DeepEqual(balancer.previous, want) {
+		t.Errorf("wrong previous assignments passed to the balancer:\nwant: %+v\ngot:  %+v", want, balancer.previous)
+	}
+}
+
+func TestGenerationEnd(t *testing.T) {
+	conn := newGroupCoordinator([]Partition{{Topic: "topic-1", ID: 0}})
+	group, err := NewConsumerGroup(ConsumerGroupConfig{
+		ID:                makeGroupID(),
+		Topics:            []string{"topic-1"},
+		Brokers:           []string{"no-such-broker"},
+		HeartbeatInterval: 2 * time.Second,
+		RebalanceTimeout:  time.Second,
+		RetentionTime:     time.Hour,
+		connect: func(*Dialer, ...string) (coordinator, error) {
+			return conn, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer group.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	gen1, err := group.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-gen1.Done():
+		t.Fatal("generation ended before End was called")
+	default:
+	}
+
+	gen1.End()
+	gen1.End() // must not panic
+
+	select {
+	case <-gen1.Done():
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the generation to end")
+	}
+
+	gen2, err := group.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen2.ID != gen1.ID+1 {
+		t.Errorf("expected generation %d but got %d", gen1.ID+1, gen2.ID)
+	}
+
+	if err := group.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-gen2.Done():
+	default:
+		t.Error("closing the group did not end the generation")
+	}
+}
+
+func TestGenerationCommitInterval(t *testing.T) {
+	var lock sync.Mutex
+	var commits []offsetCommitRequestV2
+
+	conn := newGroupCoordinator([]Partition{{Topic: "topic-1", ID: 0}, {Topic: "topic-1", ID: 1}})
+	conn.offsetCommitFunc = func(req offsetCommitRequestV2) (offsetCommitResponseV2, error) {
+		lock.Lock()
+		commits = append(commits, req)
+		lock.Unlock()
+		return offsetCommitResponseV2{}, nil
+	}
+
+	group, err := NewConsumerGroup(ConsumerGroupConfig{
+		ID:                makeGroupID(),
+		Topics:            []string{"topic-1"},
+		Brokers:           []string{"no-such-broker"},
+		HeartbeatInterval: 2 * time.Second,
+		RebalanceTimeout:  time.Second,
+		RetentionTime:     time.Hour,
+		CommitInterval:    time.Hour,
+		connect: func(*Dialer, ...string) (coordinator, error) {
+			return conn, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer group.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	gen, err := group.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, offsets := range []map[string]map[int]int64{
+		{"topic-1": {0: 1}},
+		{"topic-1": {0: 2, 1: 5}},
+		{"topic-1": {0: 3}},
+	} {
+		if err := gen.CommitOffsets(offsets); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lock.Lock()
+	if len(commits) != 0 {
+		t.Errorf("offsets were committed before the interval elapsed: %+v", commits)
+	}
+	lock.Unlock()
+
+	// the batched offsets are committed when the generation ends.
+	gen.End()
+	if _, err := group.Next(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// offsets committed after the generation ended are committed
+	// synchronously.
+	if err := gen.CommitOffsets(map[string]map[int]int64{"topic-1": {1: 6}}); err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if len(commits) != 2 {
+		t.Fatalf("expected 2 commits but got %d: %+v", len(commits), commits)
+	}
+	if want := map[int32]int64{0: 3, 1: 5}; !reflect.DeepEqual(committedOffsets(commits[0]), want) {
+		t.Errorf("wrong batched offsets: want %v, got %v", want, committedOffsets(commits[0]))
+	}
+	if want := map[int32]int64{1: 6}; !reflect.DeepEqual(committedOffsets(commits[1]), want) {
+		t.Errorf("wrong offsets: want %v, got %v", want, committedOffsets(commits[1]))
+	}
+}
+
+func TestGenerationCommitRetries(t *testing.T) {
+	var lock sync.Mutex
+	attempts := 0
+
+	conn := newGroupCoordinator([]Partition{{Topic: "topic-1", ID: 0}})
+	conn.offsetCommitFunc = func(req offsetCommitRequestV2) (offsetCommitResponseV2, error) {
+		lock.Lock()
+		attempts++
+		lock.Unlock()
+		return offsetCommitResponseV2{}, RebalanceInProgress
+	}
+
+	type commitError struct {
+		offsets map[string]map[int]int64
+		err     error
+	}
+	commitErrors := make(chan commitError, 1)
+
+	group, err := NewConsumerGroup(ConsumerGroupConfig{
+		ID:                makeGroupID(),
+		Topics:            []string{"topic-1"},
+		Brokers:           []string{"no-such-broker"},
+		HeartbeatInterval: 2 * time.Second,
+		RebalanceTimeout:  time.Second,
+		RetentionTime:     time.Hour,
+		CommitInterval:    time.Hour,
+		CommitRetries:     2,
+		OnCommitError: func(offsets map[string]map[int]int64, err error) {
+			commitErrors <- commitError{offsets, err}
+		},
+		connect: func(*Dialer, ...string) (coordinator, error) {
+			return conn, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer group.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	gen, err := group.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gen.CommitOffsets(map[string]map[int]int64{"topic-1": {0: 42}}); err != nil {
+		t.Fatal(err)
+	}
+	gen.End()
+
+	select {
+	case e := <-commitErrors:
+		if !errors.Is(e.err, RebalanceInProgress) {
+			t.Errorf("expected %v but got %v", RebalanceInProgress, e.err)
+		}
+		if want := (map[string]map[int]int64{"topic-1": {0: 42}}); !reflect.DeepEqual(e.offsets, want) {
+			t.Errorf("wrong offsets: want %v, got %v", want, e.offsets)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the commit error")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts but got %d", attempts)
+	}
+}
+
+func TestGenerationCommitTimeout(t *testing.T) {
+	conn := newGroupCoordinator([]Partition{{Topic: "topic-1", ID: 0}})
+	conn.offsetCommitFunc = func(req offsetCommitRequestV2) (offsetCommitResponseV2, error) {
+		return offsetCommitResponseV2{}, RebalanceInProgress
+	}
+
+	commitErrors := make(chan error, 1)
+
+	group, err := NewConsumerGroup(ConsumerGroupConfig{
+		ID:                makeGroupID(),
+		Topics:            []string{"topic-1"},
+		Brokers:           []string{"no-such-broker"},
+		HeartbeatInterval: 2 * time.Second,
+		RebalanceTimeout:  200 * time.Millisecond,
+		RetentionTime:     time.Hour,
+		CommitInterval:    time.Hour,
+		CommitRetries:     1000,
+		OnCommitError: func(offsets map[string]map[int]int64, err error) {
+			commitErrors <- err
+		},
+		connect: func(*Dialer, ...string) (coordinator, error) {
+			return conn, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer group.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	gen, err := group.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gen.CommitOffsets(map[string]map[int]int64{"topic-1": {0: 42}}); err != nil {
+		t.Fatal(err)
+	}
+	gen.End()
+
+	select {
+	case err := <-commitErrors:
+		if !errors.Is(err, RebalanceInProgress) {
+			t.Errorf("expected %v but got %v", RebalanceInProgress, err)
+		}
+	case <-ctx.Done():
+		t.Fatal("the last commit was not bounded by the rebalance timeout")
+	}
+}
+
+func TestGenerationCommitMetadata(t *testing.T) {
+	var lock sync.Mutex
+	var commits []offsetCommitRequestV2
+
+	conn := newGroupCoordinator([]Partition{{Topic: "topic-1", ID: 0}, {Topic: "topic-1", ID: 1}})
+	conn.offsetFetchFunc = func(offsetFetchRequestV1) (offsetFetchResponseV1, error) {
+		return offsetFetchResponseV1{
+			Responses: []offsetFetchResponseV1Response{{
+				Topic: "topic-1",
+				PartitionResponses: []offsetFetchResponseV1PartitionResponse{
+					{Partition: 0, Offset: 10, Metadata: "host-A"},
+					{Partition: 1, Offset: -1},
+				},
+			}},
+		}, nil
+	}
+	conn.offsetCommitFunc = func(req offsetCommitRequestV2) (offsetCommitResponseV2, error) {
+		lock.Lock()
+		commits = append(commits, req)
+		lock.Unlock()
+		return offsetCommitResponseV2{}, nil
+	}
+
+	group, err := NewConsumerGroup(ConsumerGroupConfig{
+		ID:                makeGroupID(),
+		Topics:            []string{"topic-1"},
+		Brokers:           []string{"no-such-broker"},
+		HeartbeatInterval: 2 * time.Second,
+		RebalanceTimeout:  time.Second,
+		RetentionTime:     time.Hour,
+		CommitMetadata:    "host-B",
+		connect: func(*Dialer, ...string) (coordinator, error) {
+			return conn, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer group.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	gen, err := group.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []PartitionAssignment{
+		{ID: 0, Offset: 10, Metadata: "host-A"},
+		{ID: 1, Offset: FirstOffset},
+	}
+	if got := gen.Assignments["topic-1"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong assignments: want %+v, got %+v", want, got)
+	}
+
+	if err := gen.CommitOffsets(map[string]map[int]int64{"topic-1": {0: 11}}); err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(commits) != 1 {
+		t.Fatalf("expected 1 commit but got %d", len(commits))
+	}
+	if p := commits[0].Topics[0].Partitions[0]; p.Offset != 11 || p.Metadata != "host-B" {
+		t.Errorf("wrong committed offset: %+v", p)
+	}
+	lock.Unlock()
+
+	if err := gen.CommitOffsetsWithMetadata(map[string]map[int]int64{"topic-1": {0: 12}}, "checkpoint-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Lock()
+	if len(commits) != 2 {
+		t.Fatalf("expected 2 commits but got %d", len(commits))
+	}
+	if p := commits[1].Topics[0].Partitions[0]; p.Offset != 12 || p.Metadata != "checkpoint-1" {
+		t.Errorf("wrong committed offset: %+v", p)
+	}
+}
+
+func TestGenerationCommitIntervalMetadata(t *testing.T) {
+	commits := make(chan offsetCommitRequestV2, 1)
+
+	conn := newGroupCoordinator([]Partition{{Topic: "topic-1", ID: 0}, {Topic: "topic-1", ID: 1}})
+	conn.offsetCommitFunc = func(req offsetCommitRequestV2) (offsetCommitResponseV2, error) {
+		commits <- req
+		return offsetCommitResponseV2{}, nil
+	}
+
+	group, err := NewConsumerGroup(ConsumerGroupConfig{
+		ID:                makeGroupID(),
+		Topics:            []string{"topic-1"},
+		Brokers:           []string{"no-such-broker"},
+		HeartbeatInterval: 2 * time.Second,
+		RebalanceTimeout:  time.Second,
+		RetentionTime:     time.Hour,
+		CommitInterval:    time.Hour,
+		CommitMetadata:    "host-A",
+		connect: func(*Dialer, ...string) (coordinator, error) {
+			return conn, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer group.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	gen, err := group.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the batched offset of each partition is committed with the metadata of
+	// the last call which recorded it.
+	if err := gen.CommitOffsetsWithMetadata(map[string]map[int]int64{"topic-1": {0: 1, 1: 1}}, "checkpoint-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gen.CommitOffsets(map[string]map[int]int64{"topic-1": {0: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	gen.End()
+
+	select {
+	case req := <-commits:
+		got := map[int32]string{}
+		for _, p := range req.Topics[0].Partitions {
+			got[p.Partition] = fmt.Sprintf("%d/%s", p.Offset, p.Metadata)
+		}
+		if want := (map[int32]string{0: "2/host-A", 1: "1/checkpoint-1"}); !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong committed offsets: want %v, got %v", want, got)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the commit")
+	}
+}
+
+func committedOffsets(req offsetCommitRequestV2) map[int32]int64 {
+	offsets := map[int32]int64{}
+	for _, topic := range req.Topics {
+		for _, partition := range topic.Partitions {
+			offsets[partition.Partition] = partition.Offset
+		}
+	}
+	return offsets
+}
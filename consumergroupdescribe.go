@@ -0,0 +1,163 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/consumergroupdescribe"
+)
+
+// ConsumerGroupDescribeRequest represents a request sent to kafka to describe
+// consumer groups using the consumer group protocol introduced by KIP-848.
+type ConsumerGroupDescribeRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// GroupIDs is a slice of groups to get details for.
+	GroupIDs []string
+
+	// IncludeAuthorizedOperations requests the set of operations that the
+	// client is authorized to perform on each group.
+	IncludeAuthorizedOperations bool
+}
+
+// ConsumerGroupDescribeResponse is a response from kafka with information
+// about consumer groups.
+type ConsumerGroupDescribeResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// Groups contains information about each group.
+	Groups []ConsumerGroupDescription
+}
+
+// ConsumerGroupDescription contains the response details for a single group.
+type ConsumerGroupDescription struct {
+	// Error is set to a non-nil value if there was an error fetching the
+	// details of this group.
+	Error error
+
+	// GroupID is the ID of the group.
+	GroupID string
+
+	// GroupState is a description of the group state.
+	GroupState string
+
+	// The epoch of the group, it is bumped every time the membership or the
+	// subscriptions of the group change.
+	GroupEpoch int
+
+	// The epoch of the target assignment computed by the coordinator.
+	AssignmentEpoch int
+
+	// The name of the server-side assignor used by the group.
+	AssignorName string
+
+	// Members contains details about each member of the group.
+	Members []ConsumerGroupMemberDescription
+
+	// The bit set of operations that the client is authorized to perform on
+	// the group, only set if it was requested.
+	AuthorizedOperations int
+}
+
+// ConsumerGroupMemberDescription represents the details of a member of a
+// consumer group.
+type ConsumerGroupMemberDescription struct {
+	// MemberID is the ID of the member.
+	MemberID string
+
+	// InstanceID is the static identifier of the member, if any.
+	InstanceID string
+
+	// RackID is the rack of the member, if any.
+	RackID string
+
+	// The current epoch of the member.
+	MemberEpoch int
+
+	// ClientID is the client ID of the member.
+	ClientID string
+
+	// ClientHost is the host of the member.
+	ClientHost string
+
+	// The list of topics the member is subscribed to.
+	SubscribedTopics []string
+
+	// The regular expression that the member subscribed with, if any.
+	SubscribedTopicRegex string
+
+	// The partitions currently owned by the member.
+	Assignment []ConsumerGroupTopicPartitions
+
+	// The partitions that the coordinator intends to assign to the member,
+	// they differ from Assignment while the group is rebalancing.
+	TargetAssignment []ConsumerGroupTopicPartitions
+}
+
+// ConsumerGroupDescribe describes groups using the consumer group protocol
+// introduced by KIP-848. The API is supported by kafka 3.7 and above.
+//
+// Groups using the classic protocol must be described with DescribeGroups.
+func (c *Client) ConsumerGroupDescribe(ctx context.Context, req *ConsumerGroupDescribeRequest) (*ConsumerGroupDescribeResponse, error) {
+	m, err := c.roundTrip(ctx, req.Addr, &consumergroupdescribe.Request{
+		GroupIDs:                    req.GroupIDs,
+		IncludeAuthorizedOperations: req.IncludeAuthorizedOperations,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).ConsumerGroupDescribe: %w", err)
+	}
+
+	res := m.(*consumergroupdescribe.Response)
+	ret := &ConsumerGroupDescribeResponse{
+		Throttle: makeDuration(res.ThrottleTimeMs),
+		Groups:   make([]ConsumerGroupDescription, len(res.Groups)),
+	}
+
+	for i, g := range res.Groups {
+		group := ConsumerGroupDescription{
+			Error:                makeError(g.ErrorCode, g.ErrorMessage),
+			GroupID:              g.GroupID,
+			GroupState:           g.GroupState,
+			GroupEpoch:           int(g.GroupEpoch),
+			AssignmentEpoch:      int(g.AssignmentEpoch),
+			AssignorName:         g.AssignorName,
+			Members:              make([]ConsumerGroupMemberDescription, len(g.Members)),
+			AuthorizedOperations: int(g.AuthorizedOperations),
+		}
+
+		for j, m := range g.Members {
+			group.Members[j] = ConsumerGroupMemberDescription{
+				MemberID:             m.MemberID,
+				InstanceID:           m.InstanceID,
+				RackID:               m.RackID,
+				MemberEpoch:          int(m.MemberEpoch),
+				ClientID:             m.ClientID,
+				ClientHost:           m.ClientHost,
+				SubscribedTopics:     m.SubscribedTopicNames,
+				SubscribedTopicRegex: m.SubscribedTopicRegex,
+				Assignment:           makeConsumerGroupAssignment(m.Assignment),
+				TargetAssignment:     makeConsumerGroupAssignment(m.TargetAssignment),
+			}
+		}
+
+		ret.Groups[i] = group
+	}
+
+	return ret, nil
+}
+
+func makeConsumerGroupAssignment(a consumergroupdescribe.Assignment) []ConsumerGroupTopicPartitions {
+	topics := make([]ConsumerGroupTopicPartitions, len(a.TopicPartitions))
+	for i, t := range a.TopicPartitions {
+		topics[i] = ConsumerGroupTopicPartitions{
+			TopicID:    t.TopicID,
+			Topic:      t.TopicName,
+			Partitions: makePartitionList(t.Partitions),
+		}
+	}
+	return topics
+}
@@ -0,0 +1,34 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ktesting "github.com/segmentio/kafka-go/testing"
+)
+
+func TestClientConsumerGroupDescribe(t *testing.T) {
+	if !ktesting.KafkaIsAtLeast("3.7.0") {
+		return
+	}
+
+	client, shutdown := newLocalClient()
+	defer shutdown()
+
+	groupID := makeGroupID()
+
+	res, err := client.ConsumerGroupDescribe(context.Background(), &ConsumerGroupDescribeRequest{
+		GroupIDs: []string{groupID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Groups) != 1 {
+		t.Fatalf("expected one group, got %d", len(res.Groups))
+	}
+	if g := res.Groups[0]; g.GroupID != groupID || !errors.Is(g.Error, GroupIdNotFound) {
+		t.Errorf("unexpected group description: %+v", g)
+	}
+}
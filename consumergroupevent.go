@@ -0,0 +1,125 @@
+package kafka
+
+import (
+	"errors"
+	"time"
+)
+
+// GroupEventType identifies the lifecycle events of consumer group members.
+type GroupEventType int
+
+const (
+	// GroupJoining is emitted when the member starts joining the group, at
+	// the beginning of a rebalance or after a failed attempt to join.
+	GroupJoining GroupEventType = iota
+
+	// GroupSyncing is emitted when the member has joined the group and
+	// waits for its assignment.  It is only emitted by groups using
+	// GroupProtocolClassic.
+	GroupSyncing
+
+	// GroupStable is emitted when the member received its assignment and
+	// the generation is about to be returned by Next.  The Duration of the
+	// event is the time spent rebalancing since the member started joining
+	// the group.
+	GroupStable
+
+	// GroupRebalancing is emitted when the generation ended and the member
+	// is about to rejoin the group.  The Duration of the event is the time
+	// that the generation was stable, and Err is the error that ended it, if
+	// any, like RebalanceInProgress when another member joined the group.
+	GroupRebalancing
+
+	// GroupLeaving is emitted when the member leaves the group, because the
+	// group was closed or after an error.
+	GroupLeaving
+
+	// GroupFenced is emitted when the coordinator removed the member from
+	// the group, for example because it missed heartbeats for longer than
+	// the session timeout.  Err holds the error returned by the coordinator.
+	GroupFenced
+)
+
+func (t GroupEventType) String() string {
+	switch t {
+	case GroupJoining:
+		return "joining"
+	case GroupSyncing:
+		return "syncing"
+	case GroupStable:
+		return "stable"
+	case GroupRebalancing:
+		return "rebalancing"
+	case GroupLeaving:
+		return "leaving"
+	case GroupFenced:
+		return "fenced"
+	default:
+		return "unknown"
+	}
+}
+
+// GroupEvent describes a change in the state of a consumer group member, see
+// ConsumerGroupConfig.OnEvent.
+type GroupEvent struct {
+	// Type is the type of event.
+	Type GroupEventType
+
+	// Time is the time at which the event occurred.
+	Time time.Time
+
+	// GroupID is the name of the consumer group.
+	GroupID string
+
+	// MemberID is the ID of the member, empty when it joins the group for
+	// the first time.
+	MemberID string
+
+	// GenerationID is the ID of the generation that the event applies to,
+	// or the member epoch when using GroupProtocolConsumer.  It is zero
+	// when the member is not part of a generation.
+	GenerationID int32
+
+	// Duration is the time spent in the previous state, for GroupStable
+	// and GroupRebalancing events.
+	Duration time.Duration
+
+	// Err is the error which caused the event, if any.
+	Err error
+}
+
+// emit reports the event to the OnEvent function of the configuration.  It
+// also tracks the time at which rebalances start and end to compute the
+// duration of events.  It must only be called by the goroutine running the
+// group.
+func (cg *ConsumerGroup) emit(event GroupEvent) {
+	event.Time = cg.clock().Now()
+	event.GroupID = cg.config.ID
+
+	switch event.Type {
+	case GroupJoining:
+		if cg.rebalanceStart.IsZero() {
+			cg.rebalanceStart = event.Time
+		}
+	case GroupStable:
+		event.Duration = event.Time.Sub(cg.rebalanceStart)
+		cg.rebalanceStart = time.Time{}
+		cg.stableStart = event.Time
+	case GroupRebalancing:
+		event.Duration = event.Time.Sub(cg.stableStart)
+	case GroupLeaving, GroupFenced:
+		cg.rebalanceStart = time.Time{}
+	}
+
+	if cg.config.OnEvent != nil {
+		cg.config.OnEvent(event)
+	}
+}
+
+// isFenced returns true if err indicates that the coordinator removed the
+// member from the group.
+func isFenced(err error) bool {
+	return errors.Is(err, UnknownMemberId) ||
+		errors.Is(err, FencedInstanceID) ||
+		errors.Is(err, FencedMemberEpoch)
+}
@@ -0,0 +1,142 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConsumerGroupEvents(t *testing.T) {
+	events := make(chan GroupEvent, 100)
+
+	conn := newGroupCoordinator([]Partition{{Topic: "topic-1", ID: 0}})
+	joinGroup := conn.joinGroupFunc
+	conn.joinGroupFunc = func(req joinGroupRequestV1) (joinGroupResponseV1, error) {
+		// the member was removed from the group after its heartbeat failed.
+		if req.MemberID != "" {
+			return joinGroupResponseV1{ErrorCode: int16(UnknownMemberId)}, nil
+		}
+		return joinGroup(req)
+	}
+	fenced := make(chan struct{})
+	conn.heartbeatFunc = func(heartbeatRequestV0) (heartbeatResponseV0, error) {
+		select {
+		case <-fenced:
+			return heartbeatResponseV0{}, UnknownMemberId
+		default:
+			return heartbeatResponseV0{}, nil
+		}
+	}
+
+	group, err := NewConsumerGroup(ConsumerGroupConfig{
+		ID:                makeGroupID(),
+		Topics:            []string{"topic-1"},
+		Brokers:           []string{"no-such-broker"},
+		HeartbeatInterval: 10 * time.Millisecond,
+		RebalanceTimeout:  time.Second,
+		JoinGroupBackoff:  10 * time.Millisecond,
+		RetentionTime:     time.Hour,
+		OnEvent:           func(e GroupEvent) { events <- e },
+		connect: func(*Dialer, ...string) (coordinator, error) {
+			return conn, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer group.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	gen1, err := group.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(fenced)
+
+	// the generation ends when the heartbeat fails, and rejoining the group
+	// with the previous member ID fails.
+	select {
+	case <-gen1.Done():
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the generation to end")
+	}
+	if _, err := group.Next(ctx); !errors.Is(err, UnknownMemberId) {
+		t.Fatalf("expected %v but got %v", UnknownMemberId, err)
+	}
+
+	gen2, err := group.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group.Close()
+
+	type event struct {
+		Type         GroupEventType
+		MemberID     string
+		GenerationID int32
+		Err          error
+	}
+	want := []event{
+		{GroupJoining, "", 0, nil},
+		{GroupSyncing, "member-1", gen1.ID, nil},
+		{GroupStable, "member-1", gen1.ID, nil},
+		{GroupRebalancing, "member-1", gen1.ID, UnknownMemberId},
+		{GroupJoining, "member-1", 0, nil},
+		{GroupFenced, "member-1", 0, UnknownMemberId},
+		{GroupJoining, "", 0, nil},
+		{GroupSyncing, "member-1", gen2.ID, nil},
+		{GroupStable, "member-1", gen2.ID, nil},
+		{GroupLeaving, "member-1", 0, nil},
+	}
+
+	var got []event
+	var last time.Time
+	for len(events) > 0 {
+		e := <-events
+		if e.GroupID != group.config.ID {
+			t.Errorf("wrong group ID in %v event: %q", e.Type, e.GroupID)
+		}
+		if e.Time.Before(last) {
+			t.Errorf("time of %v event is before the previous event", e.Type)
+		}
+		if e.Duration < 0 || (e.Duration != 0 && e.Type != GroupStable && e.Type != GroupRebalancing) {
+			t.Errorf("wrong duration of %v event: %v", e.Type, e.Duration)
+		}
+		last = e.Time
+
+		var err error
+		if e.Err != nil {
+			var kafkaError Error
+			if errors.As(e.Err, &kafkaError) {
+				err = kafkaError
+			} else {
+				err = e.Err
+			}
+		}
+		got = append(got, event{e.Type, e.MemberID, e.GenerationID, err})
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong events:\nwant: %v\ngot:  %v", want, got)
+	}
+}
+
+func TestGroupEventTypeString(t *testing.T) {
+	for typ, name := range map[GroupEventType]string{
+		GroupJoining:       "joining",
+		GroupSyncing:       "syncing",
+		GroupStable:        "stable",
+		GroupRebalancing:   "rebalancing",
+		GroupLeaving:       "leaving",
+		GroupFenced:        "fenced",
+		GroupEventType(-1): "unknown",
+	} {
+		if s := typ.String(); s != name {
+			t.Errorf("expected %q but got %q", name, s)
+		}
+	}
+}
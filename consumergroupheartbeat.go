@@ -0,0 +1,174 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/consumergroupheartbeat"
+)
+
+// UUID is the representation of universally unique identifiers used by kafka,
+// for example to identify topics.
+type UUID = protocol.UUID
+
+// ConsumerGroupTopicPartitions represents a set of partitions of a topic
+// assigned to a member of a consumer group using the consumer group protocol
+// introduced by KIP-848.
+type ConsumerGroupTopicPartitions struct {
+	// The ID of the topic.
+	TopicID UUID
+
+	// The name of the topic. Responses to ConsumerGroupHeartbeat requests
+	// only identify topics by ID, this field is empty in that case.
+	Topic string
+
+	// The list of partitions.
+	Partitions []int
+}
+
+// ConsumerGroupHeartbeatRequest represents a request sent to the coordinator
+// of a consumer group by members using the consumer group protocol introduced
+// by KIP-848, where partitions are assigned by the brokers.
+//
+// See https://cwiki.apache.org/confluence/display/KAFKA/KIP-848%3A+The+Next+Generation+of+the+Consumer+Rebalance+Protocol
+type ConsumerGroupHeartbeatRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// GroupID is the ID of the group.
+	GroupID string
+
+	// MemberID is the ID of the group member, it is empty when joining the
+	// group for the first time.
+	MemberID string
+
+	// MemberEpoch is the current epoch of the member. It must be 0 to join
+	// the group, -1 to leave it, and -2 for static members to leave the group
+	// temporarily.
+	MemberEpoch int
+
+	// InstanceID is the static identifier of the member, or empty for
+	// dynamic members.
+	InstanceID string
+
+	// RackID is the rack of the member, or empty if it is not known.
+	RackID string
+
+	// The maximum time that the coordinator waits for the member to revoke
+	// its partitions. Zero means that the value was not changed since the
+	// last heartbeat.
+	RebalanceTimeout time.Duration
+
+	// The list of topics the member is subscribed to. Nil means that the
+	// subscription did not change since the last heartbeat.
+	SubscribedTopics []string
+
+	// The name of the server-side assignor to use, or empty to let the
+	// coordinator pick one.
+	ServerAssignor string
+
+	// The partitions owned by the member. Nil means that they did not change
+	// since the last heartbeat.
+	TopicPartitions []ConsumerGroupTopicPartitions
+}
+
+// ConsumerGroupHeartbeatResponse represents a response from the coordinator of
+// a consumer group to a ConsumerGroupHeartbeatRequest.
+type ConsumerGroupHeartbeatResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// An error that may have occurred when the coordinator processed the
+	// heartbeat.
+	//
+	// The errors contain the kafka error code. Programs may use the standard
+	// errors.Is function to test the error against kafka error codes.
+	Error error
+
+	// The ID of the member, generated by the coordinator when it joined the
+	// group.
+	MemberID string
+
+	// The current epoch of the member.
+	MemberEpoch int
+
+	// The interval at which the member must send heartbeats.
+	HeartbeatInterval time.Duration
+
+	// Assignment is the set of partitions assigned to the member. It is nil
+	// when the assignment did not change since the last heartbeat.
+	Assignment []ConsumerGroupTopicPartitions
+}
+
+// ConsumerGroupHeartbeat sends a heartbeat request to the coordinator of a
+// consumer group using the consumer group protocol introduced by KIP-848. The
+// protocol is supported by kafka 3.7 and above.
+func (c *Client) ConsumerGroupHeartbeat(ctx context.Context, req *ConsumerGroupHeartbeatRequest) (*ConsumerGroupHeartbeatResponse, error) {
+	apiReq := &consumergroupheartbeat.Request{
+		GroupID:              req.GroupID,
+		MemberID:             req.MemberID,
+		MemberEpoch:          int32(req.MemberEpoch),
+		InstanceID:           req.InstanceID,
+		RackID:               req.RackID,
+		RebalanceTimeoutMs:   -1,
+		SubscribedTopicNames: req.SubscribedTopics,
+		ServerAssignor:       req.ServerAssignor,
+	}
+
+	if req.RebalanceTimeout != 0 {
+		apiReq.RebalanceTimeoutMs = milliseconds(req.RebalanceTimeout)
+	}
+
+	if req.TopicPartitions != nil {
+		apiReq.TopicPartitions = make([]consumergroupheartbeat.TopicPartitions, len(req.TopicPartitions))
+
+		for i, t := range req.TopicPartitions {
+			partitions := make([]int32, len(t.Partitions))
+			for j, p := range t.Partitions {
+				partitions[j] = int32(p)
+			}
+			apiReq.TopicPartitions[i] = consumergroupheartbeat.TopicPartitions{
+				TopicID:    t.TopicID,
+				Partitions: partitions,
+			}
+		}
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, apiReq)
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).ConsumerGroupHeartbeat: %w", err)
+	}
+
+	res := m.(*consumergroupheartbeat.Response)
+	ret := &ConsumerGroupHeartbeatResponse{
+		Throttle:          makeDuration(res.ThrottleTimeMs),
+		Error:             makeError(res.ErrorCode, res.ErrorMessage),
+		MemberID:          res.MemberID,
+		MemberEpoch:       int(res.MemberEpoch),
+		HeartbeatInterval: makeDuration(res.HeartbeatIntervalMs),
+	}
+
+	if res.Assignment != nil {
+		ret.Assignment = make([]ConsumerGroupTopicPartitions, len(res.Assignment.TopicPartitions))
+
+		for i, t := range res.Assignment.TopicPartitions {
+			ret.Assignment[i] = ConsumerGroupTopicPartitions{
+				TopicID:    t.TopicID,
+				Partitions: makePartitionList(t.Partitions),
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+func makePartitionList(partitions []int32) []int {
+	list := make([]int, len(partitions))
+	for i, p := range partitions {
+		list[i] = int(p)
+	}
+	return list
+}
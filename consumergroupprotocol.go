@@ -0,0 +1,596 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+// GroupProtocol represents the protocol used by the members of a consumer group
+// to coordinate partition assignments.
+type GroupProtocol string
+
+const (
+	// GroupProtocolClassic is the protocol built on the JoinGroup and
+	// SyncGroup APIs, where the leader of the group computes the partition
+	// assignments using the configured GroupBalancers.
+	GroupProtocolClassic GroupProtocol = "classic"
+
+	// GroupProtocolConsumer is the protocol introduced by KIP-848, where the
+	// coordinator computes the partition assignments and members only
+	// exchange heartbeats with it.  Rebalances are incremental and do not
+	// require all members of the group to stop consuming.
+	//
+	// Consumer groups configured with this protocol fall back to the classic
+	// protocol when the brokers do not support it.
+	GroupProtocolConsumer GroupProtocol = "consumer"
+)
+
+// consumerGroupMember carries the state of a member of a consumer group using
+// GroupProtocolConsumer across generations.
+type consumerGroupMember struct {
+	id       string
+	epoch    int
+	interval time.Duration
+
+	// assignment is the last assignment received from the coordinator, and
+	// changed is set when it was received after the current generation
+	// started.
+	assignment []ConsumerGroupTopicPartitions
+	changed    bool
+
+	// owned holds the partitions that the member must acknowledge owning on
+	// its next heartbeat, or nil if it already did.
+	owned []ConsumerGroupTopicPartitions
+
+	topicNames map[UUID]string
+
+	// err is the error that terminated the last generation, if any.
+	err error
+}
+
+// reset clears the member epoch and assignment, which causes the member to
+// rejoin the group on its next heartbeat.  The member ID is retained.
+func (m *consumerGroupMember) reset() {
+	m.epoch = 0
+	m.assignment = nil
+	m.changed = false
+	m.owned = nil
+}
+
+// runConsumerProtocol participates in the consumer group using
+// GroupProtocolConsumer.  It returns true if the brokers do not support the
+// protocol, in which case the caller must fall back to the classic protocol.
+func (cg *ConsumerGroup) runConsumerProtocol() (fallback bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-cg.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if t, ok := cg.config.client.Transport.(interface{ CloseIdleConnections() }); ok {
+		defer t.CloseIdleConnections()
+	}
+
+	for {
+		supported, err := cg.supportsConsumerProtocol(ctx)
+		if err == nil {
+			if !supported {
+				cg.withLogger(func(log Logger) {
+					log.Printf("The brokers do not support the consumer group protocol, falling back to the classic protocol for group %s", cg.config.ID)
+				})
+				return true
+			}
+			break
+		}
+		if !cg.reportAndBackoff(err) {
+			return false
+		}
+	}
+
+	member := &consumerGroupMember{topicNames: make(map[UUID]string)}
+	for {
+		err := cg.nextConsumerGeneration(ctx, member)
+
+		switch {
+		case err == nil:
+			continue
+
+		case errors.Is(err, ErrGroupClosed):
+			if member.id != "" {
+				cg.emit(GroupEvent{Type: GroupLeaving, MemberID: member.id})
+			}
+			_ = cg.leaveConsumerGroup(member)
+			return false
+
+		case errors.Is(err, FencedMemberEpoch), errors.Is(err, UnknownMemberId):
+			// the coordinator has removed the member from the group, it must
+			// rejoin with the same member ID and an epoch of zero.  the error
+			// is reported but there is no need to backoff.
+			cg.emit(GroupEvent{Type: GroupFenced, MemberID: member.id, GenerationID: int32(member.epoch), Err: err})
+			member.reset()
+			select {
+			case <-cg.done:
+				return false
+			case cg.errs <- err:
+			}
+			continue
+		}
+
+		if !cg.reportAndBackoff(err) {
+			if member.id != "" {
+				cg.emit(GroupEvent{Type: GroupLeaving, MemberID: member.id, Err: err})
+			}
+			_ = cg.leaveConsumerGroup(member)
+			return false
+		}
+	}
+}
+
+// reportAndBackoff reports err to the caller of Next, then waits for the join
+// group backoff.  It returns false if the group was closed in the meantime.
+func (cg *ConsumerGroup) reportAndBackoff(err error) bool {
+	select {
+	case <-cg.done:
+		return false
+	case cg.errs <- err:
+	}
+	return afterClock(cg.done, cg.clock(), cg.config.JoinGroupBackoff)
+}
+
+// supportsConsumerProtocol returns true if the brokers support the
+// ConsumerGroupHeartbeat API.
+func (cg *ConsumerGroup) supportsConsumerProtocol(ctx context.Context) (bool, error) {
+	res, err := cg.config.client.ApiVersions(ctx, &ApiVersionsRequest{})
+	if err != nil {
+		return false, err
+	}
+	if res.Error != nil {
+		return false, res.Error
+	}
+	for _, k := range res.ApiKeys {
+		if k.ApiKey == int(protocol.ConsumerGroupHeartbeat) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (cg *ConsumerGroup) nextConsumerGeneration(ctx context.Context, member *consumerGroupMember) error {
+	cg.emit(GroupEvent{Type: GroupJoining, MemberID: member.id, GenerationID: int32(member.epoch)})
+
+	// send heartbeats until the coordinator has computed an assignment for
+	// the member.  this happens on the first heartbeat after joining, unless
+	// the group is rebalancing.
+	for member.assignment == nil {
+		if err := cg.consumerGroupHeartbeat(ctx, member); err != nil {
+			if ctx.Err() != nil {
+				return ErrGroupClosed
+			}
+			cg.withErrorLogger(func(log Logger) {
+				log.Printf("Failed to join group %s: %v", cg.config.ID, err)
+			})
+			return err
+		}
+		if member.assignment != nil {
+			break
+		}
+		if !afterClock(cg.done, cg.clock(), member.interval) {
+			return ErrGroupClosed
+		}
+	}
+
+	assignments, err := cg.resolveConsumerAssignment(ctx, member)
+	if err != nil {
+		cg.withErrorLogger(func(log Logger) {
+			log.Printf("Failed to resolve the assignment of group %s: %v", cg.config.ID, err)
+		})
+		return err
+	}
+
+	conn := &clientCoordinator{client: cg.config.client, timeout: cg.config.Timeout}
+
+	offsets, err := cg.fetchOffsets(conn, assignments)
+	if err != nil {
+		cg.withErrorLogger(func(log Logger) {
+			log.Printf("Failed to fetch offsets for group %s: %v", cg.config.ID, err)
+		})
+		return err
+	}
+
+	cg.withLogger(func(log Logger) {
+		log.Printf("Joined group %s as member %s in epoch %d", cg.config.ID, member.id, member.epoch)
+	})
+
+	// the member acknowledges the assignment on its next heartbeat.
+	member.owned = member.assignment
+	member.changed = false
+	member.err = nil
+
+	gen := Generation{
+		ID:              int32(member.epoch),
+		GroupID:         cg.config.ID,
+		MemberID:        member.id,
+		Assignments:     cg.makeAssignments(assignments, offsets),
+		conn:            conn,
+		done:            make(chan struct{}),
+		joined:          make(chan struct{}),
+		commitInterval:  cg.config.CommitInterval,
+		commitRetries:   cg.config.CommitRetries,
+		commitTimeout:   cg.config.RebalanceTimeout,
+		onCommitError:   cg.config.OnCommitError,
+		commitMetadata:  cg.config.CommitMetadata,
+		clock:           cg.clock(),
+		retentionMillis: int64(cg.config.RetentionTime / time.Millisecond),
+		log:             cg.withLogger,
+		logError:        cg.withErrorLogger,
+	}
+
+	cg.emit(GroupEvent{Type: GroupStable, MemberID: member.id, GenerationID: gen.ID})
+	cg.consumerHeartbeatLoop(&gen, member)
+	if cg.config.CommitInterval > 0 {
+		gen.commitLoop()
+	}
+
+	select {
+	case <-cg.done:
+		gen.close()
+		return ErrGroupClosed
+	case cg.next <- &gen:
+	}
+
+	select {
+	case <-cg.done:
+		gen.close()
+		return ErrGroupClosed
+	case <-gen.done:
+		gen.close()
+		// fenced members are reported when the error is handled.
+		if !isFenced(member.err) {
+			cg.emit(GroupEvent{Type: GroupRebalancing, MemberID: member.id, GenerationID: gen.ID, Err: member.err})
+		}
+		return member.err
+	}
+}
+
+// consumerHeartbeatLoop sends heartbeats to the coordinator at the interval it
+// requested.  The generation ends when the member epoch or assignment changes,
+// or when a heartbeat fails.
+func (cg *ConsumerGroup) consumerHeartbeatLoop(gen *Generation, member *consumerGroupMember) {
+	gen.Start(func(ctx context.Context) {
+		gen.log(func(l Logger) {
+			l.Printf("started heartbeat for group, %v [%v]", gen.GroupID, member.interval)
+		})
+		defer gen.log(func(l Logger) {
+			l.Printf("stopped heartbeat for group %s\n", gen.GroupID)
+		})
+
+		for {
+			if !afterClock(ctx.Done(), clockOrDefault(gen.clock), member.interval) {
+				return
+			}
+
+			if err := cg.consumerGroupHeartbeat(ctx, member); err != nil {
+				if ctx.Err() == nil {
+					member.err = err
+				}
+				return
+			}
+
+			if member.changed || int32(member.epoch) != gen.ID {
+				gen.log(func(l Logger) {
+					l.Printf("Assignment changed, ending generation %d of group %s", gen.ID, gen.GroupID)
+				})
+				return
+			}
+		}
+	})
+}
+
+func (cg *ConsumerGroup) consumerGroupHeartbeat(ctx context.Context, member *consumerGroupMember) error {
+	req := &ConsumerGroupHeartbeatRequest{
+		GroupID:     cg.config.ID,
+		MemberID:    member.id,
+		MemberEpoch: member.epoch,
+	}
+
+	if member.epoch == 0 {
+		// all the fields must be set when joining the group.
+		req.RebalanceTimeout = cg.config.RebalanceTimeout
+		req.SubscribedTopics = cg.config.Topics
+		req.ServerAssignor = cg.config.ServerAssignor
+		req.TopicPartitions = []ConsumerGroupTopicPartitions{}
+	} else {
+		req.TopicPartitions = member.owned
+	}
+
+	res, err := cg.config.client.ConsumerGroupHeartbeat(ctx, req)
+	if err == nil {
+		err = res.Error
+	}
+	if err != nil {
+		return err
+	}
+
+	if res.MemberID != "" {
+		member.id = res.MemberID
+	}
+	member.epoch = res.MemberEpoch
+	member.interval = res.HeartbeatInterval
+	member.owned = nil
+
+	if member.interval <= 0 {
+		member.interval = cg.config.HeartbeatInterval
+	}
+
+	if res.Assignment != nil && !sameConsumerAssignment(member.assignment, res.Assignment) {
+		member.assignment = res.Assignment
+		member.changed = true
+	}
+
+	return nil
+}
+
+// resolveConsumerAssignment converts the assignment of the member, where topics
+// are identified by ID, to the topic names used by the rest of the package.
+func (cg *ConsumerGroup) resolveConsumerAssignment(ctx context.Context, member *consumerGroupMember) (map[string][]int32, error) {
+	for _, t := range member.assignment {
+		if _, ok := member.topicNames[t.TopicID]; ok {
+			continue
+		}
+
+		res, err := cg.config.client.ConsumerGroupDescribe(ctx, &ConsumerGroupDescribeRequest{
+			GroupIDs: []string{cg.config.ID},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, g := range res.Groups {
+			if g.Error != nil {
+				return nil, g.Error
+			}
+			for _, m := range g.Members {
+				for _, a := range [][]ConsumerGroupTopicPartitions{m.Assignment, m.TargetAssignment} {
+					for _, t := range a {
+						member.topicNames[t.TopicID] = t.Topic
+					}
+				}
+			}
+		}
+		break
+	}
+
+	assignments := make(map[string][]int32, len(member.assignment))
+	for _, t := range member.assignment {
+		name, ok := member.topicNames[t.TopicID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", UnknownTopicID, t.TopicID)
+		}
+		for _, p := range t.Partitions {
+			assignments[name] = append(assignments[name], int32(p))
+		}
+	}
+
+	return assignments, nil
+}
+
+func (cg *ConsumerGroup) leaveConsumerGroup(member *consumerGroupMember) error {
+	// don't attempt to leave the group if no memberID was ever assigned.
+	if member.id == "" {
+		return nil
+	}
+
+	cg.withLogger(func(log Logger) {
+		log.Printf("Leaving group %s, member %s", cg.config.ID, member.id)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), cg.config.Timeout)
+	defer cancel()
+
+	res, err := cg.config.client.ConsumerGroupHeartbeat(ctx, &ConsumerGroupHeartbeatRequest{
+		GroupID:     cg.config.ID,
+		MemberID:    member.id,
+		MemberEpoch: -1,
+	})
+	if err == nil {
+		err = res.Error
+	}
+	if err != nil {
+		cg.withErrorLogger(func(log Logger) {
+			log.Printf("leave group failed for group, %v, and member, %v: %v", cg.config.ID, member.id, err)
+		})
+	}
+
+	return err
+}
+
+func sameConsumerAssignment(a, b []ConsumerGroupTopicPartitions) bool {
+	if a == nil || len(a) != len(b) {
+		return false
+	}
+
+	partitions := make(map[UUID][]int, len(a))
+	for _, t := range a {
+		partitions[t.TopicID] = sortedPartitions(t.Partitions)
+	}
+
+	for _, t := range b {
+		p, ok := partitions[t.TopicID]
+		if !ok || len(p) != len(t.Partitions) {
+			return false
+		}
+		for i, id := range sortedPartitions(t.Partitions) {
+			if p[i] != id {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func sortedPartitions(partitions []int) []int {
+	sorted := append([]int{}, partitions...)
+	sort.Ints(sorted)
+	return sorted
+}
+
+// makeClient returns the client used to communicate with the brokers when
+// using GroupProtocolConsumer.
+func makeClient(config ConsumerGroupConfig) *Client {
+	return &Client{
+		Addr:    TCP(config.Brokers...),
+		Timeout: config.Timeout,
+		Transport: &Transport{
+			// the dialer takes care of TLS, so it is not set on the
+			// transport.
+			Dial:        config.Dialer.dialContext,
+			DialTimeout: config.Dialer.Timeout,
+			SASL:        config.Dialer.SASLMechanism,
+			ClientID:    config.Dialer.ClientID,
+		},
+	}
+}
+
+// clientCoordinator implements the coordinator interface on top of a Client,
+// for generations of groups using GroupProtocolConsumer.  Only the methods
+// used by generations are supported.
+type clientCoordinator struct {
+	client  *Client
+	timeout time.Duration
+}
+
+var errClassicGroupProtocol = errors.New("the operation is only supported by the classic consumer group protocol")
+
+func (c *clientCoordinator) Close() error { return nil }
+
+func (c *clientCoordinator) findCoordinator(findCoordinatorRequestV0) (findCoordinatorResponseV0, error) {
+	return findCoordinatorResponseV0{}, errClassicGroupProtocol
+}
+
+func (c *clientCoordinator) joinGroup(joinGroupRequestV1) (joinGroupResponseV1, error) {
+	return joinGroupResponseV1{}, errClassicGroupProtocol
+}
+
+func (c *clientCoordinator) syncGroup(syncGroupRequestV0) (syncGroupResponseV0, error) {
+	return syncGroupResponseV0{}, errClassicGroupProtocol
+}
+
+func (c *clientCoordinator) leaveGroup(leaveGroupRequestV0) (leaveGroupResponseV0, error) {
+	return leaveGroupResponseV0{}, errClassicGroupProtocol
+}
+
+func (c *clientCoordinator) heartbeat(heartbeatRequestV0) (heartbeatResponseV0, error) {
+	return heartbeatResponseV0{}, errClassicGroupProtocol
+}
+
+func (c *clientCoordinator) offsetFetch(req offsetFetchRequestV1) (offsetFetchResponseV1, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	topics := make(map[string][]int, len(req.Topics))
+	for _, t := range req.Topics {
+		topics[t.Topic] = makePartitionList(t.Partitions)
+	}
+
+	res, err := c.client.OffsetFetch(ctx, &OffsetFetchRequest{
+		GroupID: req.GroupID,
+		Topics:  topics,
+	})
+	if err == nil {
+		err = res.Error
+	}
+	if err != nil {
+		return offsetFetchResponseV1{}, err
+	}
+
+	ret := offsetFetchResponseV1{}
+	for topic, partitions := range res.Topics {
+		r := offsetFetchResponseV1Response{Topic: topic}
+		for _, p := range partitions {
+			if p.Error != nil {
+				return offsetFetchResponseV1{}, p.Error
+			}
+			r.PartitionResponses = append(r.PartitionResponses, offsetFetchResponseV1PartitionResponse{
+				Partition: int32(p.Partition),
+				Offset:    p.CommittedOffset,
+				Metadata:  p.Metadata,
+			})
+		}
+		ret.Responses = append(ret.Responses, r)
+	}
+
+	return ret, nil
+}
+
+func (c *clientCoordinator) offsetCommit(req offsetCommitRequestV2) (offsetCommitResponseV2, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	topics := make(map[string][]OffsetCommit, len(req.Topics))
+	for _, t := range req.Topics {
+		for _, p := range t.Partitions {
+			topics[t.Topic] = append(topics[t.Topic], OffsetCommit{
+				Partition: int(p.Partition),
+				Offset:    p.Offset,
+				Metadata:  p.Metadata,
+			})
+		}
+	}
+
+	res, err := c.client.OffsetCommit(ctx, &OffsetCommitRequest{
+		GroupID:      req.GroupID,
+		GenerationID: int(req.GenerationID),
+		MemberID:     req.MemberID,
+		Topics:       topics,
+	})
+	if err != nil {
+		return offsetCommitResponseV2{}, err
+	}
+
+	ret := offsetCommitResponseV2{}
+	for topic, partitions := range res.Topics {
+		r := offsetCommitResponseV2Response{Topic: topic}
+		for _, p := range partitions {
+			if p.Error != nil {
+				return offsetCommitResponseV2{}, p.Error
+			}
+			r.PartitionResponses = append(r.PartitionResponses, offsetCommitResponseV2PartitionResponse{
+				Partition: int32(p.Partition),
+			})
+		}
+		ret.Responses = append(ret.Responses, r)
+	}
+
+	return ret, nil
+}
+
+func (c *clientCoordinator) readPartitions(topics ...string) ([]Partition, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	res, err := c.client.Metadata(ctx, &MetadataRequest{Topics: topics})
+	if err != nil {
+		return nil, err
+	}
+
+	var partitions []Partition
+	for _, t := range res.Topics {
+		if t.Error != nil {
+			return nil, t.Error
+		}
+		partitions = append(partitions, t.Partitions...)
+	}
+	return partitions, nil
+}
+
+var _ coordinator = (*clientCoordinator)(nil)
@@ -0,0 +1,256 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/consumergroupdescribe"
+	"github.com/segmentio/kafka-go/protocol/consumergroupheartbeat"
+	"github.com/segmentio/kafka-go/protocol/offsetfetch"
+)
+
+type roundTripperFunc func(context.Context, net.Addr, Request) (Response, error)
+
+func (f roundTripperFunc) RoundTrip(ctx context.Context, addr net.Addr, req Request) (Response, error) {
+	return f(ctx, addr, req)
+}
+
+func newMockConsumerGroupClient(supported bool, heartbeat func(*consumergroupheartbeat.Request) *consumergroupheartbeat.Response) *Client {
+	topicID := protocol.UUID{15: 1}
+
+	return &Client{
+		Addr: TCP("mock"),
+		Transport: roundTripperFunc(func(ctx context.Context, addr net.Addr, req Request) (Response, error) {
+			switch req := req.(type) {
+			case *apiversions.Request:
+				res := &apiversions.Response{
+					ApiKeys: []apiversions.ApiKeyResponse{{ApiKey: int16(protocol.Heartbeat), MaxVersion: 4}},
+				}
+				if supported {
+					res.ApiKeys = append(res.ApiKeys, apiversions.ApiKeyResponse{ApiKey: int16(protocol.ConsumerGroupHeartbeat)})
+				}
+				return res, nil
+
+			case *consumergroupheartbeat.Request:
+				return heartbeat(req), nil
+
+			case *consumergroupdescribe.Request:
+				return &consumergroupdescribe.Response{
+					Groups: []consumergroupdescribe.ResponseGroup{{
+						GroupID: req.GroupIDs[0],
+						Members: []consumergroupdescribe.ResponseGroupMember{{
+							MemberID: "member-1",
+							TargetAssignment: consumergroupdescribe.Assignment{
+								TopicPartitions: []consumergroupdescribe.TopicPartitions{
+									{TopicID: topicID, TopicName: "topic-1", Partitions: []int32{0, 1}},
+								},
+							},
+						}},
+					}},
+				}, nil
+
+			case *offsetfetch.Request:
+				res := &offsetfetch.Response{}
+				for _, t := range req.Topics {
+					topic := offsetfetch.ResponseTopic{Name: t.Name}
+					for _, p := range t.PartitionIndexes {
+						topic.Partitions = append(topic.Partitions, offsetfetch.ResponsePartition{
+							PartitionIndex:  p,
+							CommittedOffset: 10*int64(p) - 1,
+						})
+					}
+					res.Topics = append(res.Topics, topic)
+				}
+				return res, nil
+			}
+			return nil, errors.New("unexpected request")
+		}),
+	}
+}
+
+func TestConsumerGroupProtocol(t *testing.T) {
+	topicID := protocol.UUID{15: 1}
+
+	var lock sync.Mutex
+	var heartbeats []consumergroupheartbeat.Request
+	var events []GroupEventType
+
+	client := newMockConsumerGroupClient(true, func(req *consumergroupheartbeat.Request) *consumergroupheartbeat.Response {
+		lock.Lock()
+		defer lock.Unlock()
+		heartbeats = append(heartbeats, *req)
+
+		res := &consumergroupheartbeat.Response{
+			MemberID:            "member-1",
+			MemberEpoch:         req.MemberEpoch,
+			HeartbeatIntervalMs: 10,
+		}
+
+		switch len(heartbeats) {
+		case 1:
+			res.MemberEpoch = 1
+			res.Assignment = &consumergroupheartbeat.Assignment{
+				TopicPartitions: []consumergroupheartbeat.TopicPartitions{
+					{TopicID: topicID, Partitions: []int32{0, 1}},
+				},
+			}
+		case 2:
+			// the coordinator revokes partition 1.
+			res.Assignment = &consumergroupheartbeat.Assignment{
+				TopicPartitions: []consumergroupheartbeat.TopicPartitions{
+					{TopicID: topicID, Partitions: []int32{0}},
+				},
+			}
+		case 3:
+			res.MemberEpoch = 2
+		}
+
+		if req.MemberEpoch < 0 {
+			res.MemberEpoch = req.MemberEpoch
+		}
+		return res
+	})
+
+	group, err := NewConsumerGroup(ConsumerGroupConfig{
+		ID:             "group-1",
+		Brokers:        []string{"mock"},
+		Topics:         []string{"topic-1"},
+		GroupProtocol:  GroupProtocolConsumer,
+		ServerAssignor: "uniform",
+		Timeout:        time.Second,
+		OnEvent: func(e GroupEvent) {
+			lock.Lock()
+			events = append(events, e.Type)
+			lock.Unlock()
+		},
+		client: client,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	gen, err := group.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen.ID != 1 || gen.MemberID != "member-1" {
+		t.Errorf("wrong generation: id=%d member=%q", gen.ID, gen.MemberID)
+	}
+	if want := (map[string][]PartitionAssignment{"topic-1": {{ID: 0, Offset: FirstOffset}, {ID: 1, Offset: 9}}}); !reflect.DeepEqual(gen.Assignments, want) {
+		t.Errorf("wrong assignments:\nwant = %+v\ngot  = %+v", want, gen.Assignments)
+	}
+
+	gen, err = group.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (map[string][]PartitionAssignment{"topic-1": {{ID: 0, Offset: FirstOffset}}}); !reflect.DeepEqual(gen.Assignments, want) {
+		t.Errorf("wrong assignments:\nwant = %+v\ngot  = %+v", want, gen.Assignments)
+	}
+
+	// the member epoch was bumped once the revocation was acknowledged.
+	gen, err = group.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen.ID != 2 {
+		t.Errorf("wrong generation id: %d", gen.ID)
+	}
+
+	group.Close()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	join := heartbeats[0]
+	if join.MemberEpoch != 0 || join.ServerAssignor != "uniform" || !reflect.DeepEqual(join.SubscribedTopicNames, []string{"topic-1"}) {
+		t.Errorf("wrong join heartbeat: %+v", join)
+	}
+	if ack := heartbeats[1].TopicPartitions; len(ack) != 1 || !reflect.DeepEqual(ack[0].Partitions, []int32{0, 1}) {
+		t.Errorf("the first assignment was not acknowledged: %+v", ack)
+	}
+	if ack := heartbeats[2].TopicPartitions; len(ack) != 1 || !reflect.DeepEqual(ack[0].Partitions, []int32{0}) {
+		t.Errorf("the revocation was not acknowledged: %+v", ack)
+	}
+	if leave := heartbeats[len(heartbeats)-1]; leave.MemberEpoch != -1 || leave.MemberID != "member-1" {
+		t.Errorf("the member did not leave the group: %+v", leave)
+	}
+	if want := []GroupEventType{
+		GroupJoining, GroupStable, GroupRebalancing,
+		GroupJoining, GroupStable, GroupRebalancing,
+		GroupJoining, GroupStable, GroupLeaving,
+	}; !reflect.DeepEqual(events, want) {
+		t.Errorf("wrong events:\nwant = %v\ngot  = %v", want, events)
+	}
+}
+
+func TestConsumerGroupProtocolFallback(t *testing.T) {
+	client := newMockConsumerGroupClient(false, func(*consumergroupheartbeat.Request) *consumergroupheartbeat.Response {
+		t.Error("unexpected consumer group heartbeat")
+		return &consumergroupheartbeat.Response{}
+	})
+
+	joined := make(chan struct{}, 1)
+	mc := mockCoordinator{
+		findCoordinatorFunc: func(findCoordinatorRequestV0) (findCoordinatorResponseV0, error) {
+			return findCoordinatorResponseV0{}, nil
+		},
+		joinGroupFunc: func(joinGroupRequestV1) (joinGroupResponseV1, error) {
+			select {
+			case joined <- struct{}{}:
+			default:
+			}
+			return joinGroupResponseV1{}, errors.New("join group")
+		},
+		leaveGroupFunc: func(leaveGroupRequestV0) (leaveGroupResponseV0, error) {
+			return leaveGroupResponseV0{}, nil
+		},
+	}
+
+	group, err := NewConsumerGroup(ConsumerGroupConfig{
+		ID:            "group-1",
+		Brokers:       []string{"mock"},
+		Topics:        []string{"topic-1"},
+		GroupProtocol: GroupProtocolConsumer,
+		client:        client,
+		connect: func(*Dialer, ...string) (coordinator, error) {
+			return mc, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer group.Close()
+
+	select {
+	case <-joined:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the consumer group did not fall back to the classic protocol")
+	}
+}
+
+func TestSameConsumerAssignment(t *testing.T) {
+	a := []ConsumerGroupTopicPartitions{{TopicID: UUID{1}, Partitions: []int{0, 1}}}
+	b := []ConsumerGroupTopicPartitions{{TopicID: UUID{1}, Partitions: []int{1, 0}}}
+	c := []ConsumerGroupTopicPartitions{{TopicID: UUID{2}, Partitions: []int{0, 1}}}
+
+	if !sameConsumerAssignment(a, b) {
+		t.Error("assignments with partitions in different orders must be equal")
+	}
+	if sameConsumerAssignment(a, c) {
+		t.Error("assignments of different topics must not be equal")
+	}
+	if sameConsumerAssignment(nil, []ConsumerGroupTopicPartitions{}) {
+		t.Error("an empty assignment must not be equal to no assignment")
+	}
+}
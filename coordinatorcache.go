@@ -0,0 +1,298 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/segmentio/kafka-go/protocol/addoffsetstotxn"
+	"github.com/segmentio/kafka-go/protocol/addpartitionstotxn"
+	"github.com/segmentio/kafka-go/protocol/consumergroupdescribe"
+	"github.com/segmentio/kafka-go/protocol/consumergroupheartbeat"
+	"github.com/segmentio/kafka-go/protocol/describegroups"
+	"github.com/segmentio/kafka-go/protocol/describetransactions"
+	"github.com/segmentio/kafka-go/protocol/endtxn"
+	"github.com/segmentio/kafka-go/protocol/findcoordinator"
+	"github.com/segmentio/kafka-go/protocol/initproducerid"
+	"github.com/segmentio/kafka-go/protocol/offsetcommit"
+	"github.com/segmentio/kafka-go/protocol/offsetdelete"
+	"github.com/segmentio/kafka-go/protocol/offsetfetch"
+	"github.com/segmentio/kafka-go/protocol/txnoffsetcommit"
+)
+
+// coordinatorKey identifies the coordinator of a consumer group or of a
+// transactional id.
+type coordinatorKey struct {
+	keyType CoordinatorKeyType
+	key     string
+}
+
+// coordinatorCache caches the brokers coordinating the groups and transactions
+// that the requests sent through a connection pool are routed to, so programs
+// using many groups or transactional ids do not look up coordinators for each
+// request. Concurrent lookups of the same key share a single FindCoordinator
+// request, and keys of the same type looked up while a request is in flight
+// are batched into the next one, which brokers supporting v4 of the API answer
+// with a single response.
+//
+// Entries are invalidated when requests to a coordinator fail, or when the
+// broker responds that it is not the coordinator.
+type coordinatorCache struct {
+	mutex   sync.Mutex
+	brokers map[coordinatorKey]int32
+	lookups map[coordinatorKey]*coordinatorLookup
+	// Keys waiting for the FindCoordinator request in flight for their type
+	// to complete before being looked up.
+	queued   map[CoordinatorKeyType][]coordinatorKey
+	inflight map[CoordinatorKeyType]chan struct{}
+}
+
+// coordinatorLookup is a FindCoordinator request in progress.
+type coordinatorLookup struct {
+	done   chan struct{}
+	broker int32
+	err    error
+}
+
+func (c *coordinatorCache) invalidate(key coordinatorKey) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.brokers, key)
+}
+
+// resolve completes the lookup of key, caching the broker when cache is true.
+// The mutex must be held.
+func (c *coordinatorCache) resolve(key coordinatorKey, broker int32, cache bool, err error) {
+	if cache {
+		if c.brokers == nil {
+			c.brokers = make(map[coordinatorKey]int32)
+		}
+		c.brokers[key] = broker
+	}
+	if l := c.lookups[key]; l != nil {
+		delete(c.lookups, key)
+		l.broker, l.err = broker, err
+		close(l.done)
+	}
+}
+
+// coordinator returns the id of the broker coordinating key, looking it up with
+// a FindCoordinator request when it is not cached.
+//
+// The lookup is sent right away when no other lookup of the same key type is
+// in flight. Otherwise the key is queued, and the queued keys are sent in a
+// single request by the first caller observing that the in-flight request has
+// completed.
+func (p *connPool) coordinator(ctx context.Context, key coordinatorKey, state connPoolState) (int32, error) {
+	c := &p.coordinators
+
+	for {
+		c.mutex.Lock()
+		if broker, ok := c.brokers[key]; ok {
+			c.mutex.Unlock()
+			return broker, nil
+		}
+
+		l := c.lookups[key]
+		if l == nil {
+			l = &coordinatorLookup{done: make(chan struct{})}
+			if c.lookups == nil {
+				c.lookups = make(map[coordinatorKey]*coordinatorLookup)
+			}
+			if c.queued == nil {
+				c.queued = make(map[CoordinatorKeyType][]coordinatorKey)
+			}
+			c.lookups[key] = l
+			c.queued[key.keyType] = append(c.queued[key.keyType], key)
+		}
+
+		inflight := c.inflight[key.keyType]
+		if inflight == nil {
+			// No request is in flight for this key type, so the pending
+			// lookup of key, if any, is still queued: send the queued keys.
+			keys := c.queued[key.keyType]
+			delete(c.queued, key.keyType)
+			inflight = make(chan struct{})
+			if c.inflight == nil {
+				c.inflight = make(map[CoordinatorKeyType]chan struct{})
+			}
+			c.inflight[key.keyType] = inflight
+			c.mutex.Unlock()
+
+			p.findCoordinators(ctx, key.keyType, keys, state)
+
+			c.mutex.Lock()
+			delete(c.inflight, key.keyType)
+			close(inflight)
+		}
+		c.mutex.Unlock()
+
+		select {
+		case <-l.done:
+		case <-inflight:
+			// The request completed without resolving key, which was queued
+			// after it was sent; the next iteration sends the queued keys.
+			select {
+			case <-l.done:
+			default:
+				continue
+			}
+		case <-ctx.Done():
+			return -1, ctx.Err()
+		}
+
+		// The lookup may have been interrupted by the context of the request
+		// which started it, in which case it is attempted again.
+		if l.err != nil && (errors.Is(l.err, context.Canceled) || errors.Is(l.err, context.DeadlineExceeded)) {
+			continue
+		}
+		return l.broker, l.err
+	}
+}
+
+// findCoordinators sends FindCoordinator requests for keys and completes their
+// lookups. Brokers supporting v4 of the API return the coordinators of all
+// keys in one response, earlier versions are sent one request per key.
+//
+// Brokers are only cached when the lookup succeeded, otherwise the broker id
+// returned in the response is used once, which routes the request to any
+// broker when the coordinator is not available.
+func (p *connPool) findCoordinators(ctx context.Context, keyType CoordinatorKeyType, keys []coordinatorKey, state connPoolState) {
+	c := &p.coordinators
+
+	for len(keys) != 0 {
+		names := make([]string, len(keys))
+		for i, key := range keys {
+			names[i] = key.key
+		}
+
+		r, err := p.sendRequest(ctx, &findcoordinator.Request{
+			Key:             names[0],
+			KeyType:         int8(keyType),
+			CoordinatorKeys: names,
+		}, state).await(ctx)
+
+		c.mutex.Lock()
+		if err != nil {
+			for _, key := range keys {
+				c.resolve(key, -1, false, err)
+			}
+			c.mutex.Unlock()
+			return
+		}
+
+		res := r.(*findcoordinator.Response)
+		resolved := keys
+		if res.Coordinators == nil {
+			resolved, keys = keys[:1], keys[1:]
+		} else {
+			keys = nil
+		}
+
+		for _, key := range resolved {
+			if co, ok := res.Coordinator(key.key); ok {
+				c.resolve(key, co.NodeID, co.ErrorCode == 0 && co.NodeID >= 0, nil)
+			} else {
+				c.resolve(key, -1, false, nil)
+			}
+		}
+		c.mutex.Unlock()
+	}
+}
+
+// coordinatorPromise invalidates the cached coordinator of key when the
+// request fails, or when the broker is no longer the coordinator.
+type coordinatorPromise struct {
+	promise
+	cache *coordinatorCache
+	key   coordinatorKey
+}
+
+func (p *coordinatorPromise) await(ctx context.Context) (Response, error) {
+	r, err := p.promise.await(ctx)
+	if (err != nil && ctx.Err() == nil) || (err == nil && notCoordinator(r)) {
+		p.cache.invalidate(p.key)
+	}
+	return r, err
+}
+
+func isCoordinatorError(errorCode int16) bool {
+	switch Error(errorCode) {
+	case NotCoordinatorForGroup, GroupCoordinatorNotAvailable:
+		return true
+	default:
+		return false
+	}
+}
+
+// notCoordinator returns true if the response of a request routed to a group
+// or transaction coordinator reports that the broker is not, or no longer, the
+// coordinator.
+func notCoordinator(res Response) bool {
+	switch r := res.(type) {
+	case *addoffsetstotxn.Response:
+		return isCoordinatorError(r.ErrorCode)
+	case *addpartitionstotxn.Response:
+		for _, t := range r.Results {
+			for _, p := range t.Results {
+				if isCoordinatorError(p.ErrorCode) {
+					return true
+				}
+			}
+		}
+	case *consumergroupdescribe.Response:
+		for _, g := range r.Groups {
+			if isCoordinatorError(g.ErrorCode) {
+				return true
+			}
+		}
+	case *consumergroupheartbeat.Response:
+		return isCoordinatorError(r.ErrorCode)
+	case *describegroups.Response:
+		for _, g := range r.Groups {
+			if isCoordinatorError(g.ErrorCode) {
+				return true
+			}
+		}
+	case *describetransactions.Response:
+		for _, t := range r.TransactionStates {
+			if isCoordinatorError(t.ErrorCode) {
+				return true
+			}
+		}
+	case *endtxn.Response:
+		return isCoordinatorError(r.ErrorCode)
+	case *initproducerid.Response:
+		return isCoordinatorError(r.ErrorCode)
+	case *offsetcommit.Response:
+		for _, t := range r.Topics {
+			for _, p := range t.Partitions {
+				if isCoordinatorError(p.ErrorCode) {
+					return true
+				}
+			}
+		}
+	case *offsetdelete.Response:
+		return isCoordinatorError(r.ErrorCode)
+	case *offsetfetch.Response:
+		if isCoordinatorError(r.ErrorCode) {
+			return true
+		}
+		for _, t := range r.Topics {
+			for _, p := range t.Partitions {
+				if isCoordinatorError(p.ErrorCode) {
+					return true
+				}
+			}
+		}
+	case *txnoffsetcommit.Response:
+		for _, t := range r.Topics {
+			for _, p := range t.Partitions {
+				if isCoordinatorError(p.ErrorCode) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
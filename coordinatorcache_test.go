@@ -0,0 +1,146 @@
+package kafka_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+// findCoordinatorObserver records the versions of the FindCoordinator requests
+// sent by a transport.
+type findCoordinatorObserver struct {
+	mutex    sync.Mutex
+	versions []int16
+}
+
+func (o *findCoordinatorObserver) ObserveRoundTrip(info protocol.RoundTripInfo) {
+	if info.ApiKey == protocol.FindCoordinator {
+		o.mutex.Lock()
+		o.versions = append(o.versions, info.ApiVersion)
+		o.mutex.Unlock()
+	}
+}
+
+func (o *findCoordinatorObserver) requests() []int16 {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return append([]int16(nil), o.versions...)
+}
+
+func newCoordinatorClient(t *testing.T) (*kafka.Client, *findCoordinatorObserver) {
+	t.Helper()
+	b := kafkatest.NewTestBroker(t)
+
+	observer := &findCoordinatorObserver{}
+	transport := &kafka.Transport{RoundTripObserver: observer}
+	t.Cleanup(transport.CloseIdleConnections)
+
+	return &kafka.Client{Addr: b.Addr(), Transport: transport}, observer
+}
+
+func TestClientFindCoordinatorKeys(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, observer := newCoordinatorClient(t)
+
+	res, err := client.FindCoordinator(ctx, &kafka.FindCoordinatorRequest{
+		Keys:    []string{"group-A", "group-B", "group-C"},
+		KeyType: kafka.CoordinatorKeyTypeConsumer,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Keys) != 3 {
+		t.Fatalf("wrong number of coordinators: %+v", res.Keys)
+	}
+	for key, c := range res.Keys {
+		if c.Error != nil {
+			t.Errorf("%s: %v", key, c.Error)
+		} else if c.Coordinator == nil || c.Coordinator.NodeID != 1 {
+			t.Errorf("%s: wrong coordinator %+v", key, c.Coordinator)
+		}
+	}
+
+	if versions := observer.requests(); len(versions) != 1 || versions[0] != 4 {
+		t.Errorf("the keys must be looked up in a single v4 request: %v", versions)
+	}
+}
+
+func TestTransportCoordinatorCache(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, observer := newCoordinatorClient(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		group := "group-A"
+		if i%2 != 0 {
+			group = "group-B"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+				GroupID: group,
+				Topics:  map[string][]int{"topic-A": {0}},
+			})
+			if err != nil {
+				t.Error(err)
+			} else if res.Error != nil {
+				t.Error(res.Error)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if versions := observer.requests(); len(versions) == 0 || len(versions) > 2 {
+		t.Errorf("the coordinator of each group must be looked up once, got %d lookups", len(versions))
+	}
+}
+
+func TestTransportCoordinatorLookupBatching(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, observer := newCoordinatorClient(t)
+
+	const groups = 20
+	var wg sync.WaitGroup
+	for i := 0; i < groups; i++ {
+		group := fmt.Sprintf("group-%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+				GroupID: group,
+				Topics:  map[string][]int{"topic-A": {0}},
+			})
+			if err != nil {
+				t.Error(err)
+			} else if res.Error != nil {
+				t.Error(res.Error)
+			}
+		}()
+	}
+	wg.Wait()
+
+	versions := observer.requests()
+	if len(versions) == 0 || len(versions) >= groups {
+		t.Errorf("concurrent lookups must be batched, got %d lookups for %d groups", len(versions), groups)
+	}
+	for _, v := range versions {
+		if v != 4 {
+			t.Errorf("lookups must use v4 requests: %v", versions)
+			break
+		}
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"hash/crc32"
 )
 
+// castagnoliTable is the table of the CRC32-C checksums of v2 message sets.
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
 type crc32Writer struct {
 	table  *crc32.Table
 	buffer [8]byte
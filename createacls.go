@@ -2,6 +2,7 @@ package kafka
 
 import (
 	"context"
+	"encoding"
 	"fmt"
 	"net"
 	"time"
@@ -60,6 +61,58 @@ const (
 	ACLOperationTypeIdempotentWrite ACLOperationType = 12
 )
 
+var aclPermissionTypeStrings = [...]string{
+	ACLPermissionTypeUnknown: "UNKNOWN",
+	ACLPermissionTypeAny:     "ANY",
+	ACLPermissionTypeDeny:    "DENY",
+	ACLPermissionTypeAllow:   "ALLOW",
+}
+
+func (t ACLPermissionType) String() string { return enumString(aclPermissionTypeStrings[:], int8(t)) }
+
+func (t ACLPermissionType) MarshalText() ([]byte, error) { return []byte(t.String()), nil }
+
+func (t *ACLPermissionType) UnmarshalText(b []byte) error {
+	i, err := parseEnum(aclPermissionTypeStrings[:], "permission type", b)
+	*t = ACLPermissionType(i)
+	return err
+}
+
+var aclOperationTypeStrings = [...]string{
+	ACLOperationTypeUnknown:         "UNKNOWN",
+	ACLOperationTypeAny:             "ANY",
+	ACLOperationTypeAll:             "ALL",
+	ACLOperationTypeRead:            "READ",
+	ACLOperationTypeWrite:           "WRITE",
+	ACLOperationTypeCreate:          "CREATE",
+	ACLOperationTypeDelete:          "DELETE",
+	ACLOperationTypeAlter:           "ALTER",
+	ACLOperationTypeDescribe:        "DESCRIBE",
+	ACLOperationTypeClusterAction:   "CLUSTER_ACTION",
+	ACLOperationTypeDescribeConfigs: "DESCRIBE_CONFIGS",
+	ACLOperationTypeAlterConfigs:    "ALTER_CONFIGS",
+	ACLOperationTypeIdempotentWrite: "IDEMPOTENT_WRITE",
+}
+
+func (t ACLOperationType) String() string { return enumString(aclOperationTypeStrings[:], int8(t)) }
+
+func (t ACLOperationType) MarshalText() ([]byte, error) { return []byte(t.String()), nil }
+
+func (t *ACLOperationType) UnmarshalText(b []byte) error {
+	i, err := parseEnum(aclOperationTypeStrings[:], "operation type", b)
+	*t = ACLOperationType(i)
+	return err
+}
+
+var (
+	_ encoding.TextMarshaler   = ACLPermissionType(0)
+	_ encoding.TextUnmarshaler = (*ACLPermissionType)(nil)
+	_ encoding.TextMarshaler   = ACLOperationType(0)
+	_ encoding.TextUnmarshaler = (*ACLOperationType)(nil)
+)
+
+// ACLEntry represents a single access control entry bound to a resource
+// pattern.
 type ACLEntry struct {
 	ResourceType        ResourceType
 	ResourceName        string
@@ -0,0 +1,125 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/createdelegationtoken"
+)
+
+// DelegationTokenPrincipal represents a kafka principal that owns or is allowed
+// to renew delegation tokens.
+type DelegationTokenPrincipal struct {
+	// The type of the principal, usually "User".
+	Type string
+
+	// The name of the principal.
+	Name string
+}
+
+// DelegationToken represents a delegation token issued by kafka brokers.
+//
+// Delegation tokens may be used to authenticate with the SCRAM mechanisms of
+// the brokers, see the sasl/scram package for details.
+type DelegationToken struct {
+	// The principal that owns the token.
+	Owner DelegationTokenPrincipal
+
+	// List of principals which are allowed to renew the token.
+	Renewers []DelegationTokenPrincipal
+
+	// Unique identifier of the token.
+	TokenID string
+
+	// HMAC of the token, used as a password when authenticating.
+	HMAC []byte
+
+	// The time at which the token was issued.
+	IssueTime time.Time
+
+	// The time at which the token expires, unless it gets renewed.
+	ExpiryTime time.Time
+
+	// The time after which the token cannot be renewed anymore.
+	MaxTime time.Time
+}
+
+// CreateDelegationTokenRequest represents a request sent to a kafka broker to
+// create a delegation token.
+type CreateDelegationTokenRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// List of principals which are allowed to renew the token.
+	Renewers []DelegationTokenPrincipal
+
+	// Maximum lifetime of the token, if zero the maximum lifetime configured
+	// on the brokers is used.
+	MaxLifetime time.Duration
+}
+
+// CreateDelegationTokenResponse represents a response from a kafka broker to a
+// delegation token creation request.
+type CreateDelegationTokenResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// An error that may have occurred while attempting to create the token.
+	//
+	// The error contains both the kafka error code, and an error message
+	// returned by the kafka broker. Programs may use the standard errors.Is
+	// function to test the error against kafka error codes.
+	Error error
+
+	// The created token.
+	Token DelegationToken
+}
+
+// CreateDelegationToken sends a delegation token creation request to a kafka
+// broker and returns the response.
+//
+// Note that kafka brokers only accept delegation token requests on connections
+// which were authenticated with SASL (but not with a delegation token) or TLS.
+func (c *Client) CreateDelegationToken(ctx context.Context, req *CreateDelegationTokenRequest) (*CreateDelegationTokenResponse, error) {
+	renewers := make([]createdelegationtoken.RequestRenewer, len(req.Renewers))
+
+	for i, r := range req.Renewers {
+		renewers[i] = createdelegationtoken.RequestRenewer{
+			PrincipalType: r.Type,
+			PrincipalName: r.Name,
+		}
+	}
+
+	maxLifetimeMs := int64(-1)
+	if req.MaxLifetime > 0 {
+		maxLifetimeMs = int64(req.MaxLifetime / time.Millisecond)
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &createdelegationtoken.Request{
+		Renewers:      renewers,
+		MaxLifetimeMs: maxLifetimeMs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).CreateDelegationToken: %w", err)
+	}
+
+	res := m.(*createdelegationtoken.Response)
+	return &CreateDelegationTokenResponse{
+		Throttle: makeDuration(res.ThrottleTimeMs),
+		Error:    makeError(res.ErrorCode, ""),
+		Token: DelegationToken{
+			Owner: DelegationTokenPrincipal{
+				Type: res.PrincipalType,
+				Name: res.PrincipalName,
+			},
+			Renewers:   req.Renewers,
+			TokenID:    res.TokenID,
+			HMAC:       res.HMAC,
+			IssueTime:  makeTime(res.IssueTimestampMs),
+			ExpiryTime: makeTime(res.ExpiryTimestampMs),
+			MaxTime:    makeTime(res.MaxTimestampMs),
+		},
+	}, nil
+}
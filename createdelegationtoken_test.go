@@ -0,0 +1,46 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ktesting "github.com/segmentio/kafka-go/testing"
+)
+
+func TestClientCreateDelegationToken(t *testing.T) {
+	if !ktesting.KafkaIsAtLeast("2.4.0") {
+		return
+	}
+
+	ctx := context.Background()
+	client, shutdown := newLocalClient()
+	defer shutdown()
+
+	res, err := client.CreateDelegationToken(ctx, &CreateDelegationTokenRequest{
+		Renewers: []DelegationTokenPrincipal{
+			{Type: "User", Name: "adminscram"},
+		},
+		MaxLifetime: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The local kafka broker does not have delegation tokens enabled, and the
+	// client connects over a plaintext listener, so the broker is expected to
+	// refuse creating the token.
+	if !errors.Is(res.Error, DelegationTokenAuthDisabled) && !errors.Is(res.Error, DelegationTokenRequestNotAllowed) {
+		t.Fatalf("expected the broker to refuse creating delegation tokens; got %v", res.Error)
+	}
+
+	describeRes, err := client.DescribeDelegationToken(ctx, &DescribeDelegationTokenRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if describeRes.Error == nil && len(describeRes.Tokens) != 0 {
+		t.Fatalf("expected no delegation tokens; got %d", len(describeRes.Tokens))
+	}
+}
@@ -2,6 +2,7 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"time"
@@ -43,6 +44,9 @@ func (c *Client) CreatePartitions(ctx context.Context, req *CreatePartitionsRequ
 	topics := make([]createpartitions.RequestTopic, len(req.Topics))
 
 	for i, t := range req.Topics {
+		if err := t.validate(); err != nil {
+			return nil, fmt.Errorf("kafka.(*Client).CreatePartitions: %w", err)
+		}
 		topics[i] = createpartitions.RequestTopic{
 			Name:        t.Name,
 			Count:       t.Count,
@@ -81,9 +85,39 @@ type TopicPartitionsConfig struct {
 	Count int32
 
 	// TopicPartitionAssignments among kafka brokers for this topic partitions.
+	//
+	// When set, there must be one assignment for each partition added to the
+	// topic, listing the broker IDs of its replicas with the preferred leader
+	// first. All assignments must have the same number of replicas. When
+	// empty, the brokers choose the assignments.
+	//
+	// Brokers reject the topics which do not have one assignment for each
+	// new partition with an InvalidReplicaAssignment error, reported in the
+	// Errors field of the response.
 	TopicPartitionAssignments []TopicPartitionAssignment
 }
 
+func (t *TopicPartitionsConfig) validate() error {
+	for i, a := range t.TopicPartitionAssignments {
+		if len(a.BrokerIDs) == 0 {
+			return fmt.Errorf("assignment %d of topic %q has no replicas", i, t.Name)
+		}
+
+		if n := len(t.TopicPartitionAssignments[0].BrokerIDs); len(a.BrokerIDs) != n {
+			return fmt.Errorf("assignment %d of topic %q has %d replicas instead of %d", i, t.Name, len(a.BrokerIDs), n)
+		}
+
+		for j, id := range a.BrokerIDs {
+			for _, other := range a.BrokerIDs[:j] {
+				if id == other {
+					return fmt.Errorf("assignment %d of topic %q has broker %d more than once", i, t.Name, id)
+				}
+			}
+		}
+	}
+	return nil
+}
+
 func (t *TopicPartitionsConfig) assignments() []createpartitions.RequestAssignment {
 	if len(t.TopicPartitionAssignments) == 0 {
 		return nil
@@ -101,3 +135,39 @@ type TopicPartitionAssignment struct {
 	// Broker IDs
 	BrokerIDs []int32
 }
+
+// RoundRobinPartitionAssignments generates replica assignments for count new
+// partitions of a topic, starting at partition index firstPartition, spread
+// across the given brokers in a round-robin fashion.
+//
+// The assignments only depend on the arguments, which lets capacity tooling
+// grow topics deterministically. The broker IDs are used in the order they
+// are given, and the replication factor is capped to the number of brokers.
+// An error is returned if brokerIDs is empty, count is negative, or
+// replicationFactor is lower than 1.
+func RoundRobinPartitionAssignments(brokerIDs []int32, firstPartition, count, replicationFactor int) ([]TopicPartitionAssignment, error) {
+	switch {
+	case len(brokerIDs) == 0:
+		return nil, errors.New("kafka.RoundRobinPartitionAssignments: no broker ids")
+	case count < 0:
+		return nil, fmt.Errorf("kafka.RoundRobinPartitionAssignments: negative partition count: %d", count)
+	case replicationFactor < 1:
+		return nil, fmt.Errorf("kafka.RoundRobinPartitionAssignments: replication factor must be at least 1: %d", replicationFactor)
+	}
+
+	if replicationFactor > len(brokerIDs) {
+		replicationFactor = len(brokerIDs)
+	}
+
+	assignments := make([]TopicPartitionAssignment, count)
+
+	for i := range assignments {
+		replicas := make([]int32, replicationFactor)
+		for j := range replicas {
+			replicas[j] = brokerIDs[(firstPartition+i+j)%len(brokerIDs)]
+		}
+		assignments[i].BrokerIDs = replicas
+	}
+
+	return assignments, nil
+}
@@ -2,8 +2,13 @@ package kafka
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
 	"testing"
 
+	"github.com/segmentio/kafka-go/protocol/createpartitions"
 	ktesting "github.com/segmentio/kafka-go/testing"
 )
 
@@ -71,3 +76,155 @@ func TestClientCreatePartitionsNoAssignments(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestRoundRobinPartitionAssignments(t *testing.T) {
+	assignments, err := RoundRobinPartitionAssignments([]int32{1, 2, 3}, 2, 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []TopicPartitionAssignment{
+		{BrokerIDs: []int32{3, 1}},
+		{BrokerIDs: []int32{1, 2}},
+		{BrokerIDs: []int32{2, 3}},
+		{BrokerIDs: []int32{3, 1}},
+	}
+
+	if !reflect.DeepEqual(assignments, expected) {
+		t.Errorf("unexpected assignments\nwant: %v\ngot:  %v", expected, assignments)
+	}
+}
+
+func TestRoundRobinPartitionAssignmentsInvalid(t *testing.T) {
+	tests := []struct {
+		scenario          string
+		brokerIDs         []int32
+		count             int
+		replicationFactor int
+	}{
+		{scenario: "no brokers", brokerIDs: nil, count: 1, replicationFactor: 1},
+		{scenario: "negative count", brokerIDs: []int32{1}, count: -1, replicationFactor: 1},
+		{scenario: "no replicas", brokerIDs: []int32{1}, count: 1, replicationFactor: 0},
+		{scenario: "negative replication factor", brokerIDs: []int32{1}, count: 1, replicationFactor: -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			if _, err := RoundRobinPartitionAssignments(test.brokerIDs, 0, test.count, test.replicationFactor); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestClientCreatePartitionsAssignmentsCount(t *testing.T) {
+	client := &Client{
+		Addr: TCP("mock"),
+		Transport: roundTripperFunc(func(ctx context.Context, addr net.Addr, req Request) (Response, error) {
+			switch req := req.(type) {
+			case *createpartitions.Request:
+				// The topic has 2 partitions, the broker verifies that there
+				// is one assignment for each new partition.
+				res := &createpartitions.Response{}
+				for _, t := range req.Topics {
+					r := createpartitions.ResponseResult{Name: t.Name}
+					if len(t.Assignments) != int(t.Count)-2 {
+						r.ErrorCode = int16(InvalidReplicaAssignment)
+					}
+					res.Results = append(res.Results, r)
+				}
+				return res, nil
+			}
+			return nil, fmt.Errorf("unexpected request %T", req)
+		}),
+	}
+
+	create := func(count int32, assignments int) error {
+		a, err := RoundRobinPartitionAssignments([]int32{1, 2}, 2, assignments, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := client.CreatePartitions(context.Background(), &CreatePartitionsRequest{
+			Topics: []TopicPartitionsConfig{
+				{Name: "topic-A", Count: count, TopicPartitionAssignments: a},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Errors["topic-A"]
+	}
+
+	if err := create(4, 3); !errors.Is(err, InvalidReplicaAssignment) {
+		t.Errorf("expected an InvalidReplicaAssignment error for 3 assignments of 2 new partitions, got %v", err)
+	}
+	if err := create(4, 2); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClientCreatePartitionsInvalidAssignments(t *testing.T) {
+	client := &Client{Addr: TCP("127.0.0.1:1")}
+
+	for _, assignments := range [][]TopicPartitionAssignment{
+		{{BrokerIDs: []int32{1, 2}}, {BrokerIDs: []int32{1}}},
+		{{BrokerIDs: []int32{1, 1}}},
+		{{BrokerIDs: nil}},
+	} {
+		_, err := client.CreatePartitions(context.Background(), &CreatePartitionsRequest{
+			Topics: []TopicPartitionsConfig{
+				{
+					Name:                      "topic",
+					Count:                     int32(1 + len(assignments)),
+					TopicPartitionAssignments: assignments,
+				},
+			},
+		})
+		if err == nil {
+			t.Errorf("expected an error for invalid assignments %v", assignments)
+		}
+	}
+}
+
+func TestClientCreatePartitionsValidateOnly(t *testing.T) {
+	if !ktesting.KafkaIsAtLeast("1.0.1") {
+		return
+	}
+
+	client, shutdown := newLocalClient()
+	defer shutdown()
+
+	topic := makeTopic()
+	createTopic(t, topic, 1)
+	defer deleteTopic(t, topic)
+
+	assignments, err := RoundRobinPartitionAssignments([]int32{1}, 1, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.CreatePartitions(context.Background(), &CreatePartitionsRequest{
+		Topics: []TopicPartitionsConfig{
+			{
+				Name:                      topic,
+				Count:                     3,
+				TopicPartitionAssignments: assignments,
+			},
+		},
+		ValidateOnly: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := res.Errors[topic]; err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := client.Metadata(context.Background(), &MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(meta.Topics[0].Partitions); n != 1 {
+		t.Errorf("validate only request created partitions: %d", n)
+	}
+}
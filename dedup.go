@@ -0,0 +1,169 @@
+package kafka
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DedupStore is the interface of stores used by a Deduplicator to remember
+// the keys of the messages that a reader returned.
+//
+// Stores must be safe to use concurrently from multiple goroutines.
+type DedupStore interface {
+	// Seen records that a message with the key was read at time t, and
+	// reports whether a message with the same key was already read within the
+	// window of the store.
+	Seen(key string, t time.Time) bool
+}
+
+// Deduplicator configures a Reader to skip the messages which carry the same
+// deduplication key as a message that the reader returned recently. It is
+// intended for pipelines consuming topics written by producers which cannot
+// be made idempotent, and which may write the same message more than once.
+//
+// The deduplication key of a message is the value of Header, or the key of
+// the message when Header is empty. Messages without a deduplication key are
+// never skipped.
+//
+// Deduplication only applies to the messages read by a single Reader, the
+// default store is in memory and is lost when the program restarts. Skipped
+// messages are not committed, their offsets are committed with the next
+// message that the program commits.
+type Deduplicator struct {
+	// The name of the header carrying the deduplication key of messages.
+	//
+	// Default: the message key is used
+	Header string
+
+	// The time window within which messages with the same key are duplicates.
+	// When zero, the window is only bounded by Size.
+	Window time.Duration
+
+	// The maximum number of keys remembered by the default store, the least
+	// recently read keys are forgotten first.
+	//
+	// Default: 10000
+	Size int
+
+	// The store of the keys of messages.
+	//
+	// Default: NewLRUDedupStore(Size, Window)
+	Store DedupStore
+}
+
+const defaultDedupSize = 10000
+
+func (d *Deduplicator) store() DedupStore {
+	if d.Store != nil {
+		return d.Store
+	}
+	return NewLRUDedupStore(d.Size, d.Window)
+}
+
+// key returns the deduplication key of msg, and whether it had one.
+func (d *Deduplicator) key(msg Message) (string, bool) {
+	if d.Header != "" {
+		value, ok := msg.Header(d.Header)
+		return string(value), ok
+	}
+	return string(msg.Key), msg.Key != nil
+}
+
+// dedupFilter is the state of the deduplicator of a Reader.
+type dedupFilter struct {
+	config *Deduplicator
+	store  DedupStore
+}
+
+func newDedupFilter(d *Deduplicator) *dedupFilter {
+	if d == nil {
+		return nil
+	}
+	return &dedupFilter{config: d, store: d.store()}
+}
+
+// duplicate reports whether msg is a duplicate of a message read before.
+func (f *dedupFilter) duplicate(msg Message, now time.Time) bool {
+	key, ok := f.config.key(msg)
+	return ok && f.store.Seen(key, now)
+}
+
+// LRUDedupStore is an in-memory DedupStore remembering a bounded number of
+// keys, and forgetting the least recently read keys first.
+type LRUDedupStore struct {
+	size   int
+	window time.Duration
+	mutex  sync.Mutex
+	keys   *list.List
+	index  map[string]*list.Element
+}
+
+type lruDedupEntry struct {
+	key  string
+	time time.Time
+}
+
+// NewLRUDedupStore returns a store remembering up to size keys, for the
+// duration of window, or until they are evicted when window is zero. A size of
+// zero or less uses the default of 10000 keys.
+func NewLRUDedupStore(size int, window time.Duration) *LRUDedupStore {
+	if size <= 0 {
+		size = defaultDedupSize
+	}
+	return &LRUDedupStore{
+		size:   size,
+		window: window,
+		keys:   list.New(),
+		index:  make(map[string]*list.Element),
+	}
+}
+
+// Seen satisfies the DedupStore interface.
+//
+// The window of a key starts when it is first read, reading duplicates does
+// not extend it.
+func (s *LRUDedupStore) Seen(key string, t time.Time) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if elem := s.index[key]; elem != nil {
+		entry := elem.Value.(*lruDedupEntry)
+		if s.window == 0 || t.Sub(entry.time) < s.window {
+			s.keys.MoveToFront(elem)
+			return true
+		}
+		// The key expired, it is read again as a new key.
+		entry.time = t
+		s.keys.MoveToFront(elem)
+		return false
+	}
+
+	s.index[key] = s.keys.PushFront(&lruDedupEntry{key: key, time: t})
+
+	for s.keys.Len() > s.size {
+		s.remove(s.keys.Back())
+	}
+
+	// Drop the expired keys from the back of the list, they would be read as
+	// new keys anyway.
+	if s.window != 0 {
+		for elem := s.keys.Back(); elem != nil && t.Sub(elem.Value.(*lruDedupEntry).time) >= s.window; elem = s.keys.Back() {
+			s.remove(elem)
+		}
+	}
+
+	return false
+}
+
+// Len returns the number of keys remembered by the store.
+func (s *LRUDedupStore) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.keys.Len()
+}
+
+func (s *LRUDedupStore) remove(elem *list.Element) {
+	s.keys.Remove(elem)
+	delete(s.index, elem.Value.(*lruDedupEntry).key)
+}
@@ -0,0 +1,86 @@
+package kafka_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func TestLRUDedupStore(t *testing.T) {
+	now := time.Now()
+
+	t.Run("size", func(t *testing.T) {
+		s := kafka.NewLRUDedupStore(2, 0)
+		for _, key := range []string{"a", "b", "a", "c"} {
+			s.Seen(key, now)
+		}
+		// b was the least recently read key when c was added.
+		if s.Seen("b", now) {
+			t.Error("evicted keys must be read as new keys")
+		}
+		if !s.Seen("b", now) || s.Len() != 2 {
+			t.Errorf("wrong store state: %d keys", s.Len())
+		}
+	})
+
+	t.Run("window", func(t *testing.T) {
+		s := kafka.NewLRUDedupStore(10, time.Minute)
+		if s.Seen("a", now) {
+			t.Error("a new key must not be seen")
+		}
+		if !s.Seen("a", now.Add(59*time.Second)) {
+			t.Error("keys must be seen within the window")
+		}
+		if s.Seen("a", now.Add(time.Minute)) {
+			t.Error("keys must expire after the window")
+		}
+		s.Seen("b", now.Add(3*time.Minute))
+		if n := s.Len(); n != 1 {
+			t.Errorf("expired keys must be dropped: %d keys", n)
+		}
+	})
+}
+
+func TestReaderDeduplicator(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+	for i, id := range []string{"1", "2", "1", "", "3", "2", ""} {
+		record := kafkatest.Record{Value: []byte(fmt.Sprint(i))}
+		if id != "" {
+			record.Headers = []kafka.Header{{Key: "id", Value: []byte(id)}}
+		}
+		if _, err := b.Append("topic-A", 0, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:      []string{b.Addr().String()},
+		Topic:        "topic-A",
+		MaxWait:      10 * time.Millisecond,
+		Deduplicator: &kafka.Deduplicator{Header: "id"},
+	})
+	defer r.Close()
+
+	var values []string
+	for len(values) < 5 {
+		m, err := r.ReadMessage(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, string(m.Value))
+	}
+
+	if fmt.Sprint(values) != "[0 1 3 4 6]" {
+		t.Errorf("wrong messages: %v", values)
+	}
+	if n := r.Stats().Duplicates; n != 2 {
+		t.Errorf("wrong number of duplicates: %d", n)
+	}
+}
@@ -0,0 +1,116 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/deleteacls"
+)
+
+// DeleteACLsRequest represents a request sent to a kafka broker to delete
+// the ACLs matching a list of filters.
+type DeleteACLsRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// List of filters selecting the ACLs to delete. All ACLs matched by any
+	// of the filters are deleted.
+	Filters []ACLFilter
+}
+
+// DeleteACLsResponse represents a response from a kafka broker to an ACL
+// deletion request.
+type DeleteACLsResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// The results of the deletion, in the same order as the filters of the
+	// request.
+	Results []DeleteACLsResult
+}
+
+// DeleteACLsResult is the result of deleting the ACLs matching one filter.
+type DeleteACLsResult struct {
+	// Error is set to a non-nil value if the filter could not be applied.
+	//
+	// The error contains both the kafka error code, and an error message
+	// returned by the kafka broker. Programs may use the standard errors.Is
+	// function to test the error against kafka error codes.
+	Error error
+
+	// The ACLs that were matched by the filter.
+	MatchingACLs []DeleteACLsMatchingACL
+}
+
+// DeleteACLsMatchingACL is an ACL matched by a deletion filter.
+type DeleteACLsMatchingACL struct {
+	ACLEntry
+
+	// Error is set to a non-nil value if this ACL could not be deleted.
+	Error error
+}
+
+// DeleteACLs sends a request to a kafka broker to delete the ACLs matching the
+// filters and returns the response.
+func (c *Client) DeleteACLs(ctx context.Context, req *DeleteACLsRequest) (*DeleteACLsResponse, error) {
+	filters := make([]deleteacls.RequestFilter, 0, len(req.Filters))
+
+	for _, f := range req.Filters {
+		filters = append(filters, deleteacls.RequestFilter{
+			ResourceTypeFilter: int8(f.ResourceTypeFilter),
+			ResourceNameFilter: f.ResourceNameFilter,
+			PatternTypeFilter:  int8(f.ResourcePatternTypeFilter),
+			PrincipalFilter:    f.PrincipalFilter,
+			HostFilter:         f.HostFilter,
+			Operation:          int8(f.Operation),
+			PermissionType:     int8(f.PermissionType),
+		})
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &deleteacls.Request{
+		Filters: filters,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).DeleteACLs: %w", err)
+	}
+
+	res := m.(*deleteacls.Response)
+	ret := &DeleteACLsResponse{
+		Throttle: makeDuration(res.ThrottleTimeMs),
+		Results:  make([]DeleteACLsResult, 0, len(res.FilterResults)),
+	}
+
+	for _, r := range res.FilterResults {
+		result := DeleteACLsResult{
+			Error:        makeError(r.ErrorCode, r.ErrorMessage),
+			MatchingACLs: make([]DeleteACLsMatchingACL, 0, len(r.MatchingACLs)),
+		}
+
+		for _, acl := range r.MatchingACLs {
+			patternType := PatternType(acl.PatternType)
+			// Brokers which do not support pattern types only have literal ACLs.
+			if patternType == PatternTypeUnknown {
+				patternType = PatternTypeLiteral
+			}
+
+			result.MatchingACLs = append(result.MatchingACLs, DeleteACLsMatchingACL{
+				ACLEntry: ACLEntry{
+					ResourceType:        ResourceType(acl.ResourceType),
+					ResourceName:        acl.ResourceName,
+					ResourcePatternType: patternType,
+					Principal:           acl.Principal,
+					Host:                acl.Host,
+					Operation:           ACLOperationType(acl.Operation),
+					PermissionType:      ACLPermissionType(acl.PermissionType),
+				},
+				Error: makeError(acl.ErrorCode, acl.ErrorMessage),
+			})
+		}
+
+		ret.Results = append(ret.Results, result)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,299 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/describeacls"
+)
+
+// ACLFilter is a filter matching ACLs, used to describe or delete ACLs.
+//
+// Empty strings act as wildcards, matching any value of the corresponding
+// field. The zero value of the enum fields is the "unknown" type, which
+// kafka rejects, so programs should use NewACLFilter to construct a filter
+// matching any ACL and narrow it down with the builder methods, for example:
+//
+//	filter := kafka.NewACLFilter().
+//		TopicPrefix("orders-").
+//		WithPrincipal("User:alice").
+//		WithOperation(kafka.ACLOperationTypeRead).
+//		Allow()
+//
+// The builder methods return modified copies of the filter, the receiver is
+// never mutated.
+type ACLFilter struct {
+	ResourceTypeFilter        ResourceType
+	ResourceNameFilter        string
+	ResourcePatternTypeFilter PatternType
+	PrincipalFilter           string
+	HostFilter                string
+	Operation                 ACLOperationType
+	PermissionType            ACLPermissionType
+}
+
+// NewACLFilter returns a filter which matches any ACL.
+func NewACLFilter() ACLFilter {
+	return ACLFilter{
+		ResourceTypeFilter:        ResourceTypeAny,
+		ResourcePatternTypeFilter: PatternTypeAny,
+		Operation:                 ACLOperationTypeAny,
+		PermissionType:            ACLPermissionTypeAny,
+	}
+}
+
+// WithResource restricts the filter to ACLs on resources of the given type,
+// name and pattern type.
+func (f ACLFilter) WithResource(resourceType ResourceType, name string, patternType PatternType) ACLFilter {
+	f.ResourceTypeFilter = resourceType
+	f.ResourceNameFilter = name
+	f.ResourcePatternTypeFilter = patternType
+	return f
+}
+
+// Topic restricts the filter to ACLs on the topic with the given literal name.
+func (f ACLFilter) Topic(name string) ACLFilter {
+	return f.WithResource(ResourceTypeTopic, name, PatternTypeLiteral)
+}
+
+// TopicPrefix restricts the filter to prefixed ACLs on topics.
+func (f ACLFilter) TopicPrefix(prefix string) ACLFilter {
+	return f.WithResource(ResourceTypeTopic, prefix, PatternTypePrefixed)
+}
+
+// Group restricts the filter to ACLs on the group with the given literal name.
+func (f ACLFilter) Group(name string) ACLFilter {
+	return f.WithResource(ResourceTypeGroup, name, PatternTypeLiteral)
+}
+
+// GroupPrefix restricts the filter to prefixed ACLs on groups.
+func (f ACLFilter) GroupPrefix(prefix string) ACLFilter {
+	return f.WithResource(ResourceTypeGroup, prefix, PatternTypePrefixed)
+}
+
+// TransactionalID restricts the filter to ACLs on the given transactional id.
+func (f ACLFilter) TransactionalID(id string) ACLFilter {
+	return f.WithResource(ResourceTypeTransactionalID, id, PatternTypeLiteral)
+}
+
+// Cluster restricts the filter to ACLs on the cluster resource.
+func (f ACLFilter) Cluster() ACLFilter {
+	return f.WithResource(ResourceTypeCluster, "kafka-cluster", PatternTypeLiteral)
+}
+
+// Matching changes the pattern type of the filter to PatternTypeMatch, which
+// makes it match all the ACLs that apply to the resource name: the literal
+// ACLs on that name, the wildcard ACLs, and the prefixed ACLs whose prefix
+// matches the name.
+func (f ACLFilter) Matching() ACLFilter {
+	f.ResourcePatternTypeFilter = PatternTypeMatch
+	return f
+}
+
+// WithPrincipal restricts the filter to ACLs of the given principal
+// (e.g. "User:alice").
+func (f ACLFilter) WithPrincipal(principal string) ACLFilter {
+	f.PrincipalFilter = principal
+	return f
+}
+
+// WithHost restricts the filter to ACLs of the given host.
+func (f ACLFilter) WithHost(host string) ACLFilter {
+	f.HostFilter = host
+	return f
+}
+
+// WithOperation restricts the filter to ACLs of the given operation.
+func (f ACLFilter) WithOperation(operation ACLOperationType) ACLFilter {
+	f.Operation = operation
+	return f
+}
+
+// WithPermissionType restricts the filter to ACLs of the given permission
+// type.
+func (f ACLFilter) WithPermissionType(permissionType ACLPermissionType) ACLFilter {
+	f.PermissionType = permissionType
+	return f
+}
+
+// Allow restricts the filter to ACLs allowing access.
+func (f ACLFilter) Allow() ACLFilter { return f.WithPermissionType(ACLPermissionTypeAllow) }
+
+// Deny restricts the filter to ACLs denying access.
+func (f ACLFilter) Deny() ACLFilter { return f.WithPermissionType(ACLPermissionTypeDeny) }
+
+// Matches returns true if the ACL entry is matched by the filter, following
+// the same rules that kafka brokers apply.
+func (f ACLFilter) Matches(acl ACLEntry) bool {
+	if f.ResourceTypeFilter != ResourceTypeAny && f.ResourceTypeFilter != acl.ResourceType {
+		return false
+	}
+
+	switch f.ResourcePatternTypeFilter {
+	case PatternTypeAny, PatternTypeMatch, acl.ResourcePatternType:
+	default:
+		return false
+	}
+
+	if f.ResourceNameFilter != "" {
+		switch {
+		case f.ResourcePatternTypeFilter != PatternTypeMatch:
+			if f.ResourceNameFilter != acl.ResourceName {
+				return false
+			}
+		case acl.ResourcePatternType == PatternTypeLiteral:
+			if f.ResourceNameFilter != acl.ResourceName && acl.ResourceName != "*" {
+				return false
+			}
+		case acl.ResourcePatternType == PatternTypePrefixed:
+			if !strings.HasPrefix(f.ResourceNameFilter, acl.ResourceName) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return (f.PrincipalFilter == "" || f.PrincipalFilter == acl.Principal) &&
+		(f.HostFilter == "" || f.HostFilter == acl.Host) &&
+		(f.Operation == ACLOperationTypeAny || f.Operation == acl.Operation) &&
+		(f.PermissionType == ACLPermissionTypeAny || f.PermissionType == acl.PermissionType)
+}
+
+// Filter returns a filter matching exactly the ACL entry, which is useful to
+// delete a specific ACL.
+func (acl ACLEntry) Filter() ACLFilter {
+	return ACLFilter{
+		ResourceTypeFilter:        acl.ResourceType,
+		ResourceNameFilter:        acl.ResourceName,
+		ResourcePatternTypeFilter: acl.ResourcePatternType,
+		PrincipalFilter:           acl.Principal,
+		HostFilter:                acl.Host,
+		Operation:                 acl.Operation,
+		PermissionType:            acl.PermissionType,
+	}
+}
+
+// DescribeACLsRequest represents a request sent to a kafka broker to describe
+// the ACLs matching a filter.
+type DescribeACLsRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// The filter selecting the ACLs to describe.
+	Filter ACLFilter
+}
+
+// DescribeACLsResponse represents a response from a kafka broker to an ACL
+// describe request.
+type DescribeACLsResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// Error is set to a non-nil value if the describe failed.
+	//
+	// The error contains both the kafka error code, and an error message
+	// returned by the kafka broker. Programs may use the standard errors.Is
+	// function to test the error against kafka error codes.
+	Error error
+
+	// The ACLs matching the filter, grouped by resource.
+	Resources []ACLResource
+}
+
+// ACLResource is a resource pattern and the ACLs bound to it.
+type ACLResource struct {
+	ResourceType ResourceType
+	ResourceName string
+	PatternType  PatternType
+	ACLs         []ACLDescription
+}
+
+// ACLDescription is an access control entry of an ACLResource.
+type ACLDescription struct {
+	Principal      string
+	Host           string
+	Operation      ACLOperationType
+	PermissionType ACLPermissionType
+}
+
+// Entries flattens the resources of the response into a list of ACL entries,
+// which can for example be passed back to CreateACLs.
+func (r *DescribeACLsResponse) Entries() []ACLEntry {
+	n := 0
+	for _, res := range r.Resources {
+		n += len(res.ACLs)
+	}
+
+	entries := make([]ACLEntry, 0, n)
+
+	for _, res := range r.Resources {
+		for _, acl := range res.ACLs {
+			entries = append(entries, ACLEntry{
+				ResourceType:        res.ResourceType,
+				ResourceName:        res.ResourceName,
+				ResourcePatternType: res.PatternType,
+				Principal:           acl.Principal,
+				Host:                acl.Host,
+				Operation:           acl.Operation,
+				PermissionType:      acl.PermissionType,
+			})
+		}
+	}
+
+	return entries
+}
+
+// DescribeACLs sends a request to a kafka broker to describe the ACLs matching
+// a filter and returns the response.
+func (c *Client) DescribeACLs(ctx context.Context, req *DescribeACLsRequest) (*DescribeACLsResponse, error) {
+	m, err := c.roundTrip(ctx, req.Addr, &describeacls.Request{
+		ResourceTypeFilter: int8(req.Filter.ResourceTypeFilter),
+		ResourceNameFilter: req.Filter.ResourceNameFilter,
+		PatternTypeFilter:  int8(req.Filter.ResourcePatternTypeFilter),
+		PrincipalFilter:    req.Filter.PrincipalFilter,
+		HostFilter:         req.Filter.HostFilter,
+		Operation:          int8(req.Filter.Operation),
+		PermissionType:     int8(req.Filter.PermissionType),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).DescribeACLs: %w", err)
+	}
+
+	res := m.(*describeacls.Response)
+	ret := &DescribeACLsResponse{
+		Throttle:  makeDuration(res.ThrottleTimeMs),
+		Error:     makeError(res.ErrorCode, res.ErrorMessage),
+		Resources: make([]ACLResource, 0, len(res.Resources)),
+	}
+
+	for _, r := range res.Resources {
+		resource := ACLResource{
+			ResourceType: ResourceType(r.ResourceType),
+			ResourceName: r.ResourceName,
+			PatternType:  PatternType(r.PatternType),
+			ACLs:         make([]ACLDescription, 0, len(r.ACLs)),
+		}
+
+		// Brokers which do not support pattern types only have literal ACLs.
+		if resource.PatternType == PatternTypeUnknown {
+			resource.PatternType = PatternTypeLiteral
+		}
+
+		for _, acl := range r.ACLs {
+			resource.ACLs = append(resource.ACLs, ACLDescription{
+				Principal:      acl.Principal,
+				Host:           acl.Host,
+				Operation:      ACLOperationType(acl.Operation),
+				PermissionType: ACLPermissionType(acl.PermissionType),
+			})
+		}
+
+		ret.Resources = append(ret.Resources, resource)
+	}
+
+	return ret, nil
+}
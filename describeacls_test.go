@@ -0,0 +1,159 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	ktesting "github.com/segmentio/kafka-go/testing"
+)
+
+func TestACLFilterMatches(t *testing.T) {
+	literal := ACLEntry{
+		ResourceType:        ResourceTypeTopic,
+		ResourceName:        "orders-eu",
+		ResourcePatternType: PatternTypeLiteral,
+		Principal:           "User:alice",
+		Host:                "*",
+		Operation:           ACLOperationTypeRead,
+		PermissionType:      ACLPermissionTypeAllow,
+	}
+
+	prefixed := literal
+	prefixed.ResourceName = "orders-"
+	prefixed.ResourcePatternType = PatternTypePrefixed
+
+	wildcard := literal
+	wildcard.ResourceName = "*"
+
+	tests := []struct {
+		scenario string
+		filter   ACLFilter
+		acl      ACLEntry
+		match    bool
+	}{
+		{scenario: "any", filter: NewACLFilter(), acl: literal, match: true},
+		{scenario: "zero value", filter: ACLFilter{}, acl: literal, match: false},
+		{scenario: "exact", filter: literal.Filter(), acl: literal, match: true},
+		{scenario: "literal topic", filter: NewACLFilter().Topic("orders-eu"), acl: literal, match: true},
+		{scenario: "literal topic does not match prefixed", filter: NewACLFilter().Topic("orders-"), acl: prefixed, match: false},
+		{scenario: "topic prefix", filter: NewACLFilter().TopicPrefix("orders-"), acl: prefixed, match: true},
+		{scenario: "group", filter: NewACLFilter().Group("orders-eu"), acl: literal, match: false},
+		{scenario: "matching literal", filter: NewACLFilter().Topic("orders-eu").Matching(), acl: literal, match: true},
+		{scenario: "matching prefixed", filter: NewACLFilter().Topic("orders-eu").Matching(), acl: prefixed, match: true},
+		{scenario: "matching wildcard", filter: NewACLFilter().Topic("orders-eu").Matching(), acl: wildcard, match: true},
+		{scenario: "matching other", filter: NewACLFilter().Topic("payments").Matching(), acl: prefixed, match: false},
+		{scenario: "principal", filter: NewACLFilter().WithPrincipal("User:bob"), acl: literal, match: false},
+		{scenario: "host", filter: NewACLFilter().WithHost("*"), acl: literal, match: true},
+		{scenario: "operation", filter: NewACLFilter().WithOperation(ACLOperationTypeWrite), acl: literal, match: false},
+		{scenario: "allow", filter: NewACLFilter().Allow(), acl: literal, match: true},
+		{scenario: "deny", filter: NewACLFilter().Deny(), acl: literal, match: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			if match := test.filter.Matches(test.acl); match != test.match {
+				t.Errorf("filter %+v matching %+v: want %t, got %t", test.filter, test.acl, test.match, match)
+			}
+		})
+	}
+}
+
+func TestACLEnumsText(t *testing.T) {
+	var op ACLOperationType
+	if err := op.UnmarshalText([]byte("describe_configs")); err != nil {
+		t.Fatal(err)
+	}
+	if op != ACLOperationTypeDescribeConfigs {
+		t.Errorf("want %v, got %v", ACLOperationTypeDescribeConfigs, op)
+	}
+
+	var pt PatternType
+	if err := pt.UnmarshalText([]byte("PREFIXED")); err != nil {
+		t.Fatal(err)
+	}
+	if s := pt.String(); s != "PREFIXED" {
+		t.Errorf("want PREFIXED, got %s", s)
+	}
+
+	var perm ACLPermissionType
+	if err := perm.UnmarshalText([]byte("3")); err != nil {
+		t.Fatal(err)
+	}
+	if perm != ACLPermissionTypeAllow {
+		t.Errorf("want %v, got %v", ACLPermissionTypeAllow, perm)
+	}
+
+	var rt ResourceType
+	if err := rt.UnmarshalText([]byte("whatever")); err == nil {
+		t.Error("expected an error parsing an invalid resource type")
+	}
+	if s := ResourceTypeTransactionalID.String(); s != "TRANSACTIONAL_ID" {
+		t.Errorf("want TRANSACTIONAL_ID, got %s", s)
+	}
+}
+
+func TestClientDescribeAndDeleteACLs(t *testing.T) {
+	if !ktesting.KafkaIsAtLeast("2.0.1") {
+		return
+	}
+
+	client, shutdown := newLocalClient()
+	defer shutdown()
+
+	topic := makeTopic()
+	acl := ACLEntry{
+		Principal:           "User:alice",
+		PermissionType:      ACLPermissionTypeAllow,
+		Operation:           ACLOperationTypeRead,
+		ResourceType:        ResourceTypeTopic,
+		ResourcePatternType: PatternTypePrefixed,
+		ResourceName:        topic,
+		Host:                "*",
+	}
+
+	createRes, err := client.CreateACLs(context.Background(), &CreateACLsRequest{
+		ACLs: []ACLEntry{acl},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range createRes.Errors {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	filter := NewACLFilter().TopicPrefix(topic).WithPrincipal("User:alice")
+
+	describeRes, err := client.DescribeACLs(context.Background(), &DescribeACLsRequest{
+		Filter: filter,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if describeRes.Error != nil {
+		t.Fatal(describeRes.Error)
+	}
+	if entries := describeRes.Entries(); len(entries) != 1 || entries[0] != acl {
+		t.Errorf("unexpected ACLs: %+v", entries)
+	}
+
+	deleteRes, err := client.DeleteACLs(context.Background(), &DeleteACLsRequest{
+		Filters: []ACLFilter{filter, NewACLFilter().Group(makeGroupID())},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleteRes.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(deleteRes.Results))
+	}
+	if err := deleteRes.Results[0].Error; err != nil {
+		t.Fatal(err)
+	}
+	if matching := deleteRes.Results[0].MatchingACLs; len(matching) != 1 || matching[0].ACLEntry != acl || matching[0].Error != nil {
+		t.Errorf("unexpected matching ACLs: %+v", matching)
+	}
+	if matching := deleteRes.Results[1].MatchingACLs; len(matching) != 0 {
+		t.Errorf("unexpected matching ACLs: %+v", matching)
+	}
+}
@@ -0,0 +1,162 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/describeclientquotas"
+)
+
+// ClientQuotaEntityType represents the type of entities that client quotas
+// can be applied to.
+type ClientQuotaEntityType string
+
+const (
+	ClientQuotaEntityUser     ClientQuotaEntityType = "user"
+	ClientQuotaEntityClientID ClientQuotaEntityType = "client-id"
+	ClientQuotaEntityIP       ClientQuotaEntityType = "ip"
+)
+
+// ClientQuotaKey represents the name of a client quota.
+type ClientQuotaKey string
+
+const (
+	ClientQuotaProducerByteRate       ClientQuotaKey = "producer_byte_rate"
+	ClientQuotaConsumerByteRate       ClientQuotaKey = "consumer_byte_rate"
+	ClientQuotaRequestPercentage      ClientQuotaKey = "request_percentage"
+	ClientQuotaControllerMutationRate ClientQuotaKey = "controller_mutation_rate"
+	ClientQuotaConnectionCreationRate ClientQuotaKey = "connection_creation_rate"
+)
+
+// ClientQuotaMatchType represents the way filter components of describe client
+// quotas requests are matched against entities.
+type ClientQuotaMatchType int8
+
+const (
+	// Match entities with the exact name set on the filter component.
+	ClientQuotaMatchExact ClientQuotaMatchType = 0
+	// Match the default entity of the type.
+	ClientQuotaMatchDefault ClientQuotaMatchType = 1
+	// Match any entity of the type which has a name.
+	ClientQuotaMatchAny ClientQuotaMatchType = 2
+)
+
+// ClientQuotaEntity is a component of the entity that a client quota applies
+// to, for example a user name or a client id.
+type ClientQuotaEntity struct {
+	// The type of the entity.
+	Type ClientQuotaEntityType
+
+	// The name of the entity, an empty name refers to the default entity of
+	// the type.
+	Name string
+}
+
+// ClientQuotaFilterComponent is used to select the entities that a describe
+// client quotas request applies to.
+type ClientQuotaFilterComponent struct {
+	// The entity type that the filter component applies to.
+	EntityType ClientQuotaEntityType
+
+	// How to match the entity.
+	MatchType ClientQuotaMatchType
+
+	// The name to match against, only used when MatchType is
+	// ClientQuotaMatchExact.
+	Match string
+}
+
+// DescribeClientQuotasRequest represents a request sent to a kafka broker to
+// describe client quotas.
+type DescribeClientQuotasRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// List of filter components used to select the entities to describe the
+	// quotas of.
+	Components []ClientQuotaFilterComponent
+
+	// When true, entities which have types that were not set in the filter
+	// components are excluded from the response.
+	Strict bool
+}
+
+// DescribeClientQuotasResponse represents a response from a kafka broker to a
+// describe client quotas request.
+type DescribeClientQuotasResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// An error that may have occurred while attempting to describe the quotas.
+	//
+	// The error contains both the kafka error code, and an error message
+	// returned by the kafka broker. Programs may use the standard errors.Is
+	// function to test the error against kafka error codes.
+	Error error
+
+	// List of entities matching the filter, and their quotas.
+	Entries []ClientQuotas
+}
+
+// ClientQuotas represents the set of quotas applied to an entity.
+type ClientQuotas struct {
+	// The components of the entity that the quotas are applied to.
+	Entity []ClientQuotaEntity
+
+	// The quota values of the entity.
+	Values map[ClientQuotaKey]float64
+}
+
+// DescribeClientQuotas sends a describe client quotas request to a kafka
+// broker and returns the response.
+func (c *Client) DescribeClientQuotas(ctx context.Context, req *DescribeClientQuotasRequest) (*DescribeClientQuotasResponse, error) {
+	components := make([]describeclientquotas.Component, len(req.Components))
+
+	for i, comp := range req.Components {
+		components[i] = describeclientquotas.Component{
+			EntityType: string(comp.EntityType),
+			MatchType:  int8(comp.MatchType),
+			Match:      comp.Match,
+		}
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &describeclientquotas.Request{
+		Components: components,
+		Strict:     req.Strict,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).DescribeClientQuotas: %w", err)
+	}
+
+	res := m.(*describeclientquotas.Response)
+	ret := &DescribeClientQuotasResponse{
+		Throttle: makeDuration(res.ThrottleTimeMs),
+		Error:    makeError(res.ErrorCode, res.ErrorMessage),
+		Entries:  make([]ClientQuotas, len(res.Entries)),
+	}
+
+	for i, entry := range res.Entries {
+		entity := make([]ClientQuotaEntity, len(entry.Entities))
+		values := make(map[ClientQuotaKey]float64, len(entry.Values))
+
+		for j, e := range entry.Entities {
+			entity[j] = ClientQuotaEntity{
+				Type: ClientQuotaEntityType(e.EntityType),
+				Name: e.EntityName,
+			}
+		}
+
+		for _, v := range entry.Values {
+			values[ClientQuotaKey(v.Key)] = v.Value
+		}
+
+		ret.Entries[i] = ClientQuotas{
+			Entity: entity,
+			Values: values,
+		}
+	}
+
+	return ret, nil
+}
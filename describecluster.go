@@ -0,0 +1,134 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/describecluster"
+)
+
+// DescribeClusterRequest represents a request sent to a kafka broker to
+// describe the cluster that it belongs to.
+type DescribeClusterRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// When true, the response contains the list of operations that the client
+	// is authorized to perform on the cluster.
+	IncludeAuthorizedOperations bool
+
+	// When true, the request describes the KRaft controllers of the cluster
+	// instead of its brokers. In this mode, Addr must be the address of a
+	// controller listener.
+	//
+	// Describing the controllers requires Kafka 3.7.0 or above, DescribeCluster
+	// returns an error wrapping UnsupportedVersion with older brokers.
+	Controllers bool
+}
+
+// DescribeClusterResponse represents a response from a kafka broker to a
+// describe cluster request.
+type DescribeClusterResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// An error that may have occurred while attempting to describe the cluster.
+	//
+	// The error contains both the kafka error code, and an error message
+	// returned by the kafka broker. Programs may use the standard errors.Is
+	// function to test the error against kafka error codes.
+	Error error
+
+	// Unique identifier of the kafka cluster.
+	ClusterID string
+
+	// The broker which is currently the controller for the cluster, the ID is
+	// set to -1 if the controller is unknown.
+	Controller Broker
+
+	// The list of brokers registered to the cluster.
+	Brokers []Broker
+
+	// List of operations that the client is authorized to perform on the
+	// cluster, only set if IncludeAuthorizedOperations was true.
+	AuthorizedOperations []ACLOperationType
+}
+
+// DescribeCluster sends a describe cluster request to a kafka broker and
+// returns the response.
+//
+// The DescribeCluster API was introduced in Kafka 2.8.0, programs may use the
+// Metadata API to obtain similar information from older brokers.
+func (c *Client) DescribeCluster(ctx context.Context, req *DescribeClusterRequest) (*DescribeClusterResponse, error) {
+	endpointType := int8(1) // brokers
+	if req.Controllers {
+		endpointType = 2
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &describecluster.Request{
+		IncludeClusterAuthorizedOperations: req.IncludeAuthorizedOperations,
+		EndpointType:                       endpointType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).DescribeCluster: %w", err)
+	}
+
+	res := m.(*describecluster.Response)
+	// Responses to v0 requests have no endpoint type, the broker ignored the
+	// one of the request and described its brokers.
+	if req.Controllers && res.EndpointType == 0 {
+		return nil, fmt.Errorf("kafka.(*Client).DescribeCluster: describing controllers requires DescribeCluster v1: %w", UnsupportedVersion)
+	}
+
+	ret := &DescribeClusterResponse{
+		Throttle:   makeDuration(res.ThrottleTimeMs),
+		Error:      makeError(res.ErrorCode, res.ErrorMessage),
+		ClusterID:  res.ClusterID,
+		Controller: Broker{ID: -1},
+		Brokers:    make([]Broker, len(res.Brokers)),
+	}
+
+	for i, b := range res.Brokers {
+		broker := Broker{
+			Host: b.Host,
+			Port: int(b.Port),
+			ID:   int(b.BrokerID),
+			Rack: b.Rack,
+		}
+
+		ret.Brokers[i] = broker
+
+		if b.BrokerID == res.ControllerID {
+			ret.Controller = broker
+		}
+	}
+
+	if req.IncludeAuthorizedOperations {
+		ret.AuthorizedOperations = makeACLOperations(res.ClusterAuthorizedOperations)
+	}
+
+	return ret, nil
+}
+
+// makeACLOperations converts the bit field of authorized operations returned
+// by kafka brokers to a list of operations.
+func makeACLOperations(bits int32) []ACLOperationType {
+	// The minimum int32 value is used by kafka to indicate that the authorized
+	// operations were not requested.
+	if bits == math.MinInt32 {
+		return nil
+	}
+
+	ops := []ACLOperationType{}
+
+	for op := ACLOperationTypeRead; op <= ACLOperationTypeIdempotentWrite; op++ {
+		if bits&(1<<uint(op)) != 0 {
+			ops = append(ops, op)
+		}
+	}
+
+	return ops
+}
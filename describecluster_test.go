@@ -0,0 +1,95 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/describecluster"
+	ktesting "github.com/segmentio/kafka-go/testing"
+)
+
+func TestMakeACLOperations(t *testing.T) {
+	tests := []struct {
+		bits int32
+		ops  []ACLOperationType
+	}{
+		{bits: -2147483648, ops: nil},
+		{bits: 0, ops: []ACLOperationType{}},
+		{
+			bits: 1<<3 | 1<<8 | 1<<12,
+			ops:  []ACLOperationType{ACLOperationTypeRead, ACLOperationTypeDescribe, ACLOperationTypeIdempotentWrite},
+		},
+	}
+
+	for _, test := range tests {
+		if ops := makeACLOperations(test.bits); !reflect.DeepEqual(ops, test.ops) {
+			t.Errorf("operations mismatch for %b: expected %v, got %v", test.bits, test.ops, ops)
+		}
+	}
+}
+
+func TestClientDescribeCluster(t *testing.T) {
+	if !ktesting.KafkaIsAtLeast("2.8.0") {
+		return
+	}
+
+	client, shutdown := newLocalClient()
+	defer shutdown()
+
+	res, err := client.DescribeCluster(context.Background(), &DescribeClusterRequest{
+		IncludeAuthorizedOperations: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	if res.ClusterID == "" {
+		t.Error("expected a cluster id")
+	}
+
+	if len(res.Brokers) == 0 {
+		t.Fatal("expected at least one broker")
+	}
+
+	if res.Controller.ID < 0 {
+		t.Error("expected a controller")
+	}
+
+	if res.AuthorizedOperations == nil {
+		t.Error("expected authorized operations")
+	}
+}
+
+func TestClientDescribeClusterControllersUnsupported(t *testing.T) {
+	client := &Client{
+		Addr: &net.TCPAddr{IP: net.IP{127, 0, 0, 1}, Port: 9092},
+		Transport: roundTripperFunc(func(ctx context.Context, addr net.Addr, req Request) (Response, error) {
+			// A v0 response, which does not have the endpoint type.
+			return &describecluster.Response{
+				ClusterID:    "cluster-A",
+				ControllerID: 1,
+				Brokers:      []describecluster.ResponseBroker{{BrokerID: 1, Host: "localhost", Port: 9092}},
+			}, nil
+		}),
+	}
+
+	_, err := client.DescribeCluster(context.Background(), &DescribeClusterRequest{Controllers: true})
+	if !errors.Is(err, UnsupportedVersion) {
+		t.Errorf("expected UnsupportedVersion, got %v", err)
+	}
+
+	res, err := client.DescribeCluster(context.Background(), &DescribeClusterRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Brokers) != 1 || res.Controller.ID != 1 {
+		t.Errorf("unexpected response: %+v", res)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/segmentio/kafka-go/protocol/describeconfigs"
@@ -95,6 +97,202 @@ type DescribeConfigResponseConfigSynonym struct {
 	ConfigSource int8
 }
 
+// ConfigSource describes where the value of a configuration entry comes from.
+type ConfigSource int8
+
+const (
+	ConfigSourceUnknown                    ConfigSource = 0
+	ConfigSourceDynamicTopicConfig         ConfigSource = 1
+	ConfigSourceDynamicBrokerConfig        ConfigSource = 2
+	ConfigSourceDynamicDefaultBrokerConfig ConfigSource = 3
+	ConfigSourceStaticBrokerConfig         ConfigSource = 4
+	ConfigSourceDefaultConfig              ConfigSource = 5
+	ConfigSourceDynamicBrokerLoggerConfig  ConfigSource = 6
+)
+
+var configSourceStrings = [...]string{
+	ConfigSourceUnknown:                    "UNKNOWN",
+	ConfigSourceDynamicTopicConfig:         "DYNAMIC_TOPIC_CONFIG",
+	ConfigSourceDynamicBrokerConfig:        "DYNAMIC_BROKER_CONFIG",
+	ConfigSourceDynamicDefaultBrokerConfig: "DYNAMIC_DEFAULT_BROKER_CONFIG",
+	ConfigSourceStaticBrokerConfig:         "STATIC_BROKER_CONFIG",
+	ConfigSourceDefaultConfig:              "DEFAULT_CONFIG",
+	ConfigSourceDynamicBrokerLoggerConfig:  "DYNAMIC_BROKER_LOGGER_CONFIG",
+}
+
+func (s ConfigSource) String() string { return enumString(configSourceStrings[:], int8(s)) }
+
+// IsDynamic returns true if the configuration was set dynamically, for
+// example with IncrementalAlterConfigs.
+func (s ConfigSource) IsDynamic() bool {
+	switch s {
+	case ConfigSourceDynamicTopicConfig,
+		ConfigSourceDynamicBrokerConfig,
+		ConfigSourceDynamicDefaultBrokerConfig,
+		ConfigSourceDynamicBrokerLoggerConfig:
+		return true
+	default:
+		return false
+	}
+}
+
+// ConfigType is the data type of a configuration entry.
+type ConfigType int8
+
+const (
+	ConfigTypeUnknown  ConfigType = 0
+	ConfigTypeBoolean  ConfigType = 1
+	ConfigTypeString   ConfigType = 2
+	ConfigTypeInt      ConfigType = 3
+	ConfigTypeShort    ConfigType = 4
+	ConfigTypeLong     ConfigType = 5
+	ConfigTypeDouble   ConfigType = 6
+	ConfigTypeList     ConfigType = 7
+	ConfigTypeClass    ConfigType = 8
+	ConfigTypePassword ConfigType = 9
+)
+
+var configTypeStrings = [...]string{
+	ConfigTypeUnknown:  "UNKNOWN",
+	ConfigTypeBoolean:  "BOOLEAN",
+	ConfigTypeString:   "STRING",
+	ConfigTypeInt:      "INT",
+	ConfigTypeShort:    "SHORT",
+	ConfigTypeLong:     "LONG",
+	ConfigTypeDouble:   "DOUBLE",
+	ConfigTypeList:     "LIST",
+	ConfigTypeClass:    "CLASS",
+	ConfigTypePassword: "PASSWORD",
+}
+
+func (t ConfigType) String() string { return enumString(configTypeStrings[:], int8(t)) }
+
+// Source returns the source of the configuration entry.
+//
+// Version 0 of the DescribeConfigs API only reports whether entries have
+// their default value, which is translated to ConfigSourceDefaultConfig.
+func (e *DescribeConfigResponseConfigEntry) Source() ConfigSource {
+	if e.ConfigSource == 0 && e.IsDefault {
+		return ConfigSourceDefaultConfig
+	}
+	return ConfigSource(e.ConfigSource)
+}
+
+// Type returns the data type of the configuration entry, ConfigTypeUnknown if
+// the broker did not report it.
+func (e *DescribeConfigResponseConfigEntry) Type() ConfigType {
+	return ConfigType(e.ConfigType)
+}
+
+// Bool decodes the value of the configuration entry as a boolean.
+func (e *DescribeConfigResponseConfigEntry) Bool() (bool, error) {
+	return configBool(e.ConfigName, e.ConfigValue)
+}
+
+// Int64 decodes the value of the configuration entry as an integer, which is
+// the type of sizes (e.g. "segment.bytes") and counts.
+func (e *DescribeConfigResponseConfigEntry) Int64() (int64, error) {
+	return configInt64(e.ConfigName, e.ConfigValue)
+}
+
+// Float64 decodes the value of the configuration entry as a floating point
+// number.
+func (e *DescribeConfigResponseConfigEntry) Float64() (float64, error) {
+	return configFloat64(e.ConfigName, e.ConfigValue)
+}
+
+// Duration decodes the value of the configuration entry as a duration, the
+// unit is derived from the suffix of the configuration name (".ms", ".seconds",
+// ".minutes", or ".hours").
+//
+// Kafka uses negative values to represent unlimited durations (e.g. -1 for
+// "retention.ms"), those are returned as negative durations.
+func (e *DescribeConfigResponseConfigEntry) Duration() (time.Duration, error) {
+	return configDuration(e.ConfigName, e.ConfigValue)
+}
+
+// List decodes the value of the configuration entry as a comma-separated list.
+func (e *DescribeConfigResponseConfigEntry) List() []string {
+	return configList(e.ConfigValue)
+}
+
+// Source returns the source of the configuration synonym.
+func (s *DescribeConfigResponseConfigSynonym) Source() ConfigSource {
+	return ConfigSource(s.ConfigSource)
+}
+
+// Duration decodes the value of the configuration synonym as a duration, see
+// DescribeConfigResponseConfigEntry.Duration for details.
+func (s *DescribeConfigResponseConfigSynonym) Duration() (time.Duration, error) {
+	return configDuration(s.ConfigName, s.ConfigValue)
+}
+
+// Int64 decodes the value of the configuration synonym as an integer.
+func (s *DescribeConfigResponseConfigSynonym) Int64() (int64, error) {
+	return configInt64(s.ConfigName, s.ConfigValue)
+}
+
+func configBool(name, value string) (bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("config %q: %w", name, err)
+	}
+	return b, nil
+}
+
+func configInt64(name, value string) (int64, error) {
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("config %q: %w", name, err)
+	}
+	return i, nil
+}
+
+func configFloat64(name, value string) (float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("config %q: %w", name, err)
+	}
+	return f, nil
+}
+
+var configDurationUnits = [...]struct {
+	suffix string
+	unit   time.Duration
+}{
+	{".ms", time.Millisecond},
+	{".seconds", time.Second},
+	{".minutes", time.Minute},
+	{".hours", time.Hour},
+}
+
+func configDuration(name, value string) (time.Duration, error) {
+	for _, u := range configDurationUnits {
+		if strings.HasSuffix(name, u.suffix) {
+			i, err := configInt64(name, value)
+			if err != nil {
+				return 0, err
+			}
+			if i < 0 {
+				return time.Duration(i), nil
+			}
+			return time.Duration(i) * u.unit, nil
+		}
+	}
+	return 0, fmt.Errorf("config %q: not a duration", name)
+}
+
+func configList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	list := strings.Split(value, ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list
+}
+
 // DescribeConfigs sends a config altering request to a kafka broker and returns the
 // response.
 func (c *Client) DescribeConfigs(ctx context.Context, req *DescribeConfigsRequest) (*DescribeConfigsResponse, error) {
@@ -3,6 +3,7 @@ package kafka
 import (
 	"context"
 	"testing"
+	"time"
 
 	ktesting "github.com/segmentio/kafka-go/testing"
 	"github.com/stretchr/testify/assert"
@@ -65,3 +66,49 @@ func TestClientDescribeConfigs(t *testing.T) {
 	}
 	assert.Equal(t, maxMessageBytesValue, MaxMessageBytesValue)
 }
+
+func TestDescribeConfigResponseConfigEntryValues(t *testing.T) {
+	retention := DescribeConfigResponseConfigEntry{ConfigName: "retention.ms", ConfigValue: "604800000"}
+	d, err := retention.Duration()
+	assert.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour, d)
+
+	unlimited := DescribeConfigResponseConfigEntry{ConfigName: "retention.ms", ConfigValue: "-1"}
+	d, err = unlimited.Duration()
+	assert.NoError(t, err)
+	assert.True(t, d < 0)
+
+	hours := DescribeConfigResponseConfigEntry{ConfigName: "log.retention.hours", ConfigValue: "168"}
+	d, err = hours.Duration()
+	assert.NoError(t, err)
+	assert.Equal(t, 168*time.Hour, d)
+
+	segment := DescribeConfigResponseConfigEntry{ConfigName: "segment.bytes", ConfigValue: "1073741824"}
+	_, err = segment.Duration()
+	assert.Error(t, err)
+	n, err := segment.Int64()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1<<30), n)
+
+	preallocate := DescribeConfigResponseConfigEntry{ConfigName: "preallocate", ConfigValue: "false", ConfigType: int8(ConfigTypeBoolean)}
+	b, err := preallocate.Bool()
+	assert.NoError(t, err)
+	assert.False(t, b)
+	assert.Equal(t, ConfigTypeBoolean, preallocate.Type())
+
+	policy := DescribeConfigResponseConfigEntry{ConfigName: "cleanup.policy", ConfigValue: "compact, delete"}
+	assert.Equal(t, []string{"compact", "delete"}, policy.List())
+
+	ratio := DescribeConfigResponseConfigEntry{ConfigName: "min.cleanable.dirty.ratio", ConfigValue: "0.5"}
+	f, err := ratio.Float64()
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, f)
+
+	v0Default := DescribeConfigResponseConfigEntry{IsDefault: true}
+	assert.Equal(t, ConfigSourceDefaultConfig, v0Default.Source())
+	assert.Equal(t, "DEFAULT_CONFIG", v0Default.Source().String())
+
+	dynamic := DescribeConfigResponseConfigEntry{ConfigSource: int8(ConfigSourceDynamicTopicConfig)}
+	assert.True(t, dynamic.Source().IsDynamic())
+	assert.False(t, ConfigSourceStaticBrokerConfig.IsDynamic())
+}
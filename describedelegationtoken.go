@@ -0,0 +1,95 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/describedelegationtoken"
+)
+
+// DescribeDelegationTokenRequest represents a request sent to a kafka broker to
+// describe delegation tokens.
+type DescribeDelegationTokenRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// List of owners to describe the tokens of, if empty all the tokens that
+	// the client is allowed to see are described.
+	Owners []DelegationTokenPrincipal
+}
+
+// DescribeDelegationTokenResponse represents a response from a kafka broker to
+// a delegation token describe request.
+type DescribeDelegationTokenResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// An error that may have occurred while attempting to describe the tokens.
+	//
+	// The error contains both the kafka error code, and an error message
+	// returned by the kafka broker. Programs may use the standard errors.Is
+	// function to test the error against kafka error codes.
+	Error error
+
+	// List of delegation tokens.
+	Tokens []DelegationToken
+}
+
+// DescribeDelegationToken sends a delegation token describe request to a kafka
+// broker and returns the response.
+func (c *Client) DescribeDelegationToken(ctx context.Context, req *DescribeDelegationTokenRequest) (*DescribeDelegationTokenResponse, error) {
+	var owners []describedelegationtoken.Principal
+
+	if len(req.Owners) != 0 {
+		owners = make([]describedelegationtoken.Principal, len(req.Owners))
+
+		for i, o := range req.Owners {
+			owners[i] = describedelegationtoken.Principal{
+				PrincipalType: o.Type,
+				PrincipalName: o.Name,
+			}
+		}
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &describedelegationtoken.Request{
+		Owners: owners,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).DescribeDelegationToken: %w", err)
+	}
+
+	res := m.(*describedelegationtoken.Response)
+	ret := &DescribeDelegationTokenResponse{
+		Throttle: makeDuration(res.ThrottleTimeMs),
+		Error:    makeError(res.ErrorCode, ""),
+		Tokens:   make([]DelegationToken, len(res.Tokens)),
+	}
+
+	for i, t := range res.Tokens {
+		renewers := make([]DelegationTokenPrincipal, len(t.Renewers))
+
+		for j, r := range t.Renewers {
+			renewers[j] = DelegationTokenPrincipal{
+				Type: r.PrincipalType,
+				Name: r.PrincipalName,
+			}
+		}
+
+		ret.Tokens[i] = DelegationToken{
+			Owner: DelegationTokenPrincipal{
+				Type: t.PrincipalType,
+				Name: t.PrincipalName,
+			},
+			Renewers:   renewers,
+			TokenID:    t.TokenID,
+			HMAC:       t.HMAC,
+			IssueTime:  makeTime(t.IssueTimestamp),
+			ExpiryTime: makeTime(t.ExpiryTimestamp),
+			MaxTime:    makeTime(t.MaxTimestamp),
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,137 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/describeproducers"
+)
+
+// DescribeProducersRequest represents a request sent to kafka brokers to
+// describe the active producers of partitions.
+type DescribeProducersRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// A mapping of topic names to the list of partitions to describe the
+	// producers of. The requests are sent to the leaders of the partitions.
+	Topics map[string][]int
+}
+
+// DescribeProducersResponse represents a response from kafka brokers to a
+// DescribeProducersRequest.
+type DescribeProducersResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// Mappings of topic names to the producers of their partitions, there will
+	// be one entry for each topic in the request.
+	Topics map[string][]DescribeProducersPartition
+}
+
+// DescribeProducersPartition carries the active producers of a partition.
+type DescribeProducersPartition struct {
+	// The partition that the producers write to.
+	Partition int
+
+	// An error that may have occurred while describing the producers of the
+	// partition.
+	Error error
+
+	// The producers which have state on the partition leader.
+	ActiveProducers []ProducerState
+}
+
+// ProducerState is the state that a partition leader keeps for an idempotent
+// or transactional producer.
+type ProducerState struct {
+	// The id and epoch of the producer.
+	ProducerID    int
+	ProducerEpoch int
+
+	// The sequence number of the last record batch written by the producer,
+	// or -1 if it is unknown.
+	LastSequence int
+
+	// The time of the last record batch written by the producer.
+	LastTimestamp time.Time
+
+	// The epoch of the transaction coordinator which last wrote a transaction
+	// marker for the producer, or -1.
+	CoordinatorEpoch int
+
+	// The offset of the first record of the ongoing transaction of the
+	// producer on the partition, or -1 if the producer has no open
+	// transaction.
+	//
+	// A transaction is hanging when the partition has an open transaction
+	// that the transaction coordinator does not know about, which prevents
+	// read_committed consumers from making progress past that offset.
+	CurrentTxnStartOffset int64
+}
+
+// DescribeProducers sends a request to the partition leaders to describe the
+// producers of partitions.
+//
+// Along with DescribeTransactions and ListTransactions, it is used to find
+// hanging transactions (see KIP-664).
+func (c *Client) DescribeProducers(ctx context.Context, req *DescribeProducersRequest) (*DescribeProducersResponse, error) {
+	topics := make([]describeproducers.RequestTopic, 0, len(req.Topics))
+
+	for topicName, partitions := range req.Topics {
+		indexes := make([]int32, len(partitions))
+
+		for i, p := range partitions {
+			indexes[i] = int32(p)
+		}
+
+		topics = append(topics, describeproducers.RequestTopic{
+			Name:             topicName,
+			PartitionIndexes: indexes,
+		})
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &describeproducers.Request{
+		Topics: topics,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).DescribeProducers: %w", err)
+	}
+
+	res := m.(*describeproducers.Response)
+	ret := &DescribeProducersResponse{
+		Throttle: makeDuration(res.ThrottleTimeMs),
+		Topics:   make(map[string][]DescribeProducersPartition, len(res.Topics)),
+	}
+
+	for _, t := range res.Topics {
+		partitions := make([]DescribeProducersPartition, len(t.Partitions))
+
+		for i, p := range t.Partitions {
+			producers := make([]ProducerState, len(p.ActiveProducers))
+
+			for j, s := range p.ActiveProducers {
+				producers[j] = ProducerState{
+					ProducerID:            int(s.ProducerID),
+					ProducerEpoch:         int(s.ProducerEpoch),
+					LastSequence:          int(s.LastSequence),
+					LastTimestamp:         makeTime(s.LastTimestamp),
+					CoordinatorEpoch:      int(s.CoordinatorEpoch),
+					CurrentTxnStartOffset: s.CurrentTxnStartOffset,
+				}
+			}
+
+			partitions[i] = DescribeProducersPartition{
+				Partition:       int(p.PartitionIndex),
+				Error:           makeError(p.ErrorCode, p.ErrorMessage),
+				ActiveProducers: producers,
+			}
+		}
+
+		ret.Topics[t.Name] = partitions
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,137 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/describequorum"
+)
+
+// Name of the internal topic holding the metadata log of KRaft clusters.
+const clusterMetadataTopic = "__cluster_metadata"
+
+// DescribeQuorumRequest represents a request sent to a kafka broker to
+// describe the state of the KRaft metadata quorum.
+type DescribeQuorumRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+}
+
+// DescribeQuorumResponse represents a response from a kafka broker to a
+// describe quorum request.
+type DescribeQuorumResponse struct {
+	// An error that may have occurred while attempting to describe the quorum.
+	//
+	// The error contains both the kafka error code, and an error message
+	// returned by the kafka broker. Programs may use the standard errors.Is
+	// function to test the error against kafka error codes.
+	Error error
+
+	// ID of the controller currently leading the quorum.
+	LeaderID int
+
+	// Epoch of the current quorum leader.
+	LeaderEpoch int
+
+	// The high watermark of the metadata log.
+	HighWatermark int64
+
+	// State of the replicas which are voting members of the quorum.
+	Voters []QuorumReplicaState
+
+	// State of the replicas which replicate the metadata log without voting
+	// (usually the brokers).
+	Observers []QuorumReplicaState
+}
+
+// QuorumReplicaState represents the replication state of a member of the
+// KRaft metadata quorum.
+type QuorumReplicaState struct {
+	// ID of the replica.
+	ReplicaID int
+
+	// The end offset of the metadata log on the replica.
+	LogEndOffset int64
+
+	// The number of records that the replica lags behind the leader.
+	Lag int64
+
+	// The last time the leader received a fetch request from the replica, zero
+	// if unknown.
+	LastFetchTime time.Time
+
+	// The last time the replica was caught up with the leader, zero if
+	// unknown.
+	LastCaughtUpTime time.Time
+}
+
+// DescribeQuorum sends a describe quorum request to a kafka broker and returns
+// the response.
+//
+// The DescribeQuorum API is only supported by kafka clusters running in KRaft
+// mode.
+func (c *Client) DescribeQuorum(ctx context.Context, req *DescribeQuorumRequest) (*DescribeQuorumResponse, error) {
+	m, err := c.roundTrip(ctx, req.Addr, &describequorum.Request{
+		Topics: []describequorum.RequestTopic{{
+			TopicName:  clusterMetadataTopic,
+			Partitions: []describequorum.RequestPartition{{PartitionIndex: 0}},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).DescribeQuorum: %w", err)
+	}
+
+	res := m.(*describequorum.Response)
+	ret := &DescribeQuorumResponse{
+		Error:    makeError(res.ErrorCode, ""),
+		LeaderID: -1,
+	}
+
+	if ret.Error != nil {
+		return ret, nil
+	}
+
+	if len(res.Topics) != 1 || len(res.Topics[0].Partitions) != 1 {
+		return nil, fmt.Errorf("kafka.(*Client).DescribeQuorum: unexpected response with %d topics", len(res.Topics))
+	}
+
+	p := &res.Topics[0].Partitions[0]
+	ret.Error = makeError(p.ErrorCode, "")
+	ret.LeaderID = int(p.LeaderID)
+	ret.LeaderEpoch = int(p.LeaderEpoch)
+	ret.HighWatermark = p.HighWatermark
+
+	leaderEndOffset := p.HighWatermark
+	for _, v := range p.CurrentVoters {
+		if v.ReplicaID == p.LeaderID {
+			leaderEndOffset = v.LogEndOffset
+		}
+	}
+
+	ret.Voters = makeQuorumReplicaStates(p.CurrentVoters, leaderEndOffset)
+	ret.Observers = makeQuorumReplicaStates(p.Observers, leaderEndOffset)
+	return ret, nil
+}
+
+func makeQuorumReplicaStates(replicas []describequorum.ReplicaState, leaderEndOffset int64) []QuorumReplicaState {
+	states := make([]QuorumReplicaState, len(replicas))
+
+	for i, r := range replicas {
+		lag := leaderEndOffset - r.LogEndOffset
+		if lag < 0 {
+			lag = 0
+		}
+
+		states[i] = QuorumReplicaState{
+			ReplicaID:        int(r.ReplicaID),
+			LogEndOffset:     r.LogEndOffset,
+			Lag:              lag,
+			LastFetchTime:    makeTime(r.LastFetchTimestamp),
+			LastCaughtUpTime: makeTime(r.LastCaughtUpTimestamp),
+		}
+	}
+
+	return states
+}
@@ -0,0 +1,26 @@
+package kafka
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/describequorum"
+)
+
+func TestMakeQuorumReplicaStates(t *testing.T) {
+	states := makeQuorumReplicaStates([]describequorum.ReplicaState{
+		{ReplicaID: 1, LogEndOffset: 100, LastFetchTimestamp: -1, LastCaughtUpTimestamp: -1},
+		{ReplicaID: 2, LogEndOffset: 90, LastFetchTimestamp: 1000, LastCaughtUpTimestamp: 2000},
+		{ReplicaID: 3, LogEndOffset: 110},
+	}, 100)
+
+	expected := []QuorumReplicaState{
+		{ReplicaID: 1, LogEndOffset: 100, Lag: 0},
+		{ReplicaID: 2, LogEndOffset: 90, Lag: 10, LastFetchTime: makeTime(1000), LastCaughtUpTime: makeTime(2000)},
+		{ReplicaID: 3, LogEndOffset: 110, Lag: 0},
+	}
+
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("replica states mismatch:\nexpected: %+v\nfound:    %+v", expected, states)
+	}
+}
@@ -0,0 +1,118 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/describetransactions"
+)
+
+// TransactionState represents the state of a transaction on its coordinator.
+type TransactionState string
+
+const (
+	TransactionStateEmpty             TransactionState = "Empty"
+	TransactionStateOngoing           TransactionState = "Ongoing"
+	TransactionStatePrepareCommit     TransactionState = "PrepareCommit"
+	TransactionStatePrepareAbort      TransactionState = "PrepareAbort"
+	TransactionStateCompleteCommit    TransactionState = "CompleteCommit"
+	TransactionStateCompleteAbort     TransactionState = "CompleteAbort"
+	TransactionStateDead              TransactionState = "Dead"
+	TransactionStatePrepareEpochFence TransactionState = "PrepareEpochFence"
+)
+
+// DescribeTransactionsRequest represents a request sent to kafka brokers to
+// describe transactions.
+type DescribeTransactionsRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// The transactional ids to describe, the requests are sent to the
+	// coordinators of each transactional id.
+	TransactionalIDs []string
+}
+
+// DescribeTransactionsResponse represents a response from kafka brokers to a
+// DescribeTransactionsRequest.
+type DescribeTransactionsResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// The descriptions of the transactions, there will be one entry for each
+	// transactional id in the request.
+	Transactions []TransactionDescription
+}
+
+// TransactionDescription describes the transaction of a transactional id.
+type TransactionDescription struct {
+	// The transactional id of the transaction.
+	TransactionalID string
+
+	// An error that may have occurred while describing the transaction, for
+	// example TransactionalIDNotFound.
+	Error error
+
+	// The state of the transaction.
+	State TransactionState
+
+	// The timeout of the transaction, set by the producer when it initialized
+	// its session.
+	Timeout time.Duration
+
+	// The time at which the ongoing transaction started, zero if there is no
+	// ongoing transaction.
+	StartTime time.Time
+
+	// The id and epoch of the producer using the transactional id.
+	ProducerID    int
+	ProducerEpoch int
+
+	// The partitions added to the ongoing transaction.
+	Topics map[string][]int
+}
+
+// DescribeTransactions sends requests to the transaction coordinators to
+// describe the transactions of transactional ids.
+func (c *Client) DescribeTransactions(ctx context.Context, req *DescribeTransactionsRequest) (*DescribeTransactionsResponse, error) {
+	m, err := c.roundTrip(ctx, req.Addr, &describetransactions.Request{
+		TransactionalIDs: req.TransactionalIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).DescribeTransactions: %w", err)
+	}
+
+	res := m.(*describetransactions.Response)
+	ret := &DescribeTransactionsResponse{
+		Throttle:     makeDuration(res.ThrottleTimeMs),
+		Transactions: make([]TransactionDescription, len(res.TransactionStates)),
+	}
+
+	for i, t := range res.TransactionStates {
+		topics := make(map[string][]int, len(t.Topics))
+
+		for _, topic := range t.Topics {
+			partitions := make([]int, len(topic.Partitions))
+
+			for j, p := range topic.Partitions {
+				partitions[j] = int(p)
+			}
+
+			topics[topic.Topic] = partitions
+		}
+
+		ret.Transactions[i] = TransactionDescription{
+			TransactionalID: t.TransactionalID,
+			Error:           makeError(t.ErrorCode, ""),
+			State:           TransactionState(t.TransactionState),
+			Timeout:         makeDuration(t.TransactionTimeoutMs),
+			StartTime:       makeTime(t.TransactionStartTimeMs),
+			ProducerID:      int(t.ProducerID),
+			ProducerEpoch:   int(t.ProducerEpoch),
+			Topics:          topics,
+		}
+	}
+
+	return ret, nil
+}
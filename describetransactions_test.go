@@ -0,0 +1,159 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func TestClientFindAndFenceHangingTransaction(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A", Partitions: 2})
+
+	transport := &kafka.Transport{}
+	defer transport.CloseIdleConnections()
+	client := &kafka.Client{Addr: b.Addr(), Transport: transport}
+
+	// Start a transaction which is never completed.
+	init, err := client.InitProducerID(ctx, &kafka.InitProducerIDRequest{
+		TransactionalID:      "txn-A",
+		TransactionTimeoutMs: 30000,
+		ProducerID:           -1,
+		ProducerEpoch:        -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if init.Error != nil {
+		t.Fatal(init.Error)
+	}
+	producer := init.Producer
+
+	add, err := client.AddPartitionsToTxn(ctx, &kafka.AddPartitionsToTxnRequest{
+		TransactionalID: "txn-A",
+		ProducerID:      producer.ProducerID,
+		ProducerEpoch:   producer.ProducerEpoch,
+		Topics:          map[string][]kafka.AddPartitionToTxn{"topic-A": {{Partition: 1}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range add.Topics["topic-A"] {
+		if p.Error != nil {
+			t.Fatal(p.Error)
+		}
+	}
+
+	list, err := client.ListTransactions(ctx, &kafka.ListTransactionsRequest{
+		States: []kafka.TransactionState{kafka.TransactionStateOngoing, "Hanging"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Error != nil {
+		t.Fatal(list.Error)
+	}
+	if !reflect.DeepEqual(list.UnknownStates, []kafka.TransactionState{"Hanging"}) {
+		t.Errorf("wrong unknown states: %v", list.UnknownStates)
+	}
+	if want := []kafka.ListTransactionsResponseTransaction{{
+		TransactionalID: "txn-A",
+		ProducerID:      producer.ProducerID,
+		State:           kafka.TransactionStateOngoing,
+		Coordinator:     1,
+	}}; !reflect.DeepEqual(list.Transactions, want) {
+		t.Errorf("wrong transactions:\nwant: %+v\ngot:  %+v", want, list.Transactions)
+	}
+
+	producers, err := client.DescribeProducers(ctx, &kafka.DescribeProducersRequest{
+		Topics: map[string][]int{"topic-A": {0, 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	partitions := producers.Topics["topic-A"]
+	if len(partitions) != 2 {
+		t.Fatalf("wrong partitions: %+v", partitions)
+	}
+	if p := partitions[0]; p.Error != nil || len(p.ActiveProducers) != 0 {
+		t.Errorf("partition 0 has no producers: %+v", p)
+	}
+	if p := partitions[1]; p.Error != nil || len(p.ActiveProducers) != 1 {
+		t.Errorf("partition 1 has one producer: %+v", p)
+	} else if s := p.ActiveProducers[0]; s.ProducerID != producer.ProducerID || s.CurrentTxnStartOffset != 0 {
+		t.Errorf("wrong producer state: %+v", s)
+	}
+
+	txns, err := client.DescribeTransactions(ctx, &kafka.DescribeTransactionsRequest{
+		TransactionalIDs: []string{"txn-A", "txn-B"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txns.Transactions) != 2 {
+		t.Fatalf("wrong transactions: %+v", txns.Transactions)
+	}
+	for _, txn := range txns.Transactions {
+		switch txn.TransactionalID {
+		case "txn-A":
+			if txn.Error != nil {
+				t.Fatal(txn.Error)
+			}
+			if txn.State != kafka.TransactionStateOngoing || txn.Timeout != 30*time.Second || txn.StartTime.IsZero() {
+				t.Errorf("wrong transaction: %+v", txn)
+			}
+			if !reflect.DeepEqual(txn.Topics, map[string][]int{"topic-A": {1}}) {
+				t.Errorf("wrong partitions: %v", txn.Topics)
+			}
+		case "txn-B":
+			if !errors.Is(txn.Error, kafka.TransactionalIDNotFound) {
+				t.Errorf("expected TransactionalIDNotFound, got %v", txn.Error)
+			}
+		}
+	}
+
+	fence, err := client.FenceProducers(ctx, &kafka.FenceProducersRequest{
+		TransactionalIDs: []string{"txn-A"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fenced := fence.Producers["txn-A"]
+	if fenced.Error != nil {
+		t.Fatal(fenced.Error)
+	}
+	if fenced.Producer.ProducerID != producer.ProducerID || fenced.Producer.ProducerEpoch != producer.ProducerEpoch+1 {
+		t.Errorf("the epoch of the producer was not bumped: %+v", fenced.Producer)
+	}
+
+	txns, err = client.DescribeTransactions(ctx, &kafka.DescribeTransactionsRequest{
+		TransactionalIDs: []string{"txn-A"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn := txns.Transactions[0]; txn.State != kafka.TransactionStateEmpty || len(txn.Topics) != 0 {
+		t.Errorf("the transaction was not aborted: %+v", txn)
+	}
+
+	// The fenced producer can no longer use the transaction.
+	add, err = client.AddPartitionsToTxn(ctx, &kafka.AddPartitionsToTxnRequest{
+		TransactionalID: "txn-A",
+		ProducerID:      producer.ProducerID,
+		ProducerEpoch:   producer.ProducerEpoch,
+		Topics:          map[string][]kafka.AddPartitionToTxn{"topic-A": {{Partition: 0}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := add.Topics["topic-A"]; len(p) != 1 || !errors.Is(p[0].Error, kafka.ProducerFenced) {
+		t.Errorf("expected ProducerFenced, got %+v", p)
+	}
+}
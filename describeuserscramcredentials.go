@@ -0,0 +1,133 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/describeuserscramcredentials"
+)
+
+// ScramMechanism represents the SCRAM mechanisms that user credentials can be
+// managed for.
+type ScramMechanism int8
+
+const (
+	ScramMechanismUnknown ScramMechanism = 0
+	ScramMechanismSha256  ScramMechanism = 1
+	ScramMechanismSha512  ScramMechanism = 2
+)
+
+// String returns the name of the SASL mechanism that m corresponds to.
+func (m ScramMechanism) String() string {
+	switch m {
+	case ScramMechanismSha256:
+		return "SCRAM-SHA-256"
+	case ScramMechanismSha512:
+		return "SCRAM-SHA-512"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// DescribeUserScramCredentialsRequest represents a request sent to a kafka
+// broker to describe user scram credentials.
+type DescribeUserScramCredentialsRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// List of users to describe the credentials of, if empty the credentials
+	// of all users are described.
+	Users []string
+}
+
+// DescribeUserScramCredentialsResponse represents a response from a kafka
+// broker to a describe user credentials request.
+type DescribeUserScramCredentialsResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// Top level error that occurred while attempting to describe the user
+	// scram credentials.
+	//
+	// The errors contain the kafka error code. Programs may use the standard
+	// errors.Is function to test the error against kafka error codes.
+	Error error
+
+	// List of described user scram credentials.
+	Results []DescribeUserScramCredentialsResponseResult
+}
+
+// DescribeUserScramCredentialsResponseResult represents the credentials of a
+// single user.
+type DescribeUserScramCredentialsResponseResult struct {
+	// Name of the user.
+	User string
+
+	// List of credentials configured for the user.
+	CredentialInfos []DescribeUserScramCredentialsCredentialInfo
+
+	// An error that may have occurred while attempting to describe the
+	// credentials of the user.
+	Error error
+}
+
+// DescribeUserScramCredentialsCredentialInfo represents the configuration of a
+// single SCRAM mechanism for a user.
+type DescribeUserScramCredentialsCredentialInfo struct {
+	// The SCRAM mechanism.
+	Mechanism ScramMechanism
+
+	// The number of iterations used by the mechanism.
+	Iterations int
+}
+
+// DescribeUserScramCredentials sends a user scram credentials describe request
+// to a kafka broker and returns the response.
+func (c *Client) DescribeUserScramCredentials(ctx context.Context, req *DescribeUserScramCredentialsRequest) (*DescribeUserScramCredentialsResponse, error) {
+	var users []describeuserscramcredentials.RequestUser
+
+	if len(req.Users) != 0 {
+		users = make([]describeuserscramcredentials.RequestUser, len(req.Users))
+
+		for i, user := range req.Users {
+			users[i] = describeuserscramcredentials.RequestUser{
+				Name: user,
+			}
+		}
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &describeuserscramcredentials.Request{
+		Users: users,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).DescribeUserScramCredentials: %w", err)
+	}
+
+	res := m.(*describeuserscramcredentials.Response)
+	ret := &DescribeUserScramCredentialsResponse{
+		Throttle: makeDuration(res.ThrottleTimeMs),
+		Error:    makeError(res.ErrorCode, res.ErrorMessage),
+		Results:  make([]DescribeUserScramCredentialsResponseResult, len(res.Results)),
+	}
+
+	for i, r := range res.Results {
+		infos := make([]DescribeUserScramCredentialsCredentialInfo, len(r.CredentialInfos))
+
+		for j, info := range r.CredentialInfos {
+			infos[j] = DescribeUserScramCredentialsCredentialInfo{
+				Mechanism:  ScramMechanism(info.Mechanism),
+				Iterations: int(info.Iterations),
+			}
+		}
+
+		ret.Results[i] = DescribeUserScramCredentialsResponseResult{
+			User:            r.User,
+			CredentialInfos: infos,
+			Error:           makeError(r.ErrorCode, r.ErrorMessage),
+		}
+	}
+
+	return ret, nil
+}
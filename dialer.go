@@ -3,14 +3,12 @@ package kafka
 import (
 	"context"
 	"crypto/tls"
-	"errors"
 	"fmt"
-	"io"
 	"net"
 	"strconv"
-	"strings"
 	"time"
 
+	"github.com/segmentio/kafka-go/protocol"
 	"github.com/segmentio/kafka-go/sasl"
 )
 
@@ -23,8 +21,8 @@ type Dialer struct {
 	// Optionally specifies the function that the dialer uses to establish
 	// network connections. If nil, net.(*Dialer).DialContext is used instead.
 	//
-	// When DialFunc is set, LocalAddr, DualStack, FallbackDelay, and KeepAlive
-	// are ignored.
+	// When DialFunc is set, LocalAddr, DualStack, FallbackDelay, KeepAlive,
+	// KeepAliveCount, and TCPUserTimeout are ignored.
 	DialFunc func(ctx context.Context, network string, address string) (net.Conn, error)
 
 	// Timeout is the maximum amount of time a dial will wait for a connect to
@@ -67,6 +65,25 @@ type Dialer struct {
 	// support keep-alives ignore this field.
 	KeepAlive time.Duration
 
+	// KeepAliveCount is the number of unanswered keep-alive probes after
+	// which the connection is considered dead. Combined with KeepAlive, it
+	// bounds the time needed to detect half-open connections (e.g. after a
+	// load balancer dropped them) to KeepAlive * (KeepAliveCount + 1).
+	// If zero, the operating system default is used.
+	//
+	// Only supported on linux, ignored on other platforms.
+	KeepAliveCount int
+
+	// TCPUserTimeout is the maximum amount of time that data written to a
+	// connection may remain unacknowledged before the connection is closed
+	// (see TCP_USER_TIMEOUT in tcp(7)). This prevents writes to half-open
+	// connections from blocking for the duration of the kernel retransmission
+	// timeouts, which often amount to several minutes.
+	// If zero, the operating system default is used.
+	//
+	// Only supported on linux, ignored on other platforms.
+	TCPUserTimeout time.Duration
+
 	// Resolver optionally gives a hook to convert the broker address into an
 	// alternate host or IP address which is useful for custom service discovery.
 	// If a custom resolver returns any possible hosts, the first one will be
@@ -80,6 +97,29 @@ type Dialer struct {
 	// will be used.
 	TLS *tls.Config
 
+	// GetTLSConfig optionally returns the TLS configuration of each connection
+	// that the Dialer opens, in place of TLS. The broker passed to the
+	// function has the host and port that the dialer connects to, and an ID
+	// of -1 since dialers do not know the IDs of brokers.
+	//
+	// Programs use it to rotate client certificates without restarting, see
+	// CertificateReloader, or to use different configurations per broker.
+	GetTLSConfig func(broker Broker) (*tls.Config, error)
+
+	// TLSServerName optionally returns the server name used to verify the
+	// certificates of brokers, for example when connecting through tunnels
+	// where the dialed host names differ from the names of the certificates.
+	// Returning an empty string uses the ServerName of the TLS configuration,
+	// or the dialed host name.
+	TLSServerName func(broker Broker) string
+
+	// TLSPins optionally pins the public keys that brokers must present
+	// certificates for, in addition to the verification of the TLS
+	// configuration. Connections are rejected with a *TLSPinError unless one
+	// of the certificates presented by the broker matches a pin, see SPKIPin
+	// for the format of pins.
+	TLSPins []string
+
 	// SASLMechanism configures the Dialer to use SASL authentication.  If nil,
 	// no authentication will be performed.
 	SASLMechanism sasl.Mechanism
@@ -89,6 +129,10 @@ type Dialer struct {
 	// For more details look at transactional.id description here: http://kafka.apache.org/documentation.html#producerconfigs
 	// Empty string means that the connection will be non-transactional.
 	TransactionalID string
+
+	// Pins the maximum versions of the APIs used by the connections that the
+	// dialer creates. See Transport.MaxVersions for details.
+	MaxVersions map[protocol.ApiKey]int16
 }
 
 // Dial connects to the address on the named network.
@@ -117,6 +161,7 @@ func (d *Dialer) DialContext(ctx context.Context, network string, address string
 		ConnConfig{
 			ClientID:        d.ClientID,
 			TransactionalID: d.TransactionalID,
+			MaxVersions:     d.MaxVersions,
 		},
 	)
 }
@@ -148,6 +193,7 @@ func (d *Dialer) DialPartition(ctx context.Context, network string, address stri
 		Broker:          partition.Leader.ID,
 		Rack:            partition.Leader.Rack,
 		TransactionalID: d.TransactionalID,
+		MaxVersions:     d.MaxVersions,
 	})
 }
 
@@ -290,6 +336,7 @@ func (d *Dialer) connect(ctx context.Context, network, address string, connCfg C
 		metadata := &sasl.Metadata{
 			Host: host,
 			Port: port,
+			TLS:  tlsConnectionState(c),
 		}
 		if err := d.authenticateSASL(sasl.WithMetadata(ctx, metadata), conn); err != nil {
 			_ = conn.Close()
@@ -306,39 +353,41 @@ func (d *Dialer) connect(ctx context.Context, network, address string, connCfg C
 //
 // In case of error, this function *does not* close the connection.  That is the
 // responsibility of the caller.
+//
+// When the broker limits the lifetime of SASL sessions (see KIP-368), the
+// connection is transparently re-authenticated before the session expires.
 func (d *Dialer) authenticateSASL(ctx context.Context, conn *Conn) error {
-	if err := conn.saslHandshake(d.SASLMechanism.Name()); err != nil {
-		return fmt.Errorf("SASL handshake failed: %w", err)
+	lifetimeMs, err := conn.authenticateSASL(ctx, d.SASLMechanism)
+	if err != nil {
+		return err
 	}
+	conn.setSASLSession(d.SASLMechanism, sasl.MetadataFromContext(ctx), lifetimeMs)
+	return nil
+}
 
-	sess, state, err := d.SASLMechanism.Start(ctx)
+func (d *Dialer) dialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	if !isSRVAddress(addr) {
+		return d.dialAddress(ctx, network, addr)
+	}
+
+	targets, err := lookupSRVAddresses(ctx, addr)
 	if err != nil {
-		return fmt.Errorf("SASL authentication process could not be started: %w", err)
+		return nil, fmt.Errorf("failed to resolve %s: %w", addr, err)
 	}
 
-	for completed := false; !completed; {
-		challenge, err := conn.saslAuthenticate(state)
-		switch {
-		case err == nil:
-		case errors.Is(err, io.EOF):
-			// the broker may communicate a failed exchange by closing the
-			// connection (esp. in the case where we're passing opaque sasl
-			// data over the wire since there's no protocol info).
-			return SASLAuthenticationFailed
-		default:
-			return err
-		}
-
-		completed, state, err = sess.Next(ctx, challenge)
-		if err != nil {
-			return fmt.Errorf("SASL authentication process has failed: %w", err)
+	// The targets are ordered by priority, the first one that accepts the
+	// connection is used.
+	for _, target := range targets {
+		var conn net.Conn
+		if conn, err = d.dialAddress(ctx, network, target); err == nil {
+			return conn, nil
 		}
 	}
 
-	return nil
+	return nil, err
 }
 
-func (d *Dialer) dialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+func (d *Dialer) dialAddress(ctx context.Context, network string, addr string) (net.Conn, error) {
 	address, err := lookupHost(ctx, addr, d.Resolver)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve host: %w", err)
@@ -352,6 +401,21 @@ func (d *Dialer) dialContext(ctx context.Context, network string, addr string) (
 			FallbackDelay: d.FallbackDelay,
 			KeepAlive:     d.KeepAlive,
 		}).DialContext
+		dial = socketOptions{
+			keepAliveInterval: d.KeepAlive,
+			keepAliveCount:    d.KeepAliveCount,
+			userTimeout:       d.TCPUserTimeout,
+		}.dial(dial)
+	}
+
+	broker := Broker{ID: -1}
+	broker.Host, broker.Port, _ = splitHostPortNumber(addr)
+
+	tlsConfig := d.TLS
+	if d.GetTLSConfig != nil {
+		if tlsConfig, err = d.GetTLSConfig(broker); err != nil {
+			return nil, fmt.Errorf("failed to get the TLS configuration of %s: %w", addr, err)
+		}
 	}
 
 	conn, err := dial(ctx, network, address)
@@ -359,20 +423,8 @@ func (d *Dialer) dialContext(ctx context.Context, network string, addr string) (
 		return nil, fmt.Errorf("failed to open connection to %s: %w", address, err)
 	}
 
-	if d.TLS != nil {
-		c := d.TLS
-		// If no ServerName is set, infer the ServerName
-		// from the hostname we're connecting to.
-		if c.ServerName == "" {
-			c = d.TLS.Clone()
-			// Copied from tls.go in the standard library.
-			colonPos := strings.LastIndex(address, ":")
-			if colonPos == -1 {
-				colonPos = len(address)
-			}
-			hostname := address[:colonPos]
-			c.ServerName = hostname
-		}
+	if tlsConfig != nil {
+		c := brokerTLSConfig(tlsConfig, broker, address, d.TLSServerName, d.TLSPins)
 		return d.connectTLS(ctx, conn, c)
 	}
 
@@ -416,22 +468,7 @@ func LookupPartitions(ctx context.Context, network string, address string, topic
 }
 
 func sleep(ctx context.Context, duration time.Duration) bool {
-	if duration == 0 {
-		select {
-		default:
-			return true
-		case <-ctx.Done():
-			return false
-		}
-	}
-	timer := time.NewTimer(duration)
-	defer timer.Stop()
-	select {
-	case <-timer.C:
-		return true
-	case <-ctx.Done():
-		return false
-	}
+	return sleepClock(ctx, SystemClock, duration)
 }
 
 func backoff(attempt int, min time.Duration, max time.Duration) time.Duration {
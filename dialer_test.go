@@ -402,3 +402,45 @@ func (mr *mockResolver) LookupHost(ctx context.Context, host string) ([]string,
 		return addrs, nil
 	}
 }
+
+func TestDialerSRV(t *testing.T) {
+	defer func(f func(context.Context, string, string, string) (string, []*net.SRV, error)) { lookupSRV = f }(lookupSRV)
+
+	lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		if name != "_kafka._tcp.example.com" {
+			return "", nil, fmt.Errorf("unrecognized SRV name %s", name)
+		}
+		return name, []*net.SRV{
+			{Target: "kafka-1.example.com.", Port: 9093},
+			{Target: "kafka-2.example.com.", Port: 9094},
+		}, nil
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	var dialed []string
+	d := &Dialer{
+		DialFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialed = append(dialed, address)
+			if len(dialed) == 1 {
+				return nil, errors.New("connection refused")
+			}
+			return client, nil
+		},
+	}
+
+	conn, err := d.dialContext(context.Background(), "tcp", SRVPrefix+"_kafka._tcp.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn != client {
+		t.Error("the connection to the second target was not returned")
+	}
+
+	want := []string{"kafka-1.example.com:9093", "kafka-2.example.com:9094"}
+	if !reflect.DeepEqual(dialed, want) {
+		t.Errorf("wrong dialed addresses:\nwant: %q\ngot:  %q", want, dialed)
+	}
+}
@@ -0,0 +1,177 @@
+package kafka
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// EncryptionKeyHeader is the name of the header carrying the ID of the key
+// that the value of encrypted messages was encrypted with.
+const EncryptionKeyHeader = "kafka-go-encryption-key"
+
+// Encryptor is an interface implemented by types that encrypt the values of
+// messages, so they are stored encrypted on kafka brokers.
+//
+// Writers configured with an Encryptor encrypt the values of messages before
+// sending them, and record the ID of the key used in the EncryptionKeyHeader
+// header. Readers configured with an Encryptor decrypt the values of messages
+// which carry the header, and remove the header. Keys and headers of messages
+// are not encrypted, since brokers use keys to compact topics and partitioners
+// use them to choose partitions, but encryptors are passed the topic and key of
+// messages so they can authenticate them along with the values.
+//
+// Encryptors must be safe to use concurrently from multiple goroutines.
+type Encryptor interface {
+	// Encrypt encrypts the value of a message with the given key produced to
+	// topic, it returns the ciphertext and the ID of the encryption key that
+	// was used.
+	Encrypt(topic string, key, plaintext []byte) (ciphertext []byte, keyID string, err error)
+
+	// Decrypt decrypts the value of a message with the given key consumed
+	// from topic, which was encrypted with the encryption key identified by
+	// keyID.
+	Decrypt(topic string, keyID string, key, ciphertext []byte) (plaintext []byte, err error)
+}
+
+// AESGCMEncryptor is an Encryptor using AES-GCM, the ciphertexts are made of a
+// random 12 bytes nonce, followed by the encrypted value and authentication
+// tag. The topic and key of messages are authenticated as additional data, so
+// values copied to another topic, or to a message with a different key, fail to
+// decrypt.
+//
+// Keys are rotated by adding a new key to Keys and changing KeyID, the previous
+// keys must be kept to decrypt the messages which were encrypted with them.
+type AESGCMEncryptor struct {
+	// The ID of the key used to encrypt values.
+	KeyID string
+
+	// AES keys indexed by ID, which must be 16, 24, or 32 bytes long to select
+	// AES-128, AES-192, or AES-256. The map must not be modified while the
+	// encryptor is in use.
+	Keys map[string][]byte
+}
+
+// Encrypt satisfies the Encryptor interface.
+func (e *AESGCMEncryptor) Encrypt(topic string, key, plaintext []byte) ([]byte, string, error) {
+	aead, err := e.aead(e.KeyID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ciphertext := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, ciphertext); err != nil {
+		return nil, "", err
+	}
+	return aead.Seal(ciphertext, ciphertext, plaintext, additionalData(topic, key)), e.KeyID, nil
+}
+
+// Decrypt satisfies the Encryptor interface.
+func (e *AESGCMEncryptor) Decrypt(topic, keyID string, key, ciphertext []byte) ([]byte, error) {
+	aead, err := e.aead(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce := ciphertext[:aead.NonceSize()]
+	return aead.Open(nil, nonce, ciphertext[aead.NonceSize():], additionalData(topic, key))
+}
+
+// additionalData returns the data authenticated with the values of messages,
+// the topic is prefixed with its length so the topic and key cannot be confused
+// with another pair.
+func additionalData(topic string, key []byte) []byte {
+	b := make([]byte, 4, 4+len(topic)+len(key))
+	binary.BigEndian.PutUint32(b, uint32(len(topic)))
+	b = append(b, topic...)
+	return append(b, key...)
+}
+
+func (e *AESGCMEncryptor) aead(keyID string) (cipher.AEAD, error) {
+	key, ok := e.Keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %q", keyID)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// DecryptionError is returned by Reader.FetchMessage when the value of a
+// message could not be decrypted. The message is returned along with the
+// error, still encrypted, so programs can commit it to skip it.
+type DecryptionError struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	KeyID     string
+	Err       error
+}
+
+func (e *DecryptionError) Error() string {
+	return fmt.Sprintf("decrypting the message at offset %d of %s/%d with key %q: %v", e.Offset, e.Topic, e.Partition, e.KeyID, e.Err)
+}
+
+func (e *DecryptionError) Unwrap() error {
+	return e.Err
+}
+
+// encryptMessages returns a copy of msgs where the values were encrypted, the
+// messages passed by the program are not modified. Messages without values,
+// which are tombstones of compacted topics, are left as is.
+func encryptMessages(e Encryptor, topic string, msgs []Message) ([]Message, error) {
+	encrypted := make([]Message, len(msgs))
+
+	for i, msg := range msgs {
+		if msg.Value != nil {
+			t := msg.Topic
+			if t == "" {
+				t = topic
+			}
+
+			value, keyID, err := e.Encrypt(t, msg.Key, msg.Value)
+			if err != nil {
+				return nil, fmt.Errorf("encrypting the value of message %d: %w", i, err)
+			}
+
+			msg.SetHeader(EncryptionKeyHeader, []byte(keyID))
+			msg.Value = value
+		}
+		encrypted[i] = msg
+	}
+
+	return encrypted, nil
+}
+
+// decryptMessage decrypts the value of msg if it carries the encryption key
+// header, and removes the header.
+func decryptMessage(e Encryptor, msg Message) (Message, error) {
+	header, ok := msg.Header(EncryptionKeyHeader)
+	if !ok {
+		return msg, nil
+	}
+
+	keyID := string(header)
+	value, err := e.Decrypt(msg.Topic, keyID, msg.Key, msg.Value)
+	if err != nil {
+		return msg, &DecryptionError{
+			Topic:     msg.Topic,
+			Partition: msg.Partition,
+			Offset:    msg.Offset,
+			KeyID:     keyID,
+			Err:       err,
+		}
+	}
+
+	msg.DeleteHeader(EncryptionKeyHeader)
+	msg.Value = value
+	return msg, nil
+}
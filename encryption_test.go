@@ -0,0 +1,102 @@
+package kafka
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAESGCMEncryptor(t *testing.T) {
+	e := &AESGCMEncryptor{
+		KeyID: "k1",
+		Keys: map[string][]byte{
+			"k1": bytes.Repeat([]byte{1}, 32),
+		},
+	}
+
+	msgs := []Message{
+		{Topic: "topic", Key: []byte("a"), Value: []byte("hello"), Headers: []Header{{Key: "h", Value: []byte("v")}}},
+		{Topic: "other", Key: []byte("b")}, // tombstone
+	}
+
+	encrypted, err := encryptMessages(e, "topic", msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(msgs[0].Value) != "hello" || len(msgs[0].Headers) != 1 {
+		t.Error("the messages of the program were modified")
+	}
+	if bytes.Contains(encrypted[0].Value, []byte("hello")) {
+		t.Error("the value was not encrypted")
+	}
+	if want := []Header{{Key: "h", Value: []byte("v")}, {Key: EncryptionKeyHeader, Value: []byte("k1")}}; !reflect.DeepEqual(encrypted[0].Headers, want) {
+		t.Errorf("wrong headers: %+v", encrypted[0].Headers)
+	}
+	if !reflect.DeepEqual(encrypted[1], msgs[1]) {
+		t.Errorf("tombstones should not be encrypted: %+v", encrypted[1])
+	}
+
+	// Rotate the key, messages encrypted with the previous key can still be
+	// decrypted.
+	e = &AESGCMEncryptor{
+		KeyID: "k2",
+		Keys: map[string][]byte{
+			"k1": bytes.Repeat([]byte{1}, 32),
+			"k2": bytes.Repeat([]byte{2}, 16),
+		},
+	}
+
+	decrypted, err := decryptMessage(e, encrypted[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decrypted, msgs[0]) {
+		t.Errorf("wrong decrypted message:\nwant: %+v\ngot:  %+v", msgs[0], decrypted)
+	}
+
+	// The topic and key are authenticated with the value, which cannot be
+	// moved to another message.
+	moved := encrypted[0]
+	moved.Topic = "other"
+	if _, err := decryptMessage(e, moved); err == nil {
+		t.Error("a value moved to another topic should not decrypt")
+	}
+	moved = encrypted[0]
+	moved.Key = []byte("b")
+	if _, err := decryptMessage(e, moved); err == nil {
+		t.Error("a value moved to another key should not decrypt")
+	}
+
+	plain, err := decryptMessage(e, msgs[1])
+	if err != nil || !reflect.DeepEqual(plain, msgs[1]) {
+		t.Errorf("messages which were not encrypted should be returned as is: %+v (%v)", plain, err)
+	}
+}
+
+func TestDecryptionError(t *testing.T) {
+	e := &AESGCMEncryptor{KeyID: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 16)}}
+
+	encrypted, err := encryptMessages(e, "topic", []Message{{Value: []byte("hello"), Offset: 42}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted[0].Value[len(encrypted[0].Value)-1] ^= 1
+
+	msg, err := decryptMessage(e, encrypted[0])
+	var decryptErr *DecryptionError
+	if !errors.As(err, &decryptErr) {
+		t.Fatalf("wrong error: %v", err)
+	}
+	if decryptErr.KeyID != "k1" || decryptErr.Offset != 42 {
+		t.Errorf("wrong error details: %+v", decryptErr)
+	}
+	if !reflect.DeepEqual(msg, encrypted[0]) {
+		t.Error("the encrypted message should be returned with the error")
+	}
+
+	if _, _, err := (&AESGCMEncryptor{KeyID: "missing"}).Encrypt("topic", nil, []byte("hello")); err == nil {
+		t.Error("encrypting with an unknown key should fail")
+	}
+}
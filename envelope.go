@@ -0,0 +1,108 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/envelope"
+)
+
+// forward sends msg to the controller of the cluster wrapped in an Envelope
+// request (see KIP-590).
+//
+// The request was rejected because the metadata cached by the transport did
+// not point at the controller, so the controller is looked up with a
+// DescribeCluster request to the brokers. The wrapped request is serialized in
+// the highest API version supported by both the brokers and the program.
+func (c *Client) forward(ctx context.Context, addr net.Addr, msg protocol.Message) (protocol.Message, error) {
+	apiKey := msg.ApiKey()
+
+	cluster, err := c.DescribeCluster(ctx, &DescribeClusterRequest{Addr: addr})
+	if err != nil {
+		return nil, err
+	}
+	if cluster.Error != nil {
+		return nil, cluster.Error
+	}
+	if cluster.Controller.ID < 0 {
+		return nil, fmt.Errorf("%s requests cannot be forwarded to the controller: %w", apiKey, NotController)
+	}
+
+	versions, err := c.ApiVersions(ctx, &ApiVersionsRequest{Addr: addr})
+	if err != nil {
+		return nil, err
+	}
+
+	apiVersion := int16(-1)
+	for _, k := range versions.ApiKeys {
+		if k.ApiKey == int(apiKey) {
+			apiVersion = apiKey.SelectVersion(int16(k.MinVersion), int16(k.MaxVersion))
+		}
+	}
+	if apiVersion < 0 {
+		return nil, fmt.Errorf("%s requests cannot be forwarded to the controller: %w", apiKey, UnsupportedVersion)
+	}
+
+	if p, _ := msg.(protocol.PreparedMessage); p != nil {
+		// Prepare modifies the message, which belongs to the caller.
+		v := reflect.New(reflect.TypeOf(msg).Elem())
+		v.Elem().Set(reflect.ValueOf(msg).Elem())
+		msg = v.Interface().(protocol.Message)
+		msg.(protocol.PreparedMessage).Prepare(apiVersion)
+	}
+
+	req, err := envelope.NewRequest(msg, apiVersion, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	req.BrokerID = int32(cluster.Controller.ID)
+
+	m, err := c.transport().RoundTrip(ctx, addr, req)
+	if err != nil {
+		return nil, err
+	}
+
+	res := m.(*envelope.Response)
+	if res.ErrorCode != 0 {
+		return nil, Error(res.ErrorCode)
+	}
+
+	_, r, err := res.Decode(apiKey, apiVersion)
+	return r, err
+}
+
+// notController returns true if msg carries a NOT_CONTROLLER error code, which
+// brokers return to requests that must be handled by the controller.
+func notController(msg protocol.Message) bool {
+	return hasErrorCode(reflect.ValueOf(msg), int64(NotController))
+}
+
+func hasErrorCode(v reflect.Value, code int64) bool {
+	switch v.Kind() {
+	case reflect.Ptr:
+		return !v.IsNil() && hasErrorCode(v.Elem(), code)
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if f := t.Field(i); f.Name == "ErrorCode" && f.Type.Kind() == reflect.Int16 {
+				if v.Field(i).Int() == code {
+					return true
+				}
+			} else if hasErrorCode(v.Field(i), code) {
+				return true
+			}
+		}
+
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if hasErrorCode(v.Index(i), code) {
+				return true
+			}
+		}
+	}
+	return false
+}
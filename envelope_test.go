@@ -0,0 +1,118 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/createtopics"
+	"github.com/segmentio/kafka-go/protocol/deletetopics"
+	"github.com/segmentio/kafka-go/protocol/describecluster"
+	"github.com/segmentio/kafka-go/protocol/envelope"
+	metadataAPI "github.com/segmentio/kafka-go/protocol/metadata"
+)
+
+func TestNotController(t *testing.T) {
+	tests := []struct {
+		scenario string
+		msg      protocol.Message
+		expected bool
+	}{
+		{
+			scenario: "responses with no errors are not redirected",
+			msg: &createtopics.Response{
+				Topics: []createtopics.ResponseTopic{{Name: "topic-1"}},
+			},
+		},
+		{
+			scenario: "responses with other errors are not redirected",
+			msg: &deletetopics.Response{
+				Responses: []deletetopics.ResponseTopic{{Name: "topic-1", ErrorCode: int16(UnknownTopicOrPartition)}},
+			},
+		},
+		{
+			scenario: "responses with NOT_CONTROLLER errors are redirected",
+			msg: &createtopics.Response{
+				Topics: []createtopics.ResponseTopic{
+					{Name: "topic-1"},
+					{Name: "topic-2", ErrorCode: int16(NotController)},
+				},
+			},
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			if found := notController(test.msg); found != test.expected {
+				t.Errorf("wrong result: want=%t got=%t", test.expected, found)
+			}
+		})
+	}
+}
+
+func TestClientForwardToController(t *testing.T) {
+	const controllerID = 2
+
+	client := &Client{
+		Addr:                TCP("localhost:9092"),
+		ForwardToController: true,
+		Transport: roundTripperFunc(func(ctx context.Context, addr net.Addr, req Request) (Response, error) {
+			switch req := req.(type) {
+			case *metadataAPI.Request:
+				return &metadataAPI.Response{
+					Topics: []metadataAPI.ResponseTopic{{Name: "topic-1", ErrorCode: int16(NotController)}},
+				}, nil
+
+			case *describecluster.Request:
+				return &describecluster.Response{
+					ControllerID: controllerID,
+					Brokers:      []describecluster.ResponseBroker{{BrokerID: 1}, {BrokerID: controllerID}},
+				}, nil
+
+			case *apiversions.Request:
+				return &apiversions.Response{
+					ApiKeys: []apiversions.ApiKeyResponse{{ApiKey: int16(protocol.Metadata), MaxVersion: 12}},
+				}, nil
+
+			case *envelope.Request:
+				if req.BrokerID != controllerID {
+					t.Errorf("envelope sent to the wrong broker: want=%d got=%d", controllerID, req.BrokerID)
+				}
+
+				b := new(bytes.Buffer)
+				res := &metadataAPI.Response{
+					ControllerID: controllerID,
+					Topics:       []metadataAPI.ResponseTopic{{Name: "topic-1"}},
+				}
+				if err := protocol.WriteResponse(b, 12, 0, res); err != nil {
+					t.Fatal(err)
+				}
+				return &envelope.Response{ResponseData: b.Bytes()[4:]}, nil
+
+			default:
+				t.Fatalf("unexpected request: %T", req)
+				return nil, nil
+			}
+		}),
+	}
+
+	req := &metadataAPI.Request{TopicNames: []string{"topic-1"}}
+
+	m, err := client.roundTrip(context.Background(), nil, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := m.(*metadataAPI.Response)
+	if len(res.Topics) != 1 || res.Topics[0].ErrorCode != 0 {
+		t.Errorf("wrong forwarded response: %+v", res)
+	}
+
+	if req.Topics != nil {
+		t.Errorf("the request of the program was modified: %+v", req.Topics)
+	}
+}
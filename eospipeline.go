@@ -0,0 +1,526 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// EOSPipeline is a consume-transform-produce loop with exactly-once semantics:
+// the messages produced from a batch of input messages, and the offsets of the
+// input messages, are committed atomically in a kafka transaction.
+//
+// The pipeline reads from a Reader, which must be part of a consumer group, and
+// writes with the configuration of a Writer (address, transport, topic,
+// balancer, compression, encryption, interceptors). The Writer is not used to
+// write messages itself, its batching options are ignored. The program must
+// not commit the messages of the Reader, the offsets are committed by the
+// transactions of the pipeline.
+//
+// Consumers of the output topics must only read committed messages (see the
+// IsolationLevel field of ReaderConfig) to get exactly-once semantics.
+//
+// When the consumer group rebalances, the ongoing transaction is aborted and
+// the messages read in the previous generation are read again from the last
+// committed offsets.
+//
+// When the producer of the pipeline is fenced, for example because the broker
+// aborted a transaction which exceeded TransactionTimeout, the pipeline bumps
+// the epoch of its producer id (KIP-360, Kafka 2.5) and retries the batch. When
+// another producer using the same TransactionalID fenced the pipeline, or the
+// brokers do not support epoch bumps, Run returns an error for which IsFencing
+// is true.
+type EOSPipeline struct {
+	// The group reader that input messages are consumed from.
+	Reader *Reader
+
+	// The writer used to produce the output messages.
+	Writer *Writer
+
+	// The transactional id of the pipeline's producer. It must be stable
+	// across restarts of the program so the broker can fence previous
+	// instances of the pipeline, and abort their pending transactions.
+	TransactionalID string
+
+	// The maximum duration that the broker waits for a transaction to be
+	// committed before aborting it.
+	//
+	// Default: 1m
+	TransactionTimeout time.Duration
+
+	// Limit on the number of input messages committed in a single transaction.
+	//
+	// Default: 100
+	BatchSize int
+
+	// Time limit on how long the pipeline waits for input messages to fill a
+	// batch before committing it.
+	//
+	// Default: 100ms
+	BatchTimeout time.Duration
+
+	// Transform is called with each input message, and returns the messages
+	// to produce. Transform may return no messages, in which case only the
+	// offset of the input message is committed.
+	//
+	// Returning an error stops the pipeline, the messages of the current batch
+	// are not committed and will be transformed again when the pipeline is
+	// restarted.
+	Transform func(ctx context.Context, msg Message) ([]Message, error)
+
+	// OnFencing is an optional function called when the producer of the
+	// pipeline is fenced, with the error returned by the broker. recovered
+	// reports whether the pipeline bumped the epoch of its producer id and
+	// continues, otherwise Run returns err.
+	//
+	// The Writer does not use producer ids and is never fenced, programs which
+	// produce with a ProducerSession through Client.Produce can recover from
+	// fencing with Client.BumpProducerEpoch.
+	OnFencing func(err error, recovered bool)
+
+	producer  *ProducerSession
+	sequences map[topicPartition]int
+}
+
+// Run runs the pipeline until the context is canceled, the reader is closed,
+// or an error occurs. Run returns nil when the reader was closed.
+//
+// Run must not be called concurrently.
+func (p *EOSPipeline) Run(ctx context.Context) error {
+	switch {
+	case p.Reader == nil:
+		return errors.New("kafka.(*EOSPipeline).Run: Reader must not be nil")
+	case p.Reader.config.GroupID == "":
+		return errors.New("kafka.(*EOSPipeline).Run: Reader must be part of a consumer group")
+	case p.Writer == nil:
+		return errors.New("kafka.(*EOSPipeline).Run: Writer must not be nil")
+	case p.Writer.Addr == nil:
+		return errors.New("kafka.(*EOSPipeline).Run: Writer must have an address")
+	case p.TransactionalID == "":
+		return errors.New("kafka.(*EOSPipeline).Run: TransactionalID must not be empty")
+	case p.Transform == nil:
+		return errors.New("kafka.(*EOSPipeline).Run: Transform must not be nil")
+	}
+
+	for {
+		inputs, gen, err := p.fetch(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var outputs []Message
+		for _, msg := range inputs {
+			msgs, err := p.Transform(ctx, msg)
+			if err != nil {
+				return fmt.Errorf("transforming the message at offset %d of %s/%d: %w", msg.Offset, msg.Topic, msg.Partition, err)
+			}
+			outputs = append(outputs, msgs...)
+		}
+
+		if err := p.commit(ctx, gen, inputs, outputs); err != nil {
+			if errors.Is(err, ErrGenerationEnded) {
+				// The inputs will be read again by the member of the
+				// group which is assigned their partitions.
+				continue
+			}
+			return err
+		}
+	}
+}
+
+// fetch reads the next batch of input messages, which were all read in the
+// same generation of the consumer group.
+func (p *EOSPipeline) fetch(ctx context.Context) ([]Message, *Generation, error) {
+	var batch []Message
+	var gen *Generation
+	// Only the first message of a batch is waited for indefinitely.
+	fetchCtx := ctx
+
+	for len(batch) < p.batchSize() {
+		msg, g, err := p.Reader.fetchMessage(fetchCtx)
+		if err != nil {
+			if fetchCtx != ctx && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				break
+			}
+			return nil, nil, err
+		}
+
+		switch {
+		case g == nil:
+			// The generation ended while the message was in flight.
+			continue
+		case g != gen:
+			// The group rebalanced, the messages of the previous generation
+			// can no longer be committed.
+			batch, gen = batch[:0], g
+		}
+		batch = append(batch, msg)
+
+		if fetchCtx == ctx {
+			var cancel context.CancelFunc
+			fetchCtx, cancel = context.WithTimeout(ctx, p.batchTimeout())
+			defer cancel()
+		}
+	}
+
+	return batch, gen, nil
+}
+
+// commit commits the outputs and the offsets of the inputs in a transaction,
+// retrying transient errors.
+func (p *EOSPipeline) commit(ctx context.Context, gen *Generation, inputs, outputs []Message) (err error) {
+	w := p.Writer
+
+	if w.Interceptor != nil {
+		outputs = interceptMessages(ctx, w.Interceptor, outputs)
+		defer func() { w.Interceptor.OnAck(outputs, err) }()
+	}
+
+	if w.Encryptor != nil {
+		if outputs, err = encryptMessages(w.Encryptor, w.Topic, outputs); err != nil {
+			return err
+		}
+	}
+
+	records, err := p.partition(ctx, outputs)
+	if err != nil {
+		return err
+	}
+
+	offsets := make(map[string][]TxnOffsetCommit)
+	index := make(map[topicPartition]int)
+	for _, msg := range inputs {
+		key := topicPartition{topic: msg.Topic, partition: int32(msg.Partition)}
+		i, ok := index[key]
+		if !ok {
+			i = len(offsets[msg.Topic])
+			index[key] = i
+			offsets[msg.Topic] = append(offsets[msg.Topic], TxnOffsetCommit{Partition: msg.Partition})
+		}
+		// The committed offset is the one of the next message to read.
+		if c := &offsets[msg.Topic][i]; msg.Offset+1 > c.Offset {
+			c.Offset = msg.Offset + 1
+		}
+	}
+
+	policy := w.retryPolicy()
+	for attempt := 1; ; attempt++ {
+		err = p.initProducer(ctx)
+		if err == nil {
+			err = p.transaction(ctx, gen, records, offsets)
+			if err == nil {
+				return nil
+			}
+
+			if IsFencing(err) {
+				if !p.bumpEpoch(ctx, err) {
+					p.producer = nil
+					return err
+				}
+				if attempt >= policy.maxAttempts() {
+					return err
+				}
+				continue
+			}
+
+			p.abort(ctx)
+		}
+
+		switch {
+		case errors.Is(err, ErrGenerationEnded),
+			errors.Is(err, IllegalGeneration),
+			errors.Is(err, UnknownMemberId),
+			errors.Is(err, RebalanceInProgress):
+			return ErrGenerationEnded
+		case attempt >= policy.maxAttempts():
+			return err
+		// The coordinator reports concurrent transactions while it completes
+		// the transaction aborted by the previous attempt.
+		case !IsRetriable(err) && !errors.Is(err, ConcurrentTransactions):
+			return err
+		}
+
+		if !sleepClock(ctx, w.clock(), policy.backoff(attempt)) {
+			return ctx.Err()
+		}
+	}
+}
+
+// partition groups the messages by the topic partition they are produced to.
+func (p *EOSPipeline) partition(ctx context.Context, msgs []Message) (map[topicPartition][]Message, error) {
+	w := p.Writer
+	balancer := w.balancer()
+	records := make(map[topicPartition][]Message)
+
+	// Partition metadata passed to the router, loaded once per topic.
+	var routes map[string][]Partition
+
+	for _, msg := range msgs {
+		topic, err := w.chooseTopic(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		var partition int
+
+		if w.Router != nil {
+			partitions, ok := routes[topic]
+			if !ok {
+				if partitions, err = w.partitionMetadata(ctx, topic); err != nil {
+					return nil, err
+				}
+				if routes == nil {
+					routes = make(map[string][]Partition)
+				}
+				routes[topic] = partitions
+			}
+
+			if partition, err = w.route(msg, topic, partitions); err != nil {
+				return nil, err
+			}
+		} else {
+			numPartitions, err := w.partitions(ctx, topic)
+			if err != nil {
+				return nil, err
+			}
+
+			partition = balancer.Balance(msg, loadCachedPartitions(numPartitions)...)
+		}
+
+		key := topicPartition{topic: topic, partition: int32(partition)}
+		records[key] = append(records[key], msg)
+	}
+
+	return records, nil
+}
+
+// initProducer initializes the producer session of the pipeline, which aborts
+// the pending transactions of the previous sessions.
+func (p *EOSPipeline) initProducer(ctx context.Context) error {
+	if p.producer != nil {
+		return nil
+	}
+
+	res, err := p.Writer.client(p.Writer.writeTimeout()).InitProducerID(ctx, &InitProducerIDRequest{
+		Addr:                 p.Writer.Addr,
+		TransactionalID:      p.TransactionalID,
+		TransactionTimeoutMs: int(p.transactionTimeout() / time.Millisecond),
+		ProducerID:           -1,
+		ProducerEpoch:        -1,
+	})
+	if err != nil {
+		return err
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+
+	p.producer = res.Producer
+	p.sequences = make(map[topicPartition]int)
+	return nil
+}
+
+// bumpEpoch attempts to recover from an error fencing the producer of the
+// pipeline by bumping the epoch of its producer id, which aborts the ongoing
+// transaction. The broker refuses to bump the epoch when another producer took
+// over the transactional id. Brokers which do not support epoch bumps would
+// instead start a new session and fence the other producer, so the pipeline
+// does not attempt it.
+func (p *EOSPipeline) bumpEpoch(ctx context.Context, fenced error) (recovered bool) {
+	if p.OnFencing != nil {
+		defer func() { p.OnFencing(fenced, recovered) }()
+	}
+
+	if !errors.Is(fenced, InvalidProducerEpoch) && !errors.Is(fenced, ProducerFenced) {
+		return false
+	}
+
+	w := p.Writer
+	client := w.client(w.writeTimeout())
+
+	res, err := client.BumpProducerEpoch(ctx, &InitProducerIDRequest{
+		Addr:                 w.Addr,
+		TransactionalID:      p.TransactionalID,
+		TransactionTimeoutMs: int(p.transactionTimeout() / time.Millisecond),
+		ProducerID:           p.producer.ProducerID,
+		ProducerEpoch:        p.producer.ProducerEpoch,
+	})
+	if err == nil {
+		err = res.Error
+	}
+	if err != nil {
+		w.withErrorLogger(func(l Logger) {
+			logKV(l, "error bumping the producer epoch", "producer_epoch", p.producer.ProducerEpoch, "error", err)
+		}, "transactional_id", p.TransactionalID)
+		return false
+	}
+
+	w.withLogger(func(l Logger) {
+		logKV(l, "bumped the producer epoch", "producer_epoch", res.Producer.ProducerEpoch, "error", fenced)
+	}, "transactional_id", p.TransactionalID)
+	p.producer = res.Producer
+	p.sequences = make(map[topicPartition]int)
+	return true
+}
+
+// transaction runs a single transaction which produces the records and
+// commits the offsets.
+func (p *EOSPipeline) transaction(ctx context.Context, gen *Generation, records map[topicPartition][]Message, offsets map[string][]TxnOffsetCommit) error {
+	w := p.Writer
+	client := w.client(w.writeTimeout())
+
+	if len(records) != 0 {
+		topics := make(map[string][]AddPartitionToTxn)
+		for key := range records {
+			topics[key.topic] = append(topics[key.topic], AddPartitionToTxn{Partition: int(key.partition)})
+		}
+
+		res, err := client.AddPartitionsToTxn(ctx, &AddPartitionsToTxnRequest{
+			Addr:            w.Addr,
+			TransactionalID: p.TransactionalID,
+			ProducerID:      p.producer.ProducerID,
+			ProducerEpoch:   p.producer.ProducerEpoch,
+			Topics:          topics,
+		})
+		if err != nil {
+			return err
+		}
+		for topic, partitions := range res.Topics {
+			for _, partition := range partitions {
+				if partition.Error != nil {
+					return withErrorContext(partition.Error, topic, partition.Partition, "")
+				}
+			}
+		}
+
+		for key, msgs := range records {
+			sequence := p.sequences[key]
+
+			res, err := client.Produce(ctx, &ProduceRequest{
+				Addr:            w.Addr,
+				Topic:           key.topic,
+				Partition:       int(key.partition),
+				RequiredAcks:    RequireAll,
+				Compression:     w.Compression,
+				TransactionalID: p.TransactionalID,
+				Producer:        p.producer,
+				BaseSequence:    sequence,
+				Records:         &writerRecords{msgs: msgs},
+			})
+			if err != nil {
+				return err
+			}
+			if res.Error != nil {
+				return withErrorContext(res.Error, key.topic, int(key.partition), "")
+			}
+
+			// Sequence numbers wrap around to zero after reaching the
+			// maximum value of an int32.
+			p.sequences[key] = int(int32(sequence+len(msgs)) & math.MaxInt32)
+		}
+	}
+
+	addRes, err := client.AddOffsetsToTxn(ctx, &AddOffsetsToTxnRequest{
+		Addr:            w.Addr,
+		TransactionalID: p.TransactionalID,
+		ProducerID:      p.producer.ProducerID,
+		ProducerEpoch:   p.producer.ProducerEpoch,
+		GroupID:         gen.GroupID,
+	})
+	if err != nil {
+		return err
+	}
+	if addRes.Error != nil {
+		return withErrorContext(addRes.Error, "", -1, gen.GroupID)
+	}
+
+	commitRes, err := client.TxnOffsetCommit(ctx, &TxnOffsetCommitRequest{
+		Addr:            w.Addr,
+		TransactionalID: p.TransactionalID,
+		GroupID:         gen.GroupID,
+		ProducerID:      p.producer.ProducerID,
+		ProducerEpoch:   p.producer.ProducerEpoch,
+		GenerationID:    int(gen.ID),
+		MemberID:        gen.MemberID,
+		Topics:          offsets,
+	})
+	if err != nil {
+		return err
+	}
+	for topic, partitions := range commitRes.Topics {
+		for _, partition := range partitions {
+			if partition.Error != nil {
+				return withErrorContext(partition.Error, topic, partition.Partition, gen.GroupID)
+			}
+		}
+	}
+
+	// The offsets were committed for the generation that the inputs were
+	// read in, but the partitions may have been assigned to another member
+	// since then.
+	if p.Reader.currentGeneration() != gen {
+		return ErrGenerationEnded
+	}
+
+	endRes, err := client.EndTxn(ctx, &EndTxnRequest{
+		Addr:            w.Addr,
+		TransactionalID: p.TransactionalID,
+		ProducerID:      p.producer.ProducerID,
+		ProducerEpoch:   p.producer.ProducerEpoch,
+		Committed:       true,
+	})
+	if err != nil {
+		return err
+	}
+	return endRes.Error
+}
+
+// abort aborts the ongoing transaction. The producer session is always reset
+// after an abort, because the sequence numbers of the records written by the
+// transaction are unknown, and initializing the producer again also aborts the
+// transaction when EndTxn failed.
+func (p *EOSPipeline) abort(ctx context.Context) {
+	res, err := p.Writer.client(p.Writer.writeTimeout()).EndTxn(ctx, &EndTxnRequest{
+		Addr:            p.Writer.Addr,
+		TransactionalID: p.TransactionalID,
+		ProducerID:      p.producer.ProducerID,
+		ProducerEpoch:   p.producer.ProducerEpoch,
+		Committed:       false,
+	})
+	if err == nil {
+		err = res.Error
+	}
+	if err != nil {
+		p.Writer.withErrorLogger(func(l Logger) {
+			logKV(l, "error aborting the transaction", "error", err)
+		}, "transactional_id", p.TransactionalID)
+	}
+	p.producer = nil
+}
+
+func (p *EOSPipeline) transactionTimeout() time.Duration {
+	if p.TransactionTimeout > 0 {
+		return p.TransactionTimeout
+	}
+	return 1 * time.Minute
+}
+
+func (p *EOSPipeline) batchSize() int {
+	if p.BatchSize > 0 {
+		return p.BatchSize
+	}
+	return 100
+}
+
+func (p *EOSPipeline) batchTimeout() time.Duration {
+	if p.BatchTimeout > 0 {
+		return p.BatchTimeout
+	}
+	return 100 * time.Millisecond
+}
@@ -0,0 +1,291 @@
+package kafka_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func newEOSPipeline(t *testing.T, b *kafkatest.Broker) *kafka.EOSPipeline {
+	t.Helper()
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:           []string{b.Addr().String()},
+		GroupID:           "group-A",
+		Topic:             "input",
+		MaxWait:           10 * time.Millisecond,
+		HeartbeatInterval: 100 * time.Millisecond,
+	})
+	t.Cleanup(func() { r.Close() })
+
+	return &kafka.EOSPipeline{
+		Reader:          r,
+		Writer:          &kafka.Writer{Addr: b.Addr(), Topic: "output"},
+		TransactionalID: "pipeline-A",
+		BatchTimeout:    10 * time.Millisecond,
+		Transform: func(ctx context.Context, msg kafka.Message) ([]kafka.Message, error) {
+			return []kafka.Message{{Value: bytes.ToUpper(msg.Value)}}, nil
+		},
+	}
+}
+
+func appendInputs(t *testing.T, b *kafkatest.Broker, values ...string) {
+	t.Helper()
+	for i, value := range values {
+		if _, err := b.Append("input", i%2, kafkatest.Record{Value: []byte(value)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func waitForOutputs(t *testing.T, b *kafkatest.Broker, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		records := b.Records("output", 0)
+		if len(records) >= n || time.Now().After(deadline) {
+			values := make([]string, len(records))
+			for i, record := range records {
+				values[i] = string(record.Value)
+			}
+			sort.Strings(values)
+			return values
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEOSPipeline(t *testing.T) {
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "input", Partitions: 2}, kafkatest.Topic{Name: "output"})
+	appendInputs(t, b, "a", "b", "c", "d")
+
+	p := newEOSPipeline(t, b)
+	errs := make(chan error, 1)
+	go func() { errs <- p.Run(context.Background()) }()
+
+	if values := waitForOutputs(t, b, 4); fmt.Sprint(values) != "[A B C D]" {
+		t.Errorf("wrong outputs: %v", values)
+	}
+	for partition := 0; partition < 2; partition++ {
+		if offset, ok := b.CommittedOffset("group-A", "input", partition); !ok || offset != 2 {
+			t.Errorf("wrong committed offset for partition %d: %d (%t)", partition, offset, ok)
+		}
+	}
+
+	p.Reader.Close()
+	if err := <-errs; err != nil {
+		t.Errorf("closing the reader must stop the pipeline: %v", err)
+	}
+}
+
+func TestEOSPipelineRouter(t *testing.T) {
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "input", Partitions: 2}, kafkatest.Topic{Name: "output", Partitions: 2})
+	appendInputs(t, b, "a", "b")
+
+	p := newEOSPipeline(t, b)
+	p.Writer.Router = func(msg kafka.Message, partitions []kafka.Partition) (int, error) {
+		return partitions[len(partitions)-1].ID, nil
+	}
+	errs := make(chan error, 1)
+	go func() { errs <- p.Run(context.Background()) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(b.Records("output", 1)) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := len(b.Records("output", 1)); n != 2 {
+		t.Errorf("wrong number of outputs routed to partition 1: %d", n)
+	}
+	if n := len(b.Records("output", 0)); n != 0 {
+		t.Errorf("outputs were written to partition 0 instead of the routed partition: %d", n)
+	}
+
+	p.Reader.Close()
+	if err := <-errs; err != nil {
+		t.Errorf("closing the reader must stop the pipeline: %v", err)
+	}
+}
+
+func TestEOSPipelineFenced(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "input", Partitions: 2}, kafkatest.Topic{Name: "output"})
+	appendInputs(t, b, "a", "b")
+
+	p := newEOSPipeline(t, b)
+	errs := make(chan error, 1)
+	go func() { errs <- p.Run(ctx) }()
+
+	if values := waitForOutputs(t, b, 2); fmt.Sprint(values) != "[A B]" {
+		t.Fatalf("wrong outputs: %v", values)
+	}
+
+	// Another instance of the pipeline starting with the same transactional
+	// id fences the running one.
+	client := &kafka.Client{Addr: b.Addr()}
+	res, err := client.InitProducerID(ctx, &kafka.InitProducerIDRequest{
+		TransactionalID:      "pipeline-A",
+		TransactionTimeoutMs: 10000,
+		ProducerID:           -1,
+		ProducerEpoch:        -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	appendInputs(t, b, "c", "d")
+
+	err = <-errs
+	if !kafka.IsFencing(err) || !errors.Is(err, kafka.ProducerFenced) {
+		t.Errorf("expected the pipeline to be fenced but got %v", err)
+	}
+	if values := waitForOutputs(t, b, 2); len(values) != 2 {
+		t.Errorf("the outputs of the fenced pipeline must not be committed: %v", values)
+	}
+	for partition := 0; partition < 2; partition++ {
+		if offset, _ := b.CommittedOffset("group-A", "input", partition); offset != 1 {
+			t.Errorf("wrong committed offset for partition %d: %d", partition, offset)
+		}
+	}
+}
+
+func TestEOSPipelineEpochBump(t *testing.T) {
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "input", Partitions: 2}, kafkatest.Topic{Name: "output"})
+	appendInputs(t, b, "a", "b")
+
+	type fencing struct {
+		err       error
+		recovered bool
+	}
+	fencings := make(chan fencing, 10)
+
+	bumps := make(chan []interface{}, 10)
+
+	p := newEOSPipeline(t, b)
+	p.OnFencing = func(err error, recovered bool) { fencings <- fencing{err, recovered} }
+	p.Writer.StructuredLogger = kafka.StructuredLoggerFunc(func(ctx context.Context, level kafka.LogLevel, msg string, args ...interface{}) {
+		if msg == "bumped the producer epoch" {
+			bumps <- args
+		}
+	})
+	errs := make(chan error, 1)
+	go func() { errs <- p.Run(context.Background()) }()
+
+	if values := waitForOutputs(t, b, 2); fmt.Sprint(values) != "[A B]" {
+		t.Fatalf("wrong outputs: %v", values)
+	}
+
+	// The transaction expires, the broker bumps the epoch of the producer,
+	// which must bump it again to recover instead of failing.
+	if err := b.ExpireTransaction("pipeline-A"); err != nil {
+		t.Fatal(err)
+	}
+	appendInputs(t, b, "c", "d")
+
+	if values := waitForOutputs(t, b, 4); fmt.Sprint(values) != "[A B C D]" {
+		t.Errorf("wrong outputs: %v", values)
+	}
+
+	select {
+	case f := <-fencings:
+		if !errors.Is(f.err, kafka.InvalidProducerEpoch) || !f.recovered {
+			t.Errorf("wrong fencing: %v (recovered=%t)", f.err, f.recovered)
+		}
+	default:
+		t.Error("OnFencing was not called")
+	}
+
+	select {
+	case args := <-bumps:
+		if len(args) < 4 || args[0] != "transactional_id" || args[1] != "pipeline-A" || args[2] != "producer_epoch" {
+			t.Errorf("wrong arguments logged with the epoch bump: %v", args)
+		}
+	default:
+		t.Error("the epoch bump was not logged")
+	}
+
+	p.Reader.Close()
+	if err := <-errs; err != nil {
+		t.Errorf("closing the reader must stop the pipeline: %v", err)
+	}
+}
+
+func TestClientBumpProducerEpoch(t *testing.T) {
+	b := kafkatest.NewTestBroker(t)
+	client := &kafka.Client{Addr: b.Addr()}
+	ctx := context.Background()
+
+	res, err := client.InitProducerID(ctx, &kafka.InitProducerIDRequest{TransactionalID: "producer-A", TransactionTimeoutMs: 60000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	session := res.Producer
+
+	if err := b.ExpireTransaction("producer-A"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = client.BumpProducerEpoch(ctx, &kafka.InitProducerIDRequest{
+		TransactionalID:      "producer-A",
+		TransactionTimeoutMs: 60000,
+		ProducerID:           session.ProducerID,
+		ProducerEpoch:        session.ProducerEpoch,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if res.Producer.ProducerID != session.ProducerID || res.Producer.ProducerEpoch <= session.ProducerEpoch {
+		t.Errorf("the epoch was not bumped: %+v => %+v", session, res.Producer)
+	}
+
+	// The fenced session cannot be recovered again once its epoch was bumped.
+	res, err = client.BumpProducerEpoch(ctx, &kafka.InitProducerIDRequest{
+		TransactionalID:      "producer-A",
+		TransactionTimeoutMs: 60000,
+		ProducerID:           session.ProducerID,
+		ProducerEpoch:        session.ProducerEpoch,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !kafka.IsFencing(res.Error) {
+		t.Errorf("expected a fencing error but got %v", res.Error)
+	}
+}
+
+func TestEOSPipelineValidation(t *testing.T) {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   []string{"localhost:9092"},
+		Topic:     "input",
+		Partition: 0,
+	})
+	defer r.Close()
+
+	p := &kafka.EOSPipeline{
+		Reader:          r,
+		Writer:          &kafka.Writer{Addr: kafka.TCP("localhost:9092")},
+		TransactionalID: "pipeline-A",
+		Transform: func(ctx context.Context, msg kafka.Message) ([]kafka.Message, error) {
+			return nil, nil
+		},
+	}
+	if err := p.Run(context.Background()); err == nil {
+		t.Error("expected the pipeline to require a group reader")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"syscall"
 )
 
@@ -119,6 +120,26 @@ const (
 	InconsistentClusterID              Error = 104
 	TransactionalIDNotFound            Error = 105
 	FetchSessionTopicIDError           Error = 106
+	IneligibleReplica                  Error = 107
+	NewLeaderElected                   Error = 108
+	OffsetMovedToTieredStorage         Error = 109
+	FencedMemberEpoch                  Error = 110
+	UnreleasedInstanceID               Error = 111
+	UnsupportedAssignor                Error = 112
+	StaleMemberEpoch                   Error = 113
+	UnknownSubscriptionID              Error = 114
+	TelemetryTooLarge                  Error = 115
+	InvalidRegistration                Error = 116
+	TransactionAbortable               Error = 117
+	InvalidRecordState                 Error = 118
+	ShareSessionNotFound               Error = 119
+	InvalidShareSessionEpoch           Error = 120
+	FencedStateEpoch                   Error = 121
+	InvalidVoterKey                    Error = 122
+	DuplicateVoter                     Error = 123
+	VoterNotFound                      Error = 124
+	InvalidRegularExpression           Error = 125
+	RebootstrapRequired                Error = 126
 )
 
 // Error satisfies the error interface.
@@ -165,13 +186,48 @@ func (e Error) Temporary() bool {
 		ThrottlingQuotaExceeded,
 		UnknownTopicID,
 		InconsistentTopicID,
-		FetchSessionTopicIDError:
+		FetchSessionTopicIDError,
+		ShareSessionNotFound,
+		InvalidShareSessionEpoch:
 		return true
 	default:
 		return false
 	}
 }
 
+// Retriable returns true if the operation that generated the error may succeed
+// if retried at a later time, it is an alias of Temporary using the terminology
+// of the kafka documentation.
+func (e Error) Retriable() bool {
+	return e.Temporary()
+}
+
+// Fencing returns true if the error indicates that the client was fenced by a
+// newer instance using the same identity, like a producer with the same
+// transactional id or a consumer with the same group instance id. Retrying
+// does not help, the client must be recreated (or rejoin its group) to
+// recover.
+func (e Error) Fencing() bool {
+	switch e {
+	case InvalidProducerEpoch,
+		TransactionCoordinatorFenced,
+		FencedInstanceID,
+		ProducerFenced,
+		FencedMemberEpoch:
+		return true
+	default:
+		return false
+	}
+}
+
+// Fatal returns true if the error is neither retriable nor fencing, which
+// means that the operation cannot succeed without changing the request or the
+// configuration of the cluster (e.g. authorization failures, invalid
+// configurations, or unsupported versions).
+func (e Error) Fatal() bool {
+	return e != 0 && !e.Retriable() && !e.Fencing()
+}
+
 // Title returns a human readable title for the error.
 func (e Error) Title() string {
 	switch e {
@@ -201,6 +257,8 @@ func (e Error) Title() string {
 		return "Stale Controller Epoch"
 	case OffsetMetadataTooLarge:
 		return "Offset Metadata Too Large"
+	case NetworkException:
+		return "Network Exception"
 	case GroupLoadInProgress:
 		return "Group Load In Progress"
 	case GroupCoordinatorNotAvailable:
@@ -327,6 +385,18 @@ func (e Error) Title() string {
 		return "Unknown Leader Epoch"
 	case UnsupportedCompressionType:
 		return "Unsupported Compression Type"
+	case StaleBrokerEpoch:
+		return "Stale Broker Epoch"
+	case OffsetNotAvailable:
+		return "Offset Not Available"
+	case MemberIDRequired:
+		return "Member ID Required"
+	case PreferredLeaderNotAvailable:
+		return "Preferred Leader Not Available"
+	case GroupMaxSizeReached:
+		return "Group Max Size Reached"
+	case FencedInstanceID:
+		return "Fenced Instance ID"
 	case EligibleLeadersNotAvailable:
 		return "Eligible Leader Not Available"
 	case ElectionNotNeeded:
@@ -375,6 +445,46 @@ func (e Error) Title() string {
 		return "Transactional ID Not Found"
 	case FetchSessionTopicIDError:
 		return "Fetch Session Topic ID Error"
+	case IneligibleReplica:
+		return "Ineligible Replica"
+	case NewLeaderElected:
+		return "New Leader Elected"
+	case OffsetMovedToTieredStorage:
+		return "Offset Moved To Tiered Storage"
+	case FencedMemberEpoch:
+		return "Fenced Member Epoch"
+	case UnreleasedInstanceID:
+		return "Unreleased Instance ID"
+	case UnsupportedAssignor:
+		return "Unsupported Assignor"
+	case StaleMemberEpoch:
+		return "Stale Member Epoch"
+	case UnknownSubscriptionID:
+		return "Unknown Subscription ID"
+	case TelemetryTooLarge:
+		return "Telemetry Too Large"
+	case InvalidRegistration:
+		return "Invalid Registration"
+	case TransactionAbortable:
+		return "Transaction Abortable"
+	case InvalidRecordState:
+		return "Invalid Record State"
+	case ShareSessionNotFound:
+		return "Share Session Not Found"
+	case InvalidShareSessionEpoch:
+		return "Invalid Share Session Epoch"
+	case FencedStateEpoch:
+		return "Fenced State Epoch"
+	case InvalidVoterKey:
+		return "Invalid Voter Key"
+	case DuplicateVoter:
+		return "Duplicate Voter"
+	case VoterNotFound:
+		return "Voter Not Found"
+	case InvalidRegularExpression:
+		return "Invalid Regular Expression"
+	case RebootstrapRequired:
+		return "Rebootstrap Required"
 	}
 	return ""
 }
@@ -408,6 +518,8 @@ func (e Error) Description() string {
 		return "internal error code for broker-to-broker communication"
 	case OffsetMetadataTooLarge:
 		return "the client specified a string larger than configured maximum for offset metadata"
+	case NetworkException:
+		return "the server disconnected before a response was received"
 	case GroupLoadInProgress:
 		return "the broker returns this error code for an offset fetch request if it is still loading offsets (after a leader change for that offsets topic partition), or in response to group membership requests (such as heartbeats) when group metadata is being loaded by the coordinator"
 	case GroupCoordinatorNotAvailable:
@@ -534,6 +646,18 @@ func (e Error) Description() string {
 		return "the leader epoch in the request is newer than the epoch on the broker"
 	case UnsupportedCompressionType:
 		return "the requesting client does not support the compression type of given partition"
+	case StaleBrokerEpoch:
+		return "the broker epoch has changed"
+	case OffsetNotAvailable:
+		return "the leader high watermark has not caught up from a recent leader election so the offsets cannot be guaranteed to be monotonically increasing"
+	case MemberIDRequired:
+		return "the group member needs to have a valid member id before actually entering a consumer group"
+	case PreferredLeaderNotAvailable:
+		return "the preferred leader was not available"
+	case GroupMaxSizeReached:
+		return "the consumer group has reached its max size"
+	case FencedInstanceID:
+		return "the broker rejected this static consumer since another consumer with the same group.instance.id has registered with a different member.id"
 	case EligibleLeadersNotAvailable:
 		return "eligible topic partition leaders are not available"
 	case ElectionNotNeeded:
@@ -582,6 +706,46 @@ func (e Error) Description() string {
 		return "The transactionalId could not be found"
 	case FetchSessionTopicIDError:
 		return "The fetch session encountered inconsistent topic ID usage"
+	case IneligibleReplica:
+		return "The new ISR contains at least one ineligible replica"
+	case NewLeaderElected:
+		return "The AlterPartition request successfully updated the partition state but the leader has changed"
+	case OffsetMovedToTieredStorage:
+		return "The requested offset is moved to tiered storage"
+	case FencedMemberEpoch:
+		return "The member epoch is fenced by the group coordinator. The member must abandon all its partitions and rejoin"
+	case UnreleasedInstanceID:
+		return "The instance ID is still used by another member in the consumer group. That member must leave first"
+	case UnsupportedAssignor:
+		return "The assignor or its version range is not supported by the consumer group"
+	case StaleMemberEpoch:
+		return "The member epoch is stale. The member must retry after receiving its updated member epoch via the ConsumerGroupHeartbeat API"
+	case UnknownSubscriptionID:
+		return "Client sent a push telemetry request with an invalid or outdated subscription ID"
+	case TelemetryTooLarge:
+		return "Client sent a push telemetry request larger than the maximum size the broker will accept"
+	case InvalidRegistration:
+		return "The controller has considered the broker registration to be invalid"
+	case TransactionAbortable:
+		return "The server encountered an error with the transaction. The client can abort the transaction to continue using this transactional ID"
+	case InvalidRecordState:
+		return "The record state is invalid. The acknowledgement of delivery could not be completed"
+	case ShareSessionNotFound:
+		return "The share session was not found"
+	case InvalidShareSessionEpoch:
+		return "The share session epoch is invalid"
+	case FencedStateEpoch:
+		return "The share coordinator rejected the request because the share-group state epoch did not match"
+	case InvalidVoterKey:
+		return "The voter key doesn't match the receiving replica's key"
+	case DuplicateVoter:
+		return "The voter is already part of the set of voters"
+	case VoterNotFound:
+		return "The voter is not part of the set of voters"
+	case InvalidRegularExpression:
+		return "The regular expression is not valid"
+	case RebootstrapRequired:
+		return "Client metadata is stale. The client should rebootstrap to obtain new metadata"
 	}
 	return ""
 }
@@ -632,6 +796,106 @@ func coalesceErrors(errs ...error) error {
 	return nil
 }
 
+// IsRetriable returns true if err, or one of the errors it wraps, indicates that
+// the operation may succeed if retried. This includes retriable errors
+// returned by kafka brokers, as well as timeouts and transient network errors.
+func IsRetriable(err error) bool {
+	return isTemporary(err) || isTransientNetworkError(err)
+}
+
+// IsFencing returns true if err wraps an Error returned by a kafka broker which
+// indicates that the client was fenced, see Error.Fencing.
+func IsFencing(err error) bool {
+	var e Error
+	return errors.As(err, &e) && e.Fencing()
+}
+
+// IsFatal returns true if err wraps an Error returned by a kafka broker which
+// cannot be recovered from by retrying, see Error.Fatal.
+func IsFatal(err error) bool {
+	var e Error
+	return errors.As(err, &e) && e.Fatal()
+}
+
+// BrokerError wraps errors returned by kafka brokers with the topic, partition,
+// and consumer group that they were returned for, when known. The error code
+// can be extracted with errors.As, or compared with errors.Is:
+//
+//	if errors.Is(err, kafka.UnknownTopicOrPartition) {
+//		...
+//	}
+//
+//	var brokerErr *kafka.BrokerError
+//	if errors.As(err, &brokerErr) {
+//		log.Printf("%s[%d]: %v", brokerErr.Topic, brokerErr.Partition, brokerErr.Err)
+//	}
+type BrokerError struct {
+	// The error returned by the broker, which wraps an Error.
+	Err error
+
+	// The topic and partition that the error was returned for. The topic is
+	// empty and the partition is -1 when the error was not specific to them.
+	Topic     string
+	Partition int
+
+	// The consumer group that the error was returned for, or empty if the
+	// error was not specific to a group.
+	Group string
+}
+
+func (e *BrokerError) Error() string {
+	s := e.Err.Error()
+	var context []string
+	if e.Topic != "" {
+		context = append(context, fmt.Sprintf("topic=%q", e.Topic))
+	}
+	if e.Partition >= 0 {
+		context = append(context, fmt.Sprintf("partition=%d", e.Partition))
+	}
+	if e.Group != "" {
+		context = append(context, fmt.Sprintf("group=%q", e.Group))
+	}
+	if len(context) != 0 {
+		s += " (" + strings.Join(context, " ") + ")"
+	}
+	return s
+}
+
+func (e *BrokerError) Unwrap() error {
+	return e.Err
+}
+
+// withErrorContext returns err wrapped in a *BrokerError carrying the given
+// context if it is an error returned by a kafka broker. The missing fields are
+// filled when err already is a *BrokerError, and other errors, or errors
+// without context to add, are returned unchanged.
+func withErrorContext(err error, topic string, partition int, group string) error {
+	if topic == "" && partition < 0 && group == "" {
+		return err
+	}
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *BrokerError:
+		c := *e
+		if c.Topic == "" {
+			c.Topic = topic
+		}
+		if c.Partition < 0 {
+			c.Partition = partition
+		}
+		if c.Group == "" {
+			c.Group = group
+		}
+		return &c
+	}
+	var e Error
+	if !errors.As(err, &e) {
+		return err
+	}
+	return &BrokerError{Err: err, Topic: topic, Partition: partition, Group: group}
+}
+
 type MessageTooLargeError struct {
 	Message   Message
 	Remaining []Message
@@ -651,6 +915,12 @@ func (e MessageTooLargeError) Error() string {
 	return MessageSizeTooLarge.Error()
 }
 
+// Unwrap returns MessageSizeTooLarge, so errors.Is can be used to test for
+// MessageTooLargeError values.
+func (e MessageTooLargeError) Unwrap() error {
+	return MessageSizeTooLarge
+}
+
 func makeError(code int16, message string) error {
 	if code == 0 {
 		return nil
@@ -699,3 +969,15 @@ func (err WriteErrors) Count() int {
 func (err WriteErrors) Error() string {
 	return fmt.Sprintf("kafka write errors (%d/%d)", err.Count(), len(err))
 }
+
+// Unwrap returns the non-nil errors of err, so errors.Is and errors.As match
+// the errors of individual messages on Go 1.20 and later.
+func (err WriteErrors) Unwrap() []error {
+	errs := make([]error, 0, len(err))
+	for _, e := range err {
+		if e != nil {
+			errs = append(errs, e)
+		}
+	}
+	return errs
+}
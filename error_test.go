@@ -1,7 +1,10 @@
 package kafka
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -111,3 +114,88 @@ func TestError(t *testing.T) {
 		}
 	})
 }
+
+func TestErrorCodesHaveTitleAndDescription(t *testing.T) {
+	for code := Unknown; code <= RebootstrapRequired; code++ {
+		if code == 0 {
+			continue
+		}
+		if len(code.Title()) == 0 || len(code.Description()) == 0 {
+			t.Errorf("error code %d has no title or description", int(code))
+		}
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		err                       Error
+		retriable, fencing, fatal bool
+	}{
+		{err: NotLeaderForPartition, retriable: true},
+		{err: RequestTimedOut, retriable: true},
+		{err: ProducerFenced, fencing: true},
+		{err: FencedInstanceID, fencing: true},
+		{err: TopicAuthorizationFailed, fatal: true},
+		{err: UnsupportedVersion, fatal: true},
+		{err: Error(0)},
+	}
+
+	for _, test := range tests {
+		if test.err.Retriable() != test.retriable || test.err.Fencing() != test.fencing || test.err.Fatal() != test.fatal {
+			t.Errorf("wrong classification of %d: retriable=%t fencing=%t fatal=%t",
+				int(test.err), test.err.Retriable(), test.err.Fencing(), test.err.Fatal())
+		}
+
+		wrapped := fmt.Errorf("wrapped: %w", withErrorContext(test.err, "topic-A", 0, ""))
+		if test.err != 0 && (IsRetriable(wrapped) != test.retriable || IsFencing(wrapped) != test.fencing || IsFatal(wrapped) != test.fatal) {
+			t.Errorf("wrong classification of wrapped error %d", int(test.err))
+		}
+	}
+
+	if !IsRetriable(io.ErrUnexpectedEOF) || IsFatal(io.ErrUnexpectedEOF) {
+		t.Error("transient network errors must be retriable")
+	}
+}
+
+func TestBrokerError(t *testing.T) {
+	err := withErrorContext(makeError(int16(NotLeaderForPartition), "leader moved"), "topic-A", 1, "")
+	err = withErrorContext(err, "", -1, "group-A")
+
+	var brokerErr *BrokerError
+	if !errors.As(err, &brokerErr) {
+		t.Fatalf("expected a *BrokerError but got %T", err)
+	}
+	if brokerErr.Topic != "topic-A" || brokerErr.Partition != 1 || brokerErr.Group != "group-A" {
+		t.Errorf("wrong error context: %+v", brokerErr)
+	}
+	if !errors.Is(err, NotLeaderForPartition) {
+		t.Error("the error code is not matched by errors.Is")
+	}
+	if s := err.Error(); !strings.HasSuffix(s, `leader moved (topic="topic-A" partition=1 group="group-A")`) {
+		t.Errorf("wrong error message: %s", s)
+	}
+
+	if err := withErrorContext(io.EOF, "topic-A", 0, ""); err != io.EOF {
+		t.Errorf("errors which are not from kafka brokers must not be wrapped: %v", err)
+	}
+
+	if err := withErrorContext(RebalanceInProgress, "", -1, ""); err != RebalanceInProgress {
+		t.Errorf("errors without context must not be wrapped: %v", err)
+	}
+}
+
+func TestWrapperErrors(t *testing.T) {
+	errs := WriteErrors{nil, withErrorContext(MessageSizeTooLarge, "topic-A", 0, "")}
+	if !errors.Is(errs, MessageSizeTooLarge) {
+		t.Error("the errors of WriteErrors are not matched by errors.Is")
+	}
+	var brokerErr *BrokerError
+	if !errors.As(errs, &brokerErr) {
+		t.Error("the errors of WriteErrors are not matched by errors.As")
+	}
+
+	msgs := []Message{{Value: []byte("A")}, {Value: []byte("B")}}
+	if !errors.Is(messageTooLarge(msgs, 0), MessageSizeTooLarge) {
+		t.Error("MessageTooLargeError does not match MessageSizeTooLarge")
+	}
+}
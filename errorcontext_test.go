@@ -0,0 +1,50 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func TestWriterErrorContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+
+	faults := &kafkatest.Faults{}
+	faults.Inject(kafkatest.Fault{
+		ApiKeys:   []protocol.ApiKey{protocol.Produce},
+		ErrorCode: kafka.TopicAuthorizationFailed,
+	})
+
+	transport := &kafka.Transport{Dial: faults.Dial}
+	defer transport.CloseIdleConnections()
+
+	w := &kafka.Writer{
+		Addr:         b.Addr(),
+		Topic:        "topic-A",
+		Transport:    transport,
+		BatchTimeout: time.Millisecond,
+		RequiredAcks: kafka.RequireOne,
+	}
+	defer w.Close()
+
+	err := w.WriteMessages(ctx, kafka.Message{Value: []byte("A")})
+	if !errors.Is(err, kafka.TopicAuthorizationFailed) || !kafka.IsFatal(err) {
+		t.Fatalf("expected a fatal TopicAuthorizationFailed error but got %v", err)
+	}
+
+	var brokerErr *kafka.BrokerError
+	if !errors.As(err, &brokerErr) {
+		t.Fatalf("expected a *kafka.BrokerError but got %v", err)
+	}
+	if brokerErr.Topic != "topic-A" || brokerErr.Partition != 0 {
+		t.Errorf("wrong error context: %+v", brokerErr)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 )
@@ -92,3 +93,64 @@ func ExampleGeneration_CommitOffsets_overwriteOffsets() {
 		os.Exit(1)
 	}
 }
+
+func ExampleGeneration_Done_conn() {
+	group, err := kafka.NewConsumerGroup(kafka.ConsumerGroupConfig{
+		ID:      "my-group",
+		Brokers: []string{"kafka:9092"},
+		Topics:  []string{"my-topic"},
+	})
+	if err != nil {
+		fmt.Printf("error creating consumer group: %+v\n", err)
+		os.Exit(1)
+	}
+	defer group.Close()
+
+	for {
+		gen, err := group.Next(context.TODO())
+		if err != nil {
+			break
+		}
+
+		// consume the assigned partitions on connections to their leaders until
+		// the generation ends.
+		for _, assignment := range gen.Assignments["my-topic"] {
+			partition, offset := assignment.ID, assignment.Offset
+			gen.Start(func(ctx context.Context) {
+				conn, err := kafka.DialLeader(ctx, "tcp", "kafka:9092", "my-topic", partition)
+				if err != nil {
+					fmt.Printf("error dialing leader: %+v\n", err)
+					return
+				}
+				defer conn.Close()
+
+				if _, err := conn.Seek(offset, kafka.SeekAbsolute); err != nil {
+					fmt.Printf("error seeking: %+v\n", err)
+					return
+				}
+
+				for {
+					select {
+					case <-gen.Done():
+						return
+					default:
+					}
+
+					conn.SetReadDeadline(time.Now().Add(time.Second))
+					batch := conn.ReadBatch(1, 1e6)
+					for {
+						msg, err := batch.ReadMessage()
+						if err != nil {
+							break
+						}
+						fmt.Printf("received message %s/%d/%d : %s\n", msg.Topic, msg.Partition, msg.Offset, string(msg.Value))
+						offset = msg.Offset + 1
+					}
+					batch.Close()
+
+					gen.CommitOffsets(map[string]map[int]int64{"my-topic": {partition: offset}})
+				}
+			})
+		}
+	}
+}
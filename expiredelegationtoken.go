@@ -0,0 +1,65 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/expiredelegationtoken"
+)
+
+// ExpireDelegationTokenRequest represents a request sent to a kafka broker to
+// expire a delegation token.
+type ExpireDelegationTokenRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// HMAC of the token to expire.
+	HMAC []byte
+
+	// The period after which the token expires, if zero the token expires
+	// immediately.
+	ExpiryTimePeriod time.Duration
+}
+
+// ExpireDelegationTokenResponse represents a response from a kafka broker to a
+// delegation token expiration request.
+type ExpireDelegationTokenResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// An error that may have occurred while attempting to expire the token.
+	//
+	// The error contains both the kafka error code, and an error message
+	// returned by the kafka broker. Programs may use the standard errors.Is
+	// function to test the error against kafka error codes.
+	Error error
+
+	// The new expiry time of the token.
+	ExpiryTime time.Time
+}
+
+// ExpireDelegationToken sends a delegation token expiration request to a kafka
+// broker and returns the response.
+func (c *Client) ExpireDelegationToken(ctx context.Context, req *ExpireDelegationTokenRequest) (*ExpireDelegationTokenResponse, error) {
+	expiryTimePeriodMs := int64(-1)
+	if req.ExpiryTimePeriod > 0 {
+		expiryTimePeriodMs = int64(req.ExpiryTimePeriod / time.Millisecond)
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &expiredelegationtoken.Request{
+		HMAC:               req.HMAC,
+		ExpiryTimePeriodMs: expiryTimePeriodMs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).ExpireDelegationToken: %w", err)
+	}
+
+	res := m.(*expiredelegationtoken.Response)
+	return &ExpireDelegationTokenResponse{
+		Throttle:   makeDuration(res.ThrottleTimeMs),
+		Error:      makeError(res.ErrorCode, ""),
+		ExpiryTime: makeTime(res.ExpiryTimestampMs),
+	}, nil
+}
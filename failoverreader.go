@@ -0,0 +1,433 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// FailoverReaderConfig is a configuration object used to create new instances
+// of FailoverReader.
+type FailoverReaderConfig struct {
+	// The ordered list of clusters that the reader consumes from, each given
+	// by its list of bootstrap broker addresses. The reader starts consuming
+	// from the first cluster, and fails over to the next one in the list when
+	// the active cluster is unreachable, wrapping around after the last one.
+	Clusters [][]string
+
+	// The configuration of the readers consuming from the clusters. The
+	// Brokers field is ignored, it is replaced with the brokers of the active
+	// cluster.
+	Reader ReaderConfig
+
+	// How long the active cluster must be unreachable before the reader fails
+	// over to the next one.
+	//
+	// Default: 30s
+	FailoverAfter time.Duration
+
+	// The interval at which the reader checks whether the active cluster is
+	// reachable.
+	//
+	// Default: 1s
+	ProbeInterval time.Duration
+
+	// An optional function called when the reader failed over from one
+	// cluster to another, with the indexes of the clusters in Clusters, and
+	// the error which made the previous cluster unreachable.
+	OnFailover func(from, to int, err error)
+}
+
+// Validate method validates FailoverReaderConfig properties.
+func (config *FailoverReaderConfig) Validate() error {
+	var errs configErrors
+
+	if len(config.Clusters) == 0 {
+		errs.add(errors.New("cannot create a new kafka failover reader with an empty list of clusters"))
+	}
+
+	for i, brokers := range config.Clusters {
+		if len(brokers) == 0 {
+			errs.addf("cluster %d has an empty list of broker addresses", i)
+		}
+	}
+
+	if config.FailoverAfter < 0 {
+		errs.addf("invalid negative failover delay (failover after = %v)", config.FailoverAfter)
+	}
+
+	if config.ProbeInterval < 0 {
+		errs.addf("invalid negative probe interval (probe interval = %v)", config.ProbeInterval)
+	}
+
+	if len(config.Clusters) != 0 {
+		readerConfig := config.Reader
+		readerConfig.Brokers = config.Clusters[0]
+		if err := readerConfig.Validate(); err != nil {
+			var configErr *ConfigError
+			if errors.As(err, &configErr) {
+				errs = append(errs, configErr.Errors...)
+			} else {
+				errs.add(err)
+			}
+		}
+	}
+
+	return errs.err()
+}
+
+// FailoverReader is a reader consuming from one of multiple clusters which
+// replicate the same topics, for example with MirrorMaker, and failing over to
+// the next cluster when the active one is unreachable.
+//
+// The offsets of a topic partition differ between clusters, so the reader
+// translates its position when failing over, using the timestamps of the last
+// messages that it consumed: the timestamps of the messages committed by the
+// program when consuming with a consumer group, or the timestamps of the
+// messages fetched otherwise. The reader resumes from the first message of the
+// next cluster with a timestamp equal to or greater than the last one
+// consumed, so some messages may be read again after a failover. Message
+// timestamps must be preserved by the replication for the translation to be
+// accurate.
+//
+// When consuming with a consumer group, the translated offsets are committed
+// to the group of the next cluster before the reader joins it, which requires
+// the group to have no active members in that cluster.
+//
+// Messages fetched before a failover must not be committed after it, their
+// offsets are not valid in the next cluster.
+type FailoverReader struct {
+	config FailoverReaderConfig
+
+	mutex     sync.Mutex
+	reader    *Reader
+	active    int
+	lastSeen  time.Time
+	positions map[TopicPartition]time.Time
+	closed    bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewFailoverReader creates and returns a new FailoverReader configured with
+// config, consuming from the first cluster.
+func NewFailoverReader(config FailoverReaderConfig) *FailoverReader {
+	if err := config.Validate(); err != nil {
+		panic(err)
+	}
+
+	if config.Reader.Dialer == nil {
+		config.Reader.Dialer = DefaultDialer
+	}
+
+	if config.FailoverAfter == 0 {
+		config.FailoverAfter = 30 * time.Second
+	}
+
+	if config.ProbeInterval == 0 {
+		config.ProbeInterval = 1 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := &FailoverReader{
+		config:    config,
+		reader:    NewReader(config.readerConfig(0)),
+		lastSeen:  config.Reader.clock().Now(),
+		positions: make(map[TopicPartition]time.Time),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+
+	go r.probe(ctx)
+	return r
+}
+
+func (config *FailoverReaderConfig) readerConfig(cluster int) ReaderConfig {
+	c := config.Reader
+	c.Brokers = config.Clusters[cluster]
+	return c
+}
+
+func (config *ReaderConfig) clock() Clock { return clockOrDefault(config.Clock) }
+
+// Cluster returns the index in Clusters of the cluster that the reader is
+// consuming from.
+func (r *FailoverReader) Cluster() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.active
+}
+
+// Stats returns a snapshot of the stats of the reader of the active cluster.
+func (r *FailoverReader) Stats() ReaderStats {
+	if reader := r.current(); reader != nil {
+		return reader.Stats()
+	}
+	return ReaderStats{}
+}
+
+// SnapshotStats is like Reader.SnapshotStats, it writes a snapshot of the stats
+// of the reader of the active cluster to stats.
+func (r *FailoverReader) SnapshotStats(stats *ReaderStats, mode StatsMode) {
+	if reader := r.current(); reader != nil {
+		reader.SnapshotStats(stats, mode)
+		return
+	}
+	*stats = ReaderStats{}
+}
+
+// ReadMessage is like Reader.ReadMessage, it reads from the active cluster.
+func (r *FailoverReader) ReadMessage(ctx context.Context) (Message, error) {
+	m, err := r.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+
+	if r.config.Reader.GroupID != "" {
+		if err := r.CommitMessages(ctx, m); err != nil {
+			return Message{}, err
+		}
+	}
+
+	return m, nil
+}
+
+// FetchMessage is like Reader.FetchMessage, it fetches from the active
+// cluster.
+//
+// The method returns io.EOF to indicate that the reader has been closed.
+func (r *FailoverReader) FetchMessage(ctx context.Context) (Message, error) {
+	for {
+		reader := r.current()
+		if reader == nil {
+			return Message{}, io.EOF
+		}
+
+		m, err := reader.FetchMessage(ctx)
+		if err != nil {
+			// The reader of the previous cluster returns io.EOF when it is
+			// closed by a failover, fetch from the next cluster instead.
+			if next := r.current(); next != nil && next != reader {
+				continue
+			}
+			return m, err
+		}
+
+		if r.config.Reader.GroupID == "" {
+			r.track(m)
+		}
+		return m, nil
+	}
+}
+
+// CommitMessages is like Reader.CommitMessages, it commits to the active
+// cluster.
+func (r *FailoverReader) CommitMessages(ctx context.Context, msgs ...Message) error {
+	reader := r.current()
+	if reader == nil {
+		return io.ErrClosedPipe
+	}
+
+	if err := reader.CommitMessages(ctx, msgs...); err != nil {
+		return err
+	}
+
+	r.track(msgs...)
+	return nil
+}
+
+// Close stops the reader of the active cluster and the probes of the cluster.
+func (r *FailoverReader) Close() error {
+	r.mutex.Lock()
+	reader := r.reader
+	r.reader, r.closed = nil, true
+	r.mutex.Unlock()
+
+	if reader == nil {
+		return nil
+	}
+
+	r.cancel()
+	<-r.done
+	return reader.Close()
+}
+
+func (r *FailoverReader) current() *Reader {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.reader
+}
+
+// track records the timestamps of the last messages consumed on each
+// partition.
+func (r *FailoverReader) track(msgs ...Message) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, m := range msgs {
+		key := TopicPartition{Topic: m.Topic, Partition: m.Partition}
+		if t, ok := r.positions[key]; !ok || m.Time.After(t) {
+			r.positions[key] = m.Time
+		}
+	}
+}
+
+// probe checks whether the active cluster is reachable every probe interval,
+// and fails over to the next cluster when it was unreachable for too long.
+func (r *FailoverReader) probe(ctx context.Context) {
+	defer close(r.done)
+
+	clock := r.config.Reader.clock()
+	ticker := clock.NewTicker(r.config.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			return
+		}
+
+		r.mutex.Lock()
+		active, lastSeen := r.active, r.lastSeen
+		r.mutex.Unlock()
+
+		err := r.reachable(ctx, r.config.Clusters[active])
+		now := clock.Now()
+
+		switch {
+		case err == nil:
+			r.mutex.Lock()
+			r.lastSeen = now
+			r.mutex.Unlock()
+
+		case ctx.Err() != nil:
+			return
+
+		case len(r.config.Clusters) > 1 && now.Sub(lastSeen) >= r.config.FailoverAfter:
+			next := (active + 1) % len(r.config.Clusters)
+			if failoverErr := r.failover(ctx, next); failoverErr != nil {
+				r.withErrorLogger(func(l Logger) {
+					l.Printf("failing over from cluster %d to cluster %d: %v", active, next, failoverErr)
+				})
+				continue
+			}
+			if r.config.OnFailover != nil {
+				r.config.OnFailover(active, next, err)
+			}
+		}
+	}
+}
+
+// reachable returns nil if a connection could be opened to one of brokers.
+func (r *FailoverReader) reachable(ctx context.Context, brokers []string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.config.ProbeInterval)
+	defer cancel()
+
+	var err error
+	for _, broker := range brokers {
+		var conn *Conn
+		if conn, err = r.config.Reader.Dialer.DialContext(ctx, "tcp", broker); err == nil {
+			conn.Close()
+			return nil
+		}
+	}
+	return err
+}
+
+// failover switches the reader to the cluster, starting from the translated
+// positions of the partitions consumed on the active cluster.
+func (r *FailoverReader) failover(ctx context.Context, cluster int) error {
+	r.mutex.Lock()
+	times := make(map[TopicPartition]time.Time, len(r.positions))
+	for key, t := range r.positions {
+		times[key] = t
+	}
+	r.mutex.Unlock()
+
+	config := r.config.readerConfig(cluster)
+	offsets, err := r.translate(ctx, config, times)
+	if err != nil {
+		return err
+	}
+
+	if config.GroupID != "" && len(offsets) != 0 {
+		if err := r.commit(ctx, config, offsets); err != nil {
+			return err
+		}
+	}
+
+	reader := NewReader(config)
+	if config.GroupID == "" {
+		if offset, ok := offsets[TopicPartition{Topic: config.Topic, Partition: config.Partition}]; ok {
+			if err := reader.SetOffset(offset); err != nil {
+				reader.Close()
+				return err
+			}
+		}
+	}
+
+	r.mutex.Lock()
+	if r.closed {
+		r.mutex.Unlock()
+		return reader.Close()
+	}
+	previous := r.reader
+	r.reader = reader
+	r.active = cluster
+	r.lastSeen = config.clock().Now()
+	r.mutex.Unlock()
+
+	return previous.Close()
+}
+
+// translate looks up the offsets of the partitions on the cluster of config at
+// the times of the last messages consumed.
+func (r *FailoverReader) translate(ctx context.Context, config ReaderConfig, times map[TopicPartition]time.Time) (map[TopicPartition]int64, error) {
+	if len(times) == 0 {
+		return nil, nil
+	}
+
+	client := makeClient(ConsumerGroupConfig{Brokers: config.Brokers, Dialer: config.Dialer})
+	// Partitions with no message after the time are resumed from their end.
+	return offsetsForTimesOrEnd(ctx, client, times)
+}
+
+// commit commits the offsets to the consumer group of the cluster of config.
+func (r *FailoverReader) commit(ctx context.Context, config ReaderConfig, offsets map[TopicPartition]int64) error {
+	topics := make(map[string][]OffsetCommit)
+	for key, offset := range offsets {
+		topics[key.Topic] = append(topics[key.Topic], OffsetCommit{Partition: key.Partition, Offset: offset})
+	}
+
+	client := makeClient(ConsumerGroupConfig{Brokers: config.Brokers, Dialer: config.Dialer})
+	res, err := client.OffsetCommit(ctx, &OffsetCommitRequest{
+		GroupID:      config.GroupID,
+		GenerationID: -1,
+		Topics:       topics,
+	})
+	if err != nil {
+		return err
+	}
+
+	for topic, partitions := range res.Topics {
+		for _, p := range partitions {
+			if p.Error != nil {
+				return withErrorContext(p.Error, topic, p.Partition, config.GroupID)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *FailoverReader) withErrorLogger(do func(Logger)) {
+	if l := newLogger(r.config.Reader.StructuredLogger, r.config.Reader.ErrorLogger, LogLevelError); l != nil {
+		do(l)
+	} else if r.config.Reader.Logger != nil {
+		do(r.config.Reader.Logger)
+	}
+}
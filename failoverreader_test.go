@@ -0,0 +1,111 @@
+package kafka_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+// newReplicatedClusters returns two brokers with the same records in topic-A,
+// where the second one is missing the first records as if its replication
+// started later, so the offsets of the records differ between them.
+func newReplicatedClusters(t *testing.T) (*kafkatest.Broker, *kafkatest.Broker) {
+	t.Helper()
+	start := time.Now().Truncate(time.Millisecond)
+
+	brokers := make([]*kafkatest.Broker, 2)
+	for i := range brokers {
+		var records []kafkatest.Record
+		for j := 2 * i; j < 6; j++ {
+			records = append(records, kafkatest.Record{
+				Time:  start.Add(time.Duration(j) * time.Second),
+				Value: []byte(fmt.Sprint(j)),
+			})
+		}
+		brokers[i] = kafkatest.NewTestBroker(t, kafkatest.Topic{
+			Name:    "topic-A",
+			Records: map[int][]kafkatest.Record{0: records},
+		})
+	}
+	return brokers[0], brokers[1]
+}
+
+func TestFailoverReader(t *testing.T) {
+	for _, groupID := range []string{"", "group-A"} {
+		t.Run(fmt.Sprintf("group=%q", groupID), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			primary, secondary := newReplicatedClusters(t)
+			failovers := make(chan [2]int, 1)
+
+			r := kafka.NewFailoverReader(kafka.FailoverReaderConfig{
+				Clusters: [][]string{{primary.Addr().String()}, {secondary.Addr().String()}},
+				Reader: kafka.ReaderConfig{
+					GroupID:           groupID,
+					Topic:             "topic-A",
+					MaxWait:           10 * time.Millisecond,
+					HeartbeatInterval: 100 * time.Millisecond,
+				},
+				FailoverAfter: 50 * time.Millisecond,
+				ProbeInterval: 10 * time.Millisecond,
+				OnFailover: func(from, to int, err error) {
+					failovers <- [2]int{from, to}
+				},
+			})
+			defer r.Close()
+
+			var values []string
+			read := func() {
+				m, err := r.ReadMessage(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				values = append(values, string(m.Value))
+			}
+
+			for i := 0; i < 3; i++ {
+				read()
+			}
+			primary.Close()
+
+			select {
+			case f := <-failovers:
+				if f != [2]int{0, 1} {
+					t.Errorf("wrong failover: %v", f)
+				}
+			case <-ctx.Done():
+				t.Fatal("the reader did not fail over")
+			}
+			if n := r.Cluster(); n != 1 {
+				t.Errorf("wrong active cluster: %d", n)
+			}
+
+			for i := 0; i < 4; i++ {
+				read()
+			}
+
+			// The last message consumed on the primary cluster is read
+			// again from the secondary cluster.
+			if fmt.Sprint(values) != "[0 1 2 2 3 4 5]" {
+				t.Errorf("wrong messages: %v", values)
+			}
+		})
+	}
+}
+
+func TestFailoverReaderConfigValidate(t *testing.T) {
+	config := kafka.FailoverReaderConfig{
+		Clusters:      [][]string{{"localhost:9092"}, {}},
+		FailoverAfter: -1,
+		Reader:        kafka.ReaderConfig{Topic: "topic-A", Partition: -1},
+	}
+	if err := config.Validate(); err == nil || !strings.HasPrefix(err.Error(), "3 configuration problems") {
+		t.Errorf("expected all the problems to be reported: %v", err)
+	}
+}
@@ -37,6 +37,38 @@ type FetchRequest struct {
 	// This field requires the kafka broker to support the Fetch API in version
 	// 4 or above (otherwise the value is ignored).
 	IsolationLevel IsolationLevel
+
+	// An optional function called with the control records of transactional
+	// batches (e.g. commit or abort markers), which are otherwise skipped when
+	// reading the records of the response. This is useful to programs which
+	// audit transactional topics, or replicate their content.
+	//
+	// The function is called when reading the records of the response, in
+	// offset order with the data records.
+	OnControlRecord func(*protocol.ControlBatch, *protocol.ControlRecord) error
+
+	// Optional partitions to retrieve records from in a single round trip, by
+	// topic name. When set, the Topic, Partition, and Offset fields are
+	// ignored, and the results are reported for each partition in the Topics
+	// field of the response.
+	//
+	// All partitions must be led by the same broker, programs can group them
+	// by leader with the Metadata API.
+	Topics map[string][]FetchPartition
+}
+
+// FetchPartition represents a partition that records are retrieved from by a
+// request with multiple topic partitions.
+type FetchPartition struct {
+	// The partition and offset to retrieve records from. The offset may be one
+	// of the special FirstOffset or LastOffset constants.
+	Partition int
+	Offset    int64
+
+	// Limit on the size of the records returned for the partition.
+	//
+	// Defaults to the MaxBytes value of the request.
+	MaxBytes int64
 }
 
 // FetchResponse represents a response from a kafka broker to a fetch request.
@@ -75,7 +107,26 @@ type FetchResponse struct {
 	// Note that kafka may return record batches that start at an offset before
 	// the one that was requested. It is the program's responsibility to skip
 	// the offsets that it is not interested in.
+	//
+	// Reading the records returns a *protocol.CorruptRecordError when reaching
+	// a batch which failed checksum verification (see Transport.CRCMode).
 	Records RecordReader
+
+	// Results for each partition, by topic name, when the request had
+	// multiple topic partitions.
+	Topics map[string][]FetchPartitionResponse
+}
+
+// FetchPartitionResponse represents the result of retrieving records from a
+// partition, reported when the request had multiple topic partitions. The
+// fields have the same meaning as the fields of FetchResponse.
+type FetchPartitionResponse struct {
+	Partition        int
+	HighWatermark    int64
+	LastStableOffset int64
+	LogStartOffset   int64
+	Error            error
+	Records          RecordReader
 }
 
 // Fetch sends a fetch request to a kafka broker and returns the response.
@@ -93,38 +144,43 @@ func (c *Client) Fetch(ctx context.Context, req *FetchRequest) (*FetchResponse,
 		timeout = maxWait
 	}
 
-	offset := req.Offset
-	switch offset {
-	case FirstOffset, LastOffset:
-		topic, partition := req.Topic, req.Partition
-
-		r, err := c.ListOffsets(ctx, &ListOffsetsRequest{
-			Addr: req.Addr,
-			Topics: map[string][]OffsetRequest{
-				topic: {{
-					Partition: partition,
-					Timestamp: offset,
-				}},
-			},
-		})
-		if err != nil {
-			return nil, fmt.Errorf("kafka.(*Client).Fetch: %w", err)
+	partitions := req.Topics
+	if partitions == nil {
+		partitions = map[string][]FetchPartition{
+			req.Topic: {{Partition: req.Partition, Offset: req.Offset}},
 		}
+	}
 
-		for _, p := range r.Topics[topic] {
-			if p.Partition == partition {
-				if p.Error != nil {
-					return nil, fmt.Errorf("kafka.(*Client).Fetch: %w", p.Error)
-				}
-				switch offset {
-				case FirstOffset:
-					offset = p.FirstOffset
-				case LastOffset:
-					offset = p.LastOffset
-				}
-				break
+	offsets, err := c.resolveFetchOffsets(ctx, req.Addr, partitions)
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).Fetch: %w", err)
+	}
+
+	topics := make([]fetchAPI.RequestTopic, 0, len(partitions))
+
+	for topic, parts := range partitions {
+		t := fetchAPI.RequestTopic{
+			Topic:      topic,
+			Partitions: make([]fetchAPI.RequestPartition, len(parts)),
+		}
+
+		for i, p := range parts {
+			maxBytes := p.MaxBytes
+			if maxBytes == 0 {
+				maxBytes = req.MaxBytes
+			}
+
+			t.Partitions[i] = fetchAPI.RequestPartition{
+				Partition:          int32(p.Partition),
+				CurrentLeaderEpoch: -1,
+				FetchOffset:        offsets[topicPartition{topic: topic, partition: int32(p.Partition)}],
+				LastFetchedEpoch:   -1,
+				LogStartOffset:     -1,
+				PartitionMaxBytes:  int32(maxBytes),
 			}
 		}
+
+		topics = append(topics, t)
 	}
 
 	m, err := c.roundTrip(ctx, req.Addr, &fetchAPI.Request{
@@ -135,16 +191,7 @@ func (c *Client) Fetch(ctx context.Context, req *FetchRequest) (*FetchResponse,
 		IsolationLevel: int8(req.IsolationLevel),
 		SessionID:      -1,
 		SessionEpoch:   -1,
-		Topics: []fetchAPI.RequestTopic{{
-			Topic: req.Topic,
-			Partitions: []fetchAPI.RequestPartition{{
-				Partition:          int32(req.Partition),
-				CurrentLeaderEpoch: -1,
-				FetchOffset:        offset,
-				LogStartOffset:     -1,
-				PartitionMaxBytes:  int32(req.MaxBytes),
-			}},
-		}},
+		Topics:         topics,
 	})
 
 	if err != nil {
@@ -152,6 +199,25 @@ func (c *Client) Fetch(ctx context.Context, req *FetchRequest) (*FetchResponse,
 	}
 
 	res := m.(*fetchAPI.Response)
+
+	if req.Topics != nil {
+		ret := &FetchResponse{
+			Throttle: makeDuration(res.ThrottleTimeMs),
+			Error:    makeError(res.ErrorCode, ""),
+			Topics:   make(map[string][]FetchPartitionResponse, len(res.Topics)),
+		}
+
+		for _, t := range res.Topics {
+			parts := make([]FetchPartitionResponse, len(t.Partitions))
+			for i := range t.Partitions {
+				parts[i] = req.makePartitionResponse(t.Topic, &t.Partitions[i], ret.Error)
+			}
+			ret.Topics[t.Topic] = parts
+		}
+
+		return ret, nil
+	}
+
 	if len(res.Topics) == 0 {
 		return nil, fmt.Errorf("kafka.(*Client).Fetch: %w", protocol.ErrNoTopic)
 	}
@@ -159,28 +225,102 @@ func (c *Client) Fetch(ctx context.Context, req *FetchRequest) (*FetchResponse,
 	if len(topic.Partitions) == 0 {
 		return nil, fmt.Errorf("kafka.(*Client).Fetch: %w", protocol.ErrNoPartition)
 	}
-	partition := &topic.Partitions[0]
+	partition := req.makePartitionResponse(topic.Topic, &topic.Partitions[0], makeError(res.ErrorCode, ""))
 
-	ret := &FetchResponse{
+	return &FetchResponse{
 		Throttle:         makeDuration(res.ThrottleTimeMs),
 		Topic:            topic.Topic,
-		Partition:        int(partition.Partition),
-		Error:            makeError(res.ErrorCode, ""),
+		Partition:        partition.Partition,
+		Error:            partition.Error,
 		HighWatermark:    partition.HighWatermark,
 		LastStableOffset: partition.LastStableOffset,
 		LogStartOffset:   partition.LogStartOffset,
-		Records:          partition.RecordSet.Records,
+		Records:          partition.Records,
+	}, nil
+}
+
+// resolveFetchOffsets returns the offsets to fetch the partitions from, with
+// the special FirstOffset and LastOffset values replaced by the offsets that
+// they designate.
+func (c *Client) resolveFetchOffsets(ctx context.Context, addr net.Addr, partitions map[string][]FetchPartition) (map[topicPartition]int64, error) {
+	offsets := make(map[topicPartition]int64)
+	lookups := make(map[string][]OffsetRequest)
+
+	for topic, parts := range partitions {
+		for _, p := range parts {
+			offsets[topicPartition{topic: topic, partition: int32(p.Partition)}] = p.Offset
+
+			switch p.Offset {
+			case FirstOffset, LastOffset:
+				lookups[topic] = append(lookups[topic], OffsetRequest{
+					Partition: p.Partition,
+					Timestamp: p.Offset,
+				})
+			}
+		}
 	}
 
-	if partition.ErrorCode != 0 {
-		ret.Error = makeError(partition.ErrorCode, "")
+	if len(lookups) == 0 {
+		return offsets, nil
+	}
+
+	r, err := c.ListOffsets(ctx, &ListOffsetsRequest{
+		Addr:   addr,
+		Topics: lookups,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for topic, parts := range r.Topics {
+		for _, p := range parts {
+			key := topicPartition{topic: topic, partition: int32(p.Partition)}
+			switch offsets[key] {
+			case FirstOffset:
+				if p.Error != nil {
+					return nil, p.Error
+				}
+				offsets[key] = p.FirstOffset
+			case LastOffset:
+				if p.Error != nil {
+					return nil, p.Error
+				}
+				offsets[key] = p.LastOffset
+			}
+		}
+	}
+
+	return offsets, nil
+}
+
+// makePartitionResponse converts a partition of a fetch response, err is the
+// top-level error of the response, which partition errors take precedence
+// over.
+func (req *FetchRequest) makePartitionResponse(topic string, p *fetchAPI.ResponsePartition, err error) FetchPartitionResponse {
+	ret := FetchPartitionResponse{
+		Partition:        int(p.Partition),
+		HighWatermark:    p.HighWatermark,
+		LastStableOffset: p.LastStableOffset,
+		LogStartOffset:   p.LogStartOffset,
+		Error:            err,
+		Records:          p.RecordSet.Records,
+	}
+
+	if p.ErrorCode != 0 {
+		ret.Error = makeError(p.ErrorCode, "")
 	}
 
 	if ret.Records == nil {
 		ret.Records = NewRecordReader()
+	} else {
+		protocol.SetCorruptRecordPartition(ret.Records, topic, ret.Partition)
+	}
+
+	if s, ok := ret.Records.(*protocol.RecordStream); ok {
+		s.OnControlRecord = req.OnControlRecord
 	}
 
-	return ret, nil
+	return ret
 }
 
 func (req *FetchRequest) maxWait() time.Duration {
@@ -31,6 +31,12 @@ type FindCoordinatorRequest struct {
 
 	// The coordinator key type. (Group, transaction, etc.)
 	KeyType CoordinatorKeyType
+
+	// Optional list of keys to look up in a single request, in which case the
+	// Key field is ignored and the coordinators are reported in the Keys field
+	// of the response. Brokers which do not support batched lookups (before
+	// Kafka 3.0) are sent one request per key.
+	Keys []string
 }
 
 // FindCoordinatorResponseCoordinator contains details about the found coordinator.
@@ -45,11 +51,25 @@ type FindCoordinatorResponseCoordinator struct {
 	Port int
 }
 
+// FindCoordinatorResponseKey contains the result of looking up the coordinator
+// of a key in a batched request.
+type FindCoordinatorResponseKey struct {
+	// The Transaction/Group Coordinator details
+	Coordinator *FindCoordinatorResponseCoordinator
+
+	// An error that may have occurred while attempting to retrieve the
+	// coordinator of the key.
+	Error error
+}
+
 // FindCoordinatorResponse is the response structure for the FindCoordinator function.
 type FindCoordinatorResponse struct {
 	// The Transaction/Group Coordinator details
 	Coordinator *FindCoordinatorResponseCoordinator
 
+	// The coordinators of the keys of batched lookups, by key.
+	Keys map[string]FindCoordinatorResponseKey
+
 	// The amount of time that the broker throttled the request.
 	Throttle time.Duration
 
@@ -63,6 +83,9 @@ type FindCoordinatorResponse struct {
 // FindCoordinator sends a findCoordinator request to a kafka broker and returns the
 // response.
 func (c *Client) FindCoordinator(ctx context.Context, req *FindCoordinatorRequest) (*FindCoordinatorResponse, error) {
+	if len(req.Keys) != 0 {
+		return c.findCoordinators(ctx, req)
+	}
 
 	m, err := c.roundTrip(ctx, req.Addr, &findcoordinator.Request{
 		Key:     req.Key,
@@ -74,20 +97,71 @@ func (c *Client) FindCoordinator(ctx context.Context, req *FindCoordinatorReques
 	}
 
 	res := m.(*findcoordinator.Response)
-	coordinator := &FindCoordinatorResponseCoordinator{
-		NodeID: int(res.NodeID),
-		Host:   res.Host,
-		Port:   int(res.Port),
-	}
+	key := makeFindCoordinatorResponseKey(res, req.Key)
 	ret := &FindCoordinatorResponse{
 		Throttle:    makeDuration(res.ThrottleTimeMs),
-		Error:       makeError(res.ErrorCode, res.ErrorMessage),
-		Coordinator: coordinator,
+		Error:       key.Error,
+		Coordinator: key.Coordinator,
+	}
+
+	return ret, nil
+}
+
+// findCoordinators looks up the coordinators of a list of keys. Brokers which
+// do not support v4 requests respond with the coordinator of the first key
+// only, the remaining keys are then looked up in subsequent requests.
+func (c *Client) findCoordinators(ctx context.Context, req *FindCoordinatorRequest) (*FindCoordinatorResponse, error) {
+	ret := &FindCoordinatorResponse{
+		Keys: make(map[string]FindCoordinatorResponseKey, len(req.Keys)),
+	}
+
+	for keys := req.Keys; len(keys) != 0; {
+		m, err := c.roundTrip(ctx, req.Addr, &findcoordinator.Request{
+			Key:             keys[0],
+			KeyType:         int8(req.KeyType),
+			CoordinatorKeys: keys,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("kafka.(*Client).FindCoordinator: %w", err)
+		}
+
+		res := m.(*findcoordinator.Response)
+		if throttle := makeDuration(res.ThrottleTimeMs); throttle > ret.Throttle {
+			ret.Throttle = throttle
+		}
+
+		if res.Coordinators == nil {
+			ret.Keys[keys[0]] = makeFindCoordinatorResponseKey(res, keys[0])
+			keys = keys[1:]
+			continue
+		}
+
+		for _, key := range keys {
+			ret.Keys[key] = makeFindCoordinatorResponseKey(res, key)
+		}
+		break
 	}
 
 	return ret, nil
 }
 
+func makeFindCoordinatorResponseKey(res *findcoordinator.Response, key string) FindCoordinatorResponseKey {
+	c, ok := res.Coordinator(key)
+	if !ok {
+		return FindCoordinatorResponseKey{
+			Error: fmt.Errorf("kafka.(*Client).FindCoordinator: no coordinator returned for key %q", key),
+		}
+	}
+	return FindCoordinatorResponseKey{
+		Coordinator: &FindCoordinatorResponseCoordinator{
+			NodeID: int(c.NodeID),
+			Host:   c.Host,
+			Port:   int(c.Port),
+		},
+		Error: makeError(c.ErrorCode, c.ErrorMessage),
+	}
+}
+
 // FindCoordinatorRequestV0 requests the coordinator for the specified group or transaction
 //
 // See http://kafka.apache.org/protocol.html#The_Messages_FindCoordinator
@@ -1,6 +1,8 @@
 package kafka
 
 import (
+	"bufio"
+	"bytes"
 	"sort"
 )
 
@@ -38,6 +40,88 @@ type GroupBalancer interface {
 	AssignGroups(members []GroupMember, partitions []Partition) GroupMemberAssignments
 }
 
+// GroupMemberAssignment is the assignment that a member of a consumer group
+// received from the group leader in a generation.
+type GroupMemberAssignment struct {
+	// GenerationID is the ID of the generation that the assignment was made
+	// in.
+	GenerationID int32
+
+	// Topics maps the names of the assigned topics to the list of assigned
+	// partitions.
+	Topics map[string][]int
+
+	// UserData is the data that the balancer of the group leader sent to the
+	// member along with its assignment.
+	UserData []byte
+}
+
+// UserDataGroupBalancer is an extension of the GroupBalancer interface for
+// balancers which exchange user data with the members of the group in both
+// directions, like assignors that rely on the previous assignments of members
+// to minimize partition movements.
+//
+// The user data flows through the group protocol as follows:
+//
+//   - When joining the group, each member calls MemberUserData with the
+//     assignment it received in the previous generation, and the returned data
+//     is sent to the coordinator in the JoinGroup metadata of the member.
+//   - The group leader receives the metadata of all members in the UserData
+//     field of GroupMember, and calls AssignGroupsWithUserData to compute the
+//     assignments and the user data returned to each member.
+//   - Members receive the user data in the SyncGroup response, it is exposed in
+//     Generation.UserData, and passed to MemberUserData on the next join.
+type UserDataGroupBalancer interface {
+	GroupBalancer
+
+	// MemberUserData is called in place of UserData when the member joins the
+	// group. The previous assignment is nil when the member joins the group
+	// for the first time, or after it was removed from the group.
+	MemberUserData(previous *GroupMemberAssignment) ([]byte, error)
+
+	// AssignGroupsWithUserData is called in place of AssignGroups when the
+	// member is the group leader. It returns the assignments of the members,
+	// and the user data sent to each member along with its assignment.
+	AssignGroupsWithUserData(members []GroupMember, partitions []Partition) (GroupMemberAssignments, map[string][]byte)
+}
+
+// GroupAssignmentRequest carries the state of a consumer group passed to a
+// GroupAssigner by the group leader.
+type GroupAssignmentRequest struct {
+	// GroupID is the name of the consumer group.
+	GroupID string
+
+	// GenerationID is the ID of the generation being assigned.
+	GenerationID int32
+
+	// LeaderID is the member ID of the group leader.
+	LeaderID string
+
+	// Protocol is the name of the group balancer selected by the coordinator
+	// among the ones supported by all members.
+	Protocol string
+
+	// Members is the list of members of the group, with the topics they
+	// subscribed to and the user data sent by their group balancers.
+	Members []GroupMember
+
+	// Partitions is the list of partitions of the topics that the members
+	// subscribed to, including their leaders, replicas, and the racks of the
+	// brokers hosting them.
+	Partitions []Partition
+}
+
+// GroupAssigner is a function called by the leader of a consumer group to
+// assign partitions to the members of the group, in place of the selected
+// GroupBalancer. It allows applications to implement assignment strategies
+// which depend on information not available to balancers, for example the
+// capacity of the hosts running the members.
+//
+// Members missing from the returned assignments are not assigned partitions.
+// Returning an error fails the generation, and the group is joined again after
+// the JoinGroupBackoff.
+type GroupAssigner func(request GroupAssignmentRequest) (GroupMemberAssignments, error)
+
 // RangeGroupBalancer groups consumers by partition
 //
 // Example: 5 partitions, 2 consumers
@@ -285,6 +369,245 @@ func (r *RackAffinityGroupBalancer) assignTopic(members []GroupMember, partition
 	return assignments
 }
 
+// StickyGroupBalancer is a balancer compatible with the "sticky" assignor of
+// the Java client. It assigns partitions as evenly as possible, while keeping
+// as many partitions as possible assigned to the members which consumed them
+// in the previous generation, to minimize the partition movements during
+// rebalances.
+//
+// Members send their previous assignment to the leader in their user data,
+// using the format of the Java client, so groups may mix members running
+// either client.
+//
+// Example: 6 partitions, 3 consumers, C2 leaves the group
+//		C0: [0, 1]		C0: [0, 1, 4]
+//		C1: [2, 3]	->	C1: [2, 3, 5]
+//		C2: [4, 5]
+//
+type StickyGroupBalancer struct{}
+
+func (s StickyGroupBalancer) ProtocolName() string {
+	return "sticky"
+}
+
+func (s StickyGroupBalancer) UserData() ([]byte, error) {
+	return nil, nil
+}
+
+// MemberUserData encodes the previous assignment of the member, which the
+// leader uses to keep partitions assigned to their previous members.
+func (s StickyGroupBalancer) MemberUserData(previous *GroupMemberAssignment) ([]byte, error) {
+	if previous == nil {
+		return nil, nil
+	}
+	userData := stickyUserData{
+		Topics:     make(map[string][]int32, len(previous.Topics)),
+		Generation: previous.GenerationID,
+	}
+	for topic, partitions := range previous.Topics {
+		ids := make([]int32, len(partitions))
+		for i, id := range partitions {
+			ids[i] = int32(id)
+		}
+		userData.Topics[topic] = ids
+	}
+	return userData.bytes(), nil
+}
+
+func (s StickyGroupBalancer) AssignGroupsWithUserData(members []GroupMember, partitions []Partition) (GroupMemberAssignments, map[string][]byte) {
+	return s.AssignGroups(members, partitions), nil
+}
+
+func (s StickyGroupBalancer) AssignGroups(members []GroupMember, partitions []Partition) GroupMemberAssignments {
+	members = append([]GroupMember(nil), members...)
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].ID < members[j].ID
+	})
+
+	// the partitions of the topics subscribed by members, in a deterministic
+	// order.
+	var all []topicPartition
+	exists := map[topicPartition]bool{}
+	for topic := range findMembersByTopic(members) {
+		for _, id := range findPartitions(topic, partitions) {
+			tp := topicPartition{topic: topic, partition: int32(id)}
+			if !exists[tp] {
+				exists[tp] = true
+				all = append(all, tp)
+			}
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].topic != all[j].topic {
+			return all[i].topic < all[j].topic
+		}
+		return all[i].partition < all[j].partition
+	})
+
+	subscribed := make(map[string]map[string]bool, len(members))
+	for _, member := range members {
+		topics := make(map[string]bool, len(member.Topics))
+		for _, topic := range member.Topics {
+			topics[topic] = true
+		}
+		subscribed[member.ID] = topics
+	}
+
+	// keep the partitions with their previous owner.  when several members
+	// claim the same partition, for example after a member was evicted from
+	// the group without noticing it, the claim of the most recent generation
+	// wins.
+	type claim struct {
+		member     string
+		generation int32
+	}
+	claims := map[topicPartition]claim{}
+	for _, member := range members {
+		var userData stickyUserData
+		if err := userData.decode(member.UserData); err != nil {
+			continue
+		}
+		for topic, ids := range userData.Topics {
+			if !subscribed[member.ID][topic] {
+				continue
+			}
+			for _, id := range ids {
+				tp := topicPartition{topic: topic, partition: id}
+				if !exists[tp] {
+					continue
+				}
+				if c, ok := claims[tp]; ok && c.generation >= userData.Generation {
+					continue
+				}
+				claims[tp] = claim{member: member.ID, generation: userData.Generation}
+			}
+		}
+	}
+
+	assigned := make(map[string][]topicPartition, len(members))
+	var unassigned []topicPartition
+	for _, tp := range all {
+		if c, ok := claims[tp]; ok {
+			assigned[c.member] = append(assigned[c.member], tp)
+		} else {
+			unassigned = append(unassigned, tp)
+		}
+	}
+
+	// leastLoaded returns the member subscribed to the topic with the fewest
+	// partitions, or an empty string if there are none.
+	leastLoaded := func(topic string) string {
+		var memberID string
+		for _, member := range members {
+			if !subscribed[member.ID][topic] {
+				continue
+			}
+			if memberID == "" || len(assigned[member.ID]) < len(assigned[memberID]) {
+				memberID = member.ID
+			}
+		}
+		return memberID
+	}
+
+	for _, tp := range unassigned {
+		if memberID := leastLoaded(tp.topic); memberID != "" {
+			assigned[memberID] = append(assigned[memberID], tp)
+		}
+	}
+
+	// move partitions from the most loaded members to members with at least
+	// two partitions less, until no such move exists.  every move reduces the
+	// sum of squares of the partition counts, so the loop terminates.
+	for moved := true; moved; {
+		moved = false
+
+		order := append([]GroupMember(nil), members...)
+		sort.SliceStable(order, func(i, j int) bool {
+			return len(assigned[order[i].ID]) > len(assigned[order[j].ID])
+		})
+
+	search:
+		for _, from := range order {
+			tps := assigned[from.ID]
+			for i := len(tps) - 1; i >= 0; i-- {
+				to := leastLoaded(tps[i].topic)
+				if to == "" || len(assigned[to]) >= len(tps)-1 {
+					continue
+				}
+				assigned[to] = append(assigned[to], tps[i])
+				assigned[from.ID] = append(tps[:i:i], tps[i+1:]...)
+				moved = true
+				break search
+			}
+		}
+	}
+
+	groupAssignments := GroupMemberAssignments{}
+	for _, member := range members {
+		topics := map[string][]int{}
+		for _, tp := range assigned[member.ID] {
+			topics[tp.topic] = append(topics[tp.topic], int(tp.partition))
+		}
+		for _, ids := range topics {
+			sort.Ints(ids)
+		}
+		groupAssignments[member.ID] = topics
+	}
+	return groupAssignments
+}
+
+// stickyUserData is the user data exchanged by members of groups using the
+// sticky assignor, in version 1 of the format of the Java client.  Version 0
+// has no generation.
+type stickyUserData struct {
+	Topics     map[string][]int32
+	Generation int32
+}
+
+func (t stickyUserData) writeTo(wb *writeBuffer) {
+	topics := make([]string, 0, len(t.Topics))
+	for topic := range t.Topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	wb.writeInt32(int32(len(topics)))
+	for _, topic := range topics {
+		wb.writeString(topic)
+		wb.writeInt32Array(t.Topics[topic])
+	}
+	wb.writeInt32(t.Generation)
+}
+
+func (t stickyUserData) bytes() []byte {
+	buf := bytes.NewBuffer(nil)
+	t.writeTo(&writeBuffer{w: buf})
+	return buf.Bytes()
+}
+
+func (t *stickyUserData) readFrom(r *bufio.Reader, size int) (remain int, err error) {
+	if remain, err = readMapStringInt32(r, size, &t.Topics); err != nil {
+		return
+	}
+	// the generation was added in version 1.
+	t.Generation = -1
+	if remain == 0 {
+		return
+	}
+	return readInt32(r, remain, &t.Generation)
+}
+
+func (t *stickyUserData) decode(b []byte) error {
+	if len(b) == 0 {
+		return errShortRead
+	}
+	remain, err := t.readFrom(bufio.NewReader(bytes.NewReader(b)), len(b))
+	if err == nil && remain != 0 {
+		err = errShortRead
+	}
+	return err
+}
+
 // findPartitions extracts the partition ids associated with the topic from the
 // list of Partitions provided.
 func findPartitions(topic string, partitions []Partition) []int {
@@ -688,3 +688,243 @@ func TestRackAffinityGroupBalancer(t *testing.T) {
 		}
 	})
 }
+
+func TestStickyGroupBalancer(t *testing.T) {
+	b := StickyGroupBalancer{}
+
+	// stickyMember returns a member which was previously assigned the given
+	// partitions in the generation.
+	stickyMember := func(id string, topics []string, generation int32, previous map[string][]int) GroupMember {
+		member := GroupMember{ID: id, Topics: topics}
+		if previous != nil {
+			userData, err := b.MemberUserData(&GroupMemberAssignment{GenerationID: generation, Topics: previous})
+			if err != nil {
+				t.Fatal(err)
+			}
+			member.UserData = userData
+		}
+		return member
+	}
+
+	partitions := func(topic string, n int) []Partition {
+		p := make([]Partition, n)
+		for i := range p {
+			p[i] = Partition{Topic: topic, ID: i}
+		}
+		return p
+	}
+
+	t.Run("user data encoding", func(t *testing.T) {
+		userData, err := b.MemberUserData(&GroupMemberAssignment{
+			GenerationID: 7,
+			Topics:       map[string][]int{"t1": {0, 2}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []byte{
+			0, 0, 0, 1, // topics
+			0, 2, 't', '1', // topic
+			0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 2, // partitions
+			0, 0, 0, 7, // generation
+		}
+		if !bytes.Equal(userData, want) {
+			t.Fatalf("expected %v but got %v", want, userData)
+		}
+
+		var decoded stickyUserData
+		if err := decoded.decode(userData); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, stickyUserData{Topics: map[string][]int32{"t1": {0, 2}}, Generation: 7}) {
+			t.Fatalf("bad decoded user data: %+v", decoded)
+		}
+
+		// version 0 has no generation.
+		if err := decoded.decode(want[:len(want)-4]); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Generation != -1 {
+			t.Fatalf("expected generation -1 but got %d", decoded.Generation)
+		}
+
+		if userData, _ := b.MemberUserData(nil); userData != nil {
+			t.Fatalf("expected no user data for new members but got %v", userData)
+		}
+	})
+
+	tests := []struct {
+		scenario   string
+		members    []GroupMember
+		partitions []Partition
+		expected   GroupMemberAssignments
+	}{
+		{
+			scenario: "new group",
+			members: []GroupMember{
+				stickyMember("c0", []string{"t1"}, 0, nil),
+				stickyMember("c1", []string{"t1"}, 0, nil),
+			},
+			partitions: partitions("t1", 5),
+			expected: GroupMemberAssignments{
+				"c0": {"t1": {0, 2, 4}},
+				"c1": {"t1": {1, 3}},
+			},
+		},
+		{
+			scenario: "member leaves",
+			members: []GroupMember{
+				stickyMember("c0", []string{"t1"}, 3, map[string][]int{"t1": {0, 1}}),
+				stickyMember("c1", []string{"t1"}, 3, map[string][]int{"t1": {2, 3}}),
+			},
+			partitions: partitions("t1", 6),
+			expected: GroupMemberAssignments{
+				"c0": {"t1": {0, 1, 4}},
+				"c1": {"t1": {2, 3, 5}},
+			},
+		},
+		{
+			scenario: "member joins",
+			members: []GroupMember{
+				stickyMember("c0", []string{"t1"}, 3, map[string][]int{"t1": {0, 1, 2}}),
+				stickyMember("c1", []string{"t1"}, 3, map[string][]int{"t1": {3, 4, 5}}),
+				stickyMember("c2", []string{"t1"}, 0, nil),
+			},
+			partitions: partitions("t1", 6),
+			expected: GroupMemberAssignments{
+				"c0": {"t1": {0, 1}},
+				"c1": {"t1": {3, 4}},
+				"c2": {"t1": {2, 5}},
+			},
+		},
+		{
+			scenario: "most recent claim wins",
+			members: []GroupMember{
+				stickyMember("c0", []string{"t1"}, 2, map[string][]int{"t1": {0, 1}}),
+				stickyMember("c1", []string{"t1"}, 3, map[string][]int{"t1": {1, 2}}),
+			},
+			partitions: partitions("t1", 4),
+			expected: GroupMemberAssignments{
+				"c0": {"t1": {0, 3}},
+				"c1": {"t1": {1, 2}},
+			},
+		},
+		{
+			scenario: "stale topics and partitions are dropped",
+			members: []GroupMember{
+				stickyMember("c0", []string{"t1"}, 3, map[string][]int{"t1": {0, 1, 9}, "t2": {0}}),
+				stickyMember("c1", []string{"t1"}, 3, map[string][]int{"t1": {2, 3}}),
+			},
+			partitions: append(partitions("t1", 4), partitions("t2", 1)...),
+			expected: GroupMemberAssignments{
+				"c0": {"t1": {0, 1}},
+				"c1": {"t1": {2, 3}},
+			},
+		},
+		{
+			scenario: "multiple topics with different subscriptions",
+			members: []GroupMember{
+				stickyMember("c0", []string{"t1", "t2"}, 1, map[string][]int{"t1": {0, 1}, "t2": {0, 1}}),
+				stickyMember("c1", []string{"t1"}, 0, nil),
+			},
+			partitions: append(partitions("t1", 2), partitions("t2", 2)...),
+			expected: GroupMemberAssignments{
+				"c0": {"t2": {0, 1}},
+				"c1": {"t1": {0, 1}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			assignments := b.AssignGroups(test.members, test.partitions)
+			if !reflect.DeepEqual(test.expected, assignments) {
+				t.Errorf("expected %v but got %v", test.expected, assignments)
+			}
+		})
+	}
+}
+
+func TestStickyGroupBalancerRebalances(t *testing.T) {
+	b := StickyGroupBalancer{}
+	var p []Partition
+	for _, topic := range []string{"t1", "t2", "t3"} {
+		for i := 0; i < 7; i++ {
+			p = append(p, Partition{Topic: topic, ID: i})
+		}
+	}
+
+	// membership of the group in successive generations.
+	generations := [][]string{
+		{"c0"},
+		{"c0", "c1", "c2"},
+		{"c0", "c2", "c3", "c4"},
+		{"c2", "c4"},
+		{"c1", "c2", "c3", "c4", "c5"},
+	}
+
+	previous := map[string]*GroupMemberAssignment{}
+	for generation, ids := range generations {
+		members := make([]GroupMember, len(ids))
+		for i, id := range ids {
+			userData, _ := b.MemberUserData(previous[id])
+			members[i] = GroupMember{ID: id, Topics: []string{"t1", "t2", "t3"}, UserData: userData}
+		}
+
+		assignments := b.AssignGroups(members, p)
+
+		counts := map[string]int{}
+		for _, id := range ids {
+			for topic, partitions := range assignments[id] {
+				for _, partition := range partitions {
+					counts[topic+"/"+strconv.Itoa(partition)]++
+				}
+			}
+		}
+		for _, partition := range p {
+			if n := counts[partition.Topic+"/"+strconv.Itoa(partition.ID)]; n != 1 {
+				t.Fatalf("generation %d: partition %s/%d assigned %d times", generation, partition.Topic, partition.ID, n)
+			}
+		}
+
+		quota := len(p) / len(ids)
+		for _, id := range ids {
+			n := 0
+			for _, partitions := range assignments[id] {
+				n += len(partitions)
+			}
+			if n != quota && n != quota+1 {
+				t.Fatalf("generation %d: unbalanced assignments %v", generation, assignments)
+			}
+
+			// members keep their previous partitions up to their share of
+			// the partitions.
+			if previous[id] == nil {
+				continue
+			}
+			had, kept := 0, 0
+			for topic, partitions := range previous[id].Topics {
+				had += len(partitions)
+				for _, partition := range partitions {
+					for _, assigned := range assignments[id][topic] {
+						if assigned == partition {
+							kept++
+						}
+					}
+				}
+			}
+			if had > quota {
+				had = quota
+			}
+			if kept < had {
+				t.Errorf("generation %d: member %s kept %d partitions, expected at least %d", generation, id, kept, had)
+			}
+		}
+
+		next := map[string]*GroupMemberAssignment{}
+		for _, id := range ids {
+			next[id] = &GroupMemberAssignment{GenerationID: int32(generation), Topics: assignments[id]}
+		}
+		previous = next
+	}
+}
@@ -0,0 +1,169 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+	metadataAPI "github.com/segmentio/kafka-go/protocol/metadata"
+)
+
+// HealthCheckResult carries the result of a cluster health check.
+type HealthCheckResult struct {
+	// Error is set to a non-nil value if the cluster could not be reached
+	// through the bootstrap address of the client, or if its metadata could
+	// not be retrieved. The other fields are left empty in that case.
+	Error error
+
+	// The time it took for a broker of the cluster to answer a request.
+	Latency time.Duration
+
+	// The ID of the kafka cluster.
+	ClusterID string
+
+	// The list of brokers registered to the cluster.
+	Brokers []Broker
+
+	// The broker which is currently the controller of the cluster.
+	Controller Broker
+
+	// ControllerError is set to a non-nil value if the cluster has no
+	// controller, or if the controller could not be reached.
+	ControllerError error
+
+	// The errors of the brokers which could not be reached, by broker ID.
+	BrokerErrors map[int]error
+
+	// The partitions which have fewer in-sync replicas than replicas.
+	UnderReplicatedPartitions []TopicPartition
+
+	// The partitions which have no leader, or whose leader could not be
+	// reached, and therefore cannot be produced to or consumed from.
+	OfflinePartitions []TopicPartition
+}
+
+// Healthy returns true if the cluster and its controller are reachable, and
+// no partitions are offline.
+//
+// Under-replicated partitions do not make the cluster unhealthy, since they
+// remain available to clients.
+func (r *HealthCheckResult) Healthy() bool {
+	return r.Error == nil && r.ControllerError == nil && len(r.OfflinePartitions) == 0
+}
+
+// HealthCheck checks the health of the kafka cluster that the client is
+// configured with, it is intended to back the readiness probes of programs
+// depending on kafka.
+//
+// Since the metadata of the cluster may be served from the cache of the
+// transport, each broker is sent an ApiVersions request to verify that it
+// answers requests.
+//
+// The method always returns a non-nil result; errors are reported in the
+// fields of the result.
+func (c *Client) HealthCheck(ctx context.Context) *HealthCheckResult {
+	ret := &HealthCheckResult{}
+
+	start := time.Now()
+	if _, err := c.roundTrip(ctx, nil, &protocol.RawRequest{Key: protocol.ApiVersions}); err != nil {
+		ret.Error = fmt.Errorf("kafka.(*Client).HealthCheck: %w", err)
+		return ret
+	}
+	ret.Latency = time.Since(start)
+
+	m, err := c.roundTrip(ctx, nil, &metadataAPI.Request{})
+	if err != nil {
+		ret.Error = fmt.Errorf("kafka.(*Client).HealthCheck: %w", err)
+		return ret
+	}
+
+	res := m.(*metadataAPI.Response)
+	ret.ClusterID = res.ClusterID
+	ret.Brokers = make([]Broker, len(res.Brokers))
+	ret.ControllerError = BrokerNotAvailable
+
+	for i, b := range res.Brokers {
+		ret.Brokers[i] = Broker{
+			Host: b.Host,
+			Port: int(b.Port),
+			ID:   int(b.NodeID),
+			Rack: b.Rack,
+		}
+
+		if b.NodeID == res.ControllerID {
+			ret.Controller = ret.Brokers[i]
+			ret.ControllerError = nil
+		}
+	}
+
+	ret.BrokerErrors = c.probeBrokers(ctx, res.Brokers)
+	if ret.ControllerError == nil {
+		ret.ControllerError = ret.BrokerErrors[ret.Controller.ID]
+	}
+
+	for _, t := range res.Topics {
+		for _, p := range t.Partitions {
+			tp := TopicPartition{Topic: t.Name, Partition: int(p.PartitionIndex)}
+
+			if p.LeaderID < 0 || ret.BrokerErrors[int(p.LeaderID)] != nil {
+				ret.OfflinePartitions = append(ret.OfflinePartitions, tp)
+			}
+
+			if len(p.IsrNodes) < len(p.ReplicaNodes) {
+				ret.UnderReplicatedPartitions = append(ret.UnderReplicatedPartitions, tp)
+			}
+		}
+	}
+
+	sortTopicPartitions(ret.OfflinePartitions)
+	sortTopicPartitions(ret.UnderReplicatedPartitions)
+	return ret
+}
+
+// probeBrokers sends an ApiVersions request to each broker, and returns the
+// errors of the brokers which did not answer.
+func (c *Client) probeBrokers(ctx context.Context, brokers []metadataAPI.ResponseBroker) map[int]error {
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	errs := make(map[int]error)
+
+	for _, b := range brokers {
+		broker := protocol.Broker{
+			ID:   b.NodeID,
+			Host: b.Host,
+			Port: b.Port,
+			Rack: b.Rack,
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.roundTrip(ctx, nil, &rawBrokerRequest{
+				RawRequest: &protocol.RawRequest{Key: protocol.ApiVersions},
+				broker: func(protocol.Cluster) (protocol.Broker, error) {
+					return broker, nil
+				},
+			})
+			if err != nil {
+				mutex.Lock()
+				errs[int(broker.ID)] = err
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return errs
+}
+
+func sortTopicPartitions(partitions []TopicPartition) {
+	sort.Slice(partitions, func(i, j int) bool {
+		if partitions[i].Topic != partitions[j].Topic {
+			return partitions[i].Topic < partitions[j].Topic
+		}
+		return partitions[i].Partition < partitions[j].Partition
+	})
+}
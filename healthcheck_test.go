@@ -0,0 +1,92 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+	metadataAPI "github.com/segmentio/kafka-go/protocol/metadata"
+)
+
+func TestClientHealthCheck(t *testing.T) {
+	client, topic, shutdown := newLocalClientAndTopic()
+	defer shutdown()
+
+	res := client.HealthCheck(context.Background())
+	if !res.Healthy() {
+		t.Fatalf("cluster is not healthy: %+v", res)
+	}
+	if len(res.Brokers) == 0 {
+		t.Error("no brokers reported by the health check")
+	}
+	if res.Controller.Host == "" {
+		t.Error("no controller reported by the health check")
+	}
+
+	for _, p := range res.OfflinePartitions {
+		if p.Topic == topic {
+			t.Errorf("partition of the test topic is offline: %+v", p)
+		}
+	}
+}
+
+func TestClientHealthCheckUnreachable(t *testing.T) {
+	client := &Client{Addr: TCP("127.0.0.1:1")}
+
+	res := client.HealthCheck(context.Background())
+	if res.Healthy() {
+		t.Fatal("unreachable cluster reported as healthy")
+	}
+	if res.Error == nil {
+		t.Error("expected an error for an unreachable cluster")
+	}
+}
+
+func TestClientHealthCheckBrokerDown(t *testing.T) {
+	// The transport serves the metadata, but broker 2 does not answer
+	// requests, as if the metadata came from the cache of the transport.
+	client := &Client{
+		Addr: TCP("127.0.0.1:9092"),
+		Transport: roundTripperFunc(func(ctx context.Context, addr net.Addr, req Request) (Response, error) {
+			switch r := req.(type) {
+			case *metadataAPI.Request:
+				return &metadataAPI.Response{
+					ControllerID: 1,
+					Brokers: []metadataAPI.ResponseBroker{
+						{NodeID: 1, Host: "localhost", Port: 9092},
+						{NodeID: 2, Host: "localhost", Port: 9093},
+					},
+					Topics: []metadataAPI.ResponseTopic{{
+						Name: "topic-A",
+						Partitions: []metadataAPI.ResponsePartition{
+							{PartitionIndex: 0, LeaderID: 1, ReplicaNodes: []int32{1}, IsrNodes: []int32{1}},
+							{PartitionIndex: 1, LeaderID: 2, ReplicaNodes: []int32{2}, IsrNodes: []int32{2}},
+						},
+					}},
+				}, nil
+			case *rawBrokerRequest:
+				broker, _ := r.Broker(protocol.Cluster{})
+				if broker.ID == 2 {
+					return nil, BrokerNotAvailable
+				}
+			}
+			return nil, nil
+		}),
+	}
+
+	res := client.HealthCheck(context.Background())
+	if res.Healthy() {
+		t.Fatal("cluster with an unreachable broker reported as healthy")
+	}
+	if res.Error != nil || res.ControllerError != nil {
+		t.Errorf("unexpected errors: %v, %v", res.Error, res.ControllerError)
+	}
+	if len(res.BrokerErrors) != 1 || !errors.Is(res.BrokerErrors[2], BrokerNotAvailable) {
+		t.Errorf("wrong broker errors: %v", res.BrokerErrors)
+	}
+	if len(res.OfflinePartitions) != 1 || res.OfflinePartitions[0] != (TopicPartition{Topic: "topic-A", Partition: 1}) {
+		t.Errorf("wrong offline partitions: %v", res.OfflinePartitions)
+	}
+}
@@ -80,3 +80,107 @@ func (c *Client) InitProducerID(ctx context.Context, req *InitProducerIDRequest)
 		Error:    makeError(res.ErrorCode, ""),
 	}, nil
 }
+
+// BumpProducerEpoch recovers a producer session fenced with an
+// InvalidProducerEpoch or ProducerFenced error, by bumping the epoch of its
+// producer id (KIP-360), which aborts the ongoing transaction of the session.
+// The ProducerID and ProducerEpoch fields of the request must be those of the
+// fenced session, and the sequence numbers of the records produced with the
+// new session restart from zero.
+//
+// The broker refuses to bump the epoch when another producer took over the
+// transactional id, the response then carries an error for which IsFencing is
+// true.  Brokers older than Kafka 2.5 would instead start a new session and
+// fence the other producer, so the method returns an UnsupportedVersion error
+// without sending the request to them.
+func (c *Client) BumpProducerEpoch(ctx context.Context, req *InitProducerIDRequest) (*InitProducerIDResponse, error) {
+	versions, err := c.ApiVersions(ctx, &ApiVersionsRequest{Addr: req.Addr})
+	if err == nil {
+		err = versions.Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).BumpProducerEpoch: %w", err)
+	}
+	if !versions.brokerApiVersions().SupportsFeature(FeatureProducerEpochBump) {
+		return nil, fmt.Errorf("kafka.(*Client).BumpProducerEpoch: %w", UnsupportedVersion)
+	}
+	return c.InitProducerID(ctx, req)
+}
+
+// FenceProducersRequest represents a request to fence the producers of
+// transactional ids.
+type FenceProducersRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// The transactional ids to fence the producers of.
+	TransactionalIDs []string
+}
+
+// FenceProducersResponse represents the result of a FenceProducersRequest.
+type FenceProducersResponse struct {
+	// The amount of time that the brokers throttled the requests.
+	Throttle time.Duration
+
+	// Mappings of transactional ids to the session which fenced their
+	// producers, there will be one entry for each transactional id in the
+	// request.
+	Producers map[string]FencedProducer
+}
+
+// FencedProducer carries the result of fencing the producers of a
+// transactional id.
+type FencedProducer struct {
+	// The producer session created by fencing, its epoch is higher than the
+	// epoch of the fenced producers.
+	Producer *ProducerSession
+
+	// An error that may have occurred while fencing the producers.
+	Error error
+}
+
+// fenceTransactionTimeoutMs is the transaction timeout of the sessions used to
+// fence producers, which never start transactions.
+const fenceTransactionTimeoutMs = 60000
+
+// FenceProducers bumps the epoch of the producers of transactional ids with
+// InitProducerId requests, like a new producer using the transactional ids
+// would (see KIP-664). The coordinators abort the ongoing transactions, and the
+// producers using the previous epochs fail with ProducerFenced errors.
+//
+// Programs use FenceProducers to abort the transactions that DescribeProducers
+// and DescribeTransactions reported as hanging.
+func (c *Client) FenceProducers(ctx context.Context, req *FenceProducersRequest) (*FenceProducersResponse, error) {
+	ret := &FenceProducersResponse{
+		Producers: make(map[string]FencedProducer, len(req.TransactionalIDs)),
+	}
+
+	for _, id := range req.TransactionalIDs {
+		m, err := c.roundTrip(ctx, req.Addr, &initproducerid.Request{
+			TransactionalID:      id,
+			TransactionTimeoutMs: fenceTransactionTimeoutMs,
+			ProducerID:           -1,
+			ProducerEpoch:        -1,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("kafka.(*Client).FenceProducers: %w", err)
+		}
+
+		res := m.(*initproducerid.Response)
+
+		if throttle := makeDuration(res.ThrottleTimeMs); throttle > ret.Throttle {
+			ret.Throttle = throttle
+		}
+
+		fenced := FencedProducer{Error: makeError(res.ErrorCode, "")}
+		if fenced.Error == nil {
+			fenced.Producer = &ProducerSession{
+				ProducerID:    int(res.ProducerID),
+				ProducerEpoch: int(res.ProducerEpoch),
+			}
+		}
+		ret.Producers[id] = fenced
+	}
+
+	return ret, nil
+}
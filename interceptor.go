@@ -0,0 +1,59 @@
+package kafka
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// WriterInterceptor intercepts the messages written by a Writer, for example to
+// instrument them with traces or metrics.
+//
+// Interceptors are called concurrently from multiple goroutines.
+type WriterInterceptor interface {
+	// OnWrite is called by WriteMessages for each message, before the message
+	// is routed to a partition and encrypted.  The context is the one passed to
+	// WriteMessages.
+	//
+	// Changes made to the message are written to kafka, which allows
+	// interceptors to add headers.  The message is a copy of the one passed to
+	// WriteMessages, but its slices are shared with the original, so they must
+	// not be modified in place.
+	OnWrite(ctx context.Context, msg *Message)
+
+	// OnAck is called with the messages of each batch once the writer has
+	// finished writing it, either successfully or with the error that made the
+	// batch fail.  The messages carry the topic, partition, and offset that
+	// they were written to, and the same WriteID as when they were passed to
+	// OnWrite.
+	//
+	// When WriteMessages fails before the messages are batched, for example
+	// because one of them is too large, OnAck is called once with all the
+	// messages and the error returned by WriteMessages.
+	OnAck(msgs []Message, err error)
+}
+
+// ReaderInterceptor intercepts the messages returned by a Reader, for example
+// to instrument them with traces or metrics.
+//
+// Interceptors are called concurrently from multiple goroutines.
+type ReaderInterceptor interface {
+	// OnFetch is called by FetchMessage and ReadMessage for each message
+	// returned to the program, after it was decrypted.  The context is the
+	// one passed to FetchMessage or ReadMessage.
+	OnFetch(ctx context.Context, msg Message)
+}
+
+// lastWriteID is the last identifier assigned to a message by
+// interceptMessages.
+var lastWriteID uint64
+
+// interceptMessages returns a copy of msgs, after it assigned them a write ID
+// and passed them to the OnWrite method of the interceptor.
+func interceptMessages(ctx context.Context, interceptor WriterInterceptor, msgs []Message) []Message {
+	msgs = append([]Message(nil), msgs...)
+	for i := range msgs {
+		msgs[i].writeID = atomic.AddUint64(&lastWriteID, 1)
+		interceptor.OnWrite(ctx, &msgs[i])
+	}
+	return msgs
+}
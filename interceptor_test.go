@@ -0,0 +1,110 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	metadataAPI "github.com/segmentio/kafka-go/protocol/metadata"
+	produceAPI "github.com/segmentio/kafka-go/protocol/produce"
+)
+
+type recordingInterceptor struct {
+	mutex   sync.Mutex
+	written []uint64
+	acked   []Message
+}
+
+func (r *recordingInterceptor) OnWrite(ctx context.Context, msg *Message) {
+	r.mutex.Lock()
+	r.written = append(r.written, msg.WriteID())
+	r.mutex.Unlock()
+	msg.Headers = append(msg.Headers[:len(msg.Headers):len(msg.Headers)], Header{
+		Key:   "intercepted",
+		Value: []byte(ctx.Value(interceptorKey{}).(string)),
+	})
+}
+
+func (r *recordingInterceptor) OnAck(msgs []Message, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err == nil {
+		r.acked = append(r.acked, msgs...)
+	}
+}
+
+type interceptorKey struct{}
+
+func TestWriterInterceptor(t *testing.T) {
+	var produced []produceAPI.Request
+	transport := roundTripperFunc(func(ctx context.Context, addr net.Addr, req Request) (Response, error) {
+		switch r := req.(type) {
+		case *metadataAPI.Request:
+			return &metadataAPI.Response{
+				Brokers: []metadataAPI.ResponseBroker{{NodeID: 1, Host: "localhost", Port: 9092}},
+				Topics: []metadataAPI.ResponseTopic{{
+					Name:       "topic-1",
+					Partitions: []metadataAPI.ResponsePartition{{PartitionIndex: 0, LeaderID: 1}},
+				}},
+			}, nil
+		case *produceAPI.Request:
+			produced = append(produced, *r)
+			return &produceAPI.Response{
+				Topics: []produceAPI.ResponseTopic{{
+					Topic:      "topic-1",
+					Partitions: []produceAPI.ResponsePartition{{Partition: 0, BaseOffset: 42}},
+				}},
+			}, nil
+		default:
+			return nil, fmt.Errorf("unexpected request: %T", req)
+		}
+	})
+
+	interceptor := &recordingInterceptor{}
+	w := &Writer{
+		Addr:         TCP("localhost:9092"),
+		Topic:        "topic-1",
+		BatchTimeout: time.Millisecond,
+		RequiredAcks: RequireOne,
+		Transport:    transport,
+		Interceptor:  interceptor,
+	}
+	defer w.Close()
+
+	msgs := []Message{{Value: []byte("hello")}}
+	ctx := context.WithValue(context.Background(), interceptorKey{}, "yes")
+	if err := w.WriteMessages(ctx, msgs...); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msgs[0].Headers) != 0 {
+		t.Errorf("the interceptor modified the messages of the program: %+v", msgs[0].Headers)
+	}
+
+	if len(produced) != 1 {
+		t.Fatalf("expected 1 produce request but got %d", len(produced))
+	}
+	records := produced[0].Topics[0].Partitions[0].RecordSet.Records
+	rec, err := records.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Headers) != 1 || rec.Headers[0].Key != "intercepted" || string(rec.Headers[0].Value) != "yes" {
+		t.Errorf("unexpected headers: %+v", rec.Headers)
+	}
+
+	interceptor.mutex.Lock()
+	defer interceptor.mutex.Unlock()
+	if len(interceptor.acked) != 1 {
+		t.Fatalf("expected 1 acknowledged message but got %d", len(interceptor.acked))
+	}
+	if m := interceptor.acked[0]; m.Topic != "topic-1" || m.Offset != 42 || len(m.Headers) != 1 {
+		t.Errorf("unexpected acknowledged message: %+v", m)
+	}
+	if id := interceptor.acked[0].WriteID(); id == 0 || len(interceptor.written) != 1 || interceptor.written[0] != id {
+		t.Errorf("the acknowledged message has write ID %d but %v were written", id, interceptor.written)
+	}
+}
@@ -15,6 +15,10 @@ type Topic struct {
 	// Name of the topic.
 	Name string
 
+	// ID of the topic, only known when the brokers support topic IDs (kafka
+	// 2.8 and above).
+	ID UUID
+
 	// True if the topic is internal.
 	Internal bool
 
@@ -0,0 +1,353 @@
+// Package kafkaprom exposes the statistics of kafka readers, writers, and
+// transports as prometheus metrics.
+//
+// The Collector takes snapshots of the statistics of the objects added to it
+// each time it is scraped, so programs do not need to run goroutines copying
+// statistics to prometheus metrics.
+//
+//	collector := kafkaprom.NewCollector()
+//	collector.AddReader(reader)
+//	collector.AddWriter(writer)
+//	collector.AddTransport(transport, kafka.TCP("localhost:9092"))
+//	prometheus.MustRegister(collector)
+//
+// The names of the metrics are the names of the `metric` tags of the
+// kafka.ReaderStats, kafka.WriterStats, and kafka.ConnPoolStats fields with
+// dots replaced by underscores, for example kafka_reader_message_count, and
+// the names of summaries are suffixed with _avg, _min, and _max.  Durations
+// are reported in seconds.
+//
+// Reader metrics are labeled with client_id, topic, partition, and group,
+// writer metrics with topic, and transport metrics with addr and broker.  The
+// latency of requests sent by transports is reported in the
+// kafka_transport_request_seconds histogram, labeled with addr, broker, and
+// api.
+//
+// The package lives in its own module so the core kafka-go module does not
+// depend on prometheus.
+package kafkaprom
+
+import (
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+var requestLatencyDesc = prometheus.NewDesc(
+	"kafka_transport_request_seconds",
+	"kafka-go statistic kafka_transport_request_seconds",
+	[]string{"addr", "broker", "api"},
+	nil,
+)
+
+var (
+	readerSchema    = newSchema(reflect.TypeOf(kafka.ReaderStats{}), "group")
+	writerSchema    = newSchema(reflect.TypeOf(kafka.WriterStats{}))
+	transportSchema = newSchema(reflect.TypeOf(kafka.ConnPoolStats{}))
+)
+
+// Collector is an implementation of the prometheus.Collector interface
+// reporting the statistics of kafka readers, writers, and transports.
+//
+// Readers and writers are collected with cumulative snapshots of their
+// statistics, which do not change the values reported by their Stats methods.
+// Transport.Stats resets the counters of transports each time it is called,
+// programs must not call it on transports added to a collector, or the values
+// reported by the collector would be missing the observations.
+type Collector struct {
+	mutex      sync.Mutex
+	readers    map[*kafka.Reader]struct{}
+	writers    map[*kafka.Writer]struct{}
+	transports map[transportKey]struct{}
+	// Counters of transports are reset by Transport.Stats, the collector
+	// accumulates them to report monotonic values.
+	totals    map[string]float64
+	latencies map[[2]string]map[protocol.ApiKey]*histogram
+}
+
+// histogram is the cumulative latency of requests sent to a broker.
+type histogram struct {
+	count   uint64
+	sum     float64
+	buckets map[float64]uint64
+}
+
+type transportKey struct {
+	transport *kafka.Transport
+	addr      string
+	network   string
+}
+
+func (k transportKey) Network() string { return k.network }
+func (k transportKey) String() string  { return k.addr }
+
+// NewCollector returns a new collector with no readers, writers, or
+// transports.
+func NewCollector() *Collector {
+	return &Collector{
+		readers:    make(map[*kafka.Reader]struct{}),
+		writers:    make(map[*kafka.Writer]struct{}),
+		transports: make(map[transportKey]struct{}),
+		totals:     make(map[string]float64),
+		latencies:  make(map[[2]string]map[protocol.ApiKey]*histogram),
+	}
+}
+
+// AddReader adds a reader to the collector.
+func (c *Collector) AddReader(r *kafka.Reader) {
+	c.mutex.Lock()
+	c.readers[r] = struct{}{}
+	c.mutex.Unlock()
+}
+
+// RemoveReader removes a reader from the collector, typically after it was
+// closed.
+func (c *Collector) RemoveReader(r *kafka.Reader) {
+	c.mutex.Lock()
+	delete(c.readers, r)
+	c.mutex.Unlock()
+}
+
+// AddWriter adds a writer to the collector.
+func (c *Collector) AddWriter(w *kafka.Writer) {
+	c.mutex.Lock()
+	c.writers[w] = struct{}{}
+	c.mutex.Unlock()
+}
+
+// RemoveWriter removes a writer from the collector, typically after it was
+// closed.
+func (c *Collector) RemoveWriter(w *kafka.Writer) {
+	c.mutex.Lock()
+	delete(c.writers, w)
+	c.mutex.Unlock()
+}
+
+// AddTransport adds the connections that a transport maintains with the kafka
+// cluster reached at addr to the collector.
+func (c *Collector) AddTransport(t *kafka.Transport, addr net.Addr) {
+	c.mutex.Lock()
+	c.transports[makeTransportKey(t, addr)] = struct{}{}
+	c.mutex.Unlock()
+}
+
+// RemoveTransport removes a transport and address previously added with
+// AddTransport from the collector.
+func (c *Collector) RemoveTransport(t *kafka.Transport, addr net.Addr) {
+	c.mutex.Lock()
+	delete(c.transports, makeTransportKey(t, addr))
+	c.mutex.Unlock()
+}
+
+func makeTransportKey(t *kafka.Transport, addr net.Addr) transportKey {
+	return transportKey{transport: t, addr: addr.String(), network: addr.Network()}
+}
+
+// Describe satisfies the prometheus.Collector interface.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, s := range []*schema{readerSchema, writerSchema, transportSchema} {
+		for _, m := range s.metrics {
+			ch <- m.desc
+		}
+	}
+	ch <- requestLatencyDesc
+}
+
+// Collect satisfies the prometheus.Collector interface.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Objects reporting the same labels, like readers of the same partition,
+	// are merged into a single series: counters are added, and gauges report
+	// the last value seen.
+	samples := make(map[string]*sample)
+
+	var readerStats kafka.ReaderStats
+	for r := range c.readers {
+		r.SnapshotStats(&readerStats, kafka.CumulativeStats)
+		c.observe(samples, readerSchema, reflect.ValueOf(readerStats), false, r.Config().GroupID)
+	}
+
+	var writerStats kafka.WriterStats
+	for w := range c.writers {
+		w.SnapshotStats(&writerStats, kafka.CumulativeStats)
+		c.observe(samples, writerSchema, reflect.ValueOf(writerStats), false)
+	}
+
+	// Transports reaching the same cluster report pools with the same labels,
+	// their histograms are merged and reported once.
+	latencies := make(map[[2]string]struct{})
+
+	for k := range c.transports {
+		for _, stats := range k.transport.Stats(k) {
+			c.observe(samples, transportSchema, reflect.ValueOf(stats), true)
+			latencies[c.observeLatency(stats)] = struct{}{}
+		}
+	}
+
+	for _, s := range samples {
+		ch <- prometheus.MustNewConstMetric(s.metric.desc, s.metric.kind, s.value, s.labels...)
+	}
+
+	for key := range latencies {
+		for apiKey, h := range c.latencies[key] {
+			ch <- prometheus.MustNewConstHistogram(requestLatencyDesc, h.count, h.sum, h.buckets, key[0], key[1], apiKey.String())
+		}
+	}
+}
+
+// observeLatency accumulates the latency histograms of a connection pool, and
+// returns the key of the pool's histograms.
+func (c *Collector) observeLatency(stats kafka.ConnPoolStats) [2]string {
+	key := [2]string{stats.Addr, strconv.Itoa(stats.BrokerID)}
+
+	histograms := c.latencies[key]
+	if histograms == nil {
+		histograms = make(map[protocol.ApiKey]*histogram)
+		c.latencies[key] = histograms
+	}
+
+	for apiKey, latency := range stats.Latency {
+		h := histograms[apiKey]
+		if h == nil {
+			h = &histogram{buckets: make(map[float64]uint64, len(latency.Buckets))}
+			histograms[apiKey] = h
+		}
+		h.count += uint64(latency.Count)
+		h.sum += latency.Sum.Seconds()
+		for _, b := range latency.Buckets {
+			h.buckets[b.UpperBound.Seconds()] += uint64(b.Count)
+		}
+	}
+
+	return key
+}
+
+type sample struct {
+	metric *metric
+	labels []string
+	value  float64
+}
+
+// observe adds the metrics of stats to samples. The counters of delta
+// snapshots are accumulated across scrapes, those of cumulative snapshots are
+// added to the counters of the other objects reporting the same labels.
+func (c *Collector) observe(samples map[string]*sample, s *schema, stats reflect.Value, delta bool, extraLabels ...string) {
+	labels := make([]string, 0, len(s.labels)+len(extraLabels))
+	for _, index := range s.labels {
+		labels = append(labels, labelValue(stats.FieldByIndex(index)))
+	}
+	labels = append(labels, extraLabels...)
+
+	for i := range s.metrics {
+		m := &s.metrics[i]
+		key := m.name + "\xff" + strings.Join(labels, "\xff")
+		value := metricValue(stats.FieldByIndex(m.index))
+
+		if m.kind == prometheus.CounterValue {
+			if delta {
+				c.totals[key] += value
+				value = c.totals[key]
+			} else if prev := samples[key]; prev != nil {
+				value += prev.value
+			}
+		}
+
+		samples[key] = &sample{metric: m, labels: labels, value: value}
+	}
+}
+
+// schema describes the metrics and labels of a statistics type.
+type schema struct {
+	metrics []metric
+	labels  [][]int
+}
+
+type metric struct {
+	name  string
+	desc  *prometheus.Desc
+	kind  prometheus.ValueType
+	index []int
+}
+
+func newSchema(t reflect.Type, extraLabels ...string) *schema {
+	s := &schema{}
+	labelNames := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if tag := f.Tag.Get("tag"); tag != "" {
+			s.labels = append(s.labels, f.Index)
+			labelNames = append(labelNames, tag)
+		}
+	}
+	labelNames = append(labelNames, extraLabels...)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get("metric")
+		// Skip fields which are not reported, and those with misspelled
+		// names kept for backward compatibility.
+		if !strings.HasPrefix(name, "kafka.") {
+			continue
+		}
+
+		if f.Type.Kind() != reflect.Struct {
+			s.metrics = append(s.metrics, newMetric(name, f.Tag.Get("type"), f.Index, labelNames))
+			continue
+		}
+
+		for j := 0; j < f.Type.NumField(); j++ {
+			sub := f.Type.Field(j)
+			index := append(f.Index[:len(f.Index):len(f.Index)], sub.Index...)
+			s.metrics = append(s.metrics, newMetric(name+"."+sub.Tag.Get("metric"), sub.Tag.Get("type"), index, labelNames))
+		}
+	}
+
+	return s
+}
+
+func newMetric(name, typ string, index []int, labelNames []string) metric {
+	kind := prometheus.GaugeValue
+	if typ == "counter" {
+		kind = prometheus.CounterValue
+	}
+	name = strings.ReplaceAll(name, ".", "_")
+	return metric{
+		name:  name,
+		desc:  prometheus.NewDesc(name, "kafka-go statistic "+name, labelNames, nil),
+		kind:  kind,
+		index: index,
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func metricValue(v reflect.Value) float64 {
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).Seconds()
+	case v.Kind() == reflect.Bool:
+		if v.Bool() {
+			return 1
+		}
+		return 0
+	default:
+		return float64(v.Int())
+	}
+}
+
+func labelValue(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	return strconv.FormatInt(v.Int(), 10)
+}
@@ -0,0 +1,197 @@
+package kafkaprom
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+	metadataAPI "github.com/segmentio/kafka-go/protocol/metadata"
+	produceAPI "github.com/segmentio/kafka-go/protocol/produce"
+)
+
+type roundTripperFunc func(context.Context, net.Addr, kafka.Request) (kafka.Response, error)
+
+func (f roundTripperFunc) RoundTrip(ctx context.Context, addr net.Addr, req kafka.Request) (kafka.Response, error) {
+	return f(ctx, addr, req)
+}
+
+func newWriter() *kafka.Writer {
+	transport := roundTripperFunc(func(ctx context.Context, addr net.Addr, req kafka.Request) (kafka.Response, error) {
+		switch req.(type) {
+		case *metadataAPI.Request:
+			return &metadataAPI.Response{
+				Brokers: []metadataAPI.ResponseBroker{{NodeID: 1, Host: "localhost", Port: 9092}},
+				Topics: []metadataAPI.ResponseTopic{{
+					Name:       "topic-1",
+					Partitions: []metadataAPI.ResponsePartition{{PartitionIndex: 0, LeaderID: 1}},
+				}},
+			}, nil
+		case *produceAPI.Request:
+			return &produceAPI.Response{
+				Topics: []produceAPI.ResponseTopic{{
+					Topic:      "topic-1",
+					Partitions: []produceAPI.ResponsePartition{{Partition: 0}},
+				}},
+			}, nil
+		default:
+			return nil, fmt.Errorf("unexpected request: %T", req)
+		}
+	})
+
+	return &kafka.Writer{
+		Addr:         kafka.TCP("localhost:9092"),
+		Topic:        "topic-1",
+		BatchTimeout: time.Millisecond,
+		RequiredAcks: kafka.RequireOne,
+		Transport:    transport,
+	}
+}
+
+func gather(t *testing.T, c *Collector) map[string]*dto.MetricFamily {
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(c); err != nil {
+		t.Fatal(err)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, f := range families {
+		byName[f.GetName()] = f
+	}
+	return byName
+}
+
+func labels(m *dto.Metric) map[string]string {
+	l := make(map[string]string)
+	for _, p := range m.GetLabel() {
+		l[p.GetName()] = p.GetValue()
+	}
+	return l
+}
+
+func TestCollectorWriter(t *testing.T) {
+	w := newWriter()
+	defer w.Close()
+
+	c := NewCollector()
+	c.AddWriter(w)
+
+	for i := 0; i < 3; i++ {
+		if err := w.WriteMessages(context.Background(), kafka.Message{Value: []byte("hello")}); err != nil {
+			t.Fatal(err)
+		}
+		// Scrapes and calls to Stats by the program must not change the
+		// values reported by the collector.
+		gather(t, c)
+		if stats := w.Stats(); stats.Messages != 1 {
+			t.Errorf("the scrape reset the stats of the writer: %d messages", stats.Messages)
+		}
+	}
+
+	families := gather(t, c)
+
+	f := families["kafka_writer_message_count"]
+	if f == nil {
+		t.Fatal("missing kafka_writer_message_count")
+	}
+	if f.GetType() != dto.MetricType_COUNTER {
+		t.Errorf("expected a counter but got %v", f.GetType())
+	}
+	m := f.GetMetric()[0]
+	if v := m.GetCounter().GetValue(); v != 3 {
+		t.Errorf("expected 3 messages but got %v", v)
+	}
+	if l := labels(m); l["topic"] != "topic-1" {
+		t.Errorf("unexpected labels: %v", l)
+	}
+
+	for _, name := range []string{
+		"kafka_writer_write_seconds_avg",
+		"kafka_writer_batch_size_max",
+		"kafka_writer_batch_timeout",
+	} {
+		f := families[name]
+		if f == nil {
+			t.Errorf("missing %s", name)
+		} else if f.GetType() != dto.MetricType_GAUGE {
+			t.Errorf("expected %s to be a gauge but got %v", name, f.GetType())
+		}
+	}
+
+	if v := families["kafka_writer_batch_timeout"].GetMetric()[0].GetGauge().GetValue(); v != 0.001 {
+		t.Errorf("expected the batch timeout in seconds but got %v", v)
+	}
+
+	c.RemoveWriter(w)
+	if families := gather(t, c); len(families) != 0 {
+		t.Errorf("expected no metrics after removing the writer but got %d", len(families))
+	}
+}
+
+func TestCollectorReader(t *testing.T) {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: []string{"localhost:9092"},
+		GroupID: "group-1",
+		Topic:   "topic-1",
+	})
+	defer r.Close()
+
+	c := NewCollector()
+	c.AddReader(r)
+
+	families := gather(t, c)
+	if _, ok := families["kafak_reader_fetch_count"]; ok {
+		t.Error("the misspelled fetch count should not be reported")
+	}
+
+	f := families["kafka_reader_lag"]
+	if f == nil {
+		t.Fatal("missing kafka_reader_lag")
+	}
+	l := labels(f.GetMetric()[0])
+	if l["topic"] != "topic-1" || l["group"] != "group-1" || l["partition"] != "-1" {
+		t.Errorf("unexpected labels: %v", l)
+	}
+}
+
+func TestCollectorTransportsOfSameCluster(t *testing.T) {
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-1"})
+	addr := b.Addr()
+
+	c := NewCollector()
+	for i := 0; i < 2; i++ {
+		transport := &kafka.Transport{}
+		defer transport.CloseIdleConnections()
+		c.AddTransport(transport, addr)
+
+		client := &kafka.Client{Addr: addr, Transport: transport}
+		if _, err := client.Metadata(context.Background(), &kafka.MetadataRequest{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Gathering fails if the same series is reported twice.
+	families := gather(t, c)
+
+	f := families["kafka_transport_request_seconds"]
+	if f == nil {
+		t.Fatal("missing kafka_transport_request_seconds")
+	}
+	var count uint64
+	for _, m := range f.GetMetric() {
+		if l := labels(m); l["api"] == "Metadata" {
+			count += m.GetHistogram().GetSampleCount()
+		}
+	}
+	if count != 2 {
+		t.Errorf("expected the latency of 2 metadata requests but got %d", count)
+	}
+}
@@ -0,0 +1,378 @@
+// Package kafkatest provides an in-memory kafka broker and fakes of readers
+// and writers for tests.
+//
+// The Broker type listens on a local socket and implements enough of the
+// kafka protocol for readers, writers, consumer groups, and clients of the
+// kafka-go package to work against it, which lets programs test the code using
+// them without running a kafka cluster:
+//
+//	broker := kafkatest.NewBroker()
+//	defer broker.Close()
+//
+//	w := &kafka.Writer{
+//		Addr:  kafka.TCP(broker.Addr().String()),
+//		Topic: "topic-A",
+//	}
+//
+// The broker keeps records in memory, it does not implement replication,
+// quotas, or authentication, and behaves as a cluster made of a single broker
+// which is the leader of all partitions. Transactions are supported, but the
+// records and offsets of a transaction are only written when it commits, so
+// all consumers behave as if they were reading committed records.
+//
+// Tests can use NewTestBroker instead, which creates topics and appends their
+// records, and closes the broker when the test completes.
+//
+// Programs which only need to substitute readers and writers can use the Reader
+// and Writer fakes instead, through the kafka.MessageReader and
+// kafka.MessageWriter interfaces.
+//
+// This package does not make any promises around backwards compatibility.
+package kafkatest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/addoffsetstotxn"
+	"github.com/segmentio/kafka-go/protocol/addpartitionstotxn"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/createtopics"
+	"github.com/segmentio/kafka-go/protocol/deletetopics"
+	"github.com/segmentio/kafka-go/protocol/describeconfigs"
+	"github.com/segmentio/kafka-go/protocol/describeproducers"
+	"github.com/segmentio/kafka-go/protocol/describetransactions"
+	"github.com/segmentio/kafka-go/protocol/endtxn"
+	"github.com/segmentio/kafka-go/protocol/fetch"
+	"github.com/segmentio/kafka-go/protocol/findcoordinator"
+	"github.com/segmentio/kafka-go/protocol/heartbeat"
+	"github.com/segmentio/kafka-go/protocol/initproducerid"
+	"github.com/segmentio/kafka-go/protocol/joingroup"
+	"github.com/segmentio/kafka-go/protocol/leavegroup"
+	"github.com/segmentio/kafka-go/protocol/listoffsets"
+	"github.com/segmentio/kafka-go/protocol/listtransactions"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/offsetcommit"
+	"github.com/segmentio/kafka-go/protocol/offsetfetch"
+	"github.com/segmentio/kafka-go/protocol/produce"
+	"github.com/segmentio/kafka-go/protocol/syncgroup"
+	"github.com/segmentio/kafka-go/protocol/txnoffsetcommit"
+	"github.com/segmentio/kafka-go/protocol/writetxnmarkers"
+)
+
+// Error codes of the kafka protocol returned by the broker.
+const (
+	errNone                      int16 = 0
+	errOffsetOutOfRange          int16 = 1
+	errCorruptMessage            int16 = 2
+	errUnknownTopicOrPartition   int16 = 3
+	errIllegalGeneration         int16 = 22
+	errInconsistentGroupProtocol int16 = 23
+	errUnknownMemberID           int16 = 25
+	errRebalanceInProgress       int16 = 27
+	errTopicAlreadyExists        int16 = 36
+	errInvalidPartitions         int16 = 37
+	errInvalidRequest            int16 = 42
+	errInvalidProducerEpoch      int16 = 47
+	errInvalidTxnState           int16 = 48
+	errInvalidProducerIDMapping  int16 = 49
+	errProducerFenced            int16 = 90
+	errTransactionalIDNotFound   int16 = 105
+)
+
+const (
+	nodeID    = 1
+	clusterID = "kafkatest"
+)
+
+// apiVersions are the APIs implemented by the broker, and the maximum version
+// that it supports for each of them. The minimum versions are the ones of the
+// protocol package.
+var apiVersions = map[protocol.ApiKey]int16{
+	protocol.Produce:            8,
+	protocol.Fetch:              11,
+	protocol.ListOffsets:        5,
+	protocol.Metadata:           8,
+	protocol.OffsetCommit:       7,
+	protocol.OffsetFetch:        5,
+	protocol.FindCoordinator:    4,
+	protocol.JoinGroup:          5,
+	protocol.Heartbeat:          3,
+	protocol.LeaveGroup:         2,
+	protocol.SyncGroup:          3,
+	protocol.ApiVersions:        2,
+	protocol.CreateTopics:       4,
+	protocol.DeleteTopics:       3,
+	protocol.InitProducerId:     4,
+	protocol.AddPartitionsToTxn: 3,
+	protocol.AddOffsetsToTxn:    3,
+	protocol.TxnOffsetCommit:    3,
+	protocol.EndTxn:             3,
+	protocol.DescribeConfigs:    1,
+
+	protocol.WriteTxnMarkers:      1,
+	protocol.DescribeProducers:    0,
+	protocol.DescribeTransactions: 0,
+	protocol.ListTransactions:     1,
+}
+
+// Broker is an in-memory kafka broker.
+//
+// The exported fields configure the broker, they must be set before calling
+// Start.
+type Broker struct {
+	// When true, topics are created when clients request their metadata.
+	AutoCreateTopics bool
+
+	// The number of partitions of topics created automatically, or with
+	// CreateTopics requests which do not specify the number of partitions.
+	//
+	// Default: 1
+	DefaultPartitions int
+
+	listener net.Listener
+	host     string
+	port     int32
+	ctx      context.Context
+	cancel   context.CancelFunc
+	join     sync.WaitGroup
+
+	mutex  sync.Mutex
+	conns  map[net.Conn]struct{}
+	topics map[string][]*partitionLog
+	// Configuration entries of topics, returned by DescribeConfigs requests.
+	configs map[string]map[string]string
+	groups  map[string]*group
+	txns    map[string]*transaction
+	// Closed and replaced each time records are appended to a partition, to
+	// wake up the fetch requests waiting for records.
+	appended chan struct{}
+	members  int
+	// The last producer id assigned by InitProducerId requests.
+	producerIDs int64
+}
+
+// NewBroker starts and returns a new broker listening on a local port, with
+// AutoCreateTopics enabled. It panics if the broker could not be started.
+func NewBroker() *Broker {
+	b := &Broker{AutoCreateTopics: true}
+	if err := b.Start(); err != nil {
+		panic(fmt.Sprintf("kafkatest: failed to start broker: %v", err))
+	}
+	return b
+}
+
+// Start starts the broker on a local port.
+func (b *Broker) Start() error {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+
+	addr := l.Addr().(*net.TCPAddr)
+	b.listener = l
+	b.host = addr.IP.String()
+	b.port = int32(addr.Port)
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	b.conns = make(map[net.Conn]struct{})
+	b.topics = make(map[string][]*partitionLog)
+	b.configs = make(map[string]map[string]string)
+	b.groups = make(map[string]*group)
+	b.txns = make(map[string]*transaction)
+	b.appended = make(chan struct{})
+
+	b.join.Add(2)
+	go b.accept()
+	go b.expireSessions()
+	return nil
+}
+
+// Addr returns the address that the broker is listening on.
+func (b *Broker) Addr() net.Addr {
+	return b.listener.Addr()
+}
+
+// Close stops the broker, closing all connections to it.
+func (b *Broker) Close() error {
+	b.cancel()
+	err := b.listener.Close()
+
+	b.mutex.Lock()
+	for conn := range b.conns {
+		conn.Close()
+	}
+	b.mutex.Unlock()
+
+	b.join.Wait()
+	return err
+}
+
+func (b *Broker) accept() {
+	defer b.join.Done()
+
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		b.mutex.Lock()
+		b.conns[conn] = struct{}{}
+		b.mutex.Unlock()
+
+		b.join.Add(1)
+		go b.serve(conn)
+	}
+}
+
+func (b *Broker) serve(conn net.Conn) {
+	defer b.join.Done()
+	defer func() {
+		b.mutex.Lock()
+		delete(b.conns, conn)
+		b.mutex.Unlock()
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		apiVersion, correlationID, clientID, req, err := protocol.ReadRequest(r)
+		if err != nil {
+			return
+		}
+
+		res, err := b.handle(apiVersion, clientID, req)
+		if err != nil {
+			return
+		}
+		if res == nil {
+			// Produce requests with no acknowledgements do not get responses.
+			continue
+		}
+
+		if err := protocol.WriteResponse(w, apiVersion, correlationID, res); err != nil {
+			return
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (b *Broker) handle(apiVersion int16, clientID string, req protocol.Message) (protocol.Message, error) {
+	switch req := req.(type) {
+	case *apiversions.Request:
+		return b.apiVersions(), nil
+	case *metadata.Request:
+		return b.metadata(apiVersion, req), nil
+	case *createtopics.Request:
+		return b.createTopics(req), nil
+	case *deletetopics.Request:
+		return b.deleteTopics(req), nil
+	case *produce.Request:
+		return b.produce(req), nil
+	case *fetch.Request:
+		return b.fetch(req), nil
+	case *listoffsets.Request:
+		return b.listOffsets(req), nil
+	case *findcoordinator.Request:
+		return b.findCoordinator(req), nil
+	case *joingroup.Request:
+		return b.joinGroup(clientID, req), nil
+	case *syncgroup.Request:
+		return b.syncGroup(req), nil
+	case *heartbeat.Request:
+		return b.heartbeat(req), nil
+	case *leavegroup.Request:
+		return b.leaveGroup(req), nil
+	case *offsetcommit.Request:
+		return b.offsetCommit(req), nil
+	case *offsetfetch.Request:
+		return b.offsetFetch(req), nil
+	case *initproducerid.Request:
+		return b.initProducerID(req), nil
+	case *addpartitionstotxn.Request:
+		return b.addPartitionsToTxn(req), nil
+	case *addoffsetstotxn.Request:
+		return b.addOffsetsToTxn(req), nil
+	case *txnoffsetcommit.Request:
+		return b.txnOffsetCommit(req), nil
+	case *endtxn.Request:
+		return b.endTxn(req), nil
+	case *describeconfigs.Request:
+		return b.describeConfigs(req), nil
+	case *describeproducers.Request:
+		return b.describeProducers(req), nil
+	case *describetransactions.Request:
+		return b.describeTransactions(req), nil
+	case *listtransactions.Request:
+		return b.listTransactions(req), nil
+	case *writetxnmarkers.Request:
+		return b.writeTxnMarkers(req), nil
+	default:
+		// The API was not advertised by ApiVersions, the connection is closed
+		// to report the error to the client.
+		return nil, fmt.Errorf("unsupported request %T", req)
+	}
+}
+
+func (b *Broker) apiVersions() *apiversions.Response {
+	res := &apiversions.Response{}
+	for apiKey, maxVersion := range apiVersions {
+		res.ApiKeys = append(res.ApiKeys, apiversions.ApiKeyResponse{
+			ApiKey:     int16(apiKey),
+			MinVersion: apiKey.MinVersion(),
+			MaxVersion: maxVersion,
+		})
+	}
+	return res
+}
+
+// findCoordinator returns the broker as the coordinator of all groups and
+// transactions, v4+ requests look up a list of keys.
+func (b *Broker) findCoordinator(req *findcoordinator.Request) *findcoordinator.Response {
+	if req.CoordinatorKeys == nil {
+		return &findcoordinator.Response{NodeID: nodeID, Host: b.host, Port: b.port}
+	}
+	res := &findcoordinator.Response{
+		Coordinators: make([]findcoordinator.ResponseCoordinator, len(req.CoordinatorKeys)),
+	}
+	for i, key := range req.CoordinatorKeys {
+		res.Coordinators[i] = findcoordinator.ResponseCoordinator{
+			Key:    key,
+			NodeID: nodeID,
+			Host:   b.host,
+			Port:   b.port,
+		}
+	}
+	return res
+}
+
+// expireSessions removes the members of consumer groups which did not send
+// heartbeats for longer than their session timeout.
+func (b *Broker) expireSessions() {
+	defer b.join.Done()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			b.mutex.Lock()
+			for _, g := range b.groups {
+				b.expire(g, now)
+			}
+			b.mutex.Unlock()
+		case <-b.ctx.Done():
+			return
+		}
+	}
+}
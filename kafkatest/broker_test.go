@@ -0,0 +1,442 @@
+package kafkatest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func TestBrokerWriterReader(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A", Partitions: 2})
+
+	w := &kafka.Writer{
+		Addr:         kafka.TCP(b.Addr().String()),
+		Topic:        "topic-A",
+		Balancer:     &kafka.RoundRobin{},
+		BatchTimeout: time.Millisecond,
+		RequiredAcks: kafka.RequireAll,
+	}
+	defer w.Close()
+
+	msgs := make([]kafka.Message, 10)
+	for i := range msgs {
+		msgs[i] = kafka.Message{
+			Key:     []byte(fmt.Sprintf("key-%d", i)),
+			Value:   []byte(fmt.Sprintf("value-%d", i)),
+			Headers: []kafka.Header{{Key: "index", Value: []byte(fmt.Sprint(i))}},
+		}
+	}
+	if err := w.WriteMessages(ctx, msgs...); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(b.Records("topic-A", 0)) + len(b.Records("topic-A", 1)); n != len(msgs) {
+		t.Fatalf("wrong number of records stored: %d", n)
+	}
+
+	for partition := 0; partition < 2; partition++ {
+		records := b.Records("topic-A", partition)
+
+		r := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   []string{b.Addr().String()},
+			Topic:     "topic-A",
+			Partition: partition,
+			MaxWait:   10 * time.Millisecond,
+		})
+
+		for i, record := range records {
+			m, err := r.ReadMessage(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Offset != int64(i) || string(m.Key) != string(record.Key) || string(m.Value) != string(record.Value) {
+				t.Errorf("wrong message at offset %d of partition %d: %d %q=%q", i, partition, m.Offset, m.Key, m.Value)
+			}
+			if len(m.Headers) != 1 || m.Headers[0].Key != "index" {
+				t.Errorf("wrong headers: %+v", m.Headers)
+			}
+		}
+
+		r.Close()
+	}
+}
+
+func TestBrokerConsumerGroup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A", Partitions: 3})
+	for partition := 0; partition < 3; partition++ {
+		for i := 0; i < 2; i++ {
+			value := fmt.Sprintf("%d-%d", partition, i)
+			if _, err := b.Append("topic-A", partition, kafkatest.Record{Value: []byte(value)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:           []string{b.Addr().String()},
+		GroupID:           "group-A",
+		Topic:             "topic-A",
+		MaxWait:           10 * time.Millisecond,
+		HeartbeatInterval: 100 * time.Millisecond,
+	})
+	defer r.Close()
+
+	seen := make(map[string]bool)
+	for len(seen) < 6 {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[string(m.Value)] = true
+
+		if err := r.CommitMessages(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for partition := 0; partition < 3; partition++ {
+		offset, ok := b.CommittedOffset("group-A", "topic-A", partition)
+		if !ok || offset != 2 {
+			t.Errorf("wrong committed offset for partition %d: %d (%t)", partition, offset, ok)
+		}
+	}
+}
+
+func TestBrokerClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t)
+	client := &kafka.Client{Addr: b.Addr()}
+
+	created, err := client.CreateTopics(ctx, &kafka.CreateTopicsRequest{
+		Topics: []kafka.TopicConfig{{Topic: "topic-A", NumPartitions: 1, ReplicationFactor: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := created.Errors["topic-A"]; err != nil {
+		t.Fatal(err)
+	}
+
+	produced, err := client.Produce(ctx, &kafka.ProduceRequest{
+		Topic:        "topic-A",
+		Partition:    0,
+		RequiredAcks: kafka.RequireAll,
+		Records: kafka.NewRecordReader(
+			kafka.Record{Value: kafka.NewBytes([]byte("hello"))},
+			kafka.Record{Value: kafka.NewBytes([]byte("world"))},
+		),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if produced.Error != nil {
+		t.Fatal(produced.Error)
+	}
+
+	offsets, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{
+			"topic-A": {kafka.FirstOffsetOf(0), kafka.LastOffsetOf(0)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := offsets.Topics["topic-A"][0]
+	if p.FirstOffset != 0 || p.LastOffset != 2 {
+		t.Errorf("wrong offsets: first=%d last=%d", p.FirstOffset, p.LastOffset)
+	}
+
+	fetched, err := client.Fetch(ctx, &kafka.FetchRequest{
+		Topic:     "topic-A",
+		Partition: 0,
+		Offset:    1,
+		MaxBytes:  1 << 20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetched.Error != nil {
+		t.Fatal(fetched.Error)
+	}
+	if fetched.HighWatermark != 2 {
+		t.Errorf("wrong high watermark: %d", fetched.HighWatermark)
+	}
+
+	record, err := fetched.Records.ReadRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := kafka.ReadAll(record.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Offset != 1 || string(value) != "world" {
+		t.Errorf("wrong record: %d %q", record.Offset, value)
+	}
+}
+
+func TestBrokerTopicConfigs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t)
+	client := &kafka.Client{Addr: b.Addr()}
+
+	created, err := client.CreateTopics(ctx, &kafka.CreateTopicsRequest{
+		Topics: []kafka.TopicConfig{{
+			Topic:             "topic-A",
+			NumPartitions:     1,
+			ReplicationFactor: 1,
+			ConfigEntries:     []kafka.ConfigEntry{{ConfigName: "retention.ms", ConfigValue: "1000"}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := created.Errors["topic-A"]; err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetTopicConfig("topic-A", "min.insync.replicas", "2"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.DescribeConfigs(ctx, &kafka.DescribeConfigsRequest{
+		Resources: []kafka.DescribeConfigRequestResource{{
+			ResourceType: kafka.ResourceTypeTopic,
+			ResourceName: "topic-A",
+			ConfigNames:  []string{"retention.ms", "min.insync.replicas", "cleanup.policy"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configs := make(map[string]string)
+	for _, e := range res.Resources[0].ConfigEntries {
+		configs[e.ConfigName] = e.ConfigValue
+	}
+	if fmt.Sprint(configs) != "map[min.insync.replicas:2 retention.ms:1000]" {
+		t.Errorf("wrong topic configs: %v", configs)
+	}
+
+	if err := b.SetTopicConfig("topic-B", "min.insync.replicas", "2"); err == nil {
+		t.Error("expected an error setting the config of an unknown topic")
+	}
+}
+
+func TestBrokerConsumerGroupRebalance(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A", Partitions: 2})
+
+	newGroup := func() *kafka.ConsumerGroup {
+		g, err := kafka.NewConsumerGroup(kafka.ConsumerGroupConfig{
+			ID:                "group-A",
+			Brokers:           []string{b.Addr().String()},
+			Topics:            []string{"topic-A"},
+			HeartbeatInterval: 50 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { g.Close() })
+		return g
+	}
+
+	g1 := newGroup()
+	gen, err := g1.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(gen.Assignments["topic-A"]); n != 2 {
+		t.Fatalf("first member was assigned %d partitions", n)
+	}
+
+	g2 := newGroup()
+	gen1, err := g1.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen2, err := g2.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen1.ID != gen2.ID {
+		t.Errorf("members are in different generations: %d != %d", gen1.ID, gen2.ID)
+	}
+	if n1, n2 := len(gen1.Assignments["topic-A"]), len(gen2.Assignments["topic-A"]); n1 != 1 || n2 != 1 {
+		t.Errorf("partitions were not balanced between members: %d/%d", n1, n2)
+	}
+}
+
+func TestBrokerTransactions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+	client := &kafka.Client{Addr: b.Addr()}
+
+	initProducer := func() *kafka.ProducerSession {
+		res, err := client.InitProducerID(ctx, &kafka.InitProducerIDRequest{
+			TransactionalID:      "txn-A",
+			TransactionTimeoutMs: 10000,
+			ProducerID:           -1,
+			ProducerEpoch:        -1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		return res.Producer
+	}
+
+	transaction := func(producer *kafka.ProducerSession, value string, sequence int, commit bool) error {
+		added, err := client.AddPartitionsToTxn(ctx, &kafka.AddPartitionsToTxnRequest{
+			TransactionalID: "txn-A",
+			ProducerID:      producer.ProducerID,
+			ProducerEpoch:   producer.ProducerEpoch,
+			Topics:          map[string][]kafka.AddPartitionToTxn{"topic-A": {{Partition: 0}}},
+		})
+		if err != nil {
+			return err
+		}
+		if err := added.Topics["topic-A"][0].Error; err != nil {
+			return err
+		}
+
+		produced, err := client.Produce(ctx, &kafka.ProduceRequest{
+			Topic:           "topic-A",
+			Partition:       0,
+			RequiredAcks:    kafka.RequireAll,
+			TransactionalID: "txn-A",
+			Producer:        producer,
+			BaseSequence:    sequence,
+			Records:         kafka.NewRecordReader(kafka.Record{Value: kafka.NewBytes([]byte(value))}),
+		})
+		if err != nil {
+			return err
+		}
+		if produced.Error != nil {
+			return produced.Error
+		}
+		if n := len(b.Records("topic-A", 0)); n != 1 {
+			t.Errorf("records of ongoing transactions must not be visible: %d", n)
+		}
+
+		ended, err := client.EndTxn(ctx, &kafka.EndTxnRequest{
+			TransactionalID: "txn-A",
+			ProducerID:      producer.ProducerID,
+			ProducerEpoch:   producer.ProducerEpoch,
+			Committed:       commit,
+		})
+		if err != nil {
+			return err
+		}
+		return ended.Error
+	}
+
+	if _, err := b.Append("topic-A", 0, kafkatest.Record{Value: []byte("plain")}); err != nil {
+		t.Fatal(err)
+	}
+
+	producer := initProducer()
+	if err := transaction(producer, "aborted", 0, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := transaction(producer, "committed", 1, true); err != nil {
+		t.Fatal(err)
+	}
+
+	records := b.Records("topic-A", 0)
+	if len(records) != 2 || string(records[1].Value) != "committed" {
+		t.Fatalf("wrong records after the transactions: %+v", records)
+	}
+
+	// Initializing the transactional id again fences the previous producer.
+	if newProducer := initProducer(); newProducer.ProducerEpoch != producer.ProducerEpoch+1 {
+		t.Errorf("wrong producer epoch: %d", newProducer.ProducerEpoch)
+	}
+	if err := transaction(producer, "fenced", 2, true); !errors.Is(err, kafka.ProducerFenced) {
+		t.Errorf("expected %v but got %v", kafka.ProducerFenced, err)
+	}
+}
+
+func TestBrokerExpireTransaction(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t)
+	client := &kafka.Client{Addr: b.Addr()}
+
+	initProducer := func(producer *kafka.ProducerSession) (*kafka.ProducerSession, error) {
+		req := &kafka.InitProducerIDRequest{
+			TransactionalID:      "txn-A",
+			TransactionTimeoutMs: 10000,
+			ProducerID:           -1,
+			ProducerEpoch:        -1,
+		}
+		if producer != nil {
+			req.ProducerID, req.ProducerEpoch = producer.ProducerID, producer.ProducerEpoch
+		}
+		res, err := client.InitProducerID(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Producer, res.Error
+	}
+
+	if err := b.ExpireTransaction("txn-A"); err == nil {
+		t.Error("expected an error expiring the transaction of an unknown transactional id")
+	}
+
+	producer, err := initProducer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.ExpireTransaction("txn-A"); err != nil {
+		t.Fatal(err)
+	}
+
+	added, err := client.AddOffsetsToTxn(ctx, &kafka.AddOffsetsToTxnRequest{
+		TransactionalID: "txn-A",
+		ProducerID:      producer.ProducerID,
+		ProducerEpoch:   producer.ProducerEpoch,
+		GroupID:         "group-A",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(added.Error, kafka.InvalidProducerEpoch) {
+		t.Errorf("expected %v but got %v", kafka.InvalidProducerEpoch, added.Error)
+	}
+
+	// The producer of the expired transaction may bump the epoch again.
+	bumped, err := initProducer(producer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bumped.ProducerID != producer.ProducerID || bumped.ProducerEpoch != producer.ProducerEpoch+2 {
+		t.Errorf("wrong bumped producer: %+v", bumped)
+	}
+
+	// Once bumped, older epochs are fenced.
+	if _, err := initProducer(producer); !errors.Is(err, kafka.ProducerFenced) {
+		t.Errorf("expected %v but got %v", kafka.ProducerFenced, err)
+	}
+}
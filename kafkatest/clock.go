@@ -0,0 +1,208 @@
+package kafkatest
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Clock is a fake implementation of kafka.Clock, its time only moves forward
+// when Advance is called. Timers and tickers fire when the time is advanced
+// past their deadlines, so tests can drive batch timeouts, heartbeats, and
+// backoffs without sleeping:
+//
+//	clock := kafkatest.NewClock(time.Now())
+//	w := &kafka.Writer{
+//		Addr:         kafka.TCP(broker.Addr().String()),
+//		Topic:        "topic-A",
+//		BatchTimeout: time.Minute,
+//		Clock:        clock,
+//	}
+//
+//	go w.WriteMessages(ctx, msg)
+//	clock.WaitTimers(ctx, 1) // wait for the batch to be created
+//	clock.Advance(time.Minute)
+//
+// The zero-value is a clock starting at the zero time.
+type Clock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	// Closed and replaced when timers are created, to wake up WaitTimers.
+	added chan struct{}
+}
+
+// NewClock returns a fake clock starting at now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now satisfies the kafka.Clock interface.
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// NewTimer satisfies the kafka.Clock interface.
+func (c *Clock) NewTimer(d time.Duration) kafka.Timer {
+	return c.newTimer(d, 0)
+}
+
+// NewTicker satisfies the kafka.Clock interface.
+func (c *Clock) NewTicker(d time.Duration) kafka.Ticker {
+	if d <= 0 {
+		panic("kafkatest: non-positive interval for NewTicker")
+	}
+	return fakeTicker{c.newTimer(d, d)}
+}
+
+func (c *Clock) newTimer(d, period time.Duration) *fakeTimer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	t := &fakeTimer{
+		clock:  c,
+		period: period,
+		c:      make(chan time.Time, 1),
+	}
+	c.start(t, d)
+	return t
+}
+
+// Advance moves the time of the clock forward by d, firing the timers and
+// tickers with deadlines up to the new time, in order of their deadlines.
+//
+// Like with the time package, ticks are dropped when the ticker channels are
+// not drained quickly enough.
+func (c *Clock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	end := c.now.Add(d)
+
+	for len(c.timers) != 0 {
+		sort.SliceStable(c.timers, func(i, j int) bool {
+			return c.timers[i].when.Before(c.timers[j].when)
+		})
+
+		t := c.timers[0]
+		if t.when.After(end) {
+			break
+		}
+		if t.when.After(c.now) {
+			c.now = t.when
+		}
+
+		select {
+		case t.c <- c.now:
+		default:
+		}
+
+		if t.period > 0 {
+			t.when = t.when.Add(t.period)
+		} else {
+			c.stop(t)
+		}
+	}
+
+	c.now = end
+}
+
+// Timers returns the number of timers and tickers which are waiting to fire.
+func (c *Clock) Timers() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.timers)
+}
+
+// WaitTimers blocks until at least n timers or tickers are waiting to fire, or
+// ctx is cancelled. Tests use it to synchronize with the goroutines of the
+// code under test before advancing the time.
+func (c *Clock) WaitTimers(ctx context.Context, n int) error {
+	for {
+		c.mutex.Lock()
+		if len(c.timers) >= n {
+			c.mutex.Unlock()
+			return nil
+		}
+		if c.added == nil {
+			c.added = make(chan struct{})
+		}
+		added := c.added
+		c.mutex.Unlock()
+
+		select {
+		case <-added:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// start schedules t to fire after d, it must be called with the mutex held.
+func (c *Clock) start(t *fakeTimer, d time.Duration) {
+	t.when = c.now.Add(d)
+	if !t.active {
+		t.active = true
+		c.timers = append(c.timers, t)
+	}
+	if c.added != nil {
+		close(c.added)
+		c.added = nil
+	}
+}
+
+// stop unschedules t, it must be called with the mutex held.
+func (c *Clock) stop(t *fakeTimer) bool {
+	if !t.active {
+		return false
+	}
+	t.active = false
+	for i, x := range c.timers {
+		if x == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// fakeTimer implements kafka.Timer, and kafka.Ticker when wrapped in a
+// fakeTicker. Tickers have a non-zero period.
+type fakeTimer struct {
+	clock  *Clock
+	when   time.Time
+	period time.Duration
+	active bool
+	c      chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	return t.clock.stop(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	active := t.active
+	t.clock.start(t, d)
+	return active
+}
+
+type fakeTicker struct{ *fakeTimer }
+
+func (t fakeTicker) Stop() { t.fakeTimer.Stop() }
+
+var (
+	_ kafka.Clock  = (*Clock)(nil)
+	_ kafka.Timer  = (*fakeTimer)(nil)
+	_ kafka.Ticker = fakeTicker{}
+)
@@ -0,0 +1,134 @@
+package kafkatest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := kafkatest.NewClock(start)
+
+	timer := clock.NewTimer(time.Second)
+	ticker := clock.NewTicker(300 * time.Millisecond)
+	defer ticker.Stop()
+
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-timer.C():
+		t.Fatal("the timer fired before its deadline")
+	default:
+	}
+	if tick := <-ticker.C(); !tick.Equal(start.Add(300 * time.Millisecond)) {
+		t.Errorf("wrong tick time: %s", tick)
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if now := <-timer.C(); !now.Equal(start.Add(time.Second)) {
+		t.Errorf("wrong timer time: %s", now)
+	}
+	if timer.Stop() {
+		t.Error("stopping a timer which fired returned true")
+	}
+	if now := clock.Now(); !now.Equal(start.Add(time.Second)) {
+		t.Errorf("wrong clock time: %s", now)
+	}
+
+	timer.Reset(time.Second)
+	if !timer.Stop() {
+		t.Error("stopping a pending timer returned false")
+	}
+	if n := clock.Timers(); n != 1 {
+		t.Errorf("wrong number of pending timers: %d", n)
+	}
+}
+
+func TestClockWriterBatchTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+
+	clock := kafkatest.NewClock(time.Now())
+	w := &kafka.Writer{
+		Addr:         kafka.TCP(b.Addr().String()),
+		Topic:        "topic-A",
+		BatchTimeout: time.Hour,
+		RequiredAcks: kafka.RequireAll,
+		Clock:        clock,
+	}
+	defer w.Close()
+
+	errc := make(chan error, 1)
+	go func() { errc <- w.WriteMessages(ctx, kafka.Message{Value: []byte("hello")}) }()
+
+	if err := clock.WaitTimers(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errc:
+		t.Fatalf("the write returned before the batch timeout: %v", err)
+	default:
+	}
+
+	clock.Advance(time.Hour)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if n := len(b.Records("topic-A", 0)); n != 1 {
+		t.Errorf("wrong number of records stored: %d", n)
+	}
+}
+
+func TestClockHeartbeat(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+
+	clock := kafkatest.NewClock(time.Now())
+	faults := &kafkatest.Faults{}
+	g, err := kafka.NewConsumerGroup(kafka.ConsumerGroupConfig{
+		ID:                "group-A",
+		Brokers:           []string{b.Addr().String()},
+		Topics:            []string{"topic-A"},
+		Dialer:            &kafka.Dialer{DialFunc: faults.Dial},
+		HeartbeatInterval: time.Second,
+		SessionTimeout:    time.Minute,
+		Clock:             clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	gen1, err := g.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The heartbeat fails and ends the generation only once the clock moved
+	// past the heartbeat interval.
+	faults.Inject(kafkatest.Fault{
+		ApiKeys:   []protocol.ApiKey{protocol.Heartbeat},
+		ErrorCode: kafka.RebalanceInProgress,
+		Count:     1,
+	})
+	if err := clock.WaitTimers(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second)
+
+	gen2, err := g.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen2.ID <= gen1.ID {
+		t.Errorf("the group did not move to a new generation: %d <= %d", gen2.ID, gen1.ID)
+	}
+}
@@ -0,0 +1,89 @@
+package kafkatest
+
+import (
+	"fmt"
+
+	"github.com/segmentio/kafka-go/protocol/describeconfigs"
+)
+
+// Resource types of DescribeConfigs requests.
+const (
+	resourceTypeTopic  int8 = 2
+	resourceTypeBroker int8 = 4
+)
+
+// configSourceDynamicTopic is the source reported for topic configs.
+const configSourceDynamicTopic int8 = 1
+
+// SetTopicConfig sets a configuration entry of a topic, which is returned by
+// DescribeConfigs requests. The broker does not interpret configs, programs use
+// them to test how they react to the configuration of topics.
+func (b *Broker) SetTopicConfig(topic, name, value string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.topics[topic]; !ok {
+		return fmt.Errorf("kafkatest: unknown topic %q", topic)
+	}
+	b.setTopicConfig(topic, name, value)
+	return nil
+}
+
+func (b *Broker) setTopicConfig(topic, name, value string) {
+	configs := b.configs[topic]
+	if configs == nil {
+		configs = make(map[string]string)
+		b.configs[topic] = configs
+	}
+	configs[name] = value
+}
+
+func (b *Broker) describeConfigs(req *describeconfigs.Request) *describeconfigs.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	res := &describeconfigs.Response{
+		Resources: make([]describeconfigs.ResponseResource, len(req.Resources)),
+	}
+
+	for i, resource := range req.Resources {
+		r := &res.Resources[i]
+		r.ResourceType = resource.ResourceType
+		r.ResourceName = resource.ResourceName
+		r.ConfigEntries = []describeconfigs.ResponseConfigEntry{}
+
+		switch resource.ResourceType {
+		case resourceTypeTopic:
+			if _, ok := b.topics[resource.ResourceName]; !ok {
+				r.ErrorCode = errUnknownTopicOrPartition
+				continue
+			}
+		case resourceTypeBroker:
+			// The broker has no configs, an empty list is returned.
+			continue
+		default:
+			r.ErrorCode = errInvalidRequest
+			continue
+		}
+
+		configs := b.configs[resource.ResourceName]
+		names := resource.ConfigNames
+		if names == nil {
+			for name := range configs {
+				names = append(names, name)
+			}
+		}
+
+		for _, name := range names {
+			if value, ok := configs[name]; ok {
+				r.ConfigEntries = append(r.ConfigEntries, describeconfigs.ResponseConfigEntry{
+					ConfigName:   name,
+					ConfigValue:  value,
+					ConfigSource: configSourceDynamicTopic,
+				})
+			}
+		}
+	}
+
+	return res
+}
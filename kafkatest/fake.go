@@ -0,0 +1,270 @@
+package kafkatest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Reader is an in-memory implementation of kafka.MessageReader, returning the
+// messages given to NewReader or Push in order.
+//
+// The exported fields configure the behavior of the reader, they must not be
+// modified after the first method call.
+type Reader struct {
+	// Latency is the time that FetchMessage, ReadMessage, and CommitMessages
+	// wait before returning, to simulate the round trips to kafka.
+	Latency time.Duration
+
+	// When set, FetchError is called by FetchMessage and ReadMessage before
+	// returning a message. If it returns a non-nil error, the call fails with
+	// it and the message remains available for the next call.
+	FetchError func() error
+
+	// When set, CommitError is called by CommitMessages and ReadMessage with
+	// the messages to commit. If it returns a non-nil error, the call fails
+	// with it and the messages are not committed.
+	CommitError func(msgs []kafka.Message) error
+
+	mutex     sync.Mutex
+	messages  []kafka.Message
+	committed []kafka.Message
+	closed    bool
+	// Closed when messages are pushed or the reader is closed, to wake up the
+	// calls to FetchMessage waiting for messages.
+	ready chan struct{}
+}
+
+// NewReader returns a new fake reader, returning msgs.
+func NewReader(msgs ...kafka.Message) *Reader {
+	return &Reader{messages: append([]kafka.Message(nil), msgs...)}
+}
+
+// Push appends messages to the reader, waking up calls to FetchMessage or
+// ReadMessage waiting for them.
+func (r *Reader) Push(msgs ...kafka.Message) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.messages = append(r.messages, msgs...)
+	r.wakeup()
+}
+
+// Committed returns the messages committed by CommitMessages and
+// ReadMessage, in the order that they were committed.
+func (r *Reader) Committed() []kafka.Message {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]kafka.Message(nil), r.committed...)
+}
+
+// ReadMessage satisfies the kafka.MessageReader interface.
+func (r *Reader) ReadMessage(ctx context.Context) (kafka.Message, error) {
+	m, err := r.FetchMessage(ctx)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	if err := r.CommitMessages(ctx, m); err != nil {
+		return kafka.Message{}, err
+	}
+	return m, nil
+}
+
+// FetchMessage satisfies the kafka.MessageReader interface. It blocks until a
+// message is available, the context is cancelled, or the reader is closed.
+func (r *Reader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	if err := wait(ctx, r.Latency); err != nil {
+		return kafka.Message{}, err
+	}
+
+	for {
+		r.mutex.Lock()
+		if r.ready == nil {
+			r.ready = make(chan struct{})
+		}
+		closed, ready := r.closed, r.ready
+
+		if !closed && len(r.messages) != 0 {
+			if r.FetchError != nil {
+				if err := r.FetchError(); err != nil {
+					r.mutex.Unlock()
+					return kafka.Message{}, err
+				}
+			}
+			m := r.messages[0]
+			r.messages = r.messages[1:]
+			r.mutex.Unlock()
+			return m, nil
+		}
+		r.mutex.Unlock()
+
+		if closed {
+			return kafka.Message{}, io.EOF
+		}
+
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		}
+	}
+}
+
+// CommitMessages satisfies the kafka.MessageReader interface.
+func (r *Reader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if err := wait(ctx, r.Latency); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return io.ErrClosedPipe
+	}
+	if r.CommitError != nil {
+		if err := r.CommitError(msgs); err != nil {
+			return err
+		}
+	}
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+// Close satisfies the kafka.MessageReader interface.
+func (r *Reader) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.closed {
+		r.closed = true
+		r.wakeup()
+	}
+	return nil
+}
+
+func (r *Reader) wakeup() {
+	if r.ready != nil {
+		close(r.ready)
+		r.ready = nil
+	}
+}
+
+// Writer is an in-memory implementation of kafka.MessageWriter, recording the
+// messages written to it.
+//
+// The exported fields configure the behavior of the writer, they must not be
+// modified after the first method call.
+type Writer struct {
+	// The topic set on messages which do not have one, like the Topic field
+	// of kafka.Writer.
+	Topic string
+
+	// Latency is the time that WriteMessages waits before returning, to
+	// simulate the round trips to kafka.
+	Latency time.Duration
+
+	// When set, WriteError is called by WriteMessages with the messages to
+	// write. If it returns a non-nil error, the call fails with it and the
+	// messages are not recorded.
+	WriteError func(msgs []kafka.Message) error
+
+	mutex    sync.Mutex
+	messages []kafka.Message
+	offsets  map[string]int64
+	closed   bool
+}
+
+// Messages returns the messages written to the writer, in the order that they
+// were written.
+//
+// The messages carry the topic that they were written to, an offset which
+// increases for each message written to a topic, and the time at which they
+// were written if they did not have one.
+func (w *Writer) Messages() []kafka.Message {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]kafka.Message(nil), w.messages...)
+}
+
+// WriteMessages satisfies the kafka.MessageWriter interface.
+func (w *Writer) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if err := wait(ctx, w.Latency); err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return io.ErrClosedPipe
+	}
+
+	msgs = append([]kafka.Message(nil), msgs...)
+	now := time.Now()
+
+	for i := range msgs {
+		m := &msgs[i]
+		switch {
+		case m.Topic != "" && w.Topic != "":
+			return errors.New("kafkatest.(*Writer): Topic must not be specified for both Writer and Message")
+		case m.Topic == "" && w.Topic == "":
+			return errors.New("kafkatest.(*Writer): Topic must be specified for Writer or Message")
+		case m.Topic == "":
+			m.Topic = w.Topic
+		}
+		if m.Time.IsZero() {
+			m.Time = now
+		}
+	}
+
+	if w.WriteError != nil {
+		if err := w.WriteError(msgs); err != nil {
+			return err
+		}
+	}
+
+	if w.offsets == nil {
+		w.offsets = make(map[string]int64)
+	}
+	for i := range msgs {
+		m := &msgs[i]
+		m.Offset = w.offsets[m.Topic]
+		w.offsets[m.Topic]++
+	}
+
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+// Close satisfies the kafka.MessageWriter interface.
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.closed = true
+	return nil
+}
+
+func wait(ctx context.Context, latency time.Duration) error {
+	if latency <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var (
+	_ kafka.MessageReader = (*Reader)(nil)
+	_ kafka.MessageWriter = (*Writer)(nil)
+)
@@ -0,0 +1,128 @@
+package kafkatest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+// copyMessages is an example of application code depending on the interfaces
+// rather than the kafka-go types.
+func copyMessages(ctx context.Context, r kafka.MessageReader, w kafka.MessageWriter, n int) error {
+	for i := 0; i < n; i++ {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			return err
+		}
+		if err := w.WriteMessages(ctx, kafka.Message{Key: m.Key, Value: m.Value}); err != nil {
+			return err
+		}
+		if err := r.CommitMessages(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestFakeReaderWriter(t *testing.T) {
+	ctx := context.Background()
+
+	r := kafkatest.NewReader(
+		kafka.Message{Topic: "topic-A", Offset: 0, Value: []byte("hello")},
+		kafka.Message{Topic: "topic-A", Offset: 1, Value: []byte("world")},
+	)
+	w := &kafkatest.Writer{Topic: "topic-B"}
+
+	if err := copyMessages(ctx, r, w, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := w.Messages()
+	if len(msgs) != 2 {
+		t.Fatalf("wrong number of messages written: %d", len(msgs))
+	}
+	for i, value := range []string{"hello", "world"} {
+		m := msgs[i]
+		if m.Topic != "topic-B" || m.Offset != int64(i) || string(m.Value) != value || m.Time.IsZero() {
+			t.Errorf("wrong message written: %+v", m)
+		}
+	}
+	if n := len(r.Committed()); n != 2 {
+		t.Errorf("wrong number of messages committed: %d", n)
+	}
+
+	r.Close()
+	if _, err := r.FetchMessage(ctx); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF after closing the reader but got %v", err)
+	}
+}
+
+func TestFakeReaderPush(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	r := &kafkatest.Reader{}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		r.Push(kafka.Message{Value: []byte("hello")})
+	}()
+
+	m, err := r.ReadMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m.Value) != "hello" {
+		t.Errorf("wrong message: %q", m.Value)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.FetchMessage(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded but got %v", err)
+	}
+}
+
+func TestFakeErrors(t *testing.T) {
+	ctx := context.Background()
+	errFetch := errors.New("fetch failed")
+	errWrite := errors.New("write failed")
+
+	failures := 1
+	r := kafkatest.NewReader(kafka.Message{Value: []byte("hello")})
+	r.FetchError = func() error {
+		if failures > 0 {
+			failures--
+			return errFetch
+		}
+		return nil
+	}
+
+	if _, err := r.FetchMessage(ctx); !errors.Is(err, errFetch) {
+		t.Errorf("expected the injected error but got %v", err)
+	}
+	if m, err := r.FetchMessage(ctx); err != nil || string(m.Value) != "hello" {
+		t.Errorf("message was not available after the injected error: %q %v", m.Value, err)
+	}
+
+	w := &kafkatest.Writer{
+		Topic:      "topic-A",
+		Latency:    10 * time.Millisecond,
+		WriteError: func([]kafka.Message) error { return errWrite },
+	}
+
+	start := time.Now()
+	if err := w.WriteMessages(ctx, kafka.Message{}); !errors.Is(err, errWrite) {
+		t.Errorf("expected the injected error but got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < w.Latency {
+		t.Errorf("the write returned before the latency elapsed: %s", elapsed)
+	}
+	if n := len(w.Messages()); n != 0 {
+		t.Errorf("failed writes recorded %d messages", n)
+	}
+}
@@ -0,0 +1,495 @@
+package kafkatest
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+// Fault describes a fault injected by Faults in the responses of kafka
+// brokers.
+//
+// The zero-value is a fault matching all responses, which does not alter
+// them. Multiple effects may be combined in a single fault, for example
+// delaying responses and setting an error code.
+type Fault struct {
+	// The APIs of the responses affected by the fault, all APIs when empty.
+	ApiKeys []protocol.ApiKey
+
+	// The address of the broker that the fault applies to, all brokers when
+	// empty. The address must be the one that connections were dialed with.
+	Addr string
+
+	// The number of responses affected by the fault, unlimited when zero.
+	Count int
+
+	// Delay is the time that responses are held back before being returned
+	// to the client.
+	Delay time.Duration
+
+	// Drop closes the connection in place of returning the response. The
+	// request was received by the broker, the client sees a network error.
+	Drop bool
+
+	// When non-zero, ErrorCode is set on all error codes of the responses,
+	// including the error codes of topics and partitions.
+	ErrorCode kafka.Error
+
+	// Corrupt alters the checksum of the first record batch in each record
+	// set of the responses, which clients report as corrupted records.
+	Corrupt bool
+}
+
+func (f *Fault) match(addr string, apiKey protocol.ApiKey) bool {
+	if f.Addr != "" && f.Addr != addr {
+		return false
+	}
+	if len(f.ApiKeys) == 0 {
+		return true
+	}
+	for _, k := range f.ApiKeys {
+		if k == apiKey {
+			return true
+		}
+	}
+	return false
+}
+
+// Faults injects faults in the connections between kafka clients and
+// brokers, so programs can verify how they handle retries, errors, and
+// rebalances. The Dial method is meant to be set as the dial function of
+// kafka.Transport and kafka.Dialer:
+//
+//	faults := &kafkatest.Faults{}
+//	transport := &kafka.Transport{Dial: faults.Dial}
+//	dialer := &kafka.Dialer{DialFunc: faults.Dial}
+//
+//	faults.Inject(kafkatest.Fault{
+//		ApiKeys:   []protocol.ApiKey{protocol.Produce},
+//		ErrorCode: kafka.NotLeaderForPartition,
+//		Count:     1,
+//	})
+//
+// Faults can be injected and reset at any time while connections are in use.
+//
+// The faults are applied to the kafka messages exchanged on the connections,
+// they cannot be used with connections encrypted by TLS.
+type Faults struct {
+	// DialFunc is the function used to establish connections, a net.Dialer
+	// is used when nil.
+	DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+	mutex  sync.Mutex
+	faults []*Fault
+	conns  map[*faultConn]struct{}
+}
+
+// Inject adds a fault. Faults are matched in the order that they were
+// injected, each response is affected by the first fault matching it.
+func (f *Faults) Inject(fault Fault) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.faults = append(f.faults, &fault)
+}
+
+// Reset removes all faults.
+func (f *Faults) Reset() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.faults = nil
+}
+
+// DropConnections closes all connections established by Dial.
+func (f *Faults) DropConnections() {
+	f.mutex.Lock()
+	conns := make([]*faultConn, 0, len(f.conns))
+	for c := range f.conns {
+		conns = append(conns, c)
+	}
+	f.mutex.Unlock()
+
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+// Dial establishes a connection to address, in which faults are injected.
+func (f *Faults) Dial(ctx context.Context, network, address string) (net.Conn, error) {
+	dial := f.DialFunc
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	conn, err := dial(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &faultConn{
+		Conn:     conn,
+		faults:   f,
+		addr:     address,
+		requests: make(map[int32]requestHeader),
+		done:     make(chan struct{}),
+	}
+
+	f.mutex.Lock()
+	if f.conns == nil {
+		f.conns = make(map[*faultConn]struct{})
+	}
+	f.conns[c] = struct{}{}
+	f.mutex.Unlock()
+	return c, nil
+}
+
+// match returns the fault to apply to a response, or nil if there are none.
+func (f *Faults) match(addr string, apiKey protocol.ApiKey) *Fault {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for i, fault := range f.faults {
+		if !fault.match(addr, apiKey) {
+			continue
+		}
+		match := *fault
+		if fault.Count > 0 {
+			if fault.Count--; fault.Count == 0 {
+				f.faults = append(f.faults[:i:i], f.faults[i+1:]...)
+			}
+		}
+		return &match
+	}
+	return nil
+}
+
+func (f *Faults) remove(c *faultConn) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.conns, c)
+}
+
+type requestHeader struct {
+	apiKey     protocol.ApiKey
+	apiVersion int16
+}
+
+// faultConn is the connection returned by Faults.Dial. It tracks the headers
+// of requests written to the connection, so the responses read from it can
+// be decoded and altered.
+type faultConn struct {
+	net.Conn
+	faults *Faults
+	addr   string
+
+	// Write side: the beginning of the request currently being written, and
+	// the number of bytes left to write before the next request.
+	wmutex   sync.Mutex
+	wbuf     []byte
+	wskip    int
+	requests map[int32]requestHeader
+
+	// Read side: the response being returned to the client, the time at
+	// which it can be returned, and whether the connection is dropped instead.
+	rmutex sync.Mutex
+	rbuf   []byte
+	rdelay time.Time
+	rdrop  bool
+
+	// The read deadline is guarded by its own mutex because rmutex is held
+	// while Read waits for delayed responses.
+	dmutex    sync.Mutex
+	rdeadline time.Time
+
+	once sync.Once
+	done chan struct{}
+}
+
+func (c *faultConn) Write(b []byte) (int, error) {
+	c.wmutex.Lock()
+	c.parseRequests(b)
+	c.wmutex.Unlock()
+	return c.Conn.Write(b)
+}
+
+func (c *faultConn) parseRequests(b []byte) {
+	for len(b) != 0 {
+		if c.wskip > 0 {
+			n := len(b)
+			if n > c.wskip {
+				n = c.wskip
+			}
+			c.wskip -= n
+			b = b[n:]
+			continue
+		}
+
+		// size:4 + api_key:2 + api_version:2 + correlation_id:4
+		const headerSize = 12
+		n := headerSize - len(c.wbuf)
+		if n > len(b) {
+			n = len(b)
+		}
+		c.wbuf = append(c.wbuf, b[:n]...)
+		b = b[n:]
+
+		if len(c.wbuf) == headerSize {
+			size := int(int32(binary.BigEndian.Uint32(c.wbuf[0:])))
+			correlationID := int32(binary.BigEndian.Uint32(c.wbuf[8:]))
+			c.requests[correlationID] = requestHeader{
+				apiKey:     protocol.ApiKey(binary.BigEndian.Uint16(c.wbuf[4:])),
+				apiVersion: int16(binary.BigEndian.Uint16(c.wbuf[6:])),
+			}
+			c.wbuf = c.wbuf[:0]
+			c.wskip = size - (headerSize - 4)
+		}
+	}
+}
+
+func (c *faultConn) Read(b []byte) (int, error) {
+	c.rmutex.Lock()
+	defer c.rmutex.Unlock()
+
+	if len(c.rbuf) == 0 {
+		if err := c.readResponse(); err != nil {
+			return 0, err
+		}
+	}
+
+	if !c.rdelay.IsZero() {
+		if err := c.wait(); err != nil {
+			return 0, err
+		}
+	}
+
+	if c.rdrop {
+		c.Close()
+		return 0, fmt.Errorf("kafkatest: connection to %s dropped: %w", c.addr, io.ErrUnexpectedEOF)
+	}
+
+	n := copy(b, c.rbuf)
+	c.rbuf = c.rbuf[n:]
+	return n, nil
+}
+
+// readResponse reads the next response from the connection into rbuf,
+// applying the fault matching it.
+func (c *faultConn) readResponse() error {
+	var size [4]byte
+	if _, err := io.ReadFull(c.Conn, size[:]); err != nil {
+		return err
+	}
+
+	frame := make([]byte, 4+int(binary.BigEndian.Uint32(size[:])))
+	copy(frame, size[:])
+	if _, err := io.ReadFull(c.Conn, frame[4:]); err != nil {
+		return err
+	}
+	c.rbuf = frame
+
+	if len(frame) < 8 {
+		return nil
+	}
+	correlationID := int32(binary.BigEndian.Uint32(frame[4:]))
+
+	c.wmutex.Lock()
+	req, ok := c.requests[correlationID]
+	delete(c.requests, correlationID)
+	c.wmutex.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	fault := c.faults.match(c.addr, req.apiKey)
+	if fault == nil {
+		return nil
+	}
+
+	if fault.Delay > 0 {
+		c.rdelay = time.Now().Add(fault.Delay)
+	}
+	if fault.Drop {
+		c.rdrop = true
+		return nil
+	}
+	if fault.ErrorCode != 0 || fault.Corrupt {
+		b, err := alterResponse(frame, correlationID, req, fault)
+		if err != nil {
+			return fmt.Errorf("kafkatest: injecting fault in %s response: %w", req.apiKey, err)
+		}
+		c.rbuf = b
+	}
+	return nil
+}
+
+// wait blocks until the delayed response can be returned, the read deadline
+// expires, or the connection is closed.
+func (c *faultConn) wait() error {
+	delay := time.Until(c.rdelay)
+	timeout := false
+
+	c.dmutex.Lock()
+	deadline := c.rdeadline
+	c.dmutex.Unlock()
+
+	if !deadline.IsZero() {
+		if d := time.Until(deadline); d < delay {
+			delay, timeout = d, true
+		}
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-c.done:
+			return io.ErrClosedPipe
+		}
+	}
+
+	if timeout {
+		return os.ErrDeadlineExceeded
+	}
+	c.rdelay = time.Time{}
+	return nil
+}
+
+func (c *faultConn) SetDeadline(t time.Time) error {
+	c.setReadDeadline(t)
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *faultConn) SetReadDeadline(t time.Time) error {
+	c.setReadDeadline(t)
+	return c.Conn.SetReadDeadline(t)
+}
+
+func (c *faultConn) setReadDeadline(t time.Time) {
+	c.dmutex.Lock()
+	c.rdeadline = t
+	c.dmutex.Unlock()
+}
+
+func (c *faultConn) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+		c.faults.remove(c)
+	})
+	return c.Conn.Close()
+}
+
+// alterResponse decodes the response in frame, applies the error code and
+// corruption of fault, and returns the re-encoded response.
+func alterResponse(frame []byte, correlationID int32, req requestHeader, fault *Fault) ([]byte, error) {
+	_, res, err := protocol.ReadResponse(bytes.NewReader(frame), req.apiKey, req.apiVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if fault.ErrorCode != 0 {
+		setErrorCodes(reflect.ValueOf(res), int16(fault.ErrorCode))
+	}
+
+	// The records are read to encode the response, they are buffered so they
+	// can be encoded again to locate the batches to corrupt.
+	reset := bufferRecords(res)
+
+	b := new(bytes.Buffer)
+	if err := protocol.WriteResponse(b, req.apiVersion, correlationID, res); err != nil {
+		return nil, err
+	}
+	out := b.Bytes()
+
+	if fault.Corrupt {
+		walkRecordSets(reflect.ValueOf(res), func(rs *protocol.RecordSet) {
+			reset()
+			b := new(bytes.Buffer)
+			if _, err := rs.WriteTo(b); err != nil || b.Len() <= 4 {
+				return
+			}
+			// Search for the batches without the size prefix, which is
+			// encoded differently in flexible versions of the messages.
+			if i := bytes.Index(out, b.Bytes()[4:]); i >= 0 {
+				out[i+crcOffset(rs.Version)] ^= 0xFF
+			}
+		})
+	}
+
+	return out, nil
+}
+
+// crcOffset returns the position of the checksum in the first batch of a
+// record set of the given version.
+func crcOffset(version int8) int {
+	if version == 2 {
+		// base_offset:8 + length:4 + partition_leader_epoch:4 + magic:1
+		return 17
+	}
+	// offset:8 + message_size:4
+	return 12
+}
+
+func setErrorCodes(v reflect.Value, errorCode int16) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			setErrorCodes(v.Elem(), errorCode)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			setErrorCodes(v.Index(i), errorCode)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			if !f.CanSet() {
+				continue
+			}
+			if t.Field(i).Name == "ErrorCode" && f.Kind() == reflect.Int16 {
+				f.SetInt(int64(errorCode))
+				continue
+			}
+			setErrorCodes(f, errorCode)
+		}
+	}
+}
+
+var recordSetType = reflect.TypeOf(protocol.RecordSet{})
+
+func walkRecordSets(v reflect.Value, do func(*protocol.RecordSet)) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			walkRecordSets(v.Elem(), do)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkRecordSets(v.Index(i), do)
+		}
+	case reflect.Struct:
+		if v.Type() == recordSetType {
+			if rs := v.Addr().Interface().(*protocol.RecordSet); rs.Records != nil {
+				do(rs)
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.CanSet() {
+				walkRecordSets(f, do)
+			}
+		}
+	}
+}
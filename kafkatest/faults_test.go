@@ -0,0 +1,203 @@
+package kafkatest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func newFaultyClient(t *testing.T) (*kafka.Client, *kafkatest.Faults) {
+	t.Helper()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+	if _, err := b.Append("topic-A", 0, kafkatest.Record{Value: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	faults := &kafkatest.Faults{}
+	transport := &kafka.Transport{Dial: faults.Dial}
+	t.Cleanup(transport.CloseIdleConnections)
+
+	client := &kafka.Client{
+		Addr:      b.Addr(),
+		Transport: transport,
+	}
+	return client, faults
+}
+
+func fetch(ctx context.Context, client *kafka.Client) (*kafka.FetchResponse, error) {
+	return client.Fetch(ctx, &kafka.FetchRequest{
+		Topic:     "topic-A",
+		Partition: 0,
+		MaxBytes:  1 << 20,
+	})
+}
+
+func TestFaultsErrorCode(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, faults := newFaultyClient(t)
+	faults.Inject(kafkatest.Fault{
+		ApiKeys:   []protocol.ApiKey{protocol.Produce},
+		ErrorCode: kafka.NotLeaderForPartition,
+		Count:     1,
+	})
+
+	produce := func() (*kafka.ProduceResponse, error) {
+		return client.Produce(ctx, &kafka.ProduceRequest{
+			Topic:        "topic-A",
+			Partition:    0,
+			RequiredAcks: kafka.RequireAll,
+			Records:      kafka.NewRecordReader(kafka.Record{Value: kafka.NewBytes([]byte("world"))}),
+		})
+	}
+
+	res, err := produce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(res.Error, kafka.NotLeaderForPartition) {
+		t.Errorf("expected the injected error but got %v", res.Error)
+	}
+
+	res, err = produce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Error != nil {
+		t.Errorf("the fault was injected more than once: %v", res.Error)
+	}
+}
+
+func TestFaultsDrop(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, faults := newFaultyClient(t)
+	faults.Inject(kafkatest.Fault{
+		ApiKeys: []protocol.ApiKey{protocol.Fetch},
+		Drop:    true,
+		Count:   1,
+	})
+
+	if _, err := fetch(ctx, client); err == nil {
+		t.Fatal("expected an error from the dropped connection")
+	}
+	if _, err := fetch(ctx, client); err != nil {
+		t.Fatalf("the client did not reconnect after the connection was dropped: %v", err)
+	}
+
+	faults.DropConnections()
+
+	if _, err := fetch(ctx, client); err == nil {
+		t.Fatal("expected an error after dropping the connections")
+	}
+	if _, err := fetch(ctx, client); err != nil {
+		t.Fatalf("the client did not reconnect after the connections were dropped: %v", err)
+	}
+}
+
+func TestFaultsDelay(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	const delay = 100 * time.Millisecond
+	client, faults := newFaultyClient(t)
+	faults.Inject(kafkatest.Fault{
+		ApiKeys: []protocol.ApiKey{protocol.Fetch},
+		Delay:   delay,
+	})
+
+	start := time.Now()
+	if _, err := fetch(ctx, client); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("the response was not delayed: %s", elapsed)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, delay/2)
+	defer cancel()
+	if _, err := fetch(timeout, client); err == nil {
+		t.Error("expected the delayed response to exceed the deadline")
+	}
+
+	faults.Reset()
+	if _, err := fetch(ctx, client); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFaultsCorrupt(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, faults := newFaultyClient(t)
+	faults.Inject(kafkatest.Fault{
+		ApiKeys: []protocol.ApiKey{protocol.Fetch},
+		Corrupt: true,
+		Count:   1,
+	})
+
+	res, err := fetch(ctx, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var corrupt *protocol.CorruptRecordError
+	if _, err := res.Records.ReadRecord(); !errors.As(err, &corrupt) {
+		t.Errorf("expected a corrupt record error but got %v", err)
+	}
+
+	res, err = fetch(ctx, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := res.Records.ReadRecord(); err != nil {
+		t.Errorf("the fault was injected more than once: %v", err)
+	}
+}
+
+func TestFaultsRebalance(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+
+	faults := &kafkatest.Faults{}
+	g, err := kafka.NewConsumerGroup(kafka.ConsumerGroupConfig{
+		ID:                "group-A",
+		Brokers:           []string{b.Addr().String()},
+		Topics:            []string{"topic-A"},
+		Dialer:            &kafka.Dialer{DialFunc: faults.Dial},
+		HeartbeatInterval: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	gen1, err := g.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	faults.Inject(kafkatest.Fault{
+		ApiKeys:   []protocol.ApiKey{protocol.Heartbeat},
+		ErrorCode: kafka.RebalanceInProgress,
+		Count:     1,
+	})
+
+	gen2, err := g.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gen2.ID <= gen1.ID {
+		t.Errorf("the group did not move to a new generation: %d <= %d", gen2.ID, gen1.ID)
+	}
+}
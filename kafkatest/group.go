@@ -0,0 +1,496 @@
+package kafkatest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/heartbeat"
+	"github.com/segmentio/kafka-go/protocol/joingroup"
+	"github.com/segmentio/kafka-go/protocol/leavegroup"
+	"github.com/segmentio/kafka-go/protocol/offsetcommit"
+	"github.com/segmentio/kafka-go/protocol/offsetfetch"
+	"github.com/segmentio/kafka-go/protocol/syncgroup"
+)
+
+type groupState int
+
+const (
+	// The group has no members.
+	groupEmpty groupState = iota
+	// The group is rebalancing, and waits for its members to join.
+	groupPreparing
+	// The members joined the new generation, and wait for the leader to send
+	// the assignments.
+	groupCompleting
+	// The members received their assignments.
+	groupStable
+)
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+type group struct {
+	id           string
+	state        groupState
+	generationID int32
+	protocolType string
+	protocolName string
+	leaderID     string
+	// Members in the order that they joined the group, the first member is
+	// elected leader when the leader leaves.
+	members []*member
+	offsets map[topicPartition]int64
+	// The metadata committed with the offsets.
+	metadata map[topicPartition]string
+	// Fires when members which did not rejoin the group during a rebalance
+	// must be removed.
+	rebalanceTimer *time.Timer
+}
+
+type member struct {
+	id               string
+	sessionTimeout   time.Duration
+	rebalanceTimeout time.Duration
+	lastHeartbeat    time.Time
+	protocols        []joingroup.RequestProtocol
+	assignment       []byte
+	// Set while the member waits for the response to a JoinGroup or SyncGroup
+	// request.
+	join chan *joingroup.Response
+	sync chan *syncgroup.Response
+}
+
+func (m *member) metadata(protocolName string) []byte {
+	for _, p := range m.protocols {
+		if p.Name == protocolName {
+			return p.Metadata
+		}
+	}
+	return nil
+}
+
+// CommittedOffset returns the offset committed by a consumer group for a
+// partition of a topic, and whether an offset was committed.
+func (b *Broker) CommittedOffset(group, topic string, partition int) (int64, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	g := b.groups[group]
+	if g == nil {
+		return -1, false
+	}
+	offset, ok := g.offsets[topicPartition{topic: topic, partition: int32(partition)}]
+	if !ok {
+		return -1, false
+	}
+	return offset, true
+}
+
+// group must be called with the mutex held.
+func (b *Broker) group(id string) *group {
+	g := b.groups[id]
+	if g == nil {
+		g = &group{id: id, offsets: make(map[topicPartition]int64), metadata: make(map[topicPartition]string)}
+		b.groups[id] = g
+	}
+	return g
+}
+
+func (g *group) member(id string) *member {
+	for _, m := range g.members {
+		if m.id == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// rebalance starts a new rebalance of the group, members must rejoin the group
+// before the largest of their rebalance timeouts expires.
+func (b *Broker) rebalance(g *group) {
+	if g.state == groupPreparing {
+		return
+	}
+	g.state = groupPreparing
+
+	timeout := time.Duration(0)
+	for _, m := range g.members {
+		if m.sync != nil {
+			m.sync <- &syncgroup.Response{ErrorCode: errRebalanceInProgress}
+			m.sync = nil
+		}
+		if m.rebalanceTimeout > timeout {
+			timeout = m.rebalanceTimeout
+		}
+	}
+
+	g.rebalanceTimer = time.AfterFunc(timeout, func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+
+		if g.state != groupPreparing {
+			return
+		}
+		for _, m := range append([]*member(nil), g.members...) {
+			if m.join == nil {
+				b.removeMember(g, m)
+			}
+		}
+		b.completeJoin(g)
+	})
+}
+
+// completeJoin starts the next generation of the group if all its members
+// joined the group.
+func (b *Broker) completeJoin(g *group) {
+	if g.state != groupPreparing {
+		return
+	}
+	for _, m := range g.members {
+		if m.join == nil {
+			return
+		}
+	}
+
+	g.rebalanceTimer.Stop()
+	g.rebalanceTimer = nil
+	g.generationID++
+
+	if len(g.members) == 0 {
+		g.state = groupEmpty
+		g.leaderID = ""
+		g.protocolType = ""
+		g.protocolName = ""
+		return
+	}
+
+	g.state = groupCompleting
+	if g.member(g.leaderID) == nil {
+		g.leaderID = g.members[0].id
+	}
+	g.protocolName = g.selectProtocol()
+
+	now := time.Now()
+	for _, m := range g.members {
+		res := &joingroup.Response{
+			GenerationID: g.generationID,
+			ProtocolName: g.protocolName,
+			LeaderID:     g.leaderID,
+			MemberID:     m.id,
+		}
+		if m.id == g.leaderID {
+			for _, other := range g.members {
+				res.Members = append(res.Members, joingroup.ResponseMember{
+					MemberID: other.id,
+					Metadata: other.metadata(g.protocolName),
+				})
+			}
+		}
+		m.join <- res
+		m.join = nil
+		m.lastHeartbeat = now
+	}
+}
+
+// selectProtocol returns the first protocol of the leader that all members of
+// the group support.
+func (g *group) selectProtocol() string {
+	leader := g.member(g.leaderID)
+	for _, p := range leader.protocols {
+		supported := true
+		for _, m := range g.members {
+			if !m.supports(p.Name) {
+				supported = false
+				break
+			}
+		}
+		if supported {
+			return p.Name
+		}
+	}
+	return leader.protocols[0].Name
+}
+
+func (m *member) supports(protocolName string) bool {
+	for _, p := range m.protocols {
+		if p.Name == protocolName {
+			return true
+		}
+	}
+	return false
+}
+
+// removeMember removes m from the group, and starts a rebalance if the group
+// still has members.
+func (b *Broker) removeMember(g *group, m *member) {
+	for i, other := range g.members {
+		if other == m {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			break
+		}
+	}
+
+	if m.join != nil {
+		m.join <- &joingroup.Response{ErrorCode: errUnknownMemberID, GenerationID: -1}
+		m.join = nil
+	}
+	if m.sync != nil {
+		m.sync <- &syncgroup.Response{ErrorCode: errUnknownMemberID}
+		m.sync = nil
+	}
+
+	if g.state == groupPreparing {
+		b.completeJoin(g)
+	} else if len(g.members) == 0 {
+		g.state = groupEmpty
+		g.leaderID = ""
+		g.protocolType = ""
+		g.protocolName = ""
+	} else {
+		b.rebalance(g)
+	}
+}
+
+// expire removes the members of g which did not send heartbeats for longer
+// than their session timeout. It must be called with the mutex held.
+func (b *Broker) expire(g *group, now time.Time) {
+	for _, m := range append([]*member(nil), g.members...) {
+		// Members waiting for a rebalance to complete do not send heartbeats.
+		if m.join == nil && m.sync == nil && now.Sub(m.lastHeartbeat) > m.sessionTimeout {
+			b.removeMember(g, m)
+		}
+	}
+}
+
+func (b *Broker) joinGroup(clientID string, req *joingroup.Request) *joingroup.Response {
+	b.mutex.Lock()
+
+	g := b.group(req.GroupID)
+	fail := func(errorCode int16) *joingroup.Response {
+		b.mutex.Unlock()
+		return &joingroup.Response{ErrorCode: errorCode, GenerationID: -1, MemberID: req.MemberID}
+	}
+
+	if len(req.Protocols) == 0 || (g.protocolType != "" && g.protocolType != req.ProtocolType) {
+		return fail(errInconsistentGroupProtocol)
+	}
+
+	m := g.member(req.MemberID)
+	switch {
+	case req.MemberID == "":
+		b.members++
+		m = &member{id: fmt.Sprintf("%s-%d", clientID, b.members)}
+		g.members = append(g.members, m)
+	case m == nil:
+		return fail(errUnknownMemberID)
+	}
+
+	m.sessionTimeout = time.Duration(req.SessionTimeoutMs) * time.Millisecond
+	m.rebalanceTimeout = time.Duration(req.RebalanceTimeoutMs) * time.Millisecond
+	if m.rebalanceTimeout <= 0 {
+		// Version 0 of the request uses the session timeout for rebalances.
+		m.rebalanceTimeout = m.sessionTimeout
+	}
+	m.protocols = req.Protocols
+	m.lastHeartbeat = time.Now()
+	if m.join != nil {
+		m.join <- &joingroup.Response{ErrorCode: errRebalanceInProgress, GenerationID: -1, MemberID: m.id}
+	}
+	join := make(chan *joingroup.Response, 1)
+	m.join = join
+	g.protocolType = req.ProtocolType
+
+	b.rebalance(g)
+	b.completeJoin(g)
+	b.mutex.Unlock()
+
+	select {
+	case res := <-join:
+		return res
+	case <-b.ctx.Done():
+		return &joingroup.Response{ErrorCode: errRebalanceInProgress, GenerationID: -1, MemberID: m.id}
+	}
+}
+
+func (b *Broker) syncGroup(req *syncgroup.Request) *syncgroup.Response {
+	b.mutex.Lock()
+
+	g := b.group(req.GroupID)
+	m := g.member(req.MemberID)
+	fail := func(errorCode int16) *syncgroup.Response {
+		b.mutex.Unlock()
+		return &syncgroup.Response{ErrorCode: errorCode}
+	}
+
+	switch {
+	case m == nil:
+		return fail(errUnknownMemberID)
+	case req.GenerationID != g.generationID:
+		return fail(errIllegalGeneration)
+	case g.state == groupPreparing:
+		return fail(errRebalanceInProgress)
+	case g.state == groupStable:
+		b.mutex.Unlock()
+		return &syncgroup.Response{Assignment: m.assignment}
+	}
+
+	m.lastHeartbeat = time.Now()
+	sync := make(chan *syncgroup.Response, 1)
+	m.sync = sync
+
+	if m.id == g.leaderID {
+		assignments := make(map[string][]byte, len(req.Assignments))
+		for _, a := range req.Assignments {
+			assignments[a.MemberID] = a.Assignment
+		}
+
+		g.state = groupStable
+		for _, other := range g.members {
+			other.assignment = assignments[other.id]
+			if other.sync != nil {
+				other.sync <- &syncgroup.Response{Assignment: other.assignment}
+				other.sync = nil
+			}
+		}
+	}
+	b.mutex.Unlock()
+
+	select {
+	case res := <-sync:
+		return res
+	case <-b.ctx.Done():
+		return &syncgroup.Response{ErrorCode: errRebalanceInProgress}
+	}
+}
+
+func (b *Broker) heartbeat(req *heartbeat.Request) *heartbeat.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	g := b.group(req.GroupID)
+	m := g.member(req.MemberID)
+	switch {
+	case m == nil:
+		return &heartbeat.Response{ErrorCode: errUnknownMemberID}
+	case req.GenerationID != g.generationID:
+		return &heartbeat.Response{ErrorCode: errIllegalGeneration}
+	}
+
+	m.lastHeartbeat = time.Now()
+	if g.state == groupPreparing {
+		return &heartbeat.Response{ErrorCode: errRebalanceInProgress}
+	}
+	return &heartbeat.Response{}
+}
+
+func (b *Broker) leaveGroup(req *leavegroup.Request) *leavegroup.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	g := b.group(req.GroupID)
+	m := g.member(req.MemberID)
+	if m == nil {
+		return &leavegroup.Response{ErrorCode: errUnknownMemberID}
+	}
+	b.removeMember(g, m)
+	return &leavegroup.Response{}
+}
+
+func (b *Broker) offsetCommit(req *offsetcommit.Request) *offsetcommit.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	g := b.group(req.GroupID)
+	errorCode := errNone
+
+	// Commits with no member ID are made by programs which are not members of
+	// the group, they are not validated.
+	if req.MemberID != "" {
+		switch {
+		case g.member(req.MemberID) == nil:
+			errorCode = errUnknownMemberID
+		case req.GenerationID != g.generationID:
+			errorCode = errIllegalGeneration
+		case g.state == groupPreparing:
+			errorCode = errRebalanceInProgress
+		}
+	}
+
+	res := &offsetcommit.Response{
+		Topics: make([]offsetcommit.ResponseTopic, len(req.Topics)),
+	}
+
+	for i, t := range req.Topics {
+		res.Topics[i] = offsetcommit.ResponseTopic{
+			Name:       t.Name,
+			Partitions: make([]offsetcommit.ResponsePartition, len(t.Partitions)),
+		}
+
+		for j, p := range t.Partitions {
+			r := &res.Topics[i].Partitions[j]
+			r.PartitionIndex = p.PartitionIndex
+			r.ErrorCode = errorCode
+
+			if errorCode == errNone {
+				if b.partition(t.Name, p.PartitionIndex) == nil {
+					r.ErrorCode = errUnknownTopicOrPartition
+				} else {
+					key := topicPartition{topic: t.Name, partition: p.PartitionIndex}
+					g.offsets[key] = p.CommittedOffset
+					g.metadata[key] = p.CommittedMetadata
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+func (b *Broker) offsetFetch(req *offsetfetch.Request) *offsetfetch.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	g := b.group(req.GroupID)
+	topics := req.Topics
+
+	// A null array of topics requests the offsets of all partitions that the
+	// group committed offsets for.
+	if topics == nil {
+		partitions := make(map[string][]int32)
+		for tp := range g.offsets {
+			partitions[tp.topic] = append(partitions[tp.topic], tp.partition)
+		}
+		for topic, indexes := range partitions {
+			topics = append(topics, offsetfetch.RequestTopic{Name: topic, PartitionIndexes: indexes})
+		}
+	}
+
+	res := &offsetfetch.Response{
+		Topics: make([]offsetfetch.ResponseTopic, len(topics)),
+	}
+
+	for i, t := range topics {
+		res.Topics[i] = offsetfetch.ResponseTopic{
+			Name:       t.Name,
+			Partitions: make([]offsetfetch.ResponsePartition, len(t.PartitionIndexes)),
+		}
+
+		for j, partition := range t.PartitionIndexes {
+			key := topicPartition{topic: t.Name, partition: partition}
+			offset, ok := g.offsets[key]
+			if !ok {
+				offset = -1
+			}
+			res.Topics[i].Partitions[j] = offsetfetch.ResponsePartition{
+				PartitionIndex:      partition,
+				CommittedOffset:     offset,
+				ComittedLeaderEpoch: -1,
+				Metadata:            g.metadata[key],
+			}
+		}
+	}
+
+	return res
+}
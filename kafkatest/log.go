@@ -0,0 +1,459 @@
+package kafkatest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/createtopics"
+	"github.com/segmentio/kafka-go/protocol/deletetopics"
+	"github.com/segmentio/kafka-go/protocol/fetch"
+	"github.com/segmentio/kafka-go/protocol/listoffsets"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/produce"
+)
+
+// Record is a record stored in a partition of the broker.
+type Record struct {
+	Offset  int64
+	Time    time.Time
+	Key     []byte
+	Value   []byte
+	Headers []protocol.Header
+}
+
+type partitionLog struct {
+	records []Record
+}
+
+func (p *partitionLog) highWatermark() int64 {
+	return int64(len(p.records))
+}
+
+// CreateTopic creates a topic with the given number of partitions on the
+// broker.
+func (b *Broker) CreateTopic(topic string, partitions int) error {
+	if partitions <= 0 {
+		return fmt.Errorf("kafkatest: invalid number of partitions for topic %q: %d", topic, partitions)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.topics[topic]; ok {
+		return fmt.Errorf("kafkatest: topic %q already exists", topic)
+	}
+	b.createTopic(topic, partitions)
+	return nil
+}
+
+// Append appends records to a partition of a topic, returning the offset of
+// the first record. The offsets of the records are ignored, and their time is
+// set to the current time when it is zero.
+func (b *Broker) Append(topic string, partition int, records ...Record) (int64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	p := b.partition(topic, int32(partition))
+	if p == nil {
+		return 0, fmt.Errorf("kafkatest: unknown partition %d of topic %q", partition, topic)
+	}
+	return b.append(p, records), nil
+}
+
+// Records returns a copy of the records stored in a partition of a topic, or
+// nil if the partition does not exist.
+func (b *Broker) Records(topic string, partition int) []Record {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	p := b.partition(topic, int32(partition))
+	if p == nil {
+		return nil
+	}
+	return append([]Record(nil), p.records...)
+}
+
+func (b *Broker) createTopic(topic string, partitions int) {
+	logs := make([]*partitionLog, partitions)
+	for i := range logs {
+		logs[i] = new(partitionLog)
+	}
+	b.topics[topic] = logs
+}
+
+func (b *Broker) defaultPartitions() int {
+	if b.DefaultPartitions > 0 {
+		return b.DefaultPartitions
+	}
+	return 1
+}
+
+func (b *Broker) partition(topic string, partition int32) *partitionLog {
+	logs := b.topics[topic]
+	if partition < 0 || int(partition) >= len(logs) {
+		return nil
+	}
+	return logs[partition]
+}
+
+// append must be called with the mutex held.
+func (b *Broker) append(p *partitionLog, records []Record) int64 {
+	baseOffset := p.highWatermark()
+	now := time.Now()
+
+	for i, r := range records {
+		r.Offset = baseOffset + int64(i)
+		if r.Time.IsZero() {
+			r.Time = now
+		}
+		p.records = append(p.records, r)
+	}
+
+	if len(records) != 0 {
+		close(b.appended)
+		b.appended = make(chan struct{})
+	}
+	return baseOffset
+}
+
+func (b *Broker) metadata(apiVersion int16, req *metadata.Request) *metadata.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	res := &metadata.Response{
+		Brokers: []metadata.ResponseBroker{
+			{NodeID: nodeID, Host: b.host, Port: b.port},
+		},
+		ClusterID:    clusterID,
+		ControllerID: nodeID,
+	}
+
+	topics := req.TopicNames
+	// Version 0 used an empty array to request all topics, newer versions use
+	// a null array.
+	if topics == nil || (apiVersion == 0 && len(topics) == 0) {
+		for topic := range b.topics {
+			topics = append(topics, topic)
+		}
+	}
+
+	autoCreate := b.AutoCreateTopics && (apiVersion < 4 || req.AllowAutoTopicCreation)
+
+	for _, topic := range topics {
+		logs, ok := b.topics[topic]
+		if !ok && autoCreate {
+			b.createTopic(topic, b.defaultPartitions())
+			logs, ok = b.topics[topic]
+		}
+		if !ok {
+			res.Topics = append(res.Topics, metadata.ResponseTopic{
+				ErrorCode: errUnknownTopicOrPartition,
+				Name:      topic,
+			})
+			continue
+		}
+
+		partitions := make([]metadata.ResponsePartition, len(logs))
+		for i := range partitions {
+			partitions[i] = metadata.ResponsePartition{
+				PartitionIndex: int32(i),
+				LeaderID:       nodeID,
+				ReplicaNodes:   []int32{nodeID},
+				IsrNodes:       []int32{nodeID},
+			}
+		}
+		res.Topics = append(res.Topics, metadata.ResponseTopic{
+			Name:       topic,
+			Partitions: partitions,
+		})
+	}
+
+	return res
+}
+
+func (b *Broker) createTopics(req *createtopics.Request) *createtopics.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	res := &createtopics.Response{
+		Topics: make([]createtopics.ResponseTopic, len(req.Topics)),
+	}
+
+	for i, t := range req.Topics {
+		partitions := int(t.NumPartitions)
+		if len(t.Assignments) != 0 {
+			partitions = len(t.Assignments)
+		}
+		if partitions < 0 {
+			partitions = b.defaultPartitions()
+		}
+
+		r := &res.Topics[i]
+		r.Name = t.Name
+		r.NumPartitions = int32(partitions)
+		r.ReplicationFactor = 1
+
+		switch _, exists := b.topics[t.Name]; {
+		case exists:
+			r.ErrorCode = errTopicAlreadyExists
+		case partitions == 0:
+			r.ErrorCode = errInvalidPartitions
+		case !req.ValidateOnly:
+			b.createTopic(t.Name, partitions)
+			for _, c := range t.Configs {
+				b.setTopicConfig(t.Name, c.Name, c.Value)
+			}
+		}
+	}
+
+	return res
+}
+
+func (b *Broker) deleteTopics(req *deletetopics.Request) *deletetopics.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	res := &deletetopics.Response{
+		Responses: make([]deletetopics.ResponseTopic, len(req.TopicNames)),
+	}
+
+	for i, topic := range req.TopicNames {
+		res.Responses[i].Name = topic
+		if _, ok := b.topics[topic]; ok {
+			delete(b.topics, topic)
+			delete(b.configs, topic)
+		} else {
+			res.Responses[i].ErrorCode = errUnknownTopicOrPartition
+		}
+	}
+
+	return res
+}
+
+func (b *Broker) produce(req *produce.Request) protocol.Message {
+	res := &produce.Response{
+		Topics: make([]produce.ResponseTopic, len(req.Topics)),
+	}
+
+	for i, t := range req.Topics {
+		res.Topics[i] = produce.ResponseTopic{
+			Topic:      t.Topic,
+			Partitions: make([]produce.ResponsePartition, len(t.Partitions)),
+		}
+
+		for j, p := range t.Partitions {
+			r := &res.Topics[i].Partitions[j]
+			r.Partition = p.Partition
+			r.LogAppendTime = -1
+
+			records, err := readRecords(p.RecordSet.Records)
+			if err != nil {
+				r.ErrorCode = errCorruptMessage
+				r.BaseOffset = -1
+				continue
+			}
+
+			b.mutex.Lock()
+			if log := b.partition(t.Topic, p.Partition); log == nil {
+				r.ErrorCode = errUnknownTopicOrPartition
+				r.BaseOffset = -1
+			} else if req.TransactionalID != "" && p.RecordSet.Attributes.Transactional() {
+				// The offsets of the records are not known until the
+				// transaction is committed.
+				r.ErrorCode = b.produceTxn(req.TransactionalID, t.Topic, p.Partition, records)
+				r.BaseOffset = -1
+			} else {
+				r.BaseOffset = b.append(log, records)
+			}
+			b.mutex.Unlock()
+		}
+	}
+
+	if !req.HasResponse() {
+		return nil
+	}
+	return res
+}
+
+func readRecords(rr protocol.RecordReader) ([]Record, error) {
+	if rr == nil {
+		return nil, nil
+	}
+
+	var records []Record
+	for {
+		r, err := rr.ReadRecord()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return records, nil
+			}
+			return nil, err
+		}
+
+		// The headers may be reused by the reader, they must be copied.
+		record := Record{
+			Offset:  r.Offset,
+			Time:    r.Time,
+			Headers: append([]protocol.Header(nil), r.Headers...),
+		}
+		if r.Key != nil {
+			record.Key, err = protocol.ReadAll(r.Key)
+			r.Key.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if r.Value != nil {
+			record.Value, err = protocol.ReadAll(r.Value)
+			r.Value.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+		records = append(records, record)
+	}
+}
+
+func (b *Broker) fetch(req *fetch.Request) *fetch.Response {
+	var timeout <-chan time.Time
+	if req.MaxWaitTime > 0 {
+		timer := time.NewTimer(time.Duration(req.MaxWaitTime) * time.Millisecond)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		b.mutex.Lock()
+		res, records := b.fetchRecords(req)
+		appended := b.appended
+		b.mutex.Unlock()
+
+		if records != 0 || timeout == nil {
+			return res
+		}
+
+		select {
+		case <-appended:
+		case <-timeout:
+			timeout = nil
+		case <-b.ctx.Done():
+			return res
+		}
+	}
+}
+
+// fetchRecords must be called with the mutex held. It returns the response to
+// the fetch request and the number of records that it contains.
+func (b *Broker) fetchRecords(req *fetch.Request) (*fetch.Response, int) {
+	res := &fetch.Response{
+		Topics: make([]fetch.ResponseTopic, len(req.Topics)),
+	}
+	numRecords := 0
+	maxBytes := int(req.MaxBytes)
+	if maxBytes <= 0 {
+		maxBytes = int(^uint(0) >> 1)
+	}
+
+	for i, t := range req.Topics {
+		res.Topics[i] = fetch.ResponseTopic{
+			Topic:      t.Topic,
+			Partitions: make([]fetch.ResponsePartition, len(t.Partitions)),
+		}
+
+		for j, p := range t.Partitions {
+			r := &res.Topics[i].Partitions[j]
+			r.Partition = p.Partition
+			r.PreferredReadReplica = -1
+
+			log := b.partition(t.Topic, p.Partition)
+			if log == nil {
+				r.ErrorCode = errUnknownTopicOrPartition
+				r.HighWatermark = -1
+				r.LastStableOffset = -1
+				r.LogStartOffset = -1
+				continue
+			}
+
+			highWatermark := log.highWatermark()
+			r.HighWatermark = highWatermark
+			r.LastStableOffset = highWatermark
+
+			if p.FetchOffset < 0 || p.FetchOffset > highWatermark {
+				r.ErrorCode = errOffsetOutOfRange
+				continue
+			}
+
+			// At least one record is returned even if it is larger than the
+			// limits, otherwise consumers could not make progress.
+			records := log.records[p.FetchOffset:]
+			size := 0
+			for n, record := range records {
+				recordSize := len(record.Key) + len(record.Value)
+				if n != 0 && (size+recordSize > int(p.PartitionMaxBytes) || size+recordSize > maxBytes) {
+					records = records[:n]
+					break
+				}
+				size += recordSize
+			}
+
+			if len(records) != 0 {
+				r.RecordSet = protocol.RecordSet{
+					Version: 2,
+					Records: newRecordReader(records),
+				}
+				maxBytes -= size
+				numRecords += len(records)
+			}
+		}
+	}
+
+	return res, numRecords
+}
+
+func (b *Broker) listOffsets(req *listoffsets.Request) *listoffsets.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	res := &listoffsets.Response{
+		Topics: make([]listoffsets.ResponseTopic, len(req.Topics)),
+	}
+
+	for i, t := range req.Topics {
+		res.Topics[i] = listoffsets.ResponseTopic{
+			Topic:      t.Topic,
+			Partitions: make([]listoffsets.ResponsePartition, len(t.Partitions)),
+		}
+
+		for j, p := range t.Partitions {
+			r := &res.Topics[i].Partitions[j]
+			r.Partition = p.Partition
+			r.Timestamp = -1
+			r.Offset = -1
+
+			log := b.partition(t.Topic, p.Partition)
+			if log == nil {
+				r.ErrorCode = errUnknownTopicOrPartition
+				continue
+			}
+
+			switch p.Timestamp {
+			case -2: // first offset
+				r.Offset = 0
+			case -1: // last offset
+				r.Offset = log.highWatermark()
+			default:
+				for _, record := range log.records {
+					if ts := record.Time.UnixNano() / int64(time.Millisecond); ts >= p.Timestamp {
+						r.Offset, r.Timestamp = record.Offset, ts
+						break
+					}
+				}
+			}
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,303 @@
+package kafkatest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+// exchange is the representation of a round trip in the files written by
+// Recorder and read by Replayer, which contain one JSON object per line.
+//
+// Messages are stored in the binary format of the kafka protocol, at the
+// version returned by recordVersion.
+type exchange struct {
+	Addr       string `json:"addr"`
+	ApiKey     int16  `json:"api_key"`
+	ApiVersion int16  `json:"api_version"`
+	Request    []byte `json:"request,omitempty"`
+	Response   []byte `json:"response,omitempty"`
+	// The error returned by the round trip. ErrorCode is set when the error
+	// was a kafka.Error, so it can be replayed with the same type.
+	Error     string `json:"error,omitempty"`
+	ErrorCode int    `json:"error_code,omitempty"`
+}
+
+// recordVersion returns the version that messages of the given API are
+// recorded with. Messages are recorded at the highest version supported by
+// the protocol package, except fetch messages which identify topics by ID
+// instead of name in versions 13 and above.
+func recordVersion(apiKey protocol.ApiKey) int16 {
+	v := apiKey.MaxVersion()
+	if apiKey == protocol.Fetch && v > 12 {
+		v = 12
+	}
+	return v
+}
+
+// Recorder is an implementation of kafka.RoundTripper which records the
+// requests and responses exchanged through another round tripper, so they
+// can be replayed later by a Replayer.
+//
+// Recorders are useful to capture the behavior of a specific broker
+// implementation once, then run regression tests against it without access to
+// the broker:
+//
+//	f, err := os.Create("testdata/produce.jsonl")
+//	...
+//	client := &kafka.Client{
+//		Addr:      kafka.TCP("localhost:9092"),
+//		Transport: kafkatest.NewRecorder(f, kafka.DefaultTransport),
+//	}
+//
+// Readers do not use round trippers, their exchanges cannot be recorded.
+type Recorder struct {
+	transport kafka.RoundTripper
+
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	err     error
+}
+
+// NewRecorder returns a recorder writing the exchanges made through transport
+// to w.
+func NewRecorder(w io.Writer, transport kafka.RoundTripper) *Recorder {
+	return &Recorder{
+		transport: transport,
+		encoder:   json.NewEncoder(w),
+	}
+}
+
+// Err returns the first error that occurred writing exchanges, if any.
+func (r *Recorder) Err() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.err
+}
+
+// RoundTrip satisfies the kafka.RoundTripper interface.
+func (r *Recorder) RoundTrip(ctx context.Context, addr net.Addr, req kafka.Request) (kafka.Response, error) {
+	apiKey := req.ApiKey()
+	x := exchange{
+		Addr:       addr.String(),
+		ApiKey:     int16(apiKey),
+		ApiVersion: recordVersion(apiKey),
+	}
+
+	// Records are read from the messages to encode them, they are buffered in
+	// memory so they can still be sent to kafka, and returned to the program.
+	resetRequest := bufferRecords(req)
+	x.Request = encodeRequest(x.ApiVersion, req)
+	resetRequest()
+
+	res, err := r.transport.RoundTrip(ctx, addr, req)
+	if err != nil {
+		x.Error = err.Error()
+		var kafkaErr kafka.Error
+		if errors.As(err, &kafkaErr) {
+			x.ErrorCode = int(kafkaErr)
+		}
+	} else if res != nil {
+		resetResponse := bufferRecords(res)
+		var b bytes.Buffer
+		if err := protocol.WriteResponse(&b, x.ApiVersion, 0, res); err != nil {
+			resetResponse()
+			return nil, fmt.Errorf("kafkatest: recording %s response: %w", apiKey, err)
+		}
+		x.Response = b.Bytes()
+		resetResponse()
+	}
+
+	r.mutex.Lock()
+	if r.err == nil {
+		r.err = r.encoder.Encode(&x)
+	}
+	r.mutex.Unlock()
+	return res, err
+}
+
+// Replayer is an implementation of kafka.RoundTripper which responds to
+// requests with the responses recorded by a Recorder, without connecting to
+// kafka.
+//
+// Each request is matched with a recorded exchange for the same API, which
+// was not replayed yet. Exchanges which recorded an identical request are
+// preferred, otherwise exchanges are replayed in the order they were
+// recorded, which makes replays deterministic when programs send requests in
+// the same order.
+type Replayer struct {
+	mutex     sync.Mutex
+	exchanges []*exchange
+}
+
+// NewReplayer returns a replayer for the exchanges read from r.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	replayer := &Replayer{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		x := new(exchange)
+		if err := json.Unmarshal(scanner.Bytes(), x); err != nil {
+			return nil, fmt.Errorf("kafkatest: reading recorded exchange: %w", err)
+		}
+		replayer.exchanges = append(replayer.exchanges, x)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("kafkatest: reading recorded exchanges: %w", err)
+	}
+	return replayer, nil
+}
+
+// Remaining returns the number of recorded exchanges which were not replayed
+// yet.
+func (r *Replayer) Remaining() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.exchanges)
+}
+
+// RoundTrip satisfies the kafka.RoundTripper interface.
+func (r *Replayer) RoundTrip(ctx context.Context, addr net.Addr, req kafka.Request) (kafka.Response, error) {
+	apiKey := req.ApiKey()
+	request := encodeRequest(recordVersion(apiKey), req)
+
+	r.mutex.Lock()
+	x := r.match(apiKey, request)
+	r.mutex.Unlock()
+
+	if x == nil {
+		return nil, fmt.Errorf("kafkatest: no recorded exchange left for %s request to %s", apiKey, addr)
+	}
+	if x.Error != "" {
+		if x.ErrorCode != 0 {
+			return nil, kafka.Error(x.ErrorCode)
+		}
+		return nil, errors.New(x.Error)
+	}
+	if x.Response == nil {
+		return nil, nil
+	}
+
+	_, res, err := protocol.ReadResponse(bytes.NewReader(x.Response), apiKey, x.ApiVersion)
+	if err != nil {
+		return nil, fmt.Errorf("kafkatest: replaying %s response: %w", apiKey, err)
+	}
+	return res, nil
+}
+
+// match removes and returns the exchange to replay for a request, it must be
+// called with the mutex held.
+func (r *Replayer) match(apiKey protocol.ApiKey, request []byte) *exchange {
+	i := -1
+	for j, x := range r.exchanges {
+		if protocol.ApiKey(x.ApiKey) != apiKey {
+			continue
+		}
+		if request != nil && bytes.Equal(x.Request, request) {
+			i = j
+			break
+		}
+		if i < 0 {
+			i = j
+		}
+	}
+	if i < 0 {
+		return nil
+	}
+	x := r.exchanges[i]
+	r.exchanges = append(r.exchanges[:i], r.exchanges[i+1:]...)
+	return x
+}
+
+// encodeRequest returns the binary representation of req, or nil if it could
+// not be encoded, in which case replays fall back to matching the order of
+// requests.
+func encodeRequest(apiVersion int16, req kafka.Request) []byte {
+	var b bytes.Buffer
+	if err := protocol.WriteRequest(&b, apiVersion, 0, "", req); err != nil {
+		return nil
+	}
+	return b.Bytes()
+}
+
+var recordReaderType = reflect.TypeOf((*protocol.RecordReader)(nil)).Elem()
+
+// bufferRecords reads the records of msg into memory and replaces its record
+// readers with readers of the buffered records. It returns a function which
+// replaces them again, which must be called each time the records of msg need
+// to be read again.
+func bufferRecords(msg protocol.Message) (reset func()) {
+	var resets []func()
+
+	walkRecordReaders(reflect.ValueOf(msg), func(v reflect.Value) {
+		records, err := readRecords(v.Interface().(protocol.RecordReader))
+		if err != nil {
+			// Leave the reader unchanged, encoding the message reports the
+			// error.
+			return
+		}
+		resets = append(resets, func() {
+			v.Set(reflect.ValueOf(newRecordReader(records)))
+		})
+	})
+
+	reset = func() {
+		for _, f := range resets {
+			f()
+		}
+	}
+	reset()
+	return reset
+}
+
+func walkRecordReaders(v reflect.Value, do func(reflect.Value)) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			walkRecordReaders(v.Elem(), do)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkRecordReaders(v.Index(i), do)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.CanSet() {
+				walkRecordReaders(f, do)
+			}
+		}
+	case reflect.Interface:
+		if v.Type() == recordReaderType && !v.IsNil() {
+			do(v)
+		}
+	}
+}
+
+func newRecordReader(records []Record) protocol.RecordReader {
+	rs := make([]protocol.Record, len(records))
+	for i, r := range records {
+		rs[i] = protocol.Record{
+			Offset:  r.Offset,
+			Time:    r.Time,
+			Key:     protocol.NewBytes(r.Key),
+			Value:   protocol.NewBytes(r.Value),
+			Headers: r.Headers,
+		}
+	}
+	return protocol.NewRecordReader(rs...)
+}
@@ -0,0 +1,111 @@
+package kafkatest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+
+	transport := &kafka.Transport{}
+	defer transport.CloseIdleConnections()
+
+	recording := new(bytes.Buffer)
+	recorder := kafkatest.NewRecorder(recording, transport)
+
+	run := func(client *kafka.Client) ([]string, error) {
+		_, err := client.Produce(ctx, &kafka.ProduceRequest{
+			Topic:        "topic-A",
+			RequiredAcks: kafka.RequireAll,
+			Records: kafka.NewRecordReader(
+				kafka.Record{Value: kafka.NewBytes([]byte("hello"))},
+				kafka.Record{Value: kafka.NewBytes([]byte("world"))},
+			),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := client.Fetch(ctx, &kafka.FetchRequest{
+			Topic:    "topic-A",
+			MaxBytes: 1 << 20,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if res.Error != nil {
+			return nil, res.Error
+		}
+
+		var values []string
+		for {
+			r, err := res.Records.ReadRecord()
+			if err != nil {
+				break
+			}
+			v, _ := kafka.ReadAll(r.Value)
+			values = append(values, string(v))
+		}
+		return values, nil
+	}
+
+	recorded, err := run(&kafka.Client{Addr: b.Addr(), Transport: recorder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 2 {
+		t.Fatalf("wrong values fetched from the broker: %q", recorded)
+	}
+
+	// The broker is closed to ensure that the replay does not depend on it.
+	b.Close()
+
+	replayer, err := kafkatest.NewReplayer(bytes.NewReader(recording.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := run(&kafka.Client{Addr: b.Addr(), Transport: replayer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed) != len(recorded) || replayed[0] != recorded[0] || replayed[1] != recorded[1] {
+		t.Errorf("replayed values mismatch: %q != %q", replayed, recorded)
+	}
+	if n := replayer.Remaining(); n != 0 {
+		t.Errorf("%d recorded exchanges were not replayed", n)
+	}
+
+	_, err = (&kafka.Client{Addr: b.Addr(), Transport: replayer}).Fetch(ctx, &kafka.FetchRequest{Topic: "topic-A"})
+	if err == nil {
+		t.Error("expected an error replaying more requests than recorded")
+	}
+}
+
+func TestReplayError(t *testing.T) {
+	recording := bytes.NewBufferString(`{"addr":"127.0.0.1:9092","api_key":3,"api_version":12,"error":"[3] Unknown Topic Or Partition","error_code":3}` + "\n")
+
+	replayer, err := kafkatest.NewReplayer(recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &kafka.Client{Addr: kafka.TCP("127.0.0.1:9092"), Transport: replayer}
+	_, err = client.Metadata(context.Background(), &kafka.MetadataRequest{Topics: []string{"topic-A"}})
+	if !errors.Is(err, kafka.UnknownTopicOrPartition) {
+		t.Errorf("expected kafka.UnknownTopicOrPartition but got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package kafkatest
+
+import "sort"
+
+// TB is the subset of the testing.TB interface used by NewTestBroker.
+type TB interface {
+	Helper()
+	Cleanup(func())
+	Fatalf(format string, args ...interface{})
+}
+
+// Topic configures a topic created by NewTestBroker.
+type Topic struct {
+	// The name of the topic.
+	Name string
+
+	// The number of partitions of the topic.
+	//
+	// Default: 1
+	Partitions int
+
+	// Records appended to the partitions of the topic, by partition index.
+	Records map[int][]Record
+}
+
+// NewTestBroker starts a broker for the test t, creates the topics, and appends
+// their records. The broker is closed when the test and its subtests complete,
+// and the test fails if the broker could not be started or the topics could
+// not be created:
+//
+//	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A", Partitions: 2})
+func NewTestBroker(t TB, topics ...Topic) *Broker {
+	t.Helper()
+
+	b := &Broker{AutoCreateTopics: true}
+	if err := b.Start(); err != nil {
+		t.Fatalf("kafkatest: failed to start broker: %v", err)
+	}
+	t.Cleanup(func() { b.Close() })
+
+	for _, topic := range topics {
+		partitions := topic.Partitions
+		if partitions == 0 {
+			partitions = 1
+		}
+		if err := b.CreateTopic(topic.Name, partitions); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		indexes := make([]int, 0, len(topic.Records))
+		for partition := range topic.Records {
+			indexes = append(indexes, partition)
+		}
+		sort.Ints(indexes)
+
+		for _, partition := range indexes {
+			if _, err := b.Append(topic.Name, partition, topic.Records[partition]...); err != nil {
+				t.Fatalf("%v", err)
+			}
+		}
+	}
+
+	return b
+}
@@ -0,0 +1,507 @@
+package kafkatest
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/addoffsetstotxn"
+	"github.com/segmentio/kafka-go/protocol/addpartitionstotxn"
+	"github.com/segmentio/kafka-go/protocol/describeproducers"
+	"github.com/segmentio/kafka-go/protocol/describetransactions"
+	"github.com/segmentio/kafka-go/protocol/endtxn"
+	"github.com/segmentio/kafka-go/protocol/initproducerid"
+	"github.com/segmentio/kafka-go/protocol/listtransactions"
+	"github.com/segmentio/kafka-go/protocol/txnoffsetcommit"
+	"github.com/segmentio/kafka-go/protocol/writetxnmarkers"
+)
+
+// States of transactions reported by DescribeTransactions and
+// ListTransactions, the broker commits and aborts transactions synchronously
+// so they are either empty or ongoing.
+const (
+	txnStateEmpty   = "Empty"
+	txnStateOngoing = "Ongoing"
+)
+
+// txnStates are the transaction states known by kafka coordinators, which
+// ListTransactions requests may filter on.
+var txnStates = map[string]bool{
+	"Empty":             true,
+	"Ongoing":           true,
+	"PrepareCommit":     true,
+	"PrepareAbort":      true,
+	"CompleteCommit":    true,
+	"CompleteAbort":     true,
+	"Dead":              true,
+	"PrepareEpochFence": true,
+}
+
+// transaction is the state of a transactional producer.
+//
+// The broker does not write the records and offsets of transactions to the
+// partitions and groups until they are committed, which hides them from all
+// consumers, as if they were reading with the read_committed isolation level.
+type transaction struct {
+	producerID    int64
+	producerEpoch int16
+	// The epoch of the producer whose transaction expired, which may still
+	// bump the epoch with InitProducerId (KIP-360), or -1.
+	expiredEpoch int16
+	timeoutMs    int32
+	// The time at which the first partition or group was added to the
+	// ongoing transaction.
+	startTime time.Time
+	// The partitions and groups added to the ongoing transaction, and the
+	// records and offsets waiting for the transaction to be committed.
+	partitions map[topicPartition][]Record
+	offsets    map[string]map[topicPartition]int64
+}
+
+func (t *transaction) reset() {
+	t.startTime = time.Time{}
+	t.partitions = make(map[topicPartition][]Record)
+	t.offsets = make(map[string]map[topicPartition]int64)
+}
+
+// start records the start time of the transaction when the first partition or
+// group is added to it.
+func (t *transaction) start() {
+	if t.startTime.IsZero() {
+		t.startTime = time.Now()
+	}
+}
+
+func (t *transaction) state() string {
+	if t.startTime.IsZero() {
+		return txnStateEmpty
+	}
+	return txnStateOngoing
+}
+
+// transaction must be called with the mutex held. It returns the error code
+// reported to producers which do not match the current session of the
+// transactional id.
+func (b *Broker) transaction(id string, producerID int64, producerEpoch int16) (*transaction, int16) {
+	t := b.txns[id]
+	switch {
+	case t == nil || t.producerID != producerID:
+		return nil, errInvalidProducerIDMapping
+	case t.producerEpoch != producerEpoch && producerEpoch == t.expiredEpoch:
+		return nil, errInvalidProducerEpoch
+	case t.producerEpoch != producerEpoch:
+		return nil, errProducerFenced
+	}
+	return t, errNone
+}
+
+func (b *Broker) initProducerID(req *initproducerid.Request) *initproducerid.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if req.TransactionalID == "" {
+		// Idempotent producers get a new producer id each time.
+		b.producerIDs++
+		return &initproducerid.Response{ProducerID: b.producerIDs}
+	}
+
+	t := b.txns[req.TransactionalID]
+
+	// Producer ids are assigned from 1, requests below v3 have no producer
+	// id and epoch, and new producers send -1.
+	if req.ProducerID > 0 {
+		// The producer asks to bump the epoch of its session, which is only
+		// allowed if it was not fenced by another producer (KIP-360).
+		errorCode := errNone
+		switch {
+		case t == nil || t.producerID != req.ProducerID:
+			errorCode = errInvalidProducerIDMapping
+		case req.ProducerEpoch != t.producerEpoch && req.ProducerEpoch != t.expiredEpoch:
+			errorCode = errProducerFenced
+		}
+		if errorCode != errNone {
+			return &initproducerid.Response{ErrorCode: errorCode, ProducerID: -1, ProducerEpoch: -1}
+		}
+	}
+
+	if t == nil {
+		b.producerIDs++
+		t = &transaction{producerID: b.producerIDs}
+		b.txns[req.TransactionalID] = t
+	} else {
+		// Bumping the epoch fences the previous producers using the
+		// transactional id, and aborts their ongoing transaction.
+		t.producerEpoch++
+	}
+	t.expiredEpoch = -1
+	t.timeoutMs = req.TransactionTimeoutMs
+	t.reset()
+
+	return &initproducerid.Response{
+		ProducerID:    t.producerID,
+		ProducerEpoch: t.producerEpoch,
+	}
+}
+
+// ExpireTransaction aborts the ongoing transaction of a transactional id as if
+// it had timed out. Like kafka coordinators, the broker bumps the epoch of the
+// producer, which receives INVALID_PRODUCER_EPOCH errors until it bumps the
+// epoch itself with InitProducerId.
+func (b *Broker) ExpireTransaction(transactionalID string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	t := b.txns[transactionalID]
+	if t == nil {
+		return fmt.Errorf("kafkatest: unknown transactional id %q", transactionalID)
+	}
+	t.expiredEpoch = t.producerEpoch
+	t.producerEpoch++
+	t.reset()
+	return nil
+}
+
+func (b *Broker) addPartitionsToTxn(req *addpartitionstotxn.Request) *addpartitionstotxn.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	t, errorCode := b.transaction(req.TransactionalID, req.ProducerID, req.ProducerEpoch)
+	res := &addpartitionstotxn.Response{
+		Results: make([]addpartitionstotxn.ResponseResult, len(req.Topics)),
+	}
+
+	for i, topic := range req.Topics {
+		res.Results[i] = addpartitionstotxn.ResponseResult{
+			Name:    topic.Name,
+			Results: make([]addpartitionstotxn.ResponsePartition, len(topic.Partitions)),
+		}
+
+		for j, partition := range topic.Partitions {
+			r := &res.Results[i].Results[j]
+			r.PartitionIndex = partition
+			r.ErrorCode = errorCode
+
+			if errorCode == errNone {
+				if b.partition(topic.Name, partition) == nil {
+					r.ErrorCode = errUnknownTopicOrPartition
+				} else if key := (topicPartition{topic: topic.Name, partition: partition}); t.partitions[key] == nil {
+					t.start()
+					t.partitions[key] = []Record{}
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+// produceTxn must be called with the mutex held.
+func (b *Broker) produceTxn(transactionalID, topic string, partition int32, records []Record) int16 {
+	t := b.txns[transactionalID]
+	if t == nil {
+		return errInvalidProducerIDMapping
+	}
+	key := topicPartition{topic: topic, partition: partition}
+	pending, ok := t.partitions[key]
+	if !ok {
+		// Partitions must be added to the transaction before producing.
+		return errInvalidTxnState
+	}
+	t.partitions[key] = append(pending, records...)
+	return errNone
+}
+
+func (b *Broker) addOffsetsToTxn(req *addoffsetstotxn.Request) *addoffsetstotxn.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	t, errorCode := b.transaction(req.TransactionalID, req.ProducerID, req.ProducerEpoch)
+	if errorCode == errNone && t.offsets[req.GroupID] == nil {
+		t.start()
+		t.offsets[req.GroupID] = make(map[topicPartition]int64)
+	}
+	return &addoffsetstotxn.Response{ErrorCode: errorCode}
+}
+
+func (b *Broker) txnOffsetCommit(req *txnoffsetcommit.Request) *txnoffsetcommit.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	t, errorCode := b.transaction(req.TransactionalID, req.ProducerID, req.ProducerEpoch)
+	if errorCode == errNone {
+		g := b.group(req.GroupID)
+		switch {
+		case t.offsets[req.GroupID] == nil:
+			errorCode = errInvalidTxnState
+		// Commits with no member ID are made by producers which are not
+		// members of the group, they are not validated.
+		case req.MemberID == "":
+		case g.member(req.MemberID) == nil:
+			errorCode = errUnknownMemberID
+		case req.GenerationID != g.generationID:
+			errorCode = errIllegalGeneration
+		}
+	}
+
+	res := &txnoffsetcommit.Response{
+		Topics: make([]txnoffsetcommit.ResponseTopic, len(req.Topics)),
+	}
+
+	for i, topic := range req.Topics {
+		res.Topics[i] = txnoffsetcommit.ResponseTopic{
+			Name:       topic.Name,
+			Partitions: make([]txnoffsetcommit.ResponsePartition, len(topic.Partitions)),
+		}
+
+		for j, p := range topic.Partitions {
+			r := &res.Topics[i].Partitions[j]
+			r.Partition = p.Partition
+			r.ErrorCode = errorCode
+
+			if errorCode == errNone {
+				if b.partition(topic.Name, p.Partition) == nil {
+					r.ErrorCode = errUnknownTopicOrPartition
+				} else {
+					t.offsets[req.GroupID][topicPartition{topic: topic.Name, partition: p.Partition}] = p.CommittedOffset
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+func (b *Broker) endTxn(req *endtxn.Request) *endtxn.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	t, errorCode := b.transaction(req.TransactionalID, req.ProducerID, req.ProducerEpoch)
+	if errorCode != errNone {
+		return &endtxn.Response{ErrorCode: errorCode}
+	}
+
+	if req.Committed {
+		for key, records := range t.partitions {
+			if log := b.partition(key.topic, key.partition); log != nil {
+				b.append(log, records)
+			}
+		}
+		for groupID, offsets := range t.offsets {
+			g := b.group(groupID)
+			for key, offset := range offsets {
+				g.offsets[key] = offset
+				// The metadata of transactional commits is not recorded.
+				delete(g.metadata, key)
+			}
+		}
+	}
+
+	t.reset()
+	return &endtxn.Response{}
+}
+
+func (b *Broker) describeTransactions(req *describetransactions.Request) *describetransactions.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	res := &describetransactions.Response{
+		TransactionStates: make([]describetransactions.TransactionState, len(req.TransactionalIDs)),
+	}
+
+	for i, id := range req.TransactionalIDs {
+		r := &res.TransactionStates[i]
+		r.TransactionalID = id
+
+		t := b.txns[id]
+		if t == nil {
+			r.ErrorCode = errTransactionalIDNotFound
+			continue
+		}
+
+		r.TransactionState = t.state()
+		r.TransactionTimeoutMs = t.timeoutMs
+		r.TransactionStartTimeMs = -1
+		r.ProducerID = t.producerID
+		r.ProducerEpoch = t.producerEpoch
+
+		if !t.startTime.IsZero() {
+			r.TransactionStartTimeMs = t.startTime.UnixNano() / int64(time.Millisecond)
+		}
+
+		topics := make(map[string][]int32)
+		for key := range t.partitions {
+			topics[key.topic] = append(topics[key.topic], key.partition)
+		}
+		for topic, partitions := range topics {
+			sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+			r.Topics = append(r.Topics, describetransactions.TopicData{Topic: topic, Partitions: partitions})
+		}
+		sort.Slice(r.Topics, func(i, j int) bool { return r.Topics[i].Topic < r.Topics[j].Topic })
+	}
+
+	return res
+}
+
+func (b *Broker) listTransactions(req *listtransactions.Request) *listtransactions.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	res := &listtransactions.Response{}
+	states := make(map[string]bool, len(req.StateFilters))
+	producers := make(map[int64]bool, len(req.ProducerIDFilters))
+
+	for _, state := range req.StateFilters {
+		if txnStates[state] {
+			states[state] = true
+		} else {
+			res.UnknownStateFilters = append(res.UnknownStateFilters, state)
+		}
+	}
+	for _, id := range req.ProducerIDFilters {
+		producers[id] = true
+	}
+
+	now := time.Now()
+	for id, t := range b.txns {
+		state := t.state()
+		switch {
+		case len(req.StateFilters) != 0 && !states[state]:
+			continue
+		case len(req.ProducerIDFilters) != 0 && !producers[t.producerID]:
+			continue
+		case req.DurationFilter >= 0 && (t.startTime.IsZero() || now.Sub(t.startTime) < time.Duration(req.DurationFilter)*time.Millisecond):
+			continue
+		}
+		res.TransactionStates = append(res.TransactionStates, listtransactions.TransactionState{
+			TransactionalID:  id,
+			ProducerID:       t.producerID,
+			TransactionState: state,
+		})
+	}
+
+	sort.Slice(res.TransactionStates, func(i, j int) bool {
+		return res.TransactionStates[i].TransactionalID < res.TransactionStates[j].TransactionalID
+	})
+	return res
+}
+
+// describeProducers reports the transactional producers which added the
+// partitions to their ongoing transaction. Since the records of transactions
+// are only written when they commit, the start offset of the transactions is
+// the end of the partition.
+func (b *Broker) describeProducers(req *describeproducers.Request) *describeproducers.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	res := &describeproducers.Response{
+		Topics: make([]describeproducers.ResponseTopic, len(req.Topics)),
+	}
+
+	for i, topic := range req.Topics {
+		res.Topics[i] = describeproducers.ResponseTopic{
+			Name:       topic.Name,
+			Partitions: make([]describeproducers.ResponsePartition, len(topic.PartitionIndexes)),
+		}
+
+		for j, partition := range topic.PartitionIndexes {
+			r := &res.Topics[i].Partitions[j]
+			r.PartitionIndex = partition
+
+			log := b.partition(topic.Name, partition)
+			if log == nil {
+				r.ErrorCode = errUnknownTopicOrPartition
+				continue
+			}
+
+			key := topicPartition{topic: topic.Name, partition: partition}
+			for _, t := range b.txns {
+				if _, ok := t.partitions[key]; !ok {
+					continue
+				}
+				r.ActiveProducers = append(r.ActiveProducers, describeproducers.ProducerState{
+					ProducerID:            t.producerID,
+					ProducerEpoch:         int32(t.producerEpoch),
+					LastSequence:          -1,
+					LastTimestamp:         -1,
+					CoordinatorEpoch:      -1,
+					CurrentTxnStartOffset: log.highWatermark(),
+				})
+			}
+
+			sort.Slice(r.ActiveProducers, func(i, j int) bool {
+				return r.ActiveProducers[i].ProducerID < r.ActiveProducers[j].ProducerID
+			})
+		}
+	}
+
+	return res
+}
+
+// writeTxnMarkers completes the transactions of producers on partitions as if
+// their coordinator had written the markers: the records of committed
+// transactions are appended to the partitions, and those of aborted
+// transactions are dropped.
+func (b *Broker) writeTxnMarkers(req *writetxnmarkers.Request) *writetxnmarkers.Response {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	res := &writetxnmarkers.Response{
+		Markers: make([]writetxnmarkers.ResponseMarker, len(req.Markers)),
+	}
+
+	for i, m := range req.Markers {
+		var t *transaction
+		for _, txn := range b.txns {
+			if txn.producerID == m.ProducerID {
+				t = txn
+				break
+			}
+		}
+
+		errorCode := errNone
+		if t != nil && m.ProducerEpoch < t.producerEpoch {
+			errorCode = errInvalidProducerEpoch
+		}
+
+		res.Markers[i] = writetxnmarkers.ResponseMarker{
+			ProducerID: m.ProducerID,
+			Topics:     make([]writetxnmarkers.ResponseTopic, len(m.Topics)),
+		}
+
+		for j, topic := range m.Topics {
+			res.Markers[i].Topics[j] = writetxnmarkers.ResponseTopic{
+				Name:       topic.Name,
+				Partitions: make([]writetxnmarkers.ResponsePartition, len(topic.PartitionIndexes)),
+			}
+
+			for k, partition := range topic.PartitionIndexes {
+				r := &res.Markers[i].Topics[j].Partitions[k]
+				r.PartitionIndex = partition
+				r.ErrorCode = errorCode
+
+				log := b.partition(topic.Name, partition)
+				switch {
+				case log == nil:
+					r.ErrorCode = errUnknownTopicOrPartition
+				case errorCode != errNone || t == nil:
+					// Markers of producers without transactions have no
+					// effect on the partitions.
+				default:
+					key := topicPartition{topic: topic.Name, partition: partition}
+					if records, ok := t.partitions[key]; ok {
+						if m.TransactionResult {
+							b.append(log, records)
+						}
+						delete(t.partitions, key)
+					}
+				}
+			}
+		}
+
+		if t != nil && len(t.partitions) == 0 && len(t.offsets) == 0 {
+			t.startTime = time.Time{}
+		}
+	}
+
+	return res
+}
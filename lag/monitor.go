@@ -0,0 +1,264 @@
+// Package lag provides a monitor computing the lag of kafka consumer groups,
+// which is the number of messages that were written to the partitions of
+// topics but not yet committed by the groups consuming them.
+//
+// This package does not make any promises around backwards compatibility.
+package lag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const defaultInterval = 30 * time.Second
+
+// Monitor periodically computes the lag of consumer groups and publishes
+// snapshots of it to callbacks.
+//
+// Offsets are read from the brokers, the groups do not need to have active
+// members, which makes the monitor suitable to run as a standalone process
+// watching the groups of a cluster.
+type Monitor struct {
+	// The client used to send requests to the kafka cluster.
+	//
+	// This field is required.
+	Client *kafka.Client
+
+	// The consumer groups to monitor.
+	//
+	// This field is required.
+	Groups []string
+
+	// The topics to compute the lag of.  When empty, the lag is computed for
+	// all topics that the groups committed offsets for.
+	Topics []string
+
+	// The time between lag computations.
+	//
+	// Default: 30s
+	Interval time.Duration
+
+	// A function called with the lag of each group every time it is computed.
+	OnSnapshot func(Snapshot)
+
+	// An optional function called when computing the lag of a group failed.
+	// The monitor keeps running and retries on the next interval.
+	OnError func(group string, err error)
+}
+
+// Snapshot is the lag of a consumer group at a point in time.
+type Snapshot struct {
+	// The time at which the lag was computed.
+	Time time.Time
+
+	// The consumer group.
+	Group string
+
+	// The lag of each partition that the group consumes, sorted by topic
+	// and partition.
+	Partitions []PartitionLag
+}
+
+// TotalLag returns the sum of the lag of all partitions in the snapshot.
+func (s Snapshot) TotalLag() int64 {
+	total := int64(0)
+	for _, p := range s.Partitions {
+		total += p.Lag
+	}
+	return total
+}
+
+// PartitionLag is the lag of a consumer group on a partition.
+type PartitionLag struct {
+	Topic     string
+	Partition int
+
+	// The offset committed by the group, or -1 if the group never committed
+	// an offset on the partition.
+	CommittedOffset int64
+
+	// The offset of the next message that will be written to the partition.
+	LastOffset int64
+
+	// The number of messages that the group has yet to consume.  When the
+	// group never committed an offset, all the messages retained on the
+	// partition are counted.
+	Lag int64
+}
+
+// Run computes the lag of the groups every interval until ctx is canceled,
+// in which case it returns the context error.  The lag is first computed when
+// Run is called.
+func (m *Monitor) Run(ctx context.Context) error {
+	if m.Client == nil {
+		return errors.New("lag.(*Monitor).Run: a client is required")
+	}
+	if len(m.Groups) == 0 {
+		return errors.New("lag.(*Monitor).Run: at least one group is required")
+	}
+
+	interval := m.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, group := range m.Groups {
+			snapshot, err := m.Check(ctx, group)
+			switch {
+			case err == nil:
+				if m.OnSnapshot != nil {
+					m.OnSnapshot(snapshot)
+				}
+			case ctx.Err() != nil:
+				return ctx.Err()
+			default:
+				if m.OnError != nil {
+					m.OnError(group, err)
+				}
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Check computes the lag of a consumer group once.
+func (m *Monitor) Check(ctx context.Context, group string) (Snapshot, error) {
+	partitions, err := m.partitions(ctx)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	committed, err := m.Client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		Addr:    m.Client.Addr,
+		GroupID: group,
+		Topics:  partitions,
+	})
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if committed.Error != nil {
+		return Snapshot{}, fmt.Errorf("fetching offsets of group %q: %w", group, committed.Error)
+	}
+
+	// Without a list of topics, the lag is only reported for the topics that
+	// the group consumes.
+	requests := make(map[string][]kafka.OffsetRequest)
+	offsets := make(map[string]map[int]int64)
+
+	for topic, committedPartitions := range committed.Topics {
+		consumed := len(m.Topics) != 0
+		topicOffsets := make(map[int]int64, len(committedPartitions))
+
+		for _, p := range committedPartitions {
+			if p.Error != nil {
+				return Snapshot{}, fmt.Errorf("fetching offsets of group %q on partition %d of %s: %w", group, p.Partition, topic, p.Error)
+			}
+			if p.CommittedOffset >= 0 {
+				consumed = true
+			}
+			topicOffsets[p.Partition] = p.CommittedOffset
+		}
+
+		if !consumed {
+			continue
+		}
+
+		offsets[topic] = topicOffsets
+		for partition := range topicOffsets {
+			requests[topic] = append(requests[topic],
+				kafka.FirstOffsetOf(partition),
+				kafka.LastOffsetOf(partition),
+			)
+		}
+	}
+
+	snapshot := Snapshot{Time: time.Now(), Group: group}
+	if len(requests) == 0 {
+		return snapshot, nil
+	}
+
+	listed, err := m.Client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Addr:   m.Client.Addr,
+		Topics: requests,
+	})
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	for topic, partitionOffsets := range listed.Topics {
+		for _, p := range partitionOffsets {
+			if p.Error != nil {
+				return Snapshot{}, fmt.Errorf("listing offsets of partition %d of %s: %w", p.Partition, topic, p.Error)
+			}
+
+			committedOffset := offsets[topic][p.Partition]
+			lag := p.LastOffset - committedOffset
+			if committedOffset < 0 {
+				lag = p.LastOffset - p.FirstOffset
+			}
+			if lag < 0 {
+				// The offsets are not read atomically, the group may commit
+				// after the end of the partition was listed.
+				lag = 0
+			}
+
+			snapshot.Partitions = append(snapshot.Partitions, PartitionLag{
+				Topic:           topic,
+				Partition:       p.Partition,
+				CommittedOffset: committedOffset,
+				LastOffset:      p.LastOffset,
+				Lag:             lag,
+			})
+		}
+	}
+
+	sort.Slice(snapshot.Partitions, func(i, j int) bool {
+		a, b := snapshot.Partitions[i], snapshot.Partitions[j]
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		return a.Partition < b.Partition
+	})
+
+	return snapshot, nil
+}
+
+// partitions returns the partitions of the topics that the monitor computes
+// the lag of, or of all topics when none were configured.
+func (m *Monitor) partitions(ctx context.Context) (map[string][]int, error) {
+	metadata, err := m.Client.Metadata(ctx, &kafka.MetadataRequest{
+		Addr:   m.Client.Addr,
+		Topics: m.Topics,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make(map[string][]int, len(metadata.Topics))
+	for _, t := range metadata.Topics {
+		if t.Error != nil {
+			return nil, fmt.Errorf("reading metadata of %s: %w", t.Name, t.Error)
+		}
+		if t.Internal {
+			continue
+		}
+		for _, p := range t.Partitions {
+			partitions[t.Name] = append(partitions[t.Name], p.ID)
+		}
+	}
+	return partitions, nil
+}
@@ -0,0 +1,165 @@
+package lag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol/listoffsets"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/offsetfetch"
+)
+
+type roundTripperFunc func(context.Context, net.Addr, kafka.Request) (kafka.Response, error)
+
+func (f roundTripperFunc) RoundTrip(ctx context.Context, addr net.Addr, req kafka.Request) (kafka.Response, error) {
+	return f(ctx, addr, req)
+}
+
+// newClient returns a client for a cluster with two topics of two partitions,
+// where group-1 consumes topic-1.
+func newClient() *kafka.Client {
+	transport := roundTripperFunc(func(ctx context.Context, addr net.Addr, req kafka.Request) (kafka.Response, error) {
+		switch r := req.(type) {
+		case *metadata.Request:
+			res := &metadata.Response{
+				Brokers: []metadata.ResponseBroker{{NodeID: 1, Host: "localhost", Port: 9092}},
+			}
+			for _, name := range []string{"topic-1", "topic-2"} {
+				if r.TopicNames != nil && r.TopicNames[0] != name {
+					continue
+				}
+				res.Topics = append(res.Topics, metadata.ResponseTopic{
+					Name:       name,
+					Partitions: []metadata.ResponsePartition{{PartitionIndex: 0}, {PartitionIndex: 1}},
+				})
+			}
+			return res, nil
+
+		case *offsetfetch.Request:
+			committed := map[string][]int64{
+				"topic-1": {90, -1},
+				"topic-2": {-1, -1},
+			}
+			res := &offsetfetch.Response{}
+			for _, t := range r.Topics {
+				topic := offsetfetch.ResponseTopic{Name: t.Name}
+				for _, p := range t.PartitionIndexes {
+					offset := int64(-1)
+					if r.GroupID == "group-1" {
+						offset = committed[t.Name][p]
+					}
+					topic.Partitions = append(topic.Partitions, offsetfetch.ResponsePartition{
+						PartitionIndex:  p,
+						CommittedOffset: offset,
+					})
+				}
+				res.Topics = append(res.Topics, topic)
+			}
+			return res, nil
+
+		case *listoffsets.Request:
+			res := &listoffsets.Response{}
+			for _, t := range r.Topics {
+				topic := listoffsets.ResponseTopic{Topic: t.Topic}
+				for _, p := range t.Partitions {
+					offset := int64(100)
+					if p.Timestamp == kafka.FirstOffset {
+						offset = 40
+					}
+					topic.Partitions = append(topic.Partitions, listoffsets.ResponsePartition{
+						Partition: p.Partition,
+						Timestamp: p.Timestamp,
+						Offset:    offset,
+					})
+				}
+				res.Topics = append(res.Topics, topic)
+			}
+			return res, nil
+
+		default:
+			return nil, fmt.Errorf("unexpected request: %T", req)
+		}
+	})
+
+	return &kafka.Client{
+		Addr:      kafka.TCP("localhost:9092"),
+		Transport: transport,
+	}
+}
+
+func TestMonitorCheck(t *testing.T) {
+	m := &Monitor{Client: newClient(), Groups: []string{"group-1"}}
+
+	snapshot, err := m.Check(context.Background(), "group-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []PartitionLag{
+		{Topic: "topic-1", Partition: 0, CommittedOffset: 90, LastOffset: 100, Lag: 10},
+		{Topic: "topic-1", Partition: 1, CommittedOffset: -1, LastOffset: 100, Lag: 60},
+	}
+	if !reflect.DeepEqual(snapshot.Partitions, expected) {
+		t.Errorf("lag mismatch:\nwant: %+v\ngot:  %+v", expected, snapshot.Partitions)
+	}
+	if total := snapshot.TotalLag(); total != 70 {
+		t.Errorf("expected a total lag of 70 but got %d", total)
+	}
+
+	// Topics configured explicitly are reported even if the group never
+	// committed offsets on them.
+	m.Topics = []string{"topic-2"}
+	snapshot, err = m.Check(context.Background(), "group-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Partitions) != 2 || snapshot.TotalLag() != 120 {
+		t.Errorf("unexpected lag of topic-2: %+v", snapshot.Partitions)
+	}
+}
+
+func TestMonitorRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	snapshots := make(chan Snapshot)
+	m := &Monitor{
+		Client:   newClient(),
+		Groups:   []string{"group-1", "group-2"},
+		Interval: time.Millisecond,
+		OnSnapshot: func(s Snapshot) {
+			select {
+			case snapshots <- s:
+			case <-ctx.Done():
+			}
+		},
+	}
+
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+
+	for i, group := range []string{"group-1", "group-2", "group-1"} {
+		s := <-snapshots
+		if s.Group != group {
+			t.Errorf("snapshot %d: expected group %s but got %s", i, group, s.Group)
+		}
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v but got %v", context.Canceled, err)
+	}
+}
+
+func TestMonitorRunRequiresGroups(t *testing.T) {
+	m := &Monitor{Client: newClient()}
+	if err := m.Run(context.Background()); err == nil {
+		t.Error("expected an error when no groups are configured")
+	}
+}
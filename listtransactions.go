@@ -0,0 +1,111 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/listtransactions"
+)
+
+// ListTransactionsRequest represents a request sent to kafka brokers to list
+// the transactions that they coordinate.
+type ListTransactionsRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// When set, only the transactions in one of these states are listed.
+	States []TransactionState
+
+	// When set, only the transactions of these producers are listed.
+	ProducerIDs []int
+
+	// When positive, only the transactions which have been running for longer
+	// than this duration are listed. This filter requires kafka 3.8 or above,
+	// older brokers ignore it.
+	MinDuration time.Duration
+}
+
+// ListTransactionsResponse represents a response from kafka brokers to a
+// ListTransactionsRequest.
+type ListTransactionsResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// An error that may have occurred while listing the transactions of one
+	// of the brokers.
+	Error error
+
+	// The states of the request filters which the brokers did not recognize.
+	UnknownStates []TransactionState
+
+	// The transactions of all the brokers of the cluster.
+	Transactions []ListTransactionsResponseTransaction
+}
+
+// ListTransactionsResponseTransaction contains the response details for a
+// single transaction.
+type ListTransactionsResponseTransaction struct {
+	// The transactional id of the transaction.
+	TransactionalID string
+
+	// The id of the producer using the transactional id.
+	ProducerID int
+
+	// The state of the transaction.
+	State TransactionState
+
+	// The id of the broker coordinating the transaction.
+	Coordinator int
+}
+
+// ListTransactions sends requests to all the brokers of the cluster to list
+// the transactions that they coordinate.
+func (c *Client) ListTransactions(ctx context.Context, req *ListTransactionsRequest) (*ListTransactionsResponse, error) {
+	states := make([]string, len(req.States))
+	for i, s := range req.States {
+		states[i] = string(s)
+	}
+
+	producerIDs := make([]int64, len(req.ProducerIDs))
+	for i, id := range req.ProducerIDs {
+		producerIDs[i] = int64(id)
+	}
+
+	duration := int64(-1)
+	if req.MinDuration > 0 {
+		duration = int64(req.MinDuration / time.Millisecond)
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &listtransactions.Request{
+		StateFilters:      states,
+		ProducerIDFilters: producerIDs,
+		DurationFilter:    duration,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).ListTransactions: %w", err)
+	}
+
+	res := m.(*listtransactions.Response)
+	ret := &ListTransactionsResponse{
+		Throttle:     makeDuration(res.ThrottleTimeMs),
+		Error:        makeError(res.ErrorCode, ""),
+		Transactions: make([]ListTransactionsResponseTransaction, len(res.TransactionStates)),
+	}
+
+	for _, s := range res.UnknownStateFilters {
+		ret.UnknownStates = append(ret.UnknownStates, TransactionState(s))
+	}
+
+	for i, t := range res.TransactionStates {
+		ret.Transactions[i] = ListTransactionsResponseTransaction{
+			TransactionalID: t.TransactionalID,
+			ProducerID:      int(t.ProducerID),
+			State:           TransactionState(t.TransactionState),
+			Coordinator:     int(t.BrokerID),
+		}
+	}
+
+	return ret, nil
+}
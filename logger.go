@@ -1,5 +1,11 @@
 package kafka
 
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
 // Logger interface API for log.Logger.
 type Logger interface {
 	Printf(string, ...interface{})
@@ -15,3 +21,94 @@ type Logger interface {
 type LoggerFunc func(string, ...interface{})
 
 func (f LoggerFunc) Printf(msg string, args ...interface{}) { f(msg, args...) }
+
+// LogLevel is the severity of messages reported to a StructuredLogger.  The
+// values match the levels of the log/slog package.
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = -4
+	LogLevelInfo  LogLevel = 0
+	LogLevelWarn  LogLevel = 4
+	LogLevelError LogLevel = 8
+)
+
+// StructuredLogger is an interface implemented by loggers which receive
+// messages with a list of alternating keys and values describing their
+// context, like the topic, partition, or consumer group that they apply to.
+//
+// The signature of Log matches the method of the same name on slog.Logger,
+// see SlogLogger to use one with kafka-go.
+type StructuredLogger interface {
+	Log(ctx context.Context, level LogLevel, msg string, args ...interface{})
+}
+
+// StructuredLoggerFunc is a bridge between StructuredLogger and functions.
+type StructuredLoggerFunc func(ctx context.Context, level LogLevel, msg string, args ...interface{})
+
+func (f StructuredLoggerFunc) Log(ctx context.Context, level LogLevel, msg string, args ...interface{}) {
+	f(ctx, level, msg, args...)
+}
+
+// structuredLogger adapts a StructuredLogger to the Logger interface, so it
+// can be used where messages are logged with Printf.
+type structuredLogger struct {
+	logger StructuredLogger
+	level  LogLevel
+	args   []interface{}
+}
+
+func (l structuredLogger) Printf(msg string, args ...interface{}) {
+	l.logger.Log(context.Background(), l.level, fmt.Sprintf(msg, args...), l.args...)
+}
+
+// printfLogger carries the keys and values describing the context of the
+// messages logged to a Logger, they are appended to the messages logged with
+// logKV.
+type printfLogger struct {
+	logger Logger
+	args   []interface{}
+}
+
+func (l printfLogger) Printf(msg string, args ...interface{}) {
+	l.logger.Printf(msg, args...)
+}
+
+// newLogger returns the logger which messages of the given level are reported
+// to, the structured logger takes precedence over the printf-style one.  The
+// arguments are the keys and values describing the context of the messages.
+// The function returns nil if neither logger is set.
+func newLogger(structured StructuredLogger, logger Logger, level LogLevel, args ...interface{}) Logger {
+	switch {
+	case structured != nil:
+		return structuredLogger{logger: structured, level: level, args: args}
+	case logger != nil:
+		return printfLogger{logger: logger, args: args}
+	default:
+		return nil
+	}
+}
+
+// logKV logs msg with a list of alternating keys and values.  Structured
+// loggers receive them as arguments after those of the logger, other loggers
+// receive a message where they are appended as key=value pairs.
+func logKV(l Logger, msg string, kv ...interface{}) {
+	switch x := l.(type) {
+	case structuredLogger:
+		x.logger.Log(context.Background(), x.level, msg, concatArgs(x.args, kv)...)
+		return
+	case printfLogger:
+		l, kv = x.logger, concatArgs(x.args, kv)
+	}
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i+1 < len(kv); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", kv[i], kv[i+1])
+	}
+	l.Printf("%s", b.String())
+}
+
+func concatArgs(a, b []interface{}) []interface{} {
+	args := make([]interface{}, 0, len(a)+len(b))
+	return append(append(args, a...), b...)
+}
@@ -0,0 +1,112 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	metadataAPI "github.com/segmentio/kafka-go/protocol/metadata"
+	produceAPI "github.com/segmentio/kafka-go/protocol/produce"
+)
+
+type logRecord struct {
+	level LogLevel
+	msg   string
+	args  []interface{}
+}
+
+type recordingLogger struct {
+	mutex   sync.Mutex
+	records []logRecord
+}
+
+func (l *recordingLogger) Log(ctx context.Context, level LogLevel, msg string, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.records = append(l.records, logRecord{level: level, msg: msg, args: args})
+}
+
+func TestWriterStructuredLogger(t *testing.T) {
+	attempts := 0
+	transport := roundTripperFunc(func(ctx context.Context, addr net.Addr, req Request) (Response, error) {
+		switch req.(type) {
+		case *metadataAPI.Request:
+			return &metadataAPI.Response{
+				Brokers: []metadataAPI.ResponseBroker{{NodeID: 1, Host: "localhost", Port: 9092}},
+				Topics: []metadataAPI.ResponseTopic{{
+					Name:       "topic-1",
+					Partitions: []metadataAPI.ResponsePartition{{PartitionIndex: 0, LeaderID: 1}},
+				}},
+			}, nil
+		case *produceAPI.Request:
+			attempts++
+			res := &produceAPI.Response{
+				Topics: []produceAPI.ResponseTopic{{
+					Topic:      "topic-1",
+					Partitions: []produceAPI.ResponsePartition{{Partition: 0}},
+				}},
+			}
+			if attempts == 1 {
+				res.Topics[0].Partitions[0].ErrorCode = int16(NotLeaderForPartition)
+			}
+			return res, nil
+		default:
+			return nil, fmt.Errorf("unexpected request: %T", req)
+		}
+	})
+
+	logger := &recordingLogger{}
+	w := &Writer{
+		Addr:             TCP("localhost:9092"),
+		Topic:            "topic-1",
+		BatchTimeout:     time.Millisecond,
+		RequiredAcks:     RequireOne,
+		Transport:        transport,
+		Logger:           LoggerFunc(func(string, ...interface{}) { t.Error("unexpected call to Logger") }),
+		StructuredLogger: logger,
+	}
+	defer w.Close()
+
+	if err := w.WriteMessages(context.Background(), Message{Value: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	var errors int
+	for _, r := range logger.records {
+		if len(r.args) < 4 || fmt.Sprint(r.args[:4]) != "[topic topic-1 partition 0]" {
+			t.Errorf("unexpected arguments logged with %q: %v", r.msg, r.args)
+		}
+		if r.level == LogLevelError {
+			errors++
+		}
+	}
+	if errors != 1 {
+		t.Errorf("expected 1 error to be logged but got %d in %+v", errors, logger.records)
+	}
+}
+
+func TestLogKV(t *testing.T) {
+	structured := &recordingLogger{}
+	logKV(newLogger(structured, nil, LogLevelWarn, "group", "group-1"), "joined group", "member", "member-1")
+
+	if len(structured.records) != 1 {
+		t.Fatalf("expected 1 record but got %+v", structured.records)
+	}
+	if r := structured.records[0]; r.level != LogLevelWarn || r.msg != "joined group" || fmt.Sprint(r.args) != "[group group-1 member member-1]" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+
+	var msgs []string
+	printf := LoggerFunc(func(msg string, args ...interface{}) { msgs = append(msgs, fmt.Sprintf(msg, args...)) })
+	logKV(newLogger(nil, printf, LogLevelInfo, "group", "group-1"), "joined group", "member", "member-1")
+
+	if fmt.Sprint(msgs) != "[joined group group=group-1 member=member-1]" {
+		t.Errorf("unexpected messages: %q", msgs)
+	}
+}
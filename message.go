@@ -23,6 +23,63 @@ type Message struct {
 	// If not set at the creation, Time will be automatically set when
 	// writing the message.
 	Time time.Time
+
+	// The pages that the key and value were leased from, when the message was
+	// read by a reader configured with PooledMessages.
+	leases [2]*leasedPage
+
+	// The identifier assigned to the message by writers with an interceptor,
+	// see WriteID.
+	writeID uint64
+}
+
+// WriteID returns an identifier unique to the message within the program,
+// which writers assign before passing messages to WriterInterceptor.OnWrite,
+// and which the messages passed to OnAck carry as well.  It is zero if the
+// message was not passed to a writer interceptor.
+func (msg Message) WriteID() uint64 { return msg.writeID }
+
+// Header returns the value of the last header of the message with the given
+// key, and whether the message had a header with this key.
+func (msg Message) Header(key string) ([]byte, bool) {
+	for i := len(msg.Headers) - 1; i >= 0; i-- {
+		if msg.Headers[i].Key == key {
+			return msg.Headers[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// SetHeader sets the value of the header with the given key, replacing all
+// the headers of the message with the same key.
+//
+// The headers are copied, so the slice of headers that the message had, which
+// may be shared with other messages, is not modified.
+func (msg *Message) SetHeader(key string, value []byte) {
+	headers := make([]Header, 0, len(msg.Headers)+1)
+	for _, h := range msg.Headers {
+		if h.Key != key {
+			headers = append(headers, h)
+		}
+	}
+	msg.Headers = append(headers, Header{Key: key, Value: value})
+}
+
+// DeleteHeader removes all the headers of the message with the given key.
+//
+// Like with SetHeader, the headers are copied when the message had headers
+// with this key.
+func (msg *Message) DeleteHeader(key string) {
+	if _, ok := msg.Header(key); !ok {
+		return
+	}
+	headers := make([]Header, 0, len(msg.Headers)-1)
+	for _, h := range msg.Headers {
+		if h.Key != key {
+			headers = append(headers, h)
+		}
+	}
+	msg.Headers = headers
 }
 
 func (msg Message) message(cw *crc32Writer) message {
@@ -21,6 +21,9 @@ type messageSetReader struct {
 	//
 	// This is used to detect truncation of the response.
 	lengthRemain int
+	// The header of the message set or record batch that the last message
+	// read belongs to.
+	last messagesHeader
 
 	decompressed bytes.Buffer
 }
@@ -128,6 +131,9 @@ func (r *messageSetReader) readMessage(min int64, key readBytesFunc, val readByt
 	if err = r.readHeader(); err != nil {
 		return
 	}
+	// The header is saved before reading the message, which may unwind the
+	// reader stack of compressed message sets.
+	header := r.header
 	switch r.header.magic {
 	case 0, 1:
 		offset, timestamp, headers, err = r.readMessageV1(min, key, val)
@@ -138,6 +144,9 @@ func (r *messageSetReader) readMessage(min int64, key readBytesFunc, val readByt
 	default:
 		err = r.header.badMagic()
 	}
+	if err == nil {
+		r.last = header
+	}
 	return
 }
 
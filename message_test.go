@@ -695,6 +695,41 @@ func TestMessageSize(t *testing.T) {
 
 }
 
+func TestMessageHeaders(t *testing.T) {
+	shared := []Header{
+		{Key: "a", Value: []byte("1")},
+		{Key: "b", Value: []byte("2")},
+		{Key: "a", Value: []byte("3")},
+	}
+	msg := Message{Headers: shared}
+
+	if v, ok := msg.Header("a"); !ok || string(v) != "3" {
+		t.Errorf("wrong value of header a: %q (%t)", v, ok)
+	}
+	if _, ok := msg.Header("c"); ok {
+		t.Error("found a header which the message does not have")
+	}
+
+	msg.SetHeader("a", []byte("4"))
+	msg.SetHeader("c", []byte("5"))
+	require.Equal(t, []Header{
+		{Key: "b", Value: []byte("2")},
+		{Key: "a", Value: []byte("4")},
+		{Key: "c", Value: []byte("5")},
+	}, msg.Headers)
+
+	msg.DeleteHeader("b")
+	msg.DeleteHeader("d")
+	require.Equal(t, []Header{
+		{Key: "a", Value: []byte("4")},
+		{Key: "c", Value: []byte("5")},
+	}, msg.Headers)
+
+	if string(shared[0].Value) != "1" || string(shared[2].Value) != "3" || len(shared) != 3 {
+		t.Errorf("the shared headers were modified: %+v", shared)
+	}
+}
+
 // https://stackoverflow.com/questions/43495745/how-to-generate-random-date-in-go-lang/43497333#43497333
 func randate() time.Time {
 	min := time.Date(1970, 1, 0, 0, 0, 0, 0, time.UTC).Unix()
@@ -0,0 +1,129 @@
+package kafka
+
+import (
+	"bufio"
+	"io"
+	"sync/atomic"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+// Release returns the memory of the key and value of the message to the buffer
+// pool, when the message was read by a Reader configured with PooledMessages.
+// It does nothing for other messages.
+//
+// The key and value of the message, and of all its copies, must not be used
+// after calling Release, and Release must be called only once per message read
+// from the reader. Messages which are never released are reclaimed by the
+// garbage collector instead of being reused.
+func (msg *Message) Release() {
+	if msg.leases == [2]*leasedPage{} {
+		return
+	}
+	for i, p := range msg.leases {
+		if p != nil {
+			p.unref()
+			msg.leases[i] = nil
+		}
+	}
+	msg.Key, msg.Value = nil, nil
+}
+
+// leasedPage is a page of the protocol buffer pool that the keys and values of
+// messages are leased from. The page returns to the pool when the messages
+// leasing its memory were released and the reader moved to the next page.
+type leasedPage struct {
+	buffer  []byte
+	release func()
+	refc    int32
+}
+
+func (p *leasedPage) ref() { atomic.AddInt32(&p.refc, 1) }
+
+func (p *leasedPage) unref() {
+	if atomic.AddInt32(&p.refc, -1) == 0 {
+		p.release()
+	}
+}
+
+// messageBuffers allocates the keys and values of the messages read by a
+// partition reader from pages of the protocol buffer pool, instead of
+// allocating memory for each message.
+//
+// The methods of messageBuffers are not safe to use concurrently, but the
+// messages may be released from any goroutine.
+type messageBuffers struct {
+	page     *leasedPage
+	offset   int
+	pageSize int
+}
+
+func newMessageBuffers(pooled bool) *messageBuffers {
+	if !pooled {
+		return nil
+	}
+	return &messageBuffers{}
+}
+
+// alloc returns n bytes of memory, and the page that they were leased from,
+// which is nil when the bytes are larger than a page and were allocated.
+func (b *messageBuffers) alloc(n int) ([]byte, *leasedPage) {
+	if b.pageSize != 0 && n > b.pageSize {
+		return make([]byte, n), nil
+	}
+
+	if b.page == nil || len(b.page.buffer)-b.offset < n {
+		b.close()
+		buffer, release := protocol.LeasePage()
+		b.pageSize = len(buffer)
+		if n > len(buffer) {
+			release()
+			return make([]byte, n), nil
+		}
+		b.page = &leasedPage{buffer: buffer, release: release, refc: 1}
+	}
+
+	// The capacity is limited so appending to the slice never overwrites the
+	// memory of the next message.
+	s := b.page.buffer[b.offset : b.offset+n : b.offset+n]
+	b.offset += n
+	b.page.ref()
+	return s, b.page
+}
+
+// close drops the reference to the current page, which returns to the pool
+// once the messages leasing its memory are released.
+func (b *messageBuffers) close() {
+	if b != nil && b.page != nil {
+		b.page.unref()
+		b.page, b.offset = nil, 0
+	}
+}
+
+// readLeasedBytes is like readNewBytes, but allocates the bytes from b and
+// records the page that they were leased from in *lease.
+func readLeasedBytes(r *bufio.Reader, sz int, n int, b *messageBuffers, lease **leasedPage) ([]byte, int, error) {
+	if b == nil || n <= 0 {
+		return readNewBytes(r, sz, n)
+	}
+
+	var err error
+	var shortRead bool
+
+	if sz < n {
+		n = sz
+		shortRead = true
+	}
+
+	s, page := b.alloc(n)
+	n, err = io.ReadFull(r, s)
+	s = s[:n]
+	sz -= n
+	*lease = page
+
+	if err == nil && shortRead {
+		err = errShortRead
+	}
+
+	return s, sz, err
+}
@@ -0,0 +1,54 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func TestMessageBuffers(t *testing.T) {
+	protocol.SetBufferPoolConfig(protocol.BufferPoolConfig{PageSize: 8, MaxRetainedPages: 1})
+	defer protocol.SetBufferPoolConfig(protocol.BufferPoolConfig{})
+
+	b := newMessageBuffers(true)
+	defer b.close()
+
+	var m1, m2, m3 Message
+	m1.Key, m1.leases[0] = b.alloc(3)
+	m1.Value, m1.leases[1] = b.alloc(4)
+	m2.Value, m2.leases[1] = b.alloc(5)
+
+	if m1.leases[0] == nil || m1.leases[0] != m1.leases[1] {
+		t.Fatal("the key and value of the first message must be leased from the same page")
+	}
+	if m2.leases[1] == nil || m2.leases[1] == m1.leases[0] {
+		t.Fatal("the value of the second message must be leased from a new page")
+	}
+	if cap(m1.Key) != 3 {
+		t.Errorf("the capacity of leased bytes must be limited to their length: %d", cap(m1.Key))
+	}
+
+	m3.Value, m3.leases[1] = b.alloc(9)
+	if m3.leases[1] != nil || len(m3.Value) != 9 {
+		t.Error("bytes larger than a page must be allocated")
+	}
+
+	first := &m1.leases[0].buffer[0]
+	m1.Release()
+	if m1.Key != nil || m1.Value != nil || m1.leases != [2]*leasedPage{} {
+		t.Error("the message must not reference the leased memory after being released")
+	}
+
+	// The first page returned to the pool, since the buffers moved to the
+	// second page.
+	buffer, release := protocol.LeasePage()
+	defer release()
+	if &buffer[0] != first {
+		t.Error("the page of the released message was not returned to the pool")
+	}
+
+	m3.Release()
+	if len(m3.Value) != 9 {
+		t.Error("releasing a message which does not lease memory must not modify it")
+	}
+}
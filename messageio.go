@@ -0,0 +1,41 @@
+package kafka
+
+import "context"
+
+// MessageReader is the interface implemented by Reader.
+//
+// Programs which depend on MessageReader instead of *Reader can substitute
+// the reader in tests, for example with the fake of the kafkatest package.
+type MessageReader interface {
+	// ReadMessage reads and returns the next message, committing its offset
+	// when the reader is part of a consumer group.
+	ReadMessage(ctx context.Context) (Message, error)
+
+	// FetchMessage reads and returns the next message, without committing
+	// its offset.
+	FetchMessage(ctx context.Context) (Message, error)
+
+	// CommitMessages commits the offsets of the messages.
+	CommitMessages(ctx context.Context, msgs ...Message) error
+
+	// Close closes the reader, after which ReadMessage and FetchMessage
+	// return io.EOF.
+	Close() error
+}
+
+// MessageWriter is the interface implemented by Writer.
+//
+// Programs which depend on MessageWriter instead of *Writer can substitute
+// the writer in tests, for example with the fake of the kafkatest package.
+type MessageWriter interface {
+	// WriteMessages writes a batch of messages.
+	WriteMessages(ctx context.Context, msgs ...Message) error
+
+	// Close flushes pending writes and closes the writer.
+	Close() error
+}
+
+var (
+	_ MessageReader = (*Reader)(nil)
+	_ MessageWriter = (*Writer)(nil)
+)
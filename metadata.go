@@ -77,6 +77,7 @@ func (c *Client) Metadata(ctx context.Context, req *MetadataRequest) (*MetadataR
 	for i, t := range res.Topics {
 		ret.Topics[i] = Topic{
 			Name:       t.Name,
+			ID:         t.TopicID,
 			Internal:   t.IsInternal,
 			Partitions: make([]Partition, len(t.Partitions)),
 			Error:      makeError(t.ErrorCode, ""),
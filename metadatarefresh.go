@@ -0,0 +1,128 @@
+package kafka
+
+import (
+	"time"
+
+	fetchAPI "github.com/segmentio/kafka-go/protocol/fetch"
+	"github.com/segmentio/kafka-go/protocol/listoffsets"
+	meta "github.com/segmentio/kafka-go/protocol/metadata"
+	produceAPI "github.com/segmentio/kafka-go/protocol/produce"
+)
+
+// MetadataRefreshConfig configures how a Transport refreshes the metadata of
+// the kafka clusters that it sends requests to, in addition to the periodic
+// refresh which happens at the interval configured by Transport.MetadataTTL.
+type MetadataRefreshConfig struct {
+	// Bounds of the backoff applied to retry refreshing the metadata after a
+	// failure. The delay starts at MinBackoff, doubles after each consecutive
+	// failure up to MaxBackoff, and is randomized to avoid synchronizing
+	// clients.
+	//
+	// When MinBackoff is zero, failed refreshes are retried at the next
+	// periodic refresh. MaxBackoff defaults to MetadataTTL.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// When true, the transport refreshes the metadata of the topics for which
+	// produce, fetch, or list offsets responses report one of the
+	// UnknownTopicOrPartition, NotLeaderForPartition, or LeaderNotAvailable
+	// errors. Only the metadata of those topics is requested, so the cached
+	// layout is corrected without waiting for the next periodic refresh.
+	RefreshTopicsOnError bool
+}
+
+// backoff returns the randomized delay before the next attempt to refresh the
+// metadata after the given number of consecutive failures, and false if the
+// retries wait for the next periodic refresh.
+func (c *MetadataRefreshConfig) backoff(failures int, metadataTTL time.Duration, random func(int64) int64) (time.Duration, bool) {
+	if c.MinBackoff <= 0 || failures <= 0 {
+		return 0, false
+	}
+
+	maxBackoff := c.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = metadataTTL
+	}
+
+	backoff := c.MinBackoff
+	for i := 1; i < failures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+
+	// Full jitter in the upper half of the range, which retains the
+	// exponential growth of the delays.
+	return backoff/2 + time.Duration(random(int64(backoff/2)+1)), true
+}
+
+// isMetadataError returns true if the error code reported for a topic
+// partition indicates that the cached metadata of the topic is stale.
+func isMetadataError(errorCode int16) bool {
+	switch Error(errorCode) {
+	case UnknownTopicOrPartition, NotLeaderForPartition, LeaderNotAvailable:
+		return true
+	}
+	return false
+}
+
+// staleTopics returns the names of the topics for which the response reported
+// errors indicating that the cached metadata is stale.
+func staleTopics(res Response) []string {
+	var topics []string
+
+	addTopic := func(topic string, errorCode int16) {
+		if isMetadataError(errorCode) && (len(topics) == 0 || topics[len(topics)-1] != topic) {
+			topics = append(topics, topic)
+		}
+	}
+
+	switch r := res.(type) {
+	case *produceAPI.Response:
+		for _, t := range r.Topics {
+			for _, p := range t.Partitions {
+				addTopic(t.Topic, p.ErrorCode)
+			}
+		}
+	case *fetchAPI.Response:
+		for _, t := range r.Topics {
+			for _, p := range t.Partitions {
+				addTopic(t.Topic, p.ErrorCode)
+			}
+		}
+	case *listoffsets.Response:
+		for _, t := range r.Topics {
+			for _, p := range t.Partitions {
+				addTopic(t.Topic, p.ErrorCode)
+			}
+		}
+	}
+
+	return topics
+}
+
+// mergeMetadataTopics returns a copy of the cached metadata where the topics
+// present in the response of a request for specific topics were replaced, and
+// the ones that the response reports as unknown were removed. The caller is
+// responsible for sorting the topics of the returned response.
+func mergeMetadataTopics(cached, res *meta.Response) *meta.Response {
+	merged := *res
+	merged.Topics = make([]meta.ResponseTopic, 0, len(cached.Topics)+len(res.Topics))
+
+	updated := make(map[string]struct{}, len(res.Topics))
+	for _, t := range res.Topics {
+		updated[t.Name] = struct{}{}
+		if t.ErrorCode != int16(UnknownTopicOrPartition) {
+			merged.Topics = append(merged.Topics, t)
+		}
+	}
+
+	for _, t := range cached.Topics {
+		if _, ok := updated[t.Name]; !ok {
+			merged.Topics = append(merged.Topics, t)
+		}
+	}
+
+	return &merged
+}
@@ -0,0 +1,189 @@
+package kafka
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+	fetchAPI "github.com/segmentio/kafka-go/protocol/fetch"
+	meta "github.com/segmentio/kafka-go/protocol/metadata"
+	produceAPI "github.com/segmentio/kafka-go/protocol/produce"
+)
+
+func TestMetadataRefreshBackoff(t *testing.T) {
+	config := MetadataRefreshConfig{
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: time.Second,
+	}
+	noJitter := func(int64) int64 { return 0 }
+	fullJitter := func(n int64) int64 { return n - 1 }
+
+	tests := []struct {
+		failures int
+		min, max time.Duration
+	}{
+		{failures: 1, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{failures: 2, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{failures: 4, min: 400 * time.Millisecond, max: 800 * time.Millisecond},
+		{failures: 10, min: 500 * time.Millisecond, max: time.Second},
+	}
+
+	for _, test := range tests {
+		min, _ := config.backoff(test.failures, 6*time.Second, noJitter)
+		max, _ := config.backoff(test.failures, 6*time.Second, fullJitter)
+		if min != test.min || max != test.max {
+			t.Errorf("wrong backoff after %d failures: want=[%s,%s] got=[%s,%s]", test.failures, test.min, test.max, min, max)
+		}
+	}
+
+	if _, ok := config.backoff(0, 6*time.Second, noJitter); ok {
+		t.Error("no backoff should be applied after successful refreshes")
+	}
+	if _, ok := (&MetadataRefreshConfig{}).backoff(1, 6*time.Second, noJitter); ok {
+		t.Error("no backoff should be applied when MinBackoff is zero")
+	}
+}
+
+func TestStaleTopics(t *testing.T) {
+	tests := []struct {
+		scenario string
+		response Response
+		topics   []string
+	}{
+		{
+			scenario: "produce responses with leadership errors",
+			response: &produceAPI.Response{
+				Topics: []produceAPI.ResponseTopic{
+					{Topic: "A", Partitions: []produceAPI.ResponsePartition{
+						{Partition: 0, ErrorCode: int16(NotLeaderForPartition)},
+						{Partition: 1, ErrorCode: int16(NotLeaderForPartition)},
+					}},
+					{Topic: "B", Partitions: []produceAPI.ResponsePartition{
+						{Partition: 0},
+					}},
+				},
+			},
+			topics: []string{"A"},
+		},
+		{
+			scenario: "fetch responses with unknown topics",
+			response: &fetchAPI.Response{
+				Topics: []fetchAPI.ResponseTopic{
+					{Topic: "A", Partitions: []fetchAPI.ResponsePartition{
+						{Partition: 0, ErrorCode: int16(OffsetOutOfRange)},
+					}},
+					{Topic: "B", Partitions: []fetchAPI.ResponsePartition{
+						{Partition: 0, ErrorCode: int16(UnknownTopicOrPartition)},
+					}},
+				},
+			},
+			topics: []string{"B"},
+		},
+		{
+			scenario: "other responses are ignored",
+			response: &meta.Response{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			if topics := staleTopics(test.response); !reflect.DeepEqual(topics, test.topics) {
+				t.Errorf("wrong stale topics: want=%q got=%q", test.topics, topics)
+			}
+		})
+	}
+}
+
+func TestRefreshTopicsOnFetchErrorByTopicID(t *testing.T) {
+	ready := make(event)
+	ready.trigger()
+
+	requests := make(chan connRequest, 1)
+	pool := &connPool{
+		refresh: MetadataRefreshConfig{RefreshTopicsOnError: true},
+		ready:   ready,
+		topics:  make(chan []string, 1),
+		conns:   map[int32]*connGroup{},
+	}
+	pool.setState(connPoolState{
+		layout: protocol.Cluster{
+			Brokers: map[int32]protocol.Broker{0: {ID: 0}},
+			Topics: map[string]protocol.Topic{
+				"A": {Name: "A", ID: UUID{15: 1}, Partitions: map[int32]protocol.Partition{0: {Leader: 0}}},
+			},
+		},
+	})
+	pool.conns[0] = &connGroup{pool: pool, broker: Broker{ID: 0}, idleConns: []*conn{{reqs: requests}}}
+
+	go func() {
+		r := <-requests
+		// Fetch v13 responses identify the topics by ID only.
+		r.res.resolve(&fetchAPI.Response{
+			Topics: []fetchAPI.ResponseTopic{
+				{TopicID: UUID{15: 1}, Partitions: []fetchAPI.ResponsePartition{
+					{Partition: 0, ErrorCode: int16(NotLeaderForPartition)},
+				}},
+			},
+		})
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := pool.roundTrip(ctx, &fetchAPI.Request{
+		Topics: []fetchAPI.RequestTopic{
+			{Topic: "A", Partitions: []fetchAPI.RequestPartition{{Partition: 0}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case topics := <-pool.topics:
+		if !reflect.DeepEqual(topics, []string{"A"}) {
+			t.Errorf("wrong topics to refresh: want=%q got=%q", []string{"A"}, topics)
+		}
+	default:
+		t.Error("no refresh of the topic was requested")
+	}
+}
+
+func TestMergeMetadataTopics(t *testing.T) {
+	cached := &meta.Response{
+		Brokers: []meta.ResponseBroker{{NodeID: 1}},
+		Topics: []meta.ResponseTopic{
+			{Name: "A", Partitions: []meta.ResponsePartition{{PartitionIndex: 0, LeaderID: 1}}},
+			{Name: "B", Partitions: []meta.ResponsePartition{{PartitionIndex: 0, LeaderID: 1}}},
+			{Name: "C", Partitions: []meta.ResponsePartition{{PartitionIndex: 0, LeaderID: 1}}},
+		},
+	}
+
+	res := &meta.Response{
+		Brokers: []meta.ResponseBroker{{NodeID: 1}, {NodeID: 2}},
+		Topics: []meta.ResponseTopic{
+			{Name: "B", Partitions: []meta.ResponsePartition{{PartitionIndex: 0, LeaderID: 2}}},
+			{Name: "C", ErrorCode: int16(UnknownTopicOrPartition)},
+		},
+	}
+
+	merged := mergeMetadataTopics(cached, res)
+	sortMetadataTopics(merged.Topics)
+
+	want := &meta.Response{
+		Brokers: res.Brokers,
+		Topics: []meta.ResponseTopic{
+			cached.Topics[0],
+			res.Topics[0],
+		},
+	}
+
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("wrong merged metadata:\nwant: %+v\ngot:  %+v", want, merged)
+	}
+	if len(cached.Topics) != 3 {
+		t.Error("the cached metadata was modified")
+	}
+}
@@ -0,0 +1,161 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// MetadataWatcher periodically polls the metadata of a kafka cluster and
+// invokes callbacks when topics appear or disappear, partitions are added to
+// topics, or partition leaders move.
+//
+// Kafka does not offer a way to subscribe to metadata changes, the watcher
+// detects them by comparing successive snapshots of the metadata; a change
+// which is reverted between two polls goes unnoticed. Note that the Transport
+// caches metadata for up to its MetadataTTL, which bounds how quickly changes
+// are observed regardless of the polling interval.
+//
+// The callbacks are invoked sequentially from the goroutine running the
+// watcher, and may be nil. On the first poll, all the topics are reported as
+// added so programs can build their initial state from the callbacks.
+type MetadataWatcher struct {
+	// The client used to retrieve the cluster metadata. The field is required.
+	Client *Client
+
+	// The list of topics to watch. When empty, all the topics of the cluster
+	// are watched.
+	Topics []string
+
+	// The interval between two polls of the cluster metadata.
+	//
+	// Default: 10s
+	Interval time.Duration
+
+	// Invoked with the topics which appeared in the cluster.
+	OnTopicAdded func(topic Topic)
+
+	// Invoked with the last known state of topics which disappeared from the
+	// cluster.
+	OnTopicRemoved func(topic Topic)
+
+	// Invoked with the partitions which were added to an existing topic.
+	OnPartitionsAdded func(topic Topic, partitions []Partition)
+
+	// Invoked when the leader of a partition changed. The partition carries
+	// the new leader.
+	OnLeaderChanged func(partition Partition, previousLeader Broker)
+
+	// Invoked when polling the cluster metadata failed; the watcher keeps
+	// running and retries on the next interval.
+	OnError func(err error)
+
+	topics map[string]Topic
+}
+
+// Run polls the cluster metadata until ctx is canceled, then returns the
+// context error.
+func (w *MetadataWatcher) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.poll(ctx); err != nil && ctx.Err() == nil && w.OnError != nil {
+			w.OnError(err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (w *MetadataWatcher) poll(ctx context.Context) error {
+	res, err := w.Client.Metadata(ctx, &MetadataRequest{
+		Topics: w.Topics,
+	})
+	if err != nil {
+		return err
+	}
+	w.update(res.Topics)
+	return nil
+}
+
+func (w *MetadataWatcher) update(topics []Topic) {
+	prev := w.topics
+	next := make(map[string]Topic, len(topics))
+
+	for _, t := range topics {
+		switch {
+		case t.Error == nil:
+			next[t.Name] = t
+		case errors.Is(t.Error, UnknownTopicOrPartition):
+			// The topic does not exist (anymore).
+		default:
+			// Transient errors (e.g. a leader election in progress) should
+			// not be reported as changes, keep the last known state.
+			if p, ok := prev[t.Name]; ok {
+				next[t.Name] = p
+			}
+		}
+	}
+
+	w.topics = next
+
+	for _, name := range sortedTopicNames(next) {
+		t := next[name]
+		p, ok := prev[name]
+
+		if !ok {
+			if w.OnTopicAdded != nil {
+				w.OnTopicAdded(t)
+			}
+			continue
+		}
+
+		leaders := make(map[int]Broker, len(p.Partitions))
+		for _, partition := range p.Partitions {
+			leaders[partition.ID] = partition.Leader
+		}
+
+		var added []Partition
+		for _, partition := range t.Partitions {
+			leader, ok := leaders[partition.ID]
+			switch {
+			case !ok:
+				added = append(added, partition)
+			case leader != partition.Leader:
+				if w.OnLeaderChanged != nil {
+					w.OnLeaderChanged(partition, leader)
+				}
+			}
+		}
+
+		if len(added) != 0 && w.OnPartitionsAdded != nil {
+			w.OnPartitionsAdded(t, added)
+		}
+	}
+
+	for _, name := range sortedTopicNames(prev) {
+		if _, ok := next[name]; !ok && w.OnTopicRemoved != nil {
+			w.OnTopicRemoved(prev[name])
+		}
+	}
+}
+
+func sortedTopicNames(topics map[string]Topic) []string {
+	names := make([]string, 0, len(topics))
+	for name := range topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
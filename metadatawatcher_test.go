@@ -0,0 +1,75 @@
+package kafka
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMetadataWatcherUpdate(t *testing.T) {
+	var events []string
+
+	w := &MetadataWatcher{
+		OnTopicAdded: func(topic Topic) {
+			events = append(events, fmt.Sprintf("added %s", topic.Name))
+		},
+		OnTopicRemoved: func(topic Topic) {
+			events = append(events, fmt.Sprintf("removed %s", topic.Name))
+		},
+		OnPartitionsAdded: func(topic Topic, partitions []Partition) {
+			for _, p := range partitions {
+				events = append(events, fmt.Sprintf("added %s/%d", topic.Name, p.ID))
+			}
+		},
+		OnLeaderChanged: func(partition Partition, previousLeader Broker) {
+			events = append(events, fmt.Sprintf("moved %s/%d from %d to %d", partition.Topic, partition.ID, previousLeader.ID, partition.Leader.ID))
+		},
+	}
+
+	broker1 := Broker{Host: "localhost", Port: 9092, ID: 1}
+	broker2 := Broker{Host: "localhost", Port: 9093, ID: 2}
+
+	makeTopic := func(name string, leaders ...Broker) Topic {
+		t := Topic{Name: name}
+		for i, leader := range leaders {
+			t.Partitions = append(t.Partitions, Partition{Topic: name, ID: i, Leader: leader})
+		}
+		return t
+	}
+
+	steps := []struct {
+		topics []Topic
+		events []string
+	}{
+		{
+			topics: []Topic{makeTopic("b", broker1), makeTopic("a", broker1, broker2)},
+			events: []string{"added a", "added b"},
+		},
+		{
+			topics: []Topic{makeTopic("a", broker1, broker2), makeTopic("b", broker1)},
+			events: nil,
+		},
+		{
+			topics: []Topic{makeTopic("a", broker2, broker2, broker1), makeTopic("b", broker1)},
+			events: []string{"moved a/0 from 1 to 2", "added a/2"},
+		},
+		{
+			// Transient errors are not reported as changes.
+			topics: []Topic{makeTopic("a", broker2, broker2, broker1), {Name: "b", Error: LeaderNotAvailable}},
+			events: nil,
+		},
+		{
+			topics: []Topic{{Name: "a", Error: UnknownTopicOrPartition}, makeTopic("b", broker1), makeTopic("c", broker2)},
+			events: []string{"added c", "removed a"},
+		},
+	}
+
+	for i, step := range steps {
+		events = nil
+		w.update(step.topics)
+
+		if !reflect.DeepEqual(events, step.events) {
+			t.Errorf("step %d: unexpected events\nwant: %q\ngot:  %q", i, step.events, events)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// MirrorConsistency is an enumeration of the guarantees that a MirrorWriter
+// offers when writing to the secondary cluster.
+type MirrorConsistency int
+
+const (
+	// MirrorBestEffort writes messages to the primary cluster synchronously,
+	// and to the secondary cluster asynchronously. Failures to write to the
+	// secondary cluster are reported to OnSecondaryError and do not fail the
+	// writes.
+	MirrorBestEffort MirrorConsistency = iota
+
+	// MirrorRequireBoth writes messages to both clusters concurrently, and
+	// fails the writes unless both clusters acknowledged the messages.
+	MirrorRequireBoth
+)
+
+// String satisfies the fmt.Stringer interface.
+func (c MirrorConsistency) String() string {
+	switch c {
+	case MirrorBestEffort:
+		return "best-effort"
+	case MirrorRequireBoth:
+		return "require-both"
+	default:
+		return fmt.Sprintf("MirrorConsistency(%d)", int(c))
+	}
+}
+
+// MirrorError is returned by MirrorWriter when writing messages to one of the
+// clusters failed. The error of a cluster is nil when writing to it succeeded.
+type MirrorError struct {
+	Primary   error
+	Secondary error
+}
+
+func (e *MirrorError) Error() string {
+	switch {
+	case e.Primary != nil && e.Secondary != nil:
+		return fmt.Sprintf("writing to the primary cluster: %v; writing to the secondary cluster: %v", e.Primary, e.Secondary)
+	case e.Primary != nil:
+		return fmt.Sprintf("writing to the primary cluster: %v", e.Primary)
+	default:
+		return fmt.Sprintf("writing to the secondary cluster: %v", e.Secondary)
+	}
+}
+
+// Unwrap returns the errors of the clusters which failed.
+func (e *MirrorError) Unwrap() []error {
+	errs := make([]error, 0, 2)
+	for _, err := range []error{e.Primary, e.Secondary} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// MirrorWriter writes each message to two kafka clusters, for active/passive
+// disaster recovery setups which do not run MirrorMaker.
+//
+// Each cluster is written to by its own Writer, which carries the address,
+// transport, topic, and batching configuration for that cluster. The offsets
+// and partitions of messages are not preserved between the clusters, the
+// secondary writer balances messages across partitions on its own.
+type MirrorWriter struct {
+	// The writer of the primary cluster.
+	Primary *Writer
+
+	// The writer of the secondary cluster.
+	Secondary *Writer
+
+	// The guarantees offered when writing to the secondary cluster.
+	//
+	// Default: MirrorBestEffort
+	Consistency MirrorConsistency
+
+	// Limit on the number of WriteMessages calls which may be writing to the
+	// secondary cluster asynchronously in best-effort mode. WriteMessages
+	// blocks when the limit is reached, which bounds the memory used when the
+	// secondary cluster is slower than the primary.
+	//
+	// Default: 100
+	MaxPending int
+
+	// An optional function called with the messages which failed to be
+	// written to the secondary cluster in best-effort mode.
+	OnSecondaryError func(msgs []Message, err error)
+
+	mutex   sync.Mutex
+	once    sync.Once
+	closed  bool
+	group   sync.WaitGroup
+	pending chan struct{}
+}
+
+// WriteMessages writes msgs to both clusters, with the guarantees configured
+// by the Consistency field.
+//
+// Unless both clusters are written to synchronously, the error returned is the
+// one of the primary writer. In require-both mode, the error is a *MirrorError
+// when one of the clusters failed, the messages may have been written to the
+// other one.
+func (m *MirrorWriter) WriteMessages(ctx context.Context, msgs ...Message) error {
+	if m.Primary == nil || m.Secondary == nil {
+		return errors.New("kafka.(*MirrorWriter).WriteMessages: both the primary and secondary writers are required")
+	}
+
+	switch m.Consistency {
+	case MirrorBestEffort:
+		return m.writeBestEffort(ctx, msgs)
+	case MirrorRequireBoth:
+		return m.writeRequireBoth(ctx, msgs)
+	default:
+		return fmt.Errorf("kafka.(*MirrorWriter).WriteMessages: invalid consistency: %v", m.Consistency)
+	}
+}
+
+func (m *MirrorWriter) writeBestEffort(ctx context.Context, msgs []Message) error {
+	if err := m.Primary.WriteMessages(ctx, msgs...); err != nil {
+		return err
+	}
+
+	if !m.enter(ctx) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return io.ErrClosedPipe
+	}
+
+	// The messages are copied because the program may reuse the slice once
+	// WriteMessages returned.
+	msgs = append([]Message(nil), msgs...)
+
+	go func() {
+		defer m.leave()
+		// The write must outlive the context of the call, which may be
+		// canceled as soon as WriteMessages returns.
+		if err := m.Secondary.WriteMessages(context.Background(), msgs...); err != nil && m.OnSecondaryError != nil {
+			m.OnSecondaryError(msgs, err)
+		}
+	}()
+
+	return nil
+}
+
+func (m *MirrorWriter) writeRequireBoth(ctx context.Context, msgs []Message) error {
+	var secondary error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		secondary = m.Secondary.WriteMessages(ctx, msgs...)
+	}()
+
+	primary := m.Primary.WriteMessages(ctx, msgs...)
+	<-done
+
+	if primary != nil || secondary != nil {
+		return &MirrorError{Primary: primary, Secondary: secondary}
+	}
+	return nil
+}
+
+// enter reserves a slot for an asynchronous write to the secondary cluster,
+// it returns false if the writer was closed or the context was canceled.
+func (m *MirrorWriter) enter(ctx context.Context) bool {
+	m.once.Do(func() {
+		n := m.MaxPending
+		if n <= 0 {
+			n = 100
+		}
+		m.pending = make(chan struct{}, n)
+	})
+
+	select {
+	case m.pending <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.closed {
+		<-m.pending
+		return false
+	}
+	m.group.Add(1)
+	return true
+}
+
+func (m *MirrorWriter) leave() {
+	<-m.pending
+	m.group.Done()
+}
+
+// Close waits for the asynchronous writes to the secondary cluster to
+// complete, then closes both writers.
+func (m *MirrorWriter) Close() error {
+	m.mutex.Lock()
+	m.closed = true
+	m.mutex.Unlock()
+	m.group.Wait()
+
+	var err error
+	for _, w := range []*Writer{m.Primary, m.Secondary} {
+		if w != nil {
+			if closeErr := w.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+	}
+	return err
+}
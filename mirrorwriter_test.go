@@ -0,0 +1,111 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func newMirrorCluster(t *testing.T, faults *kafkatest.Faults) (*kafkatest.Broker, *kafka.Writer) {
+	t.Helper()
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+
+	w := &kafka.Writer{
+		Addr:         b.Addr(),
+		Topic:        "topic-A",
+		BatchTimeout: time.Millisecond,
+		RequiredAcks: kafka.RequireOne,
+	}
+	if faults != nil {
+		transport := &kafka.Transport{Dial: faults.Dial}
+		t.Cleanup(transport.CloseIdleConnections)
+		w.Transport = transport
+	}
+	return b, w
+}
+
+func failingProduce() *kafkatest.Faults {
+	faults := &kafkatest.Faults{}
+	faults.Inject(kafkatest.Fault{
+		ApiKeys:   []protocol.ApiKey{protocol.Produce},
+		ErrorCode: kafka.TopicAuthorizationFailed,
+	})
+	return faults
+}
+
+func TestMirrorWriter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	primary, pw := newMirrorCluster(t, nil)
+	secondary, sw := newMirrorCluster(t, nil)
+
+	w := &kafka.MirrorWriter{Primary: pw, Secondary: sw}
+	for _, consistency := range []kafka.MirrorConsistency{kafka.MirrorBestEffort, kafka.MirrorRequireBoth} {
+		w.Consistency = consistency
+		if err := w.WriteMessages(ctx, kafka.Message{Value: []byte(consistency.String())}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Close waits for the asynchronous writes to the secondary cluster.
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, b := range map[string]*kafkatest.Broker{"primary": primary, "secondary": secondary} {
+		records := b.Records("topic-A", 0)
+		if len(records) != 2 {
+			t.Errorf("wrong number of records written to the %s cluster: %d", name, len(records))
+		}
+	}
+}
+
+func TestMirrorWriterSecondaryFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	primary, pw := newMirrorCluster(t, nil)
+	_, sw := newMirrorCluster(t, failingProduce())
+
+	failed := make(chan error, 1)
+	w := &kafka.MirrorWriter{
+		Primary:   pw,
+		Secondary: sw,
+		OnSecondaryError: func(msgs []kafka.Message, err error) {
+			failed <- err
+		},
+	}
+
+	if err := w.WriteMessages(ctx, kafka.Message{Value: []byte("A")}); err != nil {
+		t.Fatalf("best-effort writes must not fail when the secondary cluster fails: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-failed; !errors.Is(err, kafka.TopicAuthorizationFailed) {
+		t.Errorf("expected %v but got %v", kafka.TopicAuthorizationFailed, err)
+	}
+	if n := len(primary.Records("topic-A", 0)); n != 1 {
+		t.Errorf("wrong number of records written to the primary cluster: %d", n)
+	}
+
+	_, pw = newMirrorCluster(t, nil)
+	_, sw = newMirrorCluster(t, failingProduce())
+	w = &kafka.MirrorWriter{Primary: pw, Secondary: sw, Consistency: kafka.MirrorRequireBoth}
+	defer w.Close()
+
+	err := w.WriteMessages(ctx, kafka.Message{Value: []byte("A")})
+	var mirrorErr *kafka.MirrorError
+	if !errors.As(err, &mirrorErr) {
+		t.Fatalf("expected a *kafka.MirrorError but got %v", err)
+	}
+	if mirrorErr.Primary != nil || !errors.Is(mirrorErr.Secondary, kafka.TopicAuthorizationFailed) {
+		t.Errorf("wrong errors: %v", mirrorErr)
+	}
+}
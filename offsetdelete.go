@@ -0,0 +1,143 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/offsetdelete"
+)
+
+// OffsetDeleteRequest represents a request sent to a kafka broker to delete
+// the committed offsets of a consumer group for a set of topic partitions.
+type OffsetDeleteRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// ID of the consumer group to delete the offsets for.
+	GroupID string
+
+	// Set of topic partitions to delete the offsets for.
+	//
+	// When the list of partitions of a topic is empty, the offsets of all the
+	// partitions of the topic are deleted, which is useful when a group has
+	// stopped consuming a topic but needs to retain its offsets for others.
+	Topics map[string][]int
+}
+
+// OffsetDeleteResponse represents a response from a kafka broker to an offset
+// delete request.
+type OffsetDeleteResponse struct {
+	// An error that may have occurred while attempting to delete the offsets
+	// of the consumer group (e.g. if the group was still subscribed to one of
+	// the topics).
+	//
+	// The error contains both the kafka error code, and an error message
+	// returned by the kafka broker. Programs may use the standard errors.Is
+	// function to test the error against kafka error codes.
+	Error error
+
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// Set of topic partitions that the kafka broker has deleted offsets for.
+	Topics map[string][]OffsetDeletePartition
+}
+
+// OffsetDeletePartition represents the state of a single partition in
+// responses to deleting offsets.
+type OffsetDeletePartition struct {
+	// ID of the partition.
+	Partition int
+
+	// An error that may have occurred while attempting to delete the offset
+	// of this partition.
+	//
+	// The error contains both the kafka error code, and an error message
+	// returned by the kafka broker. Programs may use the standard errors.Is
+	// function to test the error against kafka error codes.
+	Error error
+}
+
+// OffsetDelete sends an offset delete request to a kafka broker and returns the
+// response.
+func (c *Client) OffsetDelete(ctx context.Context, req *OffsetDeleteRequest) (*OffsetDeleteResponse, error) {
+	topics := make([]offsetdelete.RequestTopic, 0, len(req.Topics))
+	lookup := make([]string, 0, len(req.Topics))
+
+	for topicName, partitions := range req.Topics {
+		if len(partitions) == 0 {
+			lookup = append(lookup, topicName)
+			continue
+		}
+		topics = append(topics, offsetdelete.RequestTopic{
+			Name:       topicName,
+			Partitions: makeOffsetDeletePartitions(partitions),
+		})
+	}
+
+	if len(lookup) != 0 {
+		meta, err := c.Metadata(ctx, &MetadataRequest{
+			Addr:   req.Addr,
+			Topics: lookup,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("kafka.(*Client).OffsetDelete: %w", err)
+		}
+
+		for _, t := range meta.Topics {
+			if t.Error != nil {
+				return nil, fmt.Errorf("kafka.(*Client).OffsetDelete: %s: %w", t.Name, t.Error)
+			}
+			partitions := make([]int, len(t.Partitions))
+			for i, p := range t.Partitions {
+				partitions[i] = p.ID
+			}
+			topics = append(topics, offsetdelete.RequestTopic{
+				Name:       t.Name,
+				Partitions: makeOffsetDeletePartitions(partitions),
+			})
+		}
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &offsetdelete.Request{
+		GroupID: req.GroupID,
+		Topics:  topics,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).OffsetDelete: %w", err)
+	}
+	r := m.(*offsetdelete.Response)
+
+	res := &OffsetDeleteResponse{
+		Error:    makeError(r.ErrorCode, ""),
+		Throttle: makeDuration(r.ThrottleTimeMs),
+		Topics:   make(map[string][]OffsetDeletePartition, len(r.Topics)),
+	}
+
+	for _, topic := range r.Topics {
+		partitions := make([]OffsetDeletePartition, len(topic.Partitions))
+
+		for i, p := range topic.Partitions {
+			partitions[i] = OffsetDeletePartition{
+				Partition: int(p.PartitionIndex),
+				Error:     makeError(p.ErrorCode, ""),
+			}
+		}
+
+		res.Topics[topic.Name] = partitions
+	}
+
+	return res, nil
+}
+
+func makeOffsetDeletePartitions(partitions []int) []offsetdelete.RequestPartition {
+	requestPartitions := make([]offsetdelete.RequestPartition, len(partitions))
+	for i, p := range partitions {
+		requestPartitions[i] = offsetdelete.RequestPartition{
+			PartitionIndex: int32(p),
+		}
+	}
+	return requestPartitions
+}
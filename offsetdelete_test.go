@@ -0,0 +1,100 @@
+package kafka
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	ktesting "github.com/segmentio/kafka-go/testing"
+)
+
+func TestClientOffsetDelete(t *testing.T) {
+	if !ktesting.KafkaIsAtLeast("2.4.0") {
+		return
+	}
+
+	topic := makeTopic()
+	client, shutdown := newLocalClientWithTopic(topic, 3)
+	defer shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	groupID := makeGroupID()
+
+	group, err := NewConsumerGroup(ConsumerGroupConfig{
+		ID:                groupID,
+		Topics:            []string{topic},
+		Brokers:           []string{"localhost:9092"},
+		HeartbeatInterval: 2 * time.Second,
+		RebalanceTimeout:  2 * time.Second,
+		RetentionTime:     time.Hour,
+		Logger:            log.New(os.Stdout, "cg-test: ", 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gen, err := group.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.OffsetCommit(ctx, &OffsetCommitRequest{
+		GroupID:      groupID,
+		GenerationID: int(gen.ID),
+		MemberID:     gen.MemberID,
+		Topics: map[string][]OffsetCommit{
+			topic: {
+				{Partition: 0, Offset: 10},
+				{Partition: 1, Offset: 10},
+				{Partition: 2, Offset: 10},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Offsets can only be deleted once the group is no longer subscribed to
+	// the topic.
+	group.Close()
+
+	odr, err := client.OffsetDelete(ctx, &OffsetDeleteRequest{
+		GroupID: groupID,
+		Topics:  map[string][]int{topic: nil},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if odr.Error != nil {
+		t.Fatal(odr.Error)
+	}
+
+	delresps := odr.Topics[topic]
+	if len(delresps) != 3 {
+		t.Fatalf("expected 3 offsetdeletepartition responses; got %d", len(delresps))
+	}
+
+	for _, r := range delresps {
+		if r.Error != nil {
+			t.Fatal(r.Error)
+		}
+	}
+
+	ofr, err := client.OffsetFetch(ctx, &OffsetFetchRequest{
+		GroupID: groupID,
+		Topics:  map[string][]int{topic: {0, 1, 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range ofr.Topics[topic] {
+		if r.CommittedOffset != -1 {
+			t.Fatalf("expected committed offset to be -1; got: %v for partition: %v", r.CommittedOffset, r.Partition)
+		}
+	}
+}
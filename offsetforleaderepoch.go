@@ -0,0 +1,184 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/offsetforleaderepoch"
+)
+
+// LeaderEpochRequest represents a request for the end offset of a leader epoch
+// on a single partition.
+type LeaderEpochRequest struct {
+	// The partition to get the end offset for.
+	Partition int
+
+	// The current leader epoch known by the program, which the broker uses to
+	// fence requests sent to stale leaders. Zero is the first leader epoch of
+	// a partition, set it to -1 when the current leader epoch is unknown to
+	// disable the check.
+	CurrentLeaderEpoch int
+
+	// The leader epoch to get the end offset of.
+	LeaderEpoch int
+}
+
+// LeaderEpochEndOffset carries the end offset of a leader epoch on a single
+// partition.
+type LeaderEpochEndOffset struct {
+	// The partition that the end offset applies to.
+	Partition int
+
+	// The leader epoch that the end offset belongs to. It is lower than the
+	// requested epoch when the leader did not know about the requested one,
+	// in which case the end offset is the one of the largest known epoch
+	// lower than the requested epoch.
+	LeaderEpoch int
+
+	// The end offset of the leader epoch, or -1 if it is unknown.
+	EndOffset int64
+
+	// An error that may have occurred while looking up the end offset.
+	Error error
+}
+
+// OffsetForLeaderEpochRequest represents a request sent to kafka brokers to
+// get the end offsets of leader epochs.
+type OffsetForLeaderEpochRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// A mapping of topic names to the list of partitions and leader epochs
+	// that the program wishes to get the end offsets for.
+	Topics map[string][]LeaderEpochRequest
+}
+
+// OffsetForLeaderEpochResponse represents a response from kafka brokers to a
+// OffsetForLeaderEpochRequest.
+type OffsetForLeaderEpochResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// Mappings of topic names to the end offsets of leader epochs on their
+	// partitions, there will be one entry for each topic in the request.
+	Topics map[string][]LeaderEpochEndOffset
+}
+
+// OffsetForLeaderEpoch sends a request to the partition leaders to get the end
+// offsets of leader epochs.
+//
+// Consumers use this API after a leader change to detect log truncation, for
+// example after an unclean leader election (see KIP-320): if the end offset of
+// the epoch of the last consumed record is lower than the position of the
+// consumer, the records after that offset were lost, and the consumer must
+// seek back to the end offset.
+func (c *Client) OffsetForLeaderEpoch(ctx context.Context, req *OffsetForLeaderEpochRequest) (*OffsetForLeaderEpochResponse, error) {
+	topics := make([]offsetforleaderepoch.RequestTopic, 0, len(req.Topics))
+
+	for topicName, requests := range req.Topics {
+		partitions := make([]offsetforleaderepoch.RequestPartition, len(requests))
+
+		for i, r := range requests {
+			partitions[i] = offsetforleaderepoch.RequestPartition{
+				Partition:          int32(r.Partition),
+				CurrentLeaderEpoch: int32(r.CurrentLeaderEpoch),
+				LeaderEpoch:        int32(r.LeaderEpoch),
+			}
+		}
+
+		topics = append(topics, offsetforleaderepoch.RequestTopic{
+			Topic:      topicName,
+			Partitions: partitions,
+		})
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &offsetforleaderepoch.Request{
+		ReplicaID: -1,
+		Topics:    topics,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).OffsetForLeaderEpoch: %w", err)
+	}
+
+	res := m.(*offsetforleaderepoch.Response)
+	ret := &OffsetForLeaderEpochResponse{
+		Throttle: makeDuration(res.ThrottleTimeMs),
+		Topics:   make(map[string][]LeaderEpochEndOffset, len(res.Topics)),
+	}
+
+	for _, t := range res.Topics {
+		partitions := make([]LeaderEpochEndOffset, len(t.Partitions))
+
+		for i, p := range t.Partitions {
+			partitions[i] = LeaderEpochEndOffset{
+				Partition:   int(p.Partition),
+				LeaderEpoch: int(p.LeaderEpoch),
+				EndOffset:   p.EndOffset,
+				Error:       makeError(p.ErrorCode, ""),
+			}
+		}
+
+		ret.Topics[t.Topic] = partitions
+	}
+
+	return ret, nil
+}
+
+type offsetForLeaderEpochRequestV1 struct {
+	Topics []offsetForLeaderEpochRequestTopicV1
+}
+
+func (r offsetForLeaderEpochRequestV1) size() int32 {
+	return sizeofArray(len(r.Topics), func(i int) int32 { return r.Topics[i].size() })
+}
+
+func (r offsetForLeaderEpochRequestV1) writeTo(wb *writeBuffer) {
+	wb.writeArray(len(r.Topics), func(i int) { r.Topics[i].writeTo(wb) })
+}
+
+type offsetForLeaderEpochRequestTopicV1 struct {
+	Topic      string
+	Partitions []offsetForLeaderEpochRequestPartitionV1
+}
+
+func (r offsetForLeaderEpochRequestTopicV1) size() int32 {
+	return sizeofString(r.Topic) +
+		sizeofArray(len(r.Partitions), func(i int) int32 { return r.Partitions[i].size() })
+}
+
+func (r offsetForLeaderEpochRequestTopicV1) writeTo(wb *writeBuffer) {
+	wb.writeString(r.Topic)
+	wb.writeArray(len(r.Partitions), func(i int) { r.Partitions[i].writeTo(wb) })
+}
+
+type offsetForLeaderEpochRequestPartitionV1 struct {
+	Partition   int32
+	LeaderEpoch int32
+}
+
+func (r offsetForLeaderEpochRequestPartitionV1) size() int32 {
+	return 4 + 4
+}
+
+func (r offsetForLeaderEpochRequestPartitionV1) writeTo(wb *writeBuffer) {
+	wb.writeInt32(r.Partition)
+	wb.writeInt32(r.LeaderEpoch)
+}
+
+type offsetForLeaderEpochResponseV1 struct {
+	Topics []offsetForLeaderEpochResponseTopicV1
+}
+
+type offsetForLeaderEpochResponseTopicV1 struct {
+	Topic      string
+	Partitions []offsetForLeaderEpochResponsePartitionV1
+}
+
+type offsetForLeaderEpochResponsePartitionV1 struct {
+	ErrorCode   int16
+	Partition   int32
+	LeaderEpoch int32
+	EndOffset   int64
+}
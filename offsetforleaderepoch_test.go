@@ -0,0 +1,116 @@
+package kafka
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/offsetforleaderepoch"
+	ktesting "github.com/segmentio/kafka-go/testing"
+)
+
+func TestClientOffsetForLeaderEpoch(t *testing.T) {
+	if !ktesting.KafkaIsAtLeast("2.1.0") {
+		return
+	}
+
+	client, topic, shutdown := newLocalClientAndTopic()
+	defer shutdown()
+
+	now := time.Now()
+
+	_, err := client.Produce(context.Background(), &ProduceRequest{
+		Topic:        topic,
+		Partition:    0,
+		RequiredAcks: -1,
+		Records: NewRecordReader(
+			Record{Time: now, Value: NewBytes([]byte(`hello-1`))},
+			Record{Time: now, Value: NewBytes([]byte(`hello-2`))},
+			Record{Time: now, Value: NewBytes([]byte(`hello-3`))},
+		),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.OffsetForLeaderEpoch(context.Background(), &OffsetForLeaderEpochRequest{
+		Topics: map[string][]LeaderEpochRequest{
+			topic: {{Partition: 0, CurrentLeaderEpoch: -1, LeaderEpoch: 0}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	partitions := res.Topics[topic]
+	if len(partitions) != 1 {
+		t.Fatal("invalid number of partitions found in the response:", len(partitions))
+	}
+
+	p := partitions[0]
+	if p.Error != nil {
+		t.Fatal(p.Error)
+	}
+	if p.LeaderEpoch != 0 || p.EndOffset != 3 {
+		t.Errorf("wrong end offset of the leader epoch: epoch=%d offset=%d", p.LeaderEpoch, p.EndOffset)
+	}
+}
+
+func TestClientOffsetForLeaderEpochCurrentLeaderEpoch(t *testing.T) {
+	var partitions []offsetforleaderepoch.RequestPartition
+
+	client := &Client{
+		Addr: TCP("mock"),
+		Transport: roundTripperFunc(func(ctx context.Context, addr net.Addr, req Request) (Response, error) {
+			partitions = req.(*offsetforleaderepoch.Request).Topics[0].Partitions
+			return &offsetforleaderepoch.Response{}, nil
+		}),
+	}
+
+	_, err := client.OffsetForLeaderEpoch(context.Background(), &OffsetForLeaderEpochRequest{
+		Topics: map[string][]LeaderEpochRequest{
+			"topic-A": {
+				{Partition: 0, CurrentLeaderEpoch: 0, LeaderEpoch: 0},
+				{Partition: 1, CurrentLeaderEpoch: -1, LeaderEpoch: 0},
+				{Partition: 2, CurrentLeaderEpoch: 3, LeaderEpoch: 2},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []int32{0, -1, 3} {
+		if got := partitions[i].CurrentLeaderEpoch; got != want {
+			t.Errorf("wrong current leader epoch of partition %d: want=%d got=%d", i, want, got)
+		}
+	}
+}
+
+func TestConnReadOffsetForLeaderEpoch(t *testing.T) {
+	if !ktesting.KafkaIsAtLeast("2.0.0") {
+		return
+	}
+
+	client, topic, shutdown := newLocalClientAndTopic()
+	defer shutdown()
+
+	produceRecords(t, 10, client.Addr, topic, nil)
+
+	conn, err := DialLeader(context.Background(), "tcp", "localhost:9092", topic, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	epoch, offset, err := conn.ReadOffsetForLeaderEpoch(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epoch != 0 || offset != 10 {
+		t.Errorf("wrong end offset of the leader epoch: epoch=%d offset=%d", epoch, offset)
+	}
+}
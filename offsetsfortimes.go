@@ -0,0 +1,85 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/listoffsets"
+)
+
+// TopicPartition identifies a partition of a kafka topic.
+type TopicPartition struct {
+	Topic     string
+	Partition int
+}
+
+// OffsetForTime is the result of looking up the offset of a partition at a
+// point in time.
+type OffsetForTime struct {
+	// The earliest offset of the partition whose timestamp is greater than or
+	// equal to the requested time, or -1 if no such record exists (in which
+	// case the next offset of the partition will be the first one matching).
+	Offset int64
+
+	// The leader epoch of the record at Offset, or -1 if unknown.
+	//
+	// This field requires the kafka broker to support the ListOffsets API in
+	// version 4 or above (otherwise the value is zero).
+	LeaderEpoch int
+
+	// Error is set to a non-nil value if the offset could not be looked up.
+	//
+	// The error contains the kafka error code. Programs may use the standard
+	// errors.Is function to test the error against kafka error codes.
+	Error error
+}
+
+// OffsetsForTimes looks up the offsets of the given partitions at the given
+// points in time.
+//
+// The lookups are batched into one ListOffsets request per partition leader,
+// and sent to the cluster that the client is configured with.
+func (c *Client) OffsetsForTimes(ctx context.Context, times map[TopicPartition]time.Time) (map[TopicPartition]OffsetForTime, error) {
+	topics := make(map[string][]listoffsets.RequestPartition)
+
+	for tp, t := range times {
+		topics[tp.Topic] = append(topics[tp.Topic], listoffsets.RequestPartition{
+			Partition:          int32(tp.Partition),
+			CurrentLeaderEpoch: -1,
+			Timestamp:          timestamp(t),
+		})
+	}
+
+	req := &listoffsets.Request{
+		ReplicaID: -1,
+		Topics:    make([]listoffsets.RequestTopic, 0, len(topics)),
+	}
+
+	for topic, partitions := range topics {
+		req.Topics = append(req.Topics, listoffsets.RequestTopic{
+			Topic:      topic,
+			Partitions: partitions,
+		})
+	}
+
+	m, err := c.roundTrip(ctx, nil, req)
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).OffsetsForTimes: %w", err)
+	}
+
+	res := m.(*listoffsets.Response)
+	ret := make(map[TopicPartition]OffsetForTime, len(times))
+
+	for _, t := range res.Topics {
+		for _, p := range t.Partitions {
+			ret[TopicPartition{Topic: t.Topic, Partition: int(p.Partition)}] = OffsetForTime{
+				Offset:      p.Offset,
+				LeaderEpoch: int(p.LeaderEpoch),
+				Error:       makeError(p.ErrorCode, ""),
+			}
+		}
+	}
+
+	return ret, nil
+}
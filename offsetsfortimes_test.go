@@ -0,0 +1,46 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestClientOffsetsForTimes(t *testing.T) {
+	client, topic, shutdown := newLocalClientAndTopic()
+	defer shutdown()
+
+	now := time.Now()
+
+	_, err := client.Produce(context.Background(), &ProduceRequest{
+		Topic:        topic,
+		Partition:    0,
+		RequiredAcks: -1,
+		Records: NewRecordReader(
+			Record{Time: now.Add(-2 * time.Minute), Value: NewBytes([]byte(`0`))},
+			Record{Time: now.Add(-1 * time.Minute), Value: NewBytes([]byte(`1`))},
+			Record{Time: now, Value: NewBytes([]byte(`2`))},
+		),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offsets, err := client.OffsetsForTimes(context.Background(), map[TopicPartition]time.Time{
+		{Topic: topic, Partition: 0}: now.Add(-90 * time.Second),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offset, ok := offsets[TopicPartition{Topic: topic, Partition: 0}]
+	if !ok {
+		t.Fatalf("missing offset for partition 0: %+v", offsets)
+	}
+	if offset.Error != nil {
+		t.Fatal(offset.Error)
+	}
+	if offset.Offset != 1 {
+		t.Errorf("expected offset 1, got %d", offset.Offset)
+	}
+}
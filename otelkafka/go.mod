@@ -0,0 +1,22 @@
+module github.com/segmentio/kafka-go/otelkafka
+
+go 1.20
+
+require (
+	github.com/segmentio/kafka-go v0.4.47
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/sdk v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/klauspost/compress v1.14.2 // indirect
+	github.com/pierrec/lz4/v4 v4.1.14 // indirect
+	go.opentelemetry.io/otel/metric v1.24.0 // indirect
+	golang.org/x/crypto v0.0.0-20190506204251-e1dfcc566284 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+)
+
+replace github.com/segmentio/kafka-go => ../
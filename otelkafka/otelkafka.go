@@ -0,0 +1,291 @@
+// Package otelkafka instruments kafka readers and writers with OpenTelemetry
+// traces.
+//
+// The package provides interceptors which create producer spans for the
+// messages written by a kafka.Writer and consumer spans for the messages
+// returned by a kafka.Reader.  The trace context is propagated from producers
+// to consumers in the headers of messages, using the W3C traceparent format
+// by default.
+//
+//	w := &kafka.Writer{
+//		Addr:        kafka.TCP("localhost:9092"),
+//		Topic:       "topic-A",
+//		Interceptor: otelkafka.NewWriterInterceptor(),
+//	}
+//
+//	r := kafka.NewReader(kafka.ReaderConfig{
+//		Brokers:     []string{"localhost:9092"},
+//		GroupID:     "consumer-group-id",
+//		Topic:       "topic-A",
+//		Interceptor: otelkafka.NewReaderInterceptor(otelkafka.WithConsumerGroup("consumer-group-id")),
+//	})
+//
+// The package lives in its own module so the core kafka-go module does not
+// depend on OpenTelemetry.
+package otelkafka
+
+import (
+	"context"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/segmentio/kafka-go/otelkafka"
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+	consumerGroup  string
+	messageKeys    bool
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		tracerProvider: otel.GetTracerProvider(),
+		propagator:     propagation.TraceContext{},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+func (c *config) tracer() trace.Tracer {
+	return c.tracerProvider.Tracer(instrumentationName)
+}
+
+// Option configures the interceptors created by NewWriterInterceptor and
+// NewReaderInterceptor.
+type Option func(*config)
+
+// WithTracerProvider sets the provider of the tracer creating spans.  The
+// global tracer provider is used by default.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) { c.tracerProvider = provider }
+}
+
+// WithPropagator sets the propagator used to write and read the trace context
+// in the headers of messages.  The W3C trace context propagator is used by
+// default.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) { c.propagator = propagator }
+}
+
+// WithConsumerGroup sets the name of the consumer group recorded on the spans
+// of a reader interceptor.
+func WithConsumerGroup(groupID string) Option {
+	return func(c *config) { c.consumerGroup = groupID }
+}
+
+// WithMessageKeys records the keys of messages as attributes of the spans.
+// Keys are not recorded by default since they may contain sensitive data.
+func WithMessageKeys() Option {
+	return func(c *config) { c.messageKeys = true }
+}
+
+// WriterInterceptor is an implementation of the kafka.WriterInterceptor
+// interface creating a producer span for each message written.
+//
+// Spans start when the message is passed to WriteMessages and end when the
+// batch that the message belongs to was written to kafka.
+type WriterInterceptor struct {
+	tracer      trace.Tracer
+	propagator  propagation.TextMapPropagator
+	messageKeys bool
+
+	// spans are keyed by the WriteID of messages.
+	mutex sync.Mutex
+	spans map[uint64]trace.Span
+}
+
+// NewWriterInterceptor returns a new interceptor to instrument writers with.
+func NewWriterInterceptor(opts ...Option) *WriterInterceptor {
+	c := newConfig(opts)
+	return &WriterInterceptor{
+		tracer:      c.tracer(),
+		propagator:  c.propagator,
+		messageKeys: c.messageKeys,
+		spans:       make(map[uint64]trace.Span),
+	}
+}
+
+// OnWrite satisfies the kafka.WriterInterceptor interface.
+func (w *WriterInterceptor) OnWrite(ctx context.Context, msg *kafka.Message) {
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystemKafka,
+		semconv.MessagingOperationPublish,
+		semconv.MessagingMessageBodySize(len(msg.Value)),
+	}
+	if msg.Topic != "" {
+		attrs = append(attrs, semconv.MessagingDestinationName(msg.Topic))
+	}
+	if w.messageKeys && msg.Key != nil {
+		attrs = append(attrs, semconv.MessagingKafkaMessageKey(string(msg.Key)))
+	}
+	if msg.Value == nil {
+		attrs = append(attrs, semconv.MessagingKafkaMessageTombstone(true))
+	}
+
+	ctx, span := w.tracer.Start(ctx, spanName(msg.Topic, "publish"),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attrs...),
+	)
+	w.propagator.Inject(ctx, headerCarrier{msg})
+
+	if !span.SpanContext().IsValid() || msg.WriteID() == 0 {
+		span.End()
+		return
+	}
+
+	w.mutex.Lock()
+	w.spans[msg.WriteID()] = span
+	w.mutex.Unlock()
+}
+
+// OnAck satisfies the kafka.WriterInterceptor interface.
+func (w *WriterInterceptor) OnAck(msgs []kafka.Message, err error) {
+	for i := range msgs {
+		msg := &msgs[i]
+		id := msg.WriteID()
+
+		w.mutex.Lock()
+		span, ok := w.spans[id]
+		delete(w.spans, id)
+		w.mutex.Unlock()
+
+		if !ok {
+			continue
+		}
+
+		span.SetName(spanName(msg.Topic, "publish"))
+		span.SetAttributes(
+			semconv.MessagingDestinationName(msg.Topic),
+			semconv.MessagingKafkaDestinationPartition(msg.Partition),
+		)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetAttributes(semconv.MessagingKafkaMessageOffset(int(msg.Offset)))
+		}
+		span.End()
+	}
+}
+
+// ReaderInterceptor is an implementation of the kafka.ReaderInterceptor
+// interface creating a consumer span for each message returned by a reader.
+//
+// The spans are children of the producer spans of messages, when the trace
+// context was found in their headers.  Programs which want to continue the
+// trace when processing messages can use Extract.
+type ReaderInterceptor struct {
+	tracer        trace.Tracer
+	propagator    propagation.TextMapPropagator
+	consumerGroup string
+	messageKeys   bool
+}
+
+// NewReaderInterceptor returns a new interceptor to instrument readers with.
+func NewReaderInterceptor(opts ...Option) *ReaderInterceptor {
+	c := newConfig(opts)
+	return &ReaderInterceptor{
+		tracer:        c.tracer(),
+		propagator:    c.propagator,
+		consumerGroup: c.consumerGroup,
+		messageKeys:   c.messageKeys,
+	}
+}
+
+// OnFetch satisfies the kafka.ReaderInterceptor interface.
+func (r *ReaderInterceptor) OnFetch(ctx context.Context, msg kafka.Message) {
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystemKafka,
+		semconv.MessagingOperationReceive,
+		semconv.MessagingDestinationName(msg.Topic),
+		semconv.MessagingKafkaDestinationPartition(msg.Partition),
+		semconv.MessagingKafkaMessageOffset(int(msg.Offset)),
+		semconv.MessagingMessageBodySize(len(msg.Value)),
+	}
+	if r.messageKeys && msg.Key != nil {
+		attrs = append(attrs, semconv.MessagingKafkaMessageKey(string(msg.Key)))
+	}
+	if r.consumerGroup != "" {
+		attrs = append(attrs, semconv.MessagingKafkaConsumerGroup(r.consumerGroup))
+	}
+
+	ctx = r.propagator.Extract(ctx, headerCarrier{&msg})
+	_, span := r.tracer.Start(ctx, spanName(msg.Topic, "receive"),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+	)
+	span.End()
+}
+
+// Extract returns a copy of ctx carrying the trace context found in the
+// headers of msg, using the W3C trace context format.
+func Extract(ctx context.Context, msg kafka.Message) context.Context {
+	return propagation.TraceContext{}.Extract(ctx, headerCarrier{&msg})
+}
+
+// Propagator returns a kafka.ContextPropagator using p, which programs can set
+// as kafka.DefaultPropagator to propagate the trace context with
+// kafka.InjectContext and kafka.ExtractContext:
+//
+//	kafka.DefaultPropagator = otelkafka.Propagator(otel.GetTextMapPropagator())
+func Propagator(p propagation.TextMapPropagator) kafka.ContextPropagator {
+	return contextPropagator{p}
+}
+
+type contextPropagator struct {
+	propagator propagation.TextMapPropagator
+}
+
+func (p contextPropagator) Inject(ctx context.Context, msg *kafka.Message) {
+	p.propagator.Inject(ctx, headerCarrier{msg})
+}
+
+func (p contextPropagator) Extract(ctx context.Context, msg kafka.Message) context.Context {
+	return p.propagator.Extract(ctx, headerCarrier{&msg})
+}
+
+func spanName(topic, operation string) string {
+	if topic == "" {
+		return operation
+	}
+	return topic + " " + operation
+}
+
+// headerCarrier adapts the headers of a message to the
+// propagation.TextMapCarrier interface.
+type headerCarrier struct {
+	msg *kafka.Message
+}
+
+func (c headerCarrier) Get(key string) string {
+	value, _ := c.msg.Header(key)
+	return string(value)
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c.msg.SetHeader(key, []byte(value))
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, len(c.msg.Headers))
+	for i, h := range c.msg.Headers {
+		keys[i] = h.Key
+	}
+	return keys
+}
+
+var (
+	_ kafka.WriterInterceptor = (*WriterInterceptor)(nil)
+	_ kafka.ReaderInterceptor = (*ReaderInterceptor)(nil)
+)
@@ -0,0 +1,183 @@
+package otelkafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTracerProvider() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), recorder
+}
+
+func hasAttribute(span sdktrace.ReadOnlySpan, attr attribute.KeyValue) bool {
+	for _, a := range span.Attributes() {
+		if a == attr {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWriterInterceptor(t *testing.T) {
+	provider, recorder := newTracerProvider()
+	interceptor := NewWriterInterceptor(WithTracerProvider(provider), WithMessageKeys())
+
+	broker := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-1"})
+	w := &kafka.Writer{
+		Addr:         broker.Addr(),
+		Topic:        "topic-1",
+		BatchTimeout: time.Millisecond,
+		BatchBytes:   1024,
+		RequiredAcks: kafka.RequireOne,
+		Interceptor:  interceptor,
+	}
+	defer w.Close()
+
+	original := []kafka.Header{{Key: "A", Value: []byte("1")}}
+	msgs := []kafka.Message{
+		{Key: []byte("key-1"), Value: []byte("hello"), Headers: original},
+		{Key: []byte("key-1"), Value: []byte("world")},
+	}
+	if err := w.WriteMessages(context.Background(), msgs...); err != nil {
+		t.Fatal(err)
+	}
+	if len(original) != 1 || len(msgs[0].Headers) != 1 {
+		t.Fatalf("the interceptor modified the messages of the program: %+v, %+v", original, msgs[0].Headers)
+	}
+
+	// the message is too large to be batched, it is acknowledged with the
+	// error returned by WriteMessages.
+	if err := w.WriteMessages(context.Background(), kafka.Message{Value: make([]byte, 2048)}); err == nil {
+		t.Fatal("expected an error writing a message larger than the batch")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans but got %d", len(spans))
+	}
+
+	for i, span := range spans[:2] {
+		if span.Name() != "topic-1 publish" || span.SpanKind() != trace.SpanKindProducer {
+			t.Errorf("unexpected span: %s (%s)", span.Name(), span.SpanKind())
+		}
+		for _, attr := range []attribute.KeyValue{
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationPublish,
+			semconv.MessagingDestinationName("topic-1"),
+			semconv.MessagingKafkaDestinationPartition(0),
+			semconv.MessagingKafkaMessageOffset(i),
+			semconv.MessagingKafkaMessageKey("key-1"),
+		} {
+			if !hasAttribute(span, attr) {
+				t.Errorf("missing attribute %s=%s", attr.Key, attr.Value.Emit())
+			}
+		}
+	}
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: []string{broker.Addr().String()},
+		Topic:   "topic-1",
+		MaxWait: 10 * time.Millisecond,
+	})
+	defer r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	msg, err := r.ReadMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc := trace.SpanContextFromContext(Extract(context.Background(), msg)); sc.SpanID() != spans[0].SpanContext().SpanID() {
+		t.Errorf("the message header does not carry the span context: %v", sc)
+	}
+
+	if spans[2].Status().Code != codes.Error {
+		t.Errorf("expected the failed span to have an error status but got %v", spans[2].Status())
+	}
+
+	interceptor.mutex.Lock()
+	defer interceptor.mutex.Unlock()
+	if len(interceptor.spans) != 0 {
+		t.Errorf("%d spans were not ended", len(interceptor.spans))
+	}
+}
+
+func TestInterceptorMessageKeys(t *testing.T) {
+	provider, recorder := newTracerProvider()
+	reader := NewReaderInterceptor(WithTracerProvider(provider))
+
+	reader.OnFetch(context.Background(), kafka.Message{Topic: "topic-1", Key: []byte("key-1")})
+
+	for _, attr := range recorder.Ended()[0].Attributes() {
+		if attr.Key == semconv.MessagingKafkaMessageKeyKey {
+			t.Errorf("the message key was recorded without WithMessageKeys: %s", attr.Value.Emit())
+		}
+	}
+}
+
+func TestReaderInterceptor(t *testing.T) {
+	provider, recorder := newTracerProvider()
+	writer := NewWriterInterceptor(WithTracerProvider(provider))
+	reader := NewReaderInterceptor(WithTracerProvider(provider), WithConsumerGroup("group-1"))
+
+	msg := kafka.Message{Value: []byte("hello")}
+	writer.OnWrite(context.Background(), &msg)
+	msg.Topic, msg.Partition, msg.Offset = "topic-1", 1, 7
+	writer.OnAck([]kafka.Message{msg}, nil)
+
+	reader.OnFetch(context.Background(), msg)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans but got %d", len(spans))
+	}
+	producer, consumer := spans[0], spans[1]
+
+	if consumer.Name() != "topic-1 receive" || consumer.SpanKind() != trace.SpanKindConsumer {
+		t.Errorf("unexpected span: %s (%s)", consumer.Name(), consumer.SpanKind())
+	}
+	if consumer.Parent().SpanID() != producer.SpanContext().SpanID() {
+		t.Error("the consumer span is not a child of the producer span")
+	}
+	for _, attr := range []attribute.KeyValue{
+		semconv.MessagingSystemKafka,
+		semconv.MessagingOperationReceive,
+		semconv.MessagingDestinationName("topic-1"),
+		semconv.MessagingKafkaDestinationPartition(1),
+		semconv.MessagingKafkaMessageOffset(7),
+		semconv.MessagingKafkaConsumerGroup("group-1"),
+	} {
+		if !hasAttribute(consumer, attr) {
+			t.Errorf("missing attribute %s=%s", attr.Key, attr.Value.Emit())
+		}
+	}
+}
+
+func TestPropagator(t *testing.T) {
+	provider, _ := newTracerProvider()
+	ctx, span := provider.Tracer("test").Start(context.Background(), "process")
+	defer span.End()
+
+	p := Propagator(propagation.TraceContext{})
+
+	var msg kafka.Message
+	p.Inject(ctx, &msg)
+
+	extracted := trace.SpanContextFromContext(p.Extract(context.Background(), msg))
+	if extracted.TraceID() != span.SpanContext().TraceID() || extracted.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("the trace context was not propagated: %v", extracted)
+	}
+}
@@ -0,0 +1,58 @@
+package kafka_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func TestReaderPooledMessages(t *testing.T) {
+	// Small pages exercise messages spanning multiple pages, and pages being
+	// reused while the reader holds others.
+	protocol.SetBufferPoolConfig(protocol.BufferPoolConfig{PageSize: 64, MaxRetainedPages: 4})
+	defer protocol.SetBufferPoolConfig(protocol.BufferPoolConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+
+	const count = 100
+	for i := 0; i < count; i++ {
+		record := kafkatest.Record{
+			Key:   []byte(fmt.Sprintf("key-%d", i)),
+			Value: []byte(fmt.Sprintf("value-%0*d", i, i)),
+		}
+		if _, err := b.Append("topic-A", 0, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        []string{b.Addr().String()},
+		Topic:          "topic-A",
+		MaxWait:        10 * time.Millisecond,
+		PooledMessages: true,
+	})
+	defer r.Close()
+
+	for i := 0; i < count; i++ {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, value := fmt.Sprintf("key-%d", i), fmt.Sprintf("value-%0*d", i, i)
+		if string(m.Key) != key || string(m.Value) != value {
+			t.Fatalf("wrong message at offset %d: %q=%q", m.Offset, m.Key, m.Value)
+		}
+		m.Release()
+		if m.Key != nil || m.Value != nil {
+			t.Fatal("the key and value of released messages must be cleared")
+		}
+	}
+}
@@ -88,12 +88,42 @@ type ProduceRequest struct {
 	// a transaction.
 	TransactionalID string
 
+	// The idempotent or transactional producer session returned by
+	// InitProducerID, and the sequence number of the first record, which the
+	// broker uses to detect duplicates. When both Producer and TransactionalID
+	// are set, the records are written as part of the transaction.
+	Producer     *ProducerSession
+	BaseSequence int
+
 	// The sequence of records to produce to the topic partition.
 	Records RecordReader
 
 	// An optional compression algorithm to apply to the batch of records sent
 	// to the kafka broker.
 	Compression Compression
+
+	// Optional records to produce to multiple topic partitions in a single
+	// round trip, by topic name. When set, the Topic, Partition, BaseSequence,
+	// and Records fields are ignored, and the results are reported for each
+	// partition in the Topics field of the response.
+	//
+	// All partitions must be led by the same broker, programs can group them
+	// by leader with the Metadata API.
+	Topics map[string][]ProducePartition
+}
+
+// ProducePartition represents the records produced to a partition by a request
+// with multiple topic partitions.
+type ProducePartition struct {
+	// The partition to produce the records to.
+	Partition int
+
+	// The sequence number of the first record, used when the request has a
+	// Producer session.
+	BaseSequence int
+
+	// The sequence of records to produce to the topic partition.
+	Records RecordReader
 }
 
 // ProduceResponse represents a response from a kafka broker to a produce
@@ -133,6 +163,22 @@ type ProduceResponse struct {
 	// This field will always be empty if the kafka broker did no support the
 	// Produce API in version 8 or above.
 	RecordErrors map[int]error
+
+	// Results for each partition, by topic name, when the request had
+	// multiple topic partitions.
+	Topics map[string][]ProducePartitionResponse
+}
+
+// ProducePartitionResponse represents the result of producing records to a
+// partition, reported when the request had multiple topic partitions. The
+// fields have the same meaning as the fields of ProduceResponse.
+type ProducePartitionResponse struct {
+	Partition      int
+	Error          error
+	BaseOffset     int64
+	LogAppendTime  time.Time
+	LogStartOffset int64
+	RecordErrors   map[int]error
 }
 
 // Produce sends a produce request to a kafka broker and returns the response.
@@ -143,27 +189,46 @@ type ProduceResponse struct {
 // When the request is configured with RequiredAcks=none, both the response and
 // the error will be nil on success.
 func (c *Client) Produce(ctx context.Context, req *ProduceRequest) (*ProduceResponse, error) {
-	attributes := protocol.Attributes(req.Compression) & 0x7
+	var topics []produceAPI.RequestTopic
 
-	m, err := c.roundTrip(ctx, req.Addr, &produceAPI.Request{
-		TransactionalID: req.TransactionalID,
-		Acks:            int16(req.RequiredAcks),
-		Timeout:         c.timeoutMs(ctx, defaultProduceTimeout),
-		Topics: []produceAPI.RequestTopic{{
+	if req.Topics != nil {
+		topics = make([]produceAPI.RequestTopic, 0, len(req.Topics))
+
+		for topic, partitions := range req.Topics {
+			t := produceAPI.RequestTopic{
+				Topic:      topic,
+				Partitions: make([]produceAPI.RequestPartition, len(partitions)),
+			}
+
+			for i, p := range partitions {
+				t.Partitions[i] = produceAPI.RequestPartition{
+					Partition: int32(p.Partition),
+					RecordSet: req.recordSet(p.Records, p.BaseSequence),
+				}
+			}
+
+			topics = append(topics, t)
+		}
+	} else {
+		topics = []produceAPI.RequestTopic{{
 			Topic: req.Topic,
 			Partitions: []produceAPI.RequestPartition{{
 				Partition: int32(req.Partition),
-				RecordSet: protocol.RecordSet{
-					Attributes: attributes,
-					Records:    req.Records,
-				},
+				RecordSet: req.recordSet(req.Records, req.BaseSequence),
 			}},
-		}},
+		}}
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &produceAPI.Request{
+		TransactionalID: req.TransactionalID,
+		Acks:            int16(req.RequiredAcks),
+		Timeout:         c.timeoutMs(ctx, defaultProduceTimeout),
+		Topics:          topics,
 	})
 
 	switch {
 	case err == nil:
-	case errors.Is(err, protocol.ErrNoRecord):
+	case errors.Is(err, protocol.ErrNoRecord) && req.Topics == nil:
 		return new(ProduceResponse), nil
 	default:
 		return nil, fmt.Errorf("kafka.(*Client).Produce: %w", err)
@@ -174,6 +239,24 @@ func (c *Client) Produce(ctx context.Context, req *ProduceRequest) (*ProduceResp
 	}
 
 	res := m.(*produceAPI.Response)
+
+	if req.Topics != nil {
+		ret := &ProduceResponse{
+			Throttle: makeDuration(res.ThrottleTimeMs),
+			Topics:   make(map[string][]ProducePartitionResponse, len(res.Topics)),
+		}
+
+		for _, t := range res.Topics {
+			partitions := make([]ProducePartitionResponse, len(t.Partitions))
+			for i := range t.Partitions {
+				partitions[i] = makeProducePartitionResponse(&t.Partitions[i])
+			}
+			ret.Topics[t.Topic] = partitions
+		}
+
+		return ret, nil
+	}
+
 	if len(res.Topics) == 0 {
 		return nil, fmt.Errorf("kafka.(*Client).Produce: %w", protocol.ErrNoTopic)
 	}
@@ -181,25 +264,60 @@ func (c *Client) Produce(ctx context.Context, req *ProduceRequest) (*ProduceResp
 	if len(topic.Partitions) == 0 {
 		return nil, fmt.Errorf("kafka.(*Client).Produce: %w", protocol.ErrNoPartition)
 	}
-	partition := &topic.Partitions[0]
+	partition := makeProducePartitionResponse(&topic.Partitions[0])
 
-	ret := &ProduceResponse{
+	return &ProduceResponse{
 		Throttle:       makeDuration(res.ThrottleTimeMs),
-		Error:          makeError(partition.ErrorCode, partition.ErrorMessage),
+		Error:          partition.Error,
 		BaseOffset:     partition.BaseOffset,
-		LogAppendTime:  makeTime(partition.LogAppendTime),
+		LogAppendTime:  partition.LogAppendTime,
 		LogStartOffset: partition.LogStartOffset,
+		RecordErrors:   partition.RecordErrors,
+	}, nil
+}
+
+// recordSet returns the record set of the request for records, with the
+// compression and producer session of req.
+func (req *ProduceRequest) recordSet(records RecordReader, baseSequence int) protocol.RecordSet {
+	attributes := protocol.Attributes(req.Compression) & 0x7
+
+	var producer *protocol.ProducerState
+	if req.Producer != nil {
+		producer = &protocol.ProducerState{
+			ID:           int64(req.Producer.ProducerID),
+			Epoch:        int16(req.Producer.ProducerEpoch),
+			BaseSequence: int32(baseSequence),
+		}
+		if req.TransactionalID != "" {
+			attributes |= protocol.Transactional
+		}
+	}
+
+	return protocol.RecordSet{
+		Attributes: attributes,
+		Records:    records,
+		Producer:   producer,
+	}
+}
+
+func makeProducePartitionResponse(p *produceAPI.ResponsePartition) ProducePartitionResponse {
+	ret := ProducePartitionResponse{
+		Partition:      int(p.Partition),
+		Error:          makeError(p.ErrorCode, p.ErrorMessage),
+		BaseOffset:     p.BaseOffset,
+		LogAppendTime:  makeTime(p.LogAppendTime),
+		LogStartOffset: p.LogStartOffset,
 	}
 
-	if len(partition.RecordErrors) != 0 {
-		ret.RecordErrors = make(map[int]error, len(partition.RecordErrors))
+	if len(p.RecordErrors) != 0 {
+		ret.RecordErrors = make(map[int]error, len(p.RecordErrors))
 
-		for _, recErr := range partition.RecordErrors {
+		for _, recErr := range p.RecordErrors {
 			ret.RecordErrors[int(recErr.BatchIndex)] = errors.New(recErr.BatchIndexErrorMessage)
 		}
 	}
 
-	return ret, nil
+	return ret
 }
 
 type produceRequestV2 struct {
@@ -0,0 +1,95 @@
+package kafka
+
+import "context"
+
+// ContextPropagator is an interface implemented by types which propagate
+// values of contexts, like trace contexts or baggage, in the headers of
+// messages.
+//
+// Propagators must be safe to use concurrently from multiple goroutines.
+type ContextPropagator interface {
+	// Inject writes the values of ctx to the headers of msg.
+	Inject(ctx context.Context, msg *Message)
+
+	// Extract returns a copy of ctx carrying the values found in the headers
+	// of msg.
+	Extract(ctx context.Context, msg Message) context.Context
+}
+
+// DefaultPropagator is the ContextPropagator used by InjectContext and
+// ExtractContext. It propagates the W3C trace context and baggage headers.
+//
+// Programs using a tracing library may replace it with a propagator of this
+// library, for example the one returned by otelkafka.Propagator.
+var DefaultPropagator ContextPropagator = HeaderPropagator{
+	Keys: []string{"traceparent", "tracestate", "baggage"},
+}
+
+// InjectContext writes the values of ctx to the headers of msg, using
+// DefaultPropagator. Programs which produce messages while processing other
+// messages call it to carry the context of the messages they consumed:
+//
+//	m, err := r.FetchMessage(ctx)
+//	...
+//	out := kafka.Message{Value: process(m)}
+//	kafka.InjectContext(kafka.ExtractContext(m), &out)
+func InjectContext(ctx context.Context, msg *Message) {
+	DefaultPropagator.Inject(ctx, msg)
+}
+
+// ExtractContext returns a context carrying the values found in the headers of
+// msg, using DefaultPropagator.
+func ExtractContext(msg Message) context.Context {
+	return DefaultPropagator.Extract(context.Background(), msg)
+}
+
+// HeaderPropagator is a ContextPropagator which copies the headers with the
+// given keys from messages to contexts, and back.
+type HeaderPropagator struct {
+	Keys []string
+}
+
+type propagatedHeadersKey struct{}
+
+// Inject satisfies the ContextPropagator interface.
+func (p HeaderPropagator) Inject(ctx context.Context, msg *Message) {
+	headers, _ := ctx.Value(propagatedHeadersKey{}).([]Header)
+	for _, h := range headers {
+		if p.propagates(h.Key) {
+			msg.SetHeader(h.Key, h.Value)
+		}
+	}
+}
+
+// Extract satisfies the ContextPropagator interface.
+func (p HeaderPropagator) Extract(ctx context.Context, msg Message) context.Context {
+	prev, _ := ctx.Value(propagatedHeadersKey{}).([]Header)
+	headers := make([]Header, 0, len(prev)+len(p.Keys))
+
+	for _, key := range p.Keys {
+		if value, ok := msg.Header(key); ok {
+			headers = append(headers, Header{Key: key, Value: value})
+		}
+	}
+	if len(headers) == 0 {
+		return ctx
+	}
+
+	// Values of the previous context are kept unless the message carried a
+	// header with the same key.
+	for _, h := range prev {
+		if _, ok := msg.Header(h.Key); !ok {
+			headers = append(headers, h)
+		}
+	}
+	return context.WithValue(ctx, propagatedHeadersKey{}, headers)
+}
+
+func (p HeaderPropagator) propagates(key string) bool {
+	for _, k := range p.Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
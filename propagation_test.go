@@ -0,0 +1,52 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextPropagation(t *testing.T) {
+	in := Message{Headers: []Header{
+		{Key: "traceparent", Value: []byte("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")},
+		{Key: "other", Value: []byte("not propagated")},
+	}}
+
+	ctx := ExtractContext(in)
+
+	out := Message{Headers: []Header{{Key: "traceparent", Value: []byte("replaced")}}}
+	InjectContext(ctx, &out)
+
+	if v, _ := out.Header("traceparent"); string(v) != "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01" {
+		t.Errorf("wrong traceparent header: %q", v)
+	}
+	if _, ok := out.Header("other"); ok {
+		t.Error("a header which is not part of the trace context was propagated")
+	}
+	if len(out.Headers) != 1 {
+		t.Errorf("wrong number of headers: %+v", out.Headers)
+	}
+
+	if ExtractContext(Message{}) != context.Background() {
+		t.Error("extracting the context of a message without headers returned a new context")
+	}
+}
+
+func TestHeaderPropagatorMerge(t *testing.T) {
+	p := HeaderPropagator{Keys: []string{"a", "b"}}
+
+	ctx := p.Extract(context.Background(), Message{Headers: []Header{
+		{Key: "a", Value: []byte("1")},
+		{Key: "b", Value: []byte("2")},
+	}})
+	ctx = p.Extract(ctx, Message{Headers: []Header{{Key: "a", Value: []byte("3")}}})
+
+	var msg Message
+	p.Inject(ctx, &msg)
+
+	if v, _ := msg.Header("a"); string(v) != "3" {
+		t.Errorf("wrong value of header a: %q", v)
+	}
+	if v, _ := msg.Header("b"); string(v) != "2" {
+		t.Errorf("wrong value of header b: %q", v)
+	}
+}
@@ -9,7 +9,7 @@ func init() {
 type Request struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v3,max=v3,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
 
 	TransactionalID string `kafka:"min=v0,max=v2|min=v3,max=v3,compact"`
 	ProducerID      int64  `kafka:"min=v0,max=v3"`
@@ -26,7 +26,7 @@ var _ protocol.TransactionalMessage = (*Request)(nil)
 type Response struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v3,max=v3,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
 
 	ThrottleTimeMs int32 `kafka:"min=v0,max=v3"`
 	ErrorCode      int16 `kafka:"min=v0,max=v3"`
@@ -9,7 +9,7 @@ func init() {
 type Request struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v3,max=v3,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
 
 	TransactionalID string         `kafka:"min=v0,max=v2|min=v3,max=v3,compact"`
 	ProducerID      int64          `kafka:"min=v0,max=v3"`
@@ -20,7 +20,7 @@ type Request struct {
 type RequestTopic struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v3,max=v3,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
 
 	Name       string  `kafka:"min=v0,max=v2|min=v3,max=v3,compact"`
 	Partitions []int32 `kafka:"min=v0,max=v3"`
@@ -35,7 +35,7 @@ var _ protocol.TransactionalMessage = (*Request)(nil)
 type Response struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v3,max=v3,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
 
 	ThrottleTimeMs int32            `kafka:"min=v0,max=v3"`
 	Results        []ResponseResult `kafka:"min=v0,max=v3"`
@@ -44,7 +44,7 @@ type Response struct {
 type ResponseResult struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v3,max=v3,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
 
 	Name    string              `kafka:"min=v0,max=v2|min=v3,max=v3,compact"`
 	Results []ResponsePartition `kafka:"min=v0,max=v3"`
@@ -53,7 +53,7 @@ type ResponseResult struct {
 type ResponsePartition struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v3,max=v3,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
 
 	PartitionIndex int32 `kafka:"min=v0,max=v3"`
 	ErrorCode      int16 `kafka:"min=v0,max=v3"`
@@ -0,0 +1,64 @@
+package alterclientquotas_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/alterclientquotas"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+const (
+	v0 = 0
+	v1 = 1
+)
+
+func TestAlterClientQuotasRequest(t *testing.T) {
+	for _, version := range []int16{v0, v1} {
+		prototest.TestRequest(t, version, &alterclientquotas.Request{
+			ValidateOnly: true,
+			Entries: []alterclientquotas.Entry{
+				{
+					Entities: []alterclientquotas.Entity{
+						{
+							EntityType: "user",
+							EntityName: "alice",
+						},
+						{
+							EntityType: "client-id",
+						},
+					},
+					Ops: []alterclientquotas.Ops{
+						{
+							Key:   "producer_byte_rate",
+							Value: 1048576,
+						},
+						{
+							Key:    "consumer_byte_rate",
+							Remove: true,
+						},
+					},
+				},
+			},
+		})
+	}
+}
+
+func TestAlterClientQuotasResponse(t *testing.T) {
+	for _, version := range []int16{v0, v1} {
+		prototest.TestResponse(t, version, &alterclientquotas.Response{
+			ThrottleTimeMs: 500,
+			Results: []alterclientquotas.ResponseQuota{
+				{
+					ErrorCode:    1,
+					ErrorMessage: "foo",
+					Entities: []alterclientquotas.Entity{
+						{
+							EntityType: "user",
+							EntityName: "alice",
+						},
+					},
+				},
+			},
+		})
+	}
+}
@@ -10,18 +10,22 @@ func init() {
 type Request struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v0,max=v0,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
 
 	TimeoutMs int32          `kafka:"min=v0,max=v0"`
 	Topics    []RequestTopic `kafka:"min=v0,max=v0"`
 }
 
 type RequestTopic struct {
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
 	Name       string             `kafka:"min=v0,max=v0"`
 	Partitions []RequestPartition `kafka:"min=v0,max=v0"`
 }
 
 type RequestPartition struct {
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
 	PartitionIndex int32   `kafka:"min=v0,max=v0"`
 	Replicas       []int32 `kafka:"min=v0,max=v0"`
 }
@@ -37,7 +41,7 @@ func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
 type Response struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v0,max=v0,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
 
 	ThrottleTimeMs int32            `kafka:"min=v0,max=v0"`
 	ErrorCode      int16            `kafka:"min=v0,max=v0"`
@@ -46,11 +50,15 @@ type Response struct {
 }
 
 type ResponseResult struct {
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
 	Name       string              `kafka:"min=v0,max=v0"`
 	Partitions []ResponsePartition `kafka:"min=v0,max=v0"`
 }
 
 type ResponsePartition struct {
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
 	PartitionIndex int32  `kafka:"min=v0,max=v0"`
 	ErrorCode      int16  `kafka:"min=v0,max=v0"`
 	ErrorMessage   string `kafka:"min=v0,max=v0,nullable"`
@@ -0,0 +1,67 @@
+package alteruserscramcredentials
+
+import "github.com/segmentio/kafka-go/protocol"
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_AlterUserScramCredentials
+type Request struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	Deletions  []RequestUserScramCredentialsDeletion  `kafka:"min=v0,max=v0"`
+	Upsertions []RequestUserScramCredentialsUpsertion `kafka:"min=v0,max=v0"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.AlterUserScramCredentials }
+
+func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	return cluster.Brokers[cluster.Controller], nil
+}
+
+type RequestUserScramCredentialsDeletion struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	Name      string `kafka:"min=v0,max=v0"`
+	Mechanism int8   `kafka:"min=v0,max=v0"`
+}
+
+type RequestUserScramCredentialsUpsertion struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	Name           string `kafka:"min=v0,max=v0"`
+	Mechanism      int8   `kafka:"min=v0,max=v0"`
+	Iterations     int32  `kafka:"min=v0,max=v0"`
+	Salt           []byte `kafka:"min=v0,max=v0"`
+	SaltedPassword []byte `kafka:"min=v0,max=v0"`
+}
+
+type Response struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	ThrottleTimeMs int32          `kafka:"min=v0,max=v0"`
+	Results        []ResponseUser `kafka:"min=v0,max=v0"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.AlterUserScramCredentials }
+
+type ResponseUser struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	User         string `kafka:"min=v0,max=v0"`
+	ErrorCode    int16  `kafka:"min=v0,max=v0"`
+	ErrorMessage string `kafka:"min=v0,max=v0,nullable"`
+}
+
+var _ protocol.BrokerMessage = (*Request)(nil)
@@ -7,21 +7,33 @@ func init() {
 }
 
 type Request struct {
-	_ struct{} `kafka:"min=v0,max=v2"`
+	// We need at least one tagged field to indicate that v3+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
+
+	_                     struct{} `kafka:"min=v0,max=v2"`
+	ClientSoftwareName    string   `kafka:"min=v3,max=v3"`
+	ClientSoftwareVersion string   `kafka:"min=v3,max=v3"`
 }
 
 func (r *Request) ApiKey() protocol.ApiKey { return protocol.ApiVersions }
 
 type Response struct {
-	ErrorCode      int16            `kafka:"min=v0,max=v2"`
-	ApiKeys        []ApiKeyResponse `kafka:"min=v0,max=v2"`
-	ThrottleTimeMs int32            `kafka:"min=v1,max=v2"`
+	// We need at least one tagged field to indicate that v3+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
+
+	ErrorCode      int16            `kafka:"min=v0,max=v3"`
+	ApiKeys        []ApiKeyResponse `kafka:"min=v0,max=v3"`
+	ThrottleTimeMs int32            `kafka:"min=v1,max=v3"`
 }
 
 func (r *Response) ApiKey() protocol.ApiKey { return protocol.ApiVersions }
 
 type ApiKeyResponse struct {
-	ApiKey     int16 `kafka:"min=v0,max=v2"`
-	MinVersion int16 `kafka:"min=v0,max=v2"`
-	MaxVersion int16 `kafka:"min=v0,max=v2"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
+
+	ApiKey     int16 `kafka:"min=v0,max=v3"`
+	MinVersion int16 `kafka:"min=v0,max=v3"`
+	MaxVersion int16 `kafka:"min=v0,max=v3"`
 }
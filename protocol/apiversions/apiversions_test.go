@@ -11,6 +11,7 @@ const (
 	v0 = 0
 	v1 = 1
 	v2 = 2
+	v3 = 3
 )
 
 func TestApiversionsRequest(t *testing.T) {
@@ -19,6 +20,11 @@ func TestApiversionsRequest(t *testing.T) {
 	prototest.TestRequest(t, v1, &apiversions.Request{})
 
 	prototest.TestRequest(t, v2, &apiversions.Request{})
+
+	prototest.TestRequest(t, v3, &apiversions.Request{
+		ClientSoftwareName:    "kafka-go",
+		ClientSoftwareVersion: "v0.4.47",
+	})
 }
 
 func TestApiversionsResponse(t *testing.T) {
@@ -56,4 +62,15 @@ func TestApiversionsResponse(t *testing.T) {
 		},
 		ThrottleTimeMs: 50,
 	})
+	prototest.TestResponse(t, v3, &apiversions.Response{
+		ErrorCode: 0,
+		ApiKeys: []apiversions.ApiKeyResponse{
+			{
+				ApiKey:     0,
+				MinVersion: 0,
+				MaxVersion: 2,
+			},
+		},
+		ThrottleTimeMs: 50,
+	})
 }
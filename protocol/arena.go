@@ -0,0 +1,86 @@
+package protocol
+
+import "sync"
+
+// stringArena allocates the strings of responses describing the cluster state,
+// which are made of many small strings, from chunks of memory instead of
+// allocating each string separately.
+//
+// Chunks are never reused once strings were allocated from them, only the
+// remaining tail is, which is why arenas can be returned to the pool while the
+// strings of the responses are still in use.
+type stringArena struct {
+	chunk   []byte
+	scratch []byte
+}
+
+const (
+	stringArenaChunkSize = 4096
+	// Strings larger than this are allocated separately, to avoid wasting the
+	// tail of chunks.
+	stringArenaMaxSize = stringArenaChunkSize / 8
+)
+
+var stringArenaPool = sync.Pool{
+	New: func() interface{} { return new(stringArena) },
+}
+
+// alloc returns n bytes which are never returned by the arena again.
+func (a *stringArena) alloc(n int) []byte {
+	if n > stringArenaMaxSize {
+		return make([]byte, n)
+	}
+	if len(a.chunk) < n {
+		a.chunk = make([]byte, stringArenaChunkSize)
+	}
+	b := a.chunk[:n:n]
+	a.chunk = a.chunk[n:]
+	return b
+}
+
+// buffer returns n bytes which are reused by the next call to buffer, used to
+// read the strings that are interned.
+func (a *stringArena) buffer(n int) []byte {
+	if cap(a.scratch) < n {
+		a.scratch = make([]byte, n)
+	}
+	return a.scratch[:n]
+}
+
+// stringTable interns the strings which repeat across responses, like the
+// names of topics, so programs refreshing the metadata of clusters with many
+// topics do not retain a copy of each name per response.
+type stringTable struct {
+	mutex   sync.RWMutex
+	strings map[string]string
+}
+
+// The table is cleared when it reaches this size, which bounds the memory held
+// by strings that are not used anymore, like the names of deleted topics.
+const maxInternedStrings = 1 << 16
+
+var internedStrings stringTable
+
+func (t *stringTable) intern(b []byte) string {
+	t.mutex.RLock()
+	s, ok := t.strings[string(b)]
+	t.mutex.RUnlock()
+	if ok {
+		return s
+	}
+
+	s = string(b)
+	t.mutex.Lock()
+	if t.strings == nil || len(t.strings) >= maxInternedStrings {
+		t.strings = make(map[string]string)
+	}
+	t.strings[s] = s
+	t.mutex.Unlock()
+	return s
+}
+
+func (t *stringTable) len() int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return len(t.strings)
+}
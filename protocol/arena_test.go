@@ -0,0 +1,52 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
+func TestStringTable(t *testing.T) {
+	table := &stringTable{}
+
+	a := table.intern([]byte("topic-A"))
+	b := table.intern([]byte("topic-A"))
+	if a != "topic-A" || stringData(a) != stringData(b) {
+		t.Error("identical strings were not interned")
+	}
+	if c := table.intern([]byte("topic-B")); c != "topic-B" || table.len() != 2 {
+		t.Errorf("wrong string interned: %q (%d strings)", c, table.len())
+	}
+
+	for i := 0; i < maxInternedStrings; i++ {
+		table.intern([]byte{byte(i), byte(i >> 8)})
+	}
+	if n := table.len(); n > maxInternedStrings {
+		t.Errorf("the table grew past its maximum size: %d", n)
+	}
+}
+
+func TestStringArena(t *testing.T) {
+	arena := &stringArena{}
+
+	a := arena.alloc(10)
+	b := arena.alloc(10)
+	copy(a, "0123456789")
+	copy(b, "abcdefghij")
+	if string(a) != "0123456789" || cap(a) != 10 {
+		t.Errorf("allocations of the arena overlap: %q", a)
+	}
+	if &a[0] == &b[0] {
+		t.Error("the arena returned the same memory twice")
+	}
+	if n := len(arena.alloc(stringArenaMaxSize + 1)); n != stringArenaMaxSize+1 {
+		t.Errorf("wrong size of large allocation: %d", n)
+	}
+	if len(arena.chunk) != stringArenaChunkSize-20 {
+		t.Errorf("large allocations must not use the chunk: %d bytes remain", len(arena.chunk))
+	}
+}
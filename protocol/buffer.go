@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"net"
 	"sync"
 	"sync/atomic"
 )
@@ -38,7 +39,7 @@ func NewBytes(b []byte) Bytes {
 	if b == nil {
 		return nil
 	}
-	r := new(bytesReader)
+	r := &bytesReader{data: b}
 	r.Reset(b)
 	return r
 }
@@ -56,10 +57,16 @@ func ReadAll(b Bytes) ([]byte, error) {
 	return s, err
 }
 
-type bytesReader struct{ bytes.Reader }
+type bytesReader struct {
+	bytes.Reader
+	data []byte
+}
 
 func (*bytesReader) Close() error { return nil }
 
+// unread returns the bytes that were not read yet.
+func (r *bytesReader) unread() []byte { return r.data[len(r.data)-r.Len():] }
+
 type refCount uintptr
 
 func (rc *refCount) ref() { atomic.AddUintptr((*uintptr)(rc), 1) }
@@ -71,23 +78,100 @@ func (rc *refCount) unref(onZero func()) {
 }
 
 const (
-	// Size of the memory buffer for a single page. We use a farily
+	// Default size of the memory buffer for a single page. We use a farily
 	// large size here (64 KiB) because batches exchanged with kafka
 	// tend to be multiple kilobytes in size, sometimes hundreds.
 	// Using large pages amortizes the overhead of the page metadata
 	// and algorithms to manage the pages.
-	pageSize = 65536
+	defaultPageSize = 65536
+
+	// Minimum size of the record values that are spliced into buffers instead
+	// of being copied, see pageBuffer.splice. Smaller values are cheaper to
+	// copy than to write as separate buffers.
+	spliceThreshold = 8192
 )
 
-type page struct {
-	refc   refCount
-	offset int64
-	length int
-	buffer *[pageSize]byte
+// BufferPoolConfig configures the pool of memory pages that the package uses
+// to buffer the messages exchanged with kafka brokers.
+type BufferPoolConfig struct {
+	// Size of the memory pages. Large pages amortize the cost of managing
+	// pages when buffering large messages (e.g. record batches), small pages
+	// reduce the memory held by small messages.
+	//
+	// Defaults to 64 KiB.
+	PageSize int
+
+	// Maximum number of idle pages retained by the pool. When zero, idle
+	// pages are retained in a sync.Pool and reclaimed by the garbage
+	// collector.
+	MaxRetainedPages int
+}
+
+// SetBufferPoolConfig replaces the pool of memory pages used by the package.
+//
+// Buffers in use when the function is called keep allocating pages from the
+// pool that they were created with, the new configuration only applies to the
+// buffers created after the function returned.
+func SetBufferPoolConfig(config BufferPoolConfig) {
+	currentPagePool.Store(newPagePool(config))
+}
+
+// LeasePage returns the memory of a page of the pool configured with
+// SetBufferPoolConfig, and a function returning the page to the pool. The
+// program must not reference the memory after calling the function, which
+// must be called at most once.
+func LeasePage() ([]byte, func()) {
+	p := loadPagePool().newPage(0)
+	return p.buffer, p.unref
+}
+
+type pagePool struct {
+	pageSize int
+	free     chan *page // bounded list of idle pages when not nil
+	pool     sync.Pool
+}
+
+func newPagePool(config BufferPoolConfig) *pagePool {
+	pp := &pagePool{pageSize: config.PageSize}
+	if pp.pageSize <= 0 {
+		pp.pageSize = defaultPageSize
+	}
+	if config.MaxRetainedPages > 0 {
+		pp.free = make(chan *page, config.MaxRetainedPages)
+	}
+	return pp
+}
+
+func loadPagePool() *pagePool {
+	return currentPagePool.Load().(*pagePool)
+}
+
+func (pp *pagePool) get() *page {
+	if pp.free != nil {
+		select {
+		case p := <-pp.free:
+			return p
+		default:
+			return nil
+		}
+	}
+	p, _ := pp.pool.Get().(*page)
+	return p
 }
 
-func newPage(offset int64) *page {
-	p, _ := pagePool.Get().(*page)
+func (pp *pagePool) put(p *page) {
+	if pp.free != nil {
+		select {
+		case pp.free <- p:
+		default: // drop the page, the pool retains enough of them
+		}
+	} else {
+		pp.pool.Put(p)
+	}
+}
+
+func (pp *pagePool) newPage(offset int64) *page {
+	p := pp.get()
 	if p != nil {
 		p.offset = offset
 		p.length = 0
@@ -96,18 +180,28 @@ func newPage(offset int64) *page {
 		p = &page{
 			refc:   1,
 			offset: offset,
-			buffer: &[pageSize]byte{},
+			buffer: make([]byte, pp.pageSize),
+			pool:   pp,
 		}
 	}
 	return p
 }
 
+type page struct {
+	refc   refCount
+	offset int64
+	length int
+	buffer []byte
+	pool   *pagePool
+}
+
 func (p *page) ref() { p.refc.ref() }
 
-func (p *page) unref() { p.refc.unref(func() { pagePool.Put(p) }) }
+func (p *page) unref() { p.refc.unref(func() { p.pool.put(p) }) }
 
 func (p *page) slice(begin, end int64) []byte {
 	i, j := begin-p.offset, end-p.offset
+	pageSize := int64(len(p.buffer))
 
 	if i < 0 {
 		i = 0
@@ -128,7 +222,7 @@ func (p *page) slice(begin, end int64) []byte {
 	return nil
 }
 
-func (p *page) Cap() int { return pageSize }
+func (p *page) Cap() int { return len(p.buffer) }
 
 func (p *page) Len() int { return p.length }
 
@@ -141,7 +235,7 @@ func (p *page) Truncate(n int) {
 }
 
 func (p *page) ReadAt(b []byte, off int64) (int, error) {
-	if off -= p.offset; off < 0 || off > pageSize {
+	if off -= p.offset; off < 0 || off > int64(len(p.buffer)) {
 		panic("offset out of range")
 	}
 	if off > int64(p.length) {
@@ -160,7 +254,7 @@ func (p *page) ReadFrom(r io.Reader) (int64, error) {
 }
 
 func (p *page) WriteAt(b []byte, off int64) (int, error) {
-	if off -= p.offset; off < 0 || off > pageSize {
+	if off -= p.offset; off < 0 || off > int64(len(p.buffer)) {
 		panic("offset out of range")
 	}
 	n := copy(p.buffer[off:], b)
@@ -183,9 +277,25 @@ var (
 
 type pageBuffer struct {
 	refc   refCount
+	pool   *pagePool
 	pages  contiguousPages
 	length int
 	cursor int
+	// External byte slices inserted between the pages of the buffer, and the
+	// total number of bytes that they hold.
+	splices []splice
+	spliced int
+}
+
+type splice struct {
+	offset int64 // position in the pages where the data is inserted
+	data   []byte
+}
+
+// buffersWriter is implemented by writers which can write a list of buffers in
+// a single system call (e.g. with writev on a TCP socket).
+type buffersWriter interface {
+	writeBuffers(*net.Buffers) (int64, error)
 }
 
 func newPageBuffer() *pageBuffer {
@@ -199,6 +309,7 @@ func newPageBuffer() *pageBuffer {
 			pages: make(contiguousPages, 0, 16),
 		}
 	}
+	b.pool = loadPagePool()
 	return b
 }
 
@@ -227,12 +338,17 @@ func (pb *pageBuffer) unref() {
 		pb.pages.clear()
 		pb.pages = pb.pages[:0]
 		pb.length = 0
+		for i := range pb.splices {
+			pb.splices[i] = splice{}
+		}
+		pb.splices = pb.splices[:0]
+		pb.spliced = 0
 		pageBufferPool.Put(pb)
 	})
 }
 
 func (pb *pageBuffer) newPage() *page {
-	return newPage(int64(pb.length))
+	return pb.pool.newPage(int64(pb.length))
 }
 
 func (pb *pageBuffer) Close() error {
@@ -244,7 +360,84 @@ func (pb *pageBuffer) Len() int {
 }
 
 func (pb *pageBuffer) Size() int64 {
-	return int64(pb.length)
+	return int64(pb.length + pb.spliced)
+}
+
+// splice appends b to the buffer without copying it, the buffer retains b until
+// it is written, so the program must not modify it in the meantime.
+//
+// Splicing is intended for buffers that are only written to: Size, WriteAt,
+// WriteTo, and scan account for the spliced data, the other methods only see
+// the data held in the buffer pages.
+func (pb *pageBuffer) splice(b []byte) {
+	if len(b) != 0 {
+		pb.splices = append(pb.splices, splice{offset: int64(pb.length), data: b})
+		pb.spliced += len(b)
+	}
+}
+
+// pageOffset translates an offset in the buffer into an offset in its pages,
+// skipping the spliced data that precedes it.
+func (pb *pageBuffer) pageOffset(off int64) int64 {
+	n := int64(0)
+	for _, s := range pb.splices {
+		if off < s.offset+n+int64(len(s.data)) {
+			break
+		}
+		n += int64(len(s.data))
+	}
+	return off - n
+}
+
+// chunks calls f with the successive chunks of data held by the buffer, the
+// spliced argument is true for chunks which were spliced into the buffer.
+func (pb *pageBuffer) chunks(f func(b []byte, spliced bool) bool) {
+	ok := true
+	scan := func(begin, end int64) {
+		pb.pages.scan(begin, end, func(b []byte) bool {
+			if len(b) != 0 {
+				ok = f(b, false)
+			}
+			return ok
+		})
+	}
+
+	offset := int64(0)
+	for _, s := range pb.splices {
+		scan(offset, s.offset)
+		if !ok || !f(s.data, true) {
+			return
+		}
+		offset = s.offset
+	}
+
+	scan(offset, int64(pb.length))
+}
+
+// scan calls f with the chunks of data between the begin and end offsets,
+// including spliced data.
+func (pb *pageBuffer) scan(begin, end int64, f func([]byte) bool) {
+	if len(pb.splices) == 0 {
+		pb.pages.scan(begin, end, f)
+		return
+	}
+
+	offset := int64(0)
+	pb.chunks(func(b []byte, _ bool) bool {
+		i, j := begin-offset, end-offset
+		offset += int64(len(b))
+
+		if i < 0 {
+			i = 0
+		}
+		if j > int64(len(b)) {
+			j = int64(len(b))
+		}
+		if i < j && !f(b[i:j]) {
+			return false
+		}
+		return offset < end
+	})
 }
 
 func (pb *pageBuffer) Discard(n int) (int, error) {
@@ -290,6 +483,19 @@ func (pb *pageBuffer) Seek(offset int64, whence int) (int64, error) {
 	return c, nil
 }
 
+// contiguous returns the bytes following the cursor which are held in the same
+// page, they can be read without being copied.
+func (pb *pageBuffer) contiguous() []byte {
+	if pb.cursor >= pb.length || len(pb.pages) == 0 {
+		return nil
+	}
+	i := pb.pages.indexOf(int64(pb.cursor))
+	if i >= len(pb.pages) {
+		return nil
+	}
+	return pb.pages[i].slice(int64(pb.cursor), int64(pb.length))
+}
+
 func (pb *pageBuffer) ReadByte() (byte, error) {
 	b := [1]byte{}
 	_, err := pb.Read(b[:])
@@ -322,7 +528,7 @@ func (pb *pageBuffer) ReadFrom(r io.Reader) (int64, error) {
 
 		if free == 0 {
 			tail = pb.newPage()
-			free = pageSize
+			free = tail.Cap()
 			pb.pages = append(pb.pages, tail)
 		}
 
@@ -370,6 +576,9 @@ func (pb *pageBuffer) Write(b []byte) (int, error) {
 }
 
 func (pb *pageBuffer) WriteAt(b []byte, off int64) (int, error) {
+	if len(pb.splices) != 0 {
+		off = pb.pageOffset(off)
+	}
 	n, err := pb.pages.WriteAt(b, off)
 	if err != nil {
 		return n, err
@@ -381,6 +590,10 @@ func (pb *pageBuffer) WriteAt(b []byte, off int64) (int, error) {
 }
 
 func (pb *pageBuffer) WriteTo(w io.Writer) (int64, error) {
+	if len(pb.splices) != 0 {
+		return pb.writeChunksTo(w)
+	}
+
 	var wn int
 	var err error
 	pb.pages.scan(int64(pb.cursor), int64(pb.length), func(b []byte) bool {
@@ -393,6 +606,42 @@ func (pb *pageBuffer) WriteTo(w io.Writer) (int64, error) {
 	return int64(wn), err
 }
 
+// writeChunksTo writes the content of a buffer with spliced data to w. When w
+// is a pageBuffer the data is spliced into it, otherwise the chunks are written
+// as a list of buffers, which avoids copying them on writers which support it.
+func (pb *pageBuffer) writeChunksTo(w io.Writer) (int64, error) {
+	if dst, ok := w.(*pageBuffer); ok {
+		wn := int64(0)
+		pb.chunks(func(b []byte, spliced bool) bool {
+			if spliced {
+				dst.splice(b)
+			} else {
+				dst.Write(b)
+			}
+			wn += int64(len(b))
+			return true
+		})
+		pb.cursor = pb.length
+		return wn, nil
+	}
+
+	buffers := make(net.Buffers, 0, len(pb.pages)+2*len(pb.splices))
+	pb.chunks(func(b []byte, _ bool) bool {
+		buffers = append(buffers, b)
+		return true
+	})
+
+	var wn int64
+	var err error
+	if bw, ok := w.(buffersWriter); ok {
+		wn, err = bw.writeBuffers(&buffers)
+	} else {
+		wn, err = buffers.WriteTo(w)
+	}
+	pb.cursor = pb.length
+	return wn, err
+}
+
 var (
 	_ io.ReaderAt     = (*pageBuffer)(nil)
 	_ io.ReaderFrom   = (*pageBuffer)(nil)
@@ -401,10 +650,14 @@ var (
 	_ io.WriterAt     = (*pageBuffer)(nil)
 	_ io.WriterTo     = (*pageBuffer)(nil)
 
-	pagePool       sync.Pool
-	pageBufferPool sync.Pool
+	currentPagePool atomic.Value // *pagePool
+	pageBufferPool  sync.Pool
 )
 
+func init() {
+	SetBufferPoolConfig(BufferPoolConfig{})
+}
+
 type contiguousPages []*page
 
 func (pages contiguousPages) ref() {
@@ -464,7 +717,7 @@ func (pages contiguousPages) indexOf(offset int64) int {
 	if len(pages) == 0 {
 		return 0
 	}
-	return int((offset - pages[0].offset) / pageSize)
+	return int((offset - pages[0].offset) / int64(pages[0].Cap()))
 }
 
 func (pages contiguousPages) scan(begin, end int64, f func([]byte) bool) {
@@ -3,6 +3,7 @@ package protocol
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"net"
 	"sync/atomic"
 	"time"
@@ -14,6 +15,9 @@ type Conn struct {
 	clientID string
 	idgen    int32
 	versions atomic.Value // map[ApiKey]int16
+	observer atomic.Value // *roundTripObserver
+	limits   atomic.Value // Limits
+	crc      atomic.Value // CRCMode
 }
 
 func NewConn(conn net.Conn, clientID string) *Conn {
@@ -48,6 +52,12 @@ func (c *Conn) Write(b []byte) (int, error) {
 	return c.conn.Write(b)
 }
 
+// writeBuffers writes b to the underlying network connection, which uses a
+// single system call on TCP sockets.
+func (c *Conn) writeBuffers(b *net.Buffers) (int64, error) {
+	return b.WriteTo(c.conn)
+}
+
 func (c *Conn) LocalAddr() net.Addr {
 	return c.conn.LocalAddr()
 }
@@ -78,23 +88,102 @@ func (c *Conn) SetVersions(versions map[ApiKey]int16) {
 	c.versions.Store(connVersions)
 }
 
+// Versions returns the API versions used by the connection, which were set
+// by the last call to SetVersions.
+func (c *Conn) Versions() map[ApiKey]int16 {
+	versions, _ := c.versions.Load().(map[ApiKey]int16)
+	connVersions := make(map[ApiKey]int16, len(versions))
+
+	for k, v := range versions {
+		connVersions[k] = v
+	}
+
+	return connVersions
+}
+
+// SetRoundTripObserver installs an observer which is called on each round trip
+// made on the connection. The redact options control which messages are passed
+// to the observer. Passing a nil observer removes it.
+func (c *Conn) SetRoundTripObserver(observer RoundTripObserver, redact Redaction) {
+	var o *roundTripObserver
+	if observer != nil {
+		o = &roundTripObserver{observer: observer, redact: redact}
+	}
+	c.observer.Store(o)
+}
+
+// SetLimits configures the limits enforced when decoding the responses received
+// on the connection.
+func (c *Conn) SetLimits(limits Limits) {
+	c.limits.Store(limits)
+}
+
+// SetCRCMode configures how the checksums of the record batches received on the
+// connection are verified.
+func (c *Conn) SetCRCMode(mode CRCMode) {
+	c.crc.Store(mode)
+}
+
 func (c *Conn) RoundTrip(msg Message) (Message, error) {
 	correlationID := atomic.AddInt32(&c.idgen, +1)
 	versions, _ := c.versions.Load().(map[ApiKey]int16)
 	apiVersion := versions[msg.ApiKey()]
 
+	if raw, ok := msg.(rawMessage); ok {
+		apiVersion = raw.rawRequest().Version
+	}
+
+	observer, _ := c.observer.Load().(*roundTripObserver)
+	if observer == nil {
+		return c.roundTrip(c, msg, versions, apiVersion, correlationID)
+	}
+
+	rw := &observedConn{Conn: c}
+	start := time.Now()
+	res, err := c.roundTrip(rw, msg, versions, apiVersion, correlationID)
+
+	observer.observe(RoundTripInfo{
+		Addr:          c.RemoteAddr(),
+		ClientID:      c.clientID,
+		ApiKey:        msg.ApiKey(),
+		ApiVersion:    apiVersion,
+		CorrelationID: correlationID,
+		Request:       msg,
+		Response:      res,
+		RequestSize:   rw.written,
+		ResponseSize:  rw.read,
+		Start:         start,
+		Latency:       time.Since(start),
+		Error:         err,
+	})
+
+	return res, err
+}
+
+func (c *Conn) roundTrip(rw io.ReadWriter, msg Message, versions map[ApiKey]int16, apiVersion int16, correlationID int32) (Message, error) {
+	if raw, ok := msg.(rawMessage); ok {
+		res, err := raw.rawRequest().roundTrip(rw, correlationID, c.clientID)
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+
 	if p, _ := msg.(PreparedMessage); p != nil {
 		p.Prepare(apiVersion)
 	}
 
 	if raw, ok := msg.(RawExchanger); ok && raw.Required(versions) {
-		return raw.RawExchange(c)
+		return raw.RawExchange(rw)
 	}
 
-	return RoundTrip(c, apiVersion, correlationID, c.clientID, msg)
+	limits, _ := c.limits.Load().(Limits)
+	crc, _ := c.crc.Load().(CRCMode)
+	return roundTrip(rw, apiVersion, correlationID, c.clientID, msg, limits, crc)
 }
 
 var (
 	_ net.Conn       = (*Conn)(nil)
 	_ bufferedReader = (*Conn)(nil)
+	_ buffersWriter  = (*Conn)(nil)
 )
@@ -0,0 +1,128 @@
+package consumergroupdescribe
+
+import "github.com/segmentio/kafka-go/protocol"
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_ConsumerGroupDescribe
+type Request struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	GroupIDs                    []string `kafka:"min=v0,max=v0"`
+	IncludeAuthorizedOperations bool     `kafka:"min=v0,max=v0"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.ConsumerGroupDescribe }
+
+func (r *Request) Group() string {
+	return r.GroupIDs[0]
+}
+
+func (r *Request) Split(cluster protocol.Cluster) (
+	[]protocol.Message,
+	protocol.Merger,
+	error,
+) {
+	messages := []protocol.Message{}
+
+	// Split requests by group since they'll need to go to different coordinators.
+	for _, group := range r.GroupIDs {
+		messages = append(
+			messages,
+			&Request{
+				GroupIDs:                    []string{group},
+				IncludeAuthorizedOperations: r.IncludeAuthorizedOperations,
+			},
+		)
+	}
+
+	return messages, new(Response), nil
+}
+
+type Response struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	ThrottleTimeMs int32           `kafka:"min=v0,max=v0"`
+	Groups         []ResponseGroup `kafka:"min=v0,max=v0"`
+}
+
+type ResponseGroup struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	ErrorCode            int16                 `kafka:"min=v0,max=v0"`
+	ErrorMessage         string                `kafka:"min=v0,max=v0,nullable"`
+	GroupID              string                `kafka:"min=v0,max=v0"`
+	GroupState           string                `kafka:"min=v0,max=v0"`
+	GroupEpoch           int32                 `kafka:"min=v0,max=v0"`
+	AssignmentEpoch      int32                 `kafka:"min=v0,max=v0"`
+	AssignorName         string                `kafka:"min=v0,max=v0"`
+	Members              []ResponseGroupMember `kafka:"min=v0,max=v0"`
+	AuthorizedOperations int32                 `kafka:"min=v0,max=v0"`
+}
+
+type ResponseGroupMember struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	MemberID             string     `kafka:"min=v0,max=v0"`
+	InstanceID           string     `kafka:"min=v0,max=v0,nullable"`
+	RackID               string     `kafka:"min=v0,max=v0,nullable"`
+	MemberEpoch          int32      `kafka:"min=v0,max=v0"`
+	ClientID             string     `kafka:"min=v0,max=v0"`
+	ClientHost           string     `kafka:"min=v0,max=v0"`
+	SubscribedTopicNames []string   `kafka:"min=v0,max=v0"`
+	SubscribedTopicRegex string     `kafka:"min=v0,max=v0,nullable"`
+	Assignment           Assignment `kafka:"min=v0,max=v0"`
+	TargetAssignment     Assignment `kafka:"min=v0,max=v0"`
+}
+
+type Assignment struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	TopicPartitions []TopicPartitions `kafka:"min=v0,max=v0"`
+}
+
+type TopicPartitions struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	TopicID    protocol.UUID `kafka:"min=v0,max=v0"`
+	TopicName  string        `kafka:"min=v0,max=v0"`
+	Partitions []int32       `kafka:"min=v0,max=v0"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.ConsumerGroupDescribe }
+
+func (r *Response) Merge(requests []protocol.Message, results []interface{}) (
+	protocol.Message,
+	error,
+) {
+	response := &Response{}
+
+	for _, result := range results {
+		res := result.(*Response)
+		if res.ThrottleTimeMs > response.ThrottleTimeMs {
+			response.ThrottleTimeMs = res.ThrottleTimeMs
+		}
+		response.Groups = append(response.Groups, res.Groups...)
+	}
+
+	return response, nil
+}
+
+var (
+	_ protocol.GroupMessage = (*Request)(nil)
+	_ protocol.Splitter     = (*Request)(nil)
+)
@@ -0,0 +1,63 @@
+package consumergroupdescribe_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/consumergroupdescribe"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+const (
+	v0 = 0
+)
+
+func TestConsumerGroupDescribeRequest(t *testing.T) {
+	prototest.TestRequest(t, v0, &consumergroupdescribe.Request{
+		GroupIDs:                    []string{"group-1", "group-2"},
+		IncludeAuthorizedOperations: true,
+	})
+}
+
+func TestConsumerGroupDescribeResponse(t *testing.T) {
+	assignment := consumergroupdescribe.Assignment{
+		TopicPartitions: []consumergroupdescribe.TopicPartitions{
+			{
+				TopicID:    protocol.UUID{0: 1, 15: 2},
+				TopicName:  "topic-1",
+				Partitions: []int32{0, 1},
+			},
+		},
+	}
+
+	prototest.TestResponse(t, v0, &consumergroupdescribe.Response{
+		ThrottleTimeMs: 10,
+		Groups: []consumergroupdescribe.ResponseGroup{
+			{
+				GroupID:         "group-1",
+				GroupState:      "Stable",
+				GroupEpoch:      4,
+				AssignmentEpoch: 4,
+				AssignorName:    "uniform",
+				Members: []consumergroupdescribe.ResponseGroupMember{
+					{
+						MemberID:             "member-1",
+						InstanceID:           "instance-1",
+						MemberEpoch:          4,
+						ClientID:             "client-1",
+						ClientHost:           "/127.0.0.1",
+						SubscribedTopicNames: []string{"topic-1"},
+						Assignment:           assignment,
+						TargetAssignment:     assignment,
+					},
+				},
+				AuthorizedOperations: -2147483648,
+			},
+			{
+				ErrorCode:    69,
+				ErrorMessage: "group id not found",
+				GroupID:      "group-2",
+			},
+		},
+	})
+}
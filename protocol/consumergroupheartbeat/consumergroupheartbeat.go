@@ -0,0 +1,65 @@
+package consumergroupheartbeat
+
+import "github.com/segmentio/kafka-go/protocol"
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_ConsumerGroupHeartbeat
+type Request struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	GroupID              string            `kafka:"min=v0,max=v0"`
+	MemberID             string            `kafka:"min=v0,max=v0"`
+	MemberEpoch          int32             `kafka:"min=v0,max=v0"`
+	InstanceID           string            `kafka:"min=v0,max=v0,nullable"`
+	RackID               string            `kafka:"min=v0,max=v0,nullable"`
+	RebalanceTimeoutMs   int32             `kafka:"min=v0,max=v0"`
+	SubscribedTopicNames []string          `kafka:"min=v0,max=v0,nullable"`
+	ServerAssignor       string            `kafka:"min=v0,max=v0,nullable"`
+	TopicPartitions      []TopicPartitions `kafka:"min=v0,max=v0,nullable"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.ConsumerGroupHeartbeat }
+
+func (r *Request) Group() string { return r.GroupID }
+
+type TopicPartitions struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	TopicID    protocol.UUID `kafka:"min=v0,max=v0"`
+	Partitions []int32       `kafka:"min=v0,max=v0"`
+}
+
+type Response struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	ThrottleTimeMs      int32       `kafka:"min=v0,max=v0"`
+	ErrorCode           int16       `kafka:"min=v0,max=v0"`
+	ErrorMessage        string      `kafka:"min=v0,max=v0,nullable"`
+	MemberID            string      `kafka:"min=v0,max=v0,nullable"`
+	MemberEpoch         int32       `kafka:"min=v0,max=v0"`
+	HeartbeatIntervalMs int32       `kafka:"min=v0,max=v0"`
+	Assignment          *Assignment `kafka:"min=v0,max=v0"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.ConsumerGroupHeartbeat }
+
+// Assignment is the set of partitions assigned to the member. It is nil when
+// the assignment did not change since the last heartbeat.
+type Assignment struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	TopicPartitions []TopicPartitions `kafka:"min=v0,max=v0"`
+}
+
+var _ protocol.GroupMessage = (*Request)(nil)
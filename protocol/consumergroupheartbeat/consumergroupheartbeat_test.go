@@ -0,0 +1,57 @@
+package consumergroupheartbeat_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/consumergroupheartbeat"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+const (
+	v0 = 0
+)
+
+var topicID = protocol.UUID{0: 1, 7: 2, 15: 3}
+
+func TestConsumerGroupHeartbeatRequest(t *testing.T) {
+	prototest.TestRequest(t, v0, &consumergroupheartbeat.Request{
+		GroupID:              "group-1",
+		MemberEpoch:          0,
+		InstanceID:           "instance-1",
+		RackID:               "rack-1",
+		RebalanceTimeoutMs:   30000,
+		SubscribedTopicNames: []string{"topic-1", "topic-2"},
+		ServerAssignor:       "uniform",
+		TopicPartitions:      []consumergroupheartbeat.TopicPartitions{},
+	})
+
+	prototest.TestRequest(t, v0, &consumergroupheartbeat.Request{
+		GroupID:     "group-1",
+		MemberID:    "member-1",
+		MemberEpoch: 3,
+		TopicPartitions: []consumergroupheartbeat.TopicPartitions{
+			{TopicID: topicID, Partitions: []int32{0, 1, 2}},
+		},
+	})
+}
+
+func TestConsumerGroupHeartbeatResponse(t *testing.T) {
+	prototest.TestResponse(t, v0, &consumergroupheartbeat.Response{
+		ThrottleTimeMs:      10,
+		MemberID:            "member-1",
+		MemberEpoch:         3,
+		HeartbeatIntervalMs: 5000,
+		Assignment: &consumergroupheartbeat.Assignment{
+			TopicPartitions: []consumergroupheartbeat.TopicPartitions{
+				{TopicID: topicID, Partitions: []int32{0, 1, 2}},
+			},
+		},
+	})
+
+	prototest.TestResponse(t, v0, &consumergroupheartbeat.Response{
+		ErrorCode:    110,
+		ErrorMessage: "fenced",
+		MemberEpoch:  -1,
+	})
+}
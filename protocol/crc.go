@@ -0,0 +1,55 @@
+package protocol
+
+import (
+	"fmt"
+	"hash/crc32"
+)
+
+// castagnoliTable is the table of the CRC32-C checksums of record batches. The
+// hash/crc32 package uses the SSE 4.2 or ARM64 CRC32 instructions when updating
+// checksums with this table on platforms which support them.
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// CRCMode configures how the checksums of record batches are verified when
+// they are decoded.
+type CRCMode int
+
+const (
+	// CRCStrict verifies the checksums, corrupted batches are skipped and
+	// reported by a CorruptRecordError. This is the default mode.
+	CRCStrict CRCMode = iota
+
+	// CRCWarn verifies the checksums, corrupted batches are reported by a
+	// CorruptRecordError, but their records are still exposed.
+	CRCWarn
+
+	// CRCOff disables the verification of checksums.
+	CRCOff
+)
+
+// CorruptRecordError is the error returned when reading records of a batch
+// that failed checksum verification.
+//
+// The error is returned by the RecordReader of the record set, in place of the
+// records of the corrupted batch. Programs may skip the batch by calling
+// ReadRecord again, which moves on to the next batch in CRCStrict mode, or to
+// the records of the corrupted batch in CRCWarn mode.
+type CorruptRecordError struct {
+	// The topic and partition that the batch was read from, if known.
+	Topic     string
+	Partition int
+
+	// The offsets of the first and last records of the batch.
+	BaseOffset int64
+	LastOffset int64
+
+	// The checksum computed from the content of the batch, and the one found
+	// in its header.
+	Computed uint32
+	Found    uint32
+}
+
+func (e *CorruptRecordError) Error() string {
+	return fmt.Sprintf("corrupt record batch at offsets %d-%d of %s[%d]: crc32 checksum mismatch (computed=%d found=%d)",
+		e.BaseOffset, e.LastOffset, e.Topic, e.Partition, e.Computed, e.Found)
+}
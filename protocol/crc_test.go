@@ -0,0 +1,97 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestCorruptRecordBatch(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+
+	batch0 := []memoryRecord{
+		{offset: 0, time: now, value: []byte("value-0")},
+		{offset: 1, time: now, value: []byte("value-1")},
+	}
+	batch1 := []memoryRecord{
+		{offset: 2, time: now, value: []byte("value-2")},
+	}
+
+	tests := []struct {
+		scenario string
+		attrs    Attributes
+		mode     CRCMode
+		payload  bool // corrupt the payload instead of the checksum
+		expected []memoryRecord
+		corrupt  bool
+	}{
+		{
+			scenario: "corrupted batches are skipped in strict mode",
+			mode:     CRCStrict,
+			expected: batch1,
+			corrupt:  true,
+		},
+		{
+			scenario: "corrupted compressed batches are skipped in strict mode",
+			attrs:    Gzip,
+			mode:     CRCStrict,
+			payload:  true,
+			expected: batch1,
+			corrupt:  true,
+		},
+		{
+			scenario: "corrupted batches are exposed in warn mode",
+			mode:     CRCWarn,
+			expected: append(append([]memoryRecord{}, batch0...), batch1...),
+			corrupt:  true,
+		},
+		{
+			scenario: "checksums are not verified when disabled",
+			mode:     CRCOff,
+			expected: append(append([]memoryRecord{}, batch0...), batch1...),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			if err := WriteRecordBatch(b, test.attrs, NewRecordReader(makeRecords(batch0)...)); err != nil {
+				t.Fatal(err)
+			}
+			if test.payload {
+				b.Bytes()[b.Len()-1] ^= 1 // last byte of the compressed records
+			} else {
+				b.Bytes()[17] ^= 1 // first byte of the checksum
+			}
+
+			if err := WriteRecordBatch(b, test.attrs, NewRecordReader(makeRecords(batch1)...)); err != nil {
+				t.Fatal(err)
+			}
+
+			rs := &RecordSet{}
+			d := &decoder{reader: b, remain: b.Len(), crc: test.mode}
+			if _, err := rs.readRecords(d, b.Len()); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.corrupt {
+				_, err := rs.Records.ReadRecord()
+				var corrupt *CorruptRecordError
+				if !errors.As(err, &corrupt) {
+					t.Fatalf("expected a corrupt record error but got %v", err)
+				}
+				if corrupt.BaseOffset != 0 || corrupt.LastOffset != 1 {
+					t.Errorf("wrong offsets of the corrupted batch: %d-%d", corrupt.BaseOffset, corrupt.LastOffset)
+				}
+			}
+
+			assertRecords(t, rs.Records, NewRecordReader(makeRecords(test.expected)...))
+
+			if _, err := rs.Records.ReadRecord(); !errors.Is(err, io.EOF) {
+				t.Errorf("expected io.EOF but got %v", err)
+			}
+		})
+	}
+}
@@ -9,7 +9,7 @@ func init() {
 type Request struct {
 	// We need at least one tagged field to indicate that v2+ uses "flexible"
 	// messages.
-	_ struct{} `kafka:"min=v2,max=v2,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
 
 	Creations []RequestACLs `kafka:"min=v0,max=v2"`
 }
@@ -21,9 +21,11 @@ func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
 }
 
 type RequestACLs struct {
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
 	ResourceType        int8   `kafka:"min=v0,max=v2"`
 	ResourceName        string `kafka:"min=v0,max=v2"`
-	ResourcePatternType int8   `kafka:"min=v0,max=v2"`
+	ResourcePatternType int8   `kafka:"min=v1,max=v2"`
 	Principal           string `kafka:"min=v0,max=v2"`
 	Host                string `kafka:"min=v0,max=v2"`
 	Operation           int8   `kafka:"min=v0,max=v2"`
@@ -33,7 +35,7 @@ type RequestACLs struct {
 type Response struct {
 	// We need at least one tagged field to indicate that v2+ uses "flexible"
 	// messages.
-	_ struct{} `kafka:"min=v2,max=v2,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
 
 	ThrottleTimeMs int32          `kafka:"min=v0,max=v2"`
 	Results        []ResponseACLs `kafka:"min=v0,max=v2"`
@@ -42,6 +44,8 @@ type Response struct {
 func (r *Response) ApiKey() protocol.ApiKey { return protocol.CreateAcls }
 
 type ResponseACLs struct {
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
 	ErrorCode    int16  `kafka:"min=v0,max=v2"`
 	ErrorMessage string `kafka:"min=v0,max=v2,nullable"`
 }
@@ -0,0 +1,52 @@
+package createdelegationtoken
+
+import "github.com/segmentio/kafka-go/protocol"
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_CreateDelegationToken
+type Request struct {
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
+	Renewers      []RequestRenewer `kafka:"min=v0,max=v2"`
+	MaxLifetimeMs int64            `kafka:"min=v0,max=v2"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.CreateDelegationToken }
+
+func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	return cluster.Brokers[cluster.Controller], nil
+}
+
+type RequestRenewer struct {
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
+	PrincipalType string `kafka:"min=v0,max=v2"`
+	PrincipalName string `kafka:"min=v0,max=v2"`
+}
+
+type Response struct {
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
+	ErrorCode         int16  `kafka:"min=v0,max=v2"`
+	PrincipalType     string `kafka:"min=v0,max=v2"`
+	PrincipalName     string `kafka:"min=v0,max=v2"`
+	IssueTimestampMs  int64  `kafka:"min=v0,max=v2"`
+	ExpiryTimestampMs int64  `kafka:"min=v0,max=v2"`
+	MaxTimestampMs    int64  `kafka:"min=v0,max=v2"`
+	TokenID           string `kafka:"min=v0,max=v2"`
+	HMAC              []byte `kafka:"min=v0,max=v2"`
+	ThrottleTimeMs    int32  `kafka:"min=v0,max=v2"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.CreateDelegationToken }
+
+var _ protocol.BrokerMessage = (*Request)(nil)
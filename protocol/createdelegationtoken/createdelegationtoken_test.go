@@ -0,0 +1,37 @@
+package createdelegationtoken_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/createdelegationtoken"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+func TestCreateDelegationTokenRequest(t *testing.T) {
+	for _, version := range []int16{0, 1, 2} {
+		prototest.TestRequest(t, version, &createdelegationtoken.Request{
+			MaxLifetimeMs: 3600000,
+			Renewers: []createdelegationtoken.RequestRenewer{
+				{
+					PrincipalType: "User",
+					PrincipalName: "alice",
+				},
+			},
+		})
+	}
+}
+
+func TestCreateDelegationTokenResponse(t *testing.T) {
+	for _, version := range []int16{0, 1, 2} {
+		prototest.TestResponse(t, version, &createdelegationtoken.Response{
+			PrincipalType:     "User",
+			PrincipalName:     "alice",
+			IssueTimestampMs:  1000,
+			ExpiryTimestampMs: 2000,
+			MaxTimestampMs:    3000,
+			TokenID:           "token-0",
+			HMAC:              []byte("hmac"),
+			ThrottleTimeMs:    500,
+		})
+	}
+}
@@ -7,11 +7,14 @@ func init() {
 }
 
 // Detailed API definition: https://kafka.apache.org/protocol#The_Messages_CreatePartitions.
-// TODO: Support version 2.
 type Request struct {
-	Topics       []RequestTopic `kafka:"min=v0,max=v1"`
-	TimeoutMs    int32          `kafka:"min=v0,max=v1"`
-	ValidateOnly bool           `kafka:"min=v0,max=v1"`
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v3,tag"`
+
+	Topics       []RequestTopic `kafka:"min=v0,max=v3"`
+	TimeoutMs    int32          `kafka:"min=v0,max=v3"`
+	ValidateOnly bool           `kafka:"min=v0,max=v3"`
 }
 
 func (r *Request) ApiKey() protocol.ApiKey { return protocol.CreatePartitions }
@@ -21,26 +24,42 @@ func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
 }
 
 type RequestTopic struct {
-	Name        string              `kafka:"min=v0,max=v1"`
-	Count       int32               `kafka:"min=v0,max=v1"`
-	Assignments []RequestAssignment `kafka:"min=v0,max=v1,nullable"`
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v3,tag"`
+
+	Name        string              `kafka:"min=v0,max=v3"`
+	Count       int32               `kafka:"min=v0,max=v3"`
+	Assignments []RequestAssignment `kafka:"min=v0,max=v3,nullable"`
 }
 
 type RequestAssignment struct {
-	BrokerIDs []int32 `kafka:"min=v0,max=v1"`
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v3,tag"`
+
+	BrokerIDs []int32 `kafka:"min=v0,max=v3"`
 }
 
 type Response struct {
-	ThrottleTimeMs int32            `kafka:"min=v0,max=v1"`
-	Results        []ResponseResult `kafka:"min=v0,max=v1"`
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v3,tag"`
+
+	ThrottleTimeMs int32            `kafka:"min=v0,max=v3"`
+	Results        []ResponseResult `kafka:"min=v0,max=v3"`
 }
 
 func (r *Response) ApiKey() protocol.ApiKey { return protocol.CreatePartitions }
 
 type ResponseResult struct {
-	Name         string `kafka:"min=v0,max=v1"`
-	ErrorCode    int16  `kafka:"min=v0,max=v1"`
-	ErrorMessage string `kafka:"min=v0,max=v1,nullable"`
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v3,tag"`
+
+	Name         string `kafka:"min=v0,max=v3"`
+	ErrorCode    int16  `kafka:"min=v0,max=v3"`
+	ErrorMessage string `kafka:"min=v0,max=v3,nullable"`
 }
 
 var _ protocol.BrokerMessage = (*Request)(nil)
@@ -10,6 +10,8 @@ import (
 const (
 	v0 = 0
 	v1 = 1
+	v2 = 2
+	v3 = 3
 )
 
 func TestCreatePartitionsRequest(t *testing.T) {
@@ -44,6 +46,27 @@ func TestCreatePartitionsRequest(t *testing.T) {
 		TimeoutMs:    500,
 		ValidateOnly: false,
 	})
+
+	for _, version := range []int16{v2, v3} {
+		prototest.TestRequest(t, version, &createpartitions.Request{
+			Topics: []createpartitions.RequestTopic{
+				{
+					Name:  "foo",
+					Count: 3,
+					Assignments: []createpartitions.RequestAssignment{
+						{BrokerIDs: []int32{1, 2}},
+						{BrokerIDs: []int32{2, 3}},
+					},
+				},
+				{
+					Name:  "bar",
+					Count: 2,
+				},
+			},
+			TimeoutMs:    500,
+			ValidateOnly: true,
+		})
+	}
 }
 
 func TestCreatePartitionsResponse(t *testing.T) {
@@ -68,4 +91,20 @@ func TestCreatePartitionsResponse(t *testing.T) {
 			},
 		},
 	})
+
+	for _, version := range []int16{v2, v3} {
+		prototest.TestResponse(t, version, &createpartitions.Response{
+			ThrottleTimeMs: 500,
+			Results: []createpartitions.ResponseResult{
+				{
+					Name:         "foo",
+					ErrorCode:    1,
+					ErrorMessage: "foo",
+				},
+				{
+					Name: "bar",
+				},
+			},
+		})
+	}
 }
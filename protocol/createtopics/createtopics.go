@@ -9,7 +9,7 @@ func init() {
 type Request struct {
 	// We need at least one tagged field to indicate that v5+ uses "flexible"
 	// messages.
-	_ struct{} `kafka:"min=v5,max=v5,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v5,max=v5,tag"`
 
 	Topics       []RequestTopic `kafka:"min=v0,max=v5"`
 	TimeoutMs    int32          `kafka:"min=v0,max=v5"`
@@ -23,6 +23,8 @@ func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
 }
 
 type RequestTopic struct {
+	TaggedFields protocol.TaggedFields `kafka:"min=v5,max=v5,tag"`
+
 	Name              string              `kafka:"min=v0,max=v5"`
 	NumPartitions     int32               `kafka:"min=v0,max=v5"`
 	ReplicationFactor int16               `kafka:"min=v0,max=v5"`
@@ -31,11 +33,15 @@ type RequestTopic struct {
 }
 
 type RequestAssignment struct {
+	TaggedFields protocol.TaggedFields `kafka:"min=v5,max=v5,tag"`
+
 	PartitionIndex int32   `kafka:"min=v0,max=v5"`
 	BrokerIDs      []int32 `kafka:"min=v0,max=v5"`
 }
 
 type RequestConfig struct {
+	TaggedFields protocol.TaggedFields `kafka:"min=v5,max=v5,tag"`
+
 	Name  string `kafka:"min=v0,max=v5"`
 	Value string `kafka:"min=v0,max=v5,nullable"`
 }
@@ -43,7 +49,7 @@ type RequestConfig struct {
 type Response struct {
 	// We need at least one tagged field to indicate that v5+ uses "flexible"
 	// messages.
-	_ struct{} `kafka:"min=v5,max=v5,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v5,max=v5,tag"`
 
 	ThrottleTimeMs int32           `kafka:"min=v2,max=v5"`
 	Topics         []ResponseTopic `kafka:"min=v0,max=v5"`
@@ -52,6 +58,8 @@ type Response struct {
 func (r *Response) ApiKey() protocol.ApiKey { return protocol.CreateTopics }
 
 type ResponseTopic struct {
+	TaggedFields protocol.TaggedFields `kafka:"min=v5,max=v5,tag"`
+
 	Name              string `kafka:"min=v0,max=v5"`
 	ErrorCode         int16  `kafka:"min=v0,max=v5"`
 	ErrorMessage      string `kafka:"min=v1,max=v5,nullable"`
@@ -62,6 +70,8 @@ type ResponseTopic struct {
 }
 
 type ResponseTopicConfig struct {
+	TaggedFields protocol.TaggedFields `kafka:"min=v5,max=v5,tag"`
+
 	Name         string `kafka:"min=v5,max=v5"`
 	Value        string `kafka:"min=v5,max=v5,nullable"`
 	ReadOnly     bool   `kafka:"min=v5,max=v5"`
@@ -7,6 +7,7 @@ import (
 	"hash/crc32"
 	"io"
 	"io/ioutil"
+	"math"
 	"reflect"
 	"sync"
 	"sync/atomic"
@@ -23,6 +24,9 @@ type decoder struct {
 	err    error
 	table  *crc32.Table
 	crc32  uint32
+	limits Limits
+	crc    CRCMode
+	arena  *stringArena
 }
 
 func (d *decoder) Reset(r io.Reader, n int) {
@@ -32,6 +36,9 @@ func (d *decoder) Reset(r io.Reader, n int) {
 	d.err = nil
 	d.table = nil
 	d.crc32 = 0
+	d.limits = Limits{}
+	d.crc = CRCStrict
+	d.arena = nil
 }
 
 func (d *decoder) Read(b []byte) (int, error) {
@@ -85,6 +92,10 @@ func (d *decoder) decodeInt64(v value) {
 	v.setInt64(d.readInt64())
 }
 
+func (d *decoder) decodeFloat64(v value) {
+	v.setFloat64(d.readFloat64())
+}
+
 func (d *decoder) decodeString(v value) {
 	v.setString(d.readString())
 }
@@ -93,6 +104,14 @@ func (d *decoder) decodeCompactString(v value) {
 	v.setString(d.readCompactString())
 }
 
+func (d *decoder) decodeInternedString(v value) {
+	v.setString(d.readInternedString(int(d.readInt16())))
+}
+
+func (d *decoder) decodeCompactInternedString(v value) {
+	v.setString(d.readInternedString(int(d.readUnsignedVarInt()) - 1))
+}
+
 func (d *decoder) decodeBytes(v value) {
 	v.setBytes(d.readBytes())
 }
@@ -144,6 +163,12 @@ func (d *decoder) discard(n int) {
 }
 
 func (d *decoder) read(n int) []byte {
+	if n > d.remain {
+		// Lengths greater than the remaining input can only come from corrupted
+		// messages, reject them before allocating memory.
+		d.setError(io.ErrUnexpectedEOF)
+		return nil
+	}
 	b := make([]byte, n)
 	n, err := io.ReadFull(d, b)
 	b = b[:n]
@@ -177,7 +202,30 @@ func (d *decoder) readFull(b []byte) bool {
 	return n == len(b)
 }
 
+// contiguous returns the bytes that the decoder can read without copying them,
+// which is when it reads from a page buffer and does not compute a checksum.
+// The bytes are consumed by calling skip.
+func (d *decoder) contiguous() (*pageBuffer, []byte) {
+	if d.table != nil || d.err != nil {
+		return nil, nil
+	}
+	pb, _ := d.reader.(*pageBuffer)
+	if pb == nil {
+		return nil, nil
+	}
+	b := pb.contiguous()
+	if len(b) > d.remain {
+		b = b[:d.remain]
+	}
+	return pb, b
+}
+
 func (d *decoder) readByte() byte {
+	if pb, b := d.contiguous(); len(b) != 0 {
+		pb.cursor++
+		d.remain--
+		return b[0]
+	}
 	if d.readFull(d.buffer[:1]) {
 		return d.buffer[0]
 	}
@@ -189,6 +237,11 @@ func (d *decoder) readBool() bool {
 }
 
 func (d *decoder) readInt8() int8 {
+	if pb, b := d.contiguous(); len(b) != 0 {
+		pb.cursor++
+		d.remain--
+		return int8(b[0])
+	}
 	if d.readFull(d.buffer[:1]) {
 		return readInt8(d.buffer[:1])
 	}
@@ -216,11 +269,18 @@ func (d *decoder) readInt64() int64 {
 	return 0
 }
 
+func (d *decoder) readFloat64() float64 {
+	if d.readFull(d.buffer[:8]) {
+		return readFloat64(d.buffer[:8])
+	}
+	return 0
+}
+
 func (d *decoder) readString() string {
 	if n := d.readInt16(); n < 0 {
 		return ""
 	} else {
-		return bytesToString(d.read(int(n)))
+		return d.readStringBytes(int(n))
 	}
 }
 
@@ -228,7 +288,7 @@ func (d *decoder) readVarString() string {
 	if n := d.readVarInt(); n < 0 {
 		return ""
 	} else {
-		return bytesToString(d.read(int(n)))
+		return d.readStringBytes(int(n))
 	}
 }
 
@@ -236,8 +296,48 @@ func (d *decoder) readCompactString() string {
 	if n := d.readUnsignedVarInt(); n < 1 {
 		return ""
 	} else {
-		return bytesToString(d.read(int(n - 1)))
+		return d.readStringBytes(int(n - 1))
+	}
+}
+
+func (d *decoder) readStringBytes(n int) string {
+	if !d.checkLimit("MaxStringLength", n, d.limits.MaxStringLength) {
+		return ""
+	}
+	if d.arena == nil {
+		return bytesToString(d.read(n))
 	}
+	if n > d.remain {
+		d.setError(io.ErrUnexpectedEOF)
+		return ""
+	}
+	b := d.arena.alloc(n)
+	if !d.readFull(b) {
+		return ""
+	}
+	return bytesToString(b)
+}
+
+// readInternedString reads a string of length n, returning the same string
+// for identical values. Negative lengths are null strings.
+func (d *decoder) readInternedString(n int) string {
+	if n < 0 || !d.checkLimit("MaxStringLength", n, d.limits.MaxStringLength) {
+		return ""
+	}
+	if n > d.remain {
+		d.setError(io.ErrUnexpectedEOF)
+		return ""
+	}
+	var b []byte
+	if d.arena != nil {
+		b = d.arena.buffer(n)
+	} else {
+		b = make([]byte, n)
+	}
+	if !d.readFull(b) {
+		return ""
+	}
+	return internedStrings.intern(b)
 }
 
 func (d *decoder) readBytes() []byte {
@@ -265,6 +365,17 @@ func (d *decoder) readCompactBytes() []byte {
 }
 
 func (d *decoder) readVarInt() int64 {
+	// Kafka varints use the same zig-zag encoding as the encoding/binary
+	// package, which decodes them in bulk when the bytes are contiguous.
+	// Varints which straddle pages or are invalid take the slow path.
+	if pb, b := d.contiguous(); len(b) != 0 {
+		if x, n := binary.Varint(b); n > 0 {
+			pb.cursor += n
+			d.remain -= n
+			return x
+		}
+	}
+
 	n := 11 // varints are at most 11 bytes
 
 	if n > d.remain {
@@ -292,6 +403,14 @@ func (d *decoder) readVarInt() int64 {
 }
 
 func (d *decoder) readUnsignedVarInt() uint64 {
+	if pb, b := d.contiguous(); len(b) != 0 {
+		if x, n := binary.Uvarint(b); n > 0 {
+			pb.cursor += n
+			d.remain -= n
+			return x
+		}
+	}
+
 	n := 11 // varints are at most 11 bytes
 
 	if n > d.remain {
@@ -325,9 +444,15 @@ var (
 	_ io.ByteReader = (*decoder)(nil)
 
 	readerFrom = reflect.TypeOf((*io.ReaderFrom)(nil)).Elem()
+
+	recordSetType    = reflect.TypeOf(RecordSet{})
+	recordSetPtrType = reflect.PtrTo(recordSetType)
 )
 
 func decodeFuncOf(typ reflect.Type, version int16, flexible bool, tag structTag) decodeFunc {
+	if flexible && typ == recordSetType {
+		return compactRecordSetDecodeFunc
+	}
 	if reflect.PtrTo(typ).Implements(readerFrom) {
 		return readerDecodeFuncOf(typ)
 	}
@@ -342,6 +467,8 @@ func decodeFuncOf(typ reflect.Type, version int16, flexible bool, tag structTag)
 		return (*decoder).decodeInt32
 	case reflect.Int64:
 		return (*decoder).decodeInt64
+	case reflect.Float64:
+		return (*decoder).decodeFloat64
 	case reflect.String:
 		return stringDecodeFuncOf(flexible, tag)
 	case reflect.Struct:
@@ -351,12 +478,23 @@ func decodeFuncOf(typ reflect.Type, version int16, flexible bool, tag structTag)
 			return bytesDecodeFuncOf(flexible, tag)
 		}
 		return arrayDecodeFuncOf(typ, version, flexible, tag)
+	case reflect.Ptr:
+		if typ.Elem().Kind() == reflect.Struct { // nullable struct
+			return pointerDecodeFuncOf(typ, version, flexible, tag)
+		}
+		panic("unsupported type: " + typ.String())
 	default:
 		panic("unsupported type: " + typ.String())
 	}
 }
 
 func stringDecodeFuncOf(flexible bool, tag structTag) decodeFunc {
+	if tag.Intern {
+		if flexible {
+			return (*decoder).decodeCompactInternedString
+		}
+		return (*decoder).decodeInternedString
+	}
 	if flexible {
 		// In flexible messages, all strings are compact
 		return (*decoder).decodeCompactString
@@ -381,8 +519,12 @@ func structDecodeFuncOf(typ reflect.Type, version int16, flexible bool) decodeFu
 
 	var fields []field
 	taggedFields := map[int]*field{}
+	unknownFields, hasUnknownFields := taggedFieldsIndexOf(typ, version)
 
 	forEachStructField(typ, func(typ reflect.Type, index index, tag string) {
+		if typ == taggedFieldsType {
+			return
+		}
 		forEachStructTag(tag, func(tag structTag) bool {
 			if tag.MinVersion <= version && version <= tag.MaxVersion {
 				f := field{
@@ -420,9 +562,13 @@ func structDecodeFuncOf(typ reflect.Type, version int16, flexible bool) decodeFu
 				size := int(d.readUnsignedVarInt())
 
 				f, ok := taggedFields[tagID]
-				if ok {
+				switch {
+				case ok:
 					f.decode(d, v.fieldByIndex(f.index))
-				} else {
+				case hasUnknownFields:
+					unknown := v.fieldByIndex(unknownFields).iface(taggedFieldsPtrType).(*TaggedFields)
+					*unknown = append(*unknown, TaggedField{Tag: tagID, Data: d.read(size)})
+				default:
 					d.read(size)
 				}
 			}
@@ -441,6 +587,25 @@ func arrayDecodeFuncOf(typ reflect.Type, version int16, flexible bool, tag struc
 	return func(d *decoder, v value) { d.decodeArray(v, elemType, elemFunc) }
 }
 
+func pointerDecodeFuncOf(typ reflect.Type, version int16, flexible bool, tag structTag) decodeFunc {
+	elemType := typ.Elem()
+	elemFunc := decodeFuncOf(elemType, version, flexible, tag)
+	return func(d *decoder, v value) {
+		// Nullable structs are prefixed with a byte set to -1 when the value
+		// is null, and 1 when it is present.
+		if d.readInt8() < 0 {
+			return
+		}
+		elemFunc(d, v.setNew(elemType))
+	}
+}
+
+func compactRecordSetDecodeFunc(d *decoder, v value) {
+	if d.err == nil {
+		d.readCompactRecordSet(v.iface(recordSetPtrType).(*RecordSet))
+	}
+}
+
 func readerDecodeFuncOf(typ reflect.Type) decodeFunc {
 	typ = reflect.PtrTo(typ)
 	return func(d *decoder, v value) {
@@ -469,6 +634,10 @@ func readInt64(b []byte) int64 {
 	return int64(binary.BigEndian.Uint64(b))
 }
 
+func readFloat64(b []byte) float64 {
+	return math.Float64frombits(binary.BigEndian.Uint64(b))
+}
+
 func Unmarshal(data []byte, version int16, value interface{}) error {
 	typ := elemTypeOf(value)
 	cache, _ := unmarshalers.Load().(map[versionedType]decodeFunc)
@@ -0,0 +1,75 @@
+package deleteacls
+
+import "github.com/segmentio/kafka-go/protocol"
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_DeleteAcls
+type Request struct {
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
+	Filters []RequestFilter `kafka:"min=v0,max=v2"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.DeleteAcls }
+
+func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	return cluster.Brokers[cluster.Controller], nil
+}
+
+type RequestFilter struct {
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
+	ResourceTypeFilter int8   `kafka:"min=v0,max=v2"`
+	ResourceNameFilter string `kafka:"min=v0,max=v2,nullable"`
+	PatternTypeFilter  int8   `kafka:"min=v1,max=v2"`
+	PrincipalFilter    string `kafka:"min=v0,max=v2,nullable"`
+	HostFilter         string `kafka:"min=v0,max=v2,nullable"`
+	Operation          int8   `kafka:"min=v0,max=v2"`
+	PermissionType     int8   `kafka:"min=v0,max=v2"`
+}
+
+type Response struct {
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
+	ThrottleTimeMs int32                  `kafka:"min=v0,max=v2"`
+	FilterResults  []ResponseFilterResult `kafka:"min=v0,max=v2"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.DeleteAcls }
+
+type ResponseFilterResult struct {
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
+	ErrorCode    int16                 `kafka:"min=v0,max=v2"`
+	ErrorMessage string                `kafka:"min=v0,max=v2,nullable"`
+	MatchingACLs []ResponseMatchingACL `kafka:"min=v0,max=v2"`
+}
+
+type ResponseMatchingACL struct {
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
+	ErrorCode      int16  `kafka:"min=v0,max=v2"`
+	ErrorMessage   string `kafka:"min=v0,max=v2,nullable"`
+	ResourceType   int8   `kafka:"min=v0,max=v2"`
+	ResourceName   string `kafka:"min=v0,max=v2"`
+	PatternType    int8   `kafka:"min=v1,max=v2"`
+	Principal      string `kafka:"min=v0,max=v2"`
+	Host           string `kafka:"min=v0,max=v2"`
+	Operation      int8   `kafka:"min=v0,max=v2"`
+	PermissionType int8   `kafka:"min=v0,max=v2"`
+}
+
+var _ protocol.BrokerMessage = (*Request)(nil)
@@ -0,0 +1,95 @@
+package deleteacls_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/deleteacls"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+const (
+	v0 = 0
+	v1 = 1
+	v2 = 2
+)
+
+func TestDeleteACLsRequest(t *testing.T) {
+	prototest.TestRequest(t, v0, &deleteacls.Request{
+		Filters: []deleteacls.RequestFilter{
+			{
+				ResourceTypeFilter: 2,
+				ResourceNameFilter: "topic-1",
+				PrincipalFilter:    "User:alice",
+				Operation:          1,
+				PermissionType:     1,
+			},
+		},
+	})
+
+	for _, version := range []int16{v1, v2} {
+		prototest.TestRequest(t, version, &deleteacls.Request{
+			Filters: []deleteacls.RequestFilter{
+				{
+					ResourceTypeFilter: 2,
+					ResourceNameFilter: "topic-",
+					PatternTypeFilter:  4,
+					PrincipalFilter:    "User:alice",
+					HostFilter:         "*",
+					Operation:          1,
+					PermissionType:     1,
+				},
+				{
+					ResourceTypeFilter: 3,
+					PatternTypeFilter:  1,
+					Operation:          1,
+					PermissionType:     1,
+				},
+			},
+		})
+	}
+}
+
+func TestDeleteACLsResponse(t *testing.T) {
+	prototest.TestResponse(t, v0, &deleteacls.Response{
+		ThrottleTimeMs: 10,
+		FilterResults: []deleteacls.ResponseFilterResult{
+			{
+				MatchingACLs: []deleteacls.ResponseMatchingACL{
+					{
+						ResourceType:   2,
+						ResourceName:   "topic-1",
+						Principal:      "User:alice",
+						Host:           "*",
+						Operation:      3,
+						PermissionType: 3,
+					},
+				},
+			},
+		},
+	})
+
+	for _, version := range []int16{v1, v2} {
+		prototest.TestResponse(t, version, &deleteacls.Response{
+			ThrottleTimeMs: 10,
+			FilterResults: []deleteacls.ResponseFilterResult{
+				{
+					MatchingACLs: []deleteacls.ResponseMatchingACL{
+						{
+							ResourceType:   2,
+							ResourceName:   "topic-",
+							PatternType:    4,
+							Principal:      "User:alice",
+							Host:           "*",
+							Operation:      3,
+							PermissionType: 3,
+						},
+					},
+				},
+				{
+					ErrorCode:    31,
+					ErrorMessage: "Cluster authorization failed.",
+				},
+			},
+		})
+	}
+}
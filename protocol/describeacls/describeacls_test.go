@@ -0,0 +1,69 @@
+package describeacls_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/describeacls"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+const (
+	v0 = 0
+	v1 = 1
+	v2 = 2
+)
+
+func TestDescribeACLsRequest(t *testing.T) {
+	prototest.TestRequest(t, v0, &describeacls.Request{
+		ResourceTypeFilter: 2,
+		ResourceNameFilter: "topic-1",
+		PrincipalFilter:    "User:alice",
+		Operation:          3,
+		PermissionType:     3,
+	})
+
+	for _, version := range []int16{v1, v2} {
+		prototest.TestRequest(t, version, &describeacls.Request{
+			ResourceTypeFilter: 2,
+			ResourceNameFilter: "topic-",
+			PatternTypeFilter:  4,
+			PrincipalFilter:    "User:alice",
+			HostFilter:         "*",
+			Operation:          1,
+			PermissionType:     1,
+		})
+	}
+}
+
+func TestDescribeACLsResponse(t *testing.T) {
+	prototest.TestResponse(t, v0, &describeacls.Response{
+		ThrottleTimeMs: 10,
+		Resources: []describeacls.ResponseResource{
+			{
+				ResourceType: 2,
+				ResourceName: "topic-1",
+				ACLs: []describeacls.ResponseACL{
+					{Principal: "User:alice", Host: "*", Operation: 3, PermissionType: 3},
+				},
+			},
+		},
+	})
+
+	for _, version := range []int16{v1, v2} {
+		prototest.TestResponse(t, version, &describeacls.Response{
+			ThrottleTimeMs: 10,
+			ErrorCode:      0,
+			Resources: []describeacls.ResponseResource{
+				{
+					ResourceType: 2,
+					ResourceName: "topic-",
+					PatternType:  4,
+					ACLs: []describeacls.ResponseACL{
+						{Principal: "User:alice", Host: "*", Operation: 3, PermissionType: 3},
+						{Principal: "User:bob", Host: "10.0.0.1", Operation: 4, PermissionType: 2},
+					},
+				},
+			},
+		})
+	}
+}
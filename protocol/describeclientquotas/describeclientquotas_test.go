@@ -0,0 +1,60 @@
+package describeclientquotas_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/describeclientquotas"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+const (
+	v0 = 0
+	v1 = 1
+)
+
+func TestDescribeClientQuotasRequest(t *testing.T) {
+	for _, version := range []int16{v0, v1} {
+		prototest.TestRequest(t, version, &describeclientquotas.Request{
+			Strict: true,
+			Components: []describeclientquotas.Component{
+				{
+					EntityType: "user",
+					MatchType:  0,
+					Match:      "alice",
+				},
+				{
+					EntityType: "client-id",
+					MatchType:  1,
+				},
+			},
+		})
+	}
+}
+
+func TestDescribeClientQuotasResponse(t *testing.T) {
+	for _, version := range []int16{v0, v1} {
+		prototest.TestResponse(t, version, &describeclientquotas.Response{
+			ThrottleTimeMs: 500,
+			Entries: []describeclientquotas.ResponseQuota{
+				{
+					Entities: []describeclientquotas.Entity{
+						{
+							EntityType: "user",
+							EntityName: "alice",
+						},
+					},
+					Values: []describeclientquotas.Value{
+						{
+							Key:   "producer_byte_rate",
+							Value: 1048576,
+						},
+						{
+							Key:   "request_percentage",
+							Value: 12.5,
+						},
+					},
+				},
+			},
+		})
+	}
+}
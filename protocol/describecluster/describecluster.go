@@ -0,0 +1,47 @@
+package describecluster
+
+import "github.com/segmentio/kafka-go/protocol"
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_DescribeCluster
+type Request struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v1,tag"`
+
+	IncludeClusterAuthorizedOperations bool `kafka:"min=v0,max=v1"`
+	EndpointType                       int8 `kafka:"min=v1,max=v1"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.DescribeCluster }
+
+type Response struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v1,tag"`
+
+	ThrottleTimeMs              int32            `kafka:"min=v0,max=v1"`
+	ErrorCode                   int16            `kafka:"min=v0,max=v1"`
+	ErrorMessage                string           `kafka:"min=v0,max=v1,nullable"`
+	EndpointType                int8             `kafka:"min=v1,max=v1"`
+	ClusterID                   string           `kafka:"min=v0,max=v1"`
+	ControllerID                int32            `kafka:"min=v0,max=v1"`
+	Brokers                     []ResponseBroker `kafka:"min=v0,max=v1"`
+	ClusterAuthorizedOperations int32            `kafka:"min=v0,max=v1"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.DescribeCluster }
+
+type ResponseBroker struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v1,tag"`
+
+	BrokerID int32  `kafka:"min=v0,max=v1"`
+	Host     string `kafka:"min=v0,max=v1"`
+	Port     int32  `kafka:"min=v0,max=v1"`
+	Rack     string `kafka:"min=v0,max=v1,nullable"`
+}
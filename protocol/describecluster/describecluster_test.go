@@ -0,0 +1,50 @@
+package describecluster_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/describecluster"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+const (
+	v0 = 0
+	v1 = 1
+)
+
+func TestDescribeClusterRequest(t *testing.T) {
+	prototest.TestRequest(t, v0, &describecluster.Request{
+		IncludeClusterAuthorizedOperations: true,
+	})
+
+	prototest.TestRequest(t, v1, &describecluster.Request{
+		IncludeClusterAuthorizedOperations: true,
+		EndpointType:                       1,
+	})
+}
+
+func TestDescribeClusterResponse(t *testing.T) {
+	prototest.TestResponse(t, v0, &describecluster.Response{
+		ThrottleTimeMs: 500,
+		ClusterID:      "cluster-0",
+		ControllerID:   1,
+		Brokers: []describecluster.ResponseBroker{
+			{BrokerID: 1, Host: "localhost", Port: 9092, Rack: "rack-1"},
+			{BrokerID: 2, Host: "localhost", Port: 9093},
+		},
+		ClusterAuthorizedOperations: 0x18,
+	})
+
+	prototest.TestResponse(t, v1, &describecluster.Response{
+		ThrottleTimeMs: 500,
+		ErrorCode:      1,
+		ErrorMessage:   "error",
+		EndpointType:   1,
+		ClusterID:      "cluster-0",
+		ControllerID:   1,
+		Brokers: []describecluster.ResponseBroker{
+			{BrokerID: 1, Host: "localhost", Port: 9092, Rack: "rack-1"},
+		},
+		ClusterAuthorizedOperations: -2147483648,
+	})
+}
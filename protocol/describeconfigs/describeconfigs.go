@@ -27,7 +27,9 @@ func (r *Request) ApiKey() protocol.ApiKey { return protocol.DescribeConfigs }
 func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
 	// Broker metadata requests must be sent to the associated broker
 	for _, resource := range r.Resources {
-		if resource.ResourceType == resourceTypeBroker {
+		// The empty name designates the cluster-wide default broker configs,
+		// which any broker can describe.
+		if resource.ResourceType == resourceTypeBroker && resource.ResourceName != "" {
 			brokerID, err := strconv.Atoi(resource.ResourceName)
 			if err != nil {
 				return protocol.Broker{}, err
@@ -46,13 +48,18 @@ func (r *Request) Split(cluster protocol.Cluster) (
 	error,
 ) {
 	messages := []protocol.Message{}
-	topicsMessage := Request{}
+	topicsMessage := Request{
+		IncludeSynonyms:      r.IncludeSynonyms,
+		IncludeDocumentation: r.IncludeDocumentation,
+	}
 
 	for _, resource := range r.Resources {
 		// Split out broker requests to separate brokers
-		if resource.ResourceType == resourceTypeBroker {
+		if resource.ResourceType == resourceTypeBroker && resource.ResourceName != "" {
 			messages = append(messages, &Request{
-				Resources: []RequestResource{resource},
+				Resources:            []RequestResource{resource},
+				IncludeSynonyms:      r.IncludeSynonyms,
+				IncludeDocumentation: r.IncludeDocumentation,
 			})
 		} else {
 			topicsMessage.Resources = append(
@@ -90,6 +97,9 @@ func (r *Response) Merge(requests []protocol.Message, results []interface{}) (
 	for _, result := range results {
 		switch v := result.(type) {
 		case *Response:
+			if response.ThrottleTimeMs < v.ThrottleTimeMs {
+				response.ThrottleTimeMs = v.ThrottleTimeMs
+			}
 			response.Resources = append(
 				response.Resources,
 				v.Resources...,
@@ -75,3 +75,46 @@ func TestResponse_Merge(t *testing.T) {
 		t.Fatal("did not panic")
 	})
 }
+
+func TestRequest_Split(t *testing.T) {
+	r := &Request{
+		Resources: []RequestResource{
+			{ResourceType: resourceTypeBroker, ResourceName: "1"},
+			{ResourceType: 2, ResourceName: "topic-1"},
+			{ResourceType: resourceTypeBroker, ResourceName: ""},
+			{ResourceType: resourceTypeBroker, ResourceName: "2"},
+		},
+		IncludeSynonyms:      true,
+		IncludeDocumentation: true,
+	}
+
+	messages, _, err := r.Split(protocol.Cluster{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []protocol.Message{
+		&Request{
+			Resources:            []RequestResource{{ResourceType: resourceTypeBroker, ResourceName: "1"}},
+			IncludeSynonyms:      true,
+			IncludeDocumentation: true,
+		},
+		&Request{
+			Resources:            []RequestResource{{ResourceType: resourceTypeBroker, ResourceName: "2"}},
+			IncludeSynonyms:      true,
+			IncludeDocumentation: true,
+		},
+		&Request{
+			Resources: []RequestResource{
+				{ResourceType: 2, ResourceName: "topic-1"},
+				{ResourceType: resourceTypeBroker, ResourceName: ""},
+			},
+			IncludeSynonyms:      true,
+			IncludeDocumentation: true,
+		},
+	}
+
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("unexpected split requests\nwant: %+v\ngot:  %+v", want, messages)
+	}
+}
@@ -0,0 +1,60 @@
+package describedelegationtoken
+
+import "github.com/segmentio/kafka-go/protocol"
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_DescribeDelegationToken
+type Request struct {
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
+	Owners []Principal `kafka:"min=v0,max=v2,nullable"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.DescribeDelegationToken }
+
+func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	return cluster.Brokers[cluster.Controller], nil
+}
+
+type Principal struct {
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
+	PrincipalType string `kafka:"min=v0,max=v2"`
+	PrincipalName string `kafka:"min=v0,max=v2"`
+}
+
+type Response struct {
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
+	ErrorCode      int16           `kafka:"min=v0,max=v2"`
+	Tokens         []ResponseToken `kafka:"min=v0,max=v2"`
+	ThrottleTimeMs int32           `kafka:"min=v0,max=v2"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.DescribeDelegationToken }
+
+type ResponseToken struct {
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
+	PrincipalType   string      `kafka:"min=v0,max=v2"`
+	PrincipalName   string      `kafka:"min=v0,max=v2"`
+	IssueTimestamp  int64       `kafka:"min=v0,max=v2"`
+	ExpiryTimestamp int64       `kafka:"min=v0,max=v2"`
+	MaxTimestamp    int64       `kafka:"min=v0,max=v2"`
+	TokenID         string      `kafka:"min=v0,max=v2"`
+	HMAC            []byte      `kafka:"min=v0,max=v2"`
+	Renewers        []Principal `kafka:"min=v0,max=v2"`
+}
+
+var _ protocol.BrokerMessage = (*Request)(nil)
@@ -0,0 +1,46 @@
+package describedelegationtoken_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/describedelegationtoken"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+func TestDescribeDelegationTokenRequest(t *testing.T) {
+	for _, version := range []int16{0, 1, 2} {
+		prototest.TestRequest(t, version, &describedelegationtoken.Request{
+			Owners: []describedelegationtoken.Principal{
+				{
+					PrincipalType: "User",
+					PrincipalName: "alice",
+				},
+			},
+		})
+	}
+}
+
+func TestDescribeDelegationTokenResponse(t *testing.T) {
+	for _, version := range []int16{0, 1, 2} {
+		prototest.TestResponse(t, version, &describedelegationtoken.Response{
+			ThrottleTimeMs: 500,
+			Tokens: []describedelegationtoken.ResponseToken{
+				{
+					PrincipalType:   "User",
+					PrincipalName:   "alice",
+					IssueTimestamp:  1000,
+					ExpiryTimestamp: 2000,
+					MaxTimestamp:    3000,
+					TokenID:         "token-0",
+					HMAC:            []byte("hmac"),
+					Renewers: []describedelegationtoken.Principal{
+						{
+							PrincipalType: "User",
+							PrincipalName: "bob",
+						},
+					},
+				},
+			},
+		})
+	}
+}
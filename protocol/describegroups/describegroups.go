@@ -10,8 +10,12 @@ func init() {
 
 // Detailed API definition: https://kafka.apache.org/protocol#The_Messages_DescribeGroups
 type Request struct {
-	Groups                      []string `kafka:"min=v0,max=v4"`
-	IncludeAuthorizedOperations bool     `kafka:"min=v3,max=v4"`
+	// We need at least one tagged field to indicate that v5+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v5,max=v5,tag"`
+
+	Groups                      []string `kafka:"min=v0,max=v5"`
+	IncludeAuthorizedOperations bool     `kafka:"min=v3,max=v5"`
 }
 
 func (r *Request) ApiKey() protocol.ApiKey { return protocol.DescribeGroups }
@@ -42,27 +46,35 @@ func (r *Request) Split(cluster protocol.Cluster) (
 }
 
 type Response struct {
-	ThrottleTimeMs int32           `kafka:"min=v1,max=v4"`
-	Groups         []ResponseGroup `kafka:"min=v0,max=v4"`
+	// We need at least one tagged field to indicate that v5+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v5,max=v5,tag"`
+
+	ThrottleTimeMs int32           `kafka:"min=v1,max=v5"`
+	Groups         []ResponseGroup `kafka:"min=v0,max=v5"`
 }
 
 type ResponseGroup struct {
-	ErrorCode            int16                 `kafka:"min=v0,max=v4"`
-	GroupID              string                `kafka:"min=v0,max=v4"`
-	GroupState           string                `kafka:"min=v0,max=v4"`
-	ProtocolType         string                `kafka:"min=v0,max=v4"`
-	ProtocolData         string                `kafka:"min=v0,max=v4"`
-	Members              []ResponseGroupMember `kafka:"min=v0,max=v4"`
-	AuthorizedOperations int32                 `kafka:"min=v3,max=v4"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v5,max=v5,tag"`
+
+	ErrorCode            int16                 `kafka:"min=v0,max=v5"`
+	GroupID              string                `kafka:"min=v0,max=v5,intern"`
+	GroupState           string                `kafka:"min=v0,max=v5,intern"`
+	ProtocolType         string                `kafka:"min=v0,max=v5,intern"`
+	ProtocolData         string                `kafka:"min=v0,max=v5,intern"`
+	Members              []ResponseGroupMember `kafka:"min=v0,max=v5"`
+	AuthorizedOperations int32                 `kafka:"min=v3,max=v5"`
 }
 
 type ResponseGroupMember struct {
-	MemberID         string `kafka:"min=v0,max=v4"`
-	GroupInstanceID  string `kafka:"min=v4,max=v4,nullable"`
-	ClientID         string `kafka:"min=v0,max=v4"`
-	ClientHost       string `kafka:"min=v0,max=v4"`
-	MemberMetadata   []byte `kafka:"min=v0,max=v4"`
-	MemberAssignment []byte `kafka:"min=v0,max=v4"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v5,max=v5,tag"`
+
+	MemberID         string `kafka:"min=v0,max=v5"`
+	GroupInstanceID  string `kafka:"min=v4,max=v5,nullable"`
+	ClientID         string `kafka:"min=v0,max=v5,intern"`
+	ClientHost       string `kafka:"min=v0,max=v5,intern"`
+	MemberMetadata   []byte `kafka:"min=v0,max=v5"`
+	MemberAssignment []byte `kafka:"min=v0,max=v5"`
 }
 
 func (r *Response) ApiKey() protocol.ApiKey { return protocol.DescribeGroups }
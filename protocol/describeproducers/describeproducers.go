@@ -0,0 +1,202 @@
+package describeproducers
+
+import (
+	"sort"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_DescribeProducers
+type Request struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	Topics []RequestTopic `kafka:"min=v0,max=v0"`
+}
+
+type RequestTopic struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	Name             string  `kafka:"min=v0,max=v0"`
+	PartitionIndexes []int32 `kafka:"min=v0,max=v0"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.DescribeProducers }
+
+func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	// Expects r to be a request that was returned by Split, will likely panic
+	// or produce the wrong result if that's not the case.
+	partition := r.Topics[0].PartitionIndexes[0]
+	topic := r.Topics[0].Name
+
+	if leader := leaderOf(cluster, topic, partition); leader >= 0 {
+		return cluster.Brokers[leader], nil
+	}
+
+	return protocol.Broker{ID: -1}, nil
+}
+
+func (r *Request) Split(cluster protocol.Cluster) ([]protocol.Message, protocol.Merger, error) {
+	// DescribeProducers requests must be sent to partition leaders, the
+	// partitions are batched by leader and the responses merged back.
+	type batch struct {
+		request *Request
+		topics  map[string]int
+	}
+
+	batches := make(map[int32]*batch)
+	leaders := make([]int32, 0, 8)
+
+	for _, t := range r.Topics {
+		for _, p := range t.PartitionIndexes {
+			leader := leaderOf(cluster, t.Name, p)
+
+			b := batches[leader]
+			if b == nil {
+				b = &batch{
+					request: &Request{},
+					topics:  make(map[string]int),
+				}
+				batches[leader] = b
+				leaders = append(leaders, leader)
+			}
+
+			i, ok := b.topics[t.Name]
+			if !ok {
+				i = len(b.request.Topics)
+				b.topics[t.Name] = i
+				b.request.Topics = append(b.request.Topics, RequestTopic{Name: t.Name})
+			}
+
+			b.request.Topics[i].PartitionIndexes = append(b.request.Topics[i].PartitionIndexes, p)
+		}
+	}
+
+	messages := make([]protocol.Message, len(leaders))
+
+	for i, leader := range leaders {
+		messages[i] = batches[leader].request
+	}
+
+	return messages, new(Response), nil
+}
+
+func leaderOf(cluster protocol.Cluster, topic string, partition int32) int32 {
+	if p, ok := cluster.Topics[topic].Partitions[partition]; ok {
+		return p.Leader
+	}
+	return -1
+}
+
+type Response struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	ThrottleTimeMs int32           `kafka:"min=v0,max=v0"`
+	Topics         []ResponseTopic `kafka:"min=v0,max=v0"`
+}
+
+type ResponseTopic struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	Name       string              `kafka:"min=v0,max=v0"`
+	Partitions []ResponsePartition `kafka:"min=v0,max=v0"`
+}
+
+type ResponsePartition struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	PartitionIndex  int32           `kafka:"min=v0,max=v0"`
+	ErrorCode       int16           `kafka:"min=v0,max=v0"`
+	ErrorMessage    string          `kafka:"min=v0,max=v0,nullable"`
+	ActiveProducers []ProducerState `kafka:"min=v0,max=v0"`
+}
+
+type ProducerState struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	ProducerID            int64 `kafka:"min=v0,max=v0"`
+	ProducerEpoch         int32 `kafka:"min=v0,max=v0"`
+	LastSequence          int32 `kafka:"min=v0,max=v0"`
+	LastTimestamp         int64 `kafka:"min=v0,max=v0"`
+	CoordinatorEpoch      int32 `kafka:"min=v0,max=v0"`
+	CurrentTxnStartOffset int64 `kafka:"min=v0,max=v0"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.DescribeProducers }
+
+func (r *Response) Merge(requests []protocol.Message, results []interface{}) (protocol.Message, error) {
+	topics := make(map[string][]ResponsePartition)
+	errors := 0
+
+	for i, res := range results {
+		m, err := protocol.Result(res)
+		if err != nil {
+			for _, t := range requests[i].(*Request).Topics {
+				partitions := topics[t.Name]
+
+				for _, p := range t.PartitionIndexes {
+					partitions = append(partitions, ResponsePartition{
+						PartitionIndex: p,
+						ErrorCode:      -1, // UNKNOWN, can we do better?
+						ErrorMessage:   err.Error(),
+					})
+				}
+
+				topics[t.Name] = partitions
+			}
+			errors++
+			continue
+		}
+
+		response := m.(*Response)
+
+		if r.ThrottleTimeMs < response.ThrottleTimeMs {
+			r.ThrottleTimeMs = response.ThrottleTimeMs
+		}
+
+		for _, t := range response.Topics {
+			topics[t.Name] = append(topics[t.Name], t.Partitions...)
+		}
+	}
+
+	if errors > 0 && errors == len(results) {
+		_, err := protocol.Result(results[0])
+		return nil, err
+	}
+
+	r.Topics = make([]ResponseTopic, 0, len(topics))
+
+	for topicName, partitions := range topics {
+		r.Topics = append(r.Topics, ResponseTopic{
+			Name:       topicName,
+			Partitions: partitions,
+		})
+	}
+
+	sort.Slice(r.Topics, func(i, j int) bool {
+		return r.Topics[i].Name < r.Topics[j].Name
+	})
+
+	for _, t := range r.Topics {
+		sort.Slice(t.Partitions, func(i, j int) bool {
+			return t.Partitions[i].PartitionIndex < t.Partitions[j].PartitionIndex
+		})
+	}
+
+	return r, nil
+}
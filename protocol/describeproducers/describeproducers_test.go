@@ -0,0 +1,52 @@
+package describeproducers_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/describeproducers"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+const (
+	v0 = 0
+)
+
+func TestDescribeProducersRequest(t *testing.T) {
+	prototest.TestRequest(t, v0, &describeproducers.Request{
+		Topics: []describeproducers.RequestTopic{
+			{Name: "topic-A", PartitionIndexes: []int32{0, 1, 2}},
+			{Name: "topic-B", PartitionIndexes: []int32{0}},
+		},
+	})
+}
+
+func TestDescribeProducersResponse(t *testing.T) {
+	prototest.TestResponse(t, v0, &describeproducers.Response{
+		ThrottleTimeMs: 500,
+		Topics: []describeproducers.ResponseTopic{
+			{
+				Name: "topic-A",
+				Partitions: []describeproducers.ResponsePartition{
+					{
+						PartitionIndex: 0,
+						ActiveProducers: []describeproducers.ProducerState{
+							{
+								ProducerID:            1000,
+								ProducerEpoch:         2,
+								LastSequence:          41,
+								LastTimestamp:         1633046400000,
+								CoordinatorEpoch:      3,
+								CurrentTxnStartOffset: 123,
+							},
+						},
+					},
+					{
+						PartitionIndex: 1,
+						ErrorCode:      6,
+						ErrorMessage:   "not leader",
+					},
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,80 @@
+package describequorum
+
+import "github.com/segmentio/kafka-go/protocol"
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_DescribeQuorum
+type Request struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v1,tag"`
+
+	Topics []RequestTopic `kafka:"min=v0,max=v1"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.DescribeQuorum }
+
+type RequestTopic struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v1,tag"`
+
+	TopicName  string             `kafka:"min=v0,max=v1"`
+	Partitions []RequestPartition `kafka:"min=v0,max=v1"`
+}
+
+type RequestPartition struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v1,tag"`
+
+	PartitionIndex int32 `kafka:"min=v0,max=v1"`
+}
+
+type Response struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v1,tag"`
+
+	ErrorCode int16           `kafka:"min=v0,max=v1"`
+	Topics    []ResponseTopic `kafka:"min=v0,max=v1"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.DescribeQuorum }
+
+type ResponseTopic struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v1,tag"`
+
+	TopicName  string              `kafka:"min=v0,max=v1"`
+	Partitions []ResponsePartition `kafka:"min=v0,max=v1"`
+}
+
+type ResponsePartition struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v1,tag"`
+
+	PartitionIndex int32          `kafka:"min=v0,max=v1"`
+	ErrorCode      int16          `kafka:"min=v0,max=v1"`
+	LeaderID       int32          `kafka:"min=v0,max=v1"`
+	LeaderEpoch    int32          `kafka:"min=v0,max=v1"`
+	HighWatermark  int64          `kafka:"min=v0,max=v1"`
+	CurrentVoters  []ReplicaState `kafka:"min=v0,max=v1"`
+	Observers      []ReplicaState `kafka:"min=v0,max=v1"`
+}
+
+type ReplicaState struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v1,tag"`
+
+	ReplicaID             int32 `kafka:"min=v0,max=v1"`
+	LogEndOffset          int64 `kafka:"min=v0,max=v1"`
+	LastFetchTimestamp    int64 `kafka:"min=v1,max=v1"`
+	LastCaughtUpTimestamp int64 `kafka:"min=v1,max=v1"`
+}
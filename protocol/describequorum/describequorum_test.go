@@ -0,0 +1,75 @@
+package describequorum_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/describequorum"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+const (
+	v0 = 0
+	v1 = 1
+)
+
+func TestDescribeQuorumRequest(t *testing.T) {
+	for _, version := range []int16{v0, v1} {
+		prototest.TestRequest(t, version, &describequorum.Request{
+			Topics: []describequorum.RequestTopic{
+				{
+					TopicName: "__cluster_metadata",
+					Partitions: []describequorum.RequestPartition{
+						{PartitionIndex: 0},
+					},
+				},
+			},
+		})
+	}
+}
+
+func TestDescribeQuorumResponse(t *testing.T) {
+	prototest.TestResponse(t, v0, &describequorum.Response{
+		Topics: []describequorum.ResponseTopic{
+			{
+				TopicName: "__cluster_metadata",
+				Partitions: []describequorum.ResponsePartition{
+					{
+						PartitionIndex: 0,
+						LeaderID:       1,
+						LeaderEpoch:    10,
+						HighWatermark:  100,
+						CurrentVoters: []describequorum.ReplicaState{
+							{ReplicaID: 1, LogEndOffset: 100},
+							{ReplicaID: 2, LogEndOffset: 90},
+						},
+						Observers: []describequorum.ReplicaState{
+							{ReplicaID: 3, LogEndOffset: 80},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	prototest.TestResponse(t, v1, &describequorum.Response{
+		Topics: []describequorum.ResponseTopic{
+			{
+				TopicName: "__cluster_metadata",
+				Partitions: []describequorum.ResponsePartition{
+					{
+						PartitionIndex: 0,
+						LeaderID:       1,
+						LeaderEpoch:    10,
+						HighWatermark:  100,
+						CurrentVoters: []describequorum.ReplicaState{
+							{ReplicaID: 1, LogEndOffset: 100, LastFetchTimestamp: 1000, LastCaughtUpTimestamp: 1000},
+						},
+						Observers: []describequorum.ReplicaState{
+							{ReplicaID: 3, LogEndOffset: 80, LastFetchTimestamp: 900, LastCaughtUpTimestamp: 800},
+						},
+					},
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,115 @@
+package describetransactions
+
+import "github.com/segmentio/kafka-go/protocol"
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_DescribeTransactions
+type Request struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	TransactionalIDs []string `kafka:"min=v0,max=v0"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.DescribeTransactions }
+
+func (r *Request) Transaction() string {
+	return r.TransactionalIDs[0]
+}
+
+func (r *Request) Split(cluster protocol.Cluster) (
+	[]protocol.Message,
+	protocol.Merger,
+	error,
+) {
+	messages := []protocol.Message{}
+
+	// Split requests by transactional id since they'll need to go to different
+	// coordinators.
+	for _, id := range r.TransactionalIDs {
+		messages = append(
+			messages,
+			&Request{
+				TransactionalIDs: []string{id},
+			},
+		)
+	}
+
+	return messages, new(Response), nil
+}
+
+type Response struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	ThrottleTimeMs    int32              `kafka:"min=v0,max=v0"`
+	TransactionStates []TransactionState `kafka:"min=v0,max=v0"`
+}
+
+type TransactionState struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	ErrorCode              int16       `kafka:"min=v0,max=v0"`
+	TransactionalID        string      `kafka:"min=v0,max=v0"`
+	TransactionState       string      `kafka:"min=v0,max=v0"`
+	TransactionTimeoutMs   int32       `kafka:"min=v0,max=v0"`
+	TransactionStartTimeMs int64       `kafka:"min=v0,max=v0"`
+	ProducerID             int64       `kafka:"min=v0,max=v0"`
+	ProducerEpoch          int16       `kafka:"min=v0,max=v0"`
+	Topics                 []TopicData `kafka:"min=v0,max=v0"`
+}
+
+type TopicData struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	Topic      string  `kafka:"min=v0,max=v0"`
+	Partitions []int32 `kafka:"min=v0,max=v0"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.DescribeTransactions }
+
+func (r *Response) Merge(requests []protocol.Message, results []interface{}) (
+	protocol.Message,
+	error,
+) {
+	response := &Response{}
+	errors := 0
+
+	for i, result := range results {
+		m, err := protocol.Result(result)
+		if err != nil {
+			for _, id := range requests[i].(*Request).TransactionalIDs {
+				response.TransactionStates = append(response.TransactionStates, TransactionState{
+					ErrorCode:       -1, // UNKNOWN, can we do better?
+					TransactionalID: id,
+				})
+			}
+			errors++
+			continue
+		}
+
+		res := m.(*Response)
+
+		if response.ThrottleTimeMs < res.ThrottleTimeMs {
+			response.ThrottleTimeMs = res.ThrottleTimeMs
+		}
+
+		response.TransactionStates = append(response.TransactionStates, res.TransactionStates...)
+	}
+
+	if errors > 0 && errors == len(results) {
+		_, err := protocol.Result(results[0])
+		return nil, err
+	}
+
+	return response, nil
+}
@@ -0,0 +1,41 @@
+package describetransactions_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/describetransactions"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+const (
+	v0 = 0
+)
+
+func TestDescribeTransactionsRequest(t *testing.T) {
+	prototest.TestRequest(t, v0, &describetransactions.Request{
+		TransactionalIDs: []string{"txn-A", "txn-B"},
+	})
+}
+
+func TestDescribeTransactionsResponse(t *testing.T) {
+	prototest.TestResponse(t, v0, &describetransactions.Response{
+		ThrottleTimeMs: 500,
+		TransactionStates: []describetransactions.TransactionState{
+			{
+				TransactionalID:        "txn-A",
+				TransactionState:       "Ongoing",
+				TransactionTimeoutMs:   60000,
+				TransactionStartTimeMs: 1633046400000,
+				ProducerID:             1000,
+				ProducerEpoch:          2,
+				Topics: []describetransactions.TopicData{
+					{Topic: "topic-A", Partitions: []int32{0, 1}},
+				},
+			},
+			{
+				ErrorCode:       105,
+				TransactionalID: "txn-B",
+			},
+		},
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"hash/crc32"
 	"io"
+	"math"
 	"reflect"
 	"sync"
 	"sync/atomic"
@@ -17,6 +18,10 @@ type encoder struct {
 	table  *crc32.Table
 	crc32  uint32
 	buffer [32]byte
+	// When true, record sets with no records are written as empty instead of
+	// failing with ErrNoRecord, which is how brokers represent partitions that
+	// had no records to return in fetch responses.
+	emptyRecordSets bool
 }
 
 type encoderChecksum struct {
@@ -129,6 +134,10 @@ func (e *encoder) encodeInt64(v value) {
 	e.writeInt64(v.int64())
 }
 
+func (e *encoder) encodeFloat64(v value) {
+	e.writeFloat64(v.float64())
+}
+
 func (e *encoder) encodeString(v value) {
 	e.writeString(v.string())
 }
@@ -230,6 +239,11 @@ func (e *encoder) writeInt64(i int64) {
 	e.Write(e.buffer[:8])
 }
 
+func (e *encoder) writeFloat64(f float64) {
+	writeFloat64(e.buffer[:8], f)
+	e.Write(e.buffer[:8])
+}
+
 func (e *encoder) writeString(s string) {
 	e.writeInt16(int16(len(s)))
 	e.WriteString(s)
@@ -322,6 +336,18 @@ func (e *encoder) writeVarNullBytesFrom(b Bytes) error {
 	} else {
 		size := int64(b.Len())
 		e.writeVarInt(size)
+
+		// Large values held in memory are spliced into the output buffer
+		// instead of being copied, they are written directly from the
+		// program memory when the buffer is sent to the network.
+		if r, ok := b.(*bytesReader); ok && size >= spliceThreshold && e.table == nil && e.err == nil {
+			if pb, ok := e.writer.(*pageBuffer); ok {
+				pb.splice(r.unread())
+				_, err := r.Seek(0, io.SeekEnd)
+				return err
+			}
+		}
+
 		n, err := io.Copy(e, b)
 		if err == nil && n != size {
 			err = fmt.Errorf("size of nullable bytes does not match the number of bytes that were written (size=%d, written=%d): %w", size, n, io.ErrUnexpectedEOF)
@@ -352,6 +378,13 @@ func (e *encoder) writeUnsignedVarInt(i uint64) {
 	e.Write(b[:n])
 }
 
+// appendVarInt appends the varint encoding of i to b, which must have the
+// capacity to hold it.
+func appendVarInt(b []byte, i int64) []byte {
+	n := binary.PutVarint(b[len(b):cap(b)], i)
+	return b[:len(b)+n]
+}
+
 type encodeFunc func(*encoder, value)
 
 var (
@@ -364,6 +397,12 @@ var (
 )
 
 func encodeFuncOf(typ reflect.Type, version int16, flexible bool, tag structTag) encodeFunc {
+	if typ == recordSetType {
+		if flexible {
+			return compactRecordSetEncodeFunc
+		}
+		return recordSetEncodeFunc
+	}
 	if reflect.PtrTo(typ).Implements(writerTo) {
 		return writerEncodeFuncOf(typ)
 	}
@@ -378,6 +417,8 @@ func encodeFuncOf(typ reflect.Type, version int16, flexible bool, tag structTag)
 		return (*encoder).encodeInt32
 	case reflect.Int64:
 		return (*encoder).encodeInt64
+	case reflect.Float64:
+		return (*encoder).encodeFloat64
 	case reflect.String:
 		return stringEncodeFuncOf(flexible, tag)
 	case reflect.Struct:
@@ -387,6 +428,11 @@ func encodeFuncOf(typ reflect.Type, version int16, flexible bool, tag structTag)
 			return bytesEncodeFuncOf(flexible, tag)
 		}
 		return arrayEncodeFuncOf(typ, version, flexible, tag)
+	case reflect.Ptr:
+		if typ.Elem().Kind() == reflect.Struct { // nullable struct
+			return pointerEncodeFuncOf(typ, version, flexible, tag)
+		}
+		panic("unsupported type: " + typ.String())
 	default:
 		panic("unsupported type: " + typ.String())
 	}
@@ -431,9 +477,10 @@ func structEncodeFuncOf(typ reflect.Type, version int16, flexible bool) encodeFu
 
 	var fields []field
 	var taggedFields []field
+	unknownFields, hasUnknownFields := taggedFieldsIndexOf(typ, version)
 
 	forEachStructField(typ, func(typ reflect.Type, index index, tag string) {
-		if typ.Size() != 0 { // skip struct{}
+		if typ.Size() != 0 && typ != taggedFieldsType { // skip struct{} and unknown tagged fields
 			forEachStructTag(tag, func(tag structTag) bool {
 				if tag.MinVersion <= version && version <= tag.MaxVersion {
 					f := field{
@@ -465,22 +512,60 @@ func structEncodeFuncOf(typ reflect.Type, version int16, flexible bool) encodeFu
 		if flexible {
 			// See https://cwiki.apache.org/confluence/display/KAFKA/KIP-482%3A+The+Kafka+Protocol+should+Support+Optional+Tagged+Fields
 			// for details of tag buffers in "flexible" messages.
-			e.writeUnsignedVarInt(uint64(len(taggedFields)))
+			var unknown TaggedFields
+			if hasUnknownFields {
+				unknown = *v.fieldByIndex(unknownFields).iface(taggedFieldsPtrType).(*TaggedFields)
+			}
+
+			if len(unknown) == 0 {
+				e.writeUnsignedVarInt(uint64(len(taggedFields)))
+
+				for i := range taggedFields {
+					f := &taggedFields[i]
+					e.writeTaggedField(f.tagID, func(se *encoder) { f.encode(se, v.fieldByIndex(f.index)) })
+				}
+				return
+			}
+
+			// Merge the known and unknown tagged fields, which must be written
+			// in ascending order of their tags.
+			all := make(TaggedFields, 0, len(taggedFields)+len(unknown))
+			known := make(map[int]bool, len(taggedFields))
 
 			for i := range taggedFields {
 				f := &taggedFields[i]
-				e.writeUnsignedVarInt(uint64(f.tagID))
-
 				buf := &bytes.Buffer{}
-				se := &encoder{writer: buf}
-				f.encode(se, v.fieldByIndex(f.index))
-				e.writeUnsignedVarInt(uint64(buf.Len()))
-				e.Write(buf.Bytes())
+				f.encode(&encoder{writer: buf}, v.fieldByIndex(f.index))
+				all = append(all, TaggedField{Tag: f.tagID, Data: buf.Bytes()})
+				known[f.tagID] = true
+			}
+
+			for _, f := range unknown {
+				if !known[f.Tag] {
+					all = append(all, f)
+				}
+			}
+
+			all.sort()
+			e.writeUnsignedVarInt(uint64(len(all)))
+
+			for _, f := range all {
+				data := f.Data
+				e.writeTaggedField(f.Tag, func(se *encoder) { se.Write(data) })
 			}
 		}
 	}
 }
 
+func (e *encoder) writeTaggedField(tagID int, encode func(*encoder)) {
+	e.writeUnsignedVarInt(uint64(tagID))
+
+	buf := &bytes.Buffer{}
+	encode(&encoder{writer: buf})
+	e.writeUnsignedVarInt(uint64(buf.Len()))
+	e.Write(buf.Bytes())
+}
+
 func arrayEncodeFuncOf(typ reflect.Type, version int16, flexible bool, tag structTag) encodeFunc {
 	elemType := typ.Elem()
 	elemFunc := encodeFuncOf(elemType, version, flexible, tag)
@@ -498,6 +583,30 @@ func arrayEncodeFuncOf(typ reflect.Type, version int16, flexible bool, tag struc
 	}
 }
 
+func pointerEncodeFuncOf(typ reflect.Type, version int16, flexible bool, tag structTag) encodeFunc {
+	elemFunc := encodeFuncOf(typ.Elem(), version, flexible, tag)
+	return func(e *encoder, v value) {
+		if v.isNil() {
+			e.writeInt8(-1)
+		} else {
+			e.writeInt8(1)
+			elemFunc(e, v.elem())
+		}
+	}
+}
+
+func compactRecordSetEncodeFunc(e *encoder, v value) {
+	if e.err == nil {
+		e.writeCompactRecordSet(v.iface(recordSetPtrType).(*RecordSet))
+	}
+}
+
+func recordSetEncodeFunc(e *encoder, v value) {
+	if e.err == nil {
+		e.writeRecordSet(v.iface(recordSetPtrType).(*RecordSet))
+	}
+}
+
 func writerEncodeFuncOf(typ reflect.Type) encodeFunc {
 	typ = reflect.PtrTo(typ)
 	return func(e *encoder, v value) {
@@ -530,6 +639,10 @@ func writeInt64(b []byte, i int64) {
 	binary.BigEndian.PutUint64(b, uint64(i))
 }
 
+func writeFloat64(b []byte, f float64) {
+	binary.BigEndian.PutUint64(b, math.Float64bits(f))
+}
+
 func Marshal(version int16, value interface{}) ([]byte, error) {
 	typ := typeOf(value)
 	cache, _ := marshalers.Load().(map[versionedType]encodeFunc)
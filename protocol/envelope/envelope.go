@@ -0,0 +1,69 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_Envelope
+//
+// Envelope requests carry a serialized request, which the receiving broker
+// forwards to the controller (see KIP-590).
+type Request struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	RequestData       []byte `kafka:"min=v0,max=v0"`
+	RequestPrincipal  []byte `kafka:"min=v0,max=v0,nullable"`
+	ClientHostAddress []byte `kafka:"min=v0,max=v0"`
+
+	// The ID of the broker that the request is sent to, which must be the
+	// controller of the cluster. It is not part of the request.
+	BrokerID int32 `kafka:"-"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.Envelope }
+
+func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	return cluster.Brokers[r.BrokerID], nil
+}
+
+// NewRequest constructs an envelope wrapping msg, serialized in the given API
+// version with a request header carrying the correlation and client IDs.
+func NewRequest(msg protocol.Message, apiVersion int16, correlationID int32, clientID string) (*Request, error) {
+	b := new(bytes.Buffer)
+	if err := protocol.WriteRequest(b, apiVersion, correlationID, clientID, msg); err != nil {
+		return nil, err
+	}
+	// The wrapped request is not prefixed by its size.
+	return &Request{RequestData: b.Bytes()[4:]}, nil
+}
+
+type Response struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v0,tag"`
+
+	ResponseData []byte `kafka:"min=v0,max=v0,nullable"`
+	ErrorCode    int16  `kafka:"min=v0,max=v0"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.Envelope }
+
+// Decode decodes the response wrapped in r, which must be the response to a
+// request of the given API key and version.
+func (r *Response) Decode(apiKey protocol.ApiKey, apiVersion int16) (correlationID int32, msg protocol.Message, err error) {
+	b := make([]byte, 4+len(r.ResponseData))
+	binary.BigEndian.PutUint32(b, uint32(len(r.ResponseData)))
+	copy(b[4:], r.ResponseData)
+	return protocol.ReadResponse(bytes.NewReader(b), apiKey, apiVersion)
+}
+
+var _ protocol.BrokerMessage = (*Request)(nil)
@@ -0,0 +1,90 @@
+package envelope_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/createtopics"
+	"github.com/segmentio/kafka-go/protocol/envelope"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+const (
+	v0 = 0
+)
+
+func TestEnvelopeRequest(t *testing.T) {
+	prototest.TestRequest(t, v0, &envelope.Request{
+		RequestData:       []byte("request"),
+		RequestPrincipal:  []byte("principal"),
+		ClientHostAddress: []byte{127, 0, 0, 1},
+	})
+}
+
+func TestEnvelopeResponse(t *testing.T) {
+	prototest.TestResponse(t, v0, &envelope.Response{
+		ResponseData: []byte("response"),
+	})
+
+	prototest.TestResponse(t, v0, &envelope.Response{
+		ErrorCode: 41,
+	})
+}
+
+func TestEnvelopeWrapping(t *testing.T) {
+	const version = 4
+
+	req := &createtopics.Request{
+		Topics: []createtopics.RequestTopic{{
+			Name:              "topic-1",
+			NumPartitions:     1,
+			ReplicationFactor: 1,
+			Assignments:       []createtopics.RequestAssignment{},
+			Configs:           []createtopics.RequestConfig{},
+		}},
+		TimeoutMs: 1000,
+	}
+
+	env, err := envelope.NewRequest(req, version, 42, "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, 4+len(env.RequestData))
+	binary.BigEndian.PutUint32(b, uint32(len(env.RequestData)))
+	copy(b[4:], env.RequestData)
+
+	apiVersion, correlationID, clientID, msg, err := protocol.ReadRequest(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiVersion != version || correlationID != 42 || clientID != "client" {
+		t.Errorf("wrong request header: version=%d correlation=%d client=%q", apiVersion, correlationID, clientID)
+	}
+	if !reflect.DeepEqual(msg, req) {
+		t.Errorf("wrong wrapped request: %+v", msg)
+	}
+
+	res := &createtopics.Response{
+		Topics: []createtopics.ResponseTopic{{Name: "topic-1", ErrorCode: 41}},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := protocol.WriteResponse(buf, version, 42, res); err != nil {
+		t.Fatal(err)
+	}
+
+	id, found, err := (&envelope.Response{ResponseData: buf.Bytes()[4:]}).Decode(protocol.CreateTopics, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 42 {
+		t.Errorf("wrong correlation id: %d", id)
+	}
+	if !reflect.DeepEqual(found, res) {
+		t.Errorf("wrong wrapped response: %+v", found)
+	}
+}
@@ -39,6 +39,19 @@ const (
 	ErrNoReset Error = "record sequence does not support reset"
 )
 
+// UnsupportedFeatureError is returned when writing records which use a
+// feature that cannot be represented in the version of the record set, for
+// example record headers or zstd compression in message sets, which are used
+// to produce to kafka brokers older than 0.11.
+type UnsupportedFeatureError struct {
+	Feature string
+	Version int8
+}
+
+func (e *UnsupportedFeatureError) Error() string {
+	return fmt.Sprintf("%s cannot be represented in record set version %d", e.Feature, e.Version)
+}
+
 type TopicError struct {
 	Topic string
 	Err   error
@@ -0,0 +1,37 @@
+package expiredelegationtoken
+
+import "github.com/segmentio/kafka-go/protocol"
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_ExpireDelegationToken
+type Request struct {
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
+	HMAC               []byte `kafka:"min=v0,max=v2"`
+	ExpiryTimePeriodMs int64  `kafka:"min=v0,max=v2"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.ExpireDelegationToken }
+
+func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	return cluster.Brokers[cluster.Controller], nil
+}
+
+type Response struct {
+	// We need at least one tagged field to indicate that v2+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v2,max=v2,tag"`
+
+	ErrorCode         int16 `kafka:"min=v0,max=v2"`
+	ExpiryTimestampMs int64 `kafka:"min=v0,max=v2"`
+	ThrottleTimeMs    int32 `kafka:"min=v0,max=v2"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.ExpireDelegationToken }
+
+var _ protocol.BrokerMessage = (*Request)(nil)
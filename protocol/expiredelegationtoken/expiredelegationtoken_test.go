@@ -0,0 +1,27 @@
+package expiredelegationtoken_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/expiredelegationtoken"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+func TestExpireDelegationTokenRequest(t *testing.T) {
+	for _, version := range []int16{0, 1, 2} {
+		prototest.TestRequest(t, version, &expiredelegationtoken.Request{
+			HMAC:               []byte("hmac"),
+			ExpiryTimePeriodMs: -1,
+		})
+	}
+}
+
+func TestExpireDelegationTokenResponse(t *testing.T) {
+	for _, version := range []int16{0, 1, 2} {
+		prototest.TestResponse(t, version, &expiredelegationtoken.Response{
+			ErrorCode:         0,
+			ExpiryTimestampMs: 2000,
+			ThrottleTimeMs:    500,
+		})
+	}
+}
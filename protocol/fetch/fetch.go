@@ -11,16 +11,19 @@ func init() {
 }
 
 type Request struct {
-	ReplicaID       int32                   `kafka:"min=v0,max=v11"`
-	MaxWaitTime     int32                   `kafka:"min=v0,max=v11"`
-	MinBytes        int32                   `kafka:"min=v0,max=v11"`
-	MaxBytes        int32                   `kafka:"min=v3,max=v11"`
-	IsolationLevel  int8                    `kafka:"min=v4,max=v11"`
-	SessionID       int32                   `kafka:"min=v7,max=v11"`
-	SessionEpoch    int32                   `kafka:"min=v7,max=v11"`
-	Topics          []RequestTopic          `kafka:"min=v0,max=v11"`
-	ForgottenTopics []RequestForgottenTopic `kafka:"min=v7,max=v11"`
-	RackID          string                  `kafka:"min=v11,max=v11"`
+	ReplicaID       int32                   `kafka:"min=v0,max=v13"`
+	MaxWaitTime     int32                   `kafka:"min=v0,max=v13"`
+	MinBytes        int32                   `kafka:"min=v0,max=v13"`
+	MaxBytes        int32                   `kafka:"min=v3,max=v13"`
+	IsolationLevel  int8                    `kafka:"min=v4,max=v13"`
+	SessionID       int32                   `kafka:"min=v7,max=v13"`
+	SessionEpoch    int32                   `kafka:"min=v7,max=v13"`
+	Topics          []RequestTopic          `kafka:"min=v0,max=v13"`
+	ForgottenTopics []RequestForgottenTopic `kafka:"min=v7,max=v13"`
+	RackID          string                  `kafka:"min=v11,max=v13"`
+	ClusterID       string                  `kafka:"min=v12,max=v13,nullable,tag=0"`
+
+	TaggedFields protocol.TaggedFields `kafka:"min=v12,max=v13,tag"`
 }
 
 func (r *Request) ApiKey() protocol.ApiKey { return protocol.Fetch }
@@ -57,52 +60,159 @@ func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
 	return broker, nil
 }
 
+// ResolveTopicIDs returns a copy of r where the IDs of the topics are set from
+// the cluster layout, r is not modified. Topics are identified by name up to
+// v12, and by ID in v13+ requests.
+//
+// Topics that are not present in the cluster layout retain a zero ID, in which
+// case kafka responds with an UNKNOWN_TOPIC_ID error.
+func (r *Request) ResolveTopicIDs(cluster protocol.Cluster) *Request {
+	c := *r
+	c.Topics = make([]RequestTopic, len(r.Topics))
+	c.ForgottenTopics = make([]RequestForgottenTopic, len(r.ForgottenTopics))
+	copy(c.Topics, r.Topics)
+	copy(c.ForgottenTopics, r.ForgottenTopics)
+
+	for i := range c.Topics {
+		t := &c.Topics[i]
+		if t.TopicID.IsZero() {
+			t.TopicID = cluster.Topics[t.Topic].ID
+		}
+	}
+
+	for i := range c.ForgottenTopics {
+		t := &c.ForgottenTopics[i]
+		if t.TopicID.IsZero() {
+			t.TopicID = cluster.Topics[t.Topic].ID
+		}
+	}
+
+	return &c
+}
+
 type RequestTopic struct {
-	Topic      string             `kafka:"min=v0,max=v11"`
-	Partitions []RequestPartition `kafka:"min=v0,max=v11"`
+	// We need at least one tagged field to indicate that v12+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v12,max=v13,tag"`
+
+	Topic      string             `kafka:"min=v0,max=v12"`
+	TopicID    protocol.UUID      `kafka:"min=v13,max=v13"`
+	Partitions []RequestPartition `kafka:"min=v0,max=v13"`
 }
 
 type RequestPartition struct {
-	Partition          int32 `kafka:"min=v0,max=v11"`
-	CurrentLeaderEpoch int32 `kafka:"min=v9,max=v11"`
-	FetchOffset        int64 `kafka:"min=v0,max=v11"`
-	LogStartOffset     int64 `kafka:"min=v5,max=v11"`
-	PartitionMaxBytes  int32 `kafka:"min=v0,max=v11"`
+	// We need at least one tagged field to indicate that v12+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v12,max=v13,tag"`
+
+	Partition          int32 `kafka:"min=v0,max=v13"`
+	CurrentLeaderEpoch int32 `kafka:"min=v9,max=v13"`
+	FetchOffset        int64 `kafka:"min=v0,max=v13"`
+	LastFetchedEpoch   int32 `kafka:"min=v12,max=v13"`
+	LogStartOffset     int64 `kafka:"min=v5,max=v13"`
+	PartitionMaxBytes  int32 `kafka:"min=v0,max=v13"`
 }
 
 type RequestForgottenTopic struct {
-	Topic      string  `kafka:"min=v7,max=v11"`
-	Partitions []int32 `kafka:"min=v7,max=v11"`
+	// We need at least one tagged field to indicate that v12+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v12,max=v13,tag"`
+
+	Topic      string        `kafka:"min=v7,max=v12"`
+	TopicID    protocol.UUID `kafka:"min=v13,max=v13"`
+	Partitions []int32       `kafka:"min=v7,max=v13"`
 }
 
 type Response struct {
-	ThrottleTimeMs int32           `kafka:"min=v1,max=v11"`
-	ErrorCode      int16           `kafka:"min=v7,max=v11"`
-	SessionID      int32           `kafka:"min=v7,max=v11"`
-	Topics         []ResponseTopic `kafka:"min=v0,max=v11"`
+	// We need at least one tagged field to indicate that v12+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v12,max=v13,tag"`
+
+	ThrottleTimeMs int32           `kafka:"min=v1,max=v13"`
+	ErrorCode      int16           `kafka:"min=v7,max=v13"`
+	SessionID      int32           `kafka:"min=v7,max=v13"`
+	Topics         []ResponseTopic `kafka:"min=v0,max=v13"`
 }
 
 func (r *Response) ApiKey() protocol.ApiKey { return protocol.Fetch }
 
+// ResolveTopicNames sets the names of the topics in r from the request that it
+// was received for. Topics are identified by ID only in v13+ responses.
+func (r *Response) ResolveTopicNames(req *Request) {
+	for i := range r.Topics {
+		t := &r.Topics[i]
+		if t.Topic != "" || t.TopicID.IsZero() {
+			continue
+		}
+		for _, topic := range req.Topics {
+			if topic.TopicID == t.TopicID {
+				t.Topic = topic.Topic
+				break
+			}
+		}
+	}
+}
+
 type ResponseTopic struct {
-	Topic      string              `kafka:"min=v0,max=v11"`
-	Partitions []ResponsePartition `kafka:"min=v0,max=v11"`
+	// We need at least one tagged field to indicate that v12+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v12,max=v13,tag"`
+
+	Topic      string              `kafka:"min=v0,max=v12"`
+	TopicID    protocol.UUID       `kafka:"min=v13,max=v13"`
+	Partitions []ResponsePartition `kafka:"min=v0,max=v13"`
 }
 
 type ResponsePartition struct {
-	Partition            int32                 `kafka:"min=v0,max=v11"`
-	ErrorCode            int16                 `kafka:"min=v0,max=v11"`
-	HighWatermark        int64                 `kafka:"min=v0,max=v11"`
-	LastStableOffset     int64                 `kafka:"min=v4,max=v11"`
-	LogStartOffset       int64                 `kafka:"min=v5,max=v11"`
-	AbortedTransactions  []ResponseTransaction `kafka:"min=v4,max=v11"`
-	PreferredReadReplica int32                 `kafka:"min=v11,max=v11"`
-	RecordSet            protocol.RecordSet    `kafka:"min=v0,max=v11"`
+	Partition            int32                    `kafka:"min=v0,max=v13"`
+	ErrorCode            int16                    `kafka:"min=v0,max=v13"`
+	HighWatermark        int64                    `kafka:"min=v0,max=v13"`
+	LastStableOffset     int64                    `kafka:"min=v4,max=v13"`
+	LogStartOffset       int64                    `kafka:"min=v5,max=v13"`
+	DivergingEpoch       ResponseEpochEndOffset   `kafka:"min=v12,max=v13,tag=0"`
+	CurrentLeader        ResponseLeaderIDAndEpoch `kafka:"min=v12,max=v13,tag=1"`
+	SnapshotID           ResponseSnapshotID       `kafka:"min=v12,max=v13,tag=2"`
+	AbortedTransactions  []ResponseTransaction    `kafka:"min=v4,max=v13,nullable"`
+	PreferredReadReplica int32                    `kafka:"min=v11,max=v13"`
+	RecordSet            protocol.RecordSet       `kafka:"min=v0,max=v13"`
+
+	TaggedFields protocol.TaggedFields `kafka:"min=v12,max=v13,tag"`
+}
+
+type ResponseEpochEndOffset struct {
+	// We need at least one tagged field to indicate that v12+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v12,max=v13,tag"`
+
+	Epoch     int32 `kafka:"min=v12,max=v13"`
+	EndOffset int64 `kafka:"min=v12,max=v13"`
+}
+
+type ResponseLeaderIDAndEpoch struct {
+	// We need at least one tagged field to indicate that v12+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v12,max=v13,tag"`
+
+	LeaderID    int32 `kafka:"min=v12,max=v13"`
+	LeaderEpoch int32 `kafka:"min=v12,max=v13"`
+}
+
+type ResponseSnapshotID struct {
+	// We need at least one tagged field to indicate that v12+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v12,max=v13,tag"`
+
+	EndOffset int64 `kafka:"min=v12,max=v13"`
+	Epoch     int32 `kafka:"min=v12,max=v13"`
 }
 
 type ResponseTransaction struct {
-	ProducerID  int64 `kafka:"min=v4,max=v11"`
-	FirstOffset int64 `kafka:"min=v4,max=v11"`
+	// We need at least one tagged field to indicate that v12+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v12,max=v13,tag"`
+
+	ProducerID  int64 `kafka:"min=v4,max=v13"`
+	FirstOffset int64 `kafka:"min=v4,max=v13"`
 }
 
 var (
@@ -12,6 +12,8 @@ import (
 const (
 	v0  = 0
 	v11 = 11
+	v12 = 12
+	v13 = 13
 )
 
 func TestFetchRequest(t *testing.T) {
@@ -34,6 +36,118 @@ func TestFetchRequest(t *testing.T) {
 	})
 }
 
+func TestFetchRequestTopicIDs(t *testing.T) {
+	req := &fetch.Request{
+		ReplicaID:   -1,
+		MaxWaitTime: 500,
+		MinBytes:    1024,
+		MaxBytes:    1 << 20,
+		Topics: []fetch.RequestTopic{
+			{
+				Topic: "topic-1",
+				Partitions: []fetch.RequestPartition{
+					{
+						Partition:          1,
+						CurrentLeaderEpoch: -1,
+						FetchOffset:        2,
+						LastFetchedEpoch:   -1,
+						LogStartOffset:     -1,
+						PartitionMaxBytes:  1024,
+					},
+				},
+			},
+		},
+		ForgottenTopics: []fetch.RequestForgottenTopic{
+			{
+				Topic:      "topic-2",
+				Partitions: []int32{0},
+			},
+		},
+		RackID: "rack-1",
+	}
+
+	prototest.TestRequest(t, v12, req)
+
+	resolved := req.ResolveTopicIDs(protocol.Cluster{
+		Topics: map[string]protocol.Topic{
+			"topic-1": {ID: protocol.UUID{15: 1}, Name: "topic-1"},
+			"topic-2": {ID: protocol.UUID{15: 2}, Name: "topic-2"},
+		},
+	})
+
+	if id := req.Topics[0].TopicID; !id.IsZero() {
+		t.Errorf("the request was modified: %s", id)
+	}
+
+	req = resolved
+
+	if id := req.Topics[0].TopicID; id != (protocol.UUID{15: 1}) {
+		t.Errorf("wrong topic id: %s", id)
+	}
+	if id := req.ForgottenTopics[0].TopicID; id != (protocol.UUID{15: 2}) {
+		t.Errorf("wrong forgotten topic id: %s", id)
+	}
+
+	req.Topics[0].Topic = ""
+	req.ForgottenTopics[0].Topic = ""
+	prototest.TestRequest(t, v13, req)
+}
+
+func TestFetchResponseTopicIDs(t *testing.T) {
+	t0 := time.Now().Truncate(time.Millisecond)
+	t1 := t0.Add(1 * time.Millisecond)
+
+	res := &fetch.Response{
+		SessionID: 1,
+		Topics: []fetch.ResponseTopic{
+			{
+				TopicID: protocol.UUID{15: 1},
+				Partitions: []fetch.ResponsePartition{
+					{
+						Partition:        1,
+						HighWatermark:    1000,
+						LastStableOffset: 1000,
+						LogStartOffset:   0,
+						DivergingEpoch: fetch.ResponseEpochEndOffset{
+							Epoch:     -1,
+							EndOffset: -1,
+						},
+						CurrentLeader: fetch.ResponseLeaderIDAndEpoch{
+							LeaderID:    1,
+							LeaderEpoch: 2,
+						},
+						SnapshotID: fetch.ResponseSnapshotID{
+							EndOffset: -1,
+							Epoch:     -1,
+						},
+						PreferredReadReplica: -1,
+						RecordSet: protocol.RecordSet{
+							Version: 2,
+							Records: protocol.NewRecordReader(
+								protocol.Record{Offset: 0, Time: t0, Key: nil, Value: prototest.String("msg-0")},
+								protocol.Record{Offset: 1, Time: t1, Key: prototest.Bytes([]byte{1}), Value: prototest.String("msg-1")},
+							),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	prototest.TestResponse(t, v13, res)
+
+	res.ResolveTopicNames(&fetch.Request{
+		Topics: []fetch.RequestTopic{
+			{Topic: "topic-0", TopicID: protocol.UUID{15: 0}},
+			{Topic: "topic-1", TopicID: protocol.UUID{15: 1}},
+		},
+	})
+
+	if name := res.Topics[0].Topic; name != "topic-1" {
+		t.Errorf("wrong topic name: %q", name)
+	}
+}
+
 func TestFetchResponse(t *testing.T) {
 	t0 := time.Now().Truncate(time.Millisecond)
 	t1 := t0.Add(1 * time.Millisecond)
@@ -145,3 +259,33 @@ func BenchmarkFetchResponse(b *testing.B) {
 		},
 	})
 }
+
+func TestFetchResponseOffsets(t *testing.T) {
+	t0 := time.Now().Truncate(time.Millisecond)
+	t1 := t0.Add(1 * time.Millisecond)
+
+	prototest.TestResponse(t, v11, &fetch.Response{
+		Topics: []fetch.ResponseTopic{
+			{
+				Topic: "topic-1",
+				Partitions: []fetch.ResponsePartition{
+					{
+						Partition:     0,
+						HighWatermark: 1000,
+						RecordSet: protocol.RecordSet{
+							Version: 2,
+							Records: protocol.NewRecordReader(
+								protocol.Record{Offset: 10, Time: t0, Key: nil, Value: prototest.String("msg-10")},
+								protocol.Record{Offset: 11, Time: t1, Key: nil, Value: prototest.String("msg-11")},
+							),
+						},
+					},
+					{
+						Partition:     1,
+						HighWatermark: 0,
+					},
+				},
+			},
+		},
+	})
+}
@@ -7,19 +7,75 @@ func init() {
 }
 
 type Request struct {
-	Key     string `kafka:"min=v0,max=v2"`
-	KeyType int8   `kafka:"min=v1,max=v2"`
+	// We need at least one tagged field to indicate that v3+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v4,tag"`
+
+	Key             string   `kafka:"min=v0,max=v3"`
+	KeyType         int8     `kafka:"min=v1,max=v4"`
+	CoordinatorKeys []string `kafka:"min=v4,max=v4"`
 }
 
 func (r *Request) ApiKey() protocol.ApiKey { return protocol.FindCoordinator }
 
+// Prepare converts the key to the list of keys of v4+ requests, unless the
+// program already set the CoordinatorKeys field. Earlier versions only look up
+// the coordinator of Key.
+func (r *Request) Prepare(apiVersion int16) {
+	if apiVersion >= 4 && r.CoordinatorKeys == nil {
+		r.CoordinatorKeys = []string{r.Key}
+	}
+}
+
+var _ protocol.PreparedMessage = (*Request)(nil)
+
 type Response struct {
-	ThrottleTimeMs int32  `kafka:"min=v1,max=v2"`
-	ErrorCode      int16  `kafka:"min=v0,max=v2"`
-	ErrorMessage   string `kafka:"min=v1,max=v2,nullable"`
-	NodeID         int32  `kafka:"min=v0,max=v2"`
-	Host           string `kafka:"min=v0,max=v2"`
-	Port           int32  `kafka:"min=v0,max=v2"`
+	// We need at least one tagged field to indicate that v3+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v4,tag"`
+
+	ThrottleTimeMs int32                 `kafka:"min=v1,max=v4"`
+	ErrorCode      int16                 `kafka:"min=v0,max=v3"`
+	ErrorMessage   string                `kafka:"min=v1,max=v3,nullable"`
+	NodeID         int32                 `kafka:"min=v0,max=v3"`
+	Host           string                `kafka:"min=v0,max=v3"`
+	Port           int32                 `kafka:"min=v0,max=v3"`
+	Coordinators   []ResponseCoordinator `kafka:"min=v4,max=v4"`
 }
 
 func (r *Response) ApiKey() protocol.ApiKey { return protocol.FindCoordinator }
+
+type ResponseCoordinator struct {
+	// We need at least one tagged field to indicate that v4+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v4,max=v4,tag"`
+
+	Key          string `kafka:"min=v4,max=v4"`
+	NodeID       int32  `kafka:"min=v4,max=v4"`
+	Host         string `kafka:"min=v4,max=v4"`
+	Port         int32  `kafka:"min=v4,max=v4"`
+	ErrorCode    int16  `kafka:"min=v4,max=v4"`
+	ErrorMessage string `kafka:"min=v4,max=v4,nullable"`
+}
+
+// Coordinator returns the coordinator of key. v4+ responses list the
+// coordinators of each key, earlier versions only contain the coordinator of
+// the Key of the request, which is returned regardless of the key argument.
+func (r *Response) Coordinator(key string) (ResponseCoordinator, bool) {
+	if r.Coordinators == nil {
+		return ResponseCoordinator{
+			Key:          key,
+			NodeID:       r.NodeID,
+			Host:         r.Host,
+			Port:         r.Port,
+			ErrorCode:    r.ErrorCode,
+			ErrorMessage: r.ErrorMessage,
+		}, true
+	}
+	for _, c := range r.Coordinators {
+		if c.Key == key {
+			return c, true
+		}
+	}
+	return ResponseCoordinator{}, false
+}
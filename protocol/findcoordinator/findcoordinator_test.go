@@ -0,0 +1,88 @@
+package findcoordinator_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/findcoordinator"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+func TestFindCoordinatorRequest(t *testing.T) {
+	prototest.TestRequest(t, 0, &findcoordinator.Request{
+		Key: "group-0",
+	})
+
+	for _, version := range []int16{1, 2, 3} {
+		prototest.TestRequest(t, version, &findcoordinator.Request{
+			Key:     "transactional-id-0",
+			KeyType: 1,
+		})
+	}
+
+	// Version 4 replaced Key with CoordinatorKeys.
+	prototest.TestRequest(t, 4, &findcoordinator.Request{
+		KeyType:         0,
+		CoordinatorKeys: []string{"group-0", "group-1"},
+	})
+}
+
+func TestFindCoordinatorResponse(t *testing.T) {
+	prototest.TestResponse(t, 0, &findcoordinator.Response{
+		ErrorCode: 0,
+		NodeID:    1,
+		Host:      "localhost",
+		Port:      9092,
+	})
+
+	for _, version := range []int16{1, 2, 3} {
+		prototest.TestResponse(t, version, &findcoordinator.Response{
+			ThrottleTimeMs: 1000,
+			ErrorCode:      16,
+			ErrorMessage:   "not coordinator",
+			NodeID:         1,
+			Host:           "localhost",
+			Port:           9092,
+		})
+	}
+
+	// Version 4 replaced the coordinator with the list of Coordinators.
+	prototest.TestResponse(t, 4, &findcoordinator.Response{
+		ThrottleTimeMs: 1000,
+		Coordinators: []findcoordinator.ResponseCoordinator{
+			{Key: "group-0", NodeID: 1, Host: "localhost", Port: 9092},
+			{Key: "group-1", NodeID: -1, Port: -1, ErrorCode: 15, ErrorMessage: "coordinator not available"},
+		},
+	})
+}
+
+func TestFindCoordinatorPrepare(t *testing.T) {
+	req := &findcoordinator.Request{Key: "group-0"}
+	req.Prepare(3)
+	if req.CoordinatorKeys != nil {
+		t.Errorf("v3 requests must not set the coordinator keys: %q", req.CoordinatorKeys)
+	}
+	req.Prepare(4)
+	if len(req.CoordinatorKeys) != 1 || req.CoordinatorKeys[0] != "group-0" {
+		t.Errorf("wrong coordinator keys: %q", req.CoordinatorKeys)
+	}
+}
+
+func TestFindCoordinatorResponseCoordinator(t *testing.T) {
+	res := &findcoordinator.Response{NodeID: 2, Host: "broker-2", Port: 9092}
+	if c, ok := res.Coordinator("group-0"); !ok || c.NodeID != 2 || c.Key != "group-0" {
+		t.Errorf("wrong coordinator of a v3 response: %+v (%t)", c, ok)
+	}
+
+	res = &findcoordinator.Response{
+		Coordinators: []findcoordinator.ResponseCoordinator{
+			{Key: "group-0", NodeID: 1},
+			{Key: "group-1", NodeID: 3},
+		},
+	}
+	if c, ok := res.Coordinator("group-1"); !ok || c.NodeID != 3 {
+		t.Errorf("wrong coordinator of a v4 response: %+v (%t)", c, ok)
+	}
+	if _, ok := res.Coordinator("group-2"); ok {
+		t.Error("unexpected coordinator of a key missing from the response")
+	}
+}
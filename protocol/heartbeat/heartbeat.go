@@ -10,7 +10,7 @@ func init() {
 type Request struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v4,max=v4,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v4,max=v4,tag"`
 
 	GroupID         string `kafka:"min=v0,max=v4"`
 	GenerationID    int32  `kafka:"min=v0,max=v4"`
@@ -25,7 +25,7 @@ func (r *Request) ApiKey() protocol.ApiKey {
 type Response struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v4,max=v4,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v4,max=v4,tag"`
 
 	ErrorCode      int16 `kafka:"min=v0,max=v4"`
 	ThrottleTimeMs int32 `kafka:"min=v1,max=v4"`
@@ -0,0 +1,59 @@
+package joingroup
+
+import "github.com/segmentio/kafka-go/protocol"
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_JoinGroup
+type Request struct {
+	// We need at least one tagged field to indicate that v6+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v6,max=v6,tag"`
+
+	GroupID            string            `kafka:"min=v0,max=v6"`
+	SessionTimeoutMs   int32             `kafka:"min=v0,max=v6"`
+	RebalanceTimeoutMs int32             `kafka:"min=v1,max=v6"`
+	MemberID           string            `kafka:"min=v0,max=v6"`
+	GroupInstanceID    string            `kafka:"min=v5,max=v6,nullable"`
+	ProtocolType       string            `kafka:"min=v0,max=v6"`
+	Protocols          []RequestProtocol `kafka:"min=v0,max=v6"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey {
+	return protocol.JoinGroup
+}
+
+type RequestProtocol struct {
+	TaggedFields protocol.TaggedFields `kafka:"min=v6,max=v6,tag"`
+
+	Name     string `kafka:"min=v0,max=v6"`
+	Metadata []byte `kafka:"min=v0,max=v6"`
+}
+
+type Response struct {
+	// We need at least one tagged field to indicate that v6+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v6,max=v6,tag"`
+
+	ThrottleTimeMs int32            `kafka:"min=v2,max=v6"`
+	ErrorCode      int16            `kafka:"min=v0,max=v6"`
+	GenerationID   int32            `kafka:"min=v0,max=v6"`
+	ProtocolName   string           `kafka:"min=v0,max=v6"`
+	LeaderID       string           `kafka:"min=v0,max=v6"`
+	MemberID       string           `kafka:"min=v0,max=v6"`
+	Members        []ResponseMember `kafka:"min=v0,max=v6"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey {
+	return protocol.JoinGroup
+}
+
+type ResponseMember struct {
+	TaggedFields protocol.TaggedFields `kafka:"min=v6,max=v6,tag"`
+
+	MemberID        string `kafka:"min=v0,max=v6"`
+	GroupInstanceID string `kafka:"min=v5,max=v6,nullable"`
+	Metadata        []byte `kafka:"min=v0,max=v6"`
+}
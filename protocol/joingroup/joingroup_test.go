@@ -0,0 +1,77 @@
+package joingroup_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/joingroup"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+func TestJoinGroupRequest(t *testing.T) {
+	prototest.TestRequest(t, 0, &joingroup.Request{
+		GroupID:          "group-1",
+		SessionTimeoutMs: 10000,
+		MemberID:         "member-1",
+		ProtocolType:     "consumer",
+		Protocols: []joingroup.RequestProtocol{
+			{Name: "range", Metadata: []byte{0, 1, 2}},
+		},
+	})
+
+	for _, version := range []int16{1, 2, 3, 4} {
+		prototest.TestRequest(t, version, &joingroup.Request{
+			GroupID:            "group-1",
+			SessionTimeoutMs:   10000,
+			RebalanceTimeoutMs: 30000,
+			MemberID:           "member-1",
+			ProtocolType:       "consumer",
+			Protocols: []joingroup.RequestProtocol{
+				{Name: "range", Metadata: []byte{0, 1, 2}},
+			},
+		})
+	}
+
+	for _, version := range []int16{5, 6} {
+		prototest.TestRequest(t, version, &joingroup.Request{
+			GroupID:            "group-1",
+			SessionTimeoutMs:   10000,
+			RebalanceTimeoutMs: 30000,
+			MemberID:           "member-1",
+			GroupInstanceID:    "instance-1",
+			ProtocolType:       "consumer",
+			Protocols: []joingroup.RequestProtocol{
+				{Name: "range", Metadata: []byte{0, 1, 2}},
+			},
+		})
+	}
+}
+
+func TestJoinGroupResponse(t *testing.T) {
+	for _, version := range []int16{0, 1, 2, 3, 4} {
+		prototest.TestResponse(t, version, &joingroup.Response{
+			ErrorCode:    0,
+			GenerationID: 3,
+			ProtocolName: "range",
+			LeaderID:     "member-1",
+			MemberID:     "member-2",
+			Members: []joingroup.ResponseMember{
+				{MemberID: "member-1", Metadata: []byte{0, 1, 2}},
+				{MemberID: "member-2", Metadata: []byte{3, 4, 5}},
+			},
+		})
+	}
+
+	for _, version := range []int16{5, 6} {
+		prototest.TestResponse(t, version, &joingroup.Response{
+			ThrottleTimeMs: 10,
+			ErrorCode:      0,
+			GenerationID:   3,
+			ProtocolName:   "range",
+			LeaderID:       "member-1",
+			MemberID:       "member-2",
+			Members: []joingroup.ResponseMember{
+				{MemberID: "member-1", GroupInstanceID: "instance-1", Metadata: []byte{0, 1, 2}},
+			},
+		})
+	}
+}
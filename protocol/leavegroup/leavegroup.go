@@ -0,0 +1,26 @@
+package leavegroup
+
+import "github.com/segmentio/kafka-go/protocol"
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_LeaveGroup
+type Request struct {
+	GroupID  string `kafka:"min=v0,max=v2"`
+	MemberID string `kafka:"min=v0,max=v2"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey {
+	return protocol.LeaveGroup
+}
+
+type Response struct {
+	ThrottleTimeMs int32 `kafka:"min=v1,max=v2"`
+	ErrorCode      int16 `kafka:"min=v0,max=v2"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey {
+	return protocol.LeaveGroup
+}
@@ -0,0 +1,30 @@
+package leavegroup_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/leavegroup"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+func TestLeaveGroupRequest(t *testing.T) {
+	for _, version := range []int16{0, 1, 2} {
+		prototest.TestRequest(t, version, &leavegroup.Request{
+			GroupID:  "group-1",
+			MemberID: "member-1",
+		})
+	}
+}
+
+func TestLeaveGroupResponse(t *testing.T) {
+	prototest.TestResponse(t, 0, &leavegroup.Response{
+		ErrorCode: 25,
+	})
+
+	for _, version := range []int16{1, 2} {
+		prototest.TestResponse(t, version, &leavegroup.Response{
+			ThrottleTimeMs: 10,
+			ErrorCode:      25,
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package protocol
+
+import "fmt"
+
+// Limits configures bounds enforced when decoding messages, which protect
+// programs from allocating unbounded amounts of memory when they receive
+// corrupted or malicious responses.
+//
+// Zero values disable the limits.
+type Limits struct {
+	// The maximum size of a record in bytes, as found in record batches or
+	// message sets.
+	MaxRecordSize int
+
+	// The maximum size of a record batch in bytes, after decompression.
+	MaxBatchSize int
+
+	// The maximum number of headers on a record.
+	MaxHeaderCount int
+
+	// The maximum length of strings in bytes, including header keys.
+	MaxStringLength int
+}
+
+// LimitError is the error returned when decoding a message exceeds one of the
+// configured Limits.
+type LimitError struct {
+	// Name of the limit that was exceeded (e.g. "MaxRecordSize").
+	Limit string
+	// The size found in the message, and the configured maximum.
+	Size int
+	Max  int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("decoding limit exceeded: %s of %d > %d", e.Limit, e.Size, e.Max)
+}
+
+func checkLimit(limit string, size, max int) error {
+	if max > 0 && size > max {
+		return &LimitError{Limit: limit, Size: size, Max: max}
+	}
+	return nil
+}
+
+// checkLimit sets the error of d to a LimitError if size is greater than max,
+// returning false in that case.
+func (d *decoder) checkLimit(limit string, size, max int) bool {
+	if err := checkLimit(limit, size, max); err != nil {
+		d.setError(err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDecoderLimits(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+
+	records := []memoryRecord{
+		{
+			offset: 0,
+			time:   now,
+			key:    []byte("key-0"),
+			value:  bytes.Repeat([]byte("0"), 100),
+		},
+		{
+			offset: 1,
+			time:   now,
+			value:  []byte("value-1"),
+			headers: []Header{
+				{Key: "header-0", Value: []byte("0")},
+				{Key: "header-1", Value: []byte("1")},
+			},
+		},
+	}
+
+	tests := []struct {
+		scenario string
+		attrs    Attributes
+		limits   Limits
+		limit    string
+	}{
+		{
+			scenario: "records are decoded when the limits are not exceeded",
+			limits: Limits{
+				MaxRecordSize:   1000,
+				MaxBatchSize:    1000,
+				MaxHeaderCount:  2,
+				MaxStringLength: 10,
+			},
+		},
+		{
+			scenario: "records larger than the maximum record size are rejected",
+			limits:   Limits{MaxRecordSize: 100},
+			limit:    "MaxRecordSize",
+		},
+		{
+			scenario: "batches larger than the maximum batch size are rejected",
+			limits:   Limits{MaxBatchSize: 100},
+			limit:    "MaxBatchSize",
+		},
+		{
+			scenario: "compressed batches larger than the maximum batch size are rejected",
+			attrs:    Gzip,
+			limits:   Limits{MaxBatchSize: 100},
+			limit:    "MaxBatchSize",
+		},
+		{
+			scenario: "records with too many headers are rejected",
+			limits:   Limits{MaxHeaderCount: 1},
+			limit:    "MaxHeaderCount",
+		},
+		{
+			scenario: "header keys longer than the maximum string length are rejected",
+			limits:   Limits{MaxStringLength: 5},
+			limit:    "MaxStringLength",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			// Place the record exceeding the limit first, so the error is not
+			// ignored in favor of the records decoded before it.
+			input := makeRecords(records)
+			if test.limit == "MaxHeaderCount" || test.limit == "MaxStringLength" {
+				input[0], input[1] = input[1], input[0]
+			}
+
+			b := new(bytes.Buffer)
+			rs := &RecordSet{Version: 2, Attributes: test.attrs, Records: NewRecordReader(input...)}
+			if _, err := rs.WriteTo(b); err != nil {
+				t.Fatal(err)
+			}
+
+			d := &decoder{reader: b, remain: b.Len(), limits: test.limits}
+			_, err := rs.ReadFrom(d)
+
+			if test.limit == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				assertRecords(t, rs.Records, NewRecordReader(makeRecords(records)...))
+				return
+			}
+
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("expected a limit error but got %v", err)
+			}
+			if limitErr.Limit != test.limit {
+				t.Errorf("wrong limit exceeded: want=%s got=%s", test.limit, limitErr.Limit)
+			}
+		})
+	}
+}
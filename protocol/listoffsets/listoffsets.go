@@ -35,15 +35,13 @@ type RequestPartition struct {
 func (r *Request) ApiKey() protocol.ApiKey { return protocol.ListOffsets }
 
 func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
-	// Expects r to be a request that was returned by Map, will likely panic
+	// Expects r to be a request that was returned by Split, will likely panic
 	// or produce the wrong result if that's not the case.
 	partition := r.Topics[0].Partitions[0].Partition
 	topic := r.Topics[0].Topic
 
-	for _, p := range cluster.Topics[topic].Partitions {
-		if p.ID == partition {
-			return cluster.Brokers[p.Leader], nil
-		}
+	if leader := leaderOf(cluster, topic, partition); leader >= 0 {
+		return cluster.Brokers[leader], nil
 	}
 
 	return protocol.Broker{ID: -1}, nil
@@ -54,43 +52,86 @@ func (r *Request) Split(cluster protocol.Cluster) ([]protocol.Message, protocol.
 	// entries of unique topic/partition pairs, we submit multiple requests on
 	// the wire and merge their results back.
 	//
-	// ListOffsets requests also need to be sent to partition leaders, to keep
-	// the logic simple we simply split each offset request into a single
-	// message. This may cause a bit more requests to be sent on the wire but
-	// it keeps the code sane, we can still optimize the aggregation mechanism
-	// later if it becomes a problem.
+	// ListOffsets requests also need to be sent to partition leaders, so the
+	// offset requests are batched by leader; each batch contains at most one
+	// entry per topic/partition pair, requesting multiple offsets of the same
+	// partition creates more batches for its leader.
 	//
 	// Really the idea here is to shield applications from having to deal with
 	// the limitation of the kafka server, so they can request any combinations
 	// of topic/partition/offsets.
-	requests := make([]Request, 0, 2*len(r.Topics))
+	type topicPartition struct {
+		topic     string
+		partition int32
+	}
+
+	type batch struct {
+		leader  int32
+		request *Request
+		seen    map[topicPartition]struct{}
+		topics  map[string]int
+	}
+
+	batches := make([]*batch, 0, 8)
 
 	for _, t := range r.Topics {
 		for _, p := range t.Partitions {
-			requests = append(requests, Request{
-				ReplicaID:      r.ReplicaID,
-				IsolationLevel: r.IsolationLevel,
-				Topics: []RequestTopic{{
-					Topic: t.Topic,
-					Partitions: []RequestPartition{{
-						Partition:          p.Partition,
-						CurrentLeaderEpoch: p.CurrentLeaderEpoch,
-						Timestamp:          p.Timestamp,
-					}},
-				}},
+			key := topicPartition{topic: t.Topic, partition: p.Partition}
+			leader := leaderOf(cluster, t.Topic, p.Partition)
+
+			var b *batch
+			for _, c := range batches {
+				if _, seen := c.seen[key]; c.leader == leader && !seen {
+					b = c
+					break
+				}
+			}
+
+			if b == nil {
+				b = &batch{
+					leader: leader,
+					request: &Request{
+						ReplicaID:      r.ReplicaID,
+						IsolationLevel: r.IsolationLevel,
+					},
+					seen:   make(map[topicPartition]struct{}),
+					topics: make(map[string]int),
+				}
+				batches = append(batches, b)
+			}
+
+			i, ok := b.topics[t.Topic]
+			if !ok {
+				i = len(b.request.Topics)
+				b.topics[t.Topic] = i
+				b.request.Topics = append(b.request.Topics, RequestTopic{Topic: t.Topic})
+			}
+
+			b.request.Topics[i].Partitions = append(b.request.Topics[i].Partitions, RequestPartition{
+				Partition:          p.Partition,
+				CurrentLeaderEpoch: p.CurrentLeaderEpoch,
+				Timestamp:          p.Timestamp,
 			})
+			b.seen[key] = struct{}{}
 		}
 	}
 
-	messages := make([]protocol.Message, len(requests))
+	messages := make([]protocol.Message, len(batches))
 
-	for i := range requests {
-		messages[i] = &requests[i]
+	for i, b := range batches {
+		messages[i] = b.request
 	}
 
 	return messages, new(Response), nil
 }
 
+func leaderOf(cluster protocol.Cluster, topic string, partition int32) int32 {
+	if p, ok := cluster.Topics[topic].Partitions[partition]; ok {
+		return p.Leader
+	}
+	return -1
+}
+
 type Response struct {
 	ThrottleTimeMs int32           `kafka:"min=v2,max=v5"`
 	Topics         []ResponseTopic `kafka:"min=v1,max=v5"`
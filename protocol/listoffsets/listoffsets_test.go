@@ -3,6 +3,7 @@ package listoffsets_test
 import (
 	"testing"
 
+	"github.com/segmentio/kafka-go/protocol"
 	"github.com/segmentio/kafka-go/protocol/listoffsets"
 	"github.com/segmentio/kafka-go/protocol/prototest"
 )
@@ -102,3 +103,80 @@ func TestListOffsetsResponse(t *testing.T) {
 		},
 	})
 }
+
+func TestListOffsetsRequestSplit(t *testing.T) {
+	cluster := protocol.Cluster{
+		Brokers: map[int32]protocol.Broker{
+			1: {ID: 1},
+			2: {ID: 2},
+		},
+		Topics: map[string]protocol.Topic{
+			"topic-1": {
+				Name: "topic-1",
+				Partitions: map[int32]protocol.Partition{
+					0: {ID: 0, Leader: 1},
+					1: {ID: 1, Leader: 2},
+					2: {ID: 2, Leader: 1},
+				},
+			},
+			"topic-2": {
+				Name: "topic-2",
+				Partitions: map[int32]protocol.Partition{
+					0: {ID: 0, Leader: 2},
+				},
+			},
+		},
+	}
+
+	req := &listoffsets.Request{
+		ReplicaID: -1,
+		Topics: []listoffsets.RequestTopic{
+			{
+				Topic: "topic-1",
+				Partitions: []listoffsets.RequestPartition{
+					{Partition: 0, Timestamp: -1},
+					{Partition: 0, Timestamp: -2},
+					{Partition: 1, Timestamp: -1},
+					{Partition: 2, Timestamp: -1},
+				},
+			},
+			{
+				Topic: "topic-2",
+				Partitions: []listoffsets.RequestPartition{
+					{Partition: 0, Timestamp: -1},
+				},
+			},
+		},
+	}
+
+	messages, _, err := req.Split(cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Broker 1 leads topic-1/0 and topic-1/2, but topic-1/0 is requested twice
+	// so it needs two requests; broker 2 needs a single one.
+	brokers := map[int32]int{}
+
+	for _, m := range messages {
+		r := m.(*listoffsets.Request)
+
+		broker, err := r.Broker(cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+		brokers[broker.ID]++
+
+		for _, topic := range r.Topics {
+			for _, p := range topic.Partitions {
+				if leader := cluster.Topics[topic.Topic].Partitions[p.Partition].Leader; leader != broker.ID {
+					t.Errorf("%s/%d routed to broker %d instead of its leader %d", topic.Topic, p.Partition, broker.ID, leader)
+				}
+			}
+		}
+	}
+
+	if brokers[1] != 2 || brokers[2] != 1 {
+		t.Errorf("unexpected number of requests per broker: %v", brokers)
+	}
+}
@@ -0,0 +1,115 @@
+package listtransactions
+
+import "github.com/segmentio/kafka-go/protocol"
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_ListTransactions
+type Request struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v1,tag"`
+
+	StateFilters      []string `kafka:"min=v0,max=v1"`
+	ProducerIDFilters []int64  `kafka:"min=v0,max=v1"`
+	DurationFilter    int64    `kafka:"min=v1,max=v1"`
+
+	brokerID int32
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.ListTransactions }
+
+func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	return cluster.Brokers[r.brokerID], nil
+}
+
+func (r *Request) Split(cluster protocol.Cluster) (
+	[]protocol.Message,
+	protocol.Merger,
+	error,
+) {
+	messages := []protocol.Message{}
+
+	// Each broker only lists the transactions that it coordinates, the request
+	// is sent to all brokers of the cluster.
+	for _, broker := range cluster.Brokers {
+		messages = append(messages, &Request{
+			StateFilters:      r.StateFilters,
+			ProducerIDFilters: r.ProducerIDFilters,
+			DurationFilter:    r.DurationFilter,
+			brokerID:          broker.ID,
+		})
+	}
+
+	return messages, new(Response), nil
+}
+
+type Response struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v1,tag"`
+
+	ThrottleTimeMs      int32              `kafka:"min=v0,max=v1"`
+	ErrorCode           int16              `kafka:"min=v0,max=v1"`
+	UnknownStateFilters []string           `kafka:"min=v0,max=v1"`
+	TransactionStates   []TransactionState `kafka:"min=v0,max=v1"`
+}
+
+type TransactionState struct {
+	// We need at least one tagged field to indicate that v0+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v0,max=v1,tag"`
+
+	TransactionalID  string `kafka:"min=v0,max=v1"`
+	ProducerID       int64  `kafka:"min=v0,max=v1"`
+	TransactionState string `kafka:"min=v0,max=v1"`
+
+	// Use this to store which broker returned the response
+	BrokerID int32 `kafka:"-"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.ListTransactions }
+
+func (r *Response) Merge(requests []protocol.Message, results []interface{}) (
+	protocol.Message,
+	error,
+) {
+	response := &Response{}
+	unknown := make(map[string]bool)
+
+	for i, result := range results {
+		m, err := protocol.Result(result)
+		if err != nil {
+			// A missing broker could hide transactions, the listing fails
+			// instead of returning a partial result.
+			return nil, err
+		}
+
+		res := m.(*Response)
+		brokerID := requests[i].(*Request).brokerID
+
+		if response.ThrottleTimeMs < res.ThrottleTimeMs {
+			response.ThrottleTimeMs = res.ThrottleTimeMs
+		}
+
+		if response.ErrorCode == 0 {
+			response.ErrorCode = res.ErrorCode
+		}
+
+		for _, state := range res.UnknownStateFilters {
+			if !unknown[state] {
+				unknown[state] = true
+				response.UnknownStateFilters = append(response.UnknownStateFilters, state)
+			}
+		}
+
+		for _, state := range res.TransactionStates {
+			state.BrokerID = brokerID
+			response.TransactionStates = append(response.TransactionStates, state)
+		}
+	}
+
+	return response, nil
+}
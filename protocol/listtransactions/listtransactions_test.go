@@ -0,0 +1,40 @@
+package listtransactions_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/listtransactions"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+const (
+	v0 = 0
+	v1 = 1
+)
+
+func TestListTransactionsRequest(t *testing.T) {
+	prototest.TestRequest(t, v0, &listtransactions.Request{
+		StateFilters:      []string{"Ongoing"},
+		ProducerIDFilters: []int64{1000, 1001},
+	})
+
+	prototest.TestRequest(t, v1, &listtransactions.Request{
+		StateFilters:      []string{"Ongoing", "PrepareCommit"},
+		ProducerIDFilters: []int64{},
+		DurationFilter:    60000,
+	})
+}
+
+func TestListTransactionsResponse(t *testing.T) {
+	response := &listtransactions.Response{
+		ThrottleTimeMs:      500,
+		UnknownStateFilters: []string{"Unknown"},
+		TransactionStates: []listtransactions.TransactionState{
+			{TransactionalID: "txn-A", ProducerID: 1000, TransactionState: "Ongoing"},
+			{TransactionalID: "txn-B", ProducerID: 1001, TransactionState: "Empty"},
+		},
+	}
+
+	prototest.TestResponse(t, v0, response)
+	prototest.TestResponse(t, v1, response)
+}
@@ -7,46 +7,93 @@ func init() {
 }
 
 type Request struct {
-	TopicNames                         []string `kafka:"min=v0,max=v8,nullable"`
-	AllowAutoTopicCreation             bool     `kafka:"min=v4,max=v8"`
-	IncludeClusterAuthorizedOperations bool     `kafka:"min=v8,max=v8"`
-	IncludeTopicAuthorizedOperations   bool     `kafka:"min=v8,max=v8"`
+	// We need at least one tagged field to indicate that v9+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v9,max=v12,tag"`
+
+	TopicNames                         []string       `kafka:"min=v0,max=v8,nullable"`
+	Topics                             []RequestTopic `kafka:"min=v9,max=v12,nullable"`
+	AllowAutoTopicCreation             bool           `kafka:"min=v4,max=v12"`
+	IncludeClusterAuthorizedOperations bool           `kafka:"min=v8,max=v10"`
+	IncludeTopicAuthorizedOperations   bool           `kafka:"min=v8,max=v12"`
 }
 
 func (r *Request) ApiKey() protocol.ApiKey { return protocol.Metadata }
 
+// Prepare converts the list of topic names to the representation used by v9+
+// requests, unless the program already set the Topics field.
+func (r *Request) Prepare(apiVersion int16) {
+	if apiVersion >= 9 && r.TopicNames != nil && r.Topics == nil {
+		r.Topics = make([]RequestTopic, len(r.TopicNames))
+
+		for i, name := range r.TopicNames {
+			r.Topics[i] = RequestTopic{Name: name}
+		}
+	}
+}
+
+type RequestTopic struct {
+	// We need at least one tagged field to indicate that v9+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v9,max=v12,tag"`
+
+	TopicID protocol.UUID `kafka:"min=v10,max=v12"`
+	Name    string        `kafka:"min=v9,max=v9|min=v10,max=v12,nullable"`
+}
+
 type Response struct {
-	ThrottleTimeMs              int32            `kafka:"min=v3,max=v8"`
-	Brokers                     []ResponseBroker `kafka:"min=v0,max=v8"`
-	ClusterID                   string           `kafka:"min=v2,max=v8,nullable"`
-	ControllerID                int32            `kafka:"min=v1,max=v8"`
-	Topics                      []ResponseTopic  `kafka:"min=v0,max=v8"`
-	ClusterAuthorizedOperations int32            `kafka:"min=v8,max=v8"`
+	// We need at least one tagged field to indicate that v9+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v9,max=v12,tag"`
+
+	ThrottleTimeMs              int32            `kafka:"min=v3,max=v12"`
+	Brokers                     []ResponseBroker `kafka:"min=v0,max=v12"`
+	ClusterID                   string           `kafka:"min=v2,max=v12,nullable,intern"`
+	ControllerID                int32            `kafka:"min=v1,max=v12"`
+	Topics                      []ResponseTopic  `kafka:"min=v0,max=v12"`
+	ClusterAuthorizedOperations int32            `kafka:"min=v8,max=v10"`
 }
 
 func (r *Response) ApiKey() protocol.ApiKey { return protocol.Metadata }
 
 type ResponseBroker struct {
-	NodeID int32  `kafka:"min=v0,max=v8"`
-	Host   string `kafka:"min=v0,max=v8"`
-	Port   int32  `kafka:"min=v0,max=v8"`
-	Rack   string `kafka:"min=v1,max=v8,nullable"`
+	// We need at least one tagged field to indicate that v9+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v9,max=v12,tag"`
+
+	NodeID int32  `kafka:"min=v0,max=v12"`
+	Host   string `kafka:"min=v0,max=v12,intern"`
+	Port   int32  `kafka:"min=v0,max=v12"`
+	Rack   string `kafka:"min=v1,max=v12,nullable,intern"`
 }
 
 type ResponseTopic struct {
-	ErrorCode                 int16               `kafka:"min=v0,max=v8"`
-	Name                      string              `kafka:"min=v0,max=v8"`
-	IsInternal                bool                `kafka:"min=v1,max=v8"`
-	Partitions                []ResponsePartition `kafka:"min=v0,max=v8"`
-	TopicAuthorizedOperations int32               `kafka:"min=v8,max=v8"`
+	// We need at least one tagged field to indicate that v9+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v9,max=v12,tag"`
+
+	ErrorCode                 int16               `kafka:"min=v0,max=v12"`
+	Name                      string              `kafka:"min=v0,max=v11,intern|min=v12,max=v12,nullable,intern"`
+	TopicID                   protocol.UUID       `kafka:"min=v10,max=v12"`
+	IsInternal                bool                `kafka:"min=v1,max=v12"`
+	Partitions                []ResponsePartition `kafka:"min=v0,max=v12"`
+	TopicAuthorizedOperations int32               `kafka:"min=v8,max=v12"`
 }
 
 type ResponsePartition struct {
-	ErrorCode       int16   `kafka:"min=v0,max=v8"`
-	PartitionIndex  int32   `kafka:"min=v0,max=v8"`
-	LeaderID        int32   `kafka:"min=v0,max=v8"`
-	LeaderEpoch     int32   `kafka:"min=v7,max=v8"`
-	ReplicaNodes    []int32 `kafka:"min=v0,max=v8"`
-	IsrNodes        []int32 `kafka:"min=v0,max=v8"`
-	OfflineReplicas []int32 `kafka:"min=v5,max=v8"`
+	// We need at least one tagged field to indicate that v9+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v9,max=v12,tag"`
+
+	ErrorCode       int16   `kafka:"min=v0,max=v12"`
+	PartitionIndex  int32   `kafka:"min=v0,max=v12"`
+	LeaderID        int32   `kafka:"min=v0,max=v12"`
+	LeaderEpoch     int32   `kafka:"min=v7,max=v12"`
+	ReplicaNodes    []int32 `kafka:"min=v0,max=v12"`
+	IsrNodes        []int32 `kafka:"min=v0,max=v12"`
+	OfflineReplicas []int32 `kafka:"min=v5,max=v12"`
 }
+
+var (
+	_ protocol.PreparedMessage = (*Request)(nil)
+)
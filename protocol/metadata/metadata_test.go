@@ -1,17 +1,23 @@
 package metadata_test
 
 import (
+	"bytes"
+	"reflect"
 	"testing"
+	"unsafe"
 
+	"github.com/segmentio/kafka-go/protocol"
 	"github.com/segmentio/kafka-go/protocol/metadata"
 	"github.com/segmentio/kafka-go/protocol/prototest"
 )
 
 const (
-	v0 = 0
-	v1 = 1
-	v4 = 4
-	v8 = 8
+	v0  = 0
+	v1  = 1
+	v4  = 4
+	v8  = 8
+	v9  = 9
+	v12 = 12
 )
 
 func TestMetadataRequest(t *testing.T) {
@@ -30,6 +36,45 @@ func TestMetadataRequest(t *testing.T) {
 		IncludeClusterAuthorizedOperations: true,
 		IncludeTopicAuthorizedOperations:   true,
 	})
+
+	prototest.TestRequest(t, v9, &metadata.Request{
+		Topics: []metadata.RequestTopic{
+			{Name: "hello"},
+			{Name: "world"},
+		},
+		AllowAutoTopicCreation:             true,
+		IncludeClusterAuthorizedOperations: true,
+		IncludeTopicAuthorizedOperations:   true,
+	})
+
+	prototest.TestRequest(t, v12, &metadata.Request{
+		Topics: []metadata.RequestTopic{
+			{Name: "hello"},
+			{TopicID: protocol.UUID{15: 1}},
+		},
+		AllowAutoTopicCreation:           true,
+		IncludeTopicAuthorizedOperations: true,
+	})
+}
+
+func TestMetadataRequestPrepare(t *testing.T) {
+	req := &metadata.Request{TopicNames: []string{"hello", "world"}}
+
+	req.Prepare(v8)
+	if req.Topics != nil {
+		t.Errorf("topics must not be set on v8 requests: %+v", req.Topics)
+	}
+
+	req.Prepare(v12)
+	if len(req.Topics) != 2 || req.Topics[0].Name != "hello" || req.Topics[1].Name != "world" {
+		t.Errorf("wrong topics: %+v", req.Topics)
+	}
+
+	req = &metadata.Request{}
+	req.Prepare(v12)
+	if req.Topics != nil {
+		t.Errorf("requests for all topics must have nil topics: %+v", req.Topics)
+	}
 }
 
 func TestMetadataResponse(t *testing.T) {
@@ -141,6 +186,37 @@ func TestMetadataResponse(t *testing.T) {
 			},
 		},
 	})
+
+	prototest.TestResponse(t, v12, &metadata.Response{
+		ThrottleTimeMs: 123,
+		ClusterID:      "test",
+		ControllerID:   1,
+		Brokers: []metadata.ResponseBroker{
+			{
+				NodeID: 0,
+				Host:   "127.0.0.1",
+				Port:   9092,
+				Rack:   "rack-1",
+			},
+		},
+		Topics: []metadata.ResponseTopic{
+			{
+				Name:    "topic-1",
+				TopicID: protocol.UUID{15: 1},
+				Partitions: []metadata.ResponsePartition{
+					{
+						PartitionIndex:  0,
+						LeaderID:        0,
+						LeaderEpoch:     1,
+						ReplicaNodes:    []int32{0},
+						IsrNodes:        []int32{0},
+						OfflineReplicas: []int32{},
+					},
+				},
+				TopicAuthorizedOperations: 0x01,
+			},
+		},
+	})
 }
 
 func BenchmarkMetadataRequest(b *testing.B) {
@@ -197,3 +273,36 @@ func BenchmarkMetadataResponse(b *testing.B) {
 	})
 
 }
+
+func TestMetadataResponseInternTopicNames(t *testing.T) {
+	res := &metadata.Response{
+		Brokers: []metadata.ResponseBroker{{NodeID: 1, Host: "127.0.0.1", Port: 9092}},
+		Topics:  []metadata.ResponseTopic{{Name: "topic-A"}},
+	}
+
+	b := &bytes.Buffer{}
+	if err := protocol.WriteResponse(b, v12, 1, res); err != nil {
+		t.Fatal(err)
+	}
+	data := b.Bytes()
+
+	var names []string
+	for i := 0; i < 2; i++ {
+		_, msg, err := protocol.ReadResponse(bytes.NewReader(data), protocol.Metadata, v12)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := msg.(*metadata.Response)
+		if r.Brokers[0].Host != "127.0.0.1" {
+			t.Errorf("wrong broker host: %q", r.Brokers[0].Host)
+		}
+		names = append(names, r.Topics[0].Name)
+	}
+
+	if names[0] != "topic-A" {
+		t.Errorf("wrong topic name: %q", names[0])
+	}
+	if (*reflect.StringHeader)(unsafe.Pointer(&names[0])).Data != (*reflect.StringHeader)(unsafe.Pointer(&names[1])).Data {
+		t.Error("topic names decoded from different responses were not interned")
+	}
+}
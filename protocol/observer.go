@@ -0,0 +1,145 @@
+package protocol
+
+import (
+	"net"
+	"time"
+)
+
+// RoundTripObserver is an interface implemented by types that observe the
+// requests sent to kafka brokers and the responses received for them, for
+// example to log the messages exchanged on connections when debugging
+// compatibility issues with brokers.
+//
+// The observer is invoked once per round trip, after the response was read or
+// the round trip failed. Observers may be called concurrently from multiple
+// goroutines, and must not retain or modify the messages they receive.
+type RoundTripObserver interface {
+	ObserveRoundTrip(RoundTripInfo)
+}
+
+// RoundTripObserverFunc is an implementation of the RoundTripObserver interface
+// for functions.
+type RoundTripObserverFunc func(RoundTripInfo)
+
+// ObserveRoundTrip satisfies the RoundTripObserver interface.
+func (f RoundTripObserverFunc) ObserveRoundTrip(info RoundTripInfo) { f(info) }
+
+// RoundTripInfo carries the details of a round trip with a kafka broker.
+type RoundTripInfo struct {
+	// Address of the kafka broker.
+	Addr net.Addr
+
+	// The client ID sent in the request header.
+	ClientID string
+
+	// The API key and version of the request.
+	ApiKey     ApiKey
+	ApiVersion int16
+
+	// The correlation ID of the request.
+	CorrelationID int32
+
+	// The request and response messages. They are nil when redacted, the
+	// response is also nil if the round trip failed or when the request
+	// expects no response (e.g. produce requests with no acknowledgements).
+	Request  Message
+	Response Message
+
+	// The number of bytes written to send the request, and read to receive
+	// the response, including the message headers.
+	RequestSize  int64
+	ResponseSize int64
+
+	// The time at which the request started being written, and the time it
+	// took to complete the round trip.
+	Start   time.Time
+	Latency time.Duration
+
+	// The error that caused the round trip to fail, if any.
+	Error error
+}
+
+// Redaction is a bitset of options controlling which messages are passed to
+// round trip observers.
+//
+// The messages of SaslAuthenticate exchanges carry credentials, they are never
+// passed to observers.
+type Redaction uint
+
+const (
+	// RedactRecords omits the messages carrying records, which are produce
+	// requests and fetch responses.
+	RedactRecords Redaction = 1 << iota
+
+	// RedactMessages omits all messages, observers only receive the metadata
+	// of round trips.
+	RedactMessages
+)
+
+func (r Redaction) redacts(msg Message, request bool) bool {
+	if msg == nil || r&RedactMessages != 0 {
+		return true
+	}
+	switch msg.ApiKey() {
+	case SaslAuthenticate:
+		return true
+	case Produce:
+		return request && r&RedactRecords != 0
+	case Fetch:
+		return !request && r&RedactRecords != 0
+	}
+	return false
+}
+
+type roundTripObserver struct {
+	observer RoundTripObserver
+	redact   Redaction
+}
+
+func (o *roundTripObserver) observe(info RoundTripInfo) {
+	if o.redact.redacts(info.Request, true) {
+		info.Request = nil
+	}
+	if o.redact.redacts(info.Response, false) {
+		info.Response = nil
+	}
+	o.observer.ObserveRoundTrip(info)
+}
+
+// observedConn wraps a Conn to count the bytes exchanged during a round trip.
+// It retains the bufferedReader implementation of the connection, which the
+// decoding of record sets is optimized for.
+type observedConn struct {
+	*Conn
+	read    int64
+	written int64
+}
+
+func (c *observedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.read += int64(n)
+	return n, err
+}
+
+func (c *observedConn) Discard(n int) (int, error) {
+	n, err := c.Conn.Discard(n)
+	c.read += int64(n)
+	return n, err
+}
+
+func (c *observedConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.written += int64(n)
+	return n, err
+}
+
+func (c *observedConn) writeBuffers(b *net.Buffers) (int64, error) {
+	n, err := c.Conn.writeBuffers(b)
+	c.written += n
+	return n, err
+}
+
+var (
+	_ bufferedReader = (*observedConn)(nil)
+	_ buffersWriter  = (*observedConn)(nil)
+)
@@ -0,0 +1,105 @@
+package protocol_test
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/saslauthenticate"
+)
+
+func TestConnRoundTripObserver(t *testing.T) {
+	tests := []struct {
+		scenario string
+		redact   protocol.Redaction
+		version  int16
+		request  protocol.Message
+		response protocol.Message
+		redacted bool
+	}{
+		{
+			scenario: "messages are passed to the observer",
+			request:  &apiversions.Request{},
+			response: &apiversions.Response{
+				ApiKeys: []apiversions.ApiKeyResponse{{ApiKey: 3, MinVersion: 0, MaxVersion: 12}},
+			},
+		},
+		{
+			scenario: "messages are omitted when redacted",
+			redact:   protocol.RedactMessages,
+			request:  &apiversions.Request{},
+			response: &apiversions.Response{},
+			redacted: true,
+		},
+		{
+			scenario: "credentials are always omitted",
+			version:  1,
+			request:  &saslauthenticate.Request{AuthBytes: []byte("secret")},
+			response: &saslauthenticate.Response{},
+			redacted: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			client, server := net.Pipe()
+			conn := protocol.NewConn(client, "test")
+			conn.SetVersions(map[protocol.ApiKey]int16{
+				protocol.SaslHandshake: 1, // don't send raw SASL authentication messages
+				test.request.ApiKey():  test.version,
+			})
+			defer conn.Close()
+
+			responseSize := 0
+			go func() {
+				defer server.Close()
+
+				apiVersion, correlationID, _, _, err := protocol.ReadRequest(server)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				b := new(bytes.Buffer)
+				protocol.WriteResponse(b, apiVersion, correlationID, test.response)
+				responseSize = b.Len()
+				server.Write(b.Bytes())
+			}()
+
+			var infos []protocol.RoundTripInfo
+			conn.SetRoundTripObserver(protocol.RoundTripObserverFunc(func(info protocol.RoundTripInfo) {
+				infos = append(infos, info)
+			}), test.redact)
+
+			if _, err := conn.RoundTrip(test.request); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(infos) != 1 {
+				t.Fatalf("wrong number of observed round trips: %d", len(infos))
+			}
+
+			b := new(bytes.Buffer)
+			protocol.WriteRequest(b, test.version, 1, "test", test.request)
+
+			info := infos[0]
+			if info.ApiKey != test.request.ApiKey() || info.ApiVersion != test.version || info.CorrelationID != 1 || info.ClientID != "test" {
+				t.Errorf("wrong round trip header: %+v", info)
+			}
+			if info.RequestSize != int64(b.Len()) {
+				t.Errorf("wrong request size: want=%d got=%d", b.Len(), info.RequestSize)
+			}
+			if info.ResponseSize != int64(responseSize) {
+				t.Errorf("wrong response size: want=%d got=%d", responseSize, info.ResponseSize)
+			}
+			if info.Latency <= 0 || info.Error != nil {
+				t.Errorf("wrong round trip result: latency=%s error=%v", info.Latency, info.Error)
+			}
+			if redacted := info.Request == nil && info.Response == nil; redacted != test.redacted {
+				t.Errorf("wrong redaction: request=%+v response=%+v", info.Request, info.Response)
+			}
+		})
+	}
+}
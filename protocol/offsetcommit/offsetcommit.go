@@ -7,12 +7,16 @@ func init() {
 }
 
 type Request struct {
-	GroupID         string         `kafka:"min=v0,max=v7"`
-	GenerationID    int32          `kafka:"min=v1,max=v7"`
-	MemberID        string         `kafka:"min=v1,max=v7"`
+	// We need at least one tagged field to indicate that v8+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v8,max=v9,tag"`
+
+	GroupID         string         `kafka:"min=v0,max=v9"`
+	GenerationID    int32          `kafka:"min=v1,max=v9"`
+	MemberID        string         `kafka:"min=v1,max=v9"`
 	RetentionTimeMs int64          `kafka:"min=v2,max=v4"`
-	GroupInstanceID string         `kafka:"min=v7,max=v7,nullable"`
-	Topics          []RequestTopic `kafka:"min=v0,max=v7"`
+	GroupInstanceID string         `kafka:"min=v7,max=v9,nullable"`
+	Topics          []RequestTopic `kafka:"min=v0,max=v9"`
 }
 
 func (r *Request) ApiKey() protocol.ApiKey { return protocol.OffsetCommit }
@@ -20,16 +24,24 @@ func (r *Request) ApiKey() protocol.ApiKey { return protocol.OffsetCommit }
 func (r *Request) Group() string { return r.GroupID }
 
 type RequestTopic struct {
-	Name       string             `kafka:"min=v0,max=v7"`
-	Partitions []RequestPartition `kafka:"min=v0,max=v7"`
+	// We need at least one tagged field to indicate that v8+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v8,max=v9,tag"`
+
+	Name       string             `kafka:"min=v0,max=v9"`
+	Partitions []RequestPartition `kafka:"min=v0,max=v9"`
 }
 
 type RequestPartition struct {
-	PartitionIndex       int32  `kafka:"min=v0,max=v7"`
-	CommittedOffset      int64  `kafka:"min=v0,max=v7"`
+	// We need at least one tagged field to indicate that v8+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v8,max=v9,tag"`
+
+	PartitionIndex       int32  `kafka:"min=v0,max=v9"`
+	CommittedOffset      int64  `kafka:"min=v0,max=v9"`
 	CommitTimestamp      int64  `kafka:"min=v1,max=v1"`
-	CommittedLeaderEpoch int32  `kafka:"min=v5,max=v7"`
-	CommittedMetadata    string `kafka:"min=v0,max=v7,nullable"`
+	CommittedLeaderEpoch int32  `kafka:"min=v5,max=v9"`
+	CommittedMetadata    string `kafka:"min=v0,max=v9,nullable"`
 }
 
 var (
@@ -37,18 +49,30 @@ var (
 )
 
 type Response struct {
-	ThrottleTimeMs int32           `kafka:"min=v3,max=v7"`
-	Topics         []ResponseTopic `kafka:"min=v0,max=v7"`
+	// We need at least one tagged field to indicate that v8+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v8,max=v9,tag"`
+
+	ThrottleTimeMs int32           `kafka:"min=v3,max=v9"`
+	Topics         []ResponseTopic `kafka:"min=v0,max=v9"`
 }
 
 func (r *Response) ApiKey() protocol.ApiKey { return protocol.OffsetCommit }
 
 type ResponseTopic struct {
-	Name       string              `kafka:"min=v0,max=v7"`
-	Partitions []ResponsePartition `kafka:"min=v0,max=v7"`
+	// We need at least one tagged field to indicate that v8+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v8,max=v9,tag"`
+
+	Name       string              `kafka:"min=v0,max=v9"`
+	Partitions []ResponsePartition `kafka:"min=v0,max=v9"`
 }
 
 type ResponsePartition struct {
-	PartitionIndex int32 `kafka:"min=v0,max=v7"`
-	ErrorCode      int16 `kafka:"min=v0,max=v7"`
+	// We need at least one tagged field to indicate that v8+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v8,max=v9,tag"`
+
+	PartitionIndex int32 `kafka:"min=v0,max=v9"`
+	ErrorCode      int16 `kafka:"min=v0,max=v9"`
 }
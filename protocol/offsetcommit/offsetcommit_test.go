@@ -127,7 +127,9 @@ func TestOffsetCommitRequest(t *testing.T) {
 
 	// Version 7 added:
 	// GroupInstanceID
-	for _, version := range []int16{7} {
+	// Version 8 switched to flexible messages, version 9 is the same as 8 but
+	// members of KIP-848 consumer groups must use it.
+	for _, version := range []int16{7, 8, 9} {
 		prototest.TestRequest(t, version, &offsetcommit.Request{
 			GroupID:         "group-4",
 			GenerationID:    1,
@@ -176,8 +178,8 @@ func TestOffsetCommitResponse(t *testing.T) {
 
 	// Version 3 added:
 	// ThrottleTimeMs
-	// Field are the same through version 7.
-	for _, version := range []int16{3, 4, 5, 6, 7} {
+	// Field are the same through version 9.
+	for _, version := range []int16{3, 4, 5, 6, 7, 8, 9} {
 		prototest.TestResponse(t, version, &offsetcommit.Response{
 			ThrottleTimeMs: 10000,
 			Topics: []offsetcommit.ResponseTopic{
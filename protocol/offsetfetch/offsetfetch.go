@@ -33,7 +33,7 @@ type Response struct {
 func (r *Response) ApiKey() protocol.ApiKey { return protocol.OffsetFetch }
 
 type ResponseTopic struct {
-	Name       string              `kafka:"min=v0,max=v5"`
+	Name       string              `kafka:"min=v0,max=v5,intern"`
 	Partitions []ResponsePartition `kafka:"min=v0,max=v5"`
 }
 
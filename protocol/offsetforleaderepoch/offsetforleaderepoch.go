@@ -0,0 +1,206 @@
+package offsetforleaderepoch
+
+import (
+	"sort"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+type Request struct {
+	// We need at least one tagged field to indicate that v4+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v4,max=v4,tag"`
+
+	ReplicaID int32          `kafka:"min=v3,max=v4"`
+	Topics    []RequestTopic `kafka:"min=v0,max=v4"`
+}
+
+type RequestTopic struct {
+	// We need at least one tagged field to indicate that v4+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v4,max=v4,tag"`
+
+	Topic      string             `kafka:"min=v0,max=v4"`
+	Partitions []RequestPartition `kafka:"min=v0,max=v4"`
+}
+
+type RequestPartition struct {
+	// We need at least one tagged field to indicate that v4+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v4,max=v4,tag"`
+
+	Partition          int32 `kafka:"min=v0,max=v4"`
+	CurrentLeaderEpoch int32 `kafka:"min=v2,max=v4"`
+	LeaderEpoch        int32 `kafka:"min=v0,max=v4"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.OffsetForLeaderEpoch }
+
+func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	// Expects r to be a request that was returned by Split, will likely panic
+	// or produce the wrong result if that's not the case.
+	partition := r.Topics[0].Partitions[0].Partition
+	topic := r.Topics[0].Topic
+
+	if leader := leaderOf(cluster, topic, partition); leader >= 0 {
+		return cluster.Brokers[leader], nil
+	}
+
+	return protocol.Broker{ID: -1}, nil
+}
+
+func (r *Request) Split(cluster protocol.Cluster) ([]protocol.Message, protocol.Merger, error) {
+	// OffsetForLeaderEpoch requests must be sent to partition leaders, the
+	// partitions are batched by leader and the responses merged back.
+	type batch struct {
+		request *Request
+		topics  map[string]int
+	}
+
+	batches := make(map[int32]*batch)
+	leaders := make([]int32, 0, 8)
+
+	for _, t := range r.Topics {
+		for _, p := range t.Partitions {
+			leader := leaderOf(cluster, t.Topic, p.Partition)
+
+			b := batches[leader]
+			if b == nil {
+				b = &batch{
+					request: &Request{ReplicaID: r.ReplicaID},
+					topics:  make(map[string]int),
+				}
+				batches[leader] = b
+				leaders = append(leaders, leader)
+			}
+
+			i, ok := b.topics[t.Topic]
+			if !ok {
+				i = len(b.request.Topics)
+				b.topics[t.Topic] = i
+				b.request.Topics = append(b.request.Topics, RequestTopic{Topic: t.Topic})
+			}
+
+			b.request.Topics[i].Partitions = append(b.request.Topics[i].Partitions, p)
+		}
+	}
+
+	messages := make([]protocol.Message, len(leaders))
+
+	for i, leader := range leaders {
+		messages[i] = batches[leader].request
+	}
+
+	return messages, new(Response), nil
+}
+
+func leaderOf(cluster protocol.Cluster, topic string, partition int32) int32 {
+	if p, ok := cluster.Topics[topic].Partitions[partition]; ok {
+		return p.Leader
+	}
+	return -1
+}
+
+type Response struct {
+	// We need at least one tagged field to indicate that v4+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v4,max=v4,tag"`
+
+	ThrottleTimeMs int32           `kafka:"min=v2,max=v4"`
+	Topics         []ResponseTopic `kafka:"min=v0,max=v4"`
+}
+
+type ResponseTopic struct {
+	// We need at least one tagged field to indicate that v4+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v4,max=v4,tag"`
+
+	Topic      string              `kafka:"min=v0,max=v4"`
+	Partitions []ResponsePartition `kafka:"min=v0,max=v4"`
+}
+
+type ResponsePartition struct {
+	// We need at least one tagged field to indicate that v4+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v4,max=v4,tag"`
+
+	ErrorCode   int16 `kafka:"min=v0,max=v4"`
+	Partition   int32 `kafka:"min=v0,max=v4"`
+	LeaderEpoch int32 `kafka:"min=v1,max=v4"`
+	EndOffset   int64 `kafka:"min=v0,max=v4"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.OffsetForLeaderEpoch }
+
+func (r *Response) Merge(requests []protocol.Message, results []interface{}) (protocol.Message, error) {
+	topics := make(map[string][]ResponsePartition)
+	errors := 0
+
+	for i, res := range results {
+		m, err := protocol.Result(res)
+		if err != nil {
+			for _, t := range requests[i].(*Request).Topics {
+				partitions := topics[t.Topic]
+
+				for _, p := range t.Partitions {
+					partitions = append(partitions, ResponsePartition{
+						ErrorCode:   -1, // UNKNOWN, can we do better?
+						Partition:   p.Partition,
+						LeaderEpoch: -1,
+						EndOffset:   -1,
+					})
+				}
+
+				topics[t.Topic] = partitions
+			}
+			errors++
+			continue
+		}
+
+		response := m.(*Response)
+
+		if r.ThrottleTimeMs < response.ThrottleTimeMs {
+			r.ThrottleTimeMs = response.ThrottleTimeMs
+		}
+
+		for _, t := range response.Topics {
+			topics[t.Topic] = append(topics[t.Topic], t.Partitions...)
+		}
+	}
+
+	if errors > 0 && errors == len(results) {
+		_, err := protocol.Result(results[0])
+		return nil, err
+	}
+
+	r.Topics = make([]ResponseTopic, 0, len(topics))
+
+	for topicName, partitions := range topics {
+		r.Topics = append(r.Topics, ResponseTopic{
+			Topic:      topicName,
+			Partitions: partitions,
+		})
+	}
+
+	sort.Slice(r.Topics, func(i, j int) bool {
+		return r.Topics[i].Topic < r.Topics[j].Topic
+	})
+
+	for _, t := range r.Topics {
+		sort.Slice(t.Partitions, func(i, j int) bool {
+			return t.Partitions[i].Partition < t.Partitions[j].Partition
+		})
+	}
+
+	return r, nil
+}
+
+var (
+	_ protocol.BrokerMessage = (*Request)(nil)
+	_ protocol.Splitter      = (*Request)(nil)
+	_ protocol.Merger        = (*Response)(nil)
+)
@@ -0,0 +1,96 @@
+package offsetforleaderepoch_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/offsetforleaderepoch"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+)
+
+const (
+	v0 = 0
+	v1 = 1
+	v2 = 2
+	v3 = 3
+	v4 = 4
+)
+
+func TestOffsetForLeaderEpochRequest(t *testing.T) {
+	prototest.TestRequest(t, v0, &offsetforleaderepoch.Request{
+		Topics: []offsetforleaderepoch.RequestTopic{
+			{
+				Topic: "topic-1",
+				Partitions: []offsetforleaderepoch.RequestPartition{
+					{Partition: 0, LeaderEpoch: 1},
+					{Partition: 1, LeaderEpoch: 2},
+				},
+			},
+		},
+	})
+
+	prototest.TestRequest(t, v2, &offsetforleaderepoch.Request{
+		Topics: []offsetforleaderepoch.RequestTopic{
+			{
+				Topic: "topic-1",
+				Partitions: []offsetforleaderepoch.RequestPartition{
+					{Partition: 0, CurrentLeaderEpoch: 3, LeaderEpoch: 1},
+				},
+			},
+		},
+	})
+
+	for _, version := range []int16{v3, v4} {
+		prototest.TestRequest(t, version, &offsetforleaderepoch.Request{
+			ReplicaID: -1,
+			Topics: []offsetforleaderepoch.RequestTopic{
+				{
+					Topic: "topic-1",
+					Partitions: []offsetforleaderepoch.RequestPartition{
+						{Partition: 0, CurrentLeaderEpoch: 3, LeaderEpoch: 1},
+						{Partition: 1, CurrentLeaderEpoch: 3, LeaderEpoch: 2},
+					},
+				},
+			},
+		})
+	}
+}
+
+func TestOffsetForLeaderEpochResponse(t *testing.T) {
+	prototest.TestResponse(t, v0, &offsetforleaderepoch.Response{
+		Topics: []offsetforleaderepoch.ResponseTopic{
+			{
+				Topic: "topic-1",
+				Partitions: []offsetforleaderepoch.ResponsePartition{
+					{Partition: 0, EndOffset: 10},
+					{ErrorCode: 74, Partition: 1, EndOffset: -1},
+				},
+			},
+		},
+	})
+
+	prototest.TestResponse(t, v1, &offsetforleaderepoch.Response{
+		Topics: []offsetforleaderepoch.ResponseTopic{
+			{
+				Topic: "topic-1",
+				Partitions: []offsetforleaderepoch.ResponsePartition{
+					{Partition: 0, LeaderEpoch: 1, EndOffset: 10},
+				},
+			},
+		},
+	})
+
+	for _, version := range []int16{v2, v3, v4} {
+		prototest.TestResponse(t, version, &offsetforleaderepoch.Response{
+			ThrottleTimeMs: 100,
+			Topics: []offsetforleaderepoch.ResponseTopic{
+				{
+					Topic: "topic-1",
+					Partitions: []offsetforleaderepoch.ResponsePartition{
+						{Partition: 0, LeaderEpoch: 1, EndOffset: 10},
+						{Partition: 1, LeaderEpoch: 2, EndOffset: 20},
+					},
+				},
+			},
+		})
+	}
+}
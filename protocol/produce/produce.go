@@ -62,6 +62,10 @@ func (r *Request) Prepare(apiVersion int16) {
 	//
 	// In version 2.x, kafka refuses the message claiming that the CRC32
 	// checksum is invalid.
+	//
+	// Records which use features that message sets cannot represent (headers
+	// or zstd compression) fail to be written with an UnsupportedFeatureError
+	// when the request is downgraded.
 	var recordVersion int8
 
 	if apiVersion < 3 {
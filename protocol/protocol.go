@@ -32,6 +32,22 @@ func (k ApiKey) MinVersion() int16 { return k.apiType().minVersion() }
 
 func (k ApiKey) MaxVersion() int16 { return k.apiType().maxVersion() }
 
+// Registered reports whether message types were registered for the API.
+func (k ApiKey) Registered() bool { return len(k.apiType().requests) != 0 }
+
+// Types returns the Go types of the request and response messages registered
+// for the API, or nil if no messages were registered.
+func (k ApiKey) Types() (request, response reflect.Type) {
+	t := k.apiType()
+	if len(t.requests) != 0 {
+		request = t.requests[0].gotype
+	}
+	if len(t.responses) != 0 {
+		response = t.responses[0].gotype
+	}
+	return request, response
+}
+
 func (k ApiKey) SelectVersion(minVersion, maxVersion int16) int16 {
 	min := k.MinVersion()
 	max := k.MaxVersion()
@@ -53,111 +69,151 @@ func (k ApiKey) apiType() apiType {
 }
 
 const (
-	Produce                     ApiKey = 0
-	Fetch                       ApiKey = 1
-	ListOffsets                 ApiKey = 2
-	Metadata                    ApiKey = 3
-	LeaderAndIsr                ApiKey = 4
-	StopReplica                 ApiKey = 5
-	UpdateMetadata              ApiKey = 6
-	ControlledShutdown          ApiKey = 7
-	OffsetCommit                ApiKey = 8
-	OffsetFetch                 ApiKey = 9
-	FindCoordinator             ApiKey = 10
-	JoinGroup                   ApiKey = 11
-	Heartbeat                   ApiKey = 12
-	LeaveGroup                  ApiKey = 13
-	SyncGroup                   ApiKey = 14
-	DescribeGroups              ApiKey = 15
-	ListGroups                  ApiKey = 16
-	SaslHandshake               ApiKey = 17
-	ApiVersions                 ApiKey = 18
-	CreateTopics                ApiKey = 19
-	DeleteTopics                ApiKey = 20
-	DeleteRecords               ApiKey = 21
-	InitProducerId              ApiKey = 22
-	OffsetForLeaderEpoch        ApiKey = 23
-	AddPartitionsToTxn          ApiKey = 24
-	AddOffsetsToTxn             ApiKey = 25
-	EndTxn                      ApiKey = 26
-	WriteTxnMarkers             ApiKey = 27
-	TxnOffsetCommit             ApiKey = 28
-	DescribeAcls                ApiKey = 29
-	CreateAcls                  ApiKey = 30
-	DeleteAcls                  ApiKey = 31
-	DescribeConfigs             ApiKey = 32
-	AlterConfigs                ApiKey = 33
-	AlterReplicaLogDirs         ApiKey = 34
-	DescribeLogDirs             ApiKey = 35
-	SaslAuthenticate            ApiKey = 36
-	CreatePartitions            ApiKey = 37
-	CreateDelegationToken       ApiKey = 38
-	RenewDelegationToken        ApiKey = 39
-	ExpireDelegationToken       ApiKey = 40
-	DescribeDelegationToken     ApiKey = 41
-	DeleteGroups                ApiKey = 42
-	ElectLeaders                ApiKey = 43
-	IncrementalAlterConfigs     ApiKey = 44
-	AlterPartitionReassignments ApiKey = 45
-	ListPartitionReassignments  ApiKey = 46
-	OffsetDelete                ApiKey = 47
-	DescribeClientQuotas        ApiKey = 48
-	AlterClientQuotas           ApiKey = 49
-
-	numApis = 50
+	Produce                      ApiKey = 0
+	Fetch                        ApiKey = 1
+	ListOffsets                  ApiKey = 2
+	Metadata                     ApiKey = 3
+	LeaderAndIsr                 ApiKey = 4
+	StopReplica                  ApiKey = 5
+	UpdateMetadata               ApiKey = 6
+	ControlledShutdown           ApiKey = 7
+	OffsetCommit                 ApiKey = 8
+	OffsetFetch                  ApiKey = 9
+	FindCoordinator              ApiKey = 10
+	JoinGroup                    ApiKey = 11
+	Heartbeat                    ApiKey = 12
+	LeaveGroup                   ApiKey = 13
+	SyncGroup                    ApiKey = 14
+	DescribeGroups               ApiKey = 15
+	ListGroups                   ApiKey = 16
+	SaslHandshake                ApiKey = 17
+	ApiVersions                  ApiKey = 18
+	CreateTopics                 ApiKey = 19
+	DeleteTopics                 ApiKey = 20
+	DeleteRecords                ApiKey = 21
+	InitProducerId               ApiKey = 22
+	OffsetForLeaderEpoch         ApiKey = 23
+	AddPartitionsToTxn           ApiKey = 24
+	AddOffsetsToTxn              ApiKey = 25
+	EndTxn                       ApiKey = 26
+	WriteTxnMarkers              ApiKey = 27
+	TxnOffsetCommit              ApiKey = 28
+	DescribeAcls                 ApiKey = 29
+	CreateAcls                   ApiKey = 30
+	DeleteAcls                   ApiKey = 31
+	DescribeConfigs              ApiKey = 32
+	AlterConfigs                 ApiKey = 33
+	AlterReplicaLogDirs          ApiKey = 34
+	DescribeLogDirs              ApiKey = 35
+	SaslAuthenticate             ApiKey = 36
+	CreatePartitions             ApiKey = 37
+	CreateDelegationToken        ApiKey = 38
+	RenewDelegationToken         ApiKey = 39
+	ExpireDelegationToken        ApiKey = 40
+	DescribeDelegationToken      ApiKey = 41
+	DeleteGroups                 ApiKey = 42
+	ElectLeaders                 ApiKey = 43
+	IncrementalAlterConfigs      ApiKey = 44
+	AlterPartitionReassignments  ApiKey = 45
+	ListPartitionReassignments   ApiKey = 46
+	OffsetDelete                 ApiKey = 47
+	DescribeClientQuotas         ApiKey = 48
+	AlterClientQuotas            ApiKey = 49
+	DescribeUserScramCredentials ApiKey = 50
+	AlterUserScramCredentials    ApiKey = 51
+	Vote                         ApiKey = 52
+	BeginQuorumEpoch             ApiKey = 53
+	EndQuorumEpoch               ApiKey = 54
+	DescribeQuorum               ApiKey = 55
+	AlterPartition               ApiKey = 56
+	UpdateFeatures               ApiKey = 57
+	Envelope                     ApiKey = 58
+	FetchSnapshot                ApiKey = 59
+	DescribeCluster              ApiKey = 60
+	DescribeProducers            ApiKey = 61
+	BrokerRegistration           ApiKey = 62
+	BrokerHeartbeat              ApiKey = 63
+	UnregisterBroker             ApiKey = 64
+	DescribeTransactions         ApiKey = 65
+	ListTransactions             ApiKey = 66
+	AllocateProducerIds          ApiKey = 67
+	ConsumerGroupHeartbeat       ApiKey = 68
+	ConsumerGroupDescribe        ApiKey = 69
+
+	numApis = 70
 )
 
 var apiNames = [numApis]string{
-	Produce:                     "Produce",
-	Fetch:                       "Fetch",
-	ListOffsets:                 "ListOffsets",
-	Metadata:                    "Metadata",
-	LeaderAndIsr:                "LeaderAndIsr",
-	StopReplica:                 "StopReplica",
-	UpdateMetadata:              "UpdateMetadata",
-	ControlledShutdown:          "ControlledShutdown",
-	OffsetCommit:                "OffsetCommit",
-	OffsetFetch:                 "OffsetFetch",
-	FindCoordinator:             "FindCoordinator",
-	JoinGroup:                   "JoinGroup",
-	Heartbeat:                   "Heartbeat",
-	LeaveGroup:                  "LeaveGroup",
-	SyncGroup:                   "SyncGroup",
-	DescribeGroups:              "DescribeGroups",
-	ListGroups:                  "ListGroups",
-	SaslHandshake:               "SaslHandshake",
-	ApiVersions:                 "ApiVersions",
-	CreateTopics:                "CreateTopics",
-	DeleteTopics:                "DeleteTopics",
-	DeleteRecords:               "DeleteRecords",
-	InitProducerId:              "InitProducerId",
-	OffsetForLeaderEpoch:        "OffsetForLeaderEpoch",
-	AddPartitionsToTxn:          "AddPartitionsToTxn",
-	AddOffsetsToTxn:             "AddOffsetsToTxn",
-	EndTxn:                      "EndTxn",
-	WriteTxnMarkers:             "WriteTxnMarkers",
-	TxnOffsetCommit:             "TxnOffsetCommit",
-	DescribeAcls:                "DescribeAcls",
-	CreateAcls:                  "CreateAcls",
-	DeleteAcls:                  "DeleteAcls",
-	DescribeConfigs:             "DescribeConfigs",
-	AlterConfigs:                "AlterConfigs",
-	AlterReplicaLogDirs:         "AlterReplicaLogDirs",
-	DescribeLogDirs:             "DescribeLogDirs",
-	SaslAuthenticate:            "SaslAuthenticate",
-	CreatePartitions:            "CreatePartitions",
-	CreateDelegationToken:       "CreateDelegationToken",
-	RenewDelegationToken:        "RenewDelegationToken",
-	ExpireDelegationToken:       "ExpireDelegationToken",
-	DescribeDelegationToken:     "DescribeDelegationToken",
-	DeleteGroups:                "DeleteGroups",
-	ElectLeaders:                "ElectLeaders",
-	IncrementalAlterConfigs:     "IncrementalAlterConfigs",
-	AlterPartitionReassignments: "AlterPartitionReassignments",
-	ListPartitionReassignments:  "ListPartitionReassignments",
-	OffsetDelete:                "OffsetDelete",
-	DescribeClientQuotas:        "DescribeClientQuotas",
-	AlterClientQuotas:           "AlterClientQuotas",
+	Produce:                      "Produce",
+	Fetch:                        "Fetch",
+	ListOffsets:                  "ListOffsets",
+	Metadata:                     "Metadata",
+	LeaderAndIsr:                 "LeaderAndIsr",
+	StopReplica:                  "StopReplica",
+	UpdateMetadata:               "UpdateMetadata",
+	ControlledShutdown:           "ControlledShutdown",
+	OffsetCommit:                 "OffsetCommit",
+	OffsetFetch:                  "OffsetFetch",
+	FindCoordinator:              "FindCoordinator",
+	JoinGroup:                    "JoinGroup",
+	Heartbeat:                    "Heartbeat",
+	LeaveGroup:                   "LeaveGroup",
+	SyncGroup:                    "SyncGroup",
+	DescribeGroups:               "DescribeGroups",
+	ListGroups:                   "ListGroups",
+	SaslHandshake:                "SaslHandshake",
+	ApiVersions:                  "ApiVersions",
+	CreateTopics:                 "CreateTopics",
+	DeleteTopics:                 "DeleteTopics",
+	DeleteRecords:                "DeleteRecords",
+	InitProducerId:               "InitProducerId",
+	OffsetForLeaderEpoch:         "OffsetForLeaderEpoch",
+	AddPartitionsToTxn:           "AddPartitionsToTxn",
+	AddOffsetsToTxn:              "AddOffsetsToTxn",
+	EndTxn:                       "EndTxn",
+	WriteTxnMarkers:              "WriteTxnMarkers",
+	TxnOffsetCommit:              "TxnOffsetCommit",
+	DescribeAcls:                 "DescribeAcls",
+	CreateAcls:                   "CreateAcls",
+	DeleteAcls:                   "DeleteAcls",
+	DescribeConfigs:              "DescribeConfigs",
+	AlterConfigs:                 "AlterConfigs",
+	AlterReplicaLogDirs:          "AlterReplicaLogDirs",
+	DescribeLogDirs:              "DescribeLogDirs",
+	SaslAuthenticate:             "SaslAuthenticate",
+	CreatePartitions:             "CreatePartitions",
+	CreateDelegationToken:        "CreateDelegationToken",
+	RenewDelegationToken:         "RenewDelegationToken",
+	ExpireDelegationToken:        "ExpireDelegationToken",
+	DescribeDelegationToken:      "DescribeDelegationToken",
+	DeleteGroups:                 "DeleteGroups",
+	ElectLeaders:                 "ElectLeaders",
+	IncrementalAlterConfigs:      "IncrementalAlterConfigs",
+	AlterPartitionReassignments:  "AlterPartitionReassignments",
+	ListPartitionReassignments:   "ListPartitionReassignments",
+	OffsetDelete:                 "OffsetDelete",
+	DescribeClientQuotas:         "DescribeClientQuotas",
+	AlterClientQuotas:            "AlterClientQuotas",
+	DescribeUserScramCredentials: "DescribeUserScramCredentials",
+	AlterUserScramCredentials:    "AlterUserScramCredentials",
+	Vote:                         "Vote",
+	BeginQuorumEpoch:             "BeginQuorumEpoch",
+	EndQuorumEpoch:               "EndQuorumEpoch",
+	DescribeQuorum:               "DescribeQuorum",
+	AlterPartition:               "AlterPartition",
+	UpdateFeatures:               "UpdateFeatures",
+	Envelope:                     "Envelope",
+	FetchSnapshot:                "FetchSnapshot",
+	DescribeCluster:              "DescribeCluster",
+	DescribeProducers:            "DescribeProducers",
+	BrokerRegistration:           "BrokerRegistration",
+	BrokerHeartbeat:              "BrokerHeartbeat",
+	UnregisterBroker:             "UnregisterBroker",
+	DescribeTransactions:         "DescribeTransactions",
+	ListTransactions:             "ListTransactions",
+	AllocateProducerIds:          "AllocateProducerIds",
+	ConsumerGroupHeartbeat:       "ConsumerGroupHeartbeat",
+	ConsumerGroupDescribe:        "ConsumerGroupDescribe",
 }
 
 type messageType struct {
@@ -258,6 +314,7 @@ type structTag struct {
 	MaxVersion int16
 	Compact    bool
 	Nullable   bool
+	Intern     bool
 	TagID      int
 }
 
@@ -292,6 +349,8 @@ func forEachStructTag(tag string, do func(structTag) bool) {
 				tag.Compact = true
 			case s == "nullable":
 				tag.Nullable = true
+			case s == "intern":
+				tag.Intern = true
 			default:
 				err = fmt.Errorf("unrecognized option: %q", s)
 			}
@@ -410,6 +469,7 @@ func itoa(i int32) string {
 }
 
 type Topic struct {
+	ID         UUID
 	Name       string
 	Error      int16
 	Partitions map[int32]Partition
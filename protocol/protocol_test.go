@@ -149,6 +149,73 @@ func TestEncodeDecodeFlexibleType(t *testing.T) {
 	}
 }
 
+type testTaggedType struct {
+	Field1 int8 `kafka:"min=v0,max=v1"`
+
+	TaggedField1 int8         `kafka:"min=v1,max=v1,tag=0"`
+	TaggedField2 int8         `kafka:"min=v1,max=v1,tag=2"`
+	TaggedFields TaggedFields `kafka:"min=v1,max=v1,tag"`
+}
+
+func TestEncodeDecodeUnknownTaggedFields(t *testing.T) {
+	exp := []byte{
+		// 1 as 8-bit int
+		1,
+		// number of tagged fields
+		4,
+		// known tagged field 0
+		0, 1, 2,
+		// unknown tagged field 1
+		1, 2, 'a', 'b',
+		// known tagged field 2
+		2, 1, 3,
+		// unknown tagged field 3
+		3, 0,
+	}
+
+	types := makeTypes(reflect.TypeOf(&testTaggedType{}).Elem())
+	ft := types[1]
+
+	d := &decoder{reader: bytes.NewReader(exp), remain: len(exp)}
+	f := &testTaggedType{}
+	ft.decode(d, valueOf(f))
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+
+	want := &testTaggedType{
+		Field1:       1,
+		TaggedField1: 2,
+		TaggedField2: 3,
+		TaggedFields: TaggedFields{
+			{Tag: 1, Data: []byte("ab")},
+			{Tag: 3, Data: []byte{}},
+		},
+	}
+	if !reflect.DeepEqual(want, f) {
+		t.Errorf("wrong decoded value:\nwant: %+v\ngot:  %+v", want, f)
+	}
+
+	b := &bytes.Buffer{}
+	e := &encoder{writer: b}
+	ft.encode(e, valueOf(f))
+	if e.err != nil {
+		t.Fatal(e.err)
+	}
+	if !bytes.Equal(exp, b.Bytes()) {
+		t.Errorf("unknown tagged fields were not preserved:\nwant: %v\ngot:  %v", exp, b.Bytes())
+	}
+
+	// Known tagged fields take precedence over unknown fields with the same
+	// tag.
+	f.TaggedFields = append(f.TaggedFields, TaggedField{Tag: 0, Data: []byte{42}})
+	b.Reset()
+	ft.encode(&encoder{writer: b}, valueOf(f))
+	if !bytes.Equal(exp, b.Bytes()) {
+		t.Errorf("unknown tagged field overwrote a known one:\nwant: %v\ngot:  %v", exp, b.Bytes())
+	}
+}
+
 func TestVarInts(t *testing.T) {
 	type tc struct {
 		input      int64
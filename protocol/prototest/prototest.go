@@ -48,6 +48,8 @@ func deepEqualValue(v1, v2 reflect.Value) bool {
 		return v1.Bool() == v2.Bool()
 	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return v1.Int() == v2.Int()
+	case reflect.Float64:
+		return v1.Float() == v2.Float()
 	case reflect.String:
 		return v1.String() == v2.String()
 	case reflect.Struct:
@@ -56,6 +58,8 @@ func deepEqualValue(v1, v2 reflect.Value) bool {
 		return deepEqualPtr(v1, v2)
 	case reflect.Slice:
 		return deepEqualSlice(v1, v2)
+	case reflect.Array:
+		return reflect.DeepEqual(v1.Interface(), v2.Interface())
 	default:
 		panic("comparing values of unsupported type: " + v1.Type().String())
 	}
@@ -16,8 +16,8 @@ var (
 func closeMessage(m protocol.Message) {
 	forEachField(reflect.ValueOf(m), func(v reflect.Value) {
 		if v.Type().Implements(recordReader) {
-			rr := v.Interface().(protocol.RecordReader)
-			for {
+			rr, ok := v.Interface().(protocol.RecordReader)
+			for ok {
 				r, err := rr.ReadRecord()
 				if err != nil {
 					break
@@ -75,6 +75,11 @@ func forEachField(v reflect.Value, do func(reflect.Value)) {
 
 	case reflect.Struct:
 		for i, n := 0, v.NumField(); i < n; i++ {
+			// Unexported fields are not serialized, they are used to
+			// carry routing information like the broker of split requests.
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
 			forEachField(v.Field(i), do)
 		}
 
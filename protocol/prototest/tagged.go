@@ -0,0 +1,151 @@
+package prototest
+
+import (
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+var (
+	recordSetType    = reflect.TypeOf(protocol.RecordSet{})
+	taggedFieldsType = reflect.TypeOf(protocol.TaggedFields(nil))
+	writerTo         = reflect.TypeOf((*io.WriterTo)(nil)).Elem()
+)
+
+// TestTaggedFields verifies that the structs of all registered messages have a
+// protocol.TaggedFields field in the versions where they are flexible, so the
+// unknown tagged fields that they carry are not dropped.
+//
+// The messages are registered when their packages are imported, programs must
+// import the packages of the messages that they want to verify.
+func TestTaggedFields(t *testing.T) {
+	for k := protocol.ApiKey(0); k < math.MaxInt16; k++ {
+		if !k.Registered() {
+			continue
+		}
+
+		req, res := k.Types()
+
+		for _, typ := range []reflect.Type{req, res} {
+			flexible := flexibleVersionOf(typ)
+			if flexible < 0 {
+				continue
+			}
+
+			for v := flexible; v <= k.MaxVersion(); v++ {
+				seen := make(map[reflect.Type]bool)
+				forEachStructType(typ, v, seen, func(s reflect.Type) {
+					if !hasTaggedFields(s, v) {
+						t.Errorf("%s v%d: %s is flexible but has no protocol.TaggedFields field", k, v, s)
+					}
+				})
+			}
+		}
+	}
+}
+
+// flexibleVersionOf returns the first flexible version of the message type
+// typ, or -1 if the message is never flexible.
+func flexibleVersionOf(typ reflect.Type) int16 {
+	version := int16(-1)
+
+	for i := 0; i < typ.NumField(); i++ {
+		forEachTag(typ.Field(i), func(tag versionTag) {
+			if tag.tagged && (version < 0 || tag.min < version) {
+				version = tag.min
+			}
+		})
+	}
+
+	return version
+}
+
+func hasTaggedFields(typ reflect.Type, version int16) (ok bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		if f := typ.Field(i); f.Type == taggedFieldsType {
+			forEachTag(f, func(tag versionTag) {
+				ok = ok || tag.has(version)
+			})
+		}
+	}
+	return ok
+}
+
+// forEachStructType calls do for typ and all the struct types that it is made
+// of at the given version, except the types which are encoded by the protocol
+// package itself, like record sets.
+func forEachStructType(typ reflect.Type, version int16, seen map[reflect.Type]bool, do func(reflect.Type)) {
+	switch {
+	case typ == recordSetType, typ == taggedFieldsType, reflect.PtrTo(typ).Implements(writerTo):
+		return
+	}
+
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Slice:
+		forEachStructType(typ.Elem(), version, seen, do)
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+
+	if typ.Size() == 0 || seen[typ] {
+		return
+	}
+	seen[typ] = true
+	do(typ)
+
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		active := false
+		forEachTag(f, func(tag versionTag) {
+			active = active || tag.has(version)
+		})
+		if active {
+			forEachStructType(f.Type, version, seen, do)
+		}
+	}
+}
+
+type versionTag struct {
+	min, max int16
+	tagged   bool
+}
+
+func (tag versionTag) has(version int16) bool {
+	return tag.min <= version && version <= tag.max
+}
+
+func forEachTag(f reflect.StructField, do func(versionTag)) {
+	s, ok := f.Tag.Lookup("kafka")
+	if !ok || s == "-" {
+		return
+	}
+
+	for _, alt := range strings.Split(s, "|") {
+		tag := versionTag{min: -1, max: -1}
+
+		for _, opt := range strings.Split(alt, ",") {
+			switch {
+			case strings.HasPrefix(opt, "min=v"):
+				tag.min = parseVersion(opt[5:])
+			case strings.HasPrefix(opt, "max=v"):
+				tag.max = parseVersion(opt[5:])
+			case opt == "tag", strings.HasPrefix(opt, "tag="):
+				tag.tagged = true
+			}
+		}
+
+		do(tag)
+	}
+}
+
+func parseVersion(s string) int16 {
+	v, _ := strconv.ParseInt(s, 10, 16)
+	return int16(v)
+}
@@ -0,0 +1,62 @@
+package prototest_test
+
+import (
+	"testing"
+
+	_ "github.com/segmentio/kafka-go/protocol/addoffsetstotxn"
+	_ "github.com/segmentio/kafka-go/protocol/addpartitionstotxn"
+	_ "github.com/segmentio/kafka-go/protocol/alterclientquotas"
+	_ "github.com/segmentio/kafka-go/protocol/alterconfigs"
+	_ "github.com/segmentio/kafka-go/protocol/alterpartitionreassignments"
+	_ "github.com/segmentio/kafka-go/protocol/alteruserscramcredentials"
+	_ "github.com/segmentio/kafka-go/protocol/apiversions"
+	_ "github.com/segmentio/kafka-go/protocol/consumergroupdescribe"
+	_ "github.com/segmentio/kafka-go/protocol/consumergroupheartbeat"
+	_ "github.com/segmentio/kafka-go/protocol/createacls"
+	_ "github.com/segmentio/kafka-go/protocol/createdelegationtoken"
+	_ "github.com/segmentio/kafka-go/protocol/createpartitions"
+	_ "github.com/segmentio/kafka-go/protocol/createtopics"
+	_ "github.com/segmentio/kafka-go/protocol/deleteacls"
+	_ "github.com/segmentio/kafka-go/protocol/deletetopics"
+	_ "github.com/segmentio/kafka-go/protocol/describeacls"
+	_ "github.com/segmentio/kafka-go/protocol/describeclientquotas"
+	_ "github.com/segmentio/kafka-go/protocol/describecluster"
+	_ "github.com/segmentio/kafka-go/protocol/describeconfigs"
+	_ "github.com/segmentio/kafka-go/protocol/describedelegationtoken"
+	_ "github.com/segmentio/kafka-go/protocol/describegroups"
+	_ "github.com/segmentio/kafka-go/protocol/describeproducers"
+	_ "github.com/segmentio/kafka-go/protocol/describequorum"
+	_ "github.com/segmentio/kafka-go/protocol/describetransactions"
+	_ "github.com/segmentio/kafka-go/protocol/describeuserscramcredentials"
+	_ "github.com/segmentio/kafka-go/protocol/electleaders"
+	_ "github.com/segmentio/kafka-go/protocol/endtxn"
+	_ "github.com/segmentio/kafka-go/protocol/envelope"
+	_ "github.com/segmentio/kafka-go/protocol/expiredelegationtoken"
+	_ "github.com/segmentio/kafka-go/protocol/fetch"
+	_ "github.com/segmentio/kafka-go/protocol/findcoordinator"
+	_ "github.com/segmentio/kafka-go/protocol/heartbeat"
+	_ "github.com/segmentio/kafka-go/protocol/incrementalalterconfigs"
+	_ "github.com/segmentio/kafka-go/protocol/initproducerid"
+	_ "github.com/segmentio/kafka-go/protocol/joingroup"
+	_ "github.com/segmentio/kafka-go/protocol/leavegroup"
+	_ "github.com/segmentio/kafka-go/protocol/listgroups"
+	_ "github.com/segmentio/kafka-go/protocol/listoffsets"
+	_ "github.com/segmentio/kafka-go/protocol/listtransactions"
+	_ "github.com/segmentio/kafka-go/protocol/metadata"
+	_ "github.com/segmentio/kafka-go/protocol/offsetcommit"
+	_ "github.com/segmentio/kafka-go/protocol/offsetdelete"
+	_ "github.com/segmentio/kafka-go/protocol/offsetfetch"
+	_ "github.com/segmentio/kafka-go/protocol/offsetforleaderepoch"
+	_ "github.com/segmentio/kafka-go/protocol/produce"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+	_ "github.com/segmentio/kafka-go/protocol/renewdelegationtoken"
+	_ "github.com/segmentio/kafka-go/protocol/saslauthenticate"
+	_ "github.com/segmentio/kafka-go/protocol/saslhandshake"
+	_ "github.com/segmentio/kafka-go/protocol/syncgroup"
+	_ "github.com/segmentio/kafka-go/protocol/txnoffsetcommit"
+	_ "github.com/segmentio/kafka-go/protocol/writetxnmarkers"
+)
+
+func TestTaggedFields(t *testing.T) {
+	prototest.TestTaggedFields(t)
+}
@@ -0,0 +1,124 @@
+package protocol
+
+import (
+	"io"
+)
+
+// RawRequest is a message carrying a request body which was already encoded
+// by the program, it allows sending requests for APIs or versions that this
+// package does not support.
+//
+// The request and response headers are still written and read by the
+// connection, which takes care of correlating the request with its response.
+type RawRequest struct {
+	// The API key of the request.
+	Key ApiKey
+
+	// The version of the API that Payload was encoded with.
+	Version int16
+
+	// Flexible must be set to true if the version of the API uses flexible
+	// headers. It is only used for APIs or versions that are unknown to this
+	// package, otherwise the value is derived from the registered message
+	// types.
+	Flexible bool
+
+	// The encoded request body, not including the request header.
+	Payload []byte
+}
+
+// ApiKey satisfies the Message interface.
+func (r *RawRequest) ApiKey() ApiKey { return r.Key }
+
+// RawResponse is the response to a RawRequest.
+type RawResponse struct {
+	// The API key of the request that this response was received for.
+	Key ApiKey
+
+	// The encoded response body, not including the response header.
+	Payload []byte
+}
+
+// ApiKey satisfies the Message interface.
+func (r *RawResponse) ApiKey() ApiKey { return r.Key }
+
+func (r *RawRequest) flexible() bool {
+	t := r.Key.apiType()
+
+	if minVersion, maxVersion := t.minVersion(), t.maxVersion(); len(t.requests) != 0 && r.Version >= minVersion && r.Version <= maxVersion {
+		return t.requests[r.Version-minVersion].flexible
+	}
+
+	return r.Flexible
+}
+
+func (r *RawRequest) rawRequest() *RawRequest { return r }
+
+// rawMessage is implemented by RawRequest, as well as types embedding it to
+// customize the routing of raw requests (e.g. by implementing BrokerMessage).
+type rawMessage interface {
+	Message
+	rawRequest() *RawRequest
+}
+
+func (r *RawRequest) roundTrip(rw io.ReadWriter, correlationID int32, clientID string) (*RawResponse, error) {
+	flexible := r.flexible()
+
+	b := newPageBuffer()
+	defer b.unref()
+
+	e := &encoder{writer: b}
+	e.writeInt32(0) // placeholder for the request size
+	e.writeInt16(int16(r.Key))
+	e.writeInt16(r.Version)
+	e.writeInt32(correlationID)
+
+	if flexible {
+		e.writeNullString(clientID)
+		e.writeUnsignedVarInt(0)
+	} else {
+		e.writeString(clientID)
+	}
+
+	e.Write(r.Payload)
+
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	size := packUint32(uint32(b.Size()) - 4)
+	b.WriteAt(size[:], 0)
+
+	if _, err := b.WriteTo(rw); err != nil {
+		return nil, err
+	}
+
+	d := &decoder{reader: rw, remain: 4}
+	d.remain = int(d.readInt32())
+	id := d.readInt32()
+
+	// The ApiVersions responses always use the v0 response header, so clients
+	// can decode them before knowing which versions are supported.
+	if flexible && r.Key != ApiVersions {
+		taggedCount := int(d.readUnsignedVarInt())
+		for i := 0; i < taggedCount; i++ {
+			d.readUnsignedVarInt() // tagID
+			d.discard(int(d.readUnsignedVarInt()))
+		}
+	}
+
+	res := &RawResponse{
+		Key:     r.Key,
+		Payload: d.read(d.remain),
+	}
+
+	if d.err != nil {
+		return nil, dontExpectEOF(d.err)
+	}
+
+	if id != correlationID {
+		return nil, Errorf("correlation id mismatch (expected=%d, found=%d)", correlationID, id)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,73 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestRawRequestRoundTrip(t *testing.T) {
+	for _, flexible := range []bool{false, true} {
+		client, server := net.Pipe()
+		conn := NewConn(client, "test")
+
+		go func() {
+			defer server.Close()
+
+			var size int32
+			binary.Read(server, binary.BigEndian, &size)
+			b := make([]byte, size)
+			io.ReadFull(server, b)
+
+			correlationID := b[4:8]
+			clientID := b[10 : 10+len("test")]
+			payload := b[10+len("test"):]
+			if flexible {
+				// Skip the tagged fields of the request header.
+				payload = payload[1:]
+			}
+
+			if key := binary.BigEndian.Uint16(b[0:2]); key != 1000 {
+				t.Errorf("wrong api key: %d", key)
+			}
+			if version := binary.BigEndian.Uint16(b[2:4]); version != 3 {
+				t.Errorf("wrong api version: %d", version)
+			}
+			if string(clientID) != "test" {
+				t.Errorf("wrong client id: %q", clientID)
+			}
+
+			res := new(bytes.Buffer)
+			res.Write(correlationID)
+			if flexible {
+				res.WriteByte(0)
+			}
+			res.Write(bytes.ToUpper(payload))
+
+			binary.Write(server, binary.BigEndian, int32(res.Len()))
+			server.Write(res.Bytes())
+		}()
+
+		m, err := conn.RoundTrip(&RawRequest{
+			Key:      1000,
+			Version:  3,
+			Flexible: flexible,
+			Payload:  []byte("hello world"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := m.(*RawResponse)
+		if res.Key != 1000 {
+			t.Errorf("wrong api key: %d", res.Key)
+		}
+		if string(res.Payload) != "HELLO WORLD" {
+			t.Errorf("wrong payload: %q", res.Payload)
+		}
+
+		conn.Close()
+	}
+}
@@ -3,7 +3,9 @@ package protocol
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"time"
 
@@ -110,6 +112,23 @@ type RecordSet struct {
 	// that compose the stream, it may use type assertions to access the
 	// underlying types of each batch.
 	Records RecordReader
+
+	// The idempotent or transactional producer writing the records.
+	//
+	// When writing, the producer is written in the header of version 2
+	// batches, which are written without a producer if the field is nil.
+	//
+	// When reading, the field is not set, the producers of each batch are
+	// exposed by the RecordBatch values of the stream.
+	Producer *ProducerState
+}
+
+// ProducerState holds the identity of an idempotent or transactional
+// producer, and the sequence number of the first record of a batch.
+type ProducerState struct {
+	ID           int64
+	Epoch        int16
+	BaseSequence int32
 }
 
 // bufferedReader is an interface implemented by types like bufio.Reader, which
@@ -155,12 +174,21 @@ func (rs *RecordSet) ReadFrom(r io.Reader) (int64, error) {
 		return 4, nil
 	}
 
+	n, err := rs.readRecords(d, int(size))
+	rn := 4 + n
+	d.remain = limit - rn
+	return int64(rn), err
+}
+
+// readRecords reads size bytes of record batches from d into rs, returning the
+// number of bytes consumed from d.
+func (rs *RecordSet) readRecords(d *decoder, size int) (int, error) {
 	stream := &RecordStream{
 		Records: make([]RecordReader, 0, 4),
 	}
 
 	var err error
-	d.remain = int(size)
+	d.remain = size
 
 	for d.remain > 0 && err == nil {
 		var version byte
@@ -169,7 +197,7 @@ func (rs *RecordSet) ReadFrom(r io.Reader) (int64, error) {
 			if len(stream.Records) != 0 {
 				break
 			}
-			return 4, fmt.Errorf("impossible record set shorter than %d bytes", magicByteOffset+1)
+			return 0, fmt.Errorf("impossible record set shorter than %d bytes", magicByteOffset+1)
 		}
 
 		switch r := d.reader.(type) {
@@ -177,7 +205,7 @@ func (rs *RecordSet) ReadFrom(r io.Reader) (int64, error) {
 			b, err := r.Peek(magicByteOffset + 1)
 			if err != nil {
 				n, _ := r.Discard(len(b))
-				return 4 + int64(n), dontExpectEOF(err)
+				return n, dontExpectEOF(err)
 			}
 			version = b[magicByteOffset]
 		case bytesBuffer:
@@ -185,7 +213,7 @@ func (rs *RecordSet) ReadFrom(r io.Reader) (int64, error) {
 		default:
 			b := make([]byte, magicByteOffset+1)
 			if n, err := io.ReadFull(d.reader, b); err != nil {
-				return 4 + int64(n), dontExpectEOF(err)
+				return n, dontExpectEOF(err)
 			}
 			version = b[magicByteOffset]
 			// Reconstruct the prefix that we had to read to determine the version
@@ -208,6 +236,14 @@ func (rs *RecordSet) ReadFrom(r io.Reader) (int64, error) {
 			err = fmt.Errorf("unsupported message version %d for message of size %d", version, size)
 		}
 
+		var corrupt *CorruptRecordError
+		if errors.As(err, &corrupt) {
+			// The corrupted batch was consumed, expose the error in place of
+			// its records and carry on with the next batches.
+			tmp.Records = &CorruptBatch{Err: corrupt}
+			err = nil
+		}
+
 		if tmp.Version > rs.Version {
 			rs.Version = tmp.Version
 		}
@@ -227,9 +263,7 @@ func (rs *RecordSet) ReadFrom(r io.Reader) (int64, error) {
 	}
 
 	d.discardAll()
-	rn := 4 + (int(size) - d.remain)
-	d.remain = limit - rn
-	return int64(rn), err
+	return size - d.remain, err
 }
 
 // WriteTo writes the representation of rs into w. The value of rs.Version
@@ -261,16 +295,7 @@ func (rs *RecordSet) WriteTo(w io.Writer) (int64, error) {
 	size := packUint32(0)
 	buffer.Write(size[:]) // size placeholder
 
-	var err error
-	switch rs.Version {
-	case 0, 1:
-		err = rs.writeToVersion1(buffer, bufferOffset+4)
-	case 2:
-		err = rs.writeToVersion2(buffer, bufferOffset+4)
-	default:
-		err = fmt.Errorf("unsupported record set version %d", rs.Version)
-	}
-	if err != nil {
+	if err := rs.writeRecords(buffer, bufferOffset+4); err != nil {
 		return 0, err
 	}
 
@@ -292,6 +317,220 @@ func (rs *RecordSet) WriteTo(w io.Writer) (int64, error) {
 	return n, nil
 }
 
+// WriteRecordBatch writes the records read from records to w as a single v2
+// record batch, which is the format used by kafka to store records in log
+// segments. Unlike record sets in produce requests, the batch is not prefixed
+// by its size.
+//
+// The attributes configure the compression codec of the batch. The base offset
+// of the batch is the offset of the first record, the following records are
+// assigned consecutive offsets.
+//
+// The function returns ErrNoRecord if records is empty.
+func WriteRecordBatch(w io.Writer, attrs Attributes, records RecordReader) error {
+	r := &baseOffsetRecordReader{records: records}
+	rs := RecordSet{Version: 2, Attributes: attrs, Records: r}
+
+	buffer := newPageBuffer()
+	defer buffer.unref()
+
+	if err := rs.writeRecords(buffer, 0); err != nil {
+		return err
+	}
+
+	// The base offset is not covered by the checksum of the batch, so it can
+	// be set after the records were written.
+	baseOffset := packUint64(uint64(r.baseOffset))
+	buffer.WriteAt(baseOffset[:], 0)
+
+	_, err := buffer.WriteTo(w)
+	return err
+}
+
+// EncodedRecordBatch is a RecordReader exposing a v2 record batch which was
+// already encoded by WriteRecordBatch. Record sets in version 2 with the same
+// attributes as the batch write it as is, instead of encoding (and
+// compressing) its records again, which lets programs compress batches ahead
+// of writing the requests which contain them.
+//
+// The producer state of the record set is written in place of the one of the
+// batch, and the batch checksum is updated accordingly.
+//
+// Reading records decodes the batch, which is only needed when the record set
+// is written in other versions. The value must not be used by multiple record
+// sets at the same time.
+type EncodedRecordBatch struct {
+	// The record batch, as written by WriteRecordBatch.
+	Data []byte
+
+	records RecordReader
+}
+
+// ReadRecord satisfies the RecordReader interface.
+func (b *EncodedRecordBatch) ReadRecord() (*Record, error) {
+	if b.records == nil {
+		rs := &RecordSet{}
+		d := &decoder{reader: bytes.NewReader(b.Data), remain: len(b.Data)}
+		if _, err := rs.readRecords(d, len(b.Data)); err != nil {
+			return nil, err
+		}
+		if rs.Records == nil {
+			return nil, io.EOF
+		}
+		b.records = rs.Records
+	}
+	return b.records.ReadRecord()
+}
+
+// Reset rewinds the records read from the batch.
+func (b *EncodedRecordBatch) Reset() error {
+	b.records = nil
+	return nil
+}
+
+// attributes returns the attributes of the batch, or -1 if it is not a valid
+// v2 record batch.
+func (b *EncodedRecordBatch) attributes() Attributes {
+	// The header of v2 record batches is 61 bytes, the magic byte is at offset
+	// 16 and the attributes at offset 21.
+	if len(b.Data) < 61 || b.Data[16] != 2 {
+		return -1
+	}
+	return Attributes(readInt16(b.Data[21:]))
+}
+
+// writeTo writes the batch to buffer at bufferOffset, with the producer state.
+func (b *EncodedRecordBatch) writeTo(buffer *pageBuffer, bufferOffset int64, producer *ProducerState) {
+	buffer.Write(b.Data)
+	if producer == nil {
+		return
+	}
+
+	id := packUint64(uint64(producer.ID))
+	epoch := packUint16(uint16(producer.Epoch))
+	sequence := packUint32(uint32(producer.BaseSequence))
+	buffer.WriteAt(id[:], bufferOffset+43)
+	buffer.WriteAt(epoch[:], bufferOffset+51)
+	buffer.WriteAt(sequence[:], bufferOffset+53)
+
+	checksum := uint32(0)
+	buffer.scan(bufferOffset+21, bufferOffset+int64(len(b.Data)), func(chunk []byte) bool {
+		checksum = crc32.Update(checksum, castagnoliTable, chunk)
+		return true
+	})
+	crc := packUint32(checksum)
+	buffer.WriteAt(crc[:], bufferOffset+17)
+}
+
+// baseOffsetRecordReader captures the offset of the first record read from a
+// RecordReader.
+type baseOffsetRecordReader struct {
+	records    RecordReader
+	baseOffset int64
+	count      int
+}
+
+func (r *baseOffsetRecordReader) ReadRecord() (*Record, error) {
+	rec, err := r.records.ReadRecord()
+	if err == nil {
+		if r.count == 0 {
+			r.baseOffset = rec.Offset
+		}
+		r.count++
+	}
+	return rec, err
+}
+
+// writeRecords writes the record batches of rs to buffer, starting at
+// bufferOffset.
+func (rs *RecordSet) writeRecords(buffer *pageBuffer, bufferOffset int64) error {
+	switch rs.Version {
+	case 0, 1:
+		return rs.writeToVersion1(buffer, bufferOffset)
+	case 2:
+		return rs.writeToVersion2(buffer, bufferOffset)
+	default:
+		return fmt.Errorf("unsupported record set version %d", rs.Version)
+	}
+}
+
+// readCompactRecordSet reads a record set prefixed with an unsigned varint
+// length, which is how records are represented in "flexible" messages.
+func (d *decoder) readCompactRecordSet(rs *RecordSet) {
+	*rs = RecordSet{}
+
+	n := d.readUnsignedVarInt()
+	if d.err != nil || n < 2 { // null or empty
+		return
+	}
+
+	size := int(n - 1)
+	limit := d.remain
+	if size > limit {
+		d.setError(io.ErrUnexpectedEOF)
+		return
+	}
+
+	rn, err := rs.readRecords(d, size)
+	d.remain = limit - rn
+	d.setError(err)
+}
+
+// writeRecordSet writes rs prefixed with its int32 length, which is how records
+// are represented in messages that are not "flexible".
+func (e *encoder) writeRecordSet(rs *RecordSet) {
+	if rs.Records == nil && e.emptyRecordSets {
+		e.writeInt32(0)
+		return
+	}
+
+	// Optimization to write directly into the buffer when the encoder does no
+	// need to compute a crc32 checksum.
+	w := io.Writer(e)
+	if e.table == nil {
+		w = e.writer
+	}
+
+	if _, err := rs.WriteTo(w); err != nil {
+		e.err = err
+	}
+}
+
+// writeCompactRecordSet writes rs prefixed with an unsigned varint length, which
+// is how records are represented in "flexible" messages.
+func (e *encoder) writeCompactRecordSet(rs *RecordSet) {
+	if rs.Records == nil {
+		if e.emptyRecordSets {
+			e.writeUnsignedVarInt(1)
+		} else {
+			e.err = ErrNoRecord
+		}
+		return
+	}
+
+	buffer := newPageBuffer()
+	defer buffer.unref()
+
+	if err := rs.writeRecords(buffer, 0); err != nil {
+		e.err = err
+		return
+	}
+
+	n := buffer.Size()
+	if n == 0 {
+		e.writeUnsignedVarInt(0)
+		return
+	}
+
+	e.writeUnsignedVarInt(uint64(n) + 1)
+
+	if pb, ok := e.writer.(*pageBuffer); ok && e.table == nil {
+		buffer.WriteTo(pb)
+	} else {
+		buffer.WriteTo(e)
+	}
+}
+
 func makeTime(t int64) time.Time {
 	return time.Unix(t/1000, (t%1000)*int64(time.Millisecond))
 }
@@ -303,6 +542,11 @@ func timestamp(t time.Time) int64 {
 	return t.UnixNano() / int64(time.Millisecond)
 }
 
+func packUint16(u uint16) (b [2]byte) {
+	binary.BigEndian.PutUint16(b[:], u)
+	return
+}
+
 func packUint32(u uint32) (b [4]byte) {
 	binary.BigEndian.PutUint32(b[:], u)
 	return
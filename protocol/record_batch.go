@@ -1,6 +1,8 @@
 package protocol
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"io"
 	"time"
@@ -47,6 +49,64 @@ func MultiRecordReader(batches ...RecordReader) RecordReader {
 	}
 }
 
+// ChanRecordReader constructs a reader exposing the records received from ch,
+// which lets programs stream records into produce requests without buffering
+// them first. The reader returns io.EOF when ch is closed, or the error of ctx
+// if it gets canceled before the channel was closed.
+func ChanRecordReader(ctx context.Context, ch <-chan Record) RecordReader {
+	return &chanRecordReader{ctx: ctx, records: ch}
+}
+
+// TransformRecordReader constructs a reader which applies f to the records read
+// from r. The function may modify the record it receives and return it, or
+// return a different record, which must remain valid until the next call to
+// ReadRecord. Errors returned by f are returned by ReadRecord.
+//
+// Because f is applied again to the records read after the reader is reset,
+// it should not modify records in place when r may expose them again, which is
+// the case of readers created by NewRecordReader.
+//
+// The reader supports ResetRecordReader if r does.
+func TransformRecordReader(r RecordReader, f func(*Record) (*Record, error)) RecordReader {
+	return &transformRecordReader{reader: r, transform: f}
+}
+
+// FilterRecordReader constructs a reader exposing the records read from r for
+// which f returns true. The records that f returns false for are discarded, and
+// their keys and values closed.
+//
+// The reader supports ResetRecordReader if r does.
+func FilterRecordReader(r RecordReader, f func(*Record) bool) RecordReader {
+	return &filterRecordReader{reader: r, filter: f}
+}
+
+// ResetRecordReader rewinds r to the beginning of its sequence of records, so
+// it can be read again, for example to retry sending it to kafka.
+//
+// Record readers support being reset by implementing a Reset method. The
+// function returns ErrNoReset if r does not support it.
+func ResetRecordReader(r RecordReader) error {
+	if rr, ok := r.(interface{ Reset() error }); ok {
+		return rr.Reset()
+	}
+	return ErrNoReset
+}
+
+// NewRecordBatchReader constructs a reader exposing the records of the record
+// batches read from r.
+//
+// The stream must be a sequence of message sets (v0 or v1) or record batches
+// (v2) which are not prefixed by their total size, which is the format used by
+// kafka to store records in log segments. Batches are decoded one at a time as
+// the program reads the records, so the reader can be used on arbitrarily
+// large streams. Records of control batches are skipped.
+//
+// The reader returns io.EOF when it reaches the end of r, or an error wrapping
+// io.ErrUnexpectedEOF if r ends in the middle of a batch.
+func NewRecordBatchReader(r io.Reader) RecordReader {
+	return &recordBatchReader{reader: r}
+}
+
 func forEachRecord(r RecordReader, f func(int, *Record) error) error {
 	for i := 0; ; i++ {
 		rec, err := r.ReadRecord()
@@ -87,6 +147,31 @@ func (r *recordReader) ReadRecord() (*Record, error) {
 	return nil, io.EOF
 }
 
+func (r *recordReader) Reset() error {
+	for i := range r.records {
+		if err := resetBytes(r.records[i].Key); err != nil {
+			return err
+		}
+		if err := resetBytes(r.records[i].Value); err != nil {
+			return err
+		}
+	}
+	r.index = 0
+	return nil
+}
+
+func resetBytes(b Bytes) error {
+	if b == nil {
+		return nil
+	}
+	s, ok := b.(io.Seeker)
+	if !ok {
+		return ErrNoReset
+	}
+	_, err := s.Seek(0, io.SeekStart)
+	return err
+}
+
 type multiRecordReader struct {
 	batches []RecordReader
 	index   int
@@ -108,6 +193,75 @@ func (m *multiRecordReader) ReadRecord() (*Record, error) {
 	}
 }
 
+func (m *multiRecordReader) Reset() error {
+	for _, r := range m.batches {
+		if err := ResetRecordReader(r); err != nil {
+			return err
+		}
+	}
+	m.index = 0
+	return nil
+}
+
+type chanRecordReader struct {
+	ctx     context.Context
+	records <-chan Record
+	record  Record
+}
+
+func (r *chanRecordReader) ReadRecord() (*Record, error) {
+	select {
+	case rec, ok := <-r.records:
+		if !ok {
+			return nil, io.EOF
+		}
+		r.record = rec
+		return &r.record, nil
+	case <-r.ctx.Done():
+		return nil, r.ctx.Err()
+	}
+}
+
+type transformRecordReader struct {
+	reader    RecordReader
+	transform func(*Record) (*Record, error)
+}
+
+func (t *transformRecordReader) ReadRecord() (*Record, error) {
+	r, err := t.reader.ReadRecord()
+	if err != nil {
+		return nil, err
+	}
+	return t.transform(r)
+}
+
+func (t *transformRecordReader) Reset() error { return ResetRecordReader(t.reader) }
+
+type filterRecordReader struct {
+	reader RecordReader
+	filter func(*Record) bool
+}
+
+func (f *filterRecordReader) ReadRecord() (*Record, error) {
+	for {
+		r, err := f.reader.ReadRecord()
+		if err != nil {
+			return nil, err
+		}
+		if f.filter(r) {
+			return r, nil
+		}
+		if r.Key != nil {
+			r.Key.Close()
+		}
+		if r.Value != nil {
+			r.Value.Close()
+		}
+	}
+}
+
+func (f *filterRecordReader) Reset() error { return ResetRecordReader(f.reader) }
+
 // optimizedRecordReader is an implementation of a RecordReader which exposes a
 // sequence.
 type optimizedRecordReader struct {
@@ -165,6 +319,8 @@ type emptyRecordReader struct{}
 
 func (emptyRecordReader) ReadRecord() (*Record, error) { return nil, io.EOF }
 
+func (emptyRecordReader) Reset() error { return nil }
+
 // ControlRecord represents a record read from a control batch.
 type ControlRecord struct {
 	Offset  int64
@@ -211,6 +367,13 @@ func ReadControlRecord(r *Record) (*ControlRecord, error) {
 	return c, nil
 }
 
+// Transaction marker types of control records, found in the control batches
+// written when transactions are committed or aborted.
+const (
+	ControlRecordAbort  int16 = 0
+	ControlRecordCommit int16 = 1
+)
+
 func (cr *ControlRecord) Key() Bytes {
 	k := make([]byte, 4)
 	writeInt16(k[:2], cr.Version)
@@ -244,6 +407,46 @@ type ControlBatch struct {
 	Records              RecordReader
 }
 
+// CorruptBatch is an implementation of the RecordReader interface representing
+// record batches which failed checksum verification.
+//
+// ReadRecord first returns Err, then the records of the batch in CRCWarn mode
+// (Records is nil in CRCStrict mode, and the batch appears empty).
+type CorruptBatch struct {
+	Err     *CorruptRecordError
+	Records RecordReader
+
+	reported bool
+}
+
+func (c *CorruptBatch) ReadRecord() (*Record, error) {
+	if !c.reported {
+		c.reported = true
+		return nil, c.Err
+	}
+	if c.Records == nil {
+		return nil, io.EOF
+	}
+	if _, isControl := c.Records.(*ControlBatch); isControl {
+		return nil, io.EOF
+	}
+	return c.Records.ReadRecord()
+}
+
+// SetCorruptRecordPartition sets the topic and partition on the errors of the corrupted
+// batches in r, which are unknown when decoding responses. This is intended to
+// be used by programs which have the context of where r was read from.
+func SetCorruptRecordPartition(r RecordReader, topic string, partition int) {
+	switch x := r.(type) {
+	case *CorruptBatch:
+		x.Err.Topic, x.Err.Partition = topic, partition
+	case *RecordStream:
+		for _, b := range x.Records {
+			SetCorruptRecordPartition(b, topic, partition)
+		}
+	}
+}
+
 // NewControlBatch constructs a control batch from the list of records passed as
 // arguments.
 func NewControlBatch(records ...ControlRecord) *ControlBatch {
@@ -331,7 +534,14 @@ func (m *MessageSet) Version() int {
 // are not from control batches.
 type RecordStream struct {
 	Records []RecordReader
-	index   int
+
+	// An optional function called with the records of control batches, which
+	// are otherwise skipped. The function is called in offset order with the
+	// data records, before ReadRecord returns the record following the control
+	// batch. Errors returned by the function are returned by ReadRecord.
+	OnControlRecord func(*ControlBatch, *ControlRecord) error
+
+	index int
 }
 
 func (s *RecordStream) ReadRecord() (*Record, error) {
@@ -340,7 +550,12 @@ func (s *RecordStream) ReadRecord() (*Record, error) {
 			return nil, io.EOF
 		}
 
-		if _, isControl := s.Records[s.index].(*ControlBatch); isControl {
+		if c, isControl := s.Records[s.index].(*ControlBatch); isControl {
+			if s.OnControlRecord != nil {
+				if err := s.readControlRecords(c); err != nil {
+					return nil, err
+				}
+			}
 			s.index++
 			continue
 		}
@@ -356,3 +571,83 @@ func (s *RecordStream) ReadRecord() (*Record, error) {
 		return r, err
 	}
 }
+
+func (s *RecordStream) readControlRecords(c *ControlBatch) error {
+	for {
+		r, err := c.ReadControlRecord()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			return err
+		}
+		if err := s.OnControlRecord(c, r); err != nil {
+			return err
+		}
+	}
+}
+
+// recordBatchReader is the implementation of RecordReader returned by
+// NewRecordBatchReader.
+type recordBatchReader struct {
+	reader io.Reader
+	buffer bytes.Buffer
+	batch  RecordReader
+	err    error
+}
+
+func (r *recordBatchReader) ReadRecord() (*Record, error) {
+	for {
+		if r.batch != nil {
+			rec, err := r.batch.ReadRecord()
+			if err == nil || !errors.Is(err, io.EOF) {
+				return rec, err
+			}
+			r.batch = nil
+		}
+
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		r.batch, r.err = r.readBatch()
+	}
+}
+
+func (r *recordBatchReader) readBatch() (RecordReader, error) {
+	// All versions of message sets and record batches start with the base
+	// offset and the length of the batch, which is all we need to know how
+	// many bytes to read for the next batch.
+	//
+	// The batch is fully buffered before being decoded, so truncated streams
+	// are reported as such instead of surfacing as checksum mismatches. The
+	// records do not retain references to the buffer, it is reused across
+	// batches.
+	const headerSize = 8 + 4
+	r.buffer.Reset()
+
+	if _, err := io.CopyN(&r.buffer, r.reader, headerSize); err != nil {
+		if errors.Is(err, io.EOF) && r.buffer.Len() != 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	size := headerSize + int(readInt32(r.buffer.Bytes()[8:]))
+
+	if _, err := io.CopyN(&r.buffer, r.reader, int64(size-headerSize)); err != nil {
+		return nil, dontExpectEOF(err)
+	}
+
+	var rs RecordSet
+	d := &decoder{reader: &r.buffer, remain: size}
+
+	if _, err := rs.readRecords(d, size); err != nil {
+		return nil, err
+	}
+
+	if rs.Records == nil {
+		return emptyRecordReader{}, nil
+	}
+	return rs.Records, nil
+}
@@ -1,6 +1,8 @@
 package protocol
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"io"
 	"reflect"
@@ -138,6 +140,44 @@ func TestControlRecord(t *testing.T) {
 	}
 }
 
+func TestRecordStreamControlRecords(t *testing.T) {
+	now := time.Now()
+
+	stream := &RecordStream{
+		Records: []RecordReader{
+			NewRecordReader(Record{Offset: 0, Time: now}),
+			NewControlBatch(ControlRecord{Offset: 1, Time: now, Type: ControlRecordCommit}),
+			NewRecordReader(Record{Offset: 2, Time: now}),
+		},
+	}
+
+	offsets := []int64{}
+	stream.OnControlRecord = func(batch *ControlBatch, r *ControlRecord) error {
+		if r.Type != ControlRecordCommit {
+			t.Errorf("wrong control record type: %d", r.Type)
+		}
+		offsets = append(offsets, -r.Offset)
+		return nil
+	}
+
+	for {
+		r, err := stream.ReadRecord()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatal(err)
+			}
+			break
+		}
+		offsets = append(offsets, r.Offset)
+	}
+
+	// Control records are recorded with negative offsets to distinguish them
+	// from data records.
+	if !reflect.DeepEqual(offsets, []int64{0, -1, 2}) {
+		t.Errorf("wrong sequence of records: %v", offsets)
+	}
+}
+
 func assertRecords(t *testing.T, r1, r2 RecordReader) {
 	t.Helper()
 
@@ -198,3 +238,264 @@ func readAll(bytes Bytes) []byte {
 	}
 	return b
 }
+
+func TestRecordBatchReader(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+
+	records := []memoryRecord{
+		{
+			offset: 0,
+			time:   now,
+			key:    []byte("key-0"),
+			value:  []byte("value-0"),
+		},
+		{
+			offset: 1,
+			time:   now.Add(time.Millisecond),
+			value:  []byte("value-1"),
+		},
+		{
+			offset: 2,
+			time:   now.Add(time.Second),
+			key:    []byte("key-2"),
+			value:  []byte("value-2"),
+			headers: []Header{
+				{Key: "answer", Value: []byte("42")},
+			},
+		},
+	}
+
+	// A stream of batches in the different formats, as they would be found in
+	// a log segment.
+	stream := new(bytes.Buffer)
+
+	for i, rs := range []RecordSet{
+		{Version: 1, Records: NewRecordReader(makeRecords(records[:1])...)},
+		{Version: 2, Attributes: Gzip, Records: NewRecordReader(makeRecords(records[1:2])...)},
+		{Version: 2, Records: NewRecordReader(makeRecords(records[2:])...)},
+	} {
+		b := new(bytes.Buffer)
+		if _, err := rs.WriteTo(b); err != nil {
+			t.Fatal(err)
+		}
+		// Strip the size prefix, and set the base offset of the batch which
+		// is not covered by the checksums.
+		batch := b.Bytes()[4:]
+		writeInt64(batch[:8], records[i].offset)
+		stream.Write(batch)
+	}
+
+	data := stream.Bytes()
+
+	t.Run("read all records", func(t *testing.T) {
+		r1 := NewRecordReader(makeRecords(records)...)
+		r2 := NewRecordBatchReader(bytes.NewReader(data))
+		assertRecords(t, r1, r2)
+	})
+
+	t.Run("truncated stream", func(t *testing.T) {
+		r := NewRecordBatchReader(bytes.NewReader(data[:len(data)-1]))
+
+		for i := 0; i < 2; i++ {
+			if _, err := r.ReadRecord(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, err := r.ReadRecord(); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("expected io.ErrUnexpectedEOF but got %v", err)
+		}
+	})
+}
+
+func TestWriteRecordBatch(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+
+	records := []memoryRecord{
+		{
+			offset: 10,
+			time:   now,
+			key:    []byte("key-10"),
+			value:  []byte("value-10"),
+		},
+		{
+			offset: 11,
+			time:   now.Add(time.Millisecond),
+			value:  []byte("value-11"),
+			headers: []Header{
+				{Key: "answer", Value: []byte("42")},
+			},
+		},
+	}
+
+	for _, attrs := range []Attributes{0, Gzip, Snappy, Lz4, Zstd} {
+		t.Run(attrs.String(), func(t *testing.T) {
+			b := new(bytes.Buffer)
+
+			if err := WriteRecordBatch(b, attrs, NewRecordReader(makeRecords(records)...)); err != nil {
+				t.Fatal(err)
+			}
+
+			if baseOffset := readInt64(b.Bytes()[:8]); baseOffset != 10 {
+				t.Errorf("wrong base offset: %d", baseOffset)
+			}
+
+			r1 := NewRecordReader(makeRecords(records)...)
+			r2 := NewRecordBatchReader(b)
+			assertRecords(t, r1, r2)
+		})
+	}
+
+	if err := WriteRecordBatch(io.Discard, 0, NewRecordReader()); !errors.Is(err, ErrNoRecord) {
+		t.Errorf("expected ErrNoRecord but got %v", err)
+	}
+}
+
+func TestWriteMessageSetUnsupportedFeatures(t *testing.T) {
+	tests := []struct {
+		scenario string
+		attrs    Attributes
+		headers  []Header
+		feature  string
+	}{
+		{
+			scenario: "zstd compression",
+			attrs:    Zstd,
+			feature:  "zstd compression",
+		},
+		{
+			scenario: "record headers",
+			headers:  []Header{{Key: "answer", Value: []byte("42")}},
+			feature:  "record headers",
+		},
+		{
+			scenario: "compressed record headers",
+			attrs:    Gzip,
+			headers:  []Header{{Key: "answer", Value: []byte("42")}},
+			feature:  "record headers",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			rs := &RecordSet{
+				Version:    1,
+				Attributes: test.attrs,
+				Records: NewRecordReader(makeRecords([]memoryRecord{
+					{value: []byte("value"), headers: test.headers},
+				})...),
+			}
+
+			_, err := rs.WriteTo(io.Discard)
+
+			var unsupported *UnsupportedFeatureError
+			if !errors.As(err, &unsupported) {
+				t.Fatalf("expected UnsupportedFeatureError but got %v", err)
+			}
+			if unsupported.Feature != test.feature || unsupported.Version != 1 {
+				t.Errorf("wrong error: %v", unsupported)
+			}
+		})
+	}
+}
+
+func TestTransformAndFilterRecordReader(t *testing.T) {
+	now := time.Now()
+
+	records := []memoryRecord{
+		{
+			offset: 1,
+			time:   now,
+			key:    []byte("key-1"),
+			value:  []byte("value-1"),
+		},
+		{
+			offset: 2,
+			time:   now.Add(time.Millisecond),
+			key:    []byte("key-2"),
+			value:  []byte("value-2"),
+		},
+		{
+			offset: 3,
+			time:   now.Add(time.Second),
+			key:    []byte("key-3"),
+			value:  []byte("value-3"),
+		},
+	}
+
+	suffix := func(r *Record) (*Record, error) {
+		b, err := ReadAll(r.Value)
+		if err != nil {
+			return nil, err
+		}
+		t := *r
+		t.Value = NewBytes(append(b, "-suffix"...))
+		return &t, nil
+	}
+
+	odd := func(r *Record) bool { return r.Offset%2 != 0 }
+
+	r := FilterRecordReader(
+		TransformRecordReader(
+			MultiRecordReader(
+				NewRecordReader(makeRecords(records[:1])...),
+				NewRecordReader(makeRecords(records[1:])...),
+			),
+			suffix,
+		),
+		odd,
+	)
+
+	expected := []memoryRecord{records[0], records[2]}
+	expected[0].value = []byte("value-1-suffix")
+	expected[1].value = []byte("value-3-suffix")
+
+	for i := 0; i < 2; i++ {
+		if i != 0 {
+			if err := ResetRecordReader(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		assertRecords(t, NewRecordReader(makeRecords(expected)...), r)
+	}
+
+	if err := ResetRecordReader(NewRecordBatchReader(new(bytes.Buffer))); !errors.Is(err, ErrNoReset) {
+		t.Errorf("expected ErrNoReset but got %v", err)
+	}
+}
+
+func TestChanRecordReader(t *testing.T) {
+	now := time.Now()
+
+	records := []memoryRecord{
+		{
+			offset: 1,
+			time:   now,
+			key:    []byte("key-1"),
+		},
+		{
+			offset: 2,
+			time:   now.Add(time.Millisecond),
+			value:  []byte("value-2"),
+		},
+	}
+
+	ch := make(chan Record)
+	go func() {
+		defer close(ch)
+		for _, r := range makeRecords(records) {
+			ch <- r
+		}
+	}()
+
+	r1 := NewRecordReader(makeRecords(records)...)
+	r2 := ChanRecordReader(context.Background(), ch)
+	assertRecords(t, r1, r2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ChanRecordReader(ctx, make(chan Record)).ReadRecord(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled but got %v", err)
+	}
+}
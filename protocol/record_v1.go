@@ -8,7 +8,10 @@ import (
 	"time"
 )
 
-func readMessage(b *pageBuffer, d *decoder) (attributes int8, baseOffset, timestamp int64, key, value Bytes, err error) {
+// readMessage reads a message from d into b, returning a LimitError if the
+// size of the message is greater than max, or a CorruptRecordError if its
+// checksum does not match.
+func readMessage(b *pageBuffer, d *decoder, limit string, max int) (attributes int8, baseOffset, timestamp int64, key, value Bytes, err error) {
 	md := decoder{
 		reader: d,
 		remain: 12,
@@ -17,8 +20,14 @@ func readMessage(b *pageBuffer, d *decoder) (attributes int8, baseOffset, timest
 	baseOffset = md.readInt64()
 	md.remain = int(md.readInt32())
 
+	if err = checkLimit(limit, md.remain, max); err != nil {
+		return
+	}
+
 	crc := uint32(md.readInt32())
-	md.setCRC(crc32.IEEETable)
+	if d.crc != CRCOff {
+		md.setCRC(crc32.IEEETable)
+	}
 	magicByte := md.readInt8()
 	attributes = md.readInt8()
 	timestamp = int64(0)
@@ -43,10 +52,15 @@ func readMessage(b *pageBuffer, d *decoder) (attributes int8, baseOffset, timest
 		value = b.ref(valueOffset, b.Size())
 	}
 
-	if md.crc32 != crc {
-		err = Errorf("crc32 checksum mismatch (computed=%d found=%d)", md.crc32, crc)
-	} else {
+	if md.err != nil {
 		err = dontExpectEOF(md.err)
+	} else if md.table != nil && md.crc32 != crc {
+		err = &CorruptRecordError{
+			BaseOffset: baseOffset,
+			LastOffset: baseOffset,
+			Computed:   md.crc32,
+			Found:      crc,
+		}
 	}
 
 	return
@@ -58,7 +72,12 @@ func (rs *RecordSet) readFromVersion1(d *decoder) error {
 	b := newPageBuffer()
 	defer b.unref()
 
-	attributes, baseOffset, timestamp, key, value, err := readMessage(b, d)
+	attributes, baseOffset, timestamp, key, value, err := readMessage(b, d, "MaxBatchSize", d.limits.MaxBatchSize)
+
+	var corrupt *CorruptRecordError
+	if errors.As(err, &corrupt) && d.crc == CRCWarn {
+		err = nil
+	}
 	if err != nil {
 		return err
 	}
@@ -95,6 +114,8 @@ func (rs *RecordSet) readFromVersion1(d *decoder) error {
 			d := &decoder{
 				reader: decompressor,
 				remain: math.MaxInt32,
+				limits: d.limits,
+				crc:    d.crc,
 			}
 
 			r := &recordReader{
@@ -102,7 +123,30 @@ func (rs *RecordSet) readFromVersion1(d *decoder) error {
 			}
 
 			for !d.done() {
-				_, offset, timestamp, key, value, err := readMessage(b, d)
+				_, offset, timestamp, key, value, err := readMessage(b, d, "MaxRecordSize", d.limits.MaxRecordSize)
+				// Corrupted inner messages are reported as a corruption of
+				// the wrapper message.
+				if c := (*CorruptRecordError)(nil); errors.As(err, &c) {
+					if corrupt == nil {
+						corrupt = &CorruptRecordError{
+							BaseOffset: baseOffset,
+							LastOffset: baseOffset,
+							Computed:   c.Computed,
+							Found:      c.Found,
+						}
+					}
+					if d.crc == CRCWarn {
+						err = nil
+					} else {
+						err = corrupt
+					}
+				}
+				if err == nil {
+					if err = checkLimit("MaxBatchSize", int(b.Size()), d.limits.MaxBatchSize); err != nil {
+						closeBytes(key)
+						closeBytes(value)
+					}
+				}
 				if err != nil {
 					if errors.Is(err, io.ErrUnexpectedEOF) {
 						break
@@ -140,6 +184,10 @@ func (rs *RecordSet) readFromVersion1(d *decoder) error {
 		}
 	}
 
+	if corrupt != nil {
+		records = &CorruptBatch{Err: corrupt, Records: records}
+	}
+
 	*rs = RecordSet{
 		Version:    1,
 		Attributes: Attributes(attributes),
@@ -153,6 +201,13 @@ func (rs *RecordSet) writeToVersion1(buffer *pageBuffer, bufferOffset int64) err
 	attributes := rs.Attributes
 	records := rs.Records
 
+	// Message sets have no representation for headers, and kafka brokers
+	// which only support message sets do not support zstd, report these
+	// cases instead of silently producing different records.
+	if attributes&7 == Zstd {
+		return &UnsupportedFeatureError{Feature: "zstd compression", Version: 1}
+	}
+
 	if compression := attributes.Compression(); compression != 0 {
 		if codec := compression.Codec(); codec != nil {
 			// In the message format version 1, compression is achieved by
@@ -172,7 +227,7 @@ func (rs *RecordSet) writeToVersion1(buffer *pageBuffer, bufferOffset int64) err
 			defer compressor.Close()
 
 			var err error
-			buffer.pages.scan(bufferOffset, buffer.Size(), func(b []byte) bool {
+			buffer.scan(bufferOffset, buffer.Size(), func(b []byte) bool {
 				_, err = compressor.Write(b)
 				return err == nil
 			})
@@ -197,6 +252,10 @@ func (rs *RecordSet) writeToVersion1(buffer *pageBuffer, bufferOffset int64) err
 	currentTimestamp := timestamp(time.Now())
 
 	return forEachRecord(records, func(i int, r *Record) error {
+		if len(r.Headers) != 0 {
+			return &UnsupportedFeatureError{Feature: "record headers", Version: 1}
+		}
+
 		t := timestamp(r.Time)
 		if t == 0 {
 			t = currentTimestamp
@@ -16,16 +16,24 @@ func (rs *RecordSet) readFromVersion2(d *decoder) error {
 		return nil
 	}
 
+	if err := checkLimit("MaxBatchSize", int(batchLength), d.limits.MaxBatchSize); err != nil {
+		return err
+	}
+
 	dec := &decoder{
 		reader: d,
 		remain: int(batchLength),
+		limits: d.limits,
+		crc:    d.crc,
 	}
 
 	partitionLeaderEpoch := dec.readInt32()
 	magicByte := dec.readInt8()
 	crc := dec.readInt32()
 
-	dec.setCRC(crc32.MakeTable(crc32.Castagnoli))
+	if dec.crc != CRCOff {
+		dec.setCRC(castagnoliTable)
+	}
 
 	attributes := dec.readInt16()
 	lastOffsetDelta := dec.readInt32()
@@ -38,7 +46,6 @@ func (rs *RecordSet) readFromVersion2(d *decoder) error {
 	reader := io.Reader(dec)
 
 	// unused
-	_ = lastOffsetDelta
 	_ = maxTimestamp
 
 	if compression := Attributes(attributes).Compression(); compression != 0 {
@@ -49,22 +56,57 @@ func (rs *RecordSet) readFromVersion2(d *decoder) error {
 		decompressor := codec.NewReader(reader)
 		defer decompressor.Close()
 		reader = decompressor
+
+		if maxBatchSize := dec.limits.MaxBatchSize; maxBatchSize > 0 {
+			// Bound the amount of data read from the decompressor, the batch
+			// is rejected below if it decompresses to more than the limit.
+			reader = io.LimitReader(reader, int64(maxBatchSize)+1)
+		}
 	}
 
 	buffer := newPageBuffer()
 	defer buffer.unref()
 
 	_, err := buffer.ReadFrom(reader)
+	if err == nil {
+		if err := checkLimit("MaxBatchSize", buffer.Len(), dec.limits.MaxBatchSize); err != nil {
+			return err
+		}
+	} else if dec.table != nil {
+		// Decompression errors are likely caused by corrupted data, read the
+		// rest of the batch to determine whether the checksum matches.
+		dec.discardAll()
+	}
+
+	var corrupt *CorruptRecordError
+	if dec.table != nil && dec.crc32 != uint32(crc) {
+		corrupt = &CorruptRecordError{
+			BaseOffset: baseOffset,
+			LastOffset: baseOffset + int64(lastOffsetDelta),
+			Computed:   dec.crc32,
+			Found:      uint32(crc),
+		}
+		if err != nil || dec.crc == CRCStrict {
+			return corrupt
+		}
+	}
 	if err != nil {
 		return err
 	}
-	if dec.crc32 != uint32(crc) {
-		return fmt.Errorf("crc32 checksum mismatch (computed=%d found=%d)", dec.crc32, uint32(crc))
-	}
 
 	recordsLength := buffer.Len()
 	dec.reader = buffer
 	dec.remain = recordsLength
+	// The checksum covered the records which were read into the buffer,
+	// decoding them must not compute it again.
+	dec.table = nil
+
+	// Each record takes at least one byte, a greater count can only come from
+	// a corrupted batch, and would cause the allocation of an arbitrarily large
+	// array of records.
+	if numRecords < 0 || int(numRecords) > recordsLength {
+		return fmt.Errorf("invalid number of records in batch of %d bytes: %d", recordsLength, numRecords)
+	}
 
 	records := make([]optimizedRecord, numRecords)
 	// These are two lazy allocators that will be used to optimize allocation of
@@ -94,8 +136,13 @@ func (rs *RecordSet) readFromVersion2(d *decoder) error {
 
 	for i := range records {
 		r := &records[i]
-		_ = dec.readVarInt() // record length (unused)
-		_ = dec.readInt8()   // record attributes (unused)
+		recordLength := dec.readVarInt()
+		if !dec.checkLimit("MaxRecordSize", int(recordLength), dec.limits.MaxRecordSize) {
+			records = records[:i]
+			break
+		}
+
+		_ = dec.readInt8() // record attributes (unused)
 		timestampDelta := dec.readVarInt()
 		offsetDelta := dec.readVarInt()
 
@@ -114,7 +161,12 @@ func (rs *RecordSet) readFromVersion2(d *decoder) error {
 			dec.discard(int(valueLength))
 		}
 
-		if numHeaders := dec.readVarInt(); numHeaders > 0 {
+		numHeaders := dec.readVarInt()
+		if numHeaders > int64(dec.remain) {
+			dec.setError(fmt.Errorf("invalid number of record headers: %d", numHeaders))
+		}
+
+		if numHeaders > 0 && dec.err == nil && dec.checkLimit("MaxHeaderCount", int(numHeaders), dec.limits.MaxHeaderCount) {
 			if headers == nil {
 				headers = make([][]Header, numRecords)
 			}
@@ -185,15 +237,29 @@ func (rs *RecordSet) readFromVersion2(d *decoder) error {
 		}
 	}
 
+	if corrupt != nil {
+		rs.Records = &CorruptBatch{Err: corrupt, Records: rs.Records}
+	}
+
 	return nil
 }
 
 func (rs *RecordSet) writeToVersion2(buffer *pageBuffer, bufferOffset int64) error {
 	records := rs.Records
+
+	if b, ok := records.(*EncodedRecordBatch); ok && b.attributes() == rs.Attributes {
+		b.writeTo(buffer, bufferOffset, rs.Producer)
+		return nil
+	}
 	numRecords := int32(0)
 
+	producerID, producerEpoch, baseSequence := int64(-1), int16(-1), int32(-1)
+	if p := rs.Producer; p != nil {
+		producerID, producerEpoch, baseSequence = p.ID, p.Epoch, p.BaseSequence
+	}
+
 	e := &encoder{writer: buffer}
-	e.writeInt64(0)                    // base offset                         |  0 +8
+	e.writeInt64(0)                    // placeholder for base offset         |  0 +8
 	e.writeInt32(0)                    // placeholder for record batch length |  8 +4
 	e.writeInt32(-1)                   // partition leader epoch              | 12 +3
 	e.writeInt8(2)                     // magic byte                          | 16 +1
@@ -202,9 +268,9 @@ func (rs *RecordSet) writeToVersion2(buffer *pageBuffer, bufferOffset int64) err
 	e.writeInt32(0)                    // placeholder for lastOffsetDelta     | 23 +4
 	e.writeInt64(0)                    // placeholder for firstTimestamp      | 27 +8
 	e.writeInt64(0)                    // placeholder for maxTimestamp        | 35 +8
-	e.writeInt64(-1)                   // producer id                         | 43 +8
-	e.writeInt16(-1)                   // producer epoch                      | 51 +2
-	e.writeInt32(-1)                   // base sequence                       | 53 +4
+	e.writeInt64(producerID)           // producer id                         | 43 +8
+	e.writeInt16(producerEpoch)        // producer epoch                      | 51 +2
+	e.writeInt32(baseSequence)         // base sequence                       | 53 +4
 	e.writeInt32(0)                    // placeholder for numRecords          | 57 +4
 
 	var compressor io.WriteCloser
@@ -216,6 +282,7 @@ func (rs *RecordSet) writeToVersion2(buffer *pageBuffer, bufferOffset int64) err
 	}
 
 	currentTimestamp := timestamp(time.Now())
+	baseOffset := int64(0)
 	lastOffsetDelta := int32(0)
 	firstTimestamp := int64(0)
 	maxTimestamp := int64(0)
@@ -226,6 +293,7 @@ func (rs *RecordSet) writeToVersion2(buffer *pageBuffer, bufferOffset int64) err
 			t = currentTimestamp
 		}
 		if i == 0 {
+			baseOffset = r.Offset
 			firstTimestamp = t
 		}
 		if t > maxTimestamp {
@@ -247,10 +315,14 @@ func (rs *RecordSet) writeToVersion2(buffer *pageBuffer, bufferOffset int64) err
 			length += sizeOfVarString(h.Key) + sizeOfVarNullBytes(h.Value)
 		}
 
-		e.writeVarInt(int64(length))
-		e.writeInt8(0) // record attributes (unused)
-		e.writeVarInt(timestampDelta)
-		e.writeVarInt(offsetDelta)
+		// The fixed fields of the record take at most 31 bytes, they are
+		// encoded in a single write.
+		b := e.buffer[:0]
+		b = appendVarInt(b, int64(length))
+		b = append(b, 0) // record attributes (unused)
+		b = appendVarInt(b, timestampDelta)
+		b = appendVarInt(b, offsetDelta)
+		e.Write(b)
 
 		if err := e.writeVarNullBytesFrom(r.Key); err != nil {
 			return err
@@ -285,11 +357,13 @@ func (rs *RecordSet) writeToVersion2(buffer *pageBuffer, bufferOffset int64) err
 		return ErrNoRecord
 	}
 
+	bo := packUint64(uint64(baseOffset))
 	b2 := packUint32(uint32(lastOffsetDelta))
 	b3 := packUint64(uint64(firstTimestamp))
 	b4 := packUint64(uint64(maxTimestamp))
 	b5 := packUint32(uint32(numRecords))
 
+	buffer.WriteAt(bo[:], bufferOffset+0)
 	buffer.WriteAt(b2[:], bufferOffset+23)
 	buffer.WriteAt(b3[:], bufferOffset+27)
 	buffer.WriteAt(b4[:], bufferOffset+35)
@@ -298,11 +372,11 @@ func (rs *RecordSet) writeToVersion2(buffer *pageBuffer, bufferOffset int64) err
 	totalLength := buffer.Size() - bufferOffset
 	batchLength := totalLength - 12
 
+	// The checksum is computed over the chunks of the buffer, which avoids
+	// copying the batch into contiguous memory.
 	checksum := uint32(0)
-	crcTable := crc32.MakeTable(crc32.Castagnoli)
-
-	buffer.pages.scan(bufferOffset+21, bufferOffset+totalLength, func(chunk []byte) bool {
-		checksum = crc32.Update(checksum, crcTable, chunk)
+	buffer.scan(bufferOffset+21, bufferOffset+totalLength, func(chunk []byte) bool {
+		checksum = crc32.Update(checksum, castagnoliTable, chunk)
 		return true
 	})
 
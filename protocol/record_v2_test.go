@@ -0,0 +1,162 @@
+package protocol
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func makeBenchmarkRecords(count, size int) []memoryRecord {
+	now := time.Now().Truncate(time.Millisecond)
+	value := bytes.Repeat([]byte("x"), size)
+	records := make([]memoryRecord, count)
+	for i := range records {
+		records[i] = memoryRecord{
+			offset: int64(i),
+			time:   now.Add(time.Duration(i) * time.Millisecond),
+			key:    []byte(fmt.Sprintf("key-%d", i)),
+			value:  value,
+			headers: []Header{
+				{Key: "trace-id", Value: []byte("0123456789abcdef")},
+			},
+		}
+	}
+	return records
+}
+
+func TestRecordBatchLargeRoundTrip(t *testing.T) {
+	// Batches spanning multiple pages exercise the decoding of varints which
+	// straddle page boundaries.
+	records := makeBenchmarkRecords(5000, 97)
+
+	b := new(bytes.Buffer)
+	if err := WriteRecordBatch(b, 0, NewRecordReader(makeRecords(records)...)); err != nil {
+		t.Fatal(err)
+	}
+
+	rs := &RecordSet{}
+	d := &decoder{reader: b, remain: b.Len()}
+	if _, err := rs.readRecords(d, b.Len()); err != nil {
+		t.Fatal(err)
+	}
+
+	assertRecords(t, NewRecordReader(makeRecords(records)...), rs.Records)
+}
+
+func TestEncodedRecordBatch(t *testing.T) {
+	records := makeBenchmarkRecords(10, 100)
+	for i := range records {
+		// Headers cannot be represented in version 1.
+		records[i].headers = nil
+	}
+
+	encoded := new(bytes.Buffer)
+	if err := WriteRecordBatch(encoded, Gzip, NewRecordReader(makeRecords(records)...)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		scenario string
+		set      RecordSet
+	}{
+		{
+			scenario: "the batch is written as is",
+			set:      RecordSet{Version: 2, Attributes: Gzip},
+		},
+		{
+			scenario: "the producer state is written in the batch",
+			set:      RecordSet{Version: 2, Attributes: Gzip, Producer: &ProducerState{ID: 42, Epoch: 1, BaseSequence: 10}},
+		},
+		{
+			scenario: "batches with other attributes are encoded again",
+			set:      RecordSet{Version: 2, Attributes: Snappy},
+		},
+		{
+			scenario: "batches are decoded to be written in other versions",
+			set:      RecordSet{Version: 1, Attributes: Gzip},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			rs := test.set
+			rs.Records = &EncodedRecordBatch{Data: encoded.Bytes()}
+
+			b := new(bytes.Buffer)
+			if _, err := rs.WriteTo(b); err != nil {
+				t.Fatal(err)
+			}
+			b.Next(4) // size of the record set
+
+			found := &RecordSet{}
+			d := &decoder{reader: b, remain: b.Len()}
+			if _, err := found.readRecords(d, b.Len()); err != nil {
+				t.Fatal(err)
+			}
+
+			if found.Version != rs.Version || found.Attributes.Compression() != rs.Attributes.Compression() {
+				t.Errorf("wrong record set: version=%d attributes=%d", found.Version, found.Attributes)
+			}
+			if p := rs.Producer; p != nil {
+				stream, _ := found.Records.(*RecordStream)
+				if stream == nil || len(stream.Records) != 1 {
+					t.Fatalf("wrong records: %#v", found.Records)
+				}
+				batch, ok := stream.Records[0].(*RecordBatch)
+				if !ok {
+					t.Fatalf("wrong type of records: %T", found.Records)
+				}
+				if batch.ProducerID != p.ID || batch.ProducerEpoch != p.Epoch || batch.BaseSequence != p.BaseSequence {
+					t.Errorf("wrong producer state: %d/%d/%d", batch.ProducerID, batch.ProducerEpoch, batch.BaseSequence)
+				}
+			}
+
+			assertRecords(t, found.Records, NewRecordReader(makeRecords(records)...))
+		})
+	}
+}
+
+func BenchmarkRecordBatch(b *testing.B) {
+	for _, size := range []int{10, 100, 1000} {
+		records := makeBenchmarkRecords(1000, size)
+
+		batch := new(bytes.Buffer)
+		if err := WriteRecordBatch(batch, 0, NewRecordReader(makeRecords(records)...)); err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(fmt.Sprintf("encode/size=%d", size), func(b *testing.B) {
+			b.SetBytes(int64(batch.Len()))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := WriteRecordBatch(ioutil.Discard, 0, NewRecordReader(makeRecords(records)...)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("decode/size=%d", size), func(b *testing.B) {
+			b.SetBytes(int64(batch.Len()))
+			b.ReportAllocs()
+			r := bytes.NewReader(nil)
+			for i := 0; i < b.N; i++ {
+				r.Reset(batch.Bytes())
+				rs := &RecordSet{}
+				d := &decoder{reader: r, remain: batch.Len()}
+				if _, err := rs.readRecords(d, batch.Len()); err != nil {
+					b.Fatal(err)
+				}
+				for {
+					rec, err := rs.Records.ReadRecord()
+					if err != nil {
+						break
+					}
+					rec.Key.Close()
+					rec.Value.Close()
+				}
+			}
+		})
+	}
+}
@@ -45,6 +45,8 @@ func (v value) int32() int32 { return int32(v.int64()) }
 
 func (v value) int64() int64 { return v.val.Int() }
 
+func (v value) float64() float64 { return v.val.Float() }
+
 func (v value) string() string { return v.val.String() }
 
 func (v value) bytes() []byte { return v.val.Bytes() }
@@ -63,6 +65,8 @@ func (v value) setInt32(i int32) { v.setInt64(int64(i)) }
 
 func (v value) setInt64(i int64) { v.val.SetInt(i) }
 
+func (v value) setFloat64(f float64) { v.val.SetFloat(f) }
+
 func (v value) setString(s string) { v.val.SetString(s) }
 
 func (v value) setBytes(b []byte) { v.val.SetBytes(b) }
@@ -79,6 +83,16 @@ func (v value) fieldByIndex(i index) value {
 	return value{val: v.val.FieldByIndex(i)}
 }
 
+func (v value) isNil() bool { return v.val.IsNil() }
+
+func (v value) elem() value { return value{val: v.val.Elem()} }
+
+func (v value) setNew(t reflect.Type) value {
+	p := reflect.New(t)
+	v.val.Set(p)
+	return value{val: p.Elem()}
+}
+
 type array struct {
 	val reflect.Value
 }
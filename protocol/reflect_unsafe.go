@@ -63,6 +63,8 @@ func (v value) int32() int32 { return *(*int32)(v.ptr) }
 
 func (v value) int64() int64 { return *(*int64)(v.ptr) }
 
+func (v value) float64() float64 { return *(*float64)(v.ptr) }
+
 func (v value) string() string { return *(*string)(v.ptr) }
 
 func (v value) bytes() []byte { return *(*[]byte)(v.ptr) }
@@ -92,6 +94,8 @@ func (v value) setInt32(i int32) { *(*int32)(v.ptr) = i }
 
 func (v value) setInt64(i int64) { *(*int64)(v.ptr) = i }
 
+func (v value) setFloat64(f float64) { *(*float64)(v.ptr) = f }
+
 func (v value) setString(s string) { *(*string)(v.ptr) = s }
 
 func (v value) setBytes(b []byte) { *(*[]byte)(v.ptr) = b }
@@ -102,6 +106,16 @@ func (v value) fieldByIndex(i index) value {
 	return value{ptr: unsafe.Pointer(uintptr(v.ptr) + uintptr(i))}
 }
 
+func (v value) isNil() bool { return *(*unsafe.Pointer)(v.ptr) == nil }
+
+func (v value) elem() value { return value{ptr: *(*unsafe.Pointer)(v.ptr)} }
+
+func (v value) setNew(t reflect.Type) value {
+	p := unsafe.Pointer(reflect.New(t).Pointer())
+	*(*unsafe.Pointer)(v.ptr) = p
+	return value{ptr: p}
+}
+
 type array struct {
 	elem unsafe.Pointer
 	size uintptr
@@ -0,0 +1,27 @@
+package renewdelegationtoken_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/prototest"
+	"github.com/segmentio/kafka-go/protocol/renewdelegationtoken"
+)
+
+func TestRenewDelegationTokenRequest(t *testing.T) {
+	for _, version := range []int16{0, 1, 2} {
+		prototest.TestRequest(t, version, &renewdelegationtoken.Request{
+			HMAC:          []byte("hmac"),
+			RenewPeriodMs: 3600000,
+		})
+	}
+}
+
+func TestRenewDelegationTokenResponse(t *testing.T) {
+	for _, version := range []int16{0, 1, 2} {
+		prototest.TestResponse(t, version, &renewdelegationtoken.Response{
+			ErrorCode:         0,
+			ExpiryTimestampMs: 2000,
+			ThrottleTimeMs:    500,
+		})
+	}
+}
@@ -9,6 +9,10 @@ import (
 )
 
 func ReadResponse(r io.Reader, apiKey ApiKey, apiVersion int16) (correlationID int32, msg Message, err error) {
+	return readResponse(r, apiKey, apiVersion, Limits{}, CRCStrict)
+}
+
+func readResponse(r io.Reader, apiKey ApiKey, apiVersion int16, limits Limits, crc CRCMode) (correlationID int32, msg Message, err error) {
 	if i := int(apiKey); i < 0 || i >= len(apiTypes) {
 		err = fmt.Errorf("unsupported api key: %d", i)
 		return
@@ -37,6 +41,8 @@ func ReadResponse(r io.Reader, apiKey ApiKey, apiVersion int16) (correlationID i
 	}
 
 	d.remain = int(size)
+	d.limits = limits
+	d.crc = crc
 	correlationID = d.readInt32()
 	if err = d.err; err != nil {
 		if errors.Is(err, io.ErrUnexpectedEOF) {
@@ -60,7 +66,9 @@ func ReadResponse(r io.Reader, apiKey ApiKey, apiVersion int16) (correlationID i
 
 	res := &t.responses[apiVersion-minVersion]
 
-	if res.flexible {
+	// The ApiVersions responses always use the v0 response header, so clients
+	// can decode them before knowing which versions are supported.
+	if res.flexible && apiKey != ApiVersions {
 		// In the flexible case, there's a tag buffer at the end of the response header
 		taggedCount := int(d.readUnsignedVarInt())
 		for i := 0; i < taggedCount; i++ {
@@ -72,6 +80,32 @@ func ReadResponse(r io.Reader, apiKey ApiKey, apiVersion int16) (correlationID i
 		}
 	}
 
+	if bufferedResponse(apiKey) {
+		// Responses describing the cluster state are large and made of many
+		// small fields, they are read in a single pass into pooled pages so
+		// the connection is drained quickly, then decoded from memory.
+		b := newPageBuffer()
+		defer b.unref()
+
+		if _, err = b.ReadFrom(io.LimitReader(d, int64(d.remain))); err != nil {
+			err = dontExpectEOF(err)
+			return
+		}
+		if d.remain != 0 {
+			err = io.ErrUnexpectedEOF
+			return
+		}
+
+		d.reader = b
+		d.remain = b.Len()
+	}
+
+	if arenaResponse(apiKey) {
+		arena := stringArenaPool.Get().(*stringArena)
+		defer stringArenaPool.Put(arena)
+		d.arena = arena
+	}
+
 	msg = res.new()
 	res.decode(d, valueOf(msg))
 	d.discardAll()
@@ -83,6 +117,17 @@ func ReadResponse(r io.Reader, apiKey ApiKey, apiVersion int16) (correlationID i
 	return
 }
 
+func bufferedResponse(apiKey ApiKey) bool {
+	return apiKey == Metadata || apiKey == DescribeGroups
+}
+
+// Responses describing the cluster state are refreshed periodically and repeat
+// the same strings, which are allocated from arenas, or interned for the fields
+// tagged with the "intern" option.
+func arenaResponse(apiKey ApiKey) bool {
+	return bufferedResponse(apiKey) || apiKey == OffsetFetch
+}
+
 func WriteResponse(w io.Writer, apiVersion int16, correlationID int32, msg Message) error {
 	apiKey := msg.ApiKey()
 
@@ -107,10 +152,10 @@ func WriteResponse(w io.Writer, apiVersion int16, correlationID int32, msg Messa
 	b := newPageBuffer()
 	defer b.unref()
 
-	e := &encoder{writer: b}
+	e := &encoder{writer: b, emptyRecordSets: true}
 	e.writeInt32(0) // placeholder for the response size
 	e.writeInt32(correlationID)
-	if r.flexible {
+	if r.flexible && apiKey != ApiVersions {
 		// Flexible messages use extra space for a tag buffer,
 		// which begins with a size value. Since we're not writing any fields into the
 		// latter, we can just write zero for now.
@@ -6,13 +6,17 @@ import (
 
 // RoundTrip sends a request to a kafka broker and returns the response.
 func RoundTrip(rw io.ReadWriter, apiVersion int16, correlationID int32, clientID string, req Message) (Message, error) {
+	return roundTrip(rw, apiVersion, correlationID, clientID, req, Limits{}, CRCStrict)
+}
+
+func roundTrip(rw io.ReadWriter, apiVersion int16, correlationID int32, clientID string, req Message, limits Limits, crc CRCMode) (Message, error) {
 	if err := WriteRequest(rw, apiVersion, correlationID, clientID, req); err != nil {
 		return nil, err
 	}
 	if !hasResponse(req) {
 		return nil, nil
 	}
-	id, res, err := ReadResponse(rw, req.ApiKey(), apiVersion)
+	id, res, err := readResponse(rw, req.ApiKey(), apiVersion, limits, crc)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,35 @@
+package syncgroup
+
+import "github.com/segmentio/kafka-go/protocol"
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_SyncGroup
+type Request struct {
+	GroupID         string              `kafka:"min=v0,max=v3"`
+	GenerationID    int32               `kafka:"min=v0,max=v3"`
+	MemberID        string              `kafka:"min=v0,max=v3"`
+	GroupInstanceID string              `kafka:"min=v3,max=v3,nullable"`
+	Assignments     []RequestAssignment `kafka:"min=v0,max=v3"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey {
+	return protocol.SyncGroup
+}
+
+type RequestAssignment struct {
+	MemberID   string `kafka:"min=v0,max=v3"`
+	Assignment []byte `kafka:"min=v0,max=v3"`
+}
+
+type Response struct {
+	ThrottleTimeMs int32  `kafka:"min=v1,max=v3"`
+	ErrorCode      int16  `kafka:"min=v0,max=v3"`
+	Assignment     []byte `kafka:"min=v0,max=v3"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey {
+	return protocol.SyncGroup
+}
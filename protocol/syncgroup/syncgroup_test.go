@@ -0,0 +1,45 @@
+package syncgroup_test
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol/prototest"
+	"github.com/segmentio/kafka-go/protocol/syncgroup"
+)
+
+func TestSyncGroupRequest(t *testing.T) {
+	for _, version := range []int16{0, 1, 2} {
+		prototest.TestRequest(t, version, &syncgroup.Request{
+			GroupID:      "group-1",
+			GenerationID: 3,
+			MemberID:     "member-1",
+			Assignments: []syncgroup.RequestAssignment{
+				{MemberID: "member-1", Assignment: []byte{0, 1, 2}},
+			},
+		})
+	}
+
+	prototest.TestRequest(t, 3, &syncgroup.Request{
+		GroupID:         "group-1",
+		GenerationID:    3,
+		MemberID:        "member-1",
+		GroupInstanceID: "instance-1",
+		Assignments: []syncgroup.RequestAssignment{
+			{MemberID: "member-1", Assignment: []byte{0, 1, 2}},
+		},
+	})
+}
+
+func TestSyncGroupResponse(t *testing.T) {
+	prototest.TestResponse(t, 0, &syncgroup.Response{
+		ErrorCode:  27,
+		Assignment: []byte{0, 1, 2},
+	})
+
+	for _, version := range []int16{1, 2, 3} {
+		prototest.TestResponse(t, version, &syncgroup.Response{
+			ThrottleTimeMs: 10,
+			Assignment:     []byte{0, 1, 2},
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package protocol
+
+import (
+	"reflect"
+	"sort"
+)
+
+// TaggedField is a tagged field of a flexible message which is not known to
+// the program.
+type TaggedField struct {
+	Tag  int
+	Data []byte
+}
+
+// TaggedFields is the type of struct fields which hold the unknown tagged
+// fields of flexible messages.
+//
+// Unknown tagged fields are stored in the TaggedFields field of the struct
+// they were decoded from, and encoded back with it, so programs remain forward
+// compatible with brokers which add tagged fields to the messages, and proxies
+// do not strip them. The field is declared with the version range where the
+// struct is flexible, which makes it act as the marker of flexible versions:
+//
+//	TaggedFields protocol.TaggedFields `kafka:"min=v9,max=v12,tag"`
+//
+// Tagged fields known to the program take precedence over unknown fields with
+// the same tag when encoding.
+type TaggedFields []TaggedField
+
+var (
+	taggedFieldsType    = reflect.TypeOf(TaggedFields(nil))
+	taggedFieldsPtrType = reflect.PtrTo(taggedFieldsType)
+)
+
+// taggedFieldsIndexOf returns the index of the TaggedFields field in the
+// struct type typ, which is active at the given version.
+func taggedFieldsIndexOf(typ reflect.Type, version int16) (index, bool) {
+	var found index
+	var ok bool
+
+	forEachStructField(typ, func(typ reflect.Type, i index, tag string) {
+		if typ == taggedFieldsType {
+			forEachStructTag(tag, func(tag structTag) bool {
+				if tag.MinVersion <= version && version <= tag.MaxVersion {
+					found, ok = i, true
+					return false
+				}
+				return true
+			})
+		}
+	})
+
+	return found, ok
+}
+
+func (fields TaggedFields) sort() {
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Tag < fields[j].Tag })
+}
@@ -9,7 +9,7 @@ func init() {
 type Request struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v3,max=v3,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
 
 	TransactionalID string         `kafka:"min=v0,max=v2|min=v3,max=v3,compact"`
 	GroupID         string         `kafka:"min=v0,max=v2|min=v3,max=v3,compact"`
@@ -24,7 +24,7 @@ type Request struct {
 type RequestTopic struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v3,max=v3,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
 
 	Name       string             `kafka:"min=v0,max=v2|min=v3,max=v3,compact"`
 	Partitions []RequestPartition `kafka:"min=v0,max=v3"`
@@ -33,7 +33,7 @@ type RequestTopic struct {
 type RequestPartition struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v3,max=v3,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
 
 	Partition            int32  `kafka:"min=v0,max=v3"`
 	CommittedOffset      int64  `kafka:"min=v0,max=v3"`
@@ -50,7 +50,7 @@ var _ protocol.GroupMessage = (*Request)(nil)
 type Response struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v3,max=v3,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
 
 	ThrottleTimeMs int32           `kafka:"min=v0,max=v3"`
 	Topics         []ResponseTopic `kafka:"min=v0,max=v3"`
@@ -59,7 +59,7 @@ type Response struct {
 type ResponseTopic struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v3,max=v3,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
 
 	Name       string              `kafka:"min=v0,max=v2|min=v3,max=v3,compact"`
 	Partitions []ResponsePartition `kafka:"min=v0,max=v3"`
@@ -68,7 +68,7 @@ type ResponseTopic struct {
 type ResponsePartition struct {
 	// We need at least one tagged field to indicate that this is a "flexible" message
 	// type.
-	_ struct{} `kafka:"min=v3,max=v3,tag"`
+	TaggedFields protocol.TaggedFields `kafka:"min=v3,max=v3,tag"`
 
 	Partition int32 `kafka:"min=v0,max=v3"`
 	ErrorCode int16 `kafka:"min=v0,max=v3"`
@@ -0,0 +1,44 @@
+package protocol
+
+import (
+	"encoding/base64"
+	"io"
+)
+
+// UUID is the representation of universally unique identifiers in the kafka
+// protocol, for example to identify topics.
+type UUID [16]byte
+
+// String returns the URL-safe base64 representation of the UUID, without
+// padding, which is the format used by kafka tools.
+func (u UUID) String() string {
+	return base64.RawURLEncoding.EncodeToString(u[:])
+}
+
+// IsZero returns true if u is the zero UUID, which kafka uses to represent the
+// absence of an identifier.
+func (u UUID) IsZero() bool { return u == UUID{} }
+
+func (u *UUID) ReadFrom(r io.Reader) (int64, error) {
+	n, err := io.ReadFull(r, u[:])
+	return int64(n), err
+}
+
+func (u *UUID) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(u[:])
+	return int64(n), err
+}
+
+// ParseUUID parses the URL-safe base64 representation of a UUID.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return u, err
+	}
+	if len(b) != len(u) {
+		return u, base64.CorruptInputError(len(b))
+	}
+	copy(u[:], b)
+	return u, nil
+}
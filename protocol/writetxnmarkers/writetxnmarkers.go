@@ -0,0 +1,240 @@
+package writetxnmarkers
+
+import (
+	"sort"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func init() {
+	protocol.Register(&Request{}, &Response{})
+}
+
+// Detailed API definition: https://kafka.apache.org/protocol#The_Messages_WriteTxnMarkers
+type Request struct {
+	// We need at least one tagged field to indicate that v1+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v1,max=v1,tag"`
+
+	Markers []RequestMarker `kafka:"min=v0,max=v1"`
+}
+
+type RequestMarker struct {
+	// We need at least one tagged field to indicate that v1+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v1,max=v1,tag"`
+
+	ProducerID        int64          `kafka:"min=v0,max=v1"`
+	ProducerEpoch     int16          `kafka:"min=v0,max=v1"`
+	TransactionResult bool           `kafka:"min=v0,max=v1"`
+	Topics            []RequestTopic `kafka:"min=v0,max=v1"`
+	CoordinatorEpoch  int32          `kafka:"min=v0,max=v1"`
+}
+
+type RequestTopic struct {
+	// We need at least one tagged field to indicate that v1+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v1,max=v1,tag"`
+
+	Name             string  `kafka:"min=v0,max=v1"`
+	PartitionIndexes []int32 `kafka:"min=v0,max=v1"`
+}
+
+func (r *Request) ApiKey() protocol.ApiKey { return protocol.WriteTxnMarkers }
+
+func (r *Request) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	// Expects r to be a request that was returned by Split, will likely panic
+	// or produce the wrong result if that's not the case.
+	partition := r.Markers[0].Topics[0].PartitionIndexes[0]
+	topic := r.Markers[0].Topics[0].Name
+
+	if leader := leaderOf(cluster, topic, partition); leader >= 0 {
+		return cluster.Brokers[leader], nil
+	}
+
+	return protocol.Broker{ID: -1}, nil
+}
+
+func (r *Request) Split(cluster protocol.Cluster) ([]protocol.Message, protocol.Merger, error) {
+	// Transaction markers are written by the partition leaders, the markers
+	// are split by leader and the responses merged back.
+	type batch struct {
+		request *Request
+		markers map[int]int
+		topics  map[int]map[string]int
+	}
+
+	batches := make(map[int32]*batch)
+	leaders := make([]int32, 0, 8)
+
+	for i, m := range r.Markers {
+		for _, t := range m.Topics {
+			for _, p := range t.PartitionIndexes {
+				leader := leaderOf(cluster, t.Name, p)
+
+				b := batches[leader]
+				if b == nil {
+					b = &batch{
+						request: &Request{},
+						markers: make(map[int]int),
+						topics:  make(map[int]map[string]int),
+					}
+					batches[leader] = b
+					leaders = append(leaders, leader)
+				}
+
+				j, ok := b.markers[i]
+				if !ok {
+					j = len(b.request.Markers)
+					b.markers[i] = j
+					b.topics[j] = make(map[string]int)
+					b.request.Markers = append(b.request.Markers, RequestMarker{
+						ProducerID:        m.ProducerID,
+						ProducerEpoch:     m.ProducerEpoch,
+						TransactionResult: m.TransactionResult,
+						CoordinatorEpoch:  m.CoordinatorEpoch,
+					})
+				}
+
+				marker := &b.request.Markers[j]
+
+				k, ok := b.topics[j][t.Name]
+				if !ok {
+					k = len(marker.Topics)
+					b.topics[j][t.Name] = k
+					marker.Topics = append(marker.Topics, RequestTopic{Name: t.Name})
+				}
+
+				marker.Topics[k].PartitionIndexes = append(marker.Topics[k].PartitionIndexes, p)
+			}
+		}
+	}
+
+	messages := make([]protocol.Message, len(leaders))
+
+	for i, leader := range leaders {
+		messages[i] = batches[leader].request
+	}
+
+	return messages, new(Response), nil
+}
+
+func leaderOf(cluster protocol.Cluster, topic string, partition int32) int32 {
+	if p, ok := cluster.Topics[topic].Partitions[partition]; ok {
+		return p.Leader
+	}
+	return -1
+}
+
+type Response struct {
+	// We need at least one tagged field to indicate that v1+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v1,max=v1,tag"`
+
+	Markers []ResponseMarker `kafka:"min=v0,max=v1"`
+}
+
+type ResponseMarker struct {
+	// We need at least one tagged field to indicate that v1+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v1,max=v1,tag"`
+
+	ProducerID int64           `kafka:"min=v0,max=v1"`
+	Topics     []ResponseTopic `kafka:"min=v0,max=v1"`
+}
+
+type ResponseTopic struct {
+	// We need at least one tagged field to indicate that v1+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v1,max=v1,tag"`
+
+	Name       string              `kafka:"min=v0,max=v1"`
+	Partitions []ResponsePartition `kafka:"min=v0,max=v1"`
+}
+
+type ResponsePartition struct {
+	// We need at least one tagged field to indicate that v1+ uses "flexible"
+	// messages.
+	TaggedFields protocol.TaggedFields `kafka:"min=v1,max=v1,tag"`
+
+	PartitionIndex int32 `kafka:"min=v0,max=v1"`
+	ErrorCode      int16 `kafka:"min=v0,max=v1"`
+}
+
+func (r *Response) ApiKey() protocol.ApiKey { return protocol.WriteTxnMarkers }
+
+func (r *Response) Merge(requests []protocol.Message, results []interface{}) (protocol.Message, error) {
+	// Brokers report the results by producer id, which must therefore be
+	// unique across the markers of the request.
+	producers := make(map[int64]map[string][]ResponsePartition)
+	errors := 0
+
+	add := func(producerID int64, topic string, partitions ...ResponsePartition) {
+		topics := producers[producerID]
+		if topics == nil {
+			topics = make(map[string][]ResponsePartition)
+			producers[producerID] = topics
+		}
+		topics[topic] = append(topics[topic], partitions...)
+	}
+
+	for i, res := range results {
+		m, err := protocol.Result(res)
+		if err != nil {
+			for _, marker := range requests[i].(*Request).Markers {
+				for _, t := range marker.Topics {
+					for _, p := range t.PartitionIndexes {
+						add(marker.ProducerID, t.Name, ResponsePartition{
+							PartitionIndex: p,
+							ErrorCode:      -1, // UNKNOWN, can we do better?
+						})
+					}
+				}
+			}
+			errors++
+			continue
+		}
+
+		for _, marker := range m.(*Response).Markers {
+			for _, t := range marker.Topics {
+				add(marker.ProducerID, t.Name, t.Partitions...)
+			}
+		}
+	}
+
+	if errors > 0 && errors == len(results) {
+		_, err := protocol.Result(results[0])
+		return nil, err
+	}
+
+	r.Markers = make([]ResponseMarker, 0, len(producers))
+
+	for producerID, topics := range producers {
+		marker := ResponseMarker{
+			ProducerID: producerID,
+			Topics:     make([]ResponseTopic, 0, len(topics)),
+		}
+
+		for topicName, partitions := range topics {
+			sort.Slice(partitions, func(i, j int) bool {
+				return partitions[i].PartitionIndex < partitions[j].PartitionIndex
+			})
+			marker.Topics = append(marker.Topics, ResponseTopic{
+				Name:       topicName,
+				Partitions: partitions,
+			})
+		}
+
+		sort.Slice(marker.Topics, func(i, j int) bool {
+			return marker.Topics[i].Name < marker.Topics[j].Name
+		})
+
+		r.Markers = append(r.Markers, marker)
+	}
+
+	sort.Slice(r.Markers, func(i, j int) bool {
+		return r.Markers[i].ProducerID < r.Markers[j].ProducerID
+	})
+
+	return r, nil
+}
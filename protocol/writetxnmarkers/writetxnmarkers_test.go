@@ -0,0 +1,133 @@
+package writetxnmarkers_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/prototest"
+	"github.com/segmentio/kafka-go/protocol/writetxnmarkers"
+)
+
+const (
+	v0 = 0
+	v1 = 1
+)
+
+func TestWriteTxnMarkersRequest(t *testing.T) {
+	request := &writetxnmarkers.Request{
+		Markers: []writetxnmarkers.RequestMarker{
+			{
+				ProducerID:    1000,
+				ProducerEpoch: 2,
+				Topics: []writetxnmarkers.RequestTopic{
+					{Name: "topic-A", PartitionIndexes: []int32{0, 1}},
+				},
+				CoordinatorEpoch: 3,
+			},
+			{
+				ProducerID:        1001,
+				TransactionResult: true,
+				Topics: []writetxnmarkers.RequestTopic{
+					{Name: "topic-B", PartitionIndexes: []int32{2}},
+				},
+				CoordinatorEpoch: -1,
+			},
+		},
+	}
+
+	prototest.TestRequest(t, v0, request)
+	prototest.TestRequest(t, v1, request)
+}
+
+func TestWriteTxnMarkersResponse(t *testing.T) {
+	response := &writetxnmarkers.Response{
+		Markers: []writetxnmarkers.ResponseMarker{
+			{
+				ProducerID: 1000,
+				Topics: []writetxnmarkers.ResponseTopic{
+					{
+						Name: "topic-A",
+						Partitions: []writetxnmarkers.ResponsePartition{
+							{PartitionIndex: 0},
+							{PartitionIndex: 1, ErrorCode: 47},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	prototest.TestResponse(t, v0, response)
+	prototest.TestResponse(t, v1, response)
+}
+
+func TestWriteTxnMarkersSplit(t *testing.T) {
+	cluster := protocol.Cluster{
+		Brokers: map[int32]protocol.Broker{
+			1: {ID: 1},
+			2: {ID: 2},
+		},
+		Topics: map[string]protocol.Topic{
+			"topic-A": {
+				Name: "topic-A",
+				Partitions: map[int32]protocol.Partition{
+					0: {ID: 0, Leader: 1},
+					1: {ID: 1, Leader: 2},
+				},
+			},
+		},
+	}
+
+	request := &writetxnmarkers.Request{
+		Markers: []writetxnmarkers.RequestMarker{{
+			ProducerID:       1000,
+			ProducerEpoch:    2,
+			CoordinatorEpoch: -1,
+			Topics: []writetxnmarkers.RequestTopic{
+				{Name: "topic-A", PartitionIndexes: []int32{0, 1}},
+			},
+		}},
+	}
+
+	messages, merger, err := request.Split(cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("the markers must be split by leader: %+v", messages)
+	}
+
+	for i, m := range messages {
+		broker, err := m.(*writetxnmarkers.Request).Broker(cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if broker.ID != int32(i+1) {
+			t.Errorf("request %d sent to broker %d", i, broker.ID)
+		}
+	}
+
+	results := []interface{}{
+		&writetxnmarkers.Response{
+			Markers: []writetxnmarkers.ResponseMarker{{
+				ProducerID: 1000,
+				Topics: []writetxnmarkers.ResponseTopic{{
+					Name:       "topic-A",
+					Partitions: []writetxnmarkers.ResponsePartition{{PartitionIndex: 0}},
+				}},
+			}},
+		},
+		io.ErrUnexpectedEOF,
+	}
+
+	m, err := merger.Merge(messages, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	partitions := m.(*writetxnmarkers.Response).Markers[0].Topics[0].Partitions
+	if len(partitions) != 2 || partitions[0].ErrorCode != 0 || partitions[1].ErrorCode != -1 {
+		t.Errorf("wrong merged partitions: %+v", partitions)
+	}
+}
@@ -0,0 +1,106 @@
+package kafka
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+)
+
+// BrokerQuirks describes the deviations from the kafka protocol of a broker
+// implementation, which the transport works around when it detects that it is
+// connected to such a broker.
+//
+// Services compatible with the kafka protocol (e.g. Redpanda, Azure Event Hubs,
+// or proxies) sometimes advertise APIs that they do not implement, or versions
+// that they mishandle. Programs can describe those deviations in the
+// Transport.Quirks table instead of forking the client for each vendor:
+//
+//	transport := &kafka.Transport{
+//		Quirks: []kafka.BrokerQuirks{{
+//			Name: "event-hubs",
+//			Detect: func(addr net.Addr, _ []kafka.ApiVersion) bool {
+//				return strings.Contains(addr.String(), ".servicebus.windows.net:")
+//			},
+//			MaxVersions: map[protocol.ApiKey]int16{protocol.Fetch: 10},
+//		}},
+//	}
+type BrokerQuirks struct {
+	// Name of the broker software that the quirks apply to, used in error
+	// messages.
+	Name string
+
+	// Detect is called with the address of brokers and the API versions that
+	// they advertised when connections are established, and reports whether
+	// the quirks apply to those brokers.
+	Detect func(addr net.Addr, versions []ApiVersion) bool
+
+	// APIs that the broker advertises but does not implement. Requests for
+	// these APIs fail with UnsupportedVersion instead of being sent to the
+	// broker.
+	DisabledAPIs []protocol.ApiKey
+
+	// Pins the maximum versions of the APIs used with the broker, in addition
+	// to Transport.MaxVersions. Pinning protocol.SaslHandshake to version 0
+	// exchanges raw SASL tokens instead of SaslAuthenticate messages.
+	MaxVersions map[protocol.ApiKey]int16
+
+	// When true, the throttle times reported in the responses of the broker
+	// are ignored, which is useful when the broker does not fill them
+	// correctly.
+	IgnoreThrottle bool
+}
+
+func (q *BrokerQuirks) disables(apiKey protocol.ApiKey) bool {
+	for _, k := range q.DisabledAPIs {
+		if k == apiKey {
+			return true
+		}
+	}
+	return false
+}
+
+// detectQuirks returns the first entry of the quirks table that applies to
+// the broker at addr, or nil if none did.
+func detectQuirks(quirks []BrokerQuirks, addr net.Addr, apiKeys []apiversions.ApiKeyResponse) *BrokerQuirks {
+	if len(quirks) == 0 {
+		return nil
+	}
+
+	versions := make([]ApiVersion, len(apiKeys))
+	for i, k := range apiKeys {
+		versions[i] = ApiVersion{
+			ApiKey:     k.ApiKey,
+			MinVersion: k.MinVersion,
+			MaxVersion: k.MaxVersion,
+		}
+	}
+
+	for i := range quirks {
+		if q := &quirks[i]; q.Detect != nil && q.Detect(addr, versions) {
+			return q
+		}
+	}
+
+	return nil
+}
+
+func (q *BrokerQuirks) checkRequest(req Request) error {
+	if q != nil && q.disables(req.ApiKey()) {
+		return fmt.Errorf("%s is not supported by %s brokers: %w", req.ApiKey(), q.Name, UnsupportedVersion)
+	}
+	return nil
+}
+
+func (q *BrokerQuirks) fixResponse(res Response) {
+	if q == nil || !q.IgnoreThrottle {
+		return
+	}
+	if v := reflect.ValueOf(res); v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct {
+		if f := v.Elem().FieldByName("ThrottleTimeMs"); f.IsValid() && f.CanSet() && f.Kind() == reflect.Int32 {
+			f.SetInt(0)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package kafka
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/initproducerid"
+	meta "github.com/segmentio/kafka-go/protocol/metadata"
+)
+
+func TestBrokerQuirks(t *testing.T) {
+	quirks := []BrokerQuirks{
+		{
+			Name: "other",
+			Detect: func(net.Addr, []ApiVersion) bool {
+				return false
+			},
+		},
+		{
+			Name: "test",
+			Detect: func(addr net.Addr, versions []ApiVersion) bool {
+				return addr.String() == "localhost:9092" && len(versions) == 1 && versions[0].MaxVersion == 4
+			},
+			DisabledAPIs:   []protocol.ApiKey{protocol.InitProducerId},
+			IgnoreThrottle: true,
+		},
+	}
+
+	apiKeys := []apiversions.ApiKeyResponse{{ApiKey: int16(protocol.InitProducerId), MaxVersion: 4}}
+
+	if q := detectQuirks(quirks, TCP("localhost:9093"), apiKeys); q != nil {
+		t.Errorf("quirks of %s were detected on the wrong broker", q.Name)
+	}
+
+	q := detectQuirks(quirks, TCP("localhost:9092"), apiKeys)
+	if q == nil || q.Name != "test" {
+		t.Fatalf("wrong quirks detected: %+v", q)
+	}
+
+	if err := q.checkRequest(&initproducerid.Request{}); !errors.Is(err, UnsupportedVersion) {
+		t.Errorf("disabled API was not rejected: %v", err)
+	}
+	if err := q.checkRequest(&meta.Request{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	res := &meta.Response{ThrottleTimeMs: 100}
+	q.fixResponse(res)
+	if res.ThrottleTimeMs != 0 {
+		t.Errorf("throttle time was not ignored: %d", res.ThrottleTimeMs)
+	}
+}
@@ -0,0 +1,131 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+// RawRequest represents a request carrying an already encoded body, sent to a
+// kafka broker with RoundTripRaw. It allows programs to use broker APIs that
+// this package does not expose yet.
+//
+// Programs using the APIs that the package supports should prefer the typed
+// methods of Client, or pass protocol messages to a Transport directly.
+type RawRequest struct {
+	// Address of the kafka cluster to send the request to.
+	Addr net.Addr
+
+	// The API key of the request.
+	ApiKey int
+
+	// The version of the API that Payload is encoded with. The broker must
+	// support this version, the client does not negotiate it.
+	Version int
+
+	// Flexible must be set to true if the API version uses the flexible
+	// request and response headers. The value is only used for APIs or
+	// versions that are unknown to this package.
+	Flexible bool
+
+	// The encoded body of the request, not including the request header which
+	// is written by the transport.
+	Payload []byte
+
+	// When set to true, the request is routed to the controller of the
+	// cluster.
+	Controller bool
+
+	// When not empty, the request is routed to the coordinator of this
+	// consumer group.
+	Group string
+
+	// When not empty, the request is routed to the coordinator of this
+	// transactional id.
+	TransactionalID string
+
+	// When Leader.Topic is not empty, the request is routed to the leader of
+	// the partition.
+	Leader TopicPartition
+}
+
+// RawResponse represents the response to a RawRequest.
+type RawResponse struct {
+	// The encoded body of the response, not including the response header.
+	Payload []byte
+}
+
+// RoundTripRaw sends a request with an already encoded body to a kafka broker
+// and returns the encoded body of the response.
+//
+// The transport takes care of connecting and authenticating to the brokers,
+// of the request and response headers, and of routing the request according
+// to the fields of req. When no routing fields are set, the request is sent to
+// any broker of the cluster.
+func (c *Client) RoundTripRaw(ctx context.Context, req *RawRequest) (*RawResponse, error) {
+	raw := &protocol.RawRequest{
+		Key:      protocol.ApiKey(req.ApiKey),
+		Version:  int16(req.Version),
+		Flexible: req.Flexible,
+		Payload:  req.Payload,
+	}
+
+	var msg protocol.Message = raw
+	switch {
+	case req.Controller:
+		msg = &rawBrokerRequest{RawRequest: raw, broker: func(cluster protocol.Cluster) (protocol.Broker, error) {
+			return cluster.Brokers[cluster.Controller], nil
+		}}
+	case req.Leader.Topic != "":
+		msg = &rawBrokerRequest{RawRequest: raw, broker: func(cluster protocol.Cluster) (protocol.Broker, error) {
+			p, ok := cluster.Topics[req.Leader.Topic].Partitions[int32(req.Leader.Partition)]
+			if !ok {
+				return protocol.Broker{}, UnknownTopicOrPartition
+			}
+			return cluster.Brokers[p.Leader], nil
+		}}
+	case req.Group != "":
+		msg = &rawGroupRequest{RawRequest: raw, group: req.Group}
+	case req.TransactionalID != "":
+		msg = &rawTransactionalRequest{RawRequest: raw, transactionalID: req.TransactionalID}
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, msg)
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).RoundTripRaw: %w", err)
+	}
+
+	res := m.(*protocol.RawResponse)
+	return &RawResponse{Payload: res.Payload}, nil
+}
+
+type rawBrokerRequest struct {
+	*protocol.RawRequest
+	broker func(protocol.Cluster) (protocol.Broker, error)
+}
+
+func (r *rawBrokerRequest) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	return r.broker(cluster)
+}
+
+type rawGroupRequest struct {
+	*protocol.RawRequest
+	group string
+}
+
+func (r *rawGroupRequest) Group() string { return r.group }
+
+type rawTransactionalRequest struct {
+	*protocol.RawRequest
+	transactionalID string
+}
+
+func (r *rawTransactionalRequest) Transaction() string { return r.transactionalID }
+
+var (
+	_ protocol.BrokerMessage        = (*rawBrokerRequest)(nil)
+	_ protocol.GroupMessage         = (*rawGroupRequest)(nil)
+	_ protocol.TransactionalMessage = (*rawTransactionalRequest)(nil)
+)
@@ -0,0 +1,34 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+func TestClientRoundTripRaw(t *testing.T) {
+	client, shutdown := newLocalClient()
+	defer shutdown()
+
+	res, err := client.RoundTripRaw(context.Background(), &RawRequest{
+		ApiKey:  int(protocol.ApiVersions),
+		Version: 0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ApiVersions v0 response: error_code int16, api_keys [api_key int16,
+	// min_version int16, max_version int16].
+	if len(res.Payload) < 6 {
+		t.Fatalf("response payload is too short: %d bytes", len(res.Payload))
+	}
+	if errorCode := int16(binary.BigEndian.Uint16(res.Payload)); errorCode != 0 {
+		t.Fatal(Error(errorCode))
+	}
+	if n := int32(binary.BigEndian.Uint32(res.Payload[2:])); n <= 0 || len(res.Payload) != 6+6*int(n) {
+		t.Errorf("unexpected number of api keys in %d bytes response: %d", len(res.Payload), n)
+	}
+}
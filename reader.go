@@ -75,6 +75,21 @@ type Reader struct {
 	lag     int64
 	closed  bool
 
+	// generation is the consumer group generation that the spawned readers
+	// belong to, or nil if the reader is not a member of a generation.
+	generation *Generation
+
+	// dedup skips duplicate messages when ReaderConfig.Deduplicator is set.
+	dedup *dedupFilter
+
+	// poll tracks calls to FetchMessage when ReaderConfig.MaxPollInterval is
+	// set, it is nil otherwise.
+	poll *pollTracker
+
+	// startOffsetsApplied is set once ReaderConfig.StartOffsets was applied to
+	// the assignments of a generation, it is only accessed by the run loop.
+	startOffsetsApplied bool
+
 	// Without a group subscription (when Reader.config.GroupID == ""),
 	// when errors occur, the Reader gets a synthetic readerMessage with
 	// a non-nil err set. With group subscriptions however, when an error
@@ -96,6 +111,8 @@ type Reader struct {
 // useConsumerGroup indicates whether the Reader is part of a consumer group.
 func (r *Reader) useConsumerGroup() bool { return r.config.GroupID != "" }
 
+func (r *Reader) clock() Clock { return clockOrDefault(r.config.Clock) }
+
 func (r *Reader) getTopics() []string {
 	if len(r.config.GroupTopics) > 0 {
 		return r.config.GroupTopics[:]
@@ -104,11 +121,23 @@ func (r *Reader) getTopics() []string {
 	return []string{r.config.Topic}
 }
 
+// currentGeneration returns the consumer group generation that the reader is a
+// member of, or nil if it is not a member of a generation.
+func (r *Reader) currentGeneration() *Generation {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.generation
+}
+
 // useSyncCommits indicates whether the Reader is configured to perform sync or
 // async commits.
 func (r *Reader) useSyncCommits() bool { return r.config.CommitInterval == 0 }
 
 func (r *Reader) unsubscribe() {
+	r.mutex.Lock()
+	r.generation = nil
+	r.mutex.Unlock()
+
 	r.cancel()
 	r.join.Wait()
 	// it would be interesting to drain the r.msgs channel at this point since
@@ -121,9 +150,9 @@ func (r *Reader) unsubscribe() {
 	// another consumer to avoid such a race.
 }
 
-func (r *Reader) subscribe(allAssignments map[string][]PartitionAssignment) {
+func (r *Reader) subscribe(gen *Generation) {
 	offsets := make(map[topicPartition]int64)
-	for topic, assignments := range allAssignments {
+	for topic, assignments := range gen.Assignments {
 		for _, assignment := range assignments {
 			key := topicPartition{
 				topic:     topic,
@@ -133,36 +162,57 @@ func (r *Reader) subscribe(allAssignments map[string][]PartitionAssignment) {
 		}
 	}
 
+	if !r.startOffsetsApplied {
+		r.startOffsetsApplied = true
+		for partition, offset := range r.config.StartOffsets {
+			key := topicPartition{topic: r.config.Topic, partition: int32(partition)}
+			if _, ok := offsets[key]; ok {
+				offsets[key] = offset
+			}
+		}
+	}
+
+	if r.config.StartOffsetsFunc != nil {
+		byTopic := make(map[string]map[int]int64)
+		for key, offset := range offsets {
+			partitions := byTopic[key.topic]
+			if partitions == nil {
+				partitions = make(map[int]int64)
+				byTopic[key.topic] = partitions
+			}
+			partitions[int(key.partition)] = offset
+		}
+
+		r.config.StartOffsetsFunc(byTopic)
+
+		for key := range offsets {
+			if offset, ok := byTopic[key.topic][int(key.partition)]; ok {
+				offsets[key] = offset
+			}
+		}
+	}
+
 	r.mutex.Lock()
+	r.generation = gen
 	r.start(offsets)
 	r.mutex.Unlock()
 
 	r.withLogger(func(l Logger) {
-		l.Printf("subscribed to topics and partitions: %+v", offsets)
+		logKV(l, "subscribed to topics and partitions", "offsets", offsets)
 	})
 }
 
 // commitOffsetsWithRetry attempts to commit the specified offsets and retries
 // up to the specified number of times.
-func (r *Reader) commitOffsetsWithRetry(gen *Generation, offsetStash offsetStash, retries int) (err error) {
-	const (
-		backoffDelayMin = 100 * time.Millisecond
-		backoffDelayMax = 5 * time.Second
-	)
-
-	for attempt := 0; attempt < retries; attempt++ {
-		if attempt != 0 {
-			if !sleep(r.stctx, backoff(attempt, backoffDelayMin, backoffDelayMax)) {
-				return
-			}
-		}
-
-		if err = gen.CommitOffsets(offsetStash); err == nil {
-			return
-		}
-	}
-
-	return // err will not be nil
+func (r *Reader) commitOffsetsWithRetry(gen *Generation, offsetStash offsetStash, metadata metadataStash, retries int) error {
+	policy := &RetryPolicy{
+		MaxAttempts: retries,
+		MinBackoff:  100 * time.Millisecond,
+		MaxBackoff:  5 * time.Second,
+	}
+	return policy.do(r.stctx, r.clock(), nil, nil, func() error {
+		return gen.commitOffsetsWithMetadata(offsetStash, metadata)
+	})
 }
 
 // offsetStash holds offsets by topic => partition => offset.
@@ -183,6 +233,33 @@ func (o offsetStash) merge(commits []commit) {
 	}
 }
 
+// set records the offsets, replacing the previous offsets of the same
+// partitions.
+func (o offsetStash) set(offsets map[string]map[int]int64) {
+	for topic, partitions := range offsets {
+		offsetsByPartition, ok := o[topic]
+		if !ok {
+			offsetsByPartition = map[int]int64{}
+			o[topic] = offsetsByPartition
+		}
+		for partition, offset := range partitions {
+			offsetsByPartition[partition] = offset
+		}
+	}
+}
+
+// setMissing records the offsets of partitions which have no offsets in the
+// stash.
+func (o offsetStash) setMissing(offsets map[string]map[int]int64) {
+	for topic, partitions := range offsets {
+		for partition, offset := range partitions {
+			if _, ok := o[topic][partition]; !ok {
+				o.set(map[string]map[int]int64{topic: {partition: offset}})
+			}
+		}
+	}
+}
+
 // reset clears the contents of the offsetStash.
 func (o offsetStash) reset() {
 	for key := range o {
@@ -190,9 +267,64 @@ func (o offsetStash) reset() {
 	}
 }
 
+// metadataStash holds the metadata committed with offsets by topic =>
+// partition => metadata.  The offsets of partitions which have no metadata in
+// the stash are committed with the CommitMetadata of the configuration.
+type metadataStash map[string]map[int]string
+
+// get returns the metadata of a partition, or defaultMetadata if the stash has
+// none.
+func (m metadataStash) get(topic string, partition int, defaultMetadata string) string {
+	if metadata, ok := m[topic][partition]; ok {
+		return metadata
+	}
+	return defaultMetadata
+}
+
+// put sets the metadata of a partition, or removes it when metadata is nil.
+func (m metadataStash) put(topic string, partition int, metadata *string) {
+	if metadata == nil {
+		delete(m[topic], partition)
+		return
+	}
+	metadataByPartition, ok := m[topic]
+	if !ok {
+		metadataByPartition = map[int]string{}
+		m[topic] = metadataByPartition
+	}
+	metadataByPartition[partition] = *metadata
+}
+
+// set records the metadata of the partitions of offsets.
+func (m metadataStash) set(offsets map[string]map[int]int64, metadata *string) {
+	for topic, partitions := range offsets {
+		for partition := range partitions {
+			m.put(topic, partition, metadata)
+		}
+	}
+}
+
+// merge records the metadata of a commit request for the partitions that its
+// commits are the last offsets of in o, after they were merged into o.
+func (m metadataStash) merge(o offsetStash, req commitRequest) {
+	for _, c := range req.commits {
+		if o[c.topic][c.partition] == c.offset {
+			m.put(c.topic, c.partition, req.metadata)
+		}
+	}
+}
+
+// reset clears the contents of the metadataStash.
+func (m metadataStash) reset() {
+	for key := range m {
+		delete(m, key)
+	}
+}
+
 // commitLoopImmediate handles each commit synchronously.
 func (r *Reader) commitLoopImmediate(ctx context.Context, gen *Generation) {
 	offsets := offsetStash{}
+	metadata := metadataStash{}
 
 	for {
 		select {
@@ -206,12 +338,13 @@ func (r *Reader) commitLoopImmediate(ctx context.Context, gen *Generation) {
 				select {
 				case req := <-r.commits:
 					offsets.merge(req.commits)
+					metadata.merge(offsets, req)
 					errchs = append(errchs, req.errch)
 				default:
 					hasCommits = false
 				}
 			}
-			err := r.commitOffsetsWithRetry(gen, offsets, defaultCommitRetries)
+			err := r.commitOffsetsWithRetry(gen, offsets, metadata, defaultCommitRetries)
 			for _, errch := range errchs {
 				// NOTE : this will be a buffered channel and will not block.
 				errch <- err
@@ -220,8 +353,10 @@ func (r *Reader) commitLoopImmediate(ctx context.Context, gen *Generation) {
 
 		case req := <-r.commits:
 			offsets.merge(req.commits)
-			req.errch <- r.commitOffsetsWithRetry(gen, offsets, defaultCommitRetries)
+			metadata.merge(offsets, req)
+			req.errch <- r.commitOffsetsWithRetry(gen, offsets, metadata, defaultCommitRetries)
 			offsets.reset()
+			metadata.reset()
 		}
 	}
 }
@@ -229,18 +364,20 @@ func (r *Reader) commitLoopImmediate(ctx context.Context, gen *Generation) {
 // commitLoopInterval handles each commit asynchronously with a period defined
 // by ReaderConfig.CommitInterval.
 func (r *Reader) commitLoopInterval(ctx context.Context, gen *Generation) {
-	ticker := time.NewTicker(r.config.CommitInterval)
+	ticker := r.clock().NewTicker(r.config.CommitInterval)
 	defer ticker.Stop()
 
 	// the offset stash should not survive rebalances b/c the consumer may
 	// receive new assignments.
 	offsets := offsetStash{}
+	metadata := metadataStash{}
 
 	commit := func() {
-		if err := r.commitOffsetsWithRetry(gen, offsets, defaultCommitRetries); err != nil {
-			r.withErrorLogger(func(l Logger) { l.Printf(err.Error()) })
+		if err := r.commitOffsetsWithRetry(gen, offsets, metadata, defaultCommitRetries); err != nil {
+			r.withErrorLogger(func(l Logger) { logKV(l, "failed to commit offsets", "error", err) })
 		} else {
 			offsets.reset()
+			metadata.reset()
 		}
 	}
 
@@ -252,6 +389,7 @@ func (r *Reader) commitLoopInterval(ctx context.Context, gen *Generation) {
 				select {
 				case req := <-r.commits:
 					offsets.merge(req.commits)
+					metadata.merge(offsets, req)
 				default:
 					hasCommits = false
 				}
@@ -259,11 +397,12 @@ func (r *Reader) commitLoopInterval(ctx context.Context, gen *Generation) {
 			commit()
 			return
 
-		case <-ticker.C:
+		case <-ticker.C():
 			commit()
 
 		case req := <-r.commits:
 			offsets.merge(req.commits)
+			metadata.merge(offsets, req)
 		}
 	}
 }
@@ -271,10 +410,10 @@ func (r *Reader) commitLoopInterval(ctx context.Context, gen *Generation) {
 // commitLoop processes commits off the commit chan.
 func (r *Reader) commitLoop(ctx context.Context, gen *Generation) {
 	r.withLogger(func(l Logger) {
-		l.Printf("started commit for group %s\n", r.config.GroupID)
+		logKV(l, "started commit")
 	})
 	defer r.withLogger(func(l Logger) {
-		l.Printf("stopped commit for group %s\n", r.config.GroupID)
+		logKV(l, "stopped commit")
 	})
 
 	if r.config.CommitInterval == 0 {
@@ -293,7 +432,7 @@ func (r *Reader) run(cg *ConsumerGroup) {
 	defer cg.Close()
 
 	r.withLogger(func(l Logger) {
-		l.Printf("entering loop for consumer group, %v\n", r.config.GroupID)
+		logKV(l, "entering loop for consumer group")
 	})
 
 	for {
@@ -309,9 +448,21 @@ func (r *Reader) run(cg *ConsumerGroup) {
 			if errors.Is(err, r.stctx.Err()) {
 				return
 			}
+			if errors.Is(err, ErrPollIntervalExceeded) {
+				// the reader left the group because the program stopped
+				// fetching messages, wait for it to fetch again before
+				// rejoining the group.
+				select {
+				case <-r.poll.wait():
+				case <-r.stctx.Done():
+					return
+				}
+				attempt--
+				continue
+			}
 			r.stats.errors.observe(1)
 			r.withErrorLogger(func(l Logger) {
-				l.Printf(err.Error())
+				logKV(l, "failed to join the next generation", "error", err)
 			})
 			// Continue with next attempt...
 		}
@@ -329,11 +480,16 @@ func (r *Reader) run(cg *ConsumerGroup) {
 
 		r.stats.rebalances.observe(1)
 
-		r.subscribe(gen.Assignments)
+		r.subscribe(gen)
 
 		gen.Start(func(ctx context.Context) {
 			r.commitLoop(ctx, gen)
 		})
+		if r.poll != nil {
+			gen.Start(func(ctx context.Context) {
+				r.pollWatcher(ctx, gen)
+			})
+		}
 		gen.Start(func(ctx context.Context) {
 			// wait for the generation to end and then unsubscribe.
 			select {
@@ -411,6 +567,28 @@ type ReaderConfig struct {
 	// Only used when GroupID is set
 	GroupBalancers []GroupBalancer
 
+	// GroupAssigner is an optional function called in place of the selected
+	// GroupBalancer when the reader is elected leader of the consumer group.
+	// See ConsumerGroupConfig.GroupAssigner for details.
+	//
+	// Only used when GroupID is set
+	GroupAssigner GroupAssigner
+
+	// OnGroupEvent is an optional function called when the state of the
+	// reader in the consumer group changes.  See ConsumerGroupConfig.OnEvent
+	// for details.
+	//
+	// Only used when GroupID is set
+	OnGroupEvent func(GroupEvent)
+
+	// GroupProtocol is the protocol used by the members of the consumer group
+	// to coordinate partition assignments.
+	//
+	// Default: GroupProtocolClassic
+	//
+	// Only used when GroupID is set
+	GroupProtocol GroupProtocol
+
 	// HeartbeatInterval sets the optional frequency at which the reader sends the consumer
 	// group heartbeat update.
 	//
@@ -457,6 +635,31 @@ type ReaderConfig struct {
 	// Only used when GroupID is set
 	RebalanceTimeout time.Duration
 
+	// MaxPollInterval optionally sets the maximum length of time that may pass
+	// between calls to FetchMessage (or ReadMessage) before the reader leaves
+	// the consumer group, so its partitions are reassigned to other members
+	// instead of being held by a program which stopped consuming.  Heartbeats
+	// keep being sent in the background while the program processes messages,
+	// so SessionTimeout alone does not detect such stalls.  The reader joins
+	// the group again on the next call to FetchMessage.
+	//
+	// Time spent waiting for messages within FetchMessage does not count
+	// toward the interval, and calls to CommitMessages restart it.
+	//
+	// Default: 0 (disabled)
+	//
+	// Only used when GroupID is set
+	MaxPollInterval time.Duration
+
+	// MaxPollAction configures what the reader does when MaxPollInterval is
+	// exceeded: leave the group, pause fetching messages from the brokers
+	// until the next call to FetchMessage, or only log it.
+	//
+	// Default: MaxPollLeave
+	//
+	// Only used when GroupID and MaxPollInterval are set
+	MaxPollAction MaxPollAction
+
 	// JoinGroupBackoff optionally sets the length of time to wait between re-joining
 	// the consumer group after an error.
 	//
@@ -471,6 +674,14 @@ type ReaderConfig struct {
 	// Only used when GroupID is set
 	RetentionTime time.Duration
 
+	// CommitMetadata is an optional string committed with the offsets of the
+	// consumer group, for example to record the host that committed them. It
+	// can be read back with Client.OffsetFetch.  CommitMessagesWithMetadata
+	// commits other metadata with specific messages.
+	//
+	// Only used when GroupID is set
+	CommitMetadata string
+
 	// StartOffset determines from whence the consumer group should begin
 	// consuming when it finds a partition without a committed offset.  If
 	// non-zero, it must be set to one of FirstOffset or LastOffset.
@@ -480,6 +691,26 @@ type ReaderConfig struct {
 	// Only used when GroupID is set
 	StartOffset int64
 
+	// StartOffsets holds the offsets, by partition number, that the reader
+	// starts consuming at, for example positions kept in an external store.
+	// Values must be non-negative offsets, FirstOffset or LastOffset.
+	//
+	// Without GroupID, the entry for Partition replaces the default start at
+	// FirstOffset. With GroupID, the entries override the committed offsets
+	// of the partitions of Topic assigned by the first generation the reader
+	// joins; later generations resume from the committed offsets (see
+	// StartOffsetsFunc). StartOffsets cannot be combined with GroupTopics.
+	StartOffsets map[int]int64
+
+	// StartOffsetsFunc is called each time the consumer group assigns
+	// partitions to the reader, with the offsets it is about to start at by
+	// topic and partition. Entries modified in the map replace the offsets
+	// the reader starts at, which lets it resume from positions kept outside
+	// of Kafka after every rebalance.
+	//
+	// Only used when GroupID is set
+	StartOffsetsFunc func(offsets map[string]map[int]int64)
+
 	// BackoffDelayMin optionally sets the smallest amount of time the reader will wait before
 	// polling for new messages
 	//
@@ -492,6 +723,13 @@ type ReaderConfig struct {
 	// Default: 1s
 	ReadBackoffMax time.Duration
 
+	// Clock is an optional clock used to schedule commits, lag updates,
+	// backoffs, and the heartbeats of the consumer group, so tests can drive
+	// them with a fake clock. See Clock for details.
+	//
+	// Default: SystemClock
+	Clock Clock
+
 	// If not nil, specifies a logger used to report internal changes within the
 	// reader.
 	Logger Logger
@@ -500,6 +738,11 @@ type ReaderConfig struct {
 	// back to using Logger instead.
 	ErrorLogger Logger
 
+	// If not nil, specifies a structured logger used to report internal
+	// changes and errors within the reader, with keys and values describing
+	// their context. It takes precedence over Logger and ErrorLogger.
+	StructuredLogger StructuredLogger
+
 	// IsolationLevel controls the visibility of transactional records.
 	// ReadUncommitted makes all records visible. With ReadCommitted only
 	// non-transactional and committed records are visible.
@@ -515,51 +758,195 @@ type ReaderConfig struct {
 	// This flag is being added to retain backwards-compatibility, so it will be
 	// removed in a future version of kafka-go.
 	OffsetOutOfRangeError bool
+
+	// An optional encryptor used to decrypt the values of messages that were
+	// encrypted by writers, see Encryptor for details. Messages which were
+	// not encrypted are returned as is.
+	Encryptor Encryptor
+
+	// An optional interceptor called with the messages returned by the reader,
+	// see ReaderInterceptor for details.
+	Interceptor ReaderInterceptor
+
+	// An optional deduplicator used to skip the messages which are duplicates
+	// of messages returned recently, see Deduplicator for details.
+	Deduplicator *Deduplicator
+
+	// When true, the keys and values of the messages returned by the reader
+	// are leased from the buffer pool of the protocol package, instead of
+	// being allocated for each message. The program must call the Release
+	// method of the messages when it does not reference their keys and values
+	// anymore, which returns the memory to the pool.
+	//
+	// This is intended for high-throughput consumers which process messages
+	// and discard them quickly. Programs retaining messages (or slices of
+	// their keys and values) for long periods of time should not enable it,
+	// since a message holds the memory of the whole page that it was read
+	// into.
+	PooledMessages bool
 }
 
 // Validate method validates ReaderConfig properties.
+//
+// All the problems found in the configuration are reported in the returned
+// *ConfigError, instead of only the first one.
+//
+// Validate also reports problems that NewReader tolerates to remain compatible
+// with configurations it accepted in earlier versions, NewReaderWith refuses
+// them.
 func (config *ReaderConfig) Validate() error {
+	var errs configErrors
+	config.validate(&errs)
+	config.validateStrict(&errs)
+	return errs.err()
+}
+
+// validate checks the properties of the configuration that NewReader requires
+// to create a reader.
+func (config *ReaderConfig) validate(errs *configErrors) {
 	if len(config.Brokers) == 0 {
-		return errors.New("cannot create a new kafka reader with an empty list of broker addresses")
+		errs.add(errors.New("cannot create a new kafka reader with an empty list of broker addresses"))
 	}
 
 	if config.Partition < 0 || config.Partition >= math.MaxInt32 {
-		return fmt.Errorf("partition number out of bounds: %d", config.Partition)
+		errs.addf("partition number out of bounds: %d", config.Partition)
 	}
 
 	if config.MinBytes < 0 {
-		return fmt.Errorf("invalid negative minimum batch size (min = %d)", config.MinBytes)
+		errs.addf("invalid negative minimum batch size (min = %d)", config.MinBytes)
 	}
 
 	if config.MaxBytes < 0 {
-		return fmt.Errorf("invalid negative maximum batch size (max = %d)", config.MaxBytes)
+		errs.addf("invalid negative maximum batch size (max = %d)", config.MaxBytes)
 	}
 
 	if config.GroupID != "" {
 		if config.Partition != 0 {
-			return errors.New("either Partition or GroupID may be specified, but not both")
+			errs.add(errors.New("either Partition or GroupID may be specified, but not both"))
 		}
 
 		if len(config.Topic) == 0 && len(config.GroupTopics) == 0 {
-			return errors.New("either Topic or GroupTopics must be specified with GroupID")
+			errs.add(errors.New("either Topic or GroupTopics must be specified with GroupID"))
+		}
+
+		if config.MaxPollInterval < 0 || (config.MaxPollInterval/time.Millisecond) >= math.MaxInt32 {
+			errs.addf("MaxPollInterval out of bounds: %d", config.MaxPollInterval)
+		}
+
+		if config.MaxPollAction < MaxPollLeave || config.MaxPollAction > MaxPollLog {
+			errs.addf("MaxPollAction is not valid %d", config.MaxPollAction)
+		}
+
+		if len(config.CommitMetadata) > math.MaxInt16 {
+			errs.addf("CommitMetadata out of bounds: %d bytes", len(config.CommitMetadata))
 		}
 	} else if len(config.Topic) == 0 {
-		return errors.New("cannot create a new kafka reader with an empty topic")
+		errs.add(errors.New("cannot create a new kafka reader with an empty topic"))
+	}
+
+	for partition, offset := range config.StartOffsets {
+		if offset < 0 && offset != FirstOffset && offset != LastOffset {
+			errs.addf("StartOffsets has an invalid offset for partition %d: %d", partition, offset)
+		}
+		if config.GroupID == "" && partition != config.Partition {
+			errs.addf("StartOffsets has an offset for partition %d which is not the reader's partition %d", partition, config.Partition)
+		}
+	}
+
+	if len(config.StartOffsets) != 0 && len(config.GroupTopics) != 0 {
+		errs.add(errors.New("StartOffsets may not be specified with GroupTopics"))
 	}
 
 	if config.MinBytes > config.MaxBytes {
-		return fmt.Errorf("minimum batch size greater than the maximum (min = %d, max = %d)", config.MinBytes, config.MaxBytes)
+		errs.addf("minimum batch size greater than the maximum (min = %d, max = %d)", config.MinBytes, config.MaxBytes)
+	}
+
+	if config.QueueCapacity < 0 {
+		errs.addf("QueueCapacity out of bounds: %d", config.QueueCapacity)
 	}
 
 	if config.ReadBackoffMax < 0 {
-		return fmt.Errorf("ReadBackoffMax out of bounds: %d", config.ReadBackoffMax)
+		errs.addf("ReadBackoffMax out of bounds: %d", config.ReadBackoffMax)
 	}
 
 	if config.ReadBackoffMin < 0 {
-		return fmt.Errorf("ReadBackoffMin out of bounds: %d", config.ReadBackoffMin)
+		errs.addf("ReadBackoffMin out of bounds: %d", config.ReadBackoffMin)
 	}
 
-	return nil
+	backoffMin, backoffMax := config.ReadBackoffMin, config.ReadBackoffMax
+	if backoffMin == 0 {
+		backoffMin = defaultReadBackoffMin
+	}
+	if backoffMax == 0 {
+		backoffMax = defaultReadBackoffMax
+	}
+	if backoffMin > 0 && backoffMax > 0 && backoffMax < backoffMin {
+		errs.addf("ReadBackoffMax %d smaller than ReadBackoffMin %d", backoffMax, backoffMin)
+	}
+}
+
+// validateStrict checks the properties of the configuration that NewReader
+// did not check in earlier versions, it accepts them to remain compatible.
+func (config *ReaderConfig) validateStrict(errs *configErrors) {
+	if config.MaxWait < 0 {
+		errs.addf("MaxWait out of bounds: %d", config.MaxWait)
+	}
+
+	if config.MaxAttempts < 0 {
+		errs.addf("MaxAttempts out of bounds: %d", config.MaxAttempts)
+	}
+
+	if config.GroupID == "" {
+		if len(config.GroupTopics) != 0 {
+			errs.add(errors.New("GroupTopics may only be specified with GroupID"))
+		}
+		return
+	}
+
+	if len(config.Topic) != 0 && len(config.GroupTopics) != 0 {
+		errs.add(errors.New("either Topic or GroupTopics may be specified, but not both"))
+	}
+
+	// The fields used by readers which are part of a consumer group would
+	// otherwise only be reported when creating the group.
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"HeartbeatInterval", config.HeartbeatInterval},
+		{"SessionTimeout", config.SessionTimeout},
+		{"RebalanceTimeout", config.RebalanceTimeout},
+		{"JoinGroupBackoff", config.JoinGroupBackoff},
+		{"PartitionWatchInterval", config.PartitionWatchInterval},
+	}
+	for _, d := range durations {
+		if d.value < 0 || (d.value/time.Millisecond) >= math.MaxInt32 {
+			errs.addf("%s out of bounds: %d", d.name, d.value)
+		}
+	}
+
+	if config.CommitInterval < 0 {
+		errs.addf("CommitInterval out of bounds: %d", config.CommitInterval)
+	}
+
+	if config.RetentionTime < 0 && config.RetentionTime != defaultRetentionTime {
+		errs.addf("RetentionTime out of bounds: %d", config.RetentionTime)
+	}
+
+	if config.StartOffset != 0 && config.StartOffset != FirstOffset && config.StartOffset != LastOffset {
+		errs.addf("StartOffset is not valid %d", config.StartOffset)
+	}
+
+	heartbeat, session := config.HeartbeatInterval, config.SessionTimeout
+	if heartbeat == 0 {
+		heartbeat = defaultHeartbeatInterval
+	}
+	if session == 0 {
+		session = defaultSessionTimeout
+	}
+	if heartbeat > 0 && session > 0 && heartbeat >= session {
+		errs.addf("HeartbeatInterval %s must be smaller than SessionTimeout %s", heartbeat, session)
+	}
 }
 
 // ReaderStats is a data structure returned by a call to Reader.Stats that exposes
@@ -572,6 +959,8 @@ type ReaderStats struct {
 	Rebalances int64 `metric:"kafka.reader.rebalance.count" type:"counter"`
 	Timeouts   int64 `metric:"kafka.reader.timeout.count"   type:"counter"`
 	Errors     int64 `metric:"kafka.reader.error.count"     type:"counter"`
+	Duplicates int64 `metric:"kafka.reader.duplicate.count" type:"counter"`
+	PollStalls int64 `metric:"kafka.reader.stall.count"     type:"counter"`
 
 	DialTime   DurationStats `metric:"kafka.reader.dial.seconds"`
 	ReadTime   DurationStats `metric:"kafka.reader.read.seconds"`
@@ -579,6 +968,11 @@ type ReaderStats struct {
 	FetchSize  SummaryStats  `metric:"kafka.reader.fetch.size"`
 	FetchBytes SummaryStats  `metric:"kafka.reader.fetch.bytes"`
 
+	// PollTime measures the time spent by the program between calls to
+	// FetchMessage, its maximum shows how close the program runs to
+	// MaxPollInterval.
+	PollTime DurationStats `metric:"kafka.reader.poll.seconds"`
+
 	Offset        int64         `metric:"kafka.reader.offset"          type:"gauge"`
 	Lag           int64         `metric:"kafka.reader.lag"             type:"gauge"`
 	MinBytes      int64         `metric:"kafka.reader.fetch_bytes.min" type:"gauge"`
@@ -587,6 +981,8 @@ type ReaderStats struct {
 	QueueLength   int64         `metric:"kafka.reader.queue.length"    type:"gauge"`
 	QueueCapacity int64         `metric:"kafka.reader.queue.capacity"  type:"gauge"`
 
+	MaxPollInterval time.Duration `metric:"kafka.reader.poll_interval.max" type:"gauge"`
+
 	ClientID  string `tag:"client_id"`
 	Topic     string `tag:"topic"`
 	Partition string `tag:"partition"`
@@ -607,11 +1003,14 @@ type readerStats struct {
 	rebalances counter
 	timeouts   counter
 	errors     counter
+	duplicates counter
+	pollStalls counter
 	dialTime   summary
 	readTime   summary
 	waitTime   summary
 	fetchSize  summary
 	fetchBytes summary
+	pollTime   summary
 	offset     gauge
 	lag        gauge
 	partition  string
@@ -620,7 +1019,9 @@ type readerStats struct {
 // NewReader creates and returns a new Reader configured with config.
 // The offset is initialized to FirstOffset.
 func NewReader(config ReaderConfig) *Reader {
-	if err := config.Validate(); err != nil {
+	var errs configErrors
+	config.validate(&errs)
+	if err := errs.err(); err != nil {
 		panic(err)
 	}
 
@@ -686,6 +1087,11 @@ func NewReader(config ReaderConfig) *Reader {
 		version = 1
 	}
 
+	offset := FirstOffset
+	if o, ok := config.StartOffsets[config.Partition]; ok && config.GroupID == "" {
+		offset = o
+	}
+
 	stctx, stop := context.WithCancel(context.Background())
 	r := &Reader{
 		config:  config,
@@ -693,7 +1099,7 @@ func NewReader(config ReaderConfig) *Reader {
 		cancel:  func() {},
 		commits: make(chan commitRequest, config.QueueCapacity),
 		stop:    stop,
-		offset:  FirstOffset,
+		offset:  offset,
 		stctx:   stctx,
 		stats: &readerStats{
 			dialTime:   makeSummary(),
@@ -701,21 +1107,28 @@ func NewReader(config ReaderConfig) *Reader {
 			waitTime:   makeSummary(),
 			fetchSize:  makeSummary(),
 			fetchBytes: makeSummary(),
+			pollTime:   makeSummary(),
 			// Generate the string representation of the partition number only
 			// once when the reader is created.
 			partition: strconv.Itoa(readerStatsPartition),
 		},
 		version: version,
+		dedup:   newDedupFilter(config.Deduplicator),
 	}
 	if r.useConsumerGroup() {
 		r.done = make(chan struct{})
 		r.runError = make(chan error)
+		if config.MaxPollInterval > 0 {
+			r.poll = &pollTracker{}
+		}
 		cg, err := NewConsumerGroup(ConsumerGroupConfig{
 			ID:                     r.config.GroupID,
 			Brokers:                r.config.Brokers,
 			Dialer:                 r.config.Dialer,
 			Topics:                 r.getTopics(),
 			GroupBalancers:         r.config.GroupBalancers,
+			GroupAssigner:          r.config.GroupAssigner,
+			GroupProtocol:          r.config.GroupProtocol,
 			HeartbeatInterval:      r.config.HeartbeatInterval,
 			PartitionWatchInterval: r.config.PartitionWatchInterval,
 			WatchPartitionChanges:  r.config.WatchPartitionChanges,
@@ -723,9 +1136,13 @@ func NewReader(config ReaderConfig) *Reader {
 			RebalanceTimeout:       r.config.RebalanceTimeout,
 			JoinGroupBackoff:       r.config.JoinGroupBackoff,
 			RetentionTime:          r.config.RetentionTime,
+			CommitMetadata:         r.config.CommitMetadata,
+			Clock:                  r.config.Clock,
 			StartOffset:            r.config.StartOffset,
 			Logger:                 r.config.Logger,
 			ErrorLogger:            r.config.ErrorLogger,
+			StructuredLogger:       r.config.StructuredLogger,
+			OnEvent:                r.config.OnGroupEvent,
 		})
 		if err != nil {
 			panic(err)
@@ -801,9 +1218,28 @@ func (r *Reader) ReadMessage(ctx context.Context) (Message, error) {
 //
 // FetchMessage does not commit offsets automatically when using consumer groups.
 // Use CommitMessages to commit the offset.
+//
+// When the reader is configured with an Encryptor and the value of a message
+// cannot be decrypted, the method returns the encrypted message along with a
+// *DecryptionError.
 func (r *Reader) FetchMessage(ctx context.Context) (Message, error) {
+	m, _, err := r.fetchMessage(ctx)
+	return m, err
+}
+
+// fetchMessage is like FetchMessage, it also returns the consumer group
+// generation that the message was read in, which is nil when the generation
+// already ended, or when the reader is not part of a consumer group.
+func (r *Reader) fetchMessage(ctx context.Context) (Message, *Generation, error) {
 	r.activateReadLag()
 
+	if r.poll != nil {
+		if elapsed, idle := r.poll.begin(r.clock().Now()); idle {
+			r.stats.pollTime.observeDuration(elapsed)
+		}
+		defer func() { r.poll.end(r.clock().Now()) }()
+	}
+
 	for {
 		r.mutex.Lock()
 
@@ -816,17 +1252,18 @@ func (r *Reader) FetchMessage(ctx context.Context) (Message, error) {
 
 		select {
 		case <-ctx.Done():
-			return Message{}, ctx.Err()
+			return Message{}, nil, ctx.Err()
 
 		case err := <-r.runError:
-			return Message{}, err
+			return Message{}, nil, err
 
 		case m, ok := <-r.msgs:
 			if !ok {
-				return Message{}, io.EOF
+				return Message{}, nil, io.EOF
 			}
 
 			if m.version >= version {
+				var gen *Generation
 				r.mutex.Lock()
 
 				switch {
@@ -835,6 +1272,9 @@ func (r *Reader) FetchMessage(ctx context.Context) (Message, error) {
 					r.offset = m.message.Offset + 1
 					r.lag = m.watermark - r.offset
 				}
+				if m.version == r.version {
+					gen = r.generation
+				}
 
 				r.mutex.Unlock()
 
@@ -846,8 +1286,26 @@ func (r *Reader) FetchMessage(ctx context.Context) (Message, error) {
 					m.error = io.ErrUnexpectedEOF
 				}
 
-				return m.message, m.error
+				if m.error == nil && r.config.Encryptor != nil {
+					m.message, m.error = decryptMessage(r.config.Encryptor, m.message)
+				}
+
+				if m.error == nil && r.dedup != nil && r.dedup.duplicate(m.message, r.clock().Now()) {
+					r.stats.duplicates.observe(1)
+					m.message.Release()
+					continue
+				}
+
+				if m.error == nil && r.config.Interceptor != nil {
+					r.config.Interceptor.OnFetch(ctx, m.message)
+				}
+
+				return m.message, gen, m.error
 			}
+
+			// The message was read before the offset was changed, it is
+			// never returned to the program.
+			m.message.Release()
 		}
 	}
 }
@@ -865,13 +1323,35 @@ func (r *Reader) FetchMessage(ctx context.Context) (Message, error) {
 // topic/partition it belonged to forward, effectively committing all previous
 // messages in the partition.
 func (r *Reader) CommitMessages(ctx context.Context, msgs ...Message) error {
+	return r.commitMessages(ctx, nil, msgs)
+}
+
+// CommitMessagesWithMetadata is like CommitMessages but commits metadata with
+// the offsets instead of the CommitMetadata of the reader configuration, for
+// example to record a checkpoint of the processing of the messages.
+//
+// When commits are batched by CommitInterval, the offset of each partition is
+// committed with the metadata of the call which committed it.
+func (r *Reader) CommitMessagesWithMetadata(ctx context.Context, metadata string, msgs ...Message) error {
+	if len(metadata) > math.MaxInt16 {
+		return fmt.Errorf("commit metadata out of bounds: %d bytes", len(metadata))
+	}
+	return r.commitMessages(ctx, &metadata, msgs)
+}
+
+func (r *Reader) commitMessages(ctx context.Context, metadata *string, msgs []Message) error {
 	if !r.useConsumerGroup() {
 		return errOnlyAvailableWithGroup
 	}
 
+	if r.poll != nil {
+		r.poll.reset(r.clock().Now())
+	}
+
 	var errch <-chan error
 	creq := commitRequest{
-		commits: makeCommits(msgs...),
+		commits:  makeCommits(msgs...),
+		metadata: metadata,
 	}
 
 	if r.useSyncCommits() {
@@ -985,7 +1465,7 @@ func (r *Reader) Offset() int64 {
 	offset := r.offset
 	r.mutex.Unlock()
 	r.withLogger(func(log Logger) {
-		log.Printf("looking up offset of kafka reader for partition %d of %s: %s", r.config.Partition, r.config.Topic, toHumanOffset(offset))
+		logKV(log, "looking up offset of kafka reader", "offset", toHumanOffset(offset))
 	})
 	return offset
 }
@@ -1023,8 +1503,7 @@ func (r *Reader) SetOffset(offset int64) error {
 		err = io.ErrClosedPipe
 	} else if offset != r.offset {
 		r.withLogger(func(log Logger) {
-			log.Printf("setting the offset of the kafka reader for partition %d of %s from %s to %s",
-				r.config.Partition, r.config.Topic, toHumanOffset(r.offset), toHumanOffset(offset))
+			logKV(log, "setting the offset of the kafka reader", "from", toHumanOffset(r.offset), "to", toHumanOffset(offset))
 		})
 		r.offset = offset
 
@@ -1079,19 +1558,33 @@ func (r *Reader) SetOffsetAt(ctx context.Context, t time.Time) error {
 // call Stats on a kafka reader and report the metrics to a stats collection
 // system.
 func (r *Reader) Stats() ReaderStats {
-	stats := ReaderStats{
-		Dials:         r.stats.dials.snapshot(),
-		Fetches:       r.stats.fetches.snapshot(),
-		Messages:      r.stats.messages.snapshot(),
-		Bytes:         r.stats.bytes.snapshot(),
-		Rebalances:    r.stats.rebalances.snapshot(),
-		Timeouts:      r.stats.timeouts.snapshot(),
-		Errors:        r.stats.errors.snapshot(),
-		DialTime:      r.stats.dialTime.snapshotDuration(),
-		ReadTime:      r.stats.readTime.snapshotDuration(),
-		WaitTime:      r.stats.waitTime.snapshotDuration(),
-		FetchSize:     r.stats.fetchSize.snapshot(),
-		FetchBytes:    r.stats.fetchBytes.snapshot(),
+	var stats ReaderStats
+	r.SnapshotStats(&stats, DeltaStats)
+	return stats
+}
+
+// SnapshotStats is like Stats, but writes the snapshot to stats, and reports
+// the counters and summaries in the given mode. Unlike Stats, cumulative
+// snapshots do not reset the values reported by the next call to Stats.
+//
+// The method does not allocate memory, so it may be called at a high rate.
+func (r *Reader) SnapshotStats(stats *ReaderStats, mode StatsMode) {
+	*stats = ReaderStats{
+		Dials:         r.stats.dials.snapshot(mode),
+		Fetches:       r.stats.fetches.snapshot(mode),
+		Messages:      r.stats.messages.snapshot(mode),
+		Bytes:         r.stats.bytes.snapshot(mode),
+		Rebalances:    r.stats.rebalances.snapshot(mode),
+		Timeouts:      r.stats.timeouts.snapshot(mode),
+		Errors:        r.stats.errors.snapshot(mode),
+		Duplicates:    r.stats.duplicates.snapshot(mode),
+		PollStalls:    r.stats.pollStalls.snapshot(mode),
+		DialTime:      r.stats.dialTime.snapshotDuration(mode),
+		ReadTime:      r.stats.readTime.snapshotDuration(mode),
+		WaitTime:      r.stats.waitTime.snapshotDuration(mode),
+		FetchSize:     r.stats.fetchSize.snapshot(mode),
+		FetchBytes:    r.stats.fetchBytes.snapshot(mode),
+		PollTime:      r.stats.pollTime.snapshotDuration(mode),
 		Offset:        r.stats.offset.snapshot(),
 		Lag:           r.stats.lag.snapshot(),
 		MinBytes:      int64(r.config.MinBytes),
@@ -1103,9 +1596,9 @@ func (r *Reader) Stats() ReaderStats {
 		Topic:         r.config.Topic,
 		Partition:     r.stats.partition,
 	}
+	stats.MaxPollInterval = r.config.MaxPollInterval
 	// TODO: remove when we get rid of the deprecated field.
 	stats.DeprecatedFetchesWithTypo = stats.Fetches
-	return stats
 }
 
 func (r *Reader) getTopicPartitionOffset() map[topicPartition]int64 {
@@ -1114,19 +1607,33 @@ func (r *Reader) getTopicPartitionOffset() map[topicPartition]int64 {
 }
 
 func (r *Reader) withLogger(do func(Logger)) {
-	if r.config.Logger != nil {
-		do(r.config.Logger)
+	if l := newLogger(r.config.StructuredLogger, r.config.Logger, LogLevelInfo, r.logArgs()...); l != nil {
+		do(l)
 	}
 }
 
 func (r *Reader) withErrorLogger(do func(Logger)) {
-	if r.config.ErrorLogger != nil {
-		do(r.config.ErrorLogger)
+	if l := newLogger(r.config.StructuredLogger, r.config.ErrorLogger, LogLevelError, r.logArgs()...); l != nil {
+		do(l)
 	} else {
 		r.withLogger(do)
 	}
 }
 
+// logArgs returns the keys and values passed to the structured logger.
+func (r *Reader) logArgs() []interface{} {
+	var args []interface{}
+	if r.config.Topic != "" {
+		args = append(args, "topic", r.config.Topic)
+	}
+	if r.useConsumerGroup() {
+		args = append(args, "group", r.config.GroupID)
+	} else {
+		args = append(args, "partition", r.config.Partition)
+	}
+	return args
+}
+
 func (r *Reader) activateReadLag() {
 	if r.config.ReadLagInterval > 0 && atomic.CompareAndSwapUint32(&r.once, 0, 1) {
 		// read lag will only be calculated when not using consumer groups
@@ -1138,7 +1645,7 @@ func (r *Reader) activateReadLag() {
 }
 
 func (r *Reader) readLag(ctx context.Context) {
-	ticker := time.NewTicker(r.config.ReadLagInterval)
+	ticker := r.clock().NewTicker(r.config.ReadLagInterval)
 	defer ticker.Stop()
 
 	for {
@@ -1149,14 +1656,14 @@ func (r *Reader) readLag(ctx context.Context) {
 		if err != nil {
 			r.stats.errors.observe(1)
 			r.withErrorLogger(func(log Logger) {
-				log.Printf("kafka reader failed to read lag of partition %d of %s: %s", r.config.Partition, r.config.Topic, err)
+				logKV(log, "kafka reader failed to read lag", "error", err)
 			})
 		} else {
 			r.stats.lag.observe(lag)
 		}
 
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 		case <-ctx.Done():
 			return
 		}
@@ -1175,15 +1682,25 @@ func (r *Reader) start(offsetsByPartition map[topicPartition]int64) {
 	r.cancel = cancel
 	r.version++
 
+	var pausePoll *pollTracker
+	if r.config.MaxPollAction == MaxPollPause {
+		pausePoll = r.poll
+	}
+
 	r.join.Add(len(offsetsByPartition))
 	for key, offset := range offsetsByPartition {
 		go func(ctx context.Context, key topicPartition, offset int64, join *sync.WaitGroup) {
 			defer join.Done()
 
+			args := []interface{}{"topic", key.topic, "partition", int(key.partition)}
+			if r.useConsumerGroup() {
+				args = append(args, "group", r.config.GroupID)
+			}
+
 			(&reader{
 				dialer:          r.config.Dialer,
-				logger:          r.config.Logger,
-				errorLogger:     r.config.ErrorLogger,
+				logger:          newLogger(r.config.StructuredLogger, r.config.Logger, LogLevelInfo, args...),
+				errorLogger:     newLogger(r.config.StructuredLogger, r.config.ErrorLogger, LogLevelError, args...),
 				brokers:         r.config.Brokers,
 				topic:           key.topic,
 				partition:       int(key.partition),
@@ -1192,11 +1709,14 @@ func (r *Reader) start(offsetsByPartition map[topicPartition]int64) {
 				maxWait:         r.config.MaxWait,
 				backoffDelayMin: r.config.ReadBackoffMin,
 				backoffDelayMax: r.config.ReadBackoffMax,
+				clock:           r.clock(),
 				version:         r.version,
 				msgs:            r.msgs,
 				stats:           r.stats,
 				isolationLevel:  r.config.IsolationLevel,
 				maxAttempts:     r.config.MaxAttempts,
+				buffers:         newMessageBuffers(r.config.PooledMessages),
+				poll:            pausePoll,
 
 				// backwards-compatibility flags
 				offsetOutOfRangeError: r.config.OffsetOutOfRangeError,
@@ -1220,11 +1740,17 @@ type reader struct {
 	maxWait         time.Duration
 	backoffDelayMin time.Duration
 	backoffDelayMax time.Duration
+	clock           Clock
 	version         int64
 	msgs            chan<- readerMessage
 	stats           *readerStats
 	isolationLevel  IsolationLevel
 	maxAttempts     int
+	buffers         *messageBuffers
+
+	// poll is set when fetches are paused while the program exceeds
+	// ReaderConfig.MaxPollInterval.
+	poll *pollTracker
 
 	offsetOutOfRangeError bool
 }
@@ -1246,15 +1772,17 @@ func (r *reader) run(ctx context.Context, offset int64) {
 	// be surfaced to the program.
 	// If the reader wasn't retrying then the program would block indefinitely
 	// on a Read call after reading the first error.
+	defer r.buffers.close()
+
 	for attempt := 0; true; attempt++ {
 		if attempt != 0 {
-			if !sleep(ctx, backoff(attempt, r.backoffDelayMin, r.backoffDelayMax)) {
+			if !sleepClock(ctx, r.clock, backoff(attempt, r.backoffDelayMin, r.backoffDelayMax)) {
 				return
 			}
 		}
 
 		r.withLogger(func(log Logger) {
-			log.Printf("initializing kafka reader for partition %d of %s starting at offset %d", r.partition, r.topic, toHumanOffset(offset))
+			logKV(log, "initializing kafka reader", "offset", toHumanOffset(offset))
 		})
 
 		conn, start, err := r.initialize(ctx, offset)
@@ -1269,7 +1797,7 @@ func (r *reader) run(ctx context.Context, offset int64) {
 				// offset on the partition leader. In that case we're just going
 				// to retry later hoping that enough data has been produced.
 				r.withErrorLogger(func(log Logger) {
-					log.Printf("error initializing the kafka reader for partition %d of %s: %s", r.partition, r.topic, err)
+					logKV(log, "error initializing the kafka reader", "offset", toHumanOffset(offset), "error", err)
 				})
 
 				continue
@@ -1284,7 +1812,7 @@ func (r *reader) run(ctx context.Context, offset int64) {
 			} else {
 				r.stats.errors.observe(1)
 				r.withErrorLogger(func(log Logger) {
-					log.Printf("error initializing the kafka reader for partition %d of %s: %s", r.partition, r.topic, err)
+					logKV(log, "error initializing the kafka reader", "offset", toHumanOffset(offset), "error", err)
 				})
 			}
 			continue
@@ -1302,11 +1830,21 @@ func (r *reader) run(ctx context.Context, offset int64) {
 		errcount := 0
 	readLoop:
 		for {
-			if !sleep(ctx, backoff(errcount, r.backoffDelayMin, r.backoffDelayMax)) {
+			if !sleepClock(ctx, r.clock, backoff(errcount, r.backoffDelayMin, r.backoffDelayMax)) {
 				conn.Close()
 				return
 			}
 
+			if r.poll != nil {
+				// fetches are paused until the program reads messages again.
+				select {
+				case <-r.poll.wait():
+				case <-ctx.Done():
+					conn.Close()
+					return
+				}
+			}
+
 			offset, err = r.read(ctx, offset, conn)
 			switch {
 			case err == nil:
@@ -1323,7 +1861,7 @@ func (r *reader) run(ctx context.Context, offset int64) {
 
 			case errors.Is(err, UnknownTopicOrPartition):
 				r.withErrorLogger(func(log Logger) {
-					log.Printf("failed to read from current broker for partition %d of %s at offset %d, topic or parition not found on this broker, %v", r.partition, r.topic, toHumanOffset(offset), r.brokers)
+					logKV(log, "failed to read from current broker, topic or partition not found on this broker", append(conn.logArgs(), "offset", toHumanOffset(offset), "brokers", r.brokers)...)
 				})
 
 				conn.Close()
@@ -1335,7 +1873,7 @@ func (r *reader) run(ctx context.Context, offset int64) {
 
 			case errors.Is(err, NotLeaderForPartition):
 				r.withErrorLogger(func(log Logger) {
-					log.Printf("failed to read from current broker for partition %d of %s at offset %d, not the leader", r.partition, r.topic, toHumanOffset(offset))
+					logKV(log, "failed to read from current broker, not the leader", append(conn.logArgs(), "offset", toHumanOffset(offset))...)
 				})
 
 				conn.Close()
@@ -1349,7 +1887,7 @@ func (r *reader) run(ctx context.Context, offset int64) {
 				// Timeout on the kafka side, this can be safely retried.
 				errcount = 0
 				r.withLogger(func(log Logger) {
-					log.Printf("no messages received from kafka within the allocated time for partition %d of %s at offset %d", r.partition, r.topic, toHumanOffset(offset))
+					logKV(log, "no messages received from kafka within the allocated time", append(conn.logArgs(), "offset", toHumanOffset(offset))...)
 				})
 				r.stats.timeouts.observe(1)
 				continue
@@ -1358,7 +1896,7 @@ func (r *reader) run(ctx context.Context, offset int64) {
 				first, last, err := r.readOffsets(conn)
 				if err != nil {
 					r.withErrorLogger(func(log Logger) {
-						log.Printf("the kafka reader got an error while attempting to determine whether it was reading before the first offset or after the last offset of partition %d of %s: %s", r.partition, r.topic, err)
+						logKV(log, "the kafka reader got an error while attempting to determine whether it was reading before the first offset or after the last offset", append(conn.logArgs(), "error", err)...)
 					})
 					conn.Close()
 					break readLoop
@@ -1367,7 +1905,7 @@ func (r *reader) run(ctx context.Context, offset int64) {
 				switch {
 				case offset < first:
 					r.withErrorLogger(func(log Logger) {
-						log.Printf("the kafka reader is reading before the first offset for partition %d of %s, skipping from offset %d to %d (%d messages)", r.partition, r.topic, toHumanOffset(offset), first, first-offset)
+						logKV(log, "the kafka reader is reading before the first offset, skipping messages", append(conn.logArgs(), "from", toHumanOffset(offset), "to", first, "skipped", first-offset)...)
 					})
 					offset, errcount = first, 0
 					continue // retry immediately so we don't keep falling behind due to the backoff
@@ -1379,7 +1917,7 @@ func (r *reader) run(ctx context.Context, offset int64) {
 				default:
 					// We may be reading past the last offset, will retry later.
 					r.withErrorLogger(func(log Logger) {
-						log.Printf("the kafka reader is reading passed the last offset for partition %d of %s at offset %d", r.partition, r.topic, toHumanOffset(offset))
+						logKV(log, "the kafka reader is reading past the last offset", append(conn.logArgs(), "offset", toHumanOffset(offset))...)
 					})
 				}
 
@@ -1401,7 +1939,7 @@ func (r *reader) run(ctx context.Context, offset int64) {
 					r.sendError(ctx, err)
 				} else {
 					r.withErrorLogger(func(log Logger) {
-						log.Printf("the kafka reader got an unknown error reading partition %d of %s at offset %d: %s", r.partition, r.topic, toHumanOffset(offset), err)
+						logKV(log, "the kafka reader got an unknown error", append(conn.logArgs(), "offset", toHumanOffset(offset), "error", err)...)
 					})
 					r.stats.errors.observe(1)
 					conn.Close()
@@ -1447,7 +1985,7 @@ func (r *reader) initialize(ctx context.Context, offset int64) (conn *Conn, star
 		}
 
 		r.withLogger(func(log Logger) {
-			log.Printf("the kafka reader for partition %d of %s is seeking to offset %d", r.partition, r.topic, toHumanOffset(offset))
+			logKV(log, "the kafka reader is seeking to offset", append(conn.logArgs(), "offset", toHumanOffset(offset))...)
 		})
 
 		if start, err = conn.Seek(offset, SeekAbsolute); err != nil {
@@ -1474,6 +2012,7 @@ func (r *reader) read(ctx context.Context, offset int64, conn *Conn) (int64, err
 		MaxBytes:       r.maxBytes,
 		IsolationLevel: r.isolationLevel,
 	})
+	batch.buffers = r.buffers
 	highWaterMark := batch.HighWaterMark()
 
 	t1 := time.Now()
@@ -1495,6 +2034,7 @@ func (r *reader) read(ctx context.Context, offset int64, conn *Conn) (int64, err
 		}
 
 		if msg, err = batch.ReadMessage(); err != nil {
+			msg.Release()
 			batch.Close()
 			break
 		}
@@ -1504,6 +2044,7 @@ func (r *reader) read(ctx context.Context, offset int64, conn *Conn) (int64, err
 		r.stats.bytes.observe(n)
 
 		if err = r.sendMessage(ctx, msg, highWaterMark); err != nil {
+			msg.Release()
 			batch.Close()
 			break
 		}
@@ -1540,6 +2081,7 @@ func (r *reader) sendMessage(ctx context.Context, msg Message, watermark int64)
 }
 
 func (r *reader) sendError(ctx context.Context, err error) error {
+	err = withErrorContext(err, r.topic, r.partition, "")
 	select {
 	case r.msgs <- readerMessage{version: r.version, error: err}:
 		return nil
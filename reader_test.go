@@ -1270,6 +1270,106 @@ func TestValidateReader(t *testing.T) {
 	}
 }
 
+func TestValidateReaderReportsAllProblems(t *testing.T) {
+	tests := []struct {
+		scenario string
+		config   ReaderConfig
+		problems int
+	}{
+		{
+			scenario: "empty configuration",
+			config:   ReaderConfig{},
+			problems: 2,
+		},
+		{
+			scenario: "group topics without a group",
+			config:   ReaderConfig{Brokers: []string{"broker1"}, Topic: "topic1", GroupTopics: []string{"topic2"}},
+			problems: 1,
+		},
+		{
+			scenario: "conflicting topics of a group",
+			config:   ReaderConfig{Brokers: []string{"broker1"}, GroupID: "group1", Topic: "topic1", GroupTopics: []string{"topic2"}},
+			problems: 1,
+		},
+		{
+			scenario: "nonsensical timeouts",
+			config: ReaderConfig{
+				Brokers:           []string{"broker1"},
+				GroupID:           "group1",
+				Topic:             "topic1",
+				MaxWait:           -time.Second,
+				HeartbeatInterval: 10 * time.Second,
+				SessionTimeout:    5 * time.Second,
+				RebalanceTimeout:  -time.Second,
+				ReadBackoffMin:    time.Second,
+				ReadBackoffMax:    time.Millisecond,
+			},
+			problems: 4,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			err := test.config.Validate()
+			configErr, ok := err.(*ConfigError)
+			if !ok {
+				t.Fatalf("expected a *ConfigError but got %T: %v", err, err)
+			}
+			if len(configErr.Errors) != test.problems {
+				t.Errorf("expected %d problems but got %d: %v", test.problems, len(configErr.Errors), err)
+			}
+		})
+	}
+}
+
+func TestNewReaderWith(t *testing.T) {
+	r, err := NewReaderWith(
+		ReaderBrokers("broker1"),
+		ReaderGroup("group1", "topic1", "topic2"),
+		ReaderBytes(10, 1000),
+		ReaderCommitInterval(time.Second),
+		ReaderConfigure(func(c *ReaderConfig) { c.QueueCapacity = 10 }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := r.Config()
+	r.Close()
+
+	if config.GroupID != "group1" || len(config.GroupTopics) != 2 || config.MinBytes != 10 || config.MaxBytes != 1000 ||
+		config.CommitInterval != time.Second || config.QueueCapacity != 10 {
+		t.Errorf("options were not applied: %+v", config)
+	}
+
+	r, err = NewReaderWith(ReaderTopic("topic1"), ReaderPartition(-1))
+	if r != nil {
+		t.Error("a reader was returned with an invalid configuration")
+	}
+	if configErr, ok := err.(*ConfigError); !ok || len(configErr.Errors) != 2 {
+		t.Errorf("expected a *ConfigError with 2 problems but got %v", err)
+	}
+}
+
+func TestNewReaderAcceptsLegacyConfig(t *testing.T) {
+	config := ReaderConfig{
+		Brokers:     []string{"broker1"},
+		Topic:       "topic1",
+		GroupTopics: []string{"topic2"},
+		MaxWait:     -time.Second,
+		MaxAttempts: -1,
+	}
+
+	if err := config.Validate(); err == nil {
+		t.Error("expected Validate to report the problems of the configuration")
+	}
+	if _, err := NewReaderWith(ReaderConfigure(func(c *ReaderConfig) { *c = config })); err == nil {
+		t.Error("expected NewReaderWith to refuse the configuration")
+	}
+
+	// NewReader accepted the configuration before it was validated strictly.
+	NewReader(config).Close()
+}
+
 func TestCommitLoopImmediateFlushOnGenerationEnd(t *testing.T) {
 	t.Parallel()
 	var committedOffset int64
@@ -1359,7 +1459,7 @@ func TestCommitOffsetsWithRetry(t *testing.T) {
 			}
 
 			r := &Reader{stctx: context.Background()}
-			err := r.commitOffsetsWithRetry(gen, offsets, defaultCommitRetries)
+			err := r.commitOffsetsWithRetry(gen, offsets, nil, defaultCommitRetries)
 			switch {
 			case test.HasError && err == nil:
 				t.Error("bad err: expected not nil; got nil")
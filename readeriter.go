@@ -0,0 +1,87 @@
+//go:build go1.23
+// +build go1.23
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+)
+
+// Messages returns an iterator over the messages fetched by the reader, which
+// is convenient to write consumption loops:
+//
+//	for msg, err := range r.Messages(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		process(msg)
+//		if err := r.CommitMessages(ctx, msg); err != nil {
+//			return err
+//		}
+//	}
+//
+// Messages are read with FetchMessage, so their offsets are not committed; the
+// program may call CommitMessages within the loop, or use CommitMessagesAfter.
+//
+// When reading a message fails, the iterator yields the error with a
+// zero-value message and stops. The iteration ends without an error when the
+// reader is closed.
+//
+// Messages that cannot be decrypted are yielded still encrypted, along with a
+// *DecryptionError, and the iteration continues with the next message unless
+// the loop exits, so the program decides whether to skip them.
+func (r *Reader) Messages(ctx context.Context) iter.Seq2[Message, error] {
+	return func(yield func(Message, error) bool) {
+		for {
+			m, err := r.FetchMessage(ctx)
+			if err != nil {
+				var decryptErr *DecryptionError
+				if errors.As(err, &decryptErr) {
+					if !yield(m, err) {
+						return
+					}
+					continue
+				}
+				if !errors.Is(err, io.EOF) {
+					yield(Message{}, err)
+				}
+				return
+			}
+			if !yield(m, nil) {
+				return
+			}
+		}
+	}
+}
+
+// CommitMessagesAfter returns an iterator like Messages, which commits the
+// offset of each message after the body of the loop completed for it.
+//
+// A message is not committed when the loop exits while processing it, with a
+// break or return statement, so it is read again by the next reader of its
+// partition. When committing a message fails, the iterator yields the error
+// with a zero-value message and stops. Messages yielded with a
+// *DecryptionError are committed like the others when the loop continues,
+// which skips them.
+//
+// Committing requires the reader to be part of a consumer group.
+func (r *Reader) CommitMessagesAfter(ctx context.Context) iter.Seq2[Message, error] {
+	return func(yield func(Message, error) bool) {
+		for m, err := range r.Messages(ctx) {
+			if !yield(m, err) {
+				return
+			}
+			var decryptErr *DecryptionError
+			if err != nil && !errors.As(err, &decryptErr) {
+				return
+			}
+			if err := r.CommitMessages(ctx, m); err != nil {
+				yield(Message{}, err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+//go:build go1.23
+// +build go1.23
+
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+	"github.com/segmentio/kafka-go/protocol"
+)
+
+var iterTopic = kafkatest.Topic{
+	Name: "topic-A",
+	Records: map[int][]kafkatest.Record{
+		0: {{Value: []byte("0")}, {Value: []byte("1")}, {Value: []byte("2")}},
+	},
+}
+
+func TestReaderMessages(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, iterTopic)
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: []string{b.Addr().String()},
+		Topic:   "topic-A",
+		MaxWait: 10 * time.Millisecond,
+	})
+
+	var values []string
+	for m, err := range r.Messages(ctx) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, string(m.Value))
+		if len(values) == 3 {
+			r.Close()
+		}
+	}
+
+	if fmt.Sprint(values) != "[0 1 2]" {
+		t.Errorf("wrong messages: %v", values)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	r = kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   []string{b.Addr().String()},
+		Topic:     "topic-A",
+		MaxWait:   10 * time.Millisecond,
+		Partition: 0,
+	})
+	defer r.Close()
+	r.SetOffset(3)
+
+	var errs []error
+	for _, err := range r.Messages(timeout) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || errs[0] == nil {
+		t.Errorf("expected the iteration to fail when the context expires: %v", errs)
+	}
+}
+
+func TestReaderCommitMessagesAfter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, iterTopic)
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: []string{b.Addr().String()},
+		GroupID: "group-A",
+		Topic:   "topic-A",
+		MaxWait: 10 * time.Millisecond,
+	})
+	defer r.Close()
+
+	for m, err := range r.CommitMessagesAfter(ctx) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Offset == 1 {
+			// Exiting the loop leaves the message uncommitted.
+			break
+		}
+	}
+
+	offset, ok := b.CommittedOffset("group-A", "topic-A", 0)
+	if !ok || offset != 1 {
+		t.Errorf("wrong committed offset: %d (%t)", offset, ok)
+	}
+}
+
+func TestReaderMessagesDecryptionError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	encryptor := &kafka.AESGCMEncryptor{KeyID: "k1", Keys: map[string][]byte{"k1": make([]byte, 16)}}
+	value, keyID, err := encryptor.Encrypt("topic-A", nil, []byte("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := protocol.Header{Key: kafka.EncryptionKeyHeader, Value: []byte(keyID)}
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{
+		Name: "topic-A",
+		Records: map[int][]kafkatest.Record{
+			0: {
+				{Value: []byte("corrupted"), Headers: []protocol.Header{header}},
+				{Value: value, Headers: []protocol.Header{header}},
+			},
+		},
+	})
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   []string{b.Addr().String()},
+		GroupID:   "group-A",
+		Topic:     "topic-A",
+		MaxWait:   10 * time.Millisecond,
+		Encryptor: encryptor,
+	})
+	defer r.Close()
+
+	var values []string
+	for m, err := range r.CommitMessagesAfter(ctx) {
+		var decryptErr *kafka.DecryptionError
+		if errors.As(err, &decryptErr) {
+			if m.Offset != 0 {
+				t.Errorf("wrong message yielded with the decryption error: %+v", m)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, string(m.Value))
+		break
+	}
+
+	if fmt.Sprint(values) != "[1]" {
+		t.Errorf("wrong messages: %v", values)
+	}
+	if offset, ok := b.CommittedOffset("group-A", "topic-A", 0); !ok || offset != 1 {
+		t.Errorf("the message which failed to decrypt should be committed: %d (%t)", offset, ok)
+	}
+}
@@ -0,0 +1,98 @@
+package kafka
+
+import "time"
+
+// ReaderOption is a functional option configuring readers created by
+// NewReaderWith.
+type ReaderOption func(*ReaderConfig)
+
+// NewReaderWith creates a new Reader configured by applying opts, in order, to
+// a zero ReaderConfig:
+//
+//	r, err := kafka.NewReaderWith(
+//		kafka.ReaderBrokers("localhost:9092"),
+//		kafka.ReaderGroup("consumer-group-id", "topic-A", "topic-B"),
+//		kafka.ReaderBytes(10e3, 10e6),
+//	)
+//
+// Unlike NewReader, which panics, NewReaderWith returns the *ConfigError
+// listing all the problems of the configuration when it is invalid.
+func NewReaderWith(opts ...ReaderOption) (*Reader, error) {
+	config := ReaderConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return NewReader(config), nil
+}
+
+// ReaderConfigure returns a ReaderOption which calls fn to modify the
+// configuration, giving access to fields which have no dedicated option.
+func ReaderConfigure(fn func(*ReaderConfig)) ReaderOption {
+	return fn
+}
+
+// ReaderBrokers appends addresses to the list of brokers of the reader.
+func ReaderBrokers(addrs ...string) ReaderOption {
+	return func(c *ReaderConfig) { c.Brokers = append(c.Brokers, addrs...) }
+}
+
+// ReaderTopic sets the topic that the reader reads from.
+func ReaderTopic(topic string) ReaderOption {
+	return func(c *ReaderConfig) { c.Topic = topic }
+}
+
+// ReaderPartition sets the partition that the reader reads from, when it is
+// not part of a consumer group.
+func ReaderPartition(partition int) ReaderOption {
+	return func(c *ReaderConfig) { c.Partition = partition }
+}
+
+// ReaderGroup makes the reader part of the consumer group with the given id.
+// When topics are given they are set as the GroupTopics of the reader,
+// otherwise the reader consumes its Topic.
+func ReaderGroup(id string, topics ...string) ReaderOption {
+	return func(c *ReaderConfig) {
+		c.GroupID = id
+		if len(topics) != 0 {
+			c.GroupTopics = topics
+		}
+	}
+}
+
+// ReaderDialer sets the dialer used by the reader to connect to brokers.
+func ReaderDialer(dialer *Dialer) ReaderOption {
+	return func(c *ReaderConfig) { c.Dialer = dialer }
+}
+
+// ReaderBytes sets the minimum and maximum number of bytes of the fetch
+// requests of the reader.
+func ReaderBytes(min, max int) ReaderOption {
+	return func(c *ReaderConfig) { c.MinBytes, c.MaxBytes = min, max }
+}
+
+// ReaderMaxWait sets the maximum amount of time the reader waits for new data
+// when fetching batches of messages.
+func ReaderMaxWait(d time.Duration) ReaderOption {
+	return func(c *ReaderConfig) { c.MaxWait = d }
+}
+
+// ReaderCommitInterval sets the interval at which the offsets of a consumer
+// group reader are committed; zero makes commits synchronous.
+func ReaderCommitInterval(d time.Duration) ReaderOption {
+	return func(c *ReaderConfig) { c.CommitInterval = d }
+}
+
+// ReaderStartOffset sets the offset a consumer group reader starts from when
+// the group has no committed offset, either FirstOffset or LastOffset.
+func ReaderStartOffset(offset int64) ReaderOption {
+	return func(c *ReaderConfig) { c.StartOffset = offset }
+}
+
+// ReaderLogger sets the loggers used to report internal changes and errors
+// within the reader.
+func ReaderLogger(logger, errorLogger Logger) ReaderOption {
+	return func(c *ReaderConfig) { c.Logger, c.ErrorLogger = logger, errorLogger }
+}
@@ -0,0 +1,161 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MaxPollAction configures what readers do when the program does not fetch
+// messages within ReaderConfig.MaxPollInterval.
+type MaxPollAction int
+
+const (
+	// MaxPollLeave makes the reader leave its consumer group, so the
+	// partitions are reassigned to other members, and join again on the next
+	// call to FetchMessage. It is the default.
+	MaxPollLeave MaxPollAction = iota
+
+	// MaxPollPause makes the reader stop fetching messages from the brokers
+	// until the next call to FetchMessage, while it stays in its consumer
+	// group and keeps its partitions.
+	MaxPollPause
+
+	// MaxPollLog only logs that the interval was exceeded.
+	MaxPollLog
+)
+
+func (a MaxPollAction) String() string {
+	switch a {
+	case MaxPollLeave:
+		return "leave"
+	case MaxPollPause:
+		return "pause"
+	case MaxPollLog:
+		return "log"
+	default:
+		return "unknown"
+	}
+}
+
+// pollResumed is returned by pollTracker.wait when the program is not
+// stalled, to avoid allocating a channel on every fetch.
+var pollResumed = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+// pollTracker records when the program last called FetchMessage or
+// CommitMessages on a reader configured with a MaxPollInterval, so the reader
+// can act when the program stops consuming messages.
+type pollTracker struct {
+	mutex   sync.Mutex
+	active  int       // number of FetchMessage calls in progress
+	last    time.Time // time at which the last call started or returned
+	stalled bool      // set when the interval was exceeded, until the next fetch
+	polled  chan struct{}
+}
+
+// begin records the start of a FetchMessage call, waking up the goroutines
+// waiting for the program to fetch messages again. It returns the time spent
+// by the program since its previous call, or false if another call was still
+// in progress, or if no call or generation preceded it.
+func (p *pollTracker) begin(now time.Time) (time.Duration, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	elapsed, idle := now.Sub(p.last), p.active == 0 && !p.last.IsZero()
+	p.active++
+	p.last = now
+	p.stalled = false
+	if p.polled != nil {
+		close(p.polled)
+		p.polled = nil
+	}
+	return elapsed, idle
+}
+
+// end records that a FetchMessage call returned.
+func (p *pollTracker) end(now time.Time) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.active--
+	p.last = now
+}
+
+// reset restarts the interval, it is called when the program commits
+// messages, and when the reader joins a new generation since the program
+// cannot receive messages during rebalances.
+func (p *pollTracker) reset(now time.Time) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.last = now
+}
+
+// deadline returns how long to wait before checking the interval again, or
+// true if the program did not call FetchMessage within the interval.
+func (p *pollTracker) deadline(now time.Time, interval time.Duration) (time.Duration, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.active > 0 {
+		// the program is waiting for messages, it is not stalled.
+		return interval, false
+	}
+	if wait := interval - now.Sub(p.last); wait > 0 {
+		return wait, false
+	}
+	p.stalled = true
+	return 0, true
+}
+
+// wait returns a channel which is closed when the program calls FetchMessage
+// after it exceeded the interval.
+func (p *pollTracker) wait() <-chan struct{} {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.stalled {
+		return pollResumed
+	}
+	if p.polled == nil {
+		p.polled = make(chan struct{})
+	}
+	return p.polled
+}
+
+// pollWatcher applies ReaderConfig.MaxPollAction when the program does not
+// call FetchMessage within ReaderConfig.MaxPollInterval. To leave the group,
+// it ends the generation with ErrPollIntervalExceeded, the consumer group then
+// leaves instead of holding on to partitions that are not consumed.
+func (r *Reader) pollWatcher(ctx context.Context, gen *Generation) {
+	clock := r.clock()
+	interval := r.config.MaxPollInterval
+	r.poll.reset(clock.Now())
+
+	for {
+		wait, stalled := r.poll.deadline(clock.Now(), interval)
+		if !stalled {
+			if !sleepClock(ctx, clock, wait) {
+				return
+			}
+			continue
+		}
+
+		r.stats.pollStalls.observe(1)
+		r.withErrorLogger(func(l Logger) {
+			l.Printf("member %s of group %s did not fetch messages within %s, action: %s", gen.MemberID, gen.GroupID, interval, r.config.MaxPollAction)
+		})
+
+		if r.config.MaxPollAction == MaxPollLeave {
+			gen.fail(ErrPollIntervalExceeded)
+			return
+		}
+
+		// the partition readers wait on the same signal when fetches are
+		// paused, watch the next interval once the program fetches again.
+		select {
+		case <-r.poll.wait():
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func TestReaderMaxPollInterval(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+	for i := 0; i < 3; i++ {
+		if _, err := b.Append("topic-A", 0, kafkatest.Record{Value: []byte("hello")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events := make(chan kafka.GroupEvent, 100)
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:           []string{b.Addr().String()},
+		GroupID:           "group-A",
+		Topic:             "topic-A",
+		MaxWait:           10 * time.Millisecond,
+		HeartbeatInterval: 50 * time.Millisecond,
+		MaxPollInterval:   200 * time.Millisecond,
+		OnGroupEvent: func(e kafka.GroupEvent) {
+			select {
+			case events <- e:
+			default:
+			}
+		},
+	})
+	defer r.Close()
+
+	if _, err := r.FetchMessage(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the program stalls, the reader must leave the group.
+	waitGroupEvent(ctx, t, events, func(e kafka.GroupEvent) bool {
+		return e.Type == kafka.GroupLeaving && errors.Is(e.Err, kafka.ErrPollIntervalExceeded)
+	})
+
+	// the reader does not join again until the program fetches messages.
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected group event while stalled: %+v", e)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	if _, err := r.FetchMessage(ctx); err != nil {
+		t.Fatal(err)
+	}
+	waitGroupEvent(ctx, t, events, func(e kafka.GroupEvent) bool {
+		return e.Type == kafka.GroupStable
+	})
+}
+
+func TestReaderPollTimeFirstFetch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{
+		Name:    "topic-A",
+		Records: map[int][]kafkatest.Record{0: {{Value: []byte("hello")}}},
+	})
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:           []string{b.Addr().String()},
+		GroupID:           "group-A",
+		Topic:             "topic-A",
+		MaxWait:           10 * time.Millisecond,
+		HeartbeatInterval: 50 * time.Millisecond,
+		MaxPollInterval:   time.Minute,
+	})
+	defer r.Close()
+
+	if _, err := r.FetchMessage(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// There was no poll before the first fetch, it must not be measured from
+	// the zero time.
+	if s := r.Stats(); s.PollTime.Max >= time.Minute {
+		t.Errorf("poll time of the first fetch is %s", s.PollTime.Max)
+	}
+}
+
+func TestReaderMaxPollPause(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+	if _, err := b.Append("topic-A", 0, kafkatest.Record{Value: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan kafka.GroupEvent, 100)
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:           []string{b.Addr().String()},
+		GroupID:           "group-A",
+		Topic:             "topic-A",
+		MaxWait:           10 * time.Millisecond,
+		HeartbeatInterval: 50 * time.Millisecond,
+		MaxPollInterval:   200 * time.Millisecond,
+		MaxPollAction:     kafka.MaxPollPause,
+		OnGroupEvent: func(e kafka.GroupEvent) {
+			if e.Type == kafka.GroupLeaving {
+				events <- e
+			}
+		},
+	})
+	defer r.Close()
+
+	if _, err := r.FetchMessage(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := func() kafka.ReaderStats {
+		var stats kafka.ReaderStats
+		r.SnapshotStats(&stats, kafka.CumulativeStats)
+		return stats
+	}
+
+	// the program stalls, the reader must stop fetching.
+	for stats().PollStalls == 0 {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	fetches := stats().Fetches
+	time.Sleep(200 * time.Millisecond)
+	if n := stats().Fetches; n != fetches {
+		t.Errorf("reader fetched %d times while paused", n-fetches)
+	}
+
+	if _, err := b.Append("topic-A", 0, kafkatest.Record{Value: []byte("world")}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := r.FetchMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m.Value) != "world" {
+		t.Errorf("wrong message after resuming: %q", m.Value)
+	}
+
+	s := stats()
+	if s.PollTime.Max < 200*time.Millisecond {
+		t.Errorf("poll time %s is smaller than the interval", s.PollTime.Max)
+	}
+	if s.MaxPollInterval != 200*time.Millisecond {
+		t.Errorf("wrong max poll interval: %s", s.MaxPollInterval)
+	}
+
+	select {
+	case e := <-events:
+		t.Errorf("the reader left the group while paused: %+v", e)
+	default:
+	}
+}
+
+func waitGroupEvent(ctx context.Context, t *testing.T, events <-chan kafka.GroupEvent, match func(kafka.GroupEvent) bool) {
+	t.Helper()
+	for {
+		select {
+		case e := <-events:
+			if match(e) {
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		}
+	}
+}
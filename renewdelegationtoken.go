@@ -0,0 +1,65 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol/renewdelegationtoken"
+)
+
+// RenewDelegationTokenRequest represents a request sent to a kafka broker to
+// renew a delegation token.
+type RenewDelegationTokenRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// HMAC of the token to renew.
+	HMAC []byte
+
+	// The period by which the expiry time of the token is extended, if zero
+	// the renewal period configured on the brokers is used.
+	RenewPeriod time.Duration
+}
+
+// RenewDelegationTokenResponse represents a response from a kafka broker to a
+// delegation token renewal request.
+type RenewDelegationTokenResponse struct {
+	// The amount of time that the broker throttled the request.
+	Throttle time.Duration
+
+	// An error that may have occurred while attempting to renew the token.
+	//
+	// The error contains both the kafka error code, and an error message
+	// returned by the kafka broker. Programs may use the standard errors.Is
+	// function to test the error against kafka error codes.
+	Error error
+
+	// The new expiry time of the token.
+	ExpiryTime time.Time
+}
+
+// RenewDelegationToken sends a delegation token renewal request to a kafka
+// broker and returns the response.
+func (c *Client) RenewDelegationToken(ctx context.Context, req *RenewDelegationTokenRequest) (*RenewDelegationTokenResponse, error) {
+	renewPeriodMs := int64(-1)
+	if req.RenewPeriod > 0 {
+		renewPeriodMs = int64(req.RenewPeriod / time.Millisecond)
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &renewdelegationtoken.Request{
+		HMAC:          req.HMAC,
+		RenewPeriodMs: renewPeriodMs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).RenewDelegationToken: %w", err)
+	}
+
+	res := m.(*renewdelegationtoken.Response)
+	return &RenewDelegationTokenResponse{
+		Throttle:   makeDuration(res.ThrottleTimeMs),
+		Error:      makeError(res.ErrorCode, ""),
+		ExpiryTime: makeTime(res.ExpiryTimestampMs),
+	}, nil
+}
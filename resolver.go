@@ -3,6 +3,8 @@ package kafka
 import (
 	"context"
 	"net"
+	"strconv"
+	"strings"
 )
 
 // The Resolver interface is used as an abstraction to provide service discovery
@@ -55,3 +57,44 @@ func (r brokerResolver) LookupBrokerIPAddr(ctx context.Context, broker Broker) (
 
 	return ipAddrs, nil
 }
+
+// SRVPrefix is the prefix of addresses that name DNS SRV records instead of
+// hosts, for example "dns+srv://_kafka._tcp.example.com". Transports and
+// dialers connect to the targets of the records when they are given such
+// addresses, which lets the set of bootstrap brokers change without
+// reconfiguring programs.
+const SRVPrefix = "dns+srv://"
+
+// lookupSRV is the function used to resolve DNS SRV records, it is a variable
+// so tests can replace it.
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+func isSRVAddress(address string) bool {
+	return strings.HasPrefix(address, SRVPrefix)
+}
+
+// lookupSRVAddresses returns the addresses of the targets of the SRV records
+// named by address, ordered by priority and randomized by weight.
+func lookupSRVAddresses(ctx context.Context, address string) ([]string, error) {
+	name := strings.TrimPrefix(address, SRVPrefix)
+
+	_, records, err := lookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, &net.DNSError{
+			Err:         "no SRV records were returned by the resolver",
+			Name:        name,
+			IsTemporary: true,
+			IsNotFound:  true,
+		}
+	}
+
+	addresses := make([]string, len(records))
+	for i, r := range records {
+		addresses[i] = net.JoinHostPort(strings.TrimSuffix(r.Target, "."), strconv.Itoa(int(r.Port)))
+	}
+	return addresses, nil
+}
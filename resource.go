@@ -1,5 +1,12 @@
 package kafka
 
+import (
+	"encoding"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // https://github.com/apache/kafka/blob/trunk/clients/src/main/java/org/apache/kafka/common/resource/ResourceType.java
 type ResourceType int8
 
@@ -35,3 +42,75 @@ const (
 	// that start with 'foo'.
 	PatternTypePrefixed PatternType = 4
 )
+
+var resourceTypeStrings = [...]string{
+	ResourceTypeUnknown:         "UNKNOWN",
+	ResourceTypeAny:             "ANY",
+	ResourceTypeTopic:           "TOPIC",
+	ResourceTypeGroup:           "GROUP",
+	ResourceTypeCluster:         "CLUSTER",
+	ResourceTypeTransactionalID: "TRANSACTIONAL_ID",
+	ResourceTypeDelegationToken: "DELEGATION_TOKEN",
+}
+
+func (t ResourceType) String() string { return enumString(resourceTypeStrings[:], int8(t)) }
+
+func (t ResourceType) MarshalText() ([]byte, error) { return []byte(t.String()), nil }
+
+func (t *ResourceType) UnmarshalText(b []byte) error {
+	i, err := parseEnum(resourceTypeStrings[:], "resource type", b)
+	*t = ResourceType(i)
+	return err
+}
+
+var patternTypeStrings = [...]string{
+	PatternTypeUnknown:  "UNKNOWN",
+	PatternTypeAny:      "ANY",
+	PatternTypeMatch:    "MATCH",
+	PatternTypeLiteral:  "LITERAL",
+	PatternTypePrefixed: "PREFIXED",
+}
+
+func (t PatternType) String() string { return enumString(patternTypeStrings[:], int8(t)) }
+
+func (t PatternType) MarshalText() ([]byte, error) { return []byte(t.String()), nil }
+
+func (t *PatternType) UnmarshalText(b []byte) error {
+	i, err := parseEnum(patternTypeStrings[:], "pattern type", b)
+	*t = PatternType(i)
+	return err
+}
+
+var (
+	_ encoding.TextMarshaler   = ResourceType(0)
+	_ encoding.TextUnmarshaler = (*ResourceType)(nil)
+	_ encoding.TextMarshaler   = PatternType(0)
+	_ encoding.TextUnmarshaler = (*PatternType)(nil)
+)
+
+// enumString returns the name of i in names, which are the uppercase names
+// used by kafka in its configuration files and command line tools.
+func enumString(names []string, i int8) string {
+	if i >= 0 && int(i) < len(names) {
+		return names[i]
+	}
+	return strconv.Itoa(int(i))
+}
+
+// parseEnum is the inverse of enumString. Names are matched case-insensitively
+// and numeric values are accepted as well.
+func parseEnum(names []string, kind string, b []byte) (int8, error) {
+	s := string(b)
+
+	for i, name := range names {
+		if strings.EqualFold(s, name) {
+			return int8(i), nil
+		}
+	}
+
+	if i, err := strconv.ParseInt(s, 10, 8); err == nil && i >= 0 && i < int64(len(names)) {
+		return int8(i), nil
+	}
+
+	return 0, fmt.Errorf("%s must be one of %s, not %q", kind, strings.Join(names, ", "), s)
+}
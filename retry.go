@@ -0,0 +1,360 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+	produceAPI "github.com/segmentio/kafka-go/protocol/produce"
+)
+
+// RetryPolicy configures how a Transport retries the requests that fail with
+// errors which are safe to retry, and whether it hedges idempotent requests.
+//
+// The policy applies to each request sent through the transport. Higher level
+// types retry their operations with policies derived from their own
+// configuration: a Writer retries the batches that failed up to
+// WriterConfig.MaxAttempts times, and a Reader retries the commits of consumer
+// group offsets, each of their attempts being retried by the transport
+// according to its policy.
+//
+// The zero value disables retries and hedging, each request is sent once.
+type RetryPolicy struct {
+	// Maximum number of attempts made to send a request, including the first
+	// one. Values lower than 2 disable retries.
+	MaxAttempts int
+
+	// Time limit of each attempt. Attempts which exceed it are abandoned and
+	// may be retried. Zero means that attempts are only bounded by the context
+	// passed to RoundTrip.
+	AttemptTimeout time.Duration
+
+	// Bounds of the backoff delay between attempts, which grows with the
+	// number of attempts.
+	//
+	// Default to 100ms and 1s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Ratio of retries to requests that the transport allows, which prevents
+	// retries from amplifying the load on an overloaded cluster. Each request
+	// earns Budget retries, and each retry or hedged request spends one,
+	// allowing bursts of up to 10 retries. For example, a budget of 0.1 allows
+	// one retry for every ten requests on average.
+	//
+	// Zero means that retries are not limited by a budget.
+	Budget float64
+
+	// An optional function classifying the requests and errors which are safe
+	// to retry.
+	//
+	// The default only retries idempotent requests, like Metadata, Fetch,
+	// ListOffsets, or the Describe and List APIs, as well as produce requests
+	// of idempotent or transactional producers, which the brokers deduplicate.
+	// They are retried on temporary errors, which include the kafka errors
+	// that can be retried and attempts that exceeded AttemptTimeout, as well
+	// as transient network errors. Other requests, like produce requests
+	// without a producer id, may have been applied by the broker when an
+	// attempt fails or times out, retrying them could write the records twice,
+	// so they are never retried by default.
+	//
+	// Produce requests are only retried when their records can be rewound with
+	// protocol.ResetRecordReader, and never after an attempt that exceeded
+	// AttemptTimeout, which may still be reading the records.
+	Retryable func(req Request, err error) bool
+
+	// When positive, the Metadata and ListOffsets requests which did not
+	// complete after HedgeDelay are sent a second time, and the first
+	// response received is returned. Hedging these idempotent requests cuts
+	// the tail latency caused by slow brokers or connections.
+	HedgeDelay time.Duration
+}
+
+func (r *RetryPolicy) enabled() bool {
+	return r.MaxAttempts > 1 || r.AttemptTimeout > 0 || r.HedgeDelay > 0
+}
+
+func (r *RetryPolicy) maxAttempts() int {
+	if r.MaxAttempts > 1 {
+		return r.MaxAttempts
+	}
+	return 1
+}
+
+func (r *RetryPolicy) backoff(attempt int) time.Duration {
+	minBackoff, maxBackoff := 100*time.Millisecond, 1*time.Second
+	if r.MinBackoff > 0 {
+		minBackoff = r.MinBackoff
+	}
+	if r.MaxBackoff > 0 {
+		maxBackoff = r.MaxBackoff
+	}
+	return backoff(attempt, minBackoff, maxBackoff)
+}
+
+// do calls fn until it succeeds or fails with an error that retryable rejects,
+// backing off between attempts. It gives up when the attempts allowed by the
+// policy are exhausted, or when ctx is canceled while backing off, and returns
+// the error of the last attempt. A nil retryable function retries all errors,
+// and onRetry, when not nil, is called before backing off from each retry.
+func (r *RetryPolicy) do(ctx context.Context, clock Clock, retryable func(error) bool, onRetry func(attempt int, delay time.Duration), fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= r.maxAttempts() {
+			return err
+		}
+		if retryable != nil && !retryable(err) {
+			return err
+		}
+		delay := r.backoff(attempt)
+		if onRetry != nil {
+			onRetry(attempt, delay)
+		}
+		if !sleepClock(ctx, clock, delay) {
+			return err
+		}
+	}
+}
+
+func (r *RetryPolicy) retryable(req Request, err error) bool {
+	if r.Retryable != nil {
+		return r.Retryable(req, err)
+	}
+	if !idempotent(req) {
+		return false
+	}
+	return isTemporary(err) || isTransientNetworkError(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// idempotent returns true if sending req more than once has the same effect as
+// sending it once.
+func idempotent(req Request) bool {
+	switch req.ApiKey() {
+	case protocol.Metadata,
+		protocol.Fetch,
+		protocol.ListOffsets,
+		protocol.OffsetFetch,
+		protocol.FindCoordinator,
+		protocol.ApiVersions,
+		protocol.OffsetForLeaderEpoch,
+		protocol.ListGroups,
+		protocol.ListPartitionReassignments,
+		protocol.ListTransactions,
+		protocol.DescribeGroups,
+		protocol.DescribeConfigs,
+		protocol.DescribeAcls,
+		protocol.DescribeLogDirs,
+		protocol.DescribeDelegationToken,
+		protocol.DescribeClientQuotas,
+		protocol.DescribeUserScramCredentials,
+		protocol.DescribeQuorum,
+		protocol.DescribeCluster,
+		protocol.DescribeProducers,
+		protocol.DescribeTransactions,
+		protocol.ConsumerGroupDescribe:
+		return true
+	case protocol.Produce:
+		// The brokers deduplicate the record batches of idempotent and
+		// transactional producers, using their producer id and sequences.
+		r, ok := req.(*produceAPI.Request)
+		if !ok {
+			return false
+		}
+		for _, t := range r.Topics {
+			for _, p := range t.Partitions {
+				if p.RecordSet.Producer == nil {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (r *RetryPolicy) hedges(req Request) bool {
+	if r.HedgeDelay <= 0 {
+		return false
+	}
+	switch req.ApiKey() {
+	case protocol.Metadata, protocol.ListOffsets:
+		return true
+	}
+	return false
+}
+
+// retryBudget is a token bucket limiting the ratio of retries to requests.
+type retryBudget struct {
+	mutex  sync.Mutex
+	init   bool
+	tokens float64
+}
+
+const retryBudgetMaxTokens = 10
+
+func (b *retryBudget) deposit(ratio float64) {
+	if ratio <= 0 {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.fill()
+	if b.tokens += ratio; b.tokens > retryBudgetMaxTokens {
+		b.tokens = retryBudgetMaxTokens
+	}
+}
+
+func (b *retryBudget) withdraw(ratio float64) bool {
+	if ratio <= 0 {
+		return true
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.fill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *retryBudget) fill() {
+	if !b.init {
+		b.init, b.tokens = true, retryBudgetMaxTokens
+	}
+}
+
+// roundTripWithRetries sends req to the cluster, applying the retry policy
+// of the pool.
+func (p *connPool) roundTripWithRetries(ctx context.Context, req Request) (Response, error) {
+	policy := &p.retry
+	if !policy.enabled() {
+		return p.roundTrip(ctx, req)
+	}
+
+	p.budget.deposit(policy.Budget)
+
+	for attempt := 1; ; attempt++ {
+		res, timedOut, err := p.attempt(ctx, req)
+		if err == nil || ctx.Err() != nil || attempt >= policy.maxAttempts() {
+			return res, err
+		}
+		if !policy.retryable(req, err) {
+			return res, err
+		}
+		// Retries of produce requests send the same records, which have been
+		// consumed by the previous attempt.
+		if req.ApiKey() == protocol.Produce && (timedOut || rewindRecords(req) != nil) {
+			return res, err
+		}
+		if !p.budget.withdraw(policy.Budget) {
+			return res, err
+		}
+		if !sleepClock(ctx, clockOrDefault(p.clock), policy.backoff(attempt)) {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// attempt sends req, and reports whether the attempt was abandoned because it
+// exceeded the attempt timeout of the retry policy.
+func (p *connPool) attempt(ctx context.Context, req Request) (Response, bool, error) {
+	attemptCtx := ctx
+	if timeout := p.retry.AttemptTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var res Response
+	var err error
+	if hedge := p.hedge(req); hedge != nil {
+		res, err = p.hedgedRoundTrip(attemptCtx, req, hedge)
+	} else {
+		res, err = p.roundTrip(attemptCtx, req)
+	}
+
+	timedOut := err != nil && attemptCtx.Err() != nil && ctx.Err() == nil
+	return res, timedOut, err
+}
+
+// hedge returns a copy of req to send if the retry policy hedges it, or nil.
+func (p *connPool) hedge(req Request) Request {
+	if !p.retry.hedges(req) {
+		return nil
+	}
+	return copyRequest(req)
+}
+
+// rewindRecords resets the records of the produce request req, so they can be
+// sent again. It returns an error if the records of a partition do not
+// support being reset.
+func rewindRecords(req Request) error {
+	r, ok := req.(*produceAPI.Request)
+	if !ok {
+		return protocol.ErrNoReset
+	}
+	for _, t := range r.Topics {
+		for _, p := range t.Partitions {
+			if records := p.RecordSet.Records; records != nil {
+				if err := protocol.ResetRecordReader(records); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// hedgedRoundTrip sends req, and hedge if no responses were received after
+// the hedging delay. The first successful response is returned, or the last
+// error if both requests failed.
+func (p *connPool) hedgedRoundTrip(ctx context.Context, req, hedge Request) (Response, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		res Response
+		err error
+	}
+
+	results := make(chan result, 2)
+	send := func(req Request) {
+		res, err := p.roundTrip(ctx, req)
+		results <- result{res, err}
+	}
+	go send(req)
+
+	timer := clockOrDefault(p.clock).NewTimer(p.retry.HedgeDelay)
+	defer timer.Stop()
+
+	for pending, hedged := 1, false; ; {
+		select {
+		case r := <-results:
+			if pending--; r.err == nil || pending == 0 {
+				return r.res, r.err
+			}
+		case <-timer.C():
+			if !hedged && p.budget.withdraw(p.retry.Budget) {
+				hedged = true
+				pending++
+				go send(hedge)
+			}
+		}
+	}
+}
+
+// copyRequest returns a shallow copy of req, so it can be sent concurrently
+// with req, or nil if the request cannot be copied.
+func copyRequest(req Request) Request {
+	v := reflect.ValueOf(req)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	c := reflect.New(v.Elem().Type())
+	c.Elem().Set(v.Elem())
+	return c.Interface().(Request)
+}
@@ -0,0 +1,342 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/createtopics"
+	"github.com/segmentio/kafka-go/protocol/describeproducers"
+	metadataAPI "github.com/segmentio/kafka-go/protocol/metadata"
+	produceAPI "github.com/segmentio/kafka-go/protocol/produce"
+)
+
+// newRetryTestPool returns a connection pool which sends requests to the
+// returned channel, through the given number of connections.
+func newRetryTestPool(policy RetryPolicy, numConns int) (*connPool, <-chan connRequest) {
+	ready := make(event)
+	ready.trigger()
+
+	requests := make(chan connRequest, numConns)
+	pool := &connPool{
+		retry: policy,
+		ready: ready,
+		conns: map[int32]*connGroup{},
+	}
+
+	pool.ctrl = &connGroup{pool: pool, broker: Broker{ID: -1}}
+	for i := 0; i < numConns; i++ {
+		pool.ctrl.idleConns = append(pool.ctrl.idleConns, &conn{reqs: requests})
+	}
+
+	return pool, requests
+}
+
+func TestRetryPolicy(t *testing.T) {
+	errFatal := errors.New("fatal")
+
+	tests := []struct {
+		scenario string
+		policy   RetryPolicy
+		errors   []error
+		attempts int
+		err      error
+	}{
+		{
+			scenario: "requests are sent once by default",
+			errors:   []error{io.ErrUnexpectedEOF},
+			attempts: 1,
+			err:      io.ErrUnexpectedEOF,
+		},
+		{
+			scenario: "transient errors are retried",
+			policy:   RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond},
+			errors:   []error{io.ErrUnexpectedEOF, RequestTimedOut},
+			attempts: 3,
+		},
+		{
+			scenario: "attempts are bounded",
+			policy:   RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond},
+			errors:   []error{io.ErrUnexpectedEOF, io.ErrUnexpectedEOF},
+			attempts: 2,
+			err:      io.ErrUnexpectedEOF,
+		},
+		{
+			scenario: "fatal errors are not retried",
+			policy:   RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond},
+			errors:   []error{errFatal},
+			attempts: 1,
+			err:      errFatal,
+		},
+		{
+			scenario: "errors are classified by the policy",
+			policy: RetryPolicy{
+				MaxAttempts: 3,
+				MinBackoff:  time.Millisecond,
+				Retryable:   func(req Request, err error) bool { return errors.Is(err, errFatal) },
+			},
+			errors:   []error{errFatal},
+			attempts: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			pool, requests := newRetryTestPool(test.policy, 3)
+
+			attempts := 0
+			go func() {
+				for r := range requests {
+					if attempts++; attempts <= len(test.errors) {
+						r.res.reject(test.errors[attempts-1])
+					} else {
+						r.res.resolve(&apiversions.Response{})
+					}
+				}
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			_, err := pool.roundTripWithRetries(ctx, &apiversions.Request{})
+			if !errors.Is(err, test.err) || (err != nil) != (test.err != nil) {
+				t.Errorf("wrong error: want=%v got=%v", test.err, err)
+			}
+			if len(pool.ctrl.idleConns) != 3-test.attempts {
+				t.Errorf("wrong number of attempts: want=%d got=%d", test.attempts, 3-len(pool.ctrl.idleConns))
+			}
+		})
+	}
+}
+
+func TestRetryPolicyIdempotence(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}
+
+	produceRequest := func(producer *protocol.ProducerState) *produceAPI.Request {
+		return &produceAPI.Request{
+			Topics: []produceAPI.RequestTopic{{
+				Topic: "topic-A",
+				Partitions: []produceAPI.RequestPartition{
+					{Partition: 0, RecordSet: protocol.RecordSet{Producer: producer}},
+				},
+			}},
+		}
+	}
+
+	tests := []struct {
+		scenario  string
+		request   Request
+		retryable bool
+	}{
+		{
+			scenario:  "metadata requests are retried",
+			request:   &metadataAPI.Request{},
+			retryable: true,
+		},
+		{
+			scenario:  "describe requests are retried",
+			request:   &describeproducers.Request{},
+			retryable: true,
+		},
+		{
+			scenario: "produce requests are not retried",
+			request:  produceRequest(nil),
+		},
+		{
+			scenario:  "produce requests of idempotent producers are retried",
+			request:   produceRequest(&protocol.ProducerState{ID: 1, Epoch: 0}),
+			retryable: true,
+		},
+		{
+			scenario: "requests which are not idempotent are not retried",
+			request:  &createtopics.Request{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			if retryable := policy.retryable(test.request, io.ErrUnexpectedEOF); retryable != test.retryable {
+				t.Errorf("wrong classification: want=%t got=%t", test.retryable, retryable)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyProduce(t *testing.T) {
+	idempotentProducer := &protocol.ProducerState{ID: 1, Epoch: 0}
+
+	produceRequest := func(records protocol.RecordReader, producer *protocol.ProducerState) *produceAPI.Request {
+		return &produceAPI.Request{
+			Topics: []produceAPI.RequestTopic{{
+				Topic: "topic-A",
+				Partitions: []produceAPI.RequestPartition{{
+					Partition: 0,
+					RecordSet: protocol.RecordSet{
+						Records:  records,
+						Producer: producer,
+					},
+				}},
+			}},
+		}
+	}
+
+	tests := []struct {
+		scenario string
+		policy   RetryPolicy
+		records  protocol.RecordReader
+		producer *protocol.ProducerState
+		err      error
+		delay    time.Duration
+		attempts int
+	}{
+		{
+			scenario: "records which can be reset are sent again",
+			policy:   RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond},
+			records:  &writerRecords{msgs: []Message{{Value: []byte("A")}, {Value: []byte("B")}}},
+			producer: idempotentProducer,
+			err:      io.ErrUnexpectedEOF,
+			attempts: 2,
+		},
+		{
+			scenario: "records which cannot be reset are not retried",
+			policy:   RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond},
+			records:  protocol.NewRecordBatchReader(new(bytes.Buffer)),
+			producer: idempotentProducer,
+			err:      io.ErrUnexpectedEOF,
+			attempts: 1,
+		},
+		{
+			scenario: "attempts which timed out are not retried",
+			policy:   RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, AttemptTimeout: 10 * time.Millisecond},
+			records:  &writerRecords{msgs: []Message{{Value: []byte("A")}, {Value: []byte("B")}}},
+			producer: idempotentProducer,
+			err:      io.ErrUnexpectedEOF,
+			delay:    50 * time.Millisecond,
+			attempts: 1,
+		},
+		{
+			scenario: "requests without a producer id are not retried on network errors",
+			policy:   RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond},
+			records:  &writerRecords{msgs: []Message{{Value: []byte("A")}, {Value: []byte("B")}}},
+			err:      syscall.ECONNRESET,
+			attempts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			pool, requests := newRetryTestPool(test.policy, 3)
+			pool.setState(connPoolState{
+				layout: protocol.Cluster{
+					Brokers: map[int32]protocol.Broker{0: {ID: 0}},
+					Topics: map[string]protocol.Topic{
+						"topic-A": {Name: "topic-A", Partitions: map[int32]protocol.Partition{0: {Leader: 0}}},
+					},
+				},
+			})
+			pool.conns[0] = pool.ctrl
+
+			attempts := 0
+			go func() {
+				for r := range requests {
+					records := r.req.(*produceAPI.Request).Topics[0].Partitions[0].RecordSet.Records
+					count := 0
+					for {
+						if _, err := records.ReadRecord(); err != nil {
+							break
+						}
+						count++
+					}
+					if attempts++; attempts == 1 {
+						time.Sleep(test.delay)
+						r.res.reject(test.err)
+					} else if count != 2 {
+						r.res.reject(fmt.Errorf("attempt %d read %d records", attempts, count))
+					} else {
+						r.res.resolve(&produceAPI.Response{})
+					}
+				}
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			_, err := pool.roundTripWithRetries(ctx, produceRequest(test.records, test.producer))
+			if (err == nil) != (test.attempts > 1) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if len(pool.ctrl.idleConns) != 3-test.attempts {
+				t.Errorf("wrong number of attempts: want=%d got=%d", test.attempts, 3-len(pool.ctrl.idleConns))
+			}
+		})
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	b := retryBudget{}
+
+	for i := 0; i < retryBudgetMaxTokens; i++ {
+		if !b.withdraw(0.5) {
+			t.Fatalf("retry %d should have been allowed by the initial budget", i)
+		}
+	}
+	if b.withdraw(0.5) {
+		t.Fatal("retries should not be allowed after the budget was spent")
+	}
+
+	b.deposit(0.5)
+	if b.withdraw(0.5) {
+		t.Fatal("retries should not be allowed before requests earned a whole retry")
+	}
+	b.deposit(0.5)
+	if !b.withdraw(0.5) {
+		t.Fatal("retries should be allowed after requests earned a whole retry")
+	}
+
+	if !b.withdraw(0) {
+		t.Fatal("retries should always be allowed without a budget")
+	}
+}
+
+type hedgedRequest struct{ topic string }
+
+func (r *hedgedRequest) ApiKey() protocol.ApiKey { return protocol.ListOffsets }
+
+func TestRetryPolicyHedging(t *testing.T) {
+	pool, requests := newRetryTestPool(RetryPolicy{HedgeDelay: 10 * time.Millisecond}, 2)
+
+	slow := &hedgedRequest{topic: "A"}
+	hedge := copyRequest(slow)
+	if hedge == Request(slow) || !reflect.DeepEqual(hedge, slow) {
+		t.Fatalf("the hedged request must be a copy: %+v", hedge)
+	}
+
+	go func() {
+		first := <-requests
+		if first.req != Request(slow) {
+			t.Error("the original request was not sent first")
+		}
+		second := <-requests
+		second.res.resolve(&apiversions.Response{ErrorCode: 42})
+		<-first.ctx.Done() // the slow request is abandoned
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	r, err := pool.hedgedRoundTrip(ctx, slow, hedge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := r.(*apiversions.Response); res.ErrorCode != 42 {
+		t.Errorf("the response of the hedged request was not returned: %+v", res)
+	}
+}
@@ -0,0 +1,334 @@
+// Package retrytopic implements non-blocking retries of kafka messages with
+// tiers of retry topics and a dead letter topic.
+//
+// Messages which fail to be handled are produced to the retry topic of the
+// first tier, with a header carrying the time before which they must not be
+// handled again. The consumer of each tier waits until that time before
+// handling the messages, and produces the messages which fail again to the
+// next tier. Messages which failed in the last tier are produced to the dead
+// letter topic. The consumption of the base topic is never blocked by failing
+// messages.
+//
+// This package does not make any promises around backwards compatibility.
+package retrytopic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Names of the headers that the consumer sets on the messages produced to
+// the retry and dead letter topics.
+const (
+	// The number of times the message failed to be handled.
+	HeaderAttempt = "retry-attempt"
+	// The time in milliseconds since the unix epoch before which the message
+	// must not be handled again.
+	HeaderNotBefore = "retry-not-before"
+	// The topic, partition, and offset of the message in the base topic.
+	HeaderTopic     = "retry-original-topic"
+	HeaderPartition = "retry-original-partition"
+	HeaderOffset    = "retry-original-offset"
+	// The error returned by the handler on the last attempt.
+	HeaderError = "retry-error"
+)
+
+// DefaultTiers are the delays of the retry tiers used when none are
+// configured.
+var DefaultTiers = []time.Duration{5 * time.Second, 1 * time.Minute, 10 * time.Minute}
+
+// Handler is the type of functions called to handle messages. Returning an
+// error schedules the message to be retried in the next tier.
+type Handler func(ctx context.Context, msg kafka.Message) error
+
+// Consumer consumes a base topic and its retry topics.
+//
+// Each topic is consumed by a consumer group: the base topic by GroupID, and
+// the retry topic of each tier by GroupID suffixed with ".retry.<tier>". The
+// offset of a message is committed after the message was handled, or after it
+// was produced to the next tier, which gives at-least-once semantics.
+type Consumer struct {
+	// The list of broker addresses used to connect to the kafka cluster.
+	//
+	// This field is required.
+	Brokers []string
+
+	// The consumer group consuming the base topic.
+	//
+	// This field is required.
+	GroupID string
+
+	// The base topic.
+	//
+	// This field is required.
+	Topic string
+
+	// The delays of the retry tiers, which must be increasing.
+	//
+	// Default: DefaultTiers
+	Tiers []time.Duration
+
+	// A function returning the name of the retry topic of a tier, numbered
+	// from 1.
+	//
+	// Default: "<topic>.retry.<tier>"
+	RetryTopic func(topic string, tier int) string
+
+	// The topic that messages which failed in all tiers are produced to.
+	//
+	// Default: "<topic>.dlq"
+	DeadLetterTopic string
+
+	// The writer used to produce messages to the retry and dead letter
+	// topics. Its Topic field must be empty.
+	//
+	// This field is required.
+	Writer *kafka.Writer
+
+	// The function called to handle messages.
+	//
+	// This field is required.
+	Handler Handler
+
+	// The dialer used by the readers of the topics.
+	Dialer *kafka.Dialer
+
+	// Maximum amount of time that the readers wait for new messages.
+	MaxWait time.Duration
+
+	// The clock used to wait for the delay of messages to elapse.
+	//
+	// Default: kafka.SystemClock
+	Clock kafka.Clock
+
+	// An optional function called when a message was produced to the dead
+	// letter topic.
+	OnDeadLetter func(msg kafka.Message, err error)
+}
+
+// Permanent wraps err to report that a message must not be retried, the
+// consumer produces the message to the dead letter topic immediately.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Run consumes the base topic and the retry topics until ctx is canceled, in
+// which case it returns the context error, or until producing a message to a
+// retry topic fails.
+func (c *Consumer) Run(ctx context.Context) error {
+	switch {
+	case len(c.Brokers) == 0:
+		return errors.New("retrytopic.(*Consumer).Run: at least one broker is required")
+	case c.GroupID == "":
+		return errors.New("retrytopic.(*Consumer).Run: a group id is required")
+	case c.Topic == "":
+		return errors.New("retrytopic.(*Consumer).Run: a topic is required")
+	case c.Writer == nil:
+		return errors.New("retrytopic.(*Consumer).Run: a writer is required")
+	case c.Writer.Topic != "":
+		return errors.New("retrytopic.(*Consumer).Run: the writer must not have a topic")
+	case c.Handler == nil:
+		return errors.New("retrytopic.(*Consumer).Run: a handler is required")
+	}
+
+	tiers := c.tiers()
+	for i := 1; i < len(tiers); i++ {
+		if tiers[i] < tiers[i-1] {
+			return fmt.Errorf("retrytopic.(*Consumer).Run: the delays of tiers must be increasing: %v < %v", tiers[i], tiers[i-1])
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(tiers)+1)
+	wg := sync.WaitGroup{}
+
+	for tier := 0; tier <= len(tiers); tier++ {
+		wg.Add(1)
+		go func(tier int) {
+			defer wg.Done()
+			if err := c.consume(ctx, tier); err != nil {
+				errs <- err
+				cancel()
+			}
+		}(tier)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	// The first error is the one which canceled the other consumers.
+	for err := range errs {
+		return err
+	}
+	return ctx.Err()
+}
+
+// TopicOfTier returns the name of the topic consumed in tier, which is the
+// base topic for tier 0, and the retry topics for the following tiers.
+func (c *Consumer) TopicOfTier(tier int) string {
+	if tier == 0 {
+		return c.Topic
+	}
+	if c.RetryTopic != nil {
+		return c.RetryTopic(c.Topic, tier)
+	}
+	return fmt.Sprintf("%s.retry.%d", c.Topic, tier)
+}
+
+func (c *Consumer) deadLetterTopic() string {
+	if c.DeadLetterTopic != "" {
+		return c.DeadLetterTopic
+	}
+	return c.Topic + ".dlq"
+}
+
+func (c *Consumer) tiers() []time.Duration {
+	if len(c.Tiers) != 0 {
+		return c.Tiers
+	}
+	return DefaultTiers
+}
+
+func (c *Consumer) clock() kafka.Clock {
+	if c.Clock != nil {
+		return c.Clock
+	}
+	return kafka.SystemClock
+}
+
+func (c *Consumer) consume(ctx context.Context, tier int) error {
+	groupID := c.GroupID
+	if tier != 0 {
+		groupID = fmt.Sprintf("%s.retry.%d", c.GroupID, tier)
+	}
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: c.Brokers,
+		GroupID: groupID,
+		Topic:   c.TopicOfTier(tier),
+		Dialer:  c.Dialer,
+		MaxWait: c.MaxWait,
+	})
+	defer r.Close()
+
+	for {
+		msg, err := r.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if notBefore, ok := headerInt(msg, HeaderNotBefore); ok {
+			if !c.sleepUntil(ctx, time.Unix(0, notBefore*int64(time.Millisecond))) {
+				return nil
+			}
+		}
+
+		if err := c.Handler(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err := c.retry(ctx, tier, msg, err); err != nil {
+				return err
+			}
+		}
+
+		if err := r.CommitMessages(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// retry produces msg, which failed with cause in tier, to the next tier or to
+// the dead letter topic.
+func (c *Consumer) retry(ctx context.Context, tier int, msg kafka.Message, cause error) error {
+	tiers := c.tiers()
+	attempt, _ := headerInt(msg, HeaderAttempt)
+	attempt++
+
+	retry := kafka.Message{
+		Key:   msg.Key,
+		Value: msg.Value,
+	}
+	for _, h := range msg.Headers {
+		switch h.Key {
+		case HeaderAttempt, HeaderNotBefore, HeaderError:
+		default:
+			retry.Headers = append(retry.Headers, h)
+		}
+	}
+	if tier == 0 {
+		retry.SetHeader(HeaderTopic, []byte(msg.Topic))
+		retry.SetHeader(HeaderPartition, []byte(strconv.Itoa(msg.Partition)))
+		retry.SetHeader(HeaderOffset, []byte(strconv.FormatInt(msg.Offset, 10)))
+	}
+	retry.SetHeader(HeaderAttempt, []byte(strconv.FormatInt(attempt, 10)))
+	retry.SetHeader(HeaderError, []byte(cause.Error()))
+
+	var permanent *permanentError
+	deadLetter := tier >= len(tiers) || errors.As(cause, &permanent)
+
+	if deadLetter {
+		retry.Topic = c.deadLetterTopic()
+	} else {
+		// The time is rounded up to the next millisecond so the message is
+		// never handled before the delay of the tier elapsed.
+		notBefore := c.clock().Now().Add(tiers[tier]).UnixNano()
+		notBefore = (notBefore + int64(time.Millisecond) - 1) / int64(time.Millisecond)
+		retry.Topic = c.TopicOfTier(tier + 1)
+		retry.SetHeader(HeaderNotBefore, []byte(strconv.FormatInt(notBefore, 10)))
+	}
+
+	if err := c.Writer.WriteMessages(ctx, retry); err != nil {
+		return fmt.Errorf("producing the message at offset %d of %s/%d to %s: %w", msg.Offset, msg.Topic, msg.Partition, retry.Topic, err)
+	}
+
+	if deadLetter && c.OnDeadLetter != nil {
+		c.OnDeadLetter(retry, cause)
+	}
+	return nil
+}
+
+func (c *Consumer) sleepUntil(ctx context.Context, t time.Time) bool {
+	clock := c.clock()
+	d := t.Sub(clock.Now())
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func headerInt(msg kafka.Message, key string) (int64, bool) {
+	value, ok := msg.Header(key)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(string(value), 10, 64)
+	return n, err == nil
+}
@@ -0,0 +1,138 @@
+package retrytopic
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func TestConsumer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t)
+	for _, topic := range []string{"orders", "orders.retry.1", "orders.retry.2", "orders.dlq"} {
+		if err := b.CreateTopic(topic, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, value := range []string{"good", "flaky", "bad", "fatal"} {
+		if _, err := b.Append("orders", 0, kafkatest.Record{Key: []byte(value), Value: []byte(value)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mutex := sync.Mutex{}
+	attempts := make(map[string][]time.Time)
+
+	c := &Consumer{
+		Brokers: []string{b.Addr().String()},
+		GroupID: "group-A",
+		Topic:   "orders",
+		Tiers:   []time.Duration{20 * time.Millisecond, 40 * time.Millisecond},
+		Writer: &kafka.Writer{
+			Addr:         b.Addr(),
+			BatchTimeout: time.Millisecond,
+			RequiredAcks: kafka.RequireAll,
+		},
+		MaxWait: 10 * time.Millisecond,
+		Handler: func(ctx context.Context, msg kafka.Message) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			value := string(msg.Value)
+			attempts[value] = append(attempts[value], time.Now())
+
+			switch {
+			case value == "bad":
+				return errors.New("bad message")
+			case value == "fatal":
+				return Permanent(errors.New("fatal message"))
+			case value == "flaky" && len(attempts[value]) == 1:
+				return errors.New("flaky message")
+			}
+			return nil
+		},
+	}
+	defer c.Writer.Close()
+
+	errs := make(chan error, 1)
+	go func() { errs <- c.Run(ctx) }()
+
+	for len(b.Records("orders.dlq", 0)) < 2 {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case err := <-errs:
+			t.Fatal(err)
+		}
+	}
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v but got %v", context.Canceled, err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	for value, n := range map[string]int{"good": 1, "flaky": 2, "bad": 3, "fatal": 1} {
+		if len(attempts[value]) != n {
+			t.Errorf("wrong number of attempts for %s: %d", value, len(attempts[value]))
+		}
+	}
+	for i, delay := range c.Tiers {
+		if d := attempts["bad"][i+1].Sub(attempts["bad"][i]); d < delay {
+			t.Errorf("message retried after %v in tier %d, expected at least %v", d, i+1, delay)
+		}
+	}
+
+	if n := len(b.Records("orders.retry.1", 0)); n != 2 {
+		t.Errorf("wrong number of messages in the first tier: %d", n)
+	}
+	if n := len(b.Records("orders.retry.2", 0)); n != 1 {
+		t.Errorf("wrong number of messages in the second tier: %d", n)
+	}
+
+	for _, record := range b.Records("orders.dlq", 0) {
+		headers := make(map[string]string)
+		for _, h := range record.Headers {
+			headers[h.Key] = string(h.Value)
+		}
+		switch string(record.Value) {
+		case "bad":
+			if headers[HeaderAttempt] != "3" || headers[HeaderError] != "bad message" || headers[HeaderOffset] != "2" {
+				t.Errorf("wrong headers: %v", headers)
+			}
+		case "fatal":
+			if headers[HeaderAttempt] != "1" || headers[HeaderTopic] != "orders" || headers[HeaderOffset] != "3" {
+				t.Errorf("wrong headers: %v", headers)
+			}
+		default:
+			t.Errorf("unexpected message in the dead letter topic: %q", record.Value)
+		}
+	}
+}
+
+func TestConsumerConfig(t *testing.T) {
+	c := &Consumer{
+		Brokers: []string{"localhost:9092"},
+		GroupID: "group-A",
+		Topic:   "orders",
+		Tiers:   []time.Duration{time.Minute, time.Second},
+		Writer:  &kafka.Writer{},
+		Handler: func(context.Context, kafka.Message) error { return nil },
+	}
+	if err := c.Run(context.Background()); err == nil {
+		t.Error("expected decreasing tiers to be rejected")
+	}
+
+	if topic := c.TopicOfTier(2); topic != "orders.retry.2" {
+		t.Errorf("wrong topic: %s", topic)
+	}
+	c.RetryTopic = func(topic string, tier int) string { return topic + "-retry" }
+	if topic := c.TopicOfTier(1); topic != "orders-retry" {
+		t.Errorf("wrong topic: %s", topic)
+	}
+}
@@ -0,0 +1,182 @@
+// Package gssapi implements the GSSAPI SASL mechanism (RFC 4752), which
+// authenticates clients with Kerberos.
+//
+// The package does not implement Kerberos itself, the security contexts are
+// established by a Provider, which programs implement with the Kerberos
+// library of their choice, for example github.com/jcmturner/gokrb5 or the
+// system GSSAPI library through cgo.
+package gssapi
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+// Provider is an interface implemented by Kerberos libraries to establish
+// security contexts with kafka brokers.
+//
+// Providers may be called concurrently from multiple goroutines.
+type Provider interface {
+	// InitSecContext creates a security context for authenticating with the
+	// service principal named by service and host, for example "kafka" and
+	// "broker-1.example.com" for the principal kafka/broker-1.example.com.
+	InitSecContext(ctx context.Context, service, host string) (SecurityContext, error)
+}
+
+// SecurityContext is the client side of a GSSAPI security context, it wraps
+// the gss_init_sec_context, gss_wrap, and gss_unwrap functions of RFC 2743.
+//
+// Security contexts are used by a single connection, they do not need to be
+// safe for concurrent use.
+type SecurityContext interface {
+	// Step processes the token received from the broker, which is nil on the
+	// first call, and returns the token to send back. It returns true once
+	// the context is established, the output token of the last step must
+	// still be sent to the broker.
+	//
+	// Contexts are expected to request mutual authentication.
+	Step(ctx context.Context, token []byte) (output []byte, established bool, err error)
+
+	// Wrap and Unwrap apply and verify the integrity protection of messages
+	// exchanged once the context was established.
+	Wrap(msg []byte) ([]byte, error)
+	Unwrap(token []byte) ([]byte, error)
+
+	// Close releases the resources of the context.
+	Close() error
+}
+
+// Mechanism implements the GSSAPI mechanism.
+type Mechanism struct {
+	// The Kerberos provider establishing the security contexts, it must not
+	// be nil.
+	Provider Provider
+
+	// The primary of the service principal of brokers, it must match the
+	// sasl.kerberos.service.name setting of the brokers. Defaults to "kafka".
+	ServiceName string
+
+	// Optional identity to act as, which brokers authorize in place of the
+	// authenticated principal.
+	AuthorizationID string
+}
+
+// Name satisfies the sasl.Mechanism interface.
+func (m *Mechanism) Name() string {
+	return "GSSAPI"
+}
+
+// Start satisfies the sasl.Mechanism interface, it establishes a security
+// context with the broker that the connection is authenticating with.
+func (m *Mechanism) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	meta := sasl.MetadataFromContext(ctx)
+	if meta == nil {
+		return nil, nil, errors.New("gssapi: missing sasl metadata")
+	}
+	if m.Provider == nil {
+		return nil, nil, errors.New("gssapi: no Kerberos provider configured")
+	}
+
+	service := m.ServiceName
+	if service == "" {
+		service = "kafka"
+	}
+
+	// The host of the service principal is the canonical name of the broker,
+	// DNS names are case insensitive but Kerberos principals are not.
+	host := strings.ToLower(strings.TrimSuffix(meta.Host, "."))
+
+	secctx, err := m.Provider.InitSecContext(ctx, service, host)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gssapi: initializing the security context of %s/%s: %w", service, host, err)
+	}
+
+	s := &session{mechanism: m, secctx: secctx}
+	ir, err := s.step(ctx, nil)
+	if err != nil {
+		secctx.Close()
+		return nil, nil, err
+	}
+	if ir == nil {
+		ir = []byte{}
+	}
+	return s, ir, nil
+}
+
+// Security layers of RFC 4752 section 3.3, brokers only support the absence
+// of security layer since messages are protected by TLS.
+const noSecurityLayer = 1
+
+type session struct {
+	mechanism   *Mechanism
+	secctx      SecurityContext
+	established bool
+	negotiated  bool
+}
+
+func (s *session) Next(ctx context.Context, challenge []byte) (bool, []byte, error) {
+	switch {
+	case !s.established:
+		res, err := s.step(ctx, challenge)
+		if err != nil {
+			s.secctx.Close()
+		}
+		return false, res, err
+
+	case !s.negotiated:
+		res, err := s.negotiate(challenge)
+		s.negotiated = true
+		if err != nil {
+			s.secctx.Close()
+		}
+		return false, res, err
+
+	default:
+		// kafka will return error if it rejected the authentication, so we'd
+		// only arrive here on success.
+		return true, nil, s.secctx.Close()
+	}
+}
+
+func (s *session) step(ctx context.Context, token []byte) ([]byte, error) {
+	res, established, err := s.secctx.Step(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("gssapi: establishing the security context: %w", err)
+	}
+	s.established = established
+	if res == nil {
+		res = []byte{}
+	}
+	return res, nil
+}
+
+// negotiate processes the security layers offered by the broker once the
+// context was established, and returns the wrapped selection of the client.
+func (s *session) negotiate(challenge []byte) ([]byte, error) {
+	msg, err := s.secctx.Unwrap(challenge)
+	if err != nil {
+		return nil, fmt.Errorf("gssapi: unwrapping the security layers offered by the broker: %w", err)
+	}
+	if len(msg) != 4 {
+		return nil, fmt.Errorf("gssapi: the security layers offered by the broker must be 4 bytes long, got %d", len(msg))
+	}
+	if msg[0]&noSecurityLayer == 0 {
+		return nil, fmt.Errorf("gssapi: the broker requires unsupported security layers (%#x)", msg[0])
+	}
+
+	// The maximum message size is zero since no security layer is used.
+	res := make([]byte, 4, 4+len(s.mechanism.AuthorizationID))
+	binary.BigEndian.PutUint32(res, noSecurityLayer<<24)
+	res = append(res, s.mechanism.AuthorizationID...)
+
+	wrapped, err := s.secctx.Wrap(res)
+	if err != nil {
+		return nil, fmt.Errorf("gssapi: wrapping the security layer selected by the client: %w", err)
+	}
+	return wrapped, nil
+}
@@ -0,0 +1,119 @@
+package gssapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+// fakeProvider establishes security contexts in two steps, emulating mutual
+// authentication, and wraps messages by prefixing them with "wrap:".
+type fakeProvider struct {
+	service, host string
+	closed        bool
+}
+
+func (p *fakeProvider) InitSecContext(ctx context.Context, service, host string) (SecurityContext, error) {
+	p.service, p.host = service, host
+	return p, nil
+}
+
+func (p *fakeProvider) Step(ctx context.Context, token []byte) ([]byte, bool, error) {
+	switch string(token) {
+	case "":
+		return []byte("AP-REQ"), false, nil
+	case "AP-REP":
+		return nil, true, nil
+	default:
+		return nil, false, errors.New("unexpected token")
+	}
+}
+
+func (p *fakeProvider) Wrap(msg []byte) ([]byte, error) {
+	return append([]byte("wrap:"), msg...), nil
+}
+
+func (p *fakeProvider) Unwrap(token []byte) ([]byte, error) {
+	if !bytes.HasPrefix(token, []byte("wrap:")) {
+		return nil, errors.New("invalid token")
+	}
+	return token[5:], nil
+}
+
+func (p *fakeProvider) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestMechanism(t *testing.T) {
+	provider := &fakeProvider{}
+	m := &Mechanism{Provider: provider, AuthorizationID: "admin"}
+
+	if _, _, err := m.Start(context.Background()); err == nil {
+		t.Error("authentication should fail without sasl metadata")
+	}
+
+	ctx := sasl.WithMetadata(context.Background(), &sasl.Metadata{Host: "Broker-1.example.com.", Port: 9092})
+	sess, ir, err := m.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(ir) != "AP-REQ" {
+		t.Errorf("wrong initial response: %q", ir)
+	}
+	if provider.service != "kafka" || provider.host != "broker-1.example.com" {
+		t.Errorf("wrong service principal: %s/%s", provider.service, provider.host)
+	}
+
+	steps := []struct {
+		challenge string
+		response  string
+		done      bool
+	}{
+		{challenge: "AP-REP", response: ""},
+		{challenge: "wrap:\x07\x00\x10\x00", response: "wrap:\x01\x00\x00\x00admin"},
+		{challenge: "", done: true},
+	}
+
+	for _, step := range steps {
+		done, res, err := sess.Next(ctx, []byte(step.challenge))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done != step.done || string(res) != step.response {
+			t.Errorf("wrong response to %q: want=%q (done=%t) got=%q (done=%t)", step.challenge, step.response, step.done, res, done)
+		}
+	}
+
+	if !provider.closed {
+		t.Error("the security context was not closed")
+	}
+}
+
+func TestMechanismUnsupportedSecurityLayers(t *testing.T) {
+	provider := &fakeProvider{}
+	m := &Mechanism{Provider: provider, ServiceName: "custom"}
+
+	ctx := sasl.WithMetadata(context.Background(), &sasl.Metadata{Host: "localhost", Port: 9092})
+	sess, _, err := m.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.service != "custom" {
+		t.Errorf("wrong service name: %s", provider.service)
+	}
+
+	if _, _, err := sess.Next(ctx, []byte("AP-REP")); err != nil {
+		t.Fatal(err)
+	}
+	// Only integrity and confidentiality layers are offered.
+	if _, _, err := sess.Next(ctx, []byte("wrap:\x06\x00\x10\x00")); err == nil {
+		t.Error("the negotiation should fail when the broker requires a security layer")
+	}
+	if !provider.closed {
+		t.Error("the security context was not closed")
+	}
+}
@@ -0,0 +1,305 @@
+package mskiam
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials are the AWS credentials used to sign authentication requests.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// The time at which temporary credentials expire, zero for credentials
+	// which do not expire.
+	Expires time.Time
+}
+
+// CredentialsProvider is an interface implemented by types that retrieve AWS
+// credentials.
+//
+// Providers may be called concurrently from multiple goroutines.
+type CredentialsProvider interface {
+	Retrieve(ctx context.Context) (Credentials, error)
+}
+
+// CredentialsProviderFunc is an implementation of the CredentialsProvider
+// interface for functions.
+type CredentialsProviderFunc func(ctx context.Context) (Credentials, error)
+
+// Retrieve satisfies the CredentialsProvider interface.
+func (f CredentialsProviderFunc) Retrieve(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// ErrNoCredentials is returned by providers which are not configured in the
+// environment of the program, the providers of a chain which return this
+// error are skipped silently.
+var ErrNoCredentials = errors.New("mskiam: no credentials found")
+
+// StaticCredentials is a CredentialsProvider which always returns the same
+// credentials.
+type StaticCredentials Credentials
+
+// Retrieve satisfies the CredentialsProvider interface.
+func (c StaticCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+	return Credentials(c), nil
+}
+
+// EnvCredentials is a CredentialsProvider which reads the credentials from the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN environment
+// variables.
+type EnvCredentials struct{}
+
+// Retrieve satisfies the CredentialsProvider interface.
+func (EnvCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	return StaticCredentials{
+		AccessKeyID:     getenv("AWS_ACCESS_KEY_ID", "AWS_ACCESS_KEY"),
+		SecretAccessKey: getenv("AWS_SECRET_ACCESS_KEY", "AWS_SECRET_KEY"),
+		SessionToken:    getenv("AWS_SESSION_TOKEN"),
+	}.Retrieve(ctx)
+}
+
+// SharedCredentials is a CredentialsProvider which reads the credentials of a
+// profile from the shared credentials and config files of the AWS CLI.
+//
+// Profiles of the config file that set role_arn assume the role, using the
+// credentials of the profile named by source_profile, or the web identity
+// token of web_identity_token_file.
+type SharedCredentials struct {
+	// Path to the credentials and config files, they default to the values
+	// of the AWS_SHARED_CREDENTIALS_FILE and AWS_CONFIG_FILE environment
+	// variables, or ~/.aws/credentials and ~/.aws/config.
+	CredentialsFile string
+	ConfigFile      string
+
+	// The profile to read, defaults to the value of the AWS_PROFILE
+	// environment variable, or "default".
+	Profile string
+}
+
+// Retrieve satisfies the CredentialsProvider interface.
+func (s SharedCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	home, _ := os.UserHomeDir()
+
+	credentialsFile := s.CredentialsFile
+	if credentialsFile == "" {
+		if credentialsFile = os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); credentialsFile == "" && home != "" {
+			credentialsFile = filepath.Join(home, ".aws", "credentials")
+		}
+	}
+
+	configFile := s.ConfigFile
+	if configFile == "" {
+		if configFile = os.Getenv("AWS_CONFIG_FILE"); configFile == "" && home != "" {
+			configFile = filepath.Join(home, ".aws", "config")
+		}
+	}
+
+	profile := s.Profile
+	if profile == "" {
+		if profile = os.Getenv("AWS_PROFILE"); profile == "" {
+			profile = "default"
+		}
+	}
+
+	credentials, err := readINIFile(credentialsFile)
+	if err != nil {
+		return Credentials{}, err
+	}
+	config, err := readINIFile(configFile)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	return sharedProfile(ctx, credentials, config, profile, 0)
+}
+
+func sharedProfile(ctx context.Context, credentials, config map[string]map[string]string, profile string, depth int) (Credentials, error) {
+	if depth > 8 {
+		return Credentials{}, fmt.Errorf("mskiam: too many levels of source profiles at %q", profile)
+	}
+
+	section := config["profile "+profile]
+	if profile == "default" && section == nil {
+		section = config["default"]
+	}
+
+	if roleARN := section["role_arn"]; roleARN != "" {
+		role := AssumeRoleCredentials{
+			RoleARN:     roleARN,
+			SessionName: section["role_session_name"],
+			ExternalID:  section["external_id"],
+			Region:      section["region"],
+		}
+
+		switch {
+		case section["web_identity_token_file"] != "":
+			return WebIdentityCredentials{
+				RoleARN:     roleARN,
+				TokenFile:   section["web_identity_token_file"],
+				SessionName: section["role_session_name"],
+				Region:      section["region"],
+			}.Retrieve(ctx)
+
+		case section["source_profile"] != "" && section["source_profile"] != profile:
+			source, err := sharedProfile(ctx, credentials, config, section["source_profile"], depth+1)
+			if err != nil {
+				return Credentials{}, err
+			}
+			role.Source = StaticCredentials(source)
+			return role.Retrieve(ctx)
+		}
+	}
+
+	for _, s := range []map[string]string{credentials[profile], section} {
+		if c, err := (StaticCredentials{
+			AccessKeyID:     s["aws_access_key_id"],
+			SecretAccessKey: s["aws_secret_access_key"],
+			SessionToken:    s["aws_session_token"],
+		}).Retrieve(ctx); err == nil {
+			return c, nil
+		}
+	}
+
+	return Credentials{}, ErrNoCredentials
+}
+
+// readINIFile parses the sections of the INI file at path, a missing file is
+// not an error.
+func readINIFile(path string) (map[string]map[string]string, error) {
+	sections := make(map[string]map[string]string)
+	if path == "" {
+		return sections, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sections, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var section map[string]string
+	s := bufio.NewScanner(f)
+
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case line == "", line[0] == '#', line[0] == ';':
+		case line[0] == '[' && line[len(line)-1] == ']':
+			name := strings.Join(strings.Fields(line[1:len(line)-1]), " ")
+			if section = sections[name]; section == nil {
+				section = make(map[string]string)
+				sections[name] = section
+			}
+		case section != nil:
+			if i := strings.IndexByte(line, '='); i > 0 {
+				section[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+			}
+		}
+	}
+
+	return sections, s.Err()
+}
+
+// CredentialsChain is a CredentialsProvider which returns the credentials of
+// the first of its providers that succeeds.
+type CredentialsChain []CredentialsProvider
+
+// Retrieve satisfies the CredentialsProvider interface.
+func (chain CredentialsChain) Retrieve(ctx context.Context) (Credentials, error) {
+	var errs []string
+
+	for _, p := range chain {
+		c, err := p.Retrieve(ctx)
+		if err == nil {
+			return c, nil
+		}
+		if !errors.Is(err, ErrNoCredentials) {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) != 0 {
+		return Credentials{}, fmt.Errorf("%w: %s", ErrNoCredentials, strings.Join(errs, "; "))
+	}
+	return Credentials{}, ErrNoCredentials
+}
+
+// DefaultCredentials returns a CredentialsProvider which follows the standard
+// chain of the AWS SDKs: environment variables, web identity tokens (e.g. IAM
+// roles for service accounts on EKS), shared credentials and config files, the
+// ECS container credentials endpoint, and the EC2 instance metadata service.
+//
+// The credentials are cached until shortly before they expire.
+func DefaultCredentials() CredentialsProvider {
+	return &CachedCredentials{
+		Provider: CredentialsChain{
+			EnvCredentials{},
+			WebIdentityCredentials{},
+			SharedCredentials{},
+			ContainerCredentials{},
+			EC2RoleCredentials{},
+		},
+	}
+}
+
+// CachedCredentials is a CredentialsProvider which caches the credentials of
+// another provider until shortly before they expire.
+type CachedCredentials struct {
+	Provider CredentialsProvider
+
+	// How long before their expiry credentials are refreshed, defaults to
+	// 5 minutes.
+	RefreshBefore time.Duration
+
+	mutex       sync.Mutex
+	credentials Credentials
+}
+
+// Retrieve satisfies the CredentialsProvider interface.
+func (c *CachedCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	refreshBefore := c.RefreshBefore
+	if refreshBefore == 0 {
+		refreshBefore = 5 * time.Minute
+	}
+
+	if c.credentials.AccessKeyID != "" {
+		if c.credentials.Expires.IsZero() || time.Until(c.credentials.Expires) > refreshBefore {
+			return c.credentials, nil
+		}
+	}
+
+	credentials, err := c.Provider.Retrieve(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+	c.credentials = credentials
+	return credentials, nil
+}
+
+func getenv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
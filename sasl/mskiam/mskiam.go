@@ -0,0 +1,126 @@
+// Package mskiam implements the AWS_MSK_IAM SASL mechanism, which
+// authenticates clients of Amazon MSK clusters with IAM credentials.
+//
+// Unlike the sasl/aws_msk_iam module, the package has no dependencies on the
+// AWS SDK: it signs the authentication payloads with its own implementation of
+// AWS Signature Version 4, and retrieves credentials from the standard chain of
+// the AWS SDKs, including roles assumed with AWS STS.
+package mskiam
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+const (
+	// These constants come from https://github.com/aws/aws-msk-iam-auth#details
+	signVersion = "2020_10_22"
+	signService = "kafka-cluster"
+	signAction  = "kafka-cluster:Connect"
+)
+
+var signUserAgent = fmt.Sprintf("kafka-go/sasl/mskiam/%s", runtime.Version())
+
+// Mechanism implements the AWS_MSK_IAM mechanism.
+//
+// The zero value is usable, it retrieves credentials from DefaultCredentials
+// and reads the region from the environment.
+type Mechanism struct {
+	// The region where the MSK cluster is hosted, e.g. "us-east-1". Defaults
+	// to the value of the AWS_REGION or AWS_DEFAULT_REGION environment
+	// variables.
+	Region string
+
+	// The provider of the credentials used to sign the authentication
+	// payloads, defaults to DefaultCredentials.
+	//
+	// Use AssumeRoleCredentials to authenticate with the credentials of
+	// another role.
+	Credentials CredentialsProvider
+
+	// The duration for which the signed payloads are valid. Optional,
+	// defaults to 5 minutes.
+	Expiry time.Duration
+
+	// The time at which payloads are signed, mostly useful for testing.
+	// Optional, defaults to time.Now() at the time of authentication.
+	SignTime time.Time
+}
+
+// Name satisfies the sasl.Mechanism interface.
+func (m *Mechanism) Name() string {
+	return "AWS_MSK_IAM"
+}
+
+// Start satisfies the sasl.Mechanism interface, it returns the signed JSON
+// payload that MSK brokers expect as initial response.
+func (m *Mechanism) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	meta := sasl.MetadataFromContext(ctx)
+	if meta == nil {
+		return nil, nil, errors.New("mskiam: missing sasl metadata")
+	}
+
+	region := m.Region
+	if region == "" {
+		if region = getenv("AWS_REGION", "AWS_DEFAULT_REGION"); region == "" {
+			return nil, nil, errors.New("mskiam: the region of the MSK cluster must be configured")
+		}
+	}
+
+	provider := m.Credentials
+	if provider == nil {
+		provider = defaultCredentials
+	}
+
+	creds, err := provider.Retrieve(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signTime := m.SignTime
+	if signTime.IsZero() {
+		signTime = time.Now()
+	}
+
+	expiry := m.Expiry
+	if expiry == 0 {
+		expiry = 5 * time.Minute
+	}
+
+	query := presign(creds, meta.Host, signService, region, url.Values{"Action": {signAction}}, signTime, expiry)
+
+	payload := map[string]string{
+		"version":    signVersion,
+		"host":       meta.Host,
+		"user-agent": signUserAgent,
+		"action":     signAction,
+	}
+	// The protocol requires lowercase keys.
+	for key, values := range query {
+		if key != "Action" {
+			payload[strings.ToLower(key)] = values[0]
+		}
+	}
+
+	b, err := json.Marshal(payload)
+	return m, b, err
+}
+
+// Next satisfies the sasl.StateMachine interface. Brokers reject invalid
+// payloads with an error, so the authentication is complete after the initial
+// response was accepted.
+func (m *Mechanism) Next(ctx context.Context, challenge []byte) (bool, []byte, error) {
+	return true, nil, nil
+}
+
+// defaultCredentials is shared by mechanisms which do not configure a
+// provider, so the credentials are cached across connections.
+var defaultCredentials = DefaultCredentials()
@@ -0,0 +1,301 @@
+package mskiam
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+// using a fixed time allows the signature to be verifiable in a test
+var signTime = time.Date(2021, 10, 14, 13, 5, 0, 0, time.UTC)
+
+func TestMechanism(t *testing.T) {
+	m := &Mechanism{
+		Region:      "us-east-1",
+		Credentials: StaticCredentials{AccessKeyID: "ACCESS_KEY", SecretAccessKey: "SECRET_KEY"},
+		SignTime:    signTime,
+	}
+
+	if _, _, err := m.Start(context.Background()); err == nil {
+		t.Error("authentication should fail without sasl metadata")
+	}
+
+	ctx := sasl.WithMetadata(context.Background(), &sasl.Metadata{Host: "localhost", Port: 9092})
+	sess, ir, err := m.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := map[string]string{}
+	if err := json.Unmarshal(ir, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	// The signature matches the one computed by the signer of the AWS SDK.
+	want := map[string]string{
+		"version":             "2020_10_22",
+		"action":              "kafka-cluster:Connect",
+		"host":                "localhost",
+		"user-agent":          signUserAgent,
+		"x-amz-algorithm":     "AWS4-HMAC-SHA256",
+		"x-amz-credential":    "ACCESS_KEY/20211014/us-east-1/kafka-cluster/aws4_request",
+		"x-amz-date":          "20211014T130500Z",
+		"x-amz-expires":       "300",
+		"x-amz-signedheaders": "host",
+		"x-amz-signature":     "6b8d25f9b45b9c7db9da855a49112d80379224153a27fd279c305a5b7940d1a7",
+	}
+	if !reflect.DeepEqual(payload, want) {
+		t.Errorf("wrong payload:\nwant: %v\ngot:  %v", want, payload)
+	}
+
+	done, res, err := sess.Next(ctx, nil)
+	if !done || res != nil || err != nil {
+		t.Errorf("the authentication should have completed: done=%t response=%q error=%v", done, res, err)
+	}
+
+	m.Credentials = StaticCredentials{AccessKeyID: "ACCESS_KEY", SecretAccessKey: "SECRET_KEY", SessionToken: "TOKEN"}
+	if _, ir, err = m.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	payload = map[string]string{}
+	json.Unmarshal(ir, &payload)
+	if payload["x-amz-security-token"] != "TOKEN" {
+		t.Errorf("the session token is missing from the payload: %v", payload)
+	}
+}
+
+func TestEnvCredentials(t *testing.T) {
+	setenv(t, "AWS_ACCESS_KEY_ID", "A")
+	setenv(t, "AWS_SECRET_ACCESS_KEY", "B")
+	setenv(t, "AWS_SESSION_TOKEN", "C")
+
+	creds, err := EnvCredentials{}.Retrieve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Credentials{AccessKeyID: "A", SecretAccessKey: "B", SessionToken: "C"}); creds != want {
+		t.Errorf("wrong credentials: want=%+v got=%+v", want, creds)
+	}
+}
+
+func TestSharedCredentials(t *testing.T) {
+	sts := newSTSServer(t)
+
+	dir, err := ioutil.TempDir("", "mskiam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	credentialsFile := filepath.Join(dir, "credentials")
+	configFile := filepath.Join(dir, "config")
+
+	ioutil.WriteFile(credentialsFile, []byte(`
+[default]
+aws_access_key_id = A
+aws_secret_access_key = B
+
+# comment
+[dev]
+aws_access_key_id=C
+aws_secret_access_key=D
+aws_session_token=E
+`), 0600)
+
+	ioutil.WriteFile(configFile, []byte(`
+[profile admin]
+role_arn = arn:aws:iam::123456789012:role/admin
+source_profile = dev
+`), 0600)
+
+	setenv(t, "AWS_ENDPOINT_URL_STS", sts.URL)
+
+	tests := []struct {
+		profile string
+		creds   Credentials
+	}{
+		{profile: "default", creds: Credentials{AccessKeyID: "A", SecretAccessKey: "B"}},
+		{profile: "dev", creds: Credentials{AccessKeyID: "C", SecretAccessKey: "D", SessionToken: "E"}},
+		{profile: "admin", creds: Credentials{
+			AccessKeyID:     "ASSUMED",
+			SecretAccessKey: "SECRET",
+			SessionToken:    "SESSION",
+			Expires:         time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.profile, func(t *testing.T) {
+			creds, err := SharedCredentials{
+				CredentialsFile: credentialsFile,
+				ConfigFile:      configFile,
+				Profile:         test.profile,
+			}.Retrieve(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if creds != test.creds {
+				t.Errorf("wrong credentials: want=%+v got=%+v", test.creds, creds)
+			}
+		})
+	}
+
+	_, err = SharedCredentials{
+		CredentialsFile: credentialsFile,
+		ConfigFile:      configFile,
+		Profile:         "missing",
+	}.Retrieve(context.Background())
+	if err != ErrNoCredentials {
+		t.Errorf("wrong error for missing profiles: %v", err)
+	}
+}
+
+func TestAssumeRoleCredentials(t *testing.T) {
+	sts := newSTSServer(t)
+
+	creds, err := AssumeRoleCredentials{
+		RoleARN:    "arn:aws:iam::123456789012:role/admin",
+		ExternalID: "ext",
+		Region:     "us-west-2",
+		Source:     StaticCredentials{AccessKeyID: "A", SecretAccessKey: "B"},
+		Endpoint:   sts.URL,
+	}.Retrieve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AccessKeyID != "ASSUMED" || creds.SessionToken != "SESSION" || creds.Expires.IsZero() {
+		t.Errorf("wrong credentials: %+v", creds)
+	}
+
+	_, err = AssumeRoleCredentials{
+		RoleARN:  "arn:aws:iam::123456789012:role/denied",
+		Source:   StaticCredentials{AccessKeyID: "A", SecretAccessKey: "B"},
+		Endpoint: sts.URL,
+	}.Retrieve(context.Background())
+	if err == nil {
+		t.Error("assuming a role should fail when STS denies access")
+	}
+}
+
+func TestEC2RoleCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			w.Write([]byte("imds-token"))
+		case r.Header.Get("X-Aws-Ec2-Metadata-Token") != "imds-token":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/":
+			w.Write([]byte("instance-role"))
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/instance-role":
+			w.Write([]byte(`{"Code":"Success","AccessKeyId":"A","SecretAccessKey":"B","Token":"C","Expiration":"2030-01-01T00:00:00Z"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	creds, err := EC2RoleCredentials{Endpoint: server.URL}.Retrieve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Credentials{
+		AccessKeyID:     "A",
+		SecretAccessKey: "B",
+		SessionToken:    "C",
+		Expires:         time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if creds != want {
+		t.Errorf("wrong credentials: want=%+v got=%+v", want, creds)
+	}
+}
+
+func TestCachedCredentials(t *testing.T) {
+	calls := 0
+	expires := time.Now().Add(time.Hour)
+
+	c := &CachedCredentials{
+		Provider: CredentialsProviderFunc(func(ctx context.Context) (Credentials, error) {
+			calls++
+			return Credentials{AccessKeyID: "A", SecretAccessKey: "B", Expires: expires}, nil
+		}),
+	}
+
+	c.Retrieve(context.Background())
+	c.Retrieve(context.Background())
+	if calls != 1 {
+		t.Errorf("credentials should be cached until they expire: %d calls", calls)
+	}
+
+	expires = time.Now().Add(time.Minute)
+	c.credentials.Expires = expires
+	c.Retrieve(context.Background())
+	if calls != 2 {
+		t.Errorf("credentials should be refreshed before they expire: %d calls", calls)
+	}
+}
+
+func TestCredentialsChain(t *testing.T) {
+	chain := CredentialsChain{
+		StaticCredentials{},
+		StaticCredentials{AccessKeyID: "A", SecretAccessKey: "B"},
+	}
+
+	creds, err := chain.Retrieve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AccessKeyID != "A" {
+		t.Errorf("the chain did not skip unconfigured providers: %+v", creds)
+	}
+}
+
+func newSTSServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		if q.Get("X-Amz-Signature") == "" || q.Get("X-Amz-Credential") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if q.Get("RoleArn") == "arn:aws:iam::123456789012:role/denied" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`<ErrorResponse><Error><Code>AccessDenied</Code><Message>denied</Message></Error></ErrorResponse>`))
+			return
+		}
+
+		w.Write([]byte(`<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>ASSUMED</AccessKeyId>
+      <SecretAccessKey>SECRET</SecretAccessKey>
+      <SessionToken>SESSION</SessionToken>
+      <Expiration>2030-01-01T00:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleResult>
+</AssumeRoleResponse>`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func setenv(t *testing.T, name, value string) {
+	old, ok := os.LookupEnv(name)
+	os.Setenv(name, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(name, old)
+		} else {
+			os.Unsetenv(name)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package mskiam
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ContainerCredentials is a CredentialsProvider which retrieves the
+// credentials of the task role of ECS containers, or of EKS pod identities,
+// from the endpoint configured by the AWS_CONTAINER_CREDENTIALS_RELATIVE_URI
+// or AWS_CONTAINER_CREDENTIALS_FULL_URI environment variables.
+type ContainerCredentials struct {
+	// The URL of the credentials endpoint, defaults to the value configured
+	// in the environment.
+	Endpoint string
+
+	// Optional HTTP client used to reach the endpoint.
+	Client *http.Client
+}
+
+// Retrieve satisfies the CredentialsProvider interface.
+func (c ContainerCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+			endpoint = "http://169.254.170.2" + uri
+		} else {
+			endpoint = os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+		}
+	}
+	if endpoint == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if tokenFile := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); tokenFile != "" {
+		b, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("mskiam: reading container authorization token: %w", err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	creds, err := remoteCredentials(c.Client, req)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("mskiam: retrieving container credentials: %w", err)
+	}
+	return creds, nil
+}
+
+// EC2RoleCredentials is a CredentialsProvider which retrieves the credentials
+// of the IAM role of EC2 instances from the instance metadata service, using
+// session tokens (IMDSv2).
+type EC2RoleCredentials struct {
+	// The URL of the instance metadata service, defaults to the value of the
+	// AWS_EC2_METADATA_SERVICE_ENDPOINT environment variable, or
+	// http://169.254.169.254.
+	Endpoint string
+
+	// Optional HTTP client used to reach the metadata service. The default
+	// client gives up after one second, since the service is only reachable
+	// from EC2 instances.
+	Client *http.Client
+}
+
+// Retrieve satisfies the CredentialsProvider interface.
+func (e EC2RoleCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return Credentials{}, ErrNoCredentials
+	}
+
+	endpoint := e.Endpoint
+	if endpoint == "" {
+		if endpoint = os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT"); endpoint == "" {
+			endpoint = "http://169.254.169.254"
+		}
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+
+	client := e.Client
+	if client == nil {
+		client = &http.Client{Timeout: time.Second}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "21600")
+
+	token, err := httpCall(client, req)
+	if err != nil {
+		// The metadata service is not reachable outside of EC2 instances,
+		// which is not an error for chains of providers.
+		return Credentials{}, fmt.Errorf("%w: instance metadata service: %v", ErrNoCredentials, err)
+	}
+
+	get := func(path string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+path, nil)
+		if err == nil {
+			req.Header.Set("X-Aws-Ec2-Metadata-Token", string(token))
+		}
+		return req, err
+	}
+
+	req, err = get("/latest/meta-data/iam/security-credentials/")
+	if err != nil {
+		return Credentials{}, err
+	}
+	roles, err := httpCall(client, req)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("mskiam: retrieving the instance role: %w", err)
+	}
+	role := strings.TrimSpace(strings.SplitN(string(roles), "\n", 2)[0])
+	if role == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+
+	req, err = get("/latest/meta-data/iam/security-credentials/" + role)
+	if err != nil {
+		return Credentials{}, err
+	}
+	creds, err := remoteCredentials(client, req)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("mskiam: retrieving the credentials of instance role %s: %w", role, err)
+	}
+	return creds, nil
+}
+
+// remoteCredentials decodes the JSON credentials returned by the container
+// and instance metadata endpoints.
+func remoteCredentials(client *http.Client, req *http.Request) (Credentials, error) {
+	body, err := httpCall(client, req)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	var res struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string
+		Token           string
+		Expiration      time.Time
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return Credentials{}, err
+	}
+
+	return StaticCredentials{
+		AccessKeyID:     res.AccessKeyID,
+		SecretAccessKey: res.SecretAccessKey,
+		SessionToken:    res.Token,
+		Expires:         res.Expiration,
+	}.Retrieve(req.Context())
+}
@@ -0,0 +1,113 @@
+package mskiam
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signAlgorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat = "20060102T150405Z"
+	// Hash of the empty payload of presigned requests.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// presign signs a GET request to host with the given query parameters using
+// the query string variant of AWS Signature Version 4, and returns the query
+// parameters of the signed request, which include the signature.
+func presign(creds Credentials, host, service, region string, query url.Values, signTime time.Time, expiry time.Duration) url.Values {
+	signTime = signTime.UTC()
+	amzDate := signTime.Format(amzDateFormat)
+	scope := strings.Join([]string{signTime.Format("20060102"), region, service, "aws4_request"}, "/")
+
+	signed := make(url.Values, len(query)+6)
+	for k, v := range query {
+		signed[k] = v
+	}
+	signed.Set("X-Amz-Algorithm", signAlgorithm)
+	signed.Set("X-Amz-Credential", creds.AccessKeyID+"/"+scope)
+	signed.Set("X-Amz-Date", amzDate)
+	signed.Set("X-Amz-Expires", strconv.FormatInt(int64(expiry/time.Second), 10))
+	signed.Set("X-Amz-SignedHeaders", "host")
+	if creds.SessionToken != "" {
+		signed.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		"/",
+		canonicalQueryString(signed),
+		"host:" + host + "\n",
+		"host",
+		emptyPayloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		signAlgorithm,
+		amzDate,
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), signTime.Format("20060102"))
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+
+	signed.Set("X-Amz-Signature", hex.EncodeToString(hmacSHA256(key, stringToSign)))
+	return signed
+}
+
+func canonicalQueryString(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values := append([]string{}, query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode encodes s as required by the canonical requests of AWS Signature
+// Version 4, which only leaves the unreserved characters of RFC 3986 as is.
+func uriEncode(s string) string {
+	const hexDigits = "0123456789ABCDEF"
+	b := new(strings.Builder)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&15])
+		}
+	}
+	return b.String()
+}
+
+func hexSHA256(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
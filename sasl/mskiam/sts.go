@@ -0,0 +1,271 @@
+package mskiam
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AssumeRoleCredentials is a CredentialsProvider which returns the temporary
+// credentials of an IAM role, assumed with the credentials of its source
+// provider by calling the AWS STS AssumeRole API.
+type AssumeRoleCredentials struct {
+	// The ARN of the role to assume.
+	RoleARN string
+
+	// Name of the role session, defaults to "kafka-go-<unix time>".
+	SessionName string
+
+	// Optional external ID required by the trust policy of the role.
+	ExternalID string
+
+	// Lifetime of the credentials, defaults to 1 hour.
+	Duration time.Duration
+
+	// Region of the STS endpoint, defaults to the value of the AWS_REGION or
+	// AWS_DEFAULT_REGION environment variables, or us-east-1.
+	Region string
+
+	// Provider of the credentials used to assume the role, defaults to
+	// DefaultCredentials.
+	Source CredentialsProvider
+
+	// Optional STS endpoint and HTTP client. The endpoint defaults to the
+	// value of the AWS_ENDPOINT_URL_STS or AWS_ENDPOINT_URL environment
+	// variables, or to the regional endpoint.
+	Endpoint string
+	Client   *http.Client
+}
+
+// Retrieve satisfies the CredentialsProvider interface.
+func (r AssumeRoleCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	if r.RoleARN == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+
+	source := r.Source
+	if source == nil {
+		source = DefaultCredentials()
+	}
+
+	creds, err := source.Retrieve(ctx)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("mskiam: retrieving source credentials to assume %s: %w", r.RoleARN, err)
+	}
+
+	query := url.Values{
+		"Action":          {"AssumeRole"},
+		"Version":         {stsVersion},
+		"RoleArn":         {r.RoleARN},
+		"RoleSessionName": {sessionName(r.SessionName)},
+		"DurationSeconds": {durationSeconds(r.Duration)},
+	}
+	if r.ExternalID != "" {
+		query.Set("ExternalId", r.ExternalID)
+	}
+
+	endpoint, host := stsEndpoint(r.Endpoint, r.Region)
+	query = presign(creds, host, "sts", stsRegion(r.Region), query, time.Now(), time.Minute)
+
+	var res struct {
+		Result stsResult `xml:"AssumeRoleResult"`
+	}
+	if err := stsCall(ctx, r.Client, endpoint+"?"+query.Encode(), &res); err != nil {
+		return Credentials{}, fmt.Errorf("mskiam: assuming role %s: %w", r.RoleARN, err)
+	}
+	return res.Result.Credentials.credentials(), nil
+}
+
+// WebIdentityCredentials is a CredentialsProvider which exchanges a web
+// identity token, like the tokens of IAM roles for EKS service accounts, for
+// the temporary credentials of an IAM role by calling the AWS STS
+// AssumeRoleWithWebIdentity API.
+type WebIdentityCredentials struct {
+	// The ARN of the role to assume, and path to the file containing the
+	// token, default to the values of the AWS_ROLE_ARN and
+	// AWS_WEB_IDENTITY_TOKEN_FILE environment variables.
+	RoleARN   string
+	TokenFile string
+
+	// Name of the role session, defaults to the value of the
+	// AWS_ROLE_SESSION_NAME environment variable, or "kafka-go-<unix time>".
+	SessionName string
+
+	// Lifetime of the credentials, defaults to 1 hour.
+	Duration time.Duration
+
+	// Region of the STS endpoint, defaults to the value of the AWS_REGION or
+	// AWS_DEFAULT_REGION environment variables, or us-east-1.
+	Region string
+
+	// Optional STS endpoint and HTTP client. The endpoint defaults to the
+	// value of the AWS_ENDPOINT_URL_STS or AWS_ENDPOINT_URL environment
+	// variables, or to the regional endpoint.
+	Endpoint string
+	Client   *http.Client
+}
+
+// Retrieve satisfies the CredentialsProvider interface.
+func (w WebIdentityCredentials) Retrieve(ctx context.Context) (Credentials, error) {
+	roleARN, tokenFile, session := w.RoleARN, w.TokenFile, w.SessionName
+	if roleARN == "" {
+		roleARN = os.Getenv("AWS_ROLE_ARN")
+	}
+	if tokenFile == "" {
+		tokenFile = os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	}
+	if session == "" {
+		session = os.Getenv("AWS_ROLE_SESSION_NAME")
+	}
+	if roleARN == "" || tokenFile == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("mskiam: reading web identity token: %w", err)
+	}
+
+	// The request is authenticated by the token, it is not signed.
+	query := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {stsVersion},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {sessionName(session)},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+		"DurationSeconds":  {durationSeconds(w.Duration)},
+	}
+
+	endpoint, _ := stsEndpoint(w.Endpoint, w.Region)
+
+	var res struct {
+		Result stsResult `xml:"AssumeRoleWithWebIdentityResult"`
+	}
+	if err := stsCall(ctx, w.Client, endpoint+"?"+query.Encode(), &res); err != nil {
+		return Credentials{}, fmt.Errorf("mskiam: assuming role %s with web identity: %w", roleARN, err)
+	}
+	return res.Result.Credentials.credentials(), nil
+}
+
+const stsVersion = "2011-06-15"
+
+type stsResult struct {
+	Credentials stsCredentials `xml:"Credentials"`
+}
+
+type stsCredentials struct {
+	AccessKeyID     string    `xml:"AccessKeyId"`
+	SecretAccessKey string    `xml:"SecretAccessKey"`
+	SessionToken    string    `xml:"SessionToken"`
+	Expiration      time.Time `xml:"Expiration"`
+}
+
+func (c *stsCredentials) credentials() Credentials {
+	return Credentials{
+		AccessKeyID:     c.AccessKeyID,
+		SecretAccessKey: c.SecretAccessKey,
+		SessionToken:    c.SessionToken,
+		Expires:         c.Expiration,
+	}
+}
+
+type stsError struct {
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+func stsRegion(region string) string {
+	if region == "" {
+		if region = getenv("AWS_REGION", "AWS_DEFAULT_REGION"); region == "" {
+			region = "us-east-1"
+		}
+	}
+	return region
+}
+
+// stsEndpoint returns the URL and host name of the STS endpoint, which
+// defaults to the value of the AWS_ENDPOINT_URL_STS or AWS_ENDPOINT_URL
+// environment variables, or to the regional endpoint.
+func stsEndpoint(endpoint, region string) (string, string) {
+	if endpoint == "" {
+		if endpoint = getenv("AWS_ENDPOINT_URL_STS", "AWS_ENDPOINT_URL"); endpoint == "" {
+			endpoint = "https://sts." + stsRegion(region) + ".amazonaws.com/"
+		}
+	}
+	host := endpoint
+	if u, err := url.Parse(endpoint); err == nil {
+		host = u.Host
+	}
+	return endpoint, host
+}
+
+func sessionName(name string) string {
+	if name == "" {
+		name = "kafka-go-" + strconv.FormatInt(time.Now().Unix(), 10)
+	}
+	return name
+}
+
+func durationSeconds(d time.Duration) string {
+	if d <= 0 {
+		d = time.Hour
+	}
+	return strconv.FormatInt(int64(d/time.Second), 10)
+}
+
+func stsCall(ctx context.Context, client *http.Client, url string, res interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	body, err := httpCall(client, req)
+	if err != nil {
+		var e stsError
+		var h *httpError
+		if errors.As(err, &h) && xml.Unmarshal(h.body, &e) == nil && e.Code != "" {
+			return fmt.Errorf("%s: %s", e.Code, e.Message)
+		}
+		return err
+	}
+	return xml.Unmarshal(body, res)
+}
+
+type httpError struct {
+	status int
+	body   []byte
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.status, strings.TrimSpace(string(e.body)))
+}
+
+// httpCall sends req and returns the body of the response, or an error if the
+// response status was not 200.
+func httpCall(client *http.Client, req *http.Request) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, &httpError{status: res.StatusCode, body: body}
+	}
+	return body, nil
+}
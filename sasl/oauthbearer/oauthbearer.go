@@ -0,0 +1,180 @@
+// Package oauthbearer implements the OAUTHBEARER SASL mechanism (RFC 7628),
+// which authenticates clients with OAuth 2 bearer tokens. It is used to connect
+// to kafka clusters configured with OpenID Connect identity providers, like
+// Confluent Cloud or Strimzi.
+package oauthbearer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+// Token is an OAuth 2 bearer token used to authenticate with kafka brokers.
+type Token struct {
+	// The value of the token, usually a JWT.
+	Value string
+
+	// The time at which the token expires. Brokers bound the lifetime of SASL
+	// sessions to the expiry of tokens, connections re-authenticate with a
+	// new token before their sessions expire (see KIP-368).
+	//
+	// The zero value means that the expiry is unknown.
+	Expiry time.Time
+
+	// SASL extensions sent with the token (see KIP-342), for example the
+	// logicalCluster and identityPoolId extensions of Confluent Cloud.
+	Extensions map[string]string
+}
+
+// TokenProvider is a function called to obtain a token every time that a
+// connection authenticates, including when connections re-authenticate before
+// their sessions expire.
+//
+// Providers are called concurrently from multiple goroutines. Use
+// CachedTokenProvider to share tokens between connections instead of
+// requesting one from the identity provider on every authentication.
+type TokenProvider func(ctx context.Context) (Token, error)
+
+// CachedTokenProvider returns a TokenProvider which caches the tokens returned
+// by provider, and obtains new ones when less than refreshBefore remains
+// before they expire. Tokens with no expiry are cached indefinitely.
+func CachedTokenProvider(provider TokenProvider, refreshBefore time.Duration) TokenProvider {
+	var mutex sync.Mutex
+	var token Token
+	var cached bool
+
+	return func(ctx context.Context) (Token, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if cached && (token.Expiry.IsZero() || time.Until(token.Expiry) > refreshBefore) {
+			return token, nil
+		}
+
+		t, err := provider(ctx)
+		if err != nil {
+			return Token{}, err
+		}
+
+		token, cached = t, true
+		return token, nil
+	}
+}
+
+// Mechanism implements the OAUTHBEARER mechanism.
+type Mechanism struct {
+	// The function called to obtain tokens, it must not be nil.
+	TokenProvider TokenProvider
+
+	// SASL extensions sent on every authentication, in addition to the
+	// extensions of tokens. The extensions of tokens take precedence.
+	Extensions map[string]string
+}
+
+func (Mechanism) Name() string {
+	return "OAUTHBEARER"
+}
+
+func (m Mechanism) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	if m.TokenProvider == nil {
+		return nil, nil, errors.New("oauthbearer: no token provider configured")
+	}
+
+	token, err := m.TokenProvider(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("oauthbearer: failed to obtain a token: %w", err)
+	}
+	if token.Value == "" {
+		return nil, nil, errors.New("oauthbearer: the token provider returned an empty token")
+	}
+
+	extensions := make(map[string]string, len(m.Extensions)+len(token.Extensions))
+	for k, v := range m.Extensions {
+		extensions[k] = v
+	}
+	for k, v := range token.Extensions {
+		extensions[k] = v
+	}
+
+	ir, err := initialResponse(token.Value, extensions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &session{}, ir, nil
+}
+
+type session struct {
+	err error
+}
+
+func (s *session) Next(ctx context.Context, challenge []byte) (bool, []byte, error) {
+	if len(challenge) == 0 {
+		return true, nil, nil
+	}
+
+	if s.err != nil {
+		return false, nil, s.err
+	}
+
+	// The broker rejected the token and sent the reason in the challenge,
+	// RFC 7628 requires the client to acknowledge it with a single 0x01 byte
+	// before the broker fails the authentication.
+	s.err = fmt.Errorf("oauthbearer: the token was rejected: %s", challenge)
+	return false, []byte{separator}, nil
+}
+
+const separator = '\x01'
+
+// initialResponse formats the client initial response of the mechanism, as
+// defined in section 3.1 of RFC 7628.
+func initialResponse(token string, extensions map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(extensions))
+	for k, v := range extensions {
+		if err := validateExtension(k, v); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b := new(strings.Builder)
+	b.WriteString("n,,")
+	b.WriteByte(separator)
+	b.WriteString("auth=Bearer ")
+	b.WriteString(token)
+	b.WriteByte(separator)
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(extensions[k])
+		b.WriteByte(separator)
+	}
+	b.WriteByte(separator)
+	return []byte(b.String()), nil
+}
+
+// validateExtension checks the syntax of SASL extensions, as defined by
+// KIP-342.
+func validateExtension(key, value string) error {
+	if key == "" || key == "auth" {
+		return fmt.Errorf("oauthbearer: invalid SASL extension name %q", key)
+	}
+	for _, c := range key {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			return fmt.Errorf("oauthbearer: invalid SASL extension name %q", key)
+		}
+	}
+	for _, c := range value {
+		if !(0x21 <= c && c <= 0x7E || c == ' ' || c == '\t' || c == '\r' || c == '\n') {
+			return fmt.Errorf("oauthbearer: invalid value of SASL extension %q", key)
+		}
+	}
+	return nil
+}
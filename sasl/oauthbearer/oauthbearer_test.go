@@ -0,0 +1,106 @@
+package oauthbearer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMechanism(t *testing.T) {
+	m := Mechanism{
+		TokenProvider: func(ctx context.Context) (Token, error) {
+			return Token{
+				Value:      "eyJhbGciOiJub25lIn0.e30.",
+				Extensions: map[string]string{"logicalCluster": "lkc-123"},
+			}, nil
+		},
+		Extensions: map[string]string{
+			"identityPoolId": "pool-1",
+			"logicalCluster": "overridden",
+		},
+	}
+
+	sess, ir, err := m.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "n,,\x01auth=Bearer eyJhbGciOiJub25lIn0.e30.\x01identityPoolId=pool-1\x01logicalCluster=lkc-123\x01\x01"
+	if string(ir) != want {
+		t.Errorf("wrong initial response:\nwant: %q\ngot:  %q", want, ir)
+	}
+
+	done, res, err := sess.Next(context.Background(), nil)
+	if !done || res != nil || err != nil {
+		t.Errorf("the authentication should have completed: done=%t response=%q error=%v", done, res, err)
+	}
+}
+
+func TestMechanismRejectedToken(t *testing.T) {
+	m := Mechanism{
+		TokenProvider: func(ctx context.Context) (Token, error) {
+			return Token{Value: "expired"}, nil
+		},
+	}
+
+	sess, _, err := m.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done, res, err := sess.Next(context.Background(), []byte(`{"status":"invalid_token"}`))
+	if done || string(res) != "\x01" || err != nil {
+		t.Errorf("the error challenge should have been acknowledged: done=%t response=%q error=%v", done, res, err)
+	}
+
+	if _, _, err := sess.Next(context.Background(), []byte(`{"status":"invalid_token"}`)); err == nil {
+		t.Error("the authentication should have failed")
+	}
+}
+
+func TestMechanismInvalidExtensions(t *testing.T) {
+	for _, extensions := range []map[string]string{
+		{"auth": "reserved"},
+		{"not-alpha": "value"},
+		{"key": "\x01"},
+	} {
+		m := Mechanism{
+			TokenProvider: func(ctx context.Context) (Token, error) { return Token{Value: "token"}, nil },
+			Extensions:    extensions,
+		}
+		if _, _, err := m.Start(context.Background()); err == nil {
+			t.Errorf("invalid extensions were accepted: %q", extensions)
+		}
+	}
+}
+
+func TestCachedTokenProvider(t *testing.T) {
+	calls := 0
+	expiry := time.Now().Add(time.Hour)
+
+	provider := CachedTokenProvider(func(ctx context.Context) (Token, error) {
+		calls++
+		return Token{Value: "token", Expiry: expiry}, nil
+	}, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if _, err := provider(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("the token should have been cached: calls=%d", calls)
+	}
+
+	expiry = time.Now().Add(30 * time.Second)
+	provider = CachedTokenProvider(func(ctx context.Context) (Token, error) {
+		calls++
+		return Token{Value: "token", Expiry: expiry}, nil
+	}, time.Minute)
+
+	provider(context.Background())
+	provider(context.Background())
+	if calls != 3 {
+		t.Errorf("tokens about to expire should have been refreshed: calls=%d", calls)
+	}
+}
@@ -1,6 +1,9 @@
 package sasl
 
-import "context"
+import (
+	"context"
+	"crypto/tls"
+)
 
 type ctxKey struct{}
 
@@ -51,6 +54,12 @@ type Metadata struct {
 	// performed on.
 	Host string
 	Port int
+
+	// TLS is the state of the TLS connection that the authentication is
+	// performed on, or nil if the connection does not use TLS. Mechanisms
+	// which bind the authentication to the connection use it to compute
+	// channel binding data.
+	TLS *tls.ConnectionState
 }
 
 // WithMetadata returns a copy of the context with associated Metadata.
@@ -0,0 +1,147 @@
+package scram
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// conversation is a SCRAM client state machine (RFC 5802) supporting message
+// extensions, which are needed for kafka delegation tokens but not supported
+// by the scram package that the Mechanism function is built on.
+type conversation struct {
+	algo       Algorithm
+	username   string
+	password   string
+	extensions []string
+	gs2Header  string
+	cbindData  []byte
+
+	step            int
+	nonce           string
+	clientFirstBare string
+	serverSignature []byte
+}
+
+func (c *conversation) first() ([]byte, error) {
+	nonce := make([]byte, 24)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating scram nonce: %w", err)
+	}
+
+	c.nonce = base64.RawStdEncoding.EncodeToString(nonce)
+	c.clientFirstBare = "n=" + encodeName(c.username) + ",r=" + c.nonce
+	for _, ext := range c.extensions {
+		c.clientFirstBare += "," + ext
+	}
+
+	c.step = 1
+	return []byte(c.gs2Header + c.clientFirstBare), nil
+}
+
+func (c *conversation) next(challenge []byte) (bool, []byte, error) {
+	switch c.step {
+	case 1:
+		c.step = 2
+		res, err := c.final(string(challenge))
+		return false, res, err
+	case 2:
+		c.step = 3
+		return true, nil, c.verify(string(challenge))
+	default:
+		return false, nil, errors.New("scram conversation already completed")
+	}
+}
+
+func (c *conversation) final(serverFirst string) ([]byte, error) {
+	var nonce, salt, iters string
+
+	for _, field := range strings.Split(serverFirst, ",") {
+		switch {
+		case strings.HasPrefix(field, "r="):
+			nonce = field[2:]
+		case strings.HasPrefix(field, "s="):
+			salt = field[2:]
+		case strings.HasPrefix(field, "i="):
+			iters = field[2:]
+		case strings.HasPrefix(field, "m="):
+			return nil, errors.New("scram server requested unsupported mandatory extension")
+		case strings.HasPrefix(field, "e="):
+			return nil, fmt.Errorf("scram server error: %s", field[2:])
+		}
+	}
+
+	if !strings.HasPrefix(nonce, c.nonce) || len(nonce) == len(c.nonce) {
+		return nil, errors.New("scram server nonce does not extend the client nonce")
+	}
+
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return nil, fmt.Errorf("decoding scram salt: %w", err)
+	}
+
+	iterations, err := strconv.Atoi(iters)
+	if err != nil || iterations <= 0 {
+		return nil, fmt.Errorf("invalid scram iteration count: %q", iters)
+	}
+
+	newHash := c.algo.Hash
+	saltedPassword := pbkdf2.Key([]byte(c.password), saltBytes, iterations, newHash().Size(), newHash)
+	clientKey := computeHMAC(newHash, saltedPassword, []byte("Client Key"))
+	serverKey := computeHMAC(newHash, saltedPassword, []byte("Server Key"))
+	storedKey := computeHash(newHash, clientKey)
+
+	cbind := append([]byte(c.gs2Header), c.cbindData...)
+	clientFinal := "c=" + base64.StdEncoding.EncodeToString(cbind) + ",r=" + nonce
+	authMessage := []byte(c.clientFirstBare + "," + serverFirst + "," + clientFinal)
+
+	clientSignature := computeHMAC(newHash, storedKey, authMessage)
+	clientProof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		clientProof[i] = clientKey[i] ^ clientSignature[i]
+	}
+
+	c.serverSignature = computeHMAC(newHash, serverKey, authMessage)
+	return []byte(clientFinal + ",p=" + base64.StdEncoding.EncodeToString(clientProof)), nil
+}
+
+func (c *conversation) verify(serverFinal string) error {
+	switch {
+	case strings.HasPrefix(serverFinal, "e="):
+		return fmt.Errorf("scram server error: %s", serverFinal[2:])
+	case strings.HasPrefix(serverFinal, "v="):
+		verifier, err := base64.StdEncoding.DecodeString(serverFinal[2:])
+		if err != nil {
+			return fmt.Errorf("decoding scram server signature: %w", err)
+		}
+		if !hmac.Equal(verifier, c.serverSignature) {
+			return errors.New("scram server signature mismatch")
+		}
+		return nil
+	default:
+		return fmt.Errorf("malformed scram server final message: %q", serverFinal)
+	}
+}
+
+func computeHMAC(newHash func() hash.Hash, key, data []byte) []byte {
+	mac := hmac.New(newHash, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func computeHash(newHash func() hash.Hash, data []byte) []byte {
+	h := newHash()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func encodeName(s string) string {
+	return strings.NewReplacer("=", "=3D", ",", "=2C").Replace(s)
+}
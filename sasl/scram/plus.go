@@ -0,0 +1,106 @@
+package scram
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	// Register the hash functions that certificates may be signed with.
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+type plusMechanism struct {
+	algo     Algorithm
+	username string
+	password string
+}
+
+type plusSession struct {
+	convo *conversation
+}
+
+// PlusMechanism returns a new sasl.Mechanism that uses the channel binding
+// variant of SCRAM (SCRAM-SHA-256-PLUS or SCRAM-SHA-512-PLUS, RFC 5802) to bind
+// the authentication to the TLS connection that it happens on, which protects
+// the credentials against man-in-the-middle attacks even when the certificates
+// of the brokers are not verified.
+//
+// The mechanism uses the tls-server-end-point channel binding type (RFC 5929),
+// which is computed from the certificate presented by the broker. It can only
+// be used on TLS connections.
+func PlusMechanism(algo Algorithm, username, password string) (sasl.Mechanism, error) {
+	if username == "" {
+		return nil, errors.New("scram user name must not be empty")
+	}
+	return &plusMechanism{
+		algo:     algo,
+		username: username,
+		password: password,
+	}, nil
+}
+
+func (m *plusMechanism) Name() string {
+	return m.algo.Name() + "-PLUS"
+}
+
+func (m *plusMechanism) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	meta := sasl.MetadataFromContext(ctx)
+	if meta == nil || meta.TLS == nil {
+		return nil, nil, fmt.Errorf("%s requires a TLS connection", m.Name())
+	}
+
+	cbindData, err := tlsServerEndPoint(meta.TLS)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	convo := &conversation{
+		algo:      m.algo,
+		username:  m.username,
+		password:  m.password,
+		gs2Header: "p=tls-server-end-point,,",
+		cbindData: cbindData,
+	}
+	ir, err := convo.first()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &plusSession{convo: convo}, ir, nil
+}
+
+func (s *plusSession) Next(ctx context.Context, challenge []byte) (bool, []byte, error) {
+	return s.convo.next(challenge)
+}
+
+// tlsServerEndPoint returns the tls-server-end-point channel binding data of a
+// TLS connection, which is the hash of the certificate of the server.
+//
+// RFC 5929 section 4.1 specifies that the hash function is the one used in the
+// signature of the certificate, except for MD5 and SHA-1 which are replaced by
+// SHA-256. SHA-256 is also used for signature algorithms which do not involve a
+// separate hash function, like Ed25519.
+func tlsServerEndPoint(state *tls.ConnectionState) ([]byte, error) {
+	if len(state.PeerCertificates) == 0 {
+		return nil, errors.New("the TLS connection has no server certificate to compute the channel binding data from")
+	}
+
+	cert := state.PeerCertificates[0]
+	hash := crypto.SHA256
+
+	switch cert.SignatureAlgorithm {
+	case x509.SHA384WithRSA, x509.ECDSAWithSHA384, x509.SHA384WithRSAPSS:
+		hash = crypto.SHA384
+	case x509.SHA512WithRSA, x509.ECDSAWithSHA512, x509.SHA512WithRSAPSS:
+		hash = crypto.SHA512
+	}
+
+	h := hash.New()
+	h.Write(cert.Raw)
+	return h.Sum(nil), nil
+}
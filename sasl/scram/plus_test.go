@@ -0,0 +1,98 @@
+package scram
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+func TestPlusMechanism(t *testing.T) {
+	cert := &x509.Certificate{Raw: []byte("certificate"), SignatureAlgorithm: x509.SHA256WithRSA}
+	state := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+
+	mech, err := PlusMechanism(SHA256, "user", "pencil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := mech.Name(); name != "SCRAM-SHA-256-PLUS" {
+		t.Errorf("wrong mechanism name: %q", name)
+	}
+
+	ctx := sasl.WithMetadata(context.Background(), &sasl.Metadata{Host: "localhost", Port: 9093})
+	if _, _, err := mech.Start(ctx); err == nil {
+		t.Error("the mechanism should not be usable without TLS")
+	}
+
+	ctx = sasl.WithMetadata(context.Background(), &sasl.Metadata{Host: "localhost", Port: 9093, TLS: state})
+	sess, ir, err := mech.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const gs2Header = "p=tls-server-end-point,,"
+	if !strings.HasPrefix(string(ir), gs2Header+"n=user,r=") {
+		t.Fatalf("wrong client first message: %q", ir)
+	}
+	nonce := strings.TrimPrefix(string(ir), gs2Header+"n=user,r=")
+
+	serverFirst := "r=" + nonce + "server,s=" + base64.StdEncoding.EncodeToString([]byte("salt")) + ",i=4096"
+	done, clientFinal, err := sess.Next(ctx, []byte(serverFirst))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done {
+		t.Fatal("the conversation should not be done after the server first message")
+	}
+
+	hash := sha256.Sum256(cert.Raw)
+	cbind := base64.StdEncoding.EncodeToString(append([]byte(gs2Header), hash[:]...))
+	if !strings.HasPrefix(string(clientFinal), "c="+cbind+",r="+nonce+"server,p=") {
+		t.Errorf("wrong channel binding in the client final message: %q", clientFinal)
+	}
+}
+
+func TestTLSServerEndPoint(t *testing.T) {
+	raw := []byte("certificate")
+	sum256 := sha256.Sum256(raw)
+	sum384 := sha512.Sum384(raw)
+	sum512 := sha512.Sum512(raw)
+
+	tests := []struct {
+		algo x509.SignatureAlgorithm
+		hash []byte
+	}{
+		{algo: x509.SHA1WithRSA, hash: sum256[:]},
+		{algo: x509.MD5WithRSA, hash: sum256[:]},
+		{algo: x509.ECDSAWithSHA256, hash: sum256[:]},
+		{algo: x509.ECDSAWithSHA384, hash: sum384[:]},
+		{algo: x509.SHA512WithRSAPSS, hash: sum512[:]},
+		{algo: x509.PureEd25519, hash: sum256[:]},
+	}
+
+	for _, test := range tests {
+		t.Run(test.algo.String(), func(t *testing.T) {
+			state := &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{{Raw: raw, SignatureAlgorithm: test.algo}},
+			}
+			hash, err := tlsServerEndPoint(state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(hash, test.hash) {
+				t.Errorf("wrong channel binding data: want=%x got=%x", test.hash, hash)
+			}
+		})
+	}
+
+	if _, err := tlsServerEndPoint(&tls.ConnectionState{}); err == nil {
+		t.Error("connections without certificates should have no channel binding data")
+	}
+}
@@ -0,0 +1,63 @@
+package scram
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+
+	"github.com/segmentio/kafka-go/sasl"
+)
+
+type tokenMechanism struct {
+	algo     Algorithm
+	tokenID  string
+	password string
+}
+
+type tokenSession struct {
+	convo *conversation
+}
+
+// DelegationTokenMechanism returns a new sasl.Mechanism that authenticates with
+// a kafka delegation token, using the token ID and HMAC returned by the
+// CreateDelegationToken API.
+//
+// Delegation tokens authenticate using the SCRAM mechanism that the brokers
+// were configured to use for tokens, which is why an Algorithm must be passed
+// as argument. Delegation tokens were added to Kafka in 1.1.0.
+func DelegationTokenMechanism(algo Algorithm, tokenID string, hmac []byte) (sasl.Mechanism, error) {
+	if tokenID == "" {
+		return nil, errors.New("delegation token ID must not be empty")
+	}
+	if len(hmac) == 0 {
+		return nil, errors.New("delegation token HMAC must not be empty")
+	}
+	return &tokenMechanism{
+		algo:     algo,
+		tokenID:  tokenID,
+		password: base64.StdEncoding.EncodeToString(hmac),
+	}, nil
+}
+
+func (m *tokenMechanism) Name() string {
+	return m.algo.Name()
+}
+
+func (m *tokenMechanism) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	convo := &conversation{
+		algo:       m.algo,
+		username:   m.tokenID,
+		password:   m.password,
+		extensions: []string{"tokenauth=true"},
+		gs2Header:  "n,,",
+	}
+	ir, err := convo.first()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &tokenSession{convo: convo}, ir, nil
+}
+
+func (s *tokenSession) Next(ctx context.Context, challenge []byte) (bool, []byte, error) {
+	return s.convo.next(challenge)
+}
@@ -0,0 +1,69 @@
+package scram
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/xdg/scram"
+)
+
+func TestDelegationTokenMechanism(t *testing.T) {
+	tokenID := "token-id"
+	hmac := []byte("token-hmac")
+
+	for _, algo := range []Algorithm{SHA256, SHA512} {
+		t.Run(algo.Name(), func(t *testing.T) {
+			hashGen := scram.HashGeneratorFcn(algo.Hash)
+			client, err := hashGen.NewClient(tokenID, base64.StdEncoding.EncodeToString(hmac), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			creds := client.GetStoredCredentials(scram.KeyFactors{Salt: "salt", Iters: 4096})
+
+			server, err := hashGen.NewServer(func(user string) (scram.StoredCredentials, error) {
+				if user != tokenID {
+					t.Errorf("unexpected user name: %q", user)
+				}
+				return creds, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			serverConvo := server.NewConversation()
+
+			mech, err := DelegationTokenMechanism(algo, tokenID, hmac)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			sess, ir, err := mech.Start(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.HasSuffix(string(ir), ",tokenauth=true") {
+				t.Errorf("expected the tokenauth extension in the client first message: %q", ir)
+			}
+
+			challenge := ir
+			done := false
+			for !done {
+				serverMsg, err := serverConvo.Step(string(challenge))
+				if err != nil {
+					t.Fatal(err)
+				}
+				done, challenge, err = sess.Next(ctx, []byte(serverMsg))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if !serverConvo.Valid() {
+				t.Error("server did not validate the client proof")
+			}
+		})
+	}
+}
@@ -52,3 +52,31 @@ func (t *saslAuthenticateResponseV0) readFrom(r *bufio.Reader, sz int) (remain i
 	}
 	return
 }
+
+type saslAuthenticateResponseV1 struct {
+	saslAuthenticateResponseV0
+
+	// SessionLifetimeMs holds the number of milliseconds after which the
+	// broker closes the connection if it was not re-authenticated, or zero
+	// if the session does not expire.
+	SessionLifetimeMs int64
+}
+
+func (t saslAuthenticateResponseV1) size() int32 {
+	return t.saslAuthenticateResponseV0.size() + sizeofInt64(t.SessionLifetimeMs)
+}
+
+func (t saslAuthenticateResponseV1) writeTo(wb *writeBuffer) {
+	t.saslAuthenticateResponseV0.writeTo(wb)
+	wb.writeInt64(t.SessionLifetimeMs)
+}
+
+func (t *saslAuthenticateResponseV1) readFrom(r *bufio.Reader, sz int) (remain int, err error) {
+	if remain, err = t.saslAuthenticateResponseV0.readFrom(r, sz); err != nil {
+		return
+	}
+	if remain, err = readInt64(r, remain, &t.SessionLifetimeMs); err != nil {
+		return
+	}
+	return
+}
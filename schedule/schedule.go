@@ -0,0 +1,391 @@
+// Package schedule implements delayed delivery of kafka messages.
+//
+// Programs produce messages to a delay topic with headers carrying the time at
+// which the messages are due and the topic that they must be delivered to (see
+// At). A Scheduler consumes the delay topic, holds the messages until they are
+// due, and then produces them to their target topic.
+//
+// This package does not make any promises around backwards compatibility.
+package schedule
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Names of the headers carrying the scheduling information of messages
+// produced to the delay topic.
+const (
+	// The time in milliseconds since the unix epoch at which the message is
+	// due.
+	HeaderDueTime = "schedule-due-time"
+	// The topic that the message is delivered to.
+	HeaderTargetTopic = "schedule-target-topic"
+)
+
+const defaultMaxPending = 10000
+
+// At returns a copy of msg carrying the headers which schedule it to be
+// delivered to topic at the due time. The returned message must be produced
+// to the delay topic consumed by a Scheduler.
+func At(msg kafka.Message, topic string, due time.Time) kafka.Message {
+	msg.Headers = append([]kafka.Header(nil), msg.Headers...)
+	msg.SetHeader(HeaderTargetTopic, []byte(topic))
+	// The time is rounded up to the next millisecond so the message is never
+	// delivered early.
+	ms := (due.UnixNano() + int64(time.Millisecond) - 1) / int64(time.Millisecond)
+	msg.SetHeader(HeaderDueTime, []byte(strconv.FormatInt(ms, 10)))
+	return msg
+}
+
+// Scheduler consumes a delay topic and delivers its messages to their target
+// topics when they are due.
+//
+// The messages are held in memory until they are due. The scheduler never
+// commits the offset of a message which was not delivered yet, so messages
+// held when the program stops are read again when it restarts. Messages may be
+// delivered more than once when the program restarts or the consumer group
+// rebalances, the scheduler gives at-least-once semantics.
+type Scheduler struct {
+	// The list of broker addresses used to connect to the kafka cluster.
+	//
+	// This field is required.
+	Brokers []string
+
+	// The consumer group consuming the delay topic.
+	//
+	// This field is required.
+	GroupID string
+
+	// The delay topic.
+	//
+	// This field is required.
+	Topic string
+
+	// The writer used to deliver the messages to their target topics. Its
+	// Topic field must be empty.
+	//
+	// This field is required.
+	Writer *kafka.Writer
+
+	// The dialer used by the reader of the delay topic.
+	Dialer *kafka.Dialer
+
+	// Maximum amount of time that the reader waits for new messages.
+	MaxWait time.Duration
+
+	// Limit on the number of messages held by the scheduler. When reached,
+	// the scheduler stops consuming the delay topic until messages are
+	// delivered.
+	//
+	// Default: 10000
+	MaxPending int
+
+	// The clock used to wait for messages to be due.
+	//
+	// Default: kafka.SystemClock
+	Clock kafka.Clock
+
+	// An optional function called with the messages of the delay topic which
+	// cannot be delivered because they have no target topic. The messages
+	// are skipped.
+	OnInvalid func(msg kafka.Message, err error)
+}
+
+// Run consumes the delay topic until ctx is canceled, in which case it
+// returns the context error, or until delivering messages fails.
+func (s *Scheduler) Run(ctx context.Context) error {
+	switch {
+	case len(s.Brokers) == 0:
+		return errors.New("schedule.(*Scheduler).Run: at least one broker is required")
+	case s.GroupID == "":
+		return errors.New("schedule.(*Scheduler).Run: a group id is required")
+	case s.Topic == "":
+		return errors.New("schedule.(*Scheduler).Run: a topic is required")
+	case s.Writer == nil:
+		return errors.New("schedule.(*Scheduler).Run: a writer is required")
+	case s.Writer.Topic != "":
+		return errors.New("schedule.(*Scheduler).Run: the writer must not have a topic")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: s.Brokers,
+		GroupID: s.GroupID,
+		Topic:   s.Topic,
+		Dialer:  s.Dialer,
+		MaxWait: s.MaxWait,
+	})
+	defer r.Close()
+
+	msgs := make(chan kafka.Message)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := r.FetchMessage(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case msgs <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	clock := s.clock()
+	maxPending := s.maxPending()
+	queue := &queue{}
+	offsets := make(map[partition]*offsetTracker)
+
+	var timer kafka.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		// Deliver the messages which are due, and commit the offsets up to
+		// the first message which was not delivered on each partition.
+		var due []*entry
+		now := clock.Now()
+		for queue.Len() != 0 && !(*queue)[0].due.After(now) {
+			due = append(due, heap.Pop(queue).(*entry))
+		}
+
+		if len(due) != 0 {
+			if err := s.deliver(ctx, due); err != nil {
+				return err
+			}
+			if err := commit(ctx, r, offsets, due); err != nil {
+				return err
+			}
+		}
+
+		var wakeup <-chan time.Time
+		if queue.Len() != 0 {
+			d := (*queue)[0].due.Sub(now)
+			if timer == nil {
+				timer = clock.NewTimer(d)
+			} else {
+				timer.Stop()
+				timer.Reset(d)
+			}
+			wakeup = timer.C()
+		}
+
+		input := msgs
+		if queue.Len() >= maxPending {
+			input = nil
+		}
+
+		select {
+		case msg := <-input:
+			e := &entry{msg: msg, due: now}
+			key := partition{topic: msg.Topic, partition: msg.Partition}
+			t := offsets[key]
+			if t == nil {
+				t = &offsetTracker{}
+				offsets[key] = t
+			}
+			t.add(msg.Offset)
+
+			if err := s.parse(e); err != nil {
+				// The message is skipped by committing it as if it was
+				// delivered.
+				if s.OnInvalid != nil {
+					s.OnInvalid(msg, err)
+				}
+				e.invalid = true
+			}
+			heap.Push(queue, e)
+
+		case <-wakeup:
+
+		case err := <-errs:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Scheduler) clock() kafka.Clock {
+	if s.Clock != nil {
+		return s.Clock
+	}
+	return kafka.SystemClock
+}
+
+func (s *Scheduler) maxPending() int {
+	if s.MaxPending > 0 {
+		return s.MaxPending
+	}
+	return defaultMaxPending
+}
+
+// parse reads the scheduling headers of the message of e. Messages without a
+// due time are delivered immediately.
+func (s *Scheduler) parse(e *entry) error {
+	topic, ok := e.msg.Header(HeaderTargetTopic)
+	if !ok || len(topic) == 0 {
+		return fmt.Errorf("message at offset %d of %s/%d has no %s header", e.msg.Offset, e.msg.Topic, e.msg.Partition, HeaderTargetTopic)
+	}
+	e.topic = string(topic)
+
+	if due, ok := e.msg.Header(HeaderDueTime); ok {
+		ms, err := strconv.ParseInt(string(due), 10, 64)
+		if err != nil {
+			return fmt.Errorf("message at offset %d of %s/%d has an invalid %s header: %w", e.msg.Offset, e.msg.Topic, e.msg.Partition, HeaderDueTime, err)
+		}
+		e.due = time.Unix(0, ms*int64(time.Millisecond))
+	}
+	return nil
+}
+
+// deliver produces the messages of the entries to their target topics.
+func (s *Scheduler) deliver(ctx context.Context, entries []*entry) error {
+	msgs := make([]kafka.Message, 0, len(entries))
+	for _, e := range entries {
+		if e.invalid {
+			continue
+		}
+		msg := kafka.Message{
+			Topic: e.topic,
+			Key:   e.msg.Key,
+			Value: e.msg.Value,
+		}
+		for _, h := range e.msg.Headers {
+			switch h.Key {
+			case HeaderDueTime, HeaderTargetTopic:
+			default:
+				msg.Headers = append(msg.Headers, h)
+			}
+		}
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	if err := s.Writer.WriteMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("delivering %d scheduled messages: %w", len(msgs), err)
+	}
+	return nil
+}
+
+// commit marks the entries as delivered, and commits the offsets of the
+// partitions which advanced.
+func commit(ctx context.Context, r *kafka.Reader, offsets map[partition]*offsetTracker, entries []*entry) error {
+	advanced := make(map[partition]struct{})
+	for _, e := range entries {
+		key := partition{topic: e.msg.Topic, partition: e.msg.Partition}
+		if offsets[key].release(e.msg.Offset) {
+			advanced[key] = struct{}{}
+		}
+	}
+
+	msgs := make([]kafka.Message, 0, len(advanced))
+	for key := range advanced {
+		// CommitMessages commits the offset following the one of the
+		// messages.
+		msgs = append(msgs, kafka.Message{
+			Topic:     key.topic,
+			Partition: key.partition,
+			Offset:    offsets[key].committable() - 1,
+		})
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return r.CommitMessages(ctx, msgs...)
+}
+
+type partition struct {
+	topic     string
+	partition int
+}
+
+// offsetTracker tracks the offsets of the messages of a partition which were
+// not delivered yet. Offsets are added in increasing order.
+type offsetTracker struct {
+	pending []pendingOffset
+	next    int64
+}
+
+type pendingOffset struct {
+	offset   int64
+	released bool
+}
+
+func (t *offsetTracker) add(offset int64) {
+	t.pending = append(t.pending, pendingOffset{offset: offset})
+	t.next = offset + 1
+}
+
+// release marks the offset as delivered, and reports whether the committable
+// offset advanced.
+func (t *offsetTracker) release(offset int64) bool {
+	i := sort.Search(len(t.pending), func(i int) bool { return t.pending[i].offset >= offset })
+	if i == len(t.pending) || t.pending[i].offset != offset {
+		return false
+	}
+	t.pending[i].released = true
+
+	n := 0
+	for n < len(t.pending) && t.pending[n].released {
+		n++
+	}
+	t.pending = t.pending[n:]
+	return n != 0
+}
+
+// committable returns the offset that the consumer group can commit, which is
+// the one of the first message not delivered yet.
+func (t *offsetTracker) committable() int64 {
+	if len(t.pending) != 0 {
+		return t.pending[0].offset
+	}
+	return t.next
+}
+
+type entry struct {
+	msg     kafka.Message
+	topic   string
+	due     time.Time
+	invalid bool
+}
+
+// queue is a heap of entries ordered by due time.
+type queue []*entry
+
+func (q queue) Len() int { return len(q) }
+
+func (q queue) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+
+func (q queue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *queue) Push(x interface{}) { *q = append(*q, x.(*entry)) }
+
+func (q *queue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return e
+}
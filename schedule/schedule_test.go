@@ -0,0 +1,145 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func TestScheduler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t)
+	for _, topic := range []string{"delay", "target-A", "target-B"} {
+		if err := b.CreateTopic(topic, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	msgs := []kafka.Message{
+		At(kafka.Message{Value: []byte("later")}, "target-A", now.Add(100*time.Millisecond)),
+		At(kafka.Message{Value: []byte("soon")}, "target-A", now.Add(50*time.Millisecond)),
+		{Value: []byte("invalid")},
+		At(kafka.Message{
+			Value:   []byte("now"),
+			Headers: []kafka.Header{{Key: "h", Value: []byte("v")}},
+		}, "target-B", now.Add(-time.Second)),
+	}
+	for _, msg := range msgs {
+		record := kafkatest.Record{Value: msg.Value, Headers: msg.Headers}
+		if _, err := b.Append("delay", 0, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mutex sync.Mutex
+	var invalid []string
+
+	s := &Scheduler{
+		Brokers: []string{b.Addr().String()},
+		GroupID: "scheduler",
+		Topic:   "delay",
+		Writer: &kafka.Writer{
+			Addr:         b.Addr(),
+			BatchTimeout: time.Millisecond,
+			RequiredAcks: kafka.RequireAll,
+		},
+		MaxWait: 10 * time.Millisecond,
+		OnInvalid: func(msg kafka.Message, err error) {
+			mutex.Lock()
+			invalid = append(invalid, string(msg.Value))
+			mutex.Unlock()
+		},
+	}
+	defer s.Writer.Close()
+
+	errs := make(chan error, 1)
+	go func() { errs <- s.Run(ctx) }()
+
+	for len(b.Records("target-A", 0)) < 2 {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case err := <-errs:
+			t.Fatal(err)
+		}
+	}
+	elapsed := time.Since(now)
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v but got %v", context.Canceled, err)
+	}
+
+	if elapsed < 100*time.Millisecond {
+		t.Errorf("messages delivered before they were due: %v", elapsed)
+	}
+
+	records := b.Records("target-A", 0)
+	if string(records[0].Value) != "soon" || string(records[1].Value) != "later" {
+		t.Errorf("messages not delivered in the order of their due time: %q, %q", records[0].Value, records[1].Value)
+	}
+	for _, h := range records[0].Headers {
+		if h.Key == HeaderDueTime || h.Key == HeaderTargetTopic {
+			t.Errorf("scheduling header delivered to the target topic: %s", h.Key)
+		}
+	}
+
+	records = b.Records("target-B", 0)
+	if len(records) != 1 || string(records[0].Value) != "now" || len(records[0].Headers) != 1 {
+		t.Errorf("wrong records delivered to target-B: %+v", records)
+	}
+
+	mutex.Lock()
+	if len(invalid) != 1 || invalid[0] != "invalid" {
+		t.Errorf("wrong invalid messages: %q", invalid)
+	}
+	mutex.Unlock()
+
+	if offset, ok := b.CommittedOffset("scheduler", "delay", 0); !ok || offset != 4 {
+		t.Errorf("wrong committed offset: %d (%t)", offset, ok)
+	}
+}
+
+func TestOffsetTracker(t *testing.T) {
+	tracker := &offsetTracker{}
+	for _, offset := range []int64{3, 5, 6} {
+		tracker.add(offset)
+	}
+
+	if tracker.release(5) {
+		t.Error("releasing an offset after a pending one must not advance the committable offset")
+	}
+	if offset := tracker.committable(); offset != 3 {
+		t.Errorf("wrong committable offset: %d", offset)
+	}
+
+	if !tracker.release(3) {
+		t.Error("releasing the first pending offset must advance the committable offset")
+	}
+	if offset := tracker.committable(); offset != 6 {
+		t.Errorf("wrong committable offset: %d", offset)
+	}
+
+	tracker.release(6)
+	if offset := tracker.committable(); offset != 7 {
+		t.Errorf("wrong committable offset: %d", offset)
+	}
+}
+
+func TestSchedulerConfig(t *testing.T) {
+	s := &Scheduler{
+		Brokers: []string{"localhost:9092"},
+		GroupID: "scheduler",
+		Topic:   "delay",
+		Writer:  &kafka.Writer{Topic: "target"},
+	}
+	if err := s.Run(context.Background()); err == nil {
+		t.Error("expected a writer with a topic to be rejected")
+	}
+}
@@ -0,0 +1,22 @@
+//go:build go1.21
+// +build go1.21
+
+package kafka
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger returns a StructuredLogger writing messages to l.
+func SlogLogger(l *slog.Logger) StructuredLogger {
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l slogLogger) Log(ctx context.Context, level LogLevel, msg string, args ...interface{}) {
+	l.logger.Log(ctx, slog.Level(level), msg, args...)
+}
@@ -0,0 +1,28 @@
+//go:build go1.21
+// +build go1.21
+
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := SlogLogger(slog.New(slog.NewTextHandler(buf, nil)))
+
+	newLogger(logger, nil, LogLevelWarn, "topic", "topic-1", "partition", 2).Printf("lost %d messages", 3)
+	logger.Log(context.Background(), LogLevelDebug, "not logged")
+
+	out := buf.String()
+	if !strings.Contains(out, `level=WARN msg="lost 3 messages" topic=topic-1 partition=2`) {
+		t.Errorf("unexpected output: %s", out)
+	}
+	if strings.Contains(out, "not logged") {
+		t.Errorf("debug messages should be dropped by the default handler: %s", out)
+	}
+}
@@ -0,0 +1,55 @@
+package kafka
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"time"
+)
+
+// socketOptions carries the TCP options set on the connections established by
+// dialers and transports.
+type socketOptions struct {
+	keepAliveInterval time.Duration
+	keepAliveCount    int
+	userTimeout       time.Duration
+}
+
+func (opts socketOptions) empty() bool {
+	return opts.keepAliveInterval <= 0 && opts.keepAliveCount <= 0 && opts.userTimeout <= 0
+}
+
+// dial wraps a dial function to set the socket options on the connections that
+// it establishes. The options are set after the connections were established
+// so they take precedence over the keep-alive settings applied by net.Dialer.
+func (opts socketOptions) dial(dial func(context.Context, string, string) (net.Conn, error)) func(context.Context, string, string) (net.Conn, error) {
+	if opts.empty() {
+		return dial
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		c, err := dial(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		if err := opts.apply(c); err != nil {
+			c.Close()
+			return nil, err
+		}
+		return c, nil
+	}
+}
+
+func (opts socketOptions) apply(c net.Conn) error {
+	sc, ok := c.(syscall.Conn)
+	if !ok {
+		return nil
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	if ctrlErr := raw.Control(func(fd uintptr) { err = setSocketOptions(fd, opts) }); ctrlErr != nil {
+		return ctrlErr
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+//go:build linux
+// +build linux
+
+package kafka
+
+import (
+	"syscall"
+	"time"
+)
+
+// The value of TCP_USER_TIMEOUT, which the syscall package does not define
+// on all architectures.
+const tcpUserTimeout = 0x12
+
+func setSocketOptions(fd uintptr, opts socketOptions) error {
+	if opts.keepAliveInterval > 0 {
+		secs := int((opts.keepAliveInterval + time.Second - 1) / time.Second)
+		if err := syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, secs); err != nil {
+			return err
+		}
+	}
+	if opts.keepAliveCount > 0 {
+		if err := syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, opts.keepAliveCount); err != nil {
+			return err
+		}
+	}
+	if opts.userTimeout > 0 {
+		ms := int((opts.userTimeout + time.Millisecond - 1) / time.Millisecond)
+		if err := syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, tcpUserTimeout, ms); err != nil {
+			return err
+		}
+	}
+	return nil
+}
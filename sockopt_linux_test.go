@@ -0,0 +1,51 @@
+package kafka
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestTransportSocketOptions(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	transport := &Transport{
+		KeepAlive:      5 * time.Second,
+		KeepAliveCount: 3,
+		TCPUserTimeout: 10 * time.Second,
+	}
+
+	c, err := transport.dial()(context.Background(), "tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	raw, err := c.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keepAliveCount, userTimeout, keepAliveInterval int
+	raw.Control(func(fd uintptr) {
+		keepAliveCount, _ = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT)
+		keepAliveInterval, _ = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL)
+		userTimeout, _ = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, tcpUserTimeout)
+	})
+
+	if keepAliveCount != 3 {
+		t.Errorf("wrong keep-alive count: want=3 got=%d", keepAliveCount)
+	}
+	if keepAliveInterval != 5 {
+		t.Errorf("wrong keep-alive interval: want=5 got=%d", keepAliveInterval)
+	}
+	if userTimeout != 10000 {
+		t.Errorf("wrong TCP user timeout: want=10000 got=%d", userTimeout)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package kafka
+
+// The socket options are only supported on linux, they are ignored on other
+// platforms.
+func setSocketOptions(fd uintptr, opts socketOptions) error {
+	return nil
+}
@@ -0,0 +1,145 @@
+package kafka_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+// startOffsetsTopic has two partitions of five records, with values
+// formatted as "<partition>-<index>".
+func startOffsetsTopic() kafkatest.Topic {
+	records := make(map[int][]kafkatest.Record, 2)
+	for p := 0; p < 2; p++ {
+		for i := 0; i < 5; i++ {
+			records[p] = append(records[p], kafkatest.Record{Value: []byte(fmt.Sprintf("%d-%d", p, i))})
+		}
+	}
+	return kafkatest.Topic{Name: "topic-A", Partitions: 2, Records: records}
+}
+
+func TestReaderStartOffsets(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, startOffsetsTopic())
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:      []string{b.Addr().String()},
+		Topic:        "topic-A",
+		Partition:    1,
+		MaxWait:      10 * time.Millisecond,
+		StartOffsets: map[int]int64{1: 3},
+	})
+	defer r.Close()
+
+	m, err := r.ReadMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Offset != 3 || string(m.Value) != "1-3" {
+		t.Errorf("wrong first message: offset=%d value=%q", m.Offset, m.Value)
+	}
+}
+
+func TestReaderStartOffsetsGroup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, startOffsetsTopic())
+
+	var mutex sync.Mutex
+	var assigned map[string]map[int]int64
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:           []string{b.Addr().String()},
+		GroupID:           "group-A",
+		Topic:             "topic-A",
+		MaxWait:           10 * time.Millisecond,
+		HeartbeatInterval: 100 * time.Millisecond,
+		StartOffsets:      map[int]int64{0: 4},
+		StartOffsetsFunc: func(offsets map[string]map[int]int64) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			assigned = make(map[string]map[int]int64)
+			for topic, partitions := range offsets {
+				assigned[topic] = make(map[int]int64)
+				for p, o := range partitions {
+					assigned[topic][p] = o
+				}
+			}
+			offsets["topic-A"][1] = 2
+		},
+	})
+	defer r.Close()
+
+	got := make(map[string]bool)
+	for len(got) < 4 {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[string(m.Value)] = true
+	}
+
+	for _, v := range []string{"0-4", "1-2", "1-3", "1-4"} {
+		if !got[v] {
+			t.Errorf("message %q was not read: %v", v, got)
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if o := assigned["topic-A"][0]; o != 4 {
+		t.Errorf("StartOffsetsFunc saw offset %d for partition 0, want 4", o)
+	}
+	if o := assigned["topic-A"][1]; o != kafka.FirstOffset {
+		t.Errorf("StartOffsetsFunc saw offset %d for partition 1, want %d", o, kafka.FirstOffset)
+	}
+}
+
+func TestReaderStartOffsetsValidate(t *testing.T) {
+	tests := []struct {
+		scenario string
+		config   kafka.ReaderConfig
+	}{
+		{
+			scenario: "invalid offset",
+			config: kafka.ReaderConfig{
+				Brokers:      []string{"localhost:9092"},
+				Topic:        "topic-A",
+				StartOffsets: map[int]int64{0: -5},
+			},
+		},
+		{
+			scenario: "partition not read",
+			config: kafka.ReaderConfig{
+				Brokers:      []string{"localhost:9092"},
+				Topic:        "topic-A",
+				StartOffsets: map[int]int64{1: 0},
+			},
+		},
+		{
+			scenario: "group topics",
+			config: kafka.ReaderConfig{
+				Brokers:      []string{"localhost:9092"},
+				GroupID:      "group-A",
+				GroupTopics:  []string{"topic-A", "topic-B"},
+				StartOffsets: map[int]int64{0: 0},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			if err := test.config.Validate(); err == nil {
+				t.Error("expected a validation error")
+			}
+		})
+	}
+}
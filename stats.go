@@ -21,22 +21,62 @@ type DurationStats struct {
 	Max time.Duration `metric:"max" type:"gauge"`
 }
 
-// counter is an atomic incrementing counter which gets reset on snapshot.
+// StatsMode configures the values reported by the counters and summaries of
+// stats snapshots. Gauges report the same values in all modes.
+type StatsMode int
+
+const (
+	// DeltaStats reports the values observed since the previous delta
+	// snapshot, this is the mode used by the Stats methods.
+	DeltaStats StatsMode = iota
+
+	// CumulativeStats reports the values observed since the reader or writer
+	// was created. Cumulative snapshots do not change the values reported by
+	// the next delta snapshot.
+	CumulativeStats
+)
+
+// cacheLineSize is the size of the padding placed between statistics which are
+// updated by different goroutines, so they are not in the same CPU cache line.
+const cacheLineSize = 64
+
+// total is an atomic incrementing value which remembers the value reported by
+// the last delta snapshot.
 //
 // Since atomic is used to mutate the statistic the value must be 64-bit aligned.
 // See https://golang.org/pkg/sync/atomic/#pkg-note-BUG
-type counter int64
+type total struct {
+	value int64
+	last  int64
+}
 
-func (c *counter) ptr() *int64 {
-	return (*int64)(c)
+func (t *total) observe(v int64) {
+	atomic.AddInt64(&t.value, v)
 }
 
-func (c *counter) observe(v int64) {
-	atomic.AddInt64(c.ptr(), v)
+func (t *total) snapshot(mode StatsMode) int64 {
+	if mode == CumulativeStats {
+		return atomic.LoadInt64(&t.value)
+	}
+	for {
+		// The last value is loaded first so it is never greater than the
+		// current value, even when snapshots are taken concurrently.
+		last := atomic.LoadInt64(&t.last)
+		value := atomic.LoadInt64(&t.value)
+		if atomic.CompareAndSwapInt64(&t.last, last, value) {
+			return value - last
+		}
+	}
 }
 
-func (c *counter) snapshot() int64 {
-	return atomic.SwapInt64(c.ptr(), 0)
+// counter is a total padded to occupy its own cache line.
+type counter struct {
+	total
+	_ [cacheLineSize - 16]byte
+}
+
+func (c *counter) ptr() *int64 {
+	return &c.value
 }
 
 // gauge is an atomic integer that may be set to any arbitrary value, the value
@@ -44,18 +84,17 @@ func (c *counter) snapshot() int64 {
 //
 // Since atomic is used to mutate the statistic the value must be 64-bit aligned.
 // See https://golang.org/pkg/sync/atomic/#pkg-note-BUG
-type gauge int64
-
-func (g *gauge) ptr() *int64 {
-	return (*int64)(g)
+type gauge struct {
+	value int64
+	_     [cacheLineSize - 8]byte
 }
 
 func (g *gauge) observe(v int64) {
-	atomic.StoreInt64(g.ptr(), v)
+	atomic.StoreInt64(&g.value, v)
 }
 
 func (g *gauge) snapshot() int64 {
-	return atomic.LoadInt64(g.ptr())
+	return atomic.LoadInt64(&g.value)
 }
 
 // minimum is an atomic integral type that keeps track of the minimum of all
@@ -94,6 +133,13 @@ func (m *minimum) snapshot() int64 {
 	return v
 }
 
+func (m *minimum) load() int64 {
+	if v := atomic.LoadInt64(m.ptr()); v >= 0 {
+		return v
+	}
+	return 0
+}
+
 // maximum is an atomic integral type that keeps track of the maximum of all
 // values that it observed between snapshots.
 //
@@ -130,23 +176,39 @@ func (m *maximum) snapshot() int64 {
 	return v
 }
 
+func (m *maximum) load() int64 {
+	if v := atomic.LoadInt64(m.ptr()); v >= 0 {
+		return v
+	}
+	return 0
+}
+
+// summary tracks the minimum and maximum of the values observed since the last
+// delta snapshot, and since it was created. The values of a summary are updated
+// together, so they are not padded.
 type summary struct {
-	min   minimum
-	max   maximum
-	sum   counter
-	count counter
+	min    minimum
+	max    maximum
+	minAll minimum
+	maxAll maximum
+	sum    total
+	count  total
 }
 
 func makeSummary() summary {
 	return summary{
-		min: -1,
-		max: -1,
+		min:    -1,
+		max:    -1,
+		minAll: -1,
+		maxAll: -1,
 	}
 }
 
 func (s *summary) observe(v int64) {
 	s.min.observe(v)
 	s.max.observe(v)
+	s.minAll.observe(v)
+	s.maxAll.observe(v)
 	s.sum.observe(v)
 	s.count.observe(1)
 }
@@ -155,12 +217,17 @@ func (s *summary) observeDuration(v time.Duration) {
 	s.observe(int64(v))
 }
 
-func (s *summary) snapshot() SummaryStats {
+func (s *summary) snapshot(mode StatsMode) SummaryStats {
+	var min, max int64
+	if mode == CumulativeStats {
+		min, max = s.minAll.load(), s.maxAll.load()
+	} else {
+		min, max = s.min.snapshot(), s.max.snapshot()
+	}
+
 	avg := int64(0)
-	min := s.min.snapshot()
-	max := s.max.snapshot()
-	sum := s.sum.snapshot()
-	count := s.count.snapshot()
+	sum := s.sum.snapshot(mode)
+	count := s.count.snapshot(mode)
 
 	if count != 0 {
 		avg = int64(float64(sum) / float64(count))
@@ -173,11 +240,85 @@ func (s *summary) snapshot() SummaryStats {
 	}
 }
 
-func (s *summary) snapshotDuration() DurationStats {
-	summary := s.snapshot()
+func (s *summary) snapshotDuration(mode StatsMode) DurationStats {
+	summary := s.snapshot(mode)
 	return DurationStats{
 		Avg: time.Duration(summary.Avg),
 		Min: time.Duration(summary.Min),
 		Max: time.Duration(summary.Max),
 	}
 }
+
+// histogramBounds are the upper bounds of the buckets of duration histograms.
+var histogramBounds = [...]time.Duration{
+	1 * time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// DurationHistogram is a data structure that carries the distribution of
+// observed duration values.
+type DurationHistogram struct {
+	// The number of observed values, and their sum.
+	Count int64
+	Sum   time.Duration
+
+	// The cumulative counts of values lower or equal to the upper bound of
+	// each bucket, ordered by increasing bounds. Values greater than the last
+	// bound are only counted in Count.
+	Buckets []HistogramBucket
+}
+
+// HistogramBucket is a bucket of a DurationHistogram.
+type HistogramBucket struct {
+	UpperBound time.Duration
+	Count      int64
+}
+
+// histogram is a set of atomic totals tracking the distribution of durations
+// observed between snapshots.
+//
+// Since atomic is used to mutate the statistic the value must be 64-bit aligned.
+// See https://golang.org/pkg/sync/atomic/#pkg-note-BUG
+type histogram struct {
+	count   total
+	sum     total
+	buckets [len(histogramBounds)]total
+}
+
+func (h *histogram) observe(v time.Duration) {
+	for i, bound := range histogramBounds {
+		if v <= bound {
+			h.buckets[i].observe(1)
+			break
+		}
+	}
+	h.sum.observe(int64(v))
+	h.count.observe(1)
+}
+
+func (h *histogram) snapshot() DurationHistogram {
+	buckets := make([]HistogramBucket, len(histogramBounds))
+	count := int64(0)
+
+	for i, bound := range histogramBounds {
+		count += h.buckets[i].snapshot(DeltaStats)
+		buckets[i] = HistogramBucket{UpperBound: bound, Count: count}
+	}
+
+	return DurationHistogram{
+		Count:   h.count.snapshot(DeltaStats),
+		Sum:     time.Duration(h.sum.snapshot(DeltaStats)),
+		Buckets: buckets,
+	}
+}
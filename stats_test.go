@@ -0,0 +1,70 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsModes(t *testing.T) {
+	s := makeSummary()
+	c := &counter{}
+
+	for _, v := range []int64{2, 4} {
+		s.observe(v)
+		c.observe(v)
+	}
+	if v := c.snapshot(CumulativeStats); v != 6 {
+		t.Errorf("wrong cumulative counter: %d", v)
+	}
+	if v := c.snapshot(DeltaStats); v != 6 {
+		t.Errorf("wrong first delta counter: %d", v)
+	}
+	if v := s.snapshot(DeltaStats); v != (SummaryStats{Avg: 3, Min: 2, Max: 4}) {
+		t.Errorf("wrong first delta summary: %+v", v)
+	}
+
+	s.observe(12)
+	c.observe(12)
+	if v := c.snapshot(DeltaStats); v != 12 {
+		t.Errorf("wrong second delta counter: %d", v)
+	}
+	if v := s.snapshot(DeltaStats); v != (SummaryStats{Avg: 12, Min: 12, Max: 12}) {
+		t.Errorf("wrong second delta summary: %+v", v)
+	}
+	if v := c.snapshot(CumulativeStats); v != 18 {
+		t.Errorf("wrong cumulative counter: %d", v)
+	}
+	if v := s.snapshot(CumulativeStats); v != (SummaryStats{Avg: 6, Min: 2, Max: 12}) {
+		t.Errorf("wrong cumulative summary: %+v", v)
+	}
+
+	if v := c.snapshot(DeltaStats); v != 0 {
+		t.Errorf("cumulative snapshots must not change delta snapshots: %d", v)
+	}
+	if v := s.snapshot(DeltaStats); v != (SummaryStats{}) {
+		t.Errorf("cumulative snapshots must not change delta snapshots: %+v", v)
+	}
+}
+
+func TestSnapshotStatsDoesNotAllocate(t *testing.T) {
+	w := &Writer{Topic: "topic-A"}
+	w.stats().batchTime.observeDuration(time.Millisecond)
+
+	r := NewReader(ReaderConfig{Brokers: []string{"localhost:9092"}, Topic: "topic-A"})
+	defer r.Close()
+
+	var ws WriterStats
+	var rs ReaderStats
+	allocs := testing.AllocsPerRun(100, func() {
+		w.SnapshotStats(&ws, CumulativeStats)
+		w.SnapshotStats(&ws, DeltaStats)
+		r.SnapshotStats(&rs, CumulativeStats)
+		r.SnapshotStats(&rs, DeltaStats)
+	})
+	if allocs != 0 {
+		t.Errorf("taking snapshots allocated memory: %v allocations", allocs)
+	}
+	if ws.BatchTime != (DurationStats{}) || ws.Topic != "topic-A" || rs.Topic != "topic-A" {
+		t.Errorf("wrong snapshots: %+v %+v", ws, rs)
+	}
+}
@@ -0,0 +1,442 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TableStore is the key/value store that a TableReader materializes a topic
+// into.
+//
+// Stores must be safe to use concurrently from multiple goroutines, they are
+// updated by the TableReader while the program reads them.
+type TableStore interface {
+	// Get returns the value of key, and whether the key exists.
+	Get(key []byte) (value []byte, ok bool)
+
+	// Set sets the value of key.
+	Set(key, value []byte)
+
+	// Delete removes key from the store.
+	Delete(key []byte)
+
+	// Range calls f for each key and value of the store, until f returns
+	// false.
+	Range(f func(key, value []byte) bool)
+}
+
+// MemoryTableStore is an in-memory TableStore, it is the default store of
+// TableReader. The zero value is an empty store.
+type MemoryTableStore struct {
+	mutex  sync.RWMutex
+	values map[string][]byte
+}
+
+// Get satisfies the TableStore interface.
+func (s *MemoryTableStore) Get(key []byte) ([]byte, bool) {
+	s.mutex.RLock()
+	value, ok := s.values[string(key)]
+	s.mutex.RUnlock()
+	return value, ok
+}
+
+// Set satisfies the TableStore interface.
+func (s *MemoryTableStore) Set(key, value []byte) {
+	s.mutex.Lock()
+	if s.values == nil {
+		s.values = make(map[string][]byte)
+	}
+	s.values[string(key)] = value
+	s.mutex.Unlock()
+}
+
+// Delete satisfies the TableStore interface.
+func (s *MemoryTableStore) Delete(key []byte) {
+	s.mutex.Lock()
+	delete(s.values, string(key))
+	s.mutex.Unlock()
+}
+
+// Range satisfies the TableStore interface. The keys are visited in
+// lexicographical order, on a snapshot of the store taken when Range is
+// called, so f may use the store.
+func (s *MemoryTableStore) Range(f func(key, value []byte) bool) {
+	type entry struct {
+		key   string
+		value []byte
+	}
+
+	s.mutex.RLock()
+	entries := make([]entry, 0, len(s.values))
+	for key, value := range s.values {
+		entries = append(entries, entry{key: key, value: value})
+	}
+	s.mutex.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	for _, e := range entries {
+		if !f([]byte(e.key), e.value) {
+			return
+		}
+	}
+}
+
+// TableReaderConfig is a configuration object used to create new instances of
+// TableReader.
+type TableReaderConfig struct {
+	// The list of broker addresses used to connect to the kafka cluster.
+	Brokers []string
+
+	// The compacted topic to materialize.
+	Topic string
+
+	// An dialer used to open connections to the kafka server. This field is
+	// optional, if nil, the default dialer is used instead.
+	Dialer *Dialer
+
+	// The store that the topic is materialized into.
+	//
+	// Default: &MemoryTableStore{}
+	Store TableStore
+
+	// Maximum amount of time to wait for new data to come when fetching
+	// batches of messages from kafka.
+	//
+	// Default: 10s
+	MaxWait time.Duration
+
+	// IsolationLevel controls the visibility of transactional records, see
+	// ReaderConfig.
+	IsolationLevel IsolationLevel
+
+	// If not nil, specifies a logger used to report internal changes within
+	// the table reader.
+	Logger Logger
+
+	// ErrorLogger is the logger used to report errors. If nil, the table
+	// reader falls back to using Logger instead.
+	ErrorLogger Logger
+
+	// If not nil, specifies a structured logger used to report errors within
+	// the table reader, with keys and values describing their context. It
+	// takes precedence over Logger and ErrorLogger.
+	StructuredLogger StructuredLogger
+}
+
+// Validate method validates TableReaderConfig properties.
+func (config *TableReaderConfig) Validate() error {
+	var errs configErrors
+
+	if len(config.Brokers) == 0 {
+		errs.add(errors.New("cannot create a new kafka table reader with an empty list of broker addresses"))
+	}
+
+	if config.Topic == "" {
+		errs.add(errors.New("cannot create a new kafka table reader with an empty topic"))
+	}
+
+	if config.MaxWait < 0 {
+		errs.addf("invalid negative maximum wait time (max = %v)", config.MaxWait)
+	}
+
+	return errs.err()
+}
+
+// TableReader materializes a compacted topic into a key/value store, similarly
+// to a KTable of Kafka Streams. It is the building block for programs which
+// need to look up configuration or reference data published to kafka.
+//
+// The table reader consumes all partitions of the topic from the beginning,
+// and keeps the store updated as new messages are published. Each message sets
+// the value of its key, messages with a nil value (tombstones) delete their
+// key, and messages without keys are ignored.
+//
+// The content of the store is incomplete until the table reader has caught up
+// with the messages which were in the topic when it started, programs should
+// wait on the channel returned by CaughtUp before using it.
+//
+// The partitions of the topic are looked up when the table reader starts,
+// partitions added to the topic later are not consumed until the table reader
+// is recreated.
+type TableReader struct {
+	config TableReaderConfig
+	store  TableStore
+	ready  chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewTableReader creates and returns a new TableReader configured with config,
+// which starts consuming the topic in the background.
+func NewTableReader(config TableReaderConfig) *TableReader {
+	if err := config.Validate(); err != nil {
+		panic(err)
+	}
+
+	if config.Dialer == nil {
+		config.Dialer = DefaultDialer
+	}
+
+	if config.Store == nil {
+		config.Store = &MemoryTableStore{}
+	}
+
+	if config.MaxWait == 0 {
+		config.MaxWait = 10 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t := &TableReader{
+		config: config,
+		store:  config.Store,
+		ready:  make(chan struct{}),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go t.run(ctx)
+	return t
+}
+
+// Get returns the value of key in the table, and whether the key exists.
+func (t *TableReader) Get(key []byte) ([]byte, bool) {
+	return t.store.Get(key)
+}
+
+// Range calls f for each key and value of the table, until f returns false.
+func (t *TableReader) Range(f func(key, value []byte) bool) {
+	t.store.Range(f)
+}
+
+// CaughtUp returns a channel which is closed when the table reader has read
+// all the messages which were in the topic when it started.
+func (t *TableReader) CaughtUp() <-chan struct{} {
+	return t.ready
+}
+
+// Close stops the table reader. The store keeps the content of the table as of
+// the last message read.
+func (t *TableReader) Close() error {
+	t.once.Do(t.cancel)
+	<-t.done
+	return nil
+}
+
+func (t *TableReader) run(ctx context.Context) {
+	defer close(t.done)
+
+	var partitions []Partition
+	t.retry(ctx, "looking up the partitions", func() (err error) {
+		partitions, err = t.lookupPartitions(ctx)
+		return err
+	})
+
+	if len(partitions) == 0 {
+		if ctx.Err() == nil {
+			close(t.ready)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	pending := len(partitions)
+	caughtUp := func() {
+		mutex.Lock()
+		if pending--; pending == 0 {
+			close(t.ready)
+		}
+		mutex.Unlock()
+	}
+
+	for _, p := range partitions {
+		wg.Add(1)
+		go func(partition int) {
+			defer wg.Done()
+			t.consume(ctx, partition, caughtUp)
+		}(p.ID)
+	}
+
+	wg.Wait()
+}
+
+func (t *TableReader) consume(ctx context.Context, partition int, caughtUp func()) {
+	var first, last int64
+	if !t.retry(ctx, "reading the offsets of the partition", func() (err error) {
+		first, last, err = t.readOffsets(ctx, partition)
+		return err
+	}) {
+		return
+	}
+
+	// The table reader has caught up when its position in the partition
+	// reaches the end offset. The position is tracked by the connection
+	// rather than from the offsets of the messages, since the partition may
+	// end with records that are never returned as messages, like
+	// transaction markers, or offsets removed by compaction.
+	offset, ready := first, false
+	checkCaughtUp := func() {
+		if !ready && offset >= last {
+			ready = true
+			caughtUp()
+		}
+	}
+	checkCaughtUp()
+
+	var conn *Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for attempt := 1; ctx.Err() == nil; {
+		var err error
+		if conn == nil {
+			conn, err = t.dialLeader(ctx, partition, offset)
+		}
+		if err == nil {
+			err = t.readBatch(ctx, conn)
+			offset, _ = conn.Offset()
+			checkCaughtUp()
+		}
+		if err == nil {
+			attempt = 1
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		t.withErrorLogger(func(log Logger) {
+			logKV(log, "error reading table partition", "partition", partition, "attempt", attempt, "error", err)
+		})
+		if conn != nil {
+			conn.Close()
+			conn = nil
+		}
+		if errors.Is(err, OffsetOutOfRange) {
+			// The messages at the position were deleted by the retention
+			// policy of the topic, resume from the first available one.
+			if !t.retry(ctx, "reading the offsets of the partition", func() (err error) {
+				offset, _, err = t.readOffsets(ctx, partition)
+				return err
+			}) {
+				return
+			}
+		}
+		if !sleep(ctx, backoff(attempt, 100*time.Millisecond, 1*time.Second)) {
+			return
+		}
+		attempt++
+	}
+}
+
+// readBatch fetches a batch of messages from the position of conn, and
+// applies them to the store. The position of conn is moved past the batch.
+func (t *TableReader) readBatch(ctx context.Context, conn *Conn) error {
+	batch := conn.ReadBatchContext(ctx, ReadBatchConfig{
+		MaxBytes:       1e6, // 1 MB, the default of readers
+		IsolationLevel: t.config.IsolationLevel,
+		MaxWait:        t.config.MaxWait,
+	})
+
+	for {
+		m, err := batch.ReadMessage()
+		if err != nil {
+			break
+		}
+		if batch.RecordBatchHeader().Attributes.Control() {
+			continue
+		}
+
+		switch {
+		case m.Key == nil:
+		case m.Value == nil:
+			t.store.Delete(m.Key)
+		default:
+			t.store.Set(m.Key, m.Value)
+		}
+	}
+
+	return batch.Close()
+}
+
+func (t *TableReader) dialLeader(ctx context.Context, partition int, offset int64) (*Conn, error) {
+	var err error
+	for _, broker := range t.config.Brokers {
+		var conn *Conn
+		if conn, err = t.config.Dialer.DialLeader(ctx, "tcp", broker, t.config.Topic, partition); err == nil {
+			conn.Seek(offset, SeekAbsolute|SeekDontCheck)
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// retry calls f until it succeeds or the context is canceled, and reports
+// whether it succeeded.
+func (t *TableReader) retry(ctx context.Context, what string, f func() error) bool {
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil {
+			return true
+		}
+		t.withErrorLogger(func(log Logger) {
+			logKV(log, "error "+what+" of table", "attempt", attempt, "error", err)
+		})
+		if !sleep(ctx, backoff(attempt, 100*time.Millisecond, 1*time.Second)) {
+			return false
+		}
+	}
+}
+
+func (t *TableReader) lookupPartitions(ctx context.Context) ([]Partition, error) {
+	var err error
+	for _, broker := range t.config.Brokers {
+		var partitions []Partition
+		if partitions, err = t.config.Dialer.LookupPartitions(ctx, "tcp", broker, t.config.Topic); err == nil {
+			return partitions, nil
+		}
+	}
+	return nil, err
+}
+
+func (t *TableReader) readOffsets(ctx context.Context, partition int) (first, last int64, err error) {
+	for _, broker := range t.config.Brokers {
+		var conn *Conn
+		if conn, err = t.config.Dialer.DialLeader(ctx, "tcp", broker, t.config.Topic, partition); err != nil {
+			continue
+		}
+
+		deadline, _ := ctx.Deadline()
+		conn.SetDeadline(deadline)
+
+		first, last, err = conn.ReadOffsets()
+		conn.Close()
+
+		if err == nil {
+			break
+		}
+	}
+	return
+}
+
+func (t *TableReader) withLogger(do func(Logger)) {
+	if l := newLogger(t.config.StructuredLogger, t.config.Logger, LogLevelInfo, "topic", t.config.Topic); l != nil {
+		do(l)
+	}
+}
+
+func (t *TableReader) withErrorLogger(do func(Logger)) {
+	if l := newLogger(t.config.StructuredLogger, t.config.ErrorLogger, LogLevelError, "topic", t.config.Topic); l != nil {
+		do(l)
+	} else {
+		t.withLogger(do)
+	}
+}
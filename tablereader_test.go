@@ -0,0 +1,110 @@
+package kafka_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func tableContent(t *kafka.TableReader) string {
+	var entries []string
+	t.Range(func(key, value []byte) bool {
+		entries = append(entries, fmt.Sprintf("%s=%s", key, value))
+		return true
+	})
+	return strings.Join(entries, " ")
+}
+
+func TestTableReader(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "table-A", Partitions: 2})
+	records := map[int][]kafkatest.Record{
+		0: {
+			{Key: []byte("a"), Value: []byte("1")},
+			{Key: []byte("b"), Value: []byte("2")},
+			{Key: []byte("a"), Value: []byte("3")},
+		},
+		1: {
+			{Key: []byte("c"), Value: []byte("4")},
+			{Key: []byte("c")},
+			{Value: []byte("no key")},
+			{Key: []byte("d"), Value: []byte("5")},
+		},
+	}
+	for partition, records := range records {
+		if _, err := b.Append("table-A", partition, records...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	table := kafka.NewTableReader(kafka.TableReaderConfig{
+		Brokers: []string{b.Addr().String()},
+		Topic:   "table-A",
+		MaxWait: 10 * time.Millisecond,
+	})
+	defer table.Close()
+
+	select {
+	case <-table.CaughtUp():
+	case <-ctx.Done():
+		t.Fatal("the table reader did not catch up")
+	}
+
+	if content := tableContent(table); content != "a=3 b=2 d=5" {
+		t.Errorf("wrong table content: %s", content)
+	}
+	if _, ok := table.Get([]byte("c")); ok {
+		t.Error("deleted keys must not be found")
+	}
+
+	if _, err := b.Append("table-A", 0, kafkatest.Record{Key: []byte("b"), Value: []byte("6")}); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if value, _ := table.Get([]byte("b")); string(value) == "6" {
+			break
+		}
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("the table was not updated")
+		}
+	}
+}
+
+func TestTableReaderEmptyTopic(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "table-A", Partitions: 3})
+
+	table := kafka.NewTableReader(kafka.TableReaderConfig{
+		Brokers: []string{b.Addr().String()},
+		Topic:   "table-A",
+		Store:   &kafka.MemoryTableStore{},
+		MaxWait: 10 * time.Millisecond,
+	})
+
+	select {
+	case <-table.CaughtUp():
+	case <-ctx.Done():
+		t.Fatal("the table reader did not catch up")
+	}
+	if err := table.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTableReaderConfigValidate(t *testing.T) {
+	config := kafka.TableReaderConfig{MaxWait: -1}
+	if err := config.Validate(); err == nil || !strings.HasPrefix(err.Error(), "3 configuration problems") {
+		t.Errorf("expected all the problems to be reported: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package kafka
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// TLSPinError is returned when the certificates presented by a broker do not
+// match the public keys pinned by the TLSPins field of Dialer or Transport.
+type TLSPinError struct {
+	// The broker and address that the connection was established to. The
+	// broker ID is -1 for bootstrap connections and connections opened by
+	// dialers.
+	Broker Broker
+	Addr   string
+
+	// The pins of the public keys of the certificates presented by the
+	// broker, in the format of TLSPins.
+	Presented []string
+}
+
+func (e *TLSPinError) Error() string {
+	return fmt.Sprintf("tls: the certificates presented by broker %d at %s do not match any of the pinned public keys (presented %s)",
+		e.Broker.ID, e.Addr, strings.Join(e.Presented, ", "))
+}
+
+// SPKIPin returns the pin of the public key of cert in the format of TLSPins,
+// which is the base64 encoded SHA-256 hash of its SubjectPublicKeyInfo prefixed
+// with "sha256/".
+//
+// The pin of a certificate can be computed with openssl:
+//
+//	openssl x509 -in cert.pem -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// brokerTLSConfig returns the TLS configuration of a connection to broker at
+// addr, which applies the per-broker server name and the public key pins.
+func brokerTLSConfig(config *tls.Config, broker Broker, addr string, serverName func(Broker) string, pins []string) *tls.Config {
+	c := config
+	clone := func() {
+		if c == config {
+			c = config.Clone()
+		}
+	}
+
+	if serverName != nil {
+		if name := serverName(broker); name != "" {
+			clone()
+			c.ServerName = name
+		}
+	}
+
+	// If no ServerName is set, infer the ServerName from the hostname we're
+	// connecting to.
+	if c.ServerName == "" {
+		clone()
+		c.ServerName, _ = splitHostPort(addr)
+	}
+
+	if len(pins) != 0 {
+		clone()
+		verify := c.VerifyConnection
+		c.VerifyConnection = func(state tls.ConnectionState) error {
+			if verify != nil {
+				if err := verify(state); err != nil {
+					return err
+				}
+			}
+			return verifyPins(pins, broker, addr, state)
+		}
+	}
+
+	return c
+}
+
+// verifyPins returns an error if none of the certificates presented by the
+// broker match the pins. The certificates of the verified chains are matched
+// when they were verified, otherwise only the leaf certificate is, since other
+// certificates could be sent by servers which do not have their private keys.
+func verifyPins(pins []string, broker Broker, addr string, state tls.ConnectionState) error {
+	var certs []*x509.Certificate
+	if len(state.VerifiedChains) != 0 {
+		for _, chain := range state.VerifiedChains {
+			certs = append(certs, chain...)
+		}
+	} else if len(state.PeerCertificates) != 0 {
+		certs = state.PeerCertificates[:1]
+	}
+
+	presented := make([]string, 0, len(certs))
+	for _, cert := range certs {
+		pin := SPKIPin(cert)
+		for _, p := range pins {
+			if pin == p || pin == "sha256/"+p {
+				return nil
+			}
+		}
+		presented = append(presented, pin)
+	}
+
+	return &TLSPinError{Broker: broker, Addr: addr, Presented: presented}
+}
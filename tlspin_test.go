@@ -0,0 +1,218 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+)
+
+func TestDialerTLSPins(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kafka-go-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	cert := writeTestCertificate(t, certFile, keyFile, "broker.internal")
+	other := writeTestCertificate(t, filepath.Join(dir, "other.crt"), filepath.Join(dir, "other.key"), "other")
+
+	serverCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{serverCert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				c.(*tls.Conn).Handshake()
+				c.Read(make([]byte, 1))
+			}(c)
+		}
+	}()
+
+	_, port, _ := splitHostPortNumber(l.Addr().String())
+
+	tests := []struct {
+		scenario   string
+		pins       []string
+		serverName string
+		pinned     bool
+		failed     bool
+	}{
+		{
+			scenario: "the public key of the broker is pinned",
+			pins:     []string{SPKIPin(other), SPKIPin(cert)},
+		},
+		{
+			scenario: "pins without the sha256 prefix",
+			pins:     []string{SPKIPin(cert)[len("sha256/"):]},
+		},
+		{
+			scenario: "the public key of the broker is not pinned",
+			pins:     []string{SPKIPin(other)},
+			pinned:   true,
+		},
+		{
+			scenario:   "the server name is overridden",
+			serverName: "broker.internal",
+		},
+		{
+			scenario:   "the server name does not match the certificate",
+			serverName: "wrong.internal",
+			failed:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			d := &Dialer{
+				Timeout: 5 * time.Second,
+				TLS:     &tls.Config{RootCAs: roots},
+				TLSPins: test.pins,
+				TLSServerName: func(b Broker) string {
+					return test.serverName
+				},
+			}
+
+			conn, err := d.Dial("tcp", l.Addr().String())
+			if err == nil {
+				conn.Close()
+			}
+
+			var pinErr *TLSPinError
+			switch {
+			case test.pinned:
+				if !errors.As(err, &pinErr) {
+					t.Fatalf("expected a pin error, got %v", err)
+				}
+				if want := (Broker{ID: -1, Host: "127.0.0.1", Port: port}); pinErr.Broker != want {
+					t.Errorf("wrong broker in the pin error: %+v", pinErr.Broker)
+				}
+				if len(pinErr.Presented) != 1 || pinErr.Presented[0] != SPKIPin(cert) {
+					t.Errorf("wrong presented pins: %q", pinErr.Presented)
+				}
+			case test.failed:
+				if err == nil {
+					t.Error("the connection should have failed")
+				}
+			case err != nil:
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestTransportTLSAddrRewrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kafka-go-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	cert := writeTestCertificate(t, certFile, keyFile, "broker.internal")
+
+	serverCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{serverCert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				apiVersion, correlationID, _, _, err := protocol.ReadRequest(c)
+				if err != nil {
+					return
+				}
+				protocol.WriteResponse(c, apiVersion, correlationID, &apiversions.Response{})
+				c.Read(make([]byte, 1))
+			}(c)
+		}
+	}()
+
+	pool := &connPool{
+		dial:        defaultDialer.DialContext,
+		dialTimeout: 5 * time.Second,
+		tls:         &tls.Config{RootCAs: roots},
+		addrRewrite: func(Broker) (string, string) {
+			return "tcp", l.Addr().String()
+		},
+	}
+
+	// The certificate is verified against the host name advertised by the
+	// broker, not the address that the connection is redirected to.
+	g := pool.newBrokerConnGroup(Broker{ID: 1, Host: "broker.internal", Port: 9093})
+	c, err := g.connect(context.Background(), g.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.close()
+
+	g = pool.newBrokerConnGroup(Broker{ID: 2, Host: "other.internal", Port: 9093})
+	if c, err := g.connect(context.Background(), g.addr); err == nil {
+		c.close()
+		t.Error("the certificate should not be valid for the advertised host name of the broker")
+	}
+}
+
+func TestBrokerTLSConfig(t *testing.T) {
+	base := &tls.Config{}
+	broker := Broker{ID: 1, Host: "b1.internal", Port: 9093}
+
+	c := brokerTLSConfig(base, broker, "localhost:19093", nil, nil)
+	if c.ServerName != "localhost" {
+		t.Errorf("the server name should default to the dialed host: %q", c.ServerName)
+	}
+	if base.ServerName != "" {
+		t.Error("the base configuration was modified")
+	}
+
+	c = brokerTLSConfig(base, broker, "localhost:19093", func(b Broker) string { return b.Host }, nil)
+	if c.ServerName != "b1.internal" {
+		t.Errorf("the server name was not overridden: %q", c.ServerName)
+	}
+
+	named := &tls.Config{ServerName: "kafka"}
+	if c = brokerTLSConfig(named, broker, "localhost:19093", nil, nil); c != named {
+		t.Error("the configuration should not be copied when it is used as is")
+	}
+}
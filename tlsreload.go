@@ -0,0 +1,208 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertificateReloader loads TLS certificates from files, and reloads them when
+// the files change, so certificates rotated by tools like cert-manager or Vault
+// agents are used for new connections without restarting the program.
+//
+// The files are checked for changes when connections are established, at most
+// once per CheckInterval. When reloading fails, for example because the files
+// are being written, the previous certificates remain in use and the files are
+// checked again on the next connection.
+//
+// Reloaders are meant to be used through their GetTLSConfig method, which is
+// compatible with the GetTLSConfig field of Dialer and Transport:
+//
+//	reloader := &kafka.CertificateReloader{
+//		CertFile: "/etc/kafka/tls/tls.crt",
+//		KeyFile:  "/etc/kafka/tls/tls.key",
+//		CAFile:   "/etc/kafka/tls/ca.crt",
+//	}
+//
+//	transport := &kafka.Transport{
+//		GetTLSConfig: reloader.GetTLSConfig,
+//	}
+type CertificateReloader struct {
+	// Paths to the PEM encoded client certificate and private key. When
+	// empty, no client certificate is presented to brokers.
+	CertFile string
+	KeyFile  string
+
+	// Path to the PEM encoded certificates of the authorities that brokers
+	// are verified with. When empty, the system roots are used.
+	CAFile string
+
+	// The configuration that the TLS configurations returned by GetTLSConfig
+	// are derived from. Optional.
+	Config *tls.Config
+
+	// Minimum interval between checks of the files for changes, defaults to
+	// 10 seconds.
+	CheckInterval time.Duration
+
+	mutex     sync.Mutex
+	checkedAt time.Time
+	certStat  fileStat
+	caStat    fileStat
+	cert      *tls.Certificate
+	roots     *x509.CertPool
+	err       error
+}
+
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) (fileStat, error) {
+	if path == "" {
+		return fileStat{}, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStat{}, err
+	}
+	return fileStat{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// GetTLSConfig returns the TLS configuration for connections to the broker,
+// which presents the current client certificate and verifies the broker with
+// the current certificate authorities.
+func (r *CertificateReloader) GetTLSConfig(broker Broker) (*tls.Config, error) {
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	roots := r.roots
+	r.mutex.Unlock()
+
+	var config *tls.Config
+	if r.Config != nil {
+		config = r.Config.Clone()
+	} else {
+		config = new(tls.Config)
+	}
+	if roots != nil {
+		config.RootCAs = roots
+	}
+	if r.CertFile != "" {
+		config.GetClientCertificate = r.GetClientCertificate
+	}
+	return config, nil
+}
+
+// GetClientCertificate returns the current client certificate, it may be used
+// as the GetClientCertificate function of a tls.Config.
+func (r *CertificateReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.cert == nil {
+		// An empty certificate tells the server that the client has none.
+		return new(tls.Certificate), nil
+	}
+	return r.cert, nil
+}
+
+// reload loads the files if they changed since they were last loaded. It only
+// returns an error if no certificates were successfully loaded yet.
+func (r *CertificateReloader) reload() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	checkInterval := r.CheckInterval
+	if checkInterval == 0 {
+		checkInterval = 10 * time.Second
+	}
+
+	now := time.Now()
+	if !r.checkedAt.IsZero() && now.Sub(r.checkedAt) < checkInterval {
+		return r.err
+	}
+	r.checkedAt = now
+
+	if err := r.reloadCertificate(); err != nil && r.cert == nil {
+		r.err = err
+		return err
+	}
+	if err := r.reloadRoots(); err != nil && r.roots == nil {
+		r.err = err
+		return err
+	}
+
+	r.err = nil
+	return nil
+}
+
+func (r *CertificateReloader) reloadCertificate() error {
+	if r.CertFile == "" && r.KeyFile == "" {
+		return nil
+	}
+	if r.CertFile == "" || r.KeyFile == "" {
+		return errors.New("kafka: CertFile and KeyFile must be configured together")
+	}
+
+	stat, err := statFile(r.CertFile)
+	if err != nil {
+		return fmt.Errorf("kafka: loading client certificate: %w", err)
+	}
+	keyStat, err := statFile(r.KeyFile)
+	if err != nil {
+		return fmt.Errorf("kafka: loading client certificate: %w", err)
+	}
+	// The certificate is reloaded when either file changes, the most recent
+	// modification time and the total size identify the pair.
+	if keyStat.modTime.After(stat.modTime) {
+		stat.modTime = keyStat.modTime
+	}
+	stat.size += keyStat.size
+
+	if r.cert != nil && stat == r.certStat {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.CertFile, r.KeyFile)
+	if err != nil {
+		return fmt.Errorf("kafka: loading client certificate: %w", err)
+	}
+	r.cert, r.certStat = &cert, stat
+	return nil
+}
+
+func (r *CertificateReloader) reloadRoots() error {
+	if r.CAFile == "" {
+		return nil
+	}
+
+	stat, err := statFile(r.CAFile)
+	if err != nil {
+		return fmt.Errorf("kafka: loading certificate authorities: %w", err)
+	}
+	if r.roots != nil && stat == r.caStat {
+		return nil
+	}
+
+	pem, err := ioutil.ReadFile(r.CAFile)
+	if err != nil {
+		return fmt.Errorf("kafka: loading certificate authorities: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("kafka: loading certificate authorities: no certificates found in %s", r.CAFile)
+	}
+	r.roots, r.caStat = roots, stat
+	return nil
+}
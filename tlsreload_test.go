@@ -0,0 +1,211 @@
+package kafka
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate writes a self-signed certificate with the given common
+// name and its key to the files, and returns the certificate.
+func writeTestCertificate(t *testing.T, certFile, keyFile, commonName string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost", commonName},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if keyFile != "" {
+		if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kafka-go-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+
+	writeTestCertificate(t, certFile, keyFile, "first")
+	writeTestCertificate(t, caFile, "", "ca")
+
+	r := &CertificateReloader{
+		CertFile:      certFile,
+		KeyFile:       keyFile,
+		CAFile:        caFile,
+		Config:        &tls.Config{MinVersion: tls.VersionTLS12},
+		CheckInterval: time.Nanosecond,
+	}
+
+	commonName := func() string {
+		config, err := r.GetTLSConfig(Broker{ID: 1, Host: "localhost", Port: 9093})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.MinVersion != tls.VersionTLS12 || config.RootCAs == nil {
+			t.Fatalf("the configuration was not derived from the base configuration: %+v", config)
+		}
+		cert, err := config.GetClientCertificate(&tls.CertificateRequestInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf.Subject.CommonName
+	}
+
+	if name := commonName(); name != "first" {
+		t.Fatalf("wrong initial certificate: %q", name)
+	}
+
+	// Ensure that the modification time changes on file systems with coarse
+	// timestamps.
+	future := time.Now().Add(time.Minute)
+	writeTestCertificate(t, certFile, keyFile, "second")
+	os.Chtimes(certFile, future, future)
+
+	if name := commonName(); name != "second" {
+		t.Fatalf("the rotated certificate was not reloaded: %q", name)
+	}
+
+	// Certificates which fail to load do not replace the current one.
+	future = future.Add(time.Minute)
+	ioutil.WriteFile(keyFile, []byte("partially written"), 0600)
+	os.Chtimes(keyFile, future, future)
+
+	if name := commonName(); name != "second" {
+		t.Fatalf("the certificate should not have changed: %q", name)
+	}
+}
+
+func TestCertificateReloaderMissingFiles(t *testing.T) {
+	r := &CertificateReloader{
+		CertFile: "/does/not/exist.crt",
+		KeyFile:  "/does/not/exist.key",
+	}
+	if _, err := r.GetTLSConfig(Broker{ID: -1}); err == nil {
+		t.Error("loading missing certificates should fail")
+	}
+}
+
+func TestDialerGetTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kafka-go-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	cert := writeTestCertificate(t, certFile, keyFile, "localhost")
+
+	serverCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    roots,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	peers := make(chan []*x509.Certificate, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		tc := c.(*tls.Conn)
+		tc.Handshake()
+		peers <- tc.ConnectionState().PeerCertificates
+		// Wait for the client to close the connection.
+		c.Read(make([]byte, 1))
+	}()
+
+	reloader := &CertificateReloader{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		CAFile:   certFile,
+	}
+
+	var broker Broker
+	d := &Dialer{
+		Timeout: 5 * time.Second,
+		GetTLSConfig: func(b Broker) (*tls.Config, error) {
+			broker = b
+			return reloader.GetTLSConfig(b)
+		},
+	}
+
+	_, port, _ := splitHostPortNumber(l.Addr().String())
+	conn, err := d.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if want := (Broker{ID: -1, Host: "127.0.0.1", Port: port}); broker != want {
+		t.Errorf("wrong broker passed to GetTLSConfig: want=%+v got=%+v", want, broker)
+	}
+	if certs := <-peers; len(certs) == 0 || !certs[0].Equal(cert) {
+		t.Error("the server did not receive the client certificate")
+	}
+}
@@ -0,0 +1,407 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// OffsetMapping describes how the topics of a source cluster are replicated
+// to a destination cluster, for TranslateOffsets.
+type OffsetMapping struct {
+	// The topics to translate the offsets of, mapped from their name on the
+	// source cluster to their name on the destination cluster (for example,
+	// MirrorMaker 2 prefixes replicated topics with the alias of the source
+	// cluster by default). An empty destination name keeps the source name.
+	Topics map[string]string
+
+	// The name of the MirrorMaker 2 checkpoint topic on the destination
+	// cluster, usually "<source alias>.checkpoints.internal".
+	//
+	// When set, the offsets which match the upstream offset of the last
+	// checkpoint of a partition are translated to the downstream offset of the
+	// checkpoint, the others are translated using timestamps.
+	CheckpointTopic string
+}
+
+func (m *OffsetMapping) destination(topic string) string {
+	if dst := m.Topics[topic]; dst != "" {
+		return dst
+	}
+	return topic
+}
+
+// TranslateOffsets converts the offsets committed by a consumer group on the
+// cluster of src to the equivalent offsets on the cluster of dst, which hosts
+// replicas of the topics of the mapping. The returned offsets are keyed by the
+// partitions of the destination cluster, partitions on which the group has not
+// committed any offset are omitted.
+//
+// Unless a MirrorMaker 2 checkpoint gives the exact translation, the offset of
+// a partition is translated by looking up the timestamp of the message at the
+// committed offset on the source cluster, then the first offset with the same
+// or a later timestamp on the destination cluster. Messages sharing the same
+// timestamp may be consumed again after the translation, but none is skipped.
+// Committed offsets at the end of a source partition translate to the end of
+// the destination partition.
+//
+// The function does not commit the offsets, programs which fail over
+// consumers usually commit them to the group on the destination cluster with
+// OffsetCommit before starting the consumers.
+func TranslateOffsets(ctx context.Context, src, dst *Client, group string, mapping OffsetMapping) (map[TopicPartition]int64, error) {
+	committed, err := fetchCommittedOffsets(ctx, src, group, mapping.Topics)
+	if err != nil {
+		return nil, fmt.Errorf("kafka.TranslateOffsets: %w", err)
+	}
+
+	var checkpoints map[TopicPartition]checkpoint
+	if mapping.CheckpointTopic != "" {
+		checkpoints, err = readCheckpoints(ctx, dst, mapping.CheckpointTopic, group)
+		if err != nil {
+			return nil, fmt.Errorf("kafka.TranslateOffsets: reading checkpoints: %w", err)
+		}
+	}
+
+	offsets := make(map[TopicPartition]int64, len(committed))
+	pending := make(map[TopicPartition]int64)
+
+	for key, offset := range committed {
+		dstKey := TopicPartition{Topic: mapping.destination(key.Topic), Partition: key.Partition}
+		if c, ok := checkpoints[dstKey]; ok && c.upstream == offset {
+			offsets[dstKey] = c.downstream
+		} else {
+			pending[key] = offset
+		}
+	}
+
+	if len(pending) == 0 {
+		return offsets, nil
+	}
+
+	times, err := timesOfOffsets(ctx, src, pending)
+	if err != nil {
+		return nil, fmt.Errorf("kafka.TranslateOffsets: %w", err)
+	}
+
+	dstTimes := make(map[TopicPartition]time.Time, len(times))
+	for key, t := range times {
+		dstTimes[TopicPartition{Topic: mapping.destination(key.Topic), Partition: key.Partition}] = t
+	}
+
+	translated, err := offsetsForTimesOrEnd(ctx, dst, dstTimes)
+	if err != nil {
+		return nil, fmt.Errorf("kafka.TranslateOffsets: %w", err)
+	}
+	for key, offset := range translated {
+		offsets[key] = offset
+	}
+	return offsets, nil
+}
+
+// fetchCommittedOffsets returns the offsets committed by the group on the
+// partitions of the topics.
+func fetchCommittedOffsets(ctx context.Context, client *Client, group string, topics map[string]string) (map[TopicPartition]int64, error) {
+	names := make([]string, 0, len(topics))
+	for topic := range topics {
+		names = append(names, topic)
+	}
+
+	meta, err := client.Metadata(ctx, &MetadataRequest{Topics: names})
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make(map[string][]int, len(meta.Topics))
+	for _, t := range meta.Topics {
+		if t.Error != nil {
+			return nil, fmt.Errorf("%q: %w", t.Name, t.Error)
+		}
+		for _, p := range t.Partitions {
+			partitions[t.Name] = append(partitions[t.Name], p.ID)
+		}
+	}
+
+	res, err := client.OffsetFetch(ctx, &OffsetFetchRequest{GroupID: group, Topics: partitions})
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	offsets := make(map[TopicPartition]int64)
+	for topic, partitions := range res.Topics {
+		for _, p := range partitions {
+			switch {
+			case p.Error != nil:
+				return nil, withErrorContext(p.Error, topic, p.Partition, group)
+			case p.CommittedOffset >= 0:
+				offsets[TopicPartition{Topic: topic, Partition: p.Partition}] = p.CommittedOffset
+			}
+		}
+	}
+	return offsets, nil
+}
+
+// timesOfOffsets returns the timestamps of the messages at the offsets.
+// Offsets at the end of their partition are mapped to the zero time.
+func timesOfOffsets(ctx context.Context, client *Client, offsets map[TopicPartition]int64) (map[TopicPartition]time.Time, error) {
+	requests := make(map[string][]OffsetRequest)
+	for key := range offsets {
+		requests[key.Topic] = append(requests[key.Topic], LastOffsetOf(key.Partition))
+	}
+
+	res, err := client.ListOffsets(ctx, &ListOffsetsRequest{Topics: requests})
+	if err != nil {
+		return nil, err
+	}
+
+	times := make(map[TopicPartition]time.Time, len(offsets))
+	for topic, partitions := range res.Topics {
+		for _, p := range partitions {
+			if p.Error != nil {
+				return nil, fmt.Errorf("looking up the last offset of %s/%d: %w", topic, p.Partition, p.Error)
+			}
+			key := TopicPartition{Topic: topic, Partition: p.Partition}
+			offset := offsets[key]
+			if offset >= p.LastOffset {
+				times[key] = time.Time{}
+				continue
+			}
+			t, err := timeOfOffset(ctx, client, key, offset)
+			if err != nil {
+				return nil, fmt.Errorf("reading the message at offset %d of %s/%d: %w", offset, topic, p.Partition, err)
+			}
+			times[key] = t
+		}
+	}
+	return times, nil
+}
+
+// timeOfOffset returns the timestamp of the first message at or after the
+// offset, which is before the end of the partition.
+func timeOfOffset(ctx context.Context, client *Client, key TopicPartition, offset int64) (time.Time, error) {
+	res, err := client.Fetch(ctx, &FetchRequest{
+		Topic:     key.Topic,
+		Partition: key.Partition,
+		Offset:    offset,
+		MaxBytes:  1,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if res.Error != nil {
+		return time.Time{}, res.Error
+	}
+
+	for {
+		r, err := res.Records.ReadRecord()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				// The offsets of compacted or transactional partitions may
+				// lead to the end of the partition.
+				return time.Time{}, nil
+			}
+			return time.Time{}, err
+		}
+		if r.Offset >= offset {
+			return r.Time, nil
+		}
+	}
+}
+
+// offsetsForTimesOrEnd looks up the offsets of the partitions at the times.
+// Partitions with no message at or after their time, or whose time is zero,
+// are mapped to the end of the partition.
+func offsetsForTimesOrEnd(ctx context.Context, client *Client, times map[TopicPartition]time.Time) (map[TopicPartition]int64, error) {
+	offsets := make(map[TopicPartition]int64, len(times))
+	last := make(map[string][]OffsetRequest)
+
+	lookup := make(map[TopicPartition]time.Time, len(times))
+	for key, t := range times {
+		if t.IsZero() {
+			last[key.Topic] = append(last[key.Topic], LastOffsetOf(key.Partition))
+		} else {
+			lookup[key] = t
+		}
+	}
+
+	if len(lookup) != 0 {
+		found, err := client.OffsetsForTimes(ctx, lookup)
+		if err != nil {
+			return nil, err
+		}
+		for key, o := range found {
+			switch {
+			case o.Error != nil:
+				return nil, fmt.Errorf("looking up the offset of %s/%d: %w", key.Topic, key.Partition, o.Error)
+			case o.Offset < 0:
+				last[key.Topic] = append(last[key.Topic], LastOffsetOf(key.Partition))
+			default:
+				offsets[key] = o.Offset
+			}
+		}
+	}
+
+	if len(last) != 0 {
+		res, err := client.ListOffsets(ctx, &ListOffsetsRequest{Topics: last})
+		if err != nil {
+			return nil, err
+		}
+		for topic, partitions := range res.Topics {
+			for _, p := range partitions {
+				if p.Error != nil {
+					return nil, fmt.Errorf("looking up the last offset of %s/%d: %w", topic, p.Partition, p.Error)
+				}
+				offsets[TopicPartition{Topic: topic, Partition: p.Partition}] = p.LastOffset
+			}
+		}
+	}
+
+	return offsets, nil
+}
+
+// checkpoint is a MirrorMaker 2 checkpoint of the offset of a consumer group.
+type checkpoint struct {
+	upstream   int64
+	downstream int64
+}
+
+// readCheckpoints reads the checkpoint topic up to its end, and returns the
+// last checkpoint of each partition for the group, keyed by the partitions of
+// the destination cluster.
+func readCheckpoints(ctx context.Context, client *Client, topic, group string) (map[TopicPartition]checkpoint, error) {
+	meta, err := client.Metadata(ctx, &MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+	if len(meta.Topics) != 1 {
+		return nil, fmt.Errorf("%q: %w", topic, UnknownTopicOrPartition)
+	}
+	if err := meta.Topics[0].Error; err != nil {
+		return nil, fmt.Errorf("%q: %w", topic, err)
+	}
+
+	requests := make([]OffsetRequest, 0, 2*len(meta.Topics[0].Partitions))
+	for _, p := range meta.Topics[0].Partitions {
+		requests = append(requests, FirstOffsetOf(p.ID), LastOffsetOf(p.ID))
+	}
+
+	res, err := client.ListOffsets(ctx, &ListOffsetsRequest{
+		Topics: map[string][]OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := make(map[TopicPartition]checkpoint)
+
+	for _, p := range res.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("looking up the offsets of %s/%d: %w", topic, p.Partition, p.Error)
+		}
+
+		for offset := p.FirstOffset; offset < p.LastOffset; {
+			fetch, err := client.Fetch(ctx, &FetchRequest{
+				Topic:     topic,
+				Partition: p.Partition,
+				Offset:    offset,
+				MaxBytes:  1e6,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if fetch.Error != nil {
+				return nil, fetch.Error
+			}
+
+			next := offset
+			for {
+				r, err := fetch.Records.ReadRecord()
+				if err != nil {
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					return nil, err
+				}
+				if r.Offset < offset {
+					continue
+				}
+				next = r.Offset + 1
+
+				key, c, err := decodeCheckpoint(r, group)
+				if err != nil {
+					return nil, fmt.Errorf("decoding the checkpoint at offset %d of %s/%d: %w", r.Offset, topic, p.Partition, err)
+				}
+				if key != nil {
+					checkpoints[*key] = c
+				}
+			}
+
+			if next == offset {
+				// The remaining offsets hold no records, which happens at the
+				// end of compacted partitions.
+				break
+			}
+			offset = next
+		}
+	}
+
+	return checkpoints, nil
+}
+
+// decodeCheckpoint decodes a record of the checkpoint topic, it returns a nil
+// key if the checkpoint is for another group.
+//
+// The key is the group, topic, and partition of the checkpoint, encoded as
+// two strings and an int32. The value is a version (int16) followed by the
+// upstream offset, the downstream offset (both int64), and the metadata.
+func decodeCheckpoint(r *Record, group string) (*TopicPartition, checkpoint, error) {
+	key, err := ReadAll(r.Key)
+	if err != nil {
+		return nil, checkpoint{}, err
+	}
+
+	g, key, ok := readCheckpointString(key)
+	if !ok {
+		return nil, checkpoint{}, errors.New("invalid checkpoint key")
+	}
+	if g != group {
+		return nil, checkpoint{}, nil
+	}
+	topic, key, ok := readCheckpointString(key)
+	if !ok || len(key) < 4 {
+		return nil, checkpoint{}, errors.New("invalid checkpoint key")
+	}
+	partition := int32(binary.BigEndian.Uint32(key))
+
+	value, err := ReadAll(r.Value)
+	if err != nil {
+		return nil, checkpoint{}, err
+	}
+	if len(value) < 18 {
+		return nil, checkpoint{}, errors.New("invalid checkpoint value")
+	}
+	if version := int16(binary.BigEndian.Uint16(value)); version != 0 {
+		return nil, checkpoint{}, fmt.Errorf("unsupported checkpoint version: %d", version)
+	}
+
+	return &TopicPartition{Topic: topic, Partition: int(partition)}, checkpoint{
+		upstream:   int64(binary.BigEndian.Uint64(value[2:])),
+		downstream: int64(binary.BigEndian.Uint64(value[10:])),
+	}, nil
+}
+
+func readCheckpointString(b []byte) (string, []byte, bool) {
+	if len(b) < 2 {
+		return "", b, false
+	}
+	n := int(int16(binary.BigEndian.Uint16(b)))
+	if n < 0 || len(b) < 2+n {
+		return "", b, false
+	}
+	return string(b[2 : 2+n]), b[2+n:], true
+}
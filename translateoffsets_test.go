@@ -0,0 +1,125 @@
+package kafka_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func TestTranslateOffsets(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now().Truncate(time.Millisecond)
+
+	// The destination cluster only received the messages from the third one
+	// of each partition, so the offsets differ by two.
+	records := func(first int) map[int][]kafkatest.Record {
+		partitions := make(map[int][]kafkatest.Record, 3)
+		for partition := 0; partition < 3; partition++ {
+			for i := first; i < 6; i++ {
+				partitions[partition] = append(partitions[partition], kafkatest.Record{
+					Time:  start.Add(time.Duration(i) * time.Second),
+					Value: []byte(fmt.Sprint(i)),
+				})
+			}
+		}
+		return partitions
+	}
+	src := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A", Partitions: 3, Records: records(0)})
+	dst := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "primary.topic-A", Partitions: 3, Records: records(2)})
+
+	if err := dst.CreateTopic("primary.checkpoints.internal", 1); err != nil {
+		t.Fatal(err)
+	}
+	// Only the checkpoint of partition 1 matches the committed offset, the
+	// one of partition 0 is stale, and the one of group-B is ignored.
+	_, err := dst.Append("primary.checkpoints.internal", 0,
+		newCheckpoint("group-A", "primary.topic-A", 0, 2, 0),
+		newCheckpoint("group-A", "primary.topic-A", 1, 3, 42),
+		newCheckpoint("group-B", "primary.topic-A", 2, 6, 43),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srcClient := &kafka.Client{Addr: src.Addr()}
+	dstClient := &kafka.Client{Addr: dst.Addr()}
+
+	_, err = srcClient.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      "group-A",
+		GenerationID: -1,
+		Topics: map[string][]kafka.OffsetCommit{
+			"topic-A": {
+				{Partition: 0, Offset: 3},
+				{Partition: 1, Offset: 3},
+				{Partition: 2, Offset: 6},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mapping := kafka.OffsetMapping{
+		Topics: map[string]string{"topic-A": "primary.topic-A"},
+	}
+
+	offsets, err := kafka.TranslateOffsets(ctx, srcClient, dstClient, "group-A", mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[kafka.TopicPartition]int64{
+		{Topic: "primary.topic-A", Partition: 0}: 1,
+		{Topic: "primary.topic-A", Partition: 1}: 1,
+		{Topic: "primary.topic-A", Partition: 2}: 4,
+	}
+	if fmt.Sprint(offsets) != fmt.Sprint(expect) {
+		t.Errorf("wrong offsets translated with timestamps:\nwant: %v\ngot:  %v", expect, offsets)
+	}
+
+	mapping.CheckpointTopic = "primary.checkpoints.internal"
+	offsets, err = kafka.TranslateOffsets(ctx, srcClient, dstClient, "group-A", mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect[kafka.TopicPartition{Topic: "primary.topic-A", Partition: 1}] = 42
+	if fmt.Sprint(offsets) != fmt.Sprint(expect) {
+		t.Errorf("wrong offsets translated with checkpoints:\nwant: %v\ngot:  %v", expect, offsets)
+	}
+
+	offsets, err = kafka.TranslateOffsets(ctx, srcClient, dstClient, "group-B", mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offsets) != 0 {
+		t.Errorf("expected no offsets for a group which did not commit any: %v", offsets)
+	}
+}
+
+// newCheckpoint encodes a MirrorMaker 2 checkpoint record.
+func newCheckpoint(group, topic string, partition int32, upstream, downstream int64) kafkatest.Record {
+	key := new(bytes.Buffer)
+	writeCheckpointString(key, group)
+	writeCheckpointString(key, topic)
+	binary.Write(key, binary.BigEndian, partition)
+
+	value := new(bytes.Buffer)
+	binary.Write(value, binary.BigEndian, int16(0))
+	binary.Write(value, binary.BigEndian, upstream)
+	binary.Write(value, binary.BigEndian, downstream)
+	writeCheckpointString(value, "")
+
+	return kafkatest.Record{Key: key.Bytes(), Value: value.Bytes()}
+}
+
+func writeCheckpointString(b *bytes.Buffer, s string) {
+	binary.Write(b, binary.BigEndian, int16(len(s)))
+	b.WriteString(s)
+}
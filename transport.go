@@ -19,7 +19,7 @@ import (
 	"github.com/segmentio/kafka-go/protocol"
 	"github.com/segmentio/kafka-go/protocol/apiversions"
 	"github.com/segmentio/kafka-go/protocol/createtopics"
-	"github.com/segmentio/kafka-go/protocol/findcoordinator"
+	fetchAPI "github.com/segmentio/kafka-go/protocol/fetch"
 	meta "github.com/segmentio/kafka-go/protocol/metadata"
 	"github.com/segmentio/kafka-go/protocol/saslauthenticate"
 	"github.com/segmentio/kafka-go/protocol/saslhandshake"
@@ -56,8 +56,29 @@ type RoundTripper interface {
 // kafka.Reader and kafka.Writer types.
 type Transport struct {
 	// A function used to establish connections to the kafka cluster.
+	//
+	// When Dial is set, KeepAlive, KeepAliveCount, and TCPUserTimeout are
+	// ignored.
 	Dial func(context.Context, string, string) (net.Conn, error)
 
+	// Configures the TCP keep-alive probes of the connections to the kafka
+	// cluster, which detect half-open connections (e.g. after a load balancer
+	// dropped them) in KeepAlive * (KeepAliveCount + 1). KeepAlive is both the
+	// idle time before the first probe and the interval between probes.
+	//
+	// KeepAlive defaults to 15s, KeepAliveCount to the operating system
+	// default. KeepAliveCount is only supported on linux.
+	KeepAlive      time.Duration
+	KeepAliveCount int
+
+	// Maximum amount of time that data written to connections may remain
+	// unacknowledged before the connections are closed (see TCP_USER_TIMEOUT
+	// in tcp(7)), so requests written to half-open connections fail in
+	// seconds instead of after minutes of retransmissions.
+	//
+	// Defaults to the operating system default, only supported on linux.
+	TCPUserTimeout time.Duration
+
 	// Time limit set for establishing connections to the kafka cluster. This
 	// limit includes all round trips done to establish the connections (TLS
 	// hadbhaske, SASL negotiation, etc...).
@@ -70,6 +91,10 @@ type Transport struct {
 	// been idle for too long, and re-open them on demand when the transport is
 	// used again.
 	//
+	// The idle timeout should be lower than the ones of load balancers between
+	// the program and the kafka brokers (e.g. 350s for AWS NLBs), which may
+	// silently drop idle connections.
+	//
 	// Defaults to 30s.
 	IdleTimeout time.Duration
 
@@ -81,6 +106,18 @@ type Transport struct {
 	// Default to 6s.
 	MetadataTTL time.Duration
 
+	// Configures how the transport refreshes the cached metadata when
+	// refreshes fail or responses report stale metadata, see
+	// MetadataRefreshConfig for details.
+	MetadataRefresh MetadataRefreshConfig
+
+	// Configures how the transport retries the requests that fail with errors
+	// which are safe to retry, and hedges idempotent requests. The policy
+	// applies to all requests sent by the transport, including the ones of
+	// kafka.Client and kafka.Writer values using it, see RetryPolicy for
+	// details.
+	Retry RetryPolicy
+
 	// Unique identifier that the transport communicates to the brokers when it
 	// sends requests.
 	ClientID string
@@ -91,6 +128,28 @@ type Transport struct {
 	// If the Server
 	TLS *tls.Config
 
+	// GetTLSConfig optionally returns the TLS configuration of each connection
+	// that the transport establishes, in place of TLS. The broker passed to
+	// the function has an ID of -1 for connections to bootstrap addresses.
+	//
+	// Programs use it to rotate client certificates without restarting, see
+	// CertificateReloader, or to use different configurations per broker.
+	GetTLSConfig func(broker Broker) (*tls.Config, error)
+
+	// TLSServerName optionally returns the server name used to verify the
+	// certificates of brokers, for example when connecting through tunnels
+	// where the dialed host names differ from the names of the certificates.
+	// Returning an empty string uses the ServerName of the TLS configuration,
+	// or the host name of the broker.
+	TLSServerName func(broker Broker) string
+
+	// TLSPins optionally pins the public keys that brokers must present
+	// certificates for, in addition to the verification of the TLS
+	// configuration. Connections are rejected with a *TLSPinError unless one
+	// of the certificates presented by the broker matches a pin, see SPKIPin
+	// for the format of pins.
+	TLSPins []string
+
 	// SASL configures the Transfer to use SASL authentication.
 	SASL sasl.Mechanism
 
@@ -113,6 +172,135 @@ type Transport struct {
 	// If nil, context.Background() is used instead.
 	Context context.Context
 
+	// An optional observer called on every round trip made on connections
+	// of the transport, including the ones done to negotiate API versions and
+	// authenticate. This is mostly useful to debug compatibility issues with
+	// kafka brokers, by logging the messages that they exchange with the
+	// program.
+	RoundTripObserver protocol.RoundTripObserver
+
+	// Controls which messages are passed to the RoundTripObserver, for
+	// example protocol.RedactRecords prevents the observer from receiving the
+	// content of produced and fetched records.
+	RoundTripRedaction protocol.Redaction
+
+	// Limits enforced when decoding the responses received from kafka
+	// brokers, which protect the program from allocating unbounded amounts of
+	// memory when it receives corrupted or malicious responses.
+	//
+	// The zero value disables the limits.
+	DecodeLimits protocol.Limits
+
+	// Configures how the checksums of fetched record batches are verified,
+	// the default is protocol.CRCStrict. Corrupted batches are reported by a
+	// *protocol.CorruptRecordError returned when reading the records.
+	CRCMode protocol.CRCMode
+
+	// Pins the maximum versions of the APIs used by the transport, the
+	// versions negotiated with kafka brokers never exceed the ones set in
+	// this map. This is useful to work around brokers and proxies which
+	// advertise API versions that they do not handle correctly, for example:
+	//
+	//	MaxVersions: map[protocol.ApiKey]int16{protocol.Fetch: 11}
+	//
+	// The minimum versions supported by the package take precedence over the
+	// pinned versions.
+	MaxVersions map[protocol.ApiKey]int16
+
+	// A table of the deviations from the kafka protocol of broker
+	// implementations, which the transport consults when it establishes
+	// connections. The first entry that detects the broker applies.
+	Quirks []BrokerQuirks
+
+	// An optional function called to rewrite the addresses of the brokers
+	// learned from the cluster metadata, returning the network and address
+	// that the transport connects to.
+	//
+	// This is useful when the listeners advertised by the brokers are not
+	// reachable by the program, for example behind NAT, SSH tunnels, or port
+	// forwarding:
+	//
+	//	AddrRewrite: func(broker kafka.Broker) (string, string) {
+	//		return "tcp", fmt.Sprintf("localhost:%d", 19090+broker.ID)
+	//	},
+	//
+	// The bootstrap address passed to RoundTrip is not rewritten. TLS
+	// connections to rewritten addresses verify the certificates of brokers
+	// against their advertised host names, unless the TLS configuration sets
+	// a ServerName or TLSServerName returns one.
+	AddrRewrite func(broker Broker) (network, address string)
+
+	// Chooses the broker that Metadata, FindCoordinator, and ApiVersions
+	// requests are sent to, since any broker of the cluster can serve them.
+	// Requests are sent to the bootstrap address until the cluster layout was
+	// discovered, or when the selected broker cannot be reached. Requests
+	// sent to the address of a specific broker always go to that broker.
+	//
+	// Defaults to LeastLoadedBrokerSelector.
+	BrokerSelector BrokerSelector
+
+	// Maximum number of connections that the transport opens to each broker.
+	// When the limit is reached, requests wait for a connection to become
+	// available. The time spent waiting is reported in ConnPoolStats.
+	//
+	// Connections that remain unused for longer than IdleTimeout are closed.
+	//
+	// Zero means no limit.
+	MaxConnsPerBroker int
+
+	// Interval at which the transport resolves again the host names of the
+	// bootstrap address and of the brokers, and closes the idle connections
+	// to IP addresses that are no longer returned. This lets long-lived
+	// programs follow the changes of broker addresses (e.g. on MSK or
+	// Kubernetes) without holding on to connections to stale ones. Bootstrap
+	// addresses naming DNS SRV records (see SRVPrefix) are also resolved
+	// again, closing the connections to targets that were removed.
+	//
+	// Host names are always resolved when new connections are established,
+	// zero disables the periodic resolution.
+	ResolveInterval time.Duration
+
+	// Optional hooks called during the lifecycle of the connections to kafka
+	// brokers, which are useful to instrument the churn of connections. The
+	// broker passed to the hooks has the ID -1 for connections to the
+	// bootstrap address. Name resolution can be customized with Resolver.
+	//
+	// OnDial is called after each attempt to dial a broker, with the network
+	// connection or the error that occurred. This is where socket options
+	// (e.g. TCP_USER_TIMEOUT or SO_MARK) can be set on the connection,
+	// returning an error aborts the connection.
+	OnDial func(broker Broker, addr net.Addr, conn net.Conn, err error) error
+
+	// OnConnect is called when a connection is ready to send requests, after
+	// the TLS handshake, API versions negotiation and SASL authentication, or
+	// with the error that prevented establishing it.
+	OnConnect func(broker Broker, addr net.Addr, err error)
+
+	// OnClose is called when a connection is closed, with the error that
+	// caused it, or nil if it was closed because it was idle or the transport
+	// was closed.
+	OnClose func(broker Broker, addr net.Addr, err error)
+
+	// An optional function called when a broker took longer than
+	// SlowRequestThreshold to respond to a request, with the threshold and a
+	// description of the request. It is called synchronously by the goroutine
+	// sending requests on the connection, so it must not block.
+	//
+	// The latency of all requests is reported in ConnPoolStats.
+	OnSlowRequest func(threshold time.Duration, info RequestInfo)
+
+	// The latency above which requests are reported to OnSlowRequest.
+	//
+	// Default: 1s
+	SlowRequestThreshold time.Duration
+
+	// An optional clock used to schedule metadata refreshes, retry backoffs,
+	// and hedged requests, so tests can drive them with a fake clock. See
+	// Clock for details.
+	//
+	// Default: SystemClock
+	Clock Clock
+
 	mutex sync.RWMutex
 	pools map[networkAddress]*connPool
 }
@@ -141,6 +329,114 @@ func (t *Transport) CloseIdleConnections() {
 	}
 }
 
+// NegotiatedVersions returns the API versions negotiated by the transport
+// with the brokers of the cluster at addr, indexed by broker address.
+//
+// Versions are negotiated when connections are established, so only the
+// brokers that the transport has connected to are present in the returned
+// map, and nil is returned if the transport was never used with addr.
+func (t *Transport) NegotiatedVersions(addr net.Addr) map[string]map[protocol.ApiKey]int16 {
+	k := networkAddress{
+		network: addr.Network(),
+		address: addr.String(),
+	}
+
+	t.mutex.RLock()
+	p := t.pools[k]
+	t.mutex.RUnlock()
+
+	if p == nil {
+		return nil
+	}
+
+	p.mutex.RLock()
+	groups := make([]*connGroup, 0, len(p.conns)+1)
+	groups = append(groups, p.ctrl)
+	for _, g := range p.conns {
+		groups = append(groups, g)
+	}
+	p.mutex.RUnlock()
+
+	versions := make(map[string]map[protocol.ApiKey]int16, len(groups))
+
+	for _, g := range groups {
+		if v, _ := g.versions.Load().(map[protocol.ApiKey]int16); v != nil {
+			brokerVersions := make(map[protocol.ApiKey]int16, len(v))
+			for k, x := range v {
+				brokerVersions[k] = x
+			}
+			versions[g.addr.String()] = brokerVersions
+		}
+	}
+
+	return versions
+}
+
+// ConnPoolStats is a data structure returned by a call to Transport.Stats that
+// exposes details about the connections that the transport maintains with a
+// kafka broker.
+type ConnPoolStats struct {
+	Dials    int64 `metric:"kafka.transport.dial.count"    type:"counter"`
+	Requests int64 `metric:"kafka.transport.request.count" type:"counter"`
+
+	OpenConns int64 `metric:"kafka.transport.conns.open"      type:"gauge"`
+	IdleConns int64 `metric:"kafka.transport.conns.idle"      type:"gauge"`
+	InFlight  int64 `metric:"kafka.transport.requests.active" type:"gauge"`
+
+	DialTime DurationStats `metric:"kafka.transport.dial.seconds"`
+	WaitTime DurationStats `metric:"kafka.transport.wait.seconds"`
+
+	// Latency of the requests sent to the broker, by API. Only the APIs that
+	// requests were sent for since the last call to Stats are present.
+	Latency map[protocol.ApiKey]DurationHistogram
+
+	MaxConns    int64         `metric:"kafka.transport.conns.max"    type:"gauge"`
+	IdleTimeout time.Duration `metric:"kafka.transport.idle.timeout" type:"gauge"`
+
+	Addr     string `tag:"addr"`
+	BrokerID int    `tag:"broker"`
+}
+
+// Stats returns a snapshot of the statistics of the connections that the
+// transport maintains with the brokers of the kafka cluster reached at addr.
+// The first element describes the connections to the bootstrap address, the
+// following ones describe the connections to each broker, ordered by id.
+//
+// Counters and durations are reset by each call to Stats. The method returns
+// nil if the transport never sent requests to the cluster.
+func (t *Transport) Stats(addr net.Addr) []ConnPoolStats {
+	k := networkAddress{
+		network: addr.Network(),
+		address: addr.String(),
+	}
+
+	t.mutex.RLock()
+	p := t.pools[k]
+	t.mutex.RUnlock()
+
+	if p == nil {
+		return nil
+	}
+
+	p.mutex.RLock()
+	groups := make([]*connGroup, 0, len(p.conns))
+	for _, g := range p.conns {
+		groups = append(groups, g)
+	}
+	p.mutex.RUnlock()
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].broker.ID < groups[j].broker.ID
+	})
+
+	stats := make([]ConnPoolStats, 0, len(groups)+1)
+	stats = append(stats, p.ctrl.snapshot())
+	for _, g := range groups {
+		stats = append(stats, g.snapshot())
+	}
+	return stats
+}
+
 // RoundTrip sends a request to a kafka cluster and returns the response, or an
 // error if no responses were received.
 //
@@ -174,13 +470,22 @@ func (t *Transport) CloseIdleConnections() {
 func (t *Transport) RoundTrip(ctx context.Context, addr net.Addr, req Request) (Response, error) {
 	p := t.grabPool(addr)
 	defer p.unref()
-	return p.roundTrip(ctx, req)
+	return p.roundTripWithRetries(ctx, req)
 }
 
 func (t *Transport) dial() func(context.Context, string, string) (net.Conn, error) {
 	if t.Dial != nil {
 		return t.Dial
 	}
+	if t.KeepAlive != 0 || t.KeepAliveCount != 0 || t.TCPUserTimeout != 0 {
+		dialer := defaultDialer
+		dialer.KeepAlive = t.KeepAlive
+		return socketOptions{
+			keepAliveInterval: t.KeepAlive,
+			keepAliveCount:    t.KeepAliveCount,
+			userTimeout:       t.TCPUserTimeout,
+		}.dial(dialer.DialContext)
+	}
 	return defaultDialer.DialContext
 }
 
@@ -191,6 +496,13 @@ func (t *Transport) dialTimeout() time.Duration {
 	return 5 * time.Second
 }
 
+func (t *Transport) slowRequestThreshold() time.Duration {
+	if t.SlowRequestThreshold > 0 {
+		return t.SlowRequestThreshold
+	}
+	return 1 * time.Second
+}
+
 func (t *Transport) idleTimeout() time.Duration {
 	if t.IdleTimeout > 0 {
 		return t.IdleTimeout
@@ -239,13 +551,34 @@ func (t *Transport) grabPool(addr net.Addr) *connPool {
 		dialTimeout: t.dialTimeout(),
 		idleTimeout: t.idleTimeout(),
 		metadataTTL: t.metadataTTL(),
+		refresh:     t.MetadataRefresh,
+		retry:       t.Retry,
 		clientID:    t.ClientID,
 		tls:         t.TLS,
+		getTLS:      t.GetTLSConfig,
+		tlsName:     t.TLSServerName,
+		tlsPins:     t.TLSPins,
 		sasl:        t.SASL,
 		resolver:    t.Resolver,
+		observer:    t.RoundTripObserver,
+		redaction:   t.RoundTripRedaction,
+		limits:      t.DecodeLimits,
+		crcMode:     t.CRCMode,
+		maxVersions: t.MaxVersions,
+		quirks:      t.Quirks,
+		addrRewrite: t.AddrRewrite,
+		maxConns:    t.MaxConnsPerBroker,
+		selector:    t.BrokerSelector,
+		onDial:      t.OnDial,
+		onConnect:   t.OnConnect,
+		onClose:     t.OnClose,
+		onSlow:      t.OnSlowRequest,
+		slowLatency: t.slowRequestThreshold(),
+		clock:       clockOrDefault(t.Clock),
 
 		ready:  make(event),
 		wake:   make(chan event),
+		topics: make(chan []string, 16),
 		conns:  make(map[int32]*connGroup),
 		cancel: cancel,
 	}
@@ -253,6 +586,10 @@ func (t *Transport) grabPool(addr net.Addr) *connPool {
 	p.ctrl = p.newConnGroup(addr)
 	go p.discover(ctx, p.wake)
 
+	if t.ResolveInterval > 0 {
+		go p.resolve(ctx, t.ResolveInterval)
+	}
+
 	if t.pools == nil {
 		t.pools = make(map[networkAddress]*connPool)
 	}
@@ -280,21 +617,46 @@ type connPool struct {
 	dialTimeout time.Duration
 	idleTimeout time.Duration
 	metadataTTL time.Duration
+	refresh     MetadataRefreshConfig
+	retry       RetryPolicy
 	clientID    string
 	tls         *tls.Config
+	getTLS      func(Broker) (*tls.Config, error)
+	tlsName     func(Broker) string
+	tlsPins     []string
 	sasl        sasl.Mechanism
 	resolver    BrokerResolver
+	observer    protocol.RoundTripObserver
+	redaction   protocol.Redaction
+	limits      protocol.Limits
+	crcMode     protocol.CRCMode
+	maxVersions map[protocol.ApiKey]int16
+	quirks      []BrokerQuirks
+	addrRewrite func(Broker) (string, string)
+	maxConns    int
+	selector    BrokerSelector
+	onDial      func(Broker, net.Addr, net.Conn, error) error
+	onConnect   func(Broker, net.Addr, error)
+	onClose     func(Broker, net.Addr, error)
+	onSlow      func(time.Duration, RequestInfo)
+	slowLatency time.Duration
+	clock       Clock
 	// Signaling mechanisms to orchestrate communications between the pool and
 	// the rest of the program.
-	once   sync.Once  // ensure that `ready` is triggered only once
-	ready  event      // triggered after the first metadata update
-	wake   chan event // used to force metadata updates
+	once   sync.Once     // ensure that `ready` is triggered only once
+	ready  event         // triggered after the first metadata update
+	wake   chan event    // used to force metadata updates
+	topics chan []string // used to request updates of the metadata of topics
 	cancel context.CancelFunc
 	// Mutable fields of the connection pool, access must be synchronized.
 	mutex sync.RWMutex
 	conns map[int32]*connGroup // data connections used for produce/fetch/etc...
 	ctrl  *connGroup           // control connections used for metadata requests
 	state atomic.Value         // cached cluster state
+	// Budget of retries shared by the requests sent through the pool.
+	budget retryBudget
+	// Brokers coordinating the groups and transactions of requests.
+	coordinators coordinatorCache
 }
 
 type connPoolState struct {
@@ -371,6 +733,13 @@ func (p *connPool) roundTrip(ctx context.Context, req Request) (Response, error)
 			return cachedMeta, nil
 		}
 
+	case *fetchAPI.Request:
+		// Fetch v13+ requests identify topics by ID, which we resolve from the
+		// cached metadata since programs only know the topic names. The IDs are
+		// set on a copy, so programs can reuse the request after topics are
+		// recreated with new IDs.
+		req = m.ResolveTopicIDs(state.layout)
+
 	case protocol.Splitter:
 		// Messages that implement the Splitter interface trigger the creation of
 		// multiple requests that are all merged back into a single results by
@@ -396,6 +765,13 @@ func (p *connPool) roundTrip(ctx context.Context, req Request) (Response, error)
 	}
 
 	switch resp := r.(type) {
+	case *fetchAPI.Response:
+		resp.ResolveTopicNames(req.(*fetchAPI.Request))
+		// Topics that were deleted and recreated get a new ID, refresh the
+		// cached metadata so the next fetch requests use the new topic IDs.
+		if hasUnknownTopicID(resp) {
+			p.refreshMetadata(ctx, nil)
+		}
 	case *createtopics.Response:
 		// Force an update of the metadata when adding topics,
 		// otherwise the cached state would get out of sync.
@@ -431,6 +807,14 @@ func (p *connPool) roundTrip(ctx context.Context, req Request) (Response, error)
 		}
 	}
 
+	// The names of the topics of fetch v13+ responses were resolved above,
+	// the responses only carry their IDs.
+	if p.refresh.RefreshTopicsOnError {
+		if topics := staleTopics(r); len(topics) != 0 {
+			p.requestTopicsRefresh(topics)
+		}
+	}
+
 	return r, nil
 }
 
@@ -442,6 +826,12 @@ func (p *connPool) roundTrip(ctx context.Context, req Request) (Response, error)
 func (p *connPool) refreshMetadata(ctx context.Context, expectTopics []string) {
 	minBackoff := 100 * time.Millisecond
 	maxBackoff := 2 * time.Second
+	if p.refresh.MinBackoff > 0 {
+		minBackoff = p.refresh.MinBackoff
+	}
+	if p.refresh.MaxBackoff > 0 {
+		maxBackoff = p.refresh.MaxBackoff
+	}
 	cancel := ctx.Done()
 
 	for ctx.Err() == nil {
@@ -471,12 +861,7 @@ func (p *connPool) refreshMetadata(ctx context.Context, expectTopics []string) {
 		}
 
 		if delay := time.Duration(rand.Int63n(int64(minBackoff))); delay > 0 {
-			timer := time.NewTimer(minBackoff)
-			select {
-			case <-cancel:
-			case <-timer.C:
-			}
-			timer.Stop()
+			sleepClock(ctx, clockOrDefault(p.clock), minBackoff)
 
 			if minBackoff *= 2; minBackoff > maxBackoff {
 				minBackoff = maxBackoff
@@ -584,45 +969,154 @@ func (p *connPool) discover(ctx context.Context, wake <-chan event) {
 		return time.Duration(prng.Int63n(int64(p.metadataTTL)))
 	}
 
-	timer := time.NewTimer(metadataTTL())
+	timer := clockOrDefault(p.clock).NewTimer(metadataTTL())
 	defer timer.Stop()
 
 	var notify event
+	var failures int
 	done := ctx.Done()
 
 	for {
-		c, err := p.grabClusterConn(ctx)
+		ret, err := p.fetchMetadata(ctx, nil)
+		if err != nil && errors.Is(err, ctx.Err()) {
+			return
+		}
+		p.update(ctx, ret, err)
+
+		if notify != nil {
+			notify.trigger()
+			notify = nil
+		}
+
+		// Failed refreshes are retried after a backoff delay when one was
+		// configured, instead of waiting for the next periodic refresh.
 		if err != nil {
-			p.update(ctx, nil, err)
+			failures++
 		} else {
-			res := make(async, 1)
-			req := &meta.Request{}
-			deadline, cancel := context.WithTimeout(ctx, p.metadataTTL)
-			c.reqs <- connRequest{
-				ctx: deadline,
-				req: req,
-				res: res,
+			failures = 0
+		}
+		if backoff, ok := p.refresh.backoff(failures, p.metadataTTL, prng.Int63n); ok {
+			if !timer.Stop() {
+				<-timer.C()
 			}
-			r, err := res.await(deadline)
-			cancel()
-			if err != nil && errors.Is(err, ctx.Err()) {
+			timer.Reset(backoff)
+		}
+
+	wait:
+		for {
+			select {
+			case <-timer.C():
+				timer.Reset(metadataTTL())
+				break wait
+			case <-done:
 				return
+			case notify = <-wake:
+				break wait
+			case topics := <-p.topics:
+				p.refreshTopics(ctx, topics)
 			}
-			ret, _ := r.(*meta.Response)
-			p.update(ctx, ret, err)
 		}
+	}
+}
 
-		if notify != nil {
-			notify.trigger()
-			notify = nil
+// fetchMetadata requests the metadata of the given topics to the cluster, or
+// of all topics if the list is nil.
+func (p *connPool) fetchMetadata(ctx context.Context, topics []string) (*meta.Response, error) {
+	c, err := p.grabClusterConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(async, 1)
+	req := &meta.Request{TopicNames: topics}
+	deadline, cancel := context.WithTimeout(ctx, p.metadataTTL)
+	defer cancel()
+
+	c.reqs <- connRequest{
+		ctx: deadline,
+		req: req,
+		res: res,
+	}
+
+	r, err := res.await(deadline)
+	if err != nil {
+		return nil, err
+	}
+	ret, _ := r.(*meta.Response)
+	return ret, nil
+}
+
+// requestTopicsRefresh asks the goroutine running the discover method to
+// refresh the metadata of topics. The requests are dropped if too many are
+// pending, since the refreshes already pending will correct the layout.
+func (p *connPool) requestTopicsRefresh(topics []string) {
+	select {
+	case p.topics <- topics:
+	default:
+	}
+}
+
+// refreshTopics updates the cached metadata of the given topics, and of the
+// topics of other pending refresh requests. It is only called by the goroutine
+// running the discover method.
+func (p *connPool) refreshTopics(ctx context.Context, topics []string) {
+	names := make(map[string]struct{}, len(topics))
+	for _, t := range topics {
+		names[t] = struct{}{}
+	}
+
+	for pending := true; pending; {
+		select {
+		case more := <-p.topics:
+			for _, t := range more {
+				names[t] = struct{}{}
+			}
+		default:
+			pending = false
 		}
+	}
+
+	topics = make([]string, 0, len(names))
+	for t := range names {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+
+	state := p.grabState()
+	if state.metadata == nil {
+		return // the next periodic refresh will fetch the metadata of all topics
+	}
+
+	ret, err := p.fetchMetadata(ctx, topics)
+	if err != nil {
+		return
+	}
+	p.update(ctx, mergeMetadataTopics(state.metadata, ret), nil)
+}
+
+// resolve periodically closes the idle connections of the pool to addresses
+// that its host names no longer resolve to.
+func (p *connPool) resolve(ctx context.Context, interval time.Duration) {
+	ticker := clockOrDefault(p.clock).NewTicker(interval)
+	defer ticker.Stop()
 
+	for {
 		select {
-		case <-timer.C:
-			timer.Reset(metadataTTL())
-		case <-done:
+		case <-ticker.C():
+		case <-ctx.Done():
 			return
-		case notify = <-wake:
+		}
+
+		p.mutex.RLock()
+		groups := make([]*connGroup, 0, len(p.conns)+1)
+		groups = append(groups, p.ctrl)
+		for _, g := range p.conns {
+			groups = append(groups, g)
+		}
+		p.mutex.RUnlock()
+
+		for _, g := range groups {
+			g.closeStaleConns(ctx)
 		}
 	}
 }
@@ -657,8 +1151,24 @@ func (p *connPool) grabClusterConn(ctx context.Context) (*conn, error) {
 	return p.ctrl.grabConnOrConnect(ctx)
 }
 
+// grabAnyConn returns a connection for a request which is not routed to a
+// specific broker, to the broker chosen by the broker selector of the pool when
+// any broker can serve the request. The bootstrap address is used otherwise, or
+// when the chosen broker could not be reached.
+func (p *connPool) grabAnyConn(ctx context.Context, req Request) (*conn, error) {
+	if g := p.selectBroker(req); g != nil {
+		if c, err := g.grabConnOrConnect(ctx); err == nil {
+			return c, nil
+		}
+	}
+	return p.grabClusterConn(ctx)
+}
+
 func (p *connPool) sendRequest(ctx context.Context, req Request, state connPoolState) promise {
 	brokerID := int32(-1)
+	// Set when the request is routed to the coordinator of a group or
+	// transaction.
+	var coordinator *coordinatorKey
 
 	switch m := req.(type) {
 	case protocol.BrokerMessage:
@@ -675,24 +1185,17 @@ func (p *connPool) sendRequest(ctx context.Context, req Request, state connPoolS
 		// Some requests are supposed to be sent to a group coordinator,
 		// look up which broker is currently the coordinator for the group
 		// so we can get a connection to that broker.
-		//
-		// TODO: should we cache the coordinator info?
-		p := p.sendRequest(ctx, &findcoordinator.Request{Key: m.Group()}, state)
-		r, err := p.await(ctx)
-		if err != nil {
-			return reject(err)
-		}
-		brokerID = r.(*findcoordinator.Response).NodeID
+		coordinator = &coordinatorKey{keyType: CoordinatorKeyTypeConsumer, key: m.Group()}
 	case protocol.TransactionalMessage:
-		p := p.sendRequest(ctx, &findcoordinator.Request{
-			Key:     m.Transaction(),
-			KeyType: int8(CoordinatorKeyTypeTransaction),
-		}, state)
-		r, err := p.await(ctx)
+		coordinator = &coordinatorKey{keyType: CoordinatorKeyTypeTransaction, key: m.Transaction()}
+	}
+
+	if coordinator != nil {
+		broker, err := p.coordinator(ctx, *coordinator, state)
 		if err != nil {
 			return reject(err)
 		}
-		brokerID = r.(*findcoordinator.Response).NodeID
+		brokerID = broker
 	}
 
 	var c *conn
@@ -700,9 +1203,12 @@ func (p *connPool) sendRequest(ctx context.Context, req Request, state connPoolS
 	if brokerID >= 0 {
 		c, err = p.grabBrokerConn(ctx, brokerID)
 	} else {
-		c, err = p.grabClusterConn(ctx)
+		c, err = p.grabAnyConn(ctx, req)
 	}
 	if err != nil {
+		if coordinator != nil {
+			p.coordinators.invalidate(*coordinator)
+		}
 		return reject(err)
 	}
 
@@ -714,12 +1220,40 @@ func (p *connPool) sendRequest(ctx context.Context, req Request, state connPoolS
 		res: res,
 	}
 
+	if coordinator != nil {
+		return &coordinatorPromise{promise: res, cache: &p.coordinators, key: *coordinator}
+	}
 	return res
 }
 
 func filterMetadataResponse(req *meta.Request, res *meta.Response) *meta.Response {
 	ret := *res
 
+	if req.TopicNames == nil && req.Topics != nil {
+		ret.Topics = make([]meta.ResponseTopic, len(req.Topics))
+
+		for i, t := range req.Topics {
+			j, ok := findMetadataTopic(res.Topics, t.Name)
+			if t.Name == "" {
+				j, ok = findMetadataTopicID(res.Topics, t.TopicID)
+			}
+			switch {
+			case ok:
+				ret.Topics[i] = res.Topics[j]
+			case t.Name != "":
+				ret.Topics[i] = meta.ResponseTopic{
+					ErrorCode: int16(UnknownTopicOrPartition),
+					Name:      t.Name,
+				}
+			default:
+				ret.Topics[i] = meta.ResponseTopic{
+					ErrorCode: int16(UnknownTopicID),
+					TopicID:   t.TopicID,
+				}
+			}
+		}
+	}
+
 	if req.TopicNames != nil {
 		ret.Topics = make([]meta.ResponseTopic, len(req.TopicNames))
 
@@ -746,6 +1280,26 @@ func findMetadataTopic(topics []meta.ResponseTopic, topicName string) (int, bool
 	return i, i >= 0 && i < len(topics) && topics[i].Name == topicName
 }
 
+func findMetadataTopicID(topics []meta.ResponseTopic, topicID protocol.UUID) (int, bool) {
+	for i := range topics {
+		if topics[i].TopicID == topicID {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func hasUnknownTopicID(res *fetchAPI.Response) bool {
+	for _, t := range res.Topics {
+		for _, p := range t.Partitions {
+			if p.ErrorCode == int16(UnknownTopicID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func sortMetadataBrokers(brokers []meta.ResponseBroker) {
 	sort.Slice(brokers, func(i, j int) bool {
 		return brokers[i].NodeID < brokers[j].NodeID
@@ -788,6 +1342,7 @@ func makeLayout(metadataResponse *meta.Response) protocol.Cluster {
 		fmt.Printf("topics [%v]\n", topic.Name)
 
 		layout.Topics[topic.Name] = protocol.Topic{
+			ID:         topic.TopicID,
 			Name:       topic.Name,
 			Error:      topic.ErrorCode,
 			Partitions: makePartitions(topic.Partitions),
@@ -842,8 +1397,9 @@ func appendBrokerIDs(ids, brokers []int32) ([]int32, []int32) {
 
 func (p *connPool) newConnGroup(a net.Addr) *connGroup {
 	return &connGroup{
-		addr: a,
-		pool: p,
+		stats: makeConnGroupStats(),
+		addr:  a,
+		pool:  p,
 		broker: Broker{
 			ID: -1,
 		},
@@ -851,11 +1407,16 @@ func (p *connPool) newConnGroup(a net.Addr) *connGroup {
 }
 
 func (p *connPool) newBrokerConnGroup(broker Broker) *connGroup {
+	addr := &networkAddress{
+		network: "tcp",
+		address: net.JoinHostPort(broker.Host, strconv.Itoa(broker.Port)),
+	}
+	if p.addrRewrite != nil {
+		addr.network, addr.address = p.addrRewrite(broker)
+	}
 	return &connGroup{
-		addr: &networkAddress{
-			network: "tcp",
-			address: net.JoinHostPort(broker.Host, strconv.Itoa(broker.Port)),
-		},
+		stats:  makeConnGroupStats(),
+		addr:   addr,
 		pool:   p,
 		broker: broker,
 	}
@@ -953,6 +1514,10 @@ var defaultDialer = net.Dialer{
 // actual network connections are lazily open before sending requests, and
 // closed if they are unused for longer than the idle timeout.
 type connGroup struct {
+	// Statistics of the connection group, they are first in the struct to
+	// guarantee the 64-bit alignment of the values mutated with atomic
+	// operations.
+	stats  connGroupStats
 	addr   net.Addr
 	broker Broker
 	// Immutable state of the connection.
@@ -962,7 +1527,157 @@ type connGroup struct {
 	// the state maintained in these fields.
 	mutex     sync.Mutex
 	closed    bool
-	idleConns []*conn // stack of idle connections
+	idleConns []*conn       // stack of idle connections
+	openConns int           // number of open connections, including those being established
+	released  chan struct{} // closed when a connection is released or closed
+	// API versions negotiated by the last connection established.
+	versions atomic.Value // map[protocol.ApiKey]int16
+}
+
+type connGroupStats struct {
+	dials    counter
+	requests counter
+	inflight counter
+	dialTime summary
+	waitTime summary
+	latency  sync.Map // protocol.ApiKey => *histogram
+}
+
+func (s *connGroupStats) observeLatency(apiKey protocol.ApiKey, latency time.Duration) {
+	h, ok := s.latency.Load(apiKey)
+	if !ok {
+		h, _ = s.latency.LoadOrStore(apiKey, new(histogram))
+	}
+	h.(*histogram).observe(latency)
+}
+
+func (s *connGroupStats) snapshotLatency() map[protocol.ApiKey]DurationHistogram {
+	var latency map[protocol.ApiKey]DurationHistogram
+	s.latency.Range(func(k, v interface{}) bool {
+		if h := v.(*histogram).snapshot(); h.Count != 0 {
+			if latency == nil {
+				latency = make(map[protocol.ApiKey]DurationHistogram)
+			}
+			latency[k.(protocol.ApiKey)] = h
+		}
+		return true
+	})
+	return latency
+}
+
+func makeConnGroupStats() connGroupStats {
+	return connGroupStats{
+		dialTime: makeSummary(),
+		waitTime: makeSummary(),
+	}
+}
+
+func (g *connGroup) snapshot() ConnPoolStats {
+	g.mutex.Lock()
+	openConns := g.openConns
+	idleConns := len(g.idleConns)
+	g.mutex.Unlock()
+
+	return ConnPoolStats{
+		Dials:       g.stats.dials.snapshot(DeltaStats),
+		Requests:    g.stats.requests.snapshot(DeltaStats),
+		OpenConns:   int64(openConns),
+		IdleConns:   int64(idleConns),
+		InFlight:    atomic.LoadInt64(g.stats.inflight.ptr()),
+		DialTime:    g.stats.dialTime.snapshotDuration(DeltaStats),
+		WaitTime:    g.stats.waitTime.snapshotDuration(DeltaStats),
+		Latency:     g.stats.snapshotLatency(),
+		MaxConns:    int64(g.pool.maxConns),
+		IdleTimeout: g.pool.idleTimeout,
+		Addr:        g.addr.String(),
+		BrokerID:    g.broker.ID,
+	}
+}
+
+// brokerAt returns the broker that the group connects to at addr, the host and
+// port are taken from addr for the bootstrap group.
+func (g *connGroup) brokerAt(addr net.Addr) Broker {
+	broker := g.broker
+	if broker.ID < 0 {
+		broker.Host, broker.Port, _ = splitHostPortNumber(addr.String())
+	}
+	return broker
+}
+
+// closeStaleConns resolves the host names that the group connects to, and
+// closes the idle connections to addresses that were not returned. The
+// connections are left open when the names fail to resolve.
+func (g *connGroup) closeStaleConns(ctx context.Context) {
+	g.mutex.Lock()
+	conns := make([]*conn, len(g.idleConns))
+	copy(conns, g.idleConns)
+	g.mutex.Unlock()
+
+	if len(conns) == 0 {
+		return
+	}
+
+	rslv := g.pool.resolver
+	if rslv == nil {
+		rslv = NewBrokerResolver(nil)
+	}
+
+	// When the bootstrap address names SRV records, only the connections to
+	// their current targets, or to the other bootstrap addresses, are valid.
+	var targets map[string]bool
+	if g.broker.ID < 0 && strings.Contains(g.addr.String(), SRVPrefix) {
+		targets = make(map[string]bool)
+		for _, address := range strings.Split(g.addr.String(), ",") {
+			if !isSRVAddress(address) {
+				targets[address] = true
+				continue
+			}
+			addresses, err := lookupSRVAddresses(ctx, address)
+			if err != nil {
+				targets = nil
+				break
+			}
+			for _, a := range addresses {
+				targets[a] = true
+			}
+		}
+	}
+
+	ipAddrs := make(map[string]map[string]bool)
+	lookupIPAddrs := func(broker Broker) map[string]bool {
+		ips, ok := ipAddrs[broker.Host]
+		if !ok {
+			if addrs, err := rslv.LookupBrokerIPAddr(ctx, broker); err == nil {
+				ips = make(map[string]bool, len(addrs))
+				for _, a := range addrs {
+					ips[a.IP.String()] = true
+				}
+			}
+			ipAddrs[broker.Host] = ips
+		}
+		return ips
+	}
+
+	for _, c := range conns {
+		stale := targets != nil && !targets[c.address]
+
+		if !stale {
+			broker := g.brokerAt(g.addr)
+			if g.pool.resolver == nil || targets != nil {
+				// The connection was established to the host name, which
+				// the default resolver translated to an IP address.
+				broker.Host, _ = splitHostPort(c.address)
+			}
+			if remoteAddr, ok := c.remoteAddr.(*net.TCPAddr); ok && net.ParseIP(broker.Host) == nil {
+				ips := lookupIPAddrs(broker)
+				stale = ips != nil && !ips[remoteAddr.IP.String()]
+			}
+		}
+
+		if stale && g.removeConn(c) {
+			c.close()
+		}
+	}
 }
 
 func (g *connGroup) closeIdleConns() {
@@ -978,11 +1693,73 @@ func (g *connGroup) closeIdleConns() {
 }
 
 func (g *connGroup) grabConnOrConnect(ctx context.Context) (*conn, error) {
+	start := time.Now()
+	defer func() { g.stats.waitTime.observeDuration(time.Since(start)) }()
+
+	for {
+		c, err := g.grabIdleConnOrConnect(ctx)
+		if c != nil || err != nil {
+			return c, err
+		}
+
+		// The group reached the maximum number of connections, wait for one
+		// of them to be released or closed.
+		g.mutex.Lock()
+		if g.released == nil {
+			g.released = make(chan struct{})
+		}
+		released := g.released
+		g.mutex.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// reserveConn reserves a slot for a new connection in the group, it returns
+// false if the group reached the maximum number of connections.
+func (g *connGroup) reserveConn() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if max := g.pool.maxConns; max > 0 && g.openConns >= max {
+		return false
+	}
+
+	g.openConns++
+	return true
+}
+
+// closedConn is called when a connection of the group was closed, or failed to
+// be established, to release its slot.
+func (g *connGroup) closedConn() {
+	g.mutex.Lock()
+	g.openConns--
+	g.notifyReleased()
+	g.mutex.Unlock()
+}
+
+// notifyReleased wakes up the goroutines waiting for a connection, the mutex
+// must be held when calling the method.
+func (g *connGroup) notifyReleased() {
+	if g.released != nil {
+		close(g.released)
+		g.released = nil
+	}
+}
+
+// grabIdleConnOrConnect returns an idle connection, or establishes a new one.
+// The method returns a nil connection and error if there were no idle
+// connections and the group reached the maximum number of connections.
+func (g *connGroup) grabIdleConnOrConnect(ctx context.Context) (*conn, error) {
 	rslv := g.pool.resolver
 	addr := g.addr
 	var c *conn
 
-	if rslv == nil {
+	if rslv == nil || (g.broker.ID < 0 && isSRVAddress(addr.String())) {
 		c = g.grabConn()
 	} else {
 		var err error
@@ -1013,12 +1790,27 @@ func (g *connGroup) grabConnOrConnect(ctx context.Context) (*conn, error) {
 	}
 
 	if c == nil {
+		if !g.reserveConn() {
+			return nil, nil
+		}
+
 		connChan := make(chan *conn)
 		errChan := make(chan error)
 
 		go func() {
+			start := time.Now()
 			c, err := g.connect(ctx, addr)
+			g.stats.dials.observe(1)
+			g.stats.dialTime.observeDuration(time.Since(start))
+			if onConnect := g.pool.onConnect; onConnect != nil {
+				connAddr := addr
+				if c != nil {
+					connAddr = &networkAddress{network: c.network, address: c.address}
+				}
+				onConnect(g.brokerAt(connAddr), connAddr, err)
+			}
 			if err != nil {
+				g.closedConn()
 				select {
 				case errChan <- err:
 				case <-ctx.Done():
@@ -1132,6 +1924,7 @@ func (g *connGroup) releaseConn(c *conn) bool {
 	}
 
 	g.idleConns = append(g.idleConns, c)
+	g.notifyReleased()
 	return true
 }
 
@@ -1157,8 +1950,19 @@ func (g *connGroup) connect(ctx context.Context, addr net.Addr) (*conn, error) {
 		})
 	}
 
+	if network, address, err = expandSRVAddresses(ctx, network, address); err != nil {
+		return nil, err
+	}
+
 	for i := range address {
 		netConn, err = g.pool.dial(ctx, network[i], address[i])
+		if onDial := g.pool.onDial; onDial != nil {
+			a := &networkAddress{network: network[i], address: address[i]}
+			if dialErr := onDial(g.brokerAt(a), a, netConn, err); dialErr != nil && err == nil {
+				netConn.Close()
+				netConn, err = nil, dialErr
+			}
+		}
 		if err == nil {
 			netAddr = &networkAddress{
 				network: network[i],
@@ -1178,18 +1982,34 @@ func (g *connGroup) connect(ctx context.Context, addr net.Addr) (*conn, error) {
 		}
 	}()
 
-	if tlsConfig := g.pool.tls; tlsConfig != nil {
-		if tlsConfig.ServerName == "" {
-			host, _ := splitHostPort(netAddr.String())
-			tlsConfig = tlsConfig.Clone()
-			tlsConfig.ServerName = host
+	tlsConfig := g.pool.tls
+	if getTLS := g.pool.getTLS; getTLS != nil {
+		if tlsConfig, err = getTLS(g.brokerAt(netAddr)); err != nil {
+			return nil, err
+		}
+	}
+
+	if tlsConfig != nil {
+		// The certificates of brokers are issued for the host names that they
+		// advertise, not the addresses that AddrRewrite redirects to.
+		serverAddr := netAddr.String()
+		if g.pool.addrRewrite != nil && g.broker.ID >= 0 {
+			serverAddr = net.JoinHostPort(g.broker.Host, strconv.Itoa(g.broker.Port))
 		}
+		tlsConfig = brokerTLSConfig(tlsConfig, g.brokerAt(netAddr), serverAddr, g.pool.tlsName, g.pool.tlsPins)
 		netConn = tls.Client(netConn, tlsConfig)
 	}
 
 	pc := protocol.NewConn(netConn, g.pool.clientID)
 	pc.SetDeadline(deadline)
 
+	if g.pool.observer != nil {
+		pc.SetRoundTripObserver(g.pool.observer, g.pool.redaction)
+	}
+
+	pc.SetLimits(g.pool.limits)
+	pc.SetCRCMode(g.pool.crcMode)
+
 	r, err := pc.RoundTrip(new(apiversions.Request))
 	if err != nil {
 		return nil, err
@@ -1201,34 +2021,56 @@ func (g *connGroup) connect(ctx context.Context, addr net.Addr) (*conn, error) {
 		return nil, fmt.Errorf("negotating API versions with kafka broker at %s: %w", g.addr, Error(res.ErrorCode))
 	}
 
+	quirks := detectQuirks(g.pool.quirks, netAddr, res.ApiKeys)
+
 	for _, r := range res.ApiKeys {
 		apiKey := protocol.ApiKey(r.ApiKey)
-		ver[apiKey] = apiKey.SelectVersion(r.MinVersion, r.MaxVersion)
+		maxVersion := r.MaxVersion
+		if v, ok := g.pool.maxVersions[apiKey]; ok && v < maxVersion {
+			maxVersion = v
+		}
+		if quirks != nil {
+			if v, ok := quirks.MaxVersions[apiKey]; ok && v < maxVersion {
+				maxVersion = v
+			}
+		}
+		ver[apiKey] = apiKey.SelectVersion(r.MinVersion, maxVersion)
 	}
 
 	pc.SetVersions(ver)
+	g.versions.Store(ver)
 	pc.SetDeadline(time.Time{})
 
+	var saslMetadata *sasl.Metadata
+	var saslExpiry time.Time
+
 	if g.pool.sasl != nil {
 		host, port, err := splitHostPortNumber(netAddr.String())
 		if err != nil {
 			return nil, err
 		}
-		metadata := &sasl.Metadata{
+		saslMetadata = &sasl.Metadata{
 			Host: host,
 			Port: port,
+			TLS:  tlsConnectionState(netConn),
 		}
-		if err := authenticateSASL(sasl.WithMetadata(ctx, metadata), pc, g.pool.sasl); err != nil {
+		lifetimeMs, err := authenticateSASL(sasl.WithMetadata(ctx, saslMetadata), pc, g.pool.sasl)
+		if err != nil {
 			return nil, err
 		}
+		saslExpiry = saslReauthenticationTime(time.Now(), lifetimeMs)
 	}
 
 	reqs := make(chan connRequest)
 	c := &conn{
-		network: netAddr.Network(),
-		address: netAddr.String(),
-		reqs:    reqs,
-		group:   g,
+		network:      netAddr.Network(),
+		address:      netAddr.String(),
+		remoteAddr:   netConn.RemoteAddr(),
+		reqs:         reqs,
+		group:        g,
+		quirks:       quirks,
+		saslMetadata: saslMetadata,
+		saslExpiry:   saslExpiry,
 	}
 	go c.run(pc, reqs)
 
@@ -1236,13 +2078,44 @@ func (g *connGroup) connect(ctx context.Context, addr net.Addr) (*conn, error) {
 	return c, nil
 }
 
+// expandSRVAddresses replaces the DNS SRV names in the list of addresses with
+// the targets of their records.
+func expandSRVAddresses(ctx context.Context, network, address []string) ([]string, []string, error) {
+	for i := 0; i < len(address); i++ {
+		if !isSRVAddress(address[i]) {
+			continue
+		}
+
+		targets, err := lookupSRVAddresses(ctx, address[i])
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve %s: %w", address[i], err)
+		}
+
+		networks := make([]string, len(targets))
+		for j := range networks {
+			networks[j] = network[i]
+		}
+
+		network = append(network[:i], append(networks, network[i+1:]...)...)
+		address = append(address[:i], append(targets, address[i+1:]...)...)
+		i += len(targets) - 1
+	}
+	return network, address, nil
+}
+
 type conn struct {
-	reqs    chan<- connRequest
-	network string
-	address string
-	once    sync.Once
-	group   *connGroup
-	timer   *time.Timer
+	reqs       chan<- connRequest
+	network    string
+	address    string
+	remoteAddr net.Addr
+	once       sync.Once
+	group      *connGroup
+	timer      *time.Timer
+	quirks     *BrokerQuirks
+	// SASL session of the connection, only accessed by the goroutine running
+	// the connection.
+	saslMetadata *sasl.Metadata
+	saslExpiry   time.Time
 }
 
 func (c *conn) close() {
@@ -1250,13 +2123,35 @@ func (c *conn) close() {
 }
 
 func (c *conn) run(pc *protocol.Conn, reqs <-chan connRequest) {
+	var closeErr error
+	defer func() {
+		if onClose := c.group.pool.onClose; onClose != nil {
+			addr := &networkAddress{network: c.network, address: c.address}
+			onClose(c.group.brokerAt(addr), addr, closeErr)
+		}
+	}()
+	defer c.group.closedConn()
 	defer pc.Close()
 
 	for cr := range reqs {
+		if err := c.reauthenticateSASL(cr.ctx, pc); err != nil {
+			cr.res.reject(err)
+			closeErr = err
+			break
+		}
+
+		c.group.stats.requests.observe(1)
+		c.group.stats.inflight.observe(1)
+		start := time.Now()
 		r, err := c.roundTrip(cr.ctx, pc, cr.req)
+		c.observeLatency(cr.req, time.Since(start), err)
+		c.group.stats.inflight.observe(-1)
 		if err != nil {
 			cr.res.reject(err)
-			if !errors.Is(err, protocol.ErrNoRecord) {
+			// Requests rejected because of broker quirks were not sent, the
+			// connection remains usable.
+			if !errors.Is(err, protocol.ErrNoRecord) && !errors.Is(err, UnsupportedVersion) {
+				closeErr = err
 				break
 			}
 		} else {
@@ -1268,6 +2163,63 @@ func (c *conn) run(pc *protocol.Conn, reqs <-chan connRequest) {
 	}
 }
 
+// observeLatency records the latency of a request, and reports it to the
+// OnSlowRequest function of the transport if it exceeded the threshold.
+func (c *conn) observeLatency(req Request, latency time.Duration, err error) {
+	apiKey := req.ApiKey()
+	c.group.stats.observeLatency(apiKey, latency)
+
+	if onSlow := c.group.pool.onSlow; onSlow != nil && latency > c.group.pool.slowLatency {
+		addr := &networkAddress{network: c.network, address: c.address}
+		onSlow(c.group.pool.slowLatency, RequestInfo{
+			ApiKey:  apiKey,
+			Broker:  c.group.brokerAt(addr),
+			Addr:    addr,
+			Latency: latency,
+			Err:     err,
+		})
+	}
+}
+
+// RequestInfo describes a request sent by a Transport, see
+// Transport.OnSlowRequest.
+type RequestInfo struct {
+	// The API of the request.
+	ApiKey protocol.ApiKey
+
+	// The broker that the request was sent to, and its address. The broker
+	// has the ID -1 for requests sent to the bootstrap address.
+	Broker Broker
+	Addr   net.Addr
+
+	// The time between sending the request and receiving its response.
+	Latency time.Duration
+
+	// The error that the request failed with, if any.
+	Err error
+}
+
+// reauthenticateSASL re-authenticates the connection if its SASL session is
+// about to expire (see KIP-368).
+func (c *conn) reauthenticateSASL(ctx context.Context, pc *protocol.Conn) error {
+	if c.saslExpiry.IsZero() || time.Now().Before(c.saslExpiry) {
+		return nil
+	}
+
+	if deadline, hasDeadline := ctx.Deadline(); hasDeadline {
+		pc.SetDeadline(deadline)
+		defer pc.SetDeadline(time.Time{})
+	}
+
+	lifetimeMs, err := authenticateSASL(sasl.WithMetadata(ctx, c.saslMetadata), pc, c.group.pool.sasl)
+	if err != nil {
+		return fmt.Errorf("SASL re-authentication with kafka broker at %s failed: %w", c.address, err)
+	}
+
+	c.saslExpiry = saslReauthenticationTime(time.Now(), lifetimeMs)
+	return nil
+}
+
 func (c *conn) roundTrip(ctx context.Context, pc *protocol.Conn, req Request) (Response, error) {
 	pprof.SetGoroutineLabels(ctx)
 	defer pprof.SetGoroutineLabels(context.Background())
@@ -1277,41 +2229,81 @@ func (c *conn) roundTrip(ctx context.Context, pc *protocol.Conn, req Request) (R
 		defer pc.SetDeadline(time.Time{})
 	}
 
-	return pc.RoundTrip(req)
+	if err := c.quirks.checkRequest(req); err != nil {
+		return nil, err
+	}
+
+	res, err := pc.RoundTrip(req)
+	if err == nil {
+		c.quirks.fixResponse(res)
+	}
+	return res, err
 }
 
 // authenticateSASL performs all of the required requests to authenticate this
 // connection.  If any step fails, this function returns with an error.  A nil
 // error indicates successful authentication.
-func authenticateSASL(ctx context.Context, pc *protocol.Conn, mechanism sasl.Mechanism) error {
+//
+// The first return value is the lifetime of the SASL session in milliseconds,
+// which is zero if the session does not expire or the broker does not support
+// re-authentication (KIP-368).
+func authenticateSASL(ctx context.Context, pc *protocol.Conn, mechanism sasl.Mechanism) (int64, error) {
 	if err := saslHandshakeRoundTrip(pc, mechanism.Name()); err != nil {
-		return err
+		return 0, err
 	}
 
 	sess, state, err := mechanism.Start(ctx)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
+	var lifetimeMs int64
 	for completed := false; !completed; {
-		challenge, err := saslAuthenticateRoundTrip(pc, state)
+		var challenge []byte
+		challenge, lifetimeMs, err = saslAuthenticateRoundTrip(pc, state)
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				// the broker may communicate a failed exchange by closing the
 				// connection (esp. in the case where we're passing opaque sasl
 				// data over the wire since there's no protocol info).
-				return SASLAuthenticationFailed
+				return 0, SASLAuthenticationFailed
 			}
 
-			return err
+			return 0, err
 		}
 
 		completed, state, err = sess.Next(ctx, challenge)
 		if err != nil {
-			return err
+			return 0, err
 		}
 	}
 
+	return lifetimeMs, nil
+}
+
+// saslReauthenticationTime returns the time at which a SASL session with the
+// given lifetime must be re-authenticated, or the zero time if the session
+// does not expire.
+//
+// Like the Java client, sessions are re-authenticated after 85% to 95% of
+// their lifetime, the randomization spreads the re-authentication of the
+// connections established at the same time.
+func saslReauthenticationTime(now time.Time, lifetimeMs int64) time.Time {
+	if lifetimeMs <= 0 {
+		return time.Time{}
+	}
+	lifetime := time.Duration(lifetimeMs) * time.Millisecond
+	return now.Add(time.Duration(float64(lifetime) * (0.85 + 0.1*rand.Float64())))
+}
+
+// tlsConnectionState returns the state of conn if it is a TLS connection which
+// completed its handshake, or nil otherwise.
+func tlsConnectionState(conn net.Conn) *tls.ConnectionState {
+	if c, ok := conn.(interface{ ConnectionState() tls.ConnectionState }); ok {
+		if state := c.ConnectionState(); state.HandshakeComplete {
+			return &state
+		}
+	}
 	return nil
 }
 
@@ -1344,18 +2336,18 @@ func saslHandshakeRoundTrip(pc *protocol.Conn, mechanism string) error {
 // be immediately preceded by a successful saslHandshake.
 //
 // See http://kafka.apache.org/protocol.html#The_Messages_SaslAuthenticate
-func saslAuthenticateRoundTrip(pc *protocol.Conn, data []byte) ([]byte, error) {
+func saslAuthenticateRoundTrip(pc *protocol.Conn, data []byte) ([]byte, int64, error) {
 	msg, err := pc.RoundTrip(&saslauthenticate.Request{
 		AuthBytes: data,
 	})
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	res := msg.(*saslauthenticate.Response)
 	if res.ErrorCode != 0 {
 		err = makeError(res.ErrorCode, res.ErrorMessage)
 	}
-	return res.AuthBytes, err
+	return res.AuthBytes, res.SessionLifetimeMs, err
 }
 
 var _ RoundTripper = (*Transport)(nil)
@@ -4,13 +4,22 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
+	"io"
 	"net"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
 	"github.com/segmentio/kafka-go/protocol/createtopics"
 	meta "github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/offsetfetch"
+	"github.com/segmentio/kafka-go/protocol/saslauthenticate"
+	"github.com/segmentio/kafka-go/protocol/saslhandshake"
+	"github.com/segmentio/kafka-go/sasl/plain"
 )
 
 func TestIssue477(t *testing.T) {
@@ -304,3 +313,587 @@ func TestIssue806(t *testing.T) {
 		t.Fatalf("expected a meta.Response but got %T", r)
 	}
 }
+
+func TestFilterMetadataResponseTopicIDs(t *testing.T) {
+	res := &meta.Response{
+		Topics: []meta.ResponseTopic{
+			{Name: "topic-1", TopicID: UUID{15: 1}},
+			{Name: "topic-2", TopicID: UUID{15: 2}},
+		},
+	}
+
+	ret := filterMetadataResponse(&meta.Request{
+		Topics: []meta.RequestTopic{
+			{TopicID: UUID{15: 2}},
+			{Name: "topic-1"},
+			{TopicID: UUID{15: 3}},
+		},
+	}, res)
+
+	if len(ret.Topics) != 3 {
+		t.Fatalf("wrong number of topics: %d", len(ret.Topics))
+	}
+	if ret.Topics[0].Name != "topic-2" || ret.Topics[1].Name != "topic-1" {
+		t.Errorf("wrong topics: %+v", ret.Topics)
+	}
+	if ret.Topics[2].ErrorCode != int16(UnknownTopicID) || ret.Topics[2].TopicID != (UUID{15: 3}) {
+		t.Errorf("wrong unknown topic: %+v", ret.Topics[2])
+	}
+}
+
+func TestTransportMaxVersions(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		apiVersion, correlationID, _, _, err := protocol.ReadRequest(server)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		protocol.WriteResponse(server, apiVersion, correlationID, &apiversions.Response{
+			ApiKeys: []apiversions.ApiKeyResponse{
+				{ApiKey: int16(protocol.Fetch), MinVersion: 0, MaxVersion: protocol.Fetch.MaxVersion()},
+				{ApiKey: int16(protocol.Produce), MinVersion: 0, MaxVersion: protocol.Produce.MaxVersion()},
+			},
+		})
+	}()
+
+	addr := TCP("localhost:9092")
+	pool := &connPool{
+		dial: func(context.Context, string, string) (net.Conn, error) {
+			return client, nil
+		},
+		dialTimeout: time.Second,
+		maxVersions: map[protocol.ApiKey]int16{protocol.Fetch: 4},
+		conns:       map[int32]*connGroup{},
+	}
+	pool.ctrl = &connGroup{addr: addr, pool: pool}
+
+	c, err := pool.ctrl.connect(context.Background(), addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+
+	transport := &Transport{
+		pools: map[networkAddress]*connPool{
+			{network: addr.Network(), address: addr.String()}: pool,
+		},
+	}
+
+	versions := transport.NegotiatedVersions(addr)[addr.String()]
+	if v := versions[protocol.Fetch]; v != 4 {
+		t.Errorf("fetch version was not pinned: want=4 got=%d", v)
+	}
+	if v := versions[protocol.Produce]; v != protocol.Produce.MaxVersion() {
+		t.Errorf("wrong produce version: want=%d got=%d", protocol.Produce.MaxVersion(), v)
+	}
+}
+
+func TestTransportSASLReauthentication(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	authentications := make(chan struct{}, 10)
+	go serveSASLReauthentication(server, authentications)
+
+	addr := TCP("localhost:9092")
+	pool := &connPool{
+		dial: func(context.Context, string, string) (net.Conn, error) {
+			return client, nil
+		},
+		dialTimeout: time.Second,
+		sasl:        plain.Mechanism{Username: "user", Password: "pass"},
+		conns:       map[int32]*connGroup{},
+	}
+	pool.ctrl = &connGroup{addr: addr, pool: pool}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := pool.ctrl.connect(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+
+	time.Sleep(2 * time.Millisecond)
+
+	res := make(async, 1)
+	c.reqs <- connRequest{ctx: ctx, req: &meta.Request{}, res: res}
+	if _, err := res.await(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(authentications); n != 2 {
+		t.Errorf("wrong number of SASL authentications: want=2 got=%d", n)
+	}
+}
+
+// serveSASLReauthentication simulates a kafka broker which limits the lifetime
+// of SASL sessions to 1ms, forcing the re-authentication of connections before
+// each request.
+func serveSASLReauthentication(server net.Conn, authentications chan<- struct{}) {
+	for {
+		apiVersion, correlationID, _, msg, err := protocol.ReadRequest(server)
+		if err != nil {
+			return
+		}
+
+		var res protocol.Message
+		switch msg.(type) {
+		case *apiversions.Request:
+			keys := []protocol.ApiKey{protocol.ApiVersions, protocol.SaslHandshake, protocol.SaslAuthenticate, protocol.Metadata}
+			r := &apiversions.Response{}
+			for _, k := range keys {
+				r.ApiKeys = append(r.ApiKeys, apiversions.ApiKeyResponse{ApiKey: int16(k), MaxVersion: k.MaxVersion()})
+			}
+			res = r
+		case *saslhandshake.Request:
+			res = &saslhandshake.Response{Mechanisms: []string{"PLAIN"}}
+		case *saslauthenticate.Request:
+			res = &saslauthenticate.Response{SessionLifetimeMs: 1}
+			authentications <- struct{}{}
+		case *meta.Request:
+			res = &meta.Response{}
+		}
+
+		protocol.WriteResponse(server, apiVersion, correlationID, res)
+	}
+}
+
+func TestTransportAddrRewrite(t *testing.T) {
+	pool := &connPool{
+		addrRewrite: func(broker Broker) (string, string) {
+			return "tcp", fmt.Sprintf("localhost:%d", 19090+broker.ID)
+		},
+	}
+
+	g := pool.newBrokerConnGroup(Broker{Host: "kafka-2.internal", Port: 9092, ID: 2})
+	if g.addr.Network() != "tcp" || g.addr.String() != "localhost:19092" {
+		t.Errorf("broker address was not rewritten: %s://%s", g.addr.Network(), g.addr.String())
+	}
+	if g.broker.Host != "kafka-2.internal" || g.broker.Port != 9092 {
+		t.Errorf("advertised broker address was modified: %+v", g.broker)
+	}
+
+	pool.addrRewrite = nil
+	g = pool.newBrokerConnGroup(Broker{Host: "kafka-2.internal", Port: 9092, ID: 2})
+	if g.addr.String() != "kafka-2.internal:9092" {
+		t.Errorf("wrong broker address: %s", g.addr.String())
+	}
+}
+
+func TestTransportMaxConnsPerBroker(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		apiVersion, correlationID, _, _, err := protocol.ReadRequest(server)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		protocol.WriteResponse(server, apiVersion, correlationID, &apiversions.Response{})
+	}()
+
+	addr := TCP("localhost:9092")
+	pool := &connPool{
+		dial: func(context.Context, string, string) (net.Conn, error) {
+			return client, nil
+		},
+		dialTimeout: time.Second,
+		idleTimeout: time.Minute,
+		maxConns:    1,
+		conns:       map[int32]*connGroup{},
+	}
+	pool.ctrl = pool.newConnGroup(addr)
+
+	transport := &Transport{
+		pools: map[networkAddress]*connPool{
+			{network: addr.Network(), address: addr.String()}: pool,
+		},
+	}
+
+	c, err := pool.ctrl.grabConnOrConnect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := pool.ctrl.grabConnOrConnect(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("grabbing a connection beyond the limit should have timed out, got %v", err)
+	}
+
+	done := make(chan *conn)
+	go func() {
+		c, err := pool.ctrl.grabConnOrConnect(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		done <- c
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	pool.ctrl.releaseConn(c)
+
+	select {
+	case c2 := <-done:
+		if c2 != c {
+			t.Error("the released connection should have been reused")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the connection to be released")
+	}
+
+	stats := transport.Stats(addr)
+	if len(stats) != 1 {
+		t.Fatalf("wrong number of connection pool stats: %d", len(stats))
+	}
+
+	s := stats[0]
+	if s.Dials != 1 || s.OpenConns != 1 || s.IdleConns != 0 || s.MaxConns != 1 || s.BrokerID != -1 {
+		t.Errorf("wrong connection pool stats: %+v", s)
+	}
+	if s.WaitTime.Max < 10*time.Millisecond {
+		t.Errorf("the time spent waiting for a connection was not reported: %+v", s.WaitTime)
+	}
+}
+
+func TestTransportConnHooks(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		apiVersion, correlationID, _, _, err := protocol.ReadRequest(server)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		protocol.WriteResponse(server, apiVersion, correlationID, &apiversions.Response{})
+	}()
+
+	events := make(chan string, 3)
+	addr := TCP("localhost:9092")
+	pool := &connPool{
+		dial: func(context.Context, string, string) (net.Conn, error) {
+			return client, nil
+		},
+		dialTimeout: time.Second,
+		idleTimeout: time.Minute,
+		onDial: func(broker Broker, addr net.Addr, conn net.Conn, err error) error {
+			events <- fmt.Sprintf("dial %s:%d %s %t %v", broker.Host, broker.Port, addr, conn == client, err)
+			return nil
+		},
+		onConnect: func(broker Broker, addr net.Addr, err error) {
+			events <- fmt.Sprintf("connect %d %s %v", broker.ID, addr, err)
+		},
+		onClose: func(broker Broker, addr net.Addr, err error) {
+			events <- fmt.Sprintf("close %d %s %v", broker.ID, addr, err)
+		},
+		conns: map[int32]*connGroup{},
+	}
+	pool.ctrl = pool.newConnGroup(addr)
+
+	c, err := pool.ctrl.grabConnOrConnect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.close()
+
+	for _, want := range []string{
+		"dial localhost:9092 localhost:9092 true <nil>",
+		"connect -1 localhost:9092 <nil>",
+		"close -1 localhost:9092 <nil>",
+	} {
+		select {
+		case got := <-events:
+			if got != want {
+				t.Errorf("wrong connection event:\nwant: %s\ngot:  %s", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for connection event: %s", want)
+		}
+	}
+}
+
+func TestTransportRequestLatency(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		for i := 0; i < 2; i++ {
+			apiVersion, correlationID, _, _, err := protocol.ReadRequest(server)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if i == 1 {
+				time.Sleep(20 * time.Millisecond)
+			}
+			protocol.WriteResponse(server, apiVersion, correlationID, &apiversions.Response{})
+		}
+	}()
+
+	slow := make(chan RequestInfo, 1)
+	addr := TCP("localhost:9092")
+	pool := &connPool{
+		dial: func(context.Context, string, string) (net.Conn, error) {
+			return client, nil
+		},
+		dialTimeout: time.Second,
+		idleTimeout: time.Minute,
+		slowLatency: 10 * time.Millisecond,
+		onSlow: func(threshold time.Duration, info RequestInfo) {
+			if threshold != 10*time.Millisecond {
+				t.Errorf("wrong threshold: %s", threshold)
+			}
+			slow <- info
+		},
+		conns: map[int32]*connGroup{},
+	}
+	pool.ctrl = pool.newConnGroup(addr)
+
+	transport := &Transport{
+		pools: map[networkAddress]*connPool{
+			{network: addr.Network(), address: addr.String()}: pool,
+		},
+	}
+
+	c, err := pool.ctrl.grabConnOrConnect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+
+	res := make(async, 1)
+	c.reqs <- connRequest{
+		ctx: context.Background(),
+		req: &apiversions.Request{},
+		res: res,
+	}
+	if _, err := res.await(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case info := <-slow:
+		if info.ApiKey != protocol.ApiVersions || info.Broker.ID != -1 || info.Latency < 20*time.Millisecond || info.Err != nil {
+			t.Errorf("wrong slow request info: %+v", info)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the slow request to be reported")
+	}
+
+	stats := transport.Stats(addr)
+	if len(stats) != 1 {
+		t.Fatalf("wrong number of connection pool stats: %d", len(stats))
+	}
+	h, ok := stats[0].Latency[protocol.ApiVersions]
+	if !ok || h.Count != 1 || h.Sum < 20*time.Millisecond {
+		t.Fatalf("wrong latency histogram: %+v", stats[0].Latency)
+	}
+	for _, b := range h.Buckets {
+		want := int64(0)
+		if b.UpperBound >= h.Sum {
+			want = 1
+		}
+		if b.Count != want {
+			t.Errorf("wrong count in bucket %s: %d", b.UpperBound, b.Count)
+		}
+	}
+
+	if latency := transport.Stats(addr)[0].Latency; latency != nil {
+		t.Errorf("the latency should have been reset by the previous snapshot: %+v", latency)
+	}
+}
+
+func TestTransportOnDialError(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	errDenied := errors.New("denied")
+	addr := TCP("localhost:9092")
+	pool := &connPool{
+		dial: func(context.Context, string, string) (net.Conn, error) {
+			return client, nil
+		},
+		dialTimeout: time.Second,
+		onDial: func(Broker, net.Addr, net.Conn, error) error {
+			return errDenied
+		},
+		conns: map[int32]*connGroup{},
+	}
+	pool.ctrl = pool.newConnGroup(addr)
+
+	if _, err := pool.ctrl.grabConnOrConnect(context.Background()); !errors.Is(err, errDenied) {
+		t.Fatalf("the error returned by OnDial should have aborted the connection, got %v", err)
+	}
+	if _, err := server.Read(make([]byte, 1)); err == nil {
+		t.Error("the network connection should have been closed")
+	}
+}
+
+type remoteAddrConn struct {
+	net.Conn
+	remoteAddr net.Addr
+}
+
+func (c *remoteAddrConn) RemoteAddr() net.Addr { return c.remoteAddr }
+
+type ipAddrResolver struct {
+	mutex sync.Mutex
+	ips   map[string][]net.IPAddr
+}
+
+func (r *ipAddrResolver) set(host string, ips ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+	r.ips[host] = addrs
+}
+
+func (r *ipAddrResolver) LookupBrokerIPAddr(ctx context.Context, broker Broker) ([]net.IPAddr, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if ips, ok := r.ips[broker.Host]; ok {
+		return ips, nil
+	}
+	return nil, fmt.Errorf("unrecognized host %s", broker.Host)
+}
+
+func TestTransportCloseStaleConns(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		apiVersion, correlationID, _, _, err := protocol.ReadRequest(server)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		protocol.WriteResponse(server, apiVersion, correlationID, &apiversions.Response{})
+	}()
+
+	resolver := &ipAddrResolver{ips: map[string][]net.IPAddr{}}
+	resolver.set("kafka", "10.0.0.1")
+
+	addr := TCP("kafka:9092")
+	pool := &connPool{
+		dial: func(context.Context, string, string) (net.Conn, error) {
+			return &remoteAddrConn{
+				Conn:       client,
+				remoteAddr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 9092},
+			}, nil
+		},
+		dialTimeout: time.Second,
+		idleTimeout: time.Minute,
+		resolver:    resolver,
+		conns:       map[int32]*connGroup{},
+	}
+	pool.ctrl = pool.newConnGroup(addr)
+
+	c, err := pool.ctrl.grabConnOrConnect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.close()
+	pool.ctrl.releaseConn(c)
+
+	pool.ctrl.closeStaleConns(context.Background())
+	if n := pool.ctrl.snapshot().IdleConns; n != 1 {
+		t.Fatalf("the connection to a resolved address was closed: idle=%d", n)
+	}
+
+	resolver.set("kafka", "10.0.0.2")
+
+	pool.ctrl.closeStaleConns(context.Background())
+	if n := pool.ctrl.snapshot().IdleConns; n != 0 {
+		t.Fatalf("the connection to a stale address was not closed: idle=%d", n)
+	}
+
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := server.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Errorf("the network connection should have been closed, got %v", err)
+	}
+}
+
+func TestExpandSRVAddresses(t *testing.T) {
+	defer func(f func(context.Context, string, string, string) (string, []*net.SRV, error)) { lookupSRV = f }(lookupSRV)
+
+	lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		return name, []*net.SRV{
+			{Target: "kafka-1.example.com.", Port: 9092},
+			{Target: "kafka-2.example.com.", Port: 9092},
+		}, nil
+	}
+
+	network, address, err := expandSRVAddresses(context.Background(),
+		[]string{"tcp", "tcp"},
+		[]string{"localhost:9092", SRVPrefix + "_kafka._tcp.example.com"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantNetwork := []string{"tcp", "tcp", "tcp"}
+	wantAddress := []string{"localhost:9092", "kafka-1.example.com:9092", "kafka-2.example.com:9092"}
+	if !reflect.DeepEqual(network, wantNetwork) || !reflect.DeepEqual(address, wantAddress) {
+		t.Errorf("wrong expanded addresses:\nwant: %q %q\ngot:  %q %q", wantNetwork, wantAddress, network, address)
+	}
+}
+
+func TestCoordinatorPromiseInvalidation(t *testing.T) {
+	key := coordinatorKey{keyType: CoordinatorKeyTypeConsumer, key: "group-A"}
+
+	tests := []struct {
+		scenario   string
+		result     interface{}
+		invalidate bool
+	}{
+		{
+			scenario: "success",
+			result:   &offsetfetch.Response{},
+		},
+		{
+			scenario:   "not coordinator",
+			result:     &offsetfetch.Response{ErrorCode: int16(NotCoordinatorForGroup)},
+			invalidate: true,
+		},
+		{
+			scenario:   "coordinator not available",
+			result:     &offsetfetch.Response{ErrorCode: int16(GroupCoordinatorNotAvailable)},
+			invalidate: true,
+		},
+		{
+			scenario: "other error",
+			result:   &offsetfetch.Response{ErrorCode: int16(GroupAuthorizationFailed)},
+		},
+		{
+			scenario:   "request failed",
+			result:     io.ErrUnexpectedEOF,
+			invalidate: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			cache := &coordinatorCache{brokers: map[coordinatorKey]int32{key: 1}}
+
+			res := make(async, 1)
+			res <- test.result
+			(&coordinatorPromise{promise: res, cache: cache, key: key}).await(context.Background())
+
+			if _, cached := cache.brokers[key]; cached == test.invalidate {
+				t.Errorf("cached=%t, want %t", cached, !test.invalidate)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+//go:build go1.18
+// +build go1.18
+
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Codec is an interface implemented by types that encode and decode the values
+// of messages read and written by TypedReader and TypedWriter. The methods
+// have the signatures of json.Marshal and json.Unmarshal, so most
+// serialization packages can be adapted with a few lines of code.
+//
+// Codecs must be safe to use concurrently from multiple goroutines.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec is a Codec using the encoding/json package, it is the default
+// codec of TypedReader and TypedWriter.
+type JSONCodec struct{}
+
+// Marshal satisfies the Codec interface.
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal satisfies the Codec interface.
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+func codecOrDefault(c Codec) Codec {
+	if c == nil {
+		return JSONCodec{}
+	}
+	return c
+}
+
+// TypedMessage is a message carrying a value of type T.
+//
+// The embedded Message holds the other fields of the message; its Value
+// field is shadowed by the typed value, and holds the encoded value in
+// messages returned by TypedReader.
+type TypedMessage[T any] struct {
+	Message
+	Value T
+}
+
+// DecodeError is returned by TypedReader when the value of a message could not
+// be decoded. The message is returned along with the error, with the encoded
+// value in its Message.Value field, so programs can commit it to skip it.
+type DecodeError struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Err       error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decoding the value of the message at offset %d of %s/%d: %v", e.Offset, e.Topic, e.Partition, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// TypedReader wraps a Reader to decode the values of messages to T.
+//
+//	r := &kafka.TypedReader[Event]{Reader: kafka.NewReader(config)}
+//
+//	m, err := r.ReadMessage(ctx)
+//	...
+//	fmt.Println(m.Offset, m.Value.Name)
+//
+// Messages with a nil value, which are tombstones of compacted topics, are
+// returned with the zero-value of T.
+type TypedReader[T any] struct {
+	// The reader that messages are read from.
+	Reader *Reader
+
+	// The codec used to decode the values of messages.
+	//
+	// If nil, JSONCodec is used.
+	Codec Codec
+}
+
+// ReadMessage reads and decodes the next message, see Reader.ReadMessage.
+//
+// The message is committed before being decoded when the reader is part of a
+// consumer group, so the offset of messages returned with a *DecodeError is
+// already committed.
+func (r *TypedReader[T]) ReadMessage(ctx context.Context) (TypedMessage[T], error) {
+	m, err := r.Reader.ReadMessage(ctx)
+	if err != nil {
+		return TypedMessage[T]{Message: m}, err
+	}
+	return r.decode(m)
+}
+
+// FetchMessage reads and decodes the next message, without committing it. See
+// Reader.FetchMessage.
+func (r *TypedReader[T]) FetchMessage(ctx context.Context) (TypedMessage[T], error) {
+	m, err := r.Reader.FetchMessage(ctx)
+	if err != nil {
+		return TypedMessage[T]{Message: m}, err
+	}
+	return r.decode(m)
+}
+
+// CommitMessages commits the offsets of the messages, see
+// Reader.CommitMessages.
+func (r *TypedReader[T]) CommitMessages(ctx context.Context, msgs ...TypedMessage[T]) error {
+	raw := make([]Message, len(msgs))
+	for i, m := range msgs {
+		raw[i] = m.Message
+	}
+	return r.Reader.CommitMessages(ctx, raw...)
+}
+
+// Close closes the underlying reader.
+func (r *TypedReader[T]) Close() error {
+	return r.Reader.Close()
+}
+
+func (r *TypedReader[T]) decode(m Message) (TypedMessage[T], error) {
+	msg := TypedMessage[T]{Message: m}
+	if m.Value == nil {
+		return msg, nil
+	}
+	if err := codecOrDefault(r.Codec).Unmarshal(m.Value, &msg.Value); err != nil {
+		return msg, &DecodeError{
+			Topic:     m.Topic,
+			Partition: m.Partition,
+			Offset:    m.Offset,
+			Err:       err,
+		}
+	}
+	return msg, nil
+}
+
+// TypedWriter wraps a Writer to encode values of type T in the messages it
+// writes.
+//
+//	w := &kafka.TypedWriter[Event]{Writer: &kafka.Writer{...}}
+//
+//	err := w.WriteMessages(ctx, kafka.TypedMessage[Event]{
+//		Message: kafka.Message{Key: []byte("key")},
+//		Value:   Event{Name: "created"},
+//	})
+type TypedWriter[T any] struct {
+	// The writer that messages are written to.
+	Writer *Writer
+
+	// The codec used to encode the values of messages.
+	//
+	// If nil, JSONCodec is used.
+	Codec Codec
+}
+
+// WriteMessages encodes the values of msgs and writes the messages, see
+// Writer.WriteMessages. No messages are written if encoding any of the
+// values fails.
+func (w *TypedWriter[T]) WriteMessages(ctx context.Context, msgs ...TypedMessage[T]) error {
+	codec := codecOrDefault(w.Codec)
+	raw := make([]Message, len(msgs))
+
+	for i, m := range msgs {
+		value, err := codec.Marshal(m.Value)
+		if err != nil {
+			return fmt.Errorf("encoding the value of message %d: %w", i, err)
+		}
+		raw[i] = m.Message
+		raw[i].Value = value
+	}
+
+	return w.Writer.WriteMessages(ctx, raw...)
+}
+
+// Close closes the underlying writer.
+func (w *TypedWriter[T]) Close() error {
+	return w.Writer.Close()
+}
@@ -0,0 +1,96 @@
+//go:build go1.18
+// +build go1.18
+
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+type typedEvent struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestTypedReaderWriter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A"})
+
+	w := &kafka.TypedWriter[typedEvent]{
+		Writer: &kafka.Writer{
+			Addr:         kafka.TCP(b.Addr().String()),
+			Topic:        "topic-A",
+			BatchTimeout: time.Millisecond,
+			RequiredAcks: kafka.RequireAll,
+		},
+	}
+	defer w.Close()
+
+	if err := w.WriteMessages(ctx,
+		kafka.TypedMessage[typedEvent]{Message: kafka.Message{Key: []byte("A")}, Value: typedEvent{Name: "created", Count: 1}},
+		kafka.TypedMessage[typedEvent]{Message: kafka.Message{Key: []byte("B")}, Value: typedEvent{Name: "deleted", Count: 2}},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Append("topic-A", 0, kafkatest.Record{Value: []byte("not json")}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &kafka.TypedReader[typedEvent]{
+		Reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers: []string{b.Addr().String()},
+			Topic:   "topic-A",
+			MaxWait: 10 * time.Millisecond,
+		}),
+	}
+	defer r.Close()
+
+	for _, want := range []typedEvent{{"created", 1}, {"deleted", 2}} {
+		m, err := r.FetchMessage(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Value != want {
+			t.Errorf("wrong value at offset %d: %+v", m.Offset, m.Value)
+		}
+	}
+
+	m, err := r.FetchMessage(ctx)
+	var decodeErr *kafka.DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected a decode error but got %v", err)
+	}
+	if decodeErr.Topic != "topic-A" || decodeErr.Offset != 2 {
+		t.Errorf("wrong decode error context: %+v", decodeErr)
+	}
+	if string(m.Message.Value) != "not json" {
+		t.Errorf("the encoded value was not returned with the error: %q", m.Message.Value)
+	}
+}
+
+type failingCodec struct{ kafka.JSONCodec }
+
+var errCodec = errors.New("codec failure")
+
+func (failingCodec) Marshal(interface{}) ([]byte, error) { return nil, errCodec }
+
+func TestTypedWriterEncodeError(t *testing.T) {
+	w := &kafka.TypedWriter[typedEvent]{
+		Writer: &kafka.Writer{Addr: kafka.TCP("localhost:0"), Topic: "topic-A"},
+		Codec:  failingCodec{},
+	}
+	defer w.Close()
+
+	err := w.WriteMessages(context.Background(), kafka.TypedMessage[typedEvent]{})
+	if !errors.Is(err, errCodec) {
+		t.Errorf("expected the codec error but got %v", err)
+	}
+}
@@ -467,7 +467,7 @@ func (wb *writeBuffer) writeRecordBatch(attributes int16, size int32, count int,
 	)
 
 	// dry run to compute the checksum
-	cw := &crc32Writer{table: crc32.MakeTable(crc32.Castagnoli)}
+	cw := &crc32Writer{table: castagnoliTable}
 	wb.w = cw
 	cw.writeInt16(attributes) // attributes, timestamp type 0 - create time, not part of a transaction, no control messages
 	cw.writeInt32(lastOffsetDelta)
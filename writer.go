@@ -4,12 +4,15 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/segmentio/kafka-go/protocol"
 	metadataAPI "github.com/segmentio/kafka-go/protocol/metadata"
 )
 
@@ -95,6 +98,15 @@ type Writer struct {
 	// The default is to use a round-robin distribution.
 	Balancer Balancer
 
+	// Router optionally chooses the partition of each message with access to
+	// the metadata of the topic partitions, such as their leader and in-sync
+	// replicas, for routing decisions that the Balancer interface cannot
+	// express. The returned value must be the ID of one of the partitions, and
+	// errors abort the call to WriteMessages.
+	//
+	// When set, Router takes precedence over Balancer.
+	Router func(msg Message, partitions []Partition) (int, error)
+
 	// Limit on how many attempts will be made to deliver a message.
 	//
 	// The default is to try at most 10 times.
@@ -170,6 +182,11 @@ type Writer struct {
 	Completion func(messages []Message, err error)
 
 	// Compression set the compression codec to be used to compress messages.
+	//
+	// Batches are compressed as soon as they are ready to be written, on up to
+	// GOMAXPROCS goroutines, so the batches of different partitions are
+	// compressed in parallel. Batches of a partition are still written in
+	// order.
 	Compression Compression
 
 	// If not nil, specifies a logger used to report internal changes within the
@@ -180,6 +197,11 @@ type Writer struct {
 	// back to using Logger instead.
 	ErrorLogger Logger
 
+	// If not nil, specifies a structured logger used to report internal
+	// changes and errors within the writer, with keys and values describing
+	// their context. It takes precedence over Logger and ErrorLogger.
+	StructuredLogger StructuredLogger
+
 	// A transport used to send messages to kafka clusters.
 	//
 	// If nil, DefaultTransport is used.
@@ -188,12 +210,45 @@ type Writer struct {
 	// AllowAutoTopicCreation notifies writer to create topic if missing.
 	AllowAutoTopicCreation bool
 
+	// InsyncCheck configures a check that the partitions of a topic have
+	// enough in-sync replicas to satisfy its min.insync.replicas configuration,
+	// done before the writer produces its first messages to the topic. The
+	// check only applies when RequiredAcks is RequireAll, see InsyncCheck for
+	// details.
+	//
+	// The default is to not check the in-sync replicas.
+	InsyncCheck InsyncCheck
+
+	// An optional encryptor used to encrypt the values of messages before they
+	// are sent to kafka, see Encryptor for details.
+	//
+	// The messages passed to the Completion function carry the encrypted
+	// values.
+	Encryptor Encryptor
+
+	// An optional interceptor called with the messages written by the writer,
+	// see WriterInterceptor for details.
+	Interceptor WriterInterceptor
+
+	// An optional clock used to schedule batch timeouts and retry backoffs,
+	// so tests can drive them with a fake clock. See Clock for details.
+	//
+	// If nil, SystemClock is used.
+	Clock Clock
+
 	// Manages the current set of partition-topic writers.
 	group   sync.WaitGroup
 	mutex   sync.Mutex
 	closed  bool
 	writers map[topicPartition]*partitionWriter
 
+	// Topics which passed the in-sync replicas check, see InsyncCheck.
+	insyncTopics map[string]struct{}
+
+	// Bounds the number of batches compressed concurrently, created with the
+	// map of partition writers.
+	compressors chan struct{}
+
 	// writer stats are all made of atomic values, no need for synchronization.
 	// Use a pointer to ensure 64-bit alignment of the values. The once value is
 	// used to lazily create the value when first used, allowing programs to use
@@ -241,6 +296,15 @@ type WriterConfig struct {
 	// The default is to use a round-robin distribution.
 	Balancer Balancer
 
+	// Router optionally chooses the partition of each message with access to
+	// the metadata of the topic partitions, such as their leader and in-sync
+	// replicas, for routing decisions that the Balancer interface cannot
+	// express. The returned value must be the ID of one of the partitions, and
+	// errors abort the call to WriteMessages.
+	//
+	// When set, Router takes precedence over Balancer.
+	Router func(msg Message, partitions []Partition) (int, error)
+
 	// Limit on how many attempts will be made to deliver a message.
 	//
 	// The default is to try at most 10 times.
@@ -317,6 +381,11 @@ type WriterConfig struct {
 	// ErrorLogger is the logger used to report errors. If nil, the writer falls
 	// back to using Logger instead.
 	ErrorLogger Logger
+
+	// If not nil, specifies a structured logger used to report internal
+	// changes and errors within the writer, with keys and values describing
+	// their context. It takes precedence over Logger and ErrorLogger.
+	StructuredLogger StructuredLogger
 }
 
 type topicPartition struct {
@@ -325,11 +394,66 @@ type topicPartition struct {
 }
 
 // Validate method validates WriterConfig properties.
+//
+// All the problems found in the configuration are reported in the returned
+// *ConfigError, instead of only the first one.
+//
+// Validate also reports problems that NewWriter tolerates to remain compatible
+// with configurations it accepted in earlier versions.
 func (config *WriterConfig) Validate() error {
+	var errs configErrors
+	config.validate(&errs)
+	config.validateStrict(&errs)
+	return errs.err()
+}
+
+// validate checks the properties of the configuration that NewWriter requires
+// to create a writer.
+func (config *WriterConfig) validate(errs *configErrors) {
 	if len(config.Brokers) == 0 {
-		return errors.New("cannot create a kafka writer with an empty list of brokers")
+		errs.add(errors.New("cannot create a kafka writer with an empty list of brokers"))
+	}
+}
+
+// validateStrict checks the properties of the configuration that NewWriter
+// did not check in earlier versions, it accepts them to remain compatible.
+func (config *WriterConfig) validateStrict(errs *configErrors) {
+	counts := []struct {
+		name  string
+		value int
+	}{
+		{"MaxAttempts", config.MaxAttempts},
+		{"QueueCapacity", config.QueueCapacity},
+		{"BatchSize", config.BatchSize},
+		{"BatchBytes", config.BatchBytes},
+	}
+	for _, c := range counts {
+		if c.value < 0 {
+			errs.addf("%s out of bounds: %d", c.name, c.value)
+		}
+	}
+
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"BatchTimeout", config.BatchTimeout},
+		{"ReadTimeout", config.ReadTimeout},
+		{"WriteTimeout", config.WriteTimeout},
+		{"RebalanceInterval", config.RebalanceInterval},
+		{"IdleConnTimeout", config.IdleConnTimeout},
+	}
+	for _, d := range durations {
+		if d.value < 0 {
+			errs.addf("%s out of bounds: %d", d.name, d.value)
+		}
+	}
+
+	switch config.RequiredAcks {
+	case int(RequireAll), int(RequireNone), int(RequireOne):
+	default:
+		errs.addf("RequiredAcks must be one of -1, 0, or 1 but got %d", config.RequiredAcks)
 	}
-	return nil
 }
 
 // WriterStats is a data structure returned by a call to Writer.Stats that
@@ -395,13 +519,27 @@ type writerStats struct {
 	batchSizeBytes summary
 }
 
+func newWriterStats() *writerStats {
+	return &writerStats{
+		dialTime:       makeSummary(),
+		batchTime:      makeSummary(),
+		writeTime:      makeSummary(),
+		waitTime:       makeSummary(),
+		retries:        makeSummary(),
+		batchSize:      makeSummary(),
+		batchSizeBytes: makeSummary(),
+	}
+}
+
 // NewWriter creates and returns a new Writer configured with config.
 //
 // DEPRECATED: Writer value can be instantiated and configured directly,
 // this function is retained for backward compatibility and will be removed
 // in version 1.0.
 func NewWriter(config WriterConfig) *Writer {
-	if err := config.Validate(); err != nil {
+	var errs configErrors
+	config.validate(&errs)
+	if err := errs.err(); err != nil {
 		panic(err)
 	}
 
@@ -435,7 +573,7 @@ func NewWriter(config WriterConfig) *Writer {
 		resolver = kafkaDialer.Resolver
 	}
 
-	stats := new(writerStats)
+	stats := newWriterStats()
 	// For backward compatibility with the pre-0.4 APIs, support custom
 	// resolvers by wrapping the dial function.
 	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
@@ -466,31 +604,36 @@ func NewWriter(config WriterConfig) *Writer {
 	}
 
 	transport := &Transport{
-		Dial:        dial,
-		SASL:        kafkaDialer.SASLMechanism,
-		TLS:         kafkaDialer.TLS,
-		ClientID:    kafkaDialer.ClientID,
-		IdleTimeout: idleTimeout,
-		MetadataTTL: metadataTTL,
+		Dial:          dial,
+		SASL:          kafkaDialer.SASLMechanism,
+		TLS:           kafkaDialer.TLS,
+		GetTLSConfig:  kafkaDialer.GetTLSConfig,
+		TLSServerName: kafkaDialer.TLSServerName,
+		TLSPins:       kafkaDialer.TLSPins,
+		ClientID:      kafkaDialer.ClientID,
+		IdleTimeout:   idleTimeout,
+		MetadataTTL:   metadataTTL,
 	}
 
 	w := &Writer{
-		Addr:         TCP(config.Brokers...),
-		Topic:        config.Topic,
-		MaxAttempts:  config.MaxAttempts,
-		BatchSize:    config.BatchSize,
-		Balancer:     config.Balancer,
-		BatchBytes:   int64(config.BatchBytes),
-		BatchTimeout: config.BatchTimeout,
-		ReadTimeout:  config.ReadTimeout,
-		WriteTimeout: config.WriteTimeout,
-		RequiredAcks: RequiredAcks(config.RequiredAcks),
-		Async:        config.Async,
-		Logger:       config.Logger,
-		ErrorLogger:  config.ErrorLogger,
-		Transport:    transport,
-		transport:    transport,
-		writerStats:  stats,
+		Addr:             TCP(config.Brokers...),
+		Topic:            config.Topic,
+		MaxAttempts:      config.MaxAttempts,
+		BatchSize:        config.BatchSize,
+		Balancer:         config.Balancer,
+		Router:           config.Router,
+		BatchBytes:       int64(config.BatchBytes),
+		BatchTimeout:     config.BatchTimeout,
+		ReadTimeout:      config.ReadTimeout,
+		WriteTimeout:     config.WriteTimeout,
+		RequiredAcks:     RequiredAcks(config.RequiredAcks),
+		Async:            config.Async,
+		Logger:           config.Logger,
+		ErrorLogger:      config.ErrorLogger,
+		StructuredLogger: config.StructuredLogger,
+		Transport:        transport,
+		transport:        transport,
+		writerStats:      stats,
 	}
 
 	if config.RequiredAcks == 0 {
@@ -605,6 +748,26 @@ func (w *Writer) WriteMessages(ctx context.Context, msgs ...Message) error {
 		return nil
 	}
 
+	// fail reports errors occurring before the messages were batched to the
+	// interceptor, which would otherwise never see them acknowledged.
+	fail := func(err error) error { return err }
+
+	if w.Interceptor != nil {
+		msgs = interceptMessages(ctx, w.Interceptor, msgs)
+		fail = func(err error) error {
+			w.Interceptor.OnAck(msgs, err)
+			return err
+		}
+	}
+
+	if w.Encryptor != nil {
+		encrypted, err := encryptMessages(w.Encryptor, w.Topic, msgs)
+		if err != nil {
+			return fail(err)
+		}
+		msgs = encrypted
+	}
+
 	balancer := w.balancer()
 	batchBytes := w.batchBytes()
 
@@ -616,7 +779,7 @@ func (w *Writer) WriteMessages(ctx context.Context, msgs ...Message) error {
 			// are that the program will check if WriteMessages returned a
 			// MessageTooLargeError, discard the message that was exceeding
 			// the maximum size, and try again.
-			return messageTooLarge(msgs, i)
+			return fail(messageTooLarge(msgs, i))
 		}
 	}
 
@@ -627,18 +790,44 @@ func (w *Writer) WriteMessages(ctx context.Context, msgs ...Message) error {
 	// to increasing GC work.
 	assignments := make(map[topicPartition][]int32)
 
+	// Partition metadata passed to the router, loaded once per topic.
+	var routes map[string][]Partition
+
 	for i, msg := range msgs {
 		topic, err := w.chooseTopic(msg)
 		if err != nil {
-			return err
+			return fail(err)
 		}
 
-		numPartitions, err := w.partitions(ctx, topic)
-		if err != nil {
-			return err
+		if err := w.checkInsync(ctx, topic); err != nil {
+			return fail(err)
 		}
 
-		partition := balancer.Balance(msg, loadCachedPartitions(numPartitions)...)
+		var partition int
+
+		if w.Router != nil {
+			partitions, ok := routes[topic]
+			if !ok {
+				if partitions, err = w.partitionMetadata(ctx, topic); err != nil {
+					return fail(err)
+				}
+				if routes == nil {
+					routes = make(map[string][]Partition)
+				}
+				routes[topic] = partitions
+			}
+
+			if partition, err = w.route(msg, topic, partitions); err != nil {
+				return fail(err)
+			}
+		} else {
+			numPartitions, err := w.partitions(ctx, topic)
+			if err != nil {
+				return fail(err)
+			}
+
+			partition = balancer.Balance(msg, loadCachedPartitions(numPartitions)...)
+		}
 
 		key := topicPartition{
 			topic:     topic,
@@ -691,6 +880,7 @@ func (w *Writer) batchMessages(messages []Message, assignments map[topicPartitio
 
 	if w.writers == nil {
 		w.writers = map[topicPartition]*partitionWriter{}
+		w.compressors = make(chan struct{}, runtime.GOMAXPROCS(0))
 	}
 
 	for key, indexes := range assignments {
@@ -720,13 +910,86 @@ func (w *Writer) produce(key topicPartition, batch *writeBatch) (*ProduceRespons
 		Topic:        key.topic,
 		RequiredAcks: w.RequiredAcks,
 		Compression:  w.Compression,
-		Records: &writerRecords{
+		Records:      batch.records(),
+	})
+}
+
+// compress encodes the records of batch on a separate goroutine when the
+// writer is configured with a compression codec, so the produce request writing
+// the batch does not have to compress it.
+func (w *Writer) compress(batch *writeBatch) {
+	if w.Compression == 0 {
+		return
+	}
+	batch.compressed = make(chan struct{})
+	w.spawn(func() {
+		defer close(batch.compressed)
+		w.compressors <- struct{}{}
+		defer func() { <-w.compressors }()
+
+		buffer := new(bytes.Buffer)
+		err := protocol.WriteRecordBatch(buffer, protocol.Attributes(w.Compression)&0x7, &writerRecords{
 			msgs: batch.msgs,
-		},
+		})
+		// On errors, the records are encoded again by the produce request,
+		// which reports the error.
+		if err == nil {
+			batch.encoded = buffer.Bytes()
+		}
 	})
 }
 
 func (w *Writer) partitions(ctx context.Context, topic string) (int, error) {
+	_, t, err := w.topicMetadata(ctx, topic)
+	if err != nil {
+		return 0, err
+	}
+	return len(t.Partitions), nil
+}
+
+// partitionMetadata returns the partitions of topic passed to the router, with
+// their leader and replicas resolved to brokers.
+func (w *Writer) partitionMetadata(ctx context.Context, topic string) ([]Partition, error) {
+	r, t, err := w.topicMetadata(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	brokers := make(map[int32]Broker, len(r.Brokers))
+	for _, b := range r.Brokers {
+		brokers[b.NodeID] = Broker{
+			Host: b.Host,
+			Port: int(b.Port),
+			ID:   int(b.NodeID),
+			Rack: b.Rack,
+		}
+	}
+
+	partitions := make([]Partition, len(t.Partitions))
+	for i, p := range t.Partitions {
+		partition := Partition{
+			Topic:    topic,
+			ID:       int(p.PartitionIndex),
+			Leader:   brokers[p.LeaderID],
+			Replicas: make([]Broker, len(p.ReplicaNodes)),
+			Isr:      make([]Broker, len(p.IsrNodes)),
+			Error:    makeError(p.ErrorCode, ""),
+		}
+
+		for j, id := range p.ReplicaNodes {
+			partition.Replicas[j] = brokers[id]
+		}
+
+		for j, id := range p.IsrNodes {
+			partition.Isr[j] = brokers[id]
+		}
+
+		partitions[i] = partition
+	}
+	return partitions, nil
+}
+
+func (w *Writer) topicMetadata(ctx context.Context, topic string) (*metadataAPI.Response, *metadataAPI.ResponseTopic, error) {
 	client := w.client(w.readTimeout())
 	// Here we use the transport directly as an optimization to avoid the
 	// construction of temporary request and response objects made by the
@@ -739,18 +1002,34 @@ func (w *Writer) partitions(ctx context.Context, topic string) (int, error) {
 		AllowAutoTopicCreation: w.AllowAutoTopicCreation,
 	})
 	if err != nil {
-		return 0, err
+		return nil, nil, err
 	}
-	for _, t := range r.(*metadataAPI.Response).Topics {
-		if t.Name == topic {
+	res := r.(*metadataAPI.Response)
+	for i := range res.Topics {
+		if t := &res.Topics[i]; t.Name == topic {
 			// This should always hit, unless kafka has a bug.
 			if t.ErrorCode != 0 {
-				return 0, Error(t.ErrorCode)
+				return nil, nil, withErrorContext(Error(t.ErrorCode), topic, -1, "")
 			}
-			return len(t.Partitions), nil
+			return res, t, nil
 		}
 	}
-	return 0, UnknownTopicOrPartition
+	return nil, nil, withErrorContext(UnknownTopicOrPartition, topic, -1, "")
+}
+
+// route calls the router of w to choose the partition of msg, and verifies that
+// the partition exists.
+func (w *Writer) route(msg Message, topic string, partitions []Partition) (int, error) {
+	partition, err := w.Router(msg, partitions)
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range partitions {
+		if p.ID == partition {
+			return partition, nil
+		}
+	}
+	return 0, fmt.Errorf("kafka.(*Writer).WriteMessages: router chose partition %d which does not exist in topic %q", partition, topic)
 }
 
 func (w *Writer) client(timeout time.Duration) *Client {
@@ -779,6 +1058,15 @@ func (w *Writer) maxAttempts() int {
 	return 10
 }
 
+// retryPolicy returns the policy used to retry writing batches of messages.
+func (w *Writer) retryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: w.maxAttempts(),
+		MinBackoff:  100 * time.Millisecond,
+		MaxBackoff:  1 * time.Second,
+	}
+}
+
 func (w *Writer) batchSize() int {
 	if w.BatchSize > 0 {
 		return w.BatchSize
@@ -793,6 +1081,10 @@ func (w *Writer) batchBytes() int64 {
 	return 1048576
 }
 
+func (w *Writer) clock() Clock {
+	return clockOrDefault(w.Clock)
+}
+
 func (w *Writer) batchTimeout() time.Duration {
 	if w.BatchTimeout > 0 {
 		return w.BatchTimeout
@@ -814,17 +1106,17 @@ func (w *Writer) writeTimeout() time.Duration {
 	return 10 * time.Second
 }
 
-func (w *Writer) withLogger(do func(Logger)) {
-	if w.Logger != nil {
-		do(w.Logger)
+func (w *Writer) withLogger(do func(Logger), args ...interface{}) {
+	if l := newLogger(w.StructuredLogger, w.Logger, LogLevelInfo, args...); l != nil {
+		do(l)
 	}
 }
 
-func (w *Writer) withErrorLogger(do func(Logger)) {
-	if w.ErrorLogger != nil {
-		do(w.ErrorLogger)
+func (w *Writer) withErrorLogger(do func(Logger), args ...interface{}) {
+	if l := newLogger(w.StructuredLogger, w.ErrorLogger, LogLevelError, args...); l != nil {
+		do(l)
 	} else {
-		w.withLogger(do)
+		w.withLogger(do, args...)
 	}
 }
 
@@ -833,7 +1125,7 @@ func (w *Writer) stats() *writerStats {
 		// This field is not nil when the writer was constructed with NewWriter
 		// to share the value with the dial function and count dials.
 		if w.writerStats == nil {
-			w.writerStats = new(writerStats)
+			w.writerStats = newWriterStats()
 		}
 	})
 	return w.writerStats
@@ -847,20 +1139,31 @@ func (w *Writer) stats() *writerStats {
 // call Stats on a kafka writer and report the metrics to a stats collection
 // system.
 func (w *Writer) Stats() WriterStats {
-	stats := w.stats()
-	return WriterStats{
-		Dials:        stats.dials.snapshot(),
-		Writes:       stats.writes.snapshot(),
-		Messages:     stats.messages.snapshot(),
-		Bytes:        stats.bytes.snapshot(),
-		Errors:       stats.errors.snapshot(),
-		DialTime:     stats.dialTime.snapshotDuration(),
-		BatchTime:    stats.batchTime.snapshotDuration(),
-		WriteTime:    stats.writeTime.snapshotDuration(),
-		WaitTime:     stats.waitTime.snapshotDuration(),
-		Retries:      stats.retries.snapshot(),
-		BatchSize:    stats.batchSize.snapshot(),
-		BatchBytes:   stats.batchSizeBytes.snapshot(),
+	var stats WriterStats
+	w.SnapshotStats(&stats, DeltaStats)
+	return stats
+}
+
+// SnapshotStats is like Stats, but writes the snapshot to stats, and reports
+// the counters and summaries in the given mode. Unlike Stats, cumulative
+// snapshots do not reset the values reported by the next call to Stats.
+//
+// The method does not allocate memory, so it may be called at a high rate.
+func (w *Writer) SnapshotStats(stats *WriterStats, mode StatsMode) {
+	s := w.stats()
+	*stats = WriterStats{
+		Dials:        s.dials.snapshot(mode),
+		Writes:       s.writes.snapshot(mode),
+		Messages:     s.messages.snapshot(mode),
+		Bytes:        s.bytes.snapshot(mode),
+		Errors:       s.errors.snapshot(mode),
+		DialTime:     s.dialTime.snapshotDuration(mode),
+		BatchTime:    s.batchTime.snapshotDuration(mode),
+		WriteTime:    s.writeTime.snapshotDuration(mode),
+		WaitTime:     s.waitTime.snapshotDuration(mode),
+		Retries:      s.retries.snapshot(mode),
+		BatchSize:    s.batchSize.snapshot(mode),
+		BatchBytes:   s.batchSizeBytes.snapshot(mode),
 		MaxAttempts:  int64(w.MaxAttempts),
 		MaxBatchSize: int64(w.BatchSize),
 		BatchTimeout: w.BatchTimeout,
@@ -976,6 +1279,12 @@ func newPartitionWriter(w *Writer, key topicPartition) *partitionWriter {
 	return writer
 }
 
+// enqueue starts compressing batch and queues it for writing.
+func (ptw *partitionWriter) enqueue(batch *writeBatch) {
+	ptw.w.compress(batch)
+	ptw.queue.Put(batch)
+}
+
 func (ptw *partitionWriter) writeBatches() {
 	for {
 		batch := ptw.queue.Get()
@@ -1012,14 +1321,14 @@ func (ptw *partitionWriter) writeMessages(msgs []Message, indexes []int32) map[*
 		}
 		if !batch.add(msgs[i], batchSize, batchBytes) {
 			batch.trigger()
-			ptw.queue.Put(batch)
+			ptw.enqueue(batch)
 			ptw.currBatch = nil
 			goto assignMessage
 		}
 
 		if batch.full(batchSize, batchBytes) {
 			batch.trigger()
-			ptw.queue.Put(batch)
+			ptw.enqueue(batch)
 			ptw.currBatch = nil
 		}
 
@@ -1032,7 +1341,7 @@ func (ptw *partitionWriter) writeMessages(msgs []Message, indexes []int32) map[*
 
 // ptw.w can be accessed here because this is called with the lock ptw.mutex already held.
 func (ptw *partitionWriter) newWriteBatch() *writeBatch {
-	batch := newWriteBatch(time.Now(), ptw.w.batchTimeout())
+	batch := newWriteBatch(ptw.w.clock(), ptw.w.batchTimeout())
 	ptw.w.spawn(func() { ptw.awaitBatch(batch) })
 	return batch
 }
@@ -1042,7 +1351,7 @@ func (ptw *partitionWriter) newWriteBatch() *writeBatch {
 // expires it will queue the batch for writing if needed.
 func (ptw *partitionWriter) awaitBatch(batch *writeBatch) {
 	select {
-	case <-batch.timer.C:
+	case <-batch.timer.C():
 		ptw.mutex.Lock()
 		// detach the batch from the writer if we're still attached
 		// and queue for writing.
@@ -1052,7 +1361,7 @@ func (ptw *partitionWriter) awaitBatch(batch *writeBatch) {
 		// pw.currBatch != batch so we just move on.
 		// Otherwise, we detach the batch from the ptWriter and enqueue it for writing.
 		if ptw.currBatch == batch {
-			ptw.queue.Put(batch)
+			ptw.enqueue(batch)
 			ptw.currBatch = nil
 		}
 		ptw.mutex.Unlock()
@@ -1066,38 +1375,42 @@ func (ptw *partitionWriter) awaitBatch(batch *writeBatch) {
 
 func (ptw *partitionWriter) writeBatch(batch *writeBatch) {
 	stats := ptw.w.stats()
-	stats.batchTime.observe(int64(time.Since(batch.time)))
+	stats.batchTime.observe(int64(ptw.w.clock().Now().Sub(batch.time)))
 	stats.batchSize.observe(int64(len(batch.msgs)))
 	stats.batchSizeBytes.observe(batch.bytes)
 
+	if batch.compressed != nil {
+		<-batch.compressed
+	}
+
 	var res *ProduceResponse
-	var err error
 	key := ptw.meta
-	for attempt, maxAttempts := 0, ptw.w.maxAttempts(); attempt < maxAttempts; attempt++ {
-		if attempt != 0 {
-			stats.retries.observe(1)
-			// TODO: should there be a way to asynchronously cancel this
-			// operation?
-			//
-			// * If all goroutines that added message to this batch have stopped
-			//   waiting for it, should we abort?
-			//
-			// * If the writer has been closed? It reduces the durability
-			//   guarantees to abort, but may be better to avoid long wait times
-			//   on close.
-			//
-			delay := backoff(attempt, 100*time.Millisecond, 1*time.Second)
-			ptw.w.withLogger(func(log Logger) {
-				log.Printf("backing off %s writing %d messages to %s (partition: %d)", delay, len(batch.msgs), key.topic, key.partition)
-			})
-			time.Sleep(delay)
-		}
+	retryable := func(err error) bool {
+		return isTemporary(err) || isTransientNetworkError(err)
+	}
+	// TODO: should there be a way to asynchronously cancel retries?
+	//
+	// * If all goroutines that added message to this batch have stopped
+	//   waiting for it, should we abort?
+	//
+	// * If the writer has been closed? It reduces the durability
+	//   guarantees to abort, but may be better to avoid long wait times
+	//   on close.
+	//
+	onRetry := func(attempt int, delay time.Duration) {
+		stats.retries.observe(1)
+		ptw.w.withLogger(func(log Logger) {
+			logKV(log, "backing off writing messages", "delay", delay, "messages", len(batch.msgs))
+		}, "topic", key.topic, "partition", key.partition)
+	}
 
+	err := ptw.w.retryPolicy().do(context.Background(), ptw.w.clock(), retryable, onRetry, func() error {
 		ptw.w.withLogger(func(log Logger) {
-			log.Printf("writing %d messages to %s (partition: %d)", len(batch.msgs), key.topic, key.partition)
-		})
+			logKV(log, "writing messages", "messages", len(batch.msgs))
+		}, "topic", key.topic, "partition", key.partition)
 
 		start := time.Now()
+		var err error
 		res, err = ptw.w.produce(key, batch)
 
 		stats.writes.observe(1)
@@ -1108,27 +1421,27 @@ func (ptw *partitionWriter) writeBatch(batch *writeBatch) {
 		// range. In kafka-go 0.4, we recylced this value to instead report the
 		// duration of produce requests, and changed the stats.waitTime value to
 		// report the time that kafka has throttled the requests for.
-		stats.writeTime.observe(int64(time.Since(start)))
+		latency := time.Since(start)
+		stats.writeTime.observe(int64(latency))
 
 		if res != nil {
-			err = res.Error
+			err = withErrorContext(res.Error, key.topic, int(key.partition), "")
 			stats.waitTime.observe(int64(res.Throttle))
 		}
 
-		if err == nil {
-			break
+		if feedback, ok := ptw.w.balancer().(BalancerFeedback); ok {
+			feedback.ObserveProduce(key.topic, int(key.partition), latency, err)
 		}
 
-		stats.errors.observe(1)
-
-		ptw.w.withErrorLogger(func(log Logger) {
-			log.Printf("error writing messages to %s (partition %d): %s", key.topic, key.partition, err)
-		})
+		if err != nil {
+			stats.errors.observe(1)
 
-		if !isTemporary(err) && !isTransientNetworkError(err) {
-			break
+			ptw.w.withErrorLogger(func(log Logger) {
+				logKV(log, "error writing messages", "messages", len(batch.msgs), "error", err)
+			}, "topic", key.topic, "partition", key.partition)
 		}
-	}
+		return err
+	})
 
 	if res != nil {
 		for i := range batch.msgs {
@@ -1143,6 +1456,10 @@ func (ptw *partitionWriter) writeBatch(batch *writeBatch) {
 		}
 	}
 
+	if ptw.w.Interceptor != nil {
+		ptw.w.Interceptor.OnAck(batch.msgs, err)
+	}
+
 	if ptw.w.Completion != nil {
 		ptw.w.Completion(batch.msgs, err)
 	}
@@ -1156,7 +1473,7 @@ func (ptw *partitionWriter) close() {
 
 	if ptw.currBatch != nil {
 		batch := ptw.currBatch
-		ptw.queue.Put(batch)
+		ptw.enqueue(batch)
 		ptw.currBatch = nil
 		batch.trigger()
 	}
@@ -1171,16 +1488,22 @@ type writeBatch struct {
 	bytes int64
 	ready chan struct{}
 	done  chan struct{}
-	timer *time.Timer
+	timer Timer
 	err   error // result of the batch completion
+
+	// Closed when the records were compressed ahead of the produce requests,
+	// in which case encoded holds the compressed record batch. Both are nil
+	// when the writer does not compress messages.
+	compressed chan struct{}
+	encoded    []byte
 }
 
-func newWriteBatch(now time.Time, timeout time.Duration) *writeBatch {
+func newWriteBatch(clock Clock, timeout time.Duration) *writeBatch {
 	return &writeBatch{
-		time:  now,
+		time:  clock.Now(),
 		ready: make(chan struct{}),
 		done:  make(chan struct{}),
-		timer: time.NewTimer(timeout),
+		timer: clock.NewTimer(timeout),
 	}
 }
 
@@ -1209,6 +1532,14 @@ func (b *writeBatch) trigger() {
 	close(b.ready)
 }
 
+// records returns the records of produce requests writing the batch.
+func (b *writeBatch) records() RecordReader {
+	if b.encoded != nil {
+		return &protocol.EncodedRecordBatch{Data: b.encoded}
+	}
+	return &writerRecords{msgs: b.msgs}
+}
+
 func (b *writeBatch) complete(err error) {
 	b.err = err
 	close(b.done)
@@ -1243,6 +1574,13 @@ func (r *writerRecords) ReadRecord() (*Record, error) {
 	return nil, io.EOF
 }
 
+// Reset rewinds the records, which lets transports retry the produce requests
+// that contain them.
+func (r *writerRecords) Reset() error {
+	r.index = 0
+	return nil
+}
+
 type bytesReadCloser struct{ bytes.Reader }
 
 func (*bytesReadCloser) Close() error { return nil }
@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"net"
 	"strconv"
 	"sync"
 	"testing"
 	"time"
 
+	metadataAPI "github.com/segmentio/kafka-go/protocol/metadata"
+	produceAPI "github.com/segmentio/kafka-go/protocol/produce"
 	"github.com/segmentio/kafka-go/sasl/plain"
 )
 
@@ -54,7 +57,7 @@ func testBatchQueuePutWakesSleepingGetter(t *testing.T) {
 		batch = bq.Get()
 	}()
 	<-ready
-	bq.Put(newWriteBatch(time.Now(), time.Hour*100))
+	bq.Put(newWriteBatch(SystemClock, time.Hour*100))
 	wg.Wait()
 	if batch == nil {
 		t.Fatal("got nil batch")
@@ -64,7 +67,7 @@ func testBatchQueuePutWakesSleepingGetter(t *testing.T) {
 func testBatchQueuePutAfterCloseFails(t *testing.T) {
 	bq := newBatchQueue(10)
 	bq.Close()
-	if put := bq.Put(newWriteBatch(time.Now(), time.Hour*100)); put {
+	if put := bq.Put(newWriteBatch(SystemClock, time.Hour*100)); put {
 		t.Fatal("put batch into closed queue")
 	}
 }
@@ -72,8 +75,8 @@ func testBatchQueuePutAfterCloseFails(t *testing.T) {
 func testBatchQueueGetWorksAfterClose(t *testing.T) {
 	bq := newBatchQueue(10)
 	enqueueBatches := []*writeBatch{
-		newWriteBatch(time.Now(), time.Hour*100),
-		newWriteBatch(time.Now(), time.Hour*100),
+		newWriteBatch(SystemClock, time.Hour*100),
+		newWriteBatch(SystemClock, time.Hour*100),
 	}
 
 	for _, batch := range enqueueBatches {
@@ -294,6 +297,8 @@ func TestValidateWriter(t *testing.T) {
 		{config: WriterConfig{}, errorOccured: true},
 		{config: WriterConfig{Brokers: []string{"broker1", "broker2"}}, errorOccured: false},
 		{config: WriterConfig{Brokers: []string{"broker1"}, Topic: "topic1"}, errorOccured: false},
+		{config: WriterConfig{Brokers: []string{"broker1"}, RequiredAcks: 2}, errorOccured: true},
+		{config: WriterConfig{Brokers: []string{"broker1"}, BatchTimeout: -time.Second}, errorOccured: true},
 	}
 	for _, test := range tests {
 		err := test.config.Validate()
@@ -306,6 +311,29 @@ func TestValidateWriter(t *testing.T) {
 	}
 }
 
+func TestValidateWriterReportsAllProblems(t *testing.T) {
+	config := WriterConfig{BatchSize: -1, WriteTimeout: -time.Second, RequiredAcks: -2}
+
+	configErr, ok := config.Validate().(*ConfigError)
+	if !ok {
+		t.Fatalf("expected a *ConfigError but got %v", config.Validate())
+	}
+	if len(configErr.Errors) != 4 {
+		t.Errorf("expected 4 problems but got %d: %v", len(configErr.Errors), configErr)
+	}
+}
+
+func TestNewWriterAcceptsLegacyConfig(t *testing.T) {
+	config := WriterConfig{Brokers: []string{"broker1"}, Topic: "topic1", RequiredAcks: 2}
+
+	if err := config.Validate(); err == nil {
+		t.Error("expected Validate to report the problems of the configuration")
+	}
+
+	// NewWriter accepted the configuration before it was validated strictly.
+	NewWriter(config).Close()
+}
+
 func testWriterMaxAttemptsErr(t *testing.T) {
 	topic := makeTopic()
 	createTopic(t, topic, 1)
@@ -825,3 +853,76 @@ type staticBalancer struct {
 func (b *staticBalancer) Balance(_ Message, partitions ...int) int {
 	return b.partition
 }
+
+type feedbackBalancer struct {
+	RoundRobin
+	mutex    sync.Mutex
+	observed []error
+}
+
+func (b *feedbackBalancer) ObserveProduce(topic string, partition int, latency time.Duration, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if topic != "topic-1" || partition != 0 || latency <= 0 {
+		panic(fmt.Sprintf("unexpected feedback: %s/%d %v", topic, partition, latency))
+	}
+	b.observed = append(b.observed, err)
+}
+
+func TestWriterBalancerFeedback(t *testing.T) {
+	attempts := 0
+	transport := roundTripperFunc(func(ctx context.Context, addr net.Addr, req Request) (Response, error) {
+		switch req.(type) {
+		case *metadataAPI.Request:
+			return &metadataAPI.Response{
+				Brokers: []metadataAPI.ResponseBroker{{NodeID: 1, Host: "localhost", Port: 9092}},
+				Topics: []metadataAPI.ResponseTopic{{
+					Name:       "topic-1",
+					Partitions: []metadataAPI.ResponsePartition{{PartitionIndex: 0, LeaderID: 1}},
+				}},
+			}, nil
+		case *produceAPI.Request:
+			time.Sleep(time.Millisecond)
+			attempts++
+			res := &produceAPI.Response{
+				Topics: []produceAPI.ResponseTopic{{
+					Topic:      "topic-1",
+					Partitions: []produceAPI.ResponsePartition{{Partition: 0}},
+				}},
+			}
+			if attempts == 1 {
+				res.Topics[0].Partitions[0].ErrorCode = int16(NotLeaderForPartition)
+			}
+			return res, nil
+		default:
+			return nil, fmt.Errorf("unexpected request: %T", req)
+		}
+	})
+
+	balancer := &feedbackBalancer{}
+	w := &Writer{
+		Addr:         TCP("localhost:9092"),
+		Topic:        "topic-1",
+		Balancer:     balancer,
+		BatchTimeout: time.Millisecond,
+		RequiredAcks: RequireOne,
+		Transport:    transport,
+	}
+	defer w.Close()
+
+	if err := w.WriteMessages(context.Background(), Message{Value: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	balancer.mutex.Lock()
+	defer balancer.mutex.Unlock()
+	if len(balancer.observed) != 2 {
+		t.Fatalf("expected feedback for 2 attempts but got %d", len(balancer.observed))
+	}
+	if !errors.Is(balancer.observed[0], NotLeaderForPartition) {
+		t.Errorf("expected %v but got %v", NotLeaderForPartition, balancer.observed[0])
+	}
+	if balancer.observed[1] != nil {
+		t.Errorf("expected no error but got %v", balancer.observed[1])
+	}
+}
@@ -0,0 +1,65 @@
+package kafka_test
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func TestWriterCompression(t *testing.T) {
+	const partitions = 4
+
+	for _, codec := range []kafka.Compression{kafka.Gzip, kafka.Snappy, kafka.Lz4, kafka.Zstd} {
+		t.Run(codec.String(), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A", Partitions: partitions})
+
+			w := &kafka.Writer{
+				Addr:         b.Addr(),
+				Topic:        "topic-A",
+				Compression:  codec,
+				BatchSize:    10,
+				BatchTimeout: time.Millisecond,
+				RequiredAcks: kafka.RequireOne,
+				Balancer: kafka.BalancerFunc(func(msg kafka.Message, partitions ...int) int {
+					return int(msg.Key[0]) % len(partitions)
+				}),
+			}
+			defer w.Close()
+
+			// Several batches are written to each partition, so batches of the
+			// same partition are compressed concurrently as well.
+			msgs := make([]kafka.Message, 100)
+			for i := range msgs {
+				msgs[i] = kafka.Message{
+					Key:   []byte{byte(i % partitions)},
+					Value: []byte(strconv.Itoa(i)),
+				}
+			}
+			if err := w.WriteMessages(ctx, msgs...); err != nil {
+				t.Fatal(err)
+			}
+
+			for partition := 0; partition < partitions; partition++ {
+				var values []string
+				for _, r := range b.Records("topic-A", partition) {
+					values = append(values, string(r.Value))
+				}
+				var expect []string
+				for i := partition; i < len(msgs); i += partitions {
+					expect = append(expect, strconv.Itoa(i))
+				}
+				if fmt.Sprint(values) != fmt.Sprint(expect) {
+					t.Errorf("wrong records in partition %d:\nwant: %v\ngot:  %v", partition, expect, values)
+				}
+			}
+		})
+	}
+}
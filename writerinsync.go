@@ -0,0 +1,150 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// InsyncCheck configures how writers verify that the partitions of a topic have
+// enough in-sync replicas to satisfy its min.insync.replicas configuration
+// before producing messages to it.
+//
+// Brokers reject the messages written with RequireAll to partitions that have
+// fewer in-sync replicas than min.insync.replicas with NotEnoughReplicas
+// errors, which only happens after the messages were batched and sent, and
+// may leave large writes, such as backfills, half-completed. The check fails,
+// or waits, before the first messages are written to a topic instead.
+//
+// Since min.insync.replicas only applies to messages written with RequireAll,
+// writers configured with other RequiredAcks values skip the check.
+//
+// The check reads the configuration of topics with DescribeConfigs requests,
+// which require the DescribeConfigs permission on the topics when the cluster
+// uses ACLs.
+type InsyncCheck int
+
+const (
+	// InsyncCheckNone disables the check, it is the default.
+	InsyncCheckNone InsyncCheck = iota
+
+	// InsyncCheckFail makes WriteMessages fail with an error wrapping
+	// NotEnoughReplicas when a partition of the topic does not have enough
+	// in-sync replicas.
+	InsyncCheckFail
+
+	// InsyncCheckWait makes WriteMessages wait until all partitions of the
+	// topic have enough in-sync replicas, or the context is canceled.
+	InsyncCheckWait
+)
+
+func (c InsyncCheck) String() string {
+	switch c {
+	case InsyncCheckNone:
+		return "none"
+	case InsyncCheckFail:
+		return "fail"
+	case InsyncCheckWait:
+		return "wait"
+	default:
+		return "unknown"
+	}
+}
+
+const minInsyncReplicasConfig = "min.insync.replicas"
+
+// checkInsync runs the in-sync replicas check configured on w for topic. Once
+// the check passed, it is not repeated for the topic.
+func (w *Writer) checkInsync(ctx context.Context, topic string) error {
+	if w.InsyncCheck == InsyncCheckNone || w.RequiredAcks != RequireAll {
+		return nil
+	}
+
+	w.mutex.Lock()
+	_, checked := w.insyncTopics[topic]
+	w.mutex.Unlock()
+	if checked {
+		return nil
+	}
+
+	const (
+		backoffDelayMin = 100 * time.Millisecond
+		backoffDelayMax = 1 * time.Second
+	)
+
+	for attempt := 1; ; attempt++ {
+		err := w.insyncReplicas(ctx, topic)
+		if err == nil {
+			break
+		}
+		if w.InsyncCheck != InsyncCheckWait || !errors.Is(err, NotEnoughReplicas) {
+			return err
+		}
+		if !sleepClock(ctx, w.clock(), backoff(attempt, backoffDelayMin, backoffDelayMax)) {
+			return err
+		}
+	}
+
+	w.mutex.Lock()
+	if w.insyncTopics == nil {
+		w.insyncTopics = make(map[string]struct{})
+	}
+	w.insyncTopics[topic] = struct{}{}
+	w.mutex.Unlock()
+	return nil
+}
+
+// insyncReplicas compares the size of the ISR of each partition of topic with
+// its min.insync.replicas configuration.
+func (w *Writer) insyncReplicas(ctx context.Context, topic string) error {
+	minInsync, err := w.minInsyncReplicas(ctx, topic)
+	if err != nil {
+		return err
+	}
+
+	_, t, err := w.topicMetadata(ctx, topic)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range t.Partitions {
+		if len(p.IsrNodes) < minInsync {
+			return fmt.Errorf("kafka.(*Writer).WriteMessages: partition %d of topic %q has %d in-sync replicas, %s=%d: %w",
+				p.PartitionIndex, topic, len(p.IsrNodes), minInsyncReplicasConfig, minInsync, NotEnoughReplicas)
+		}
+	}
+	return nil
+}
+
+// minInsyncReplicas returns the min.insync.replicas configuration of topic,
+// which defaults to 1.
+func (w *Writer) minInsyncReplicas(ctx context.Context, topic string) (int, error) {
+	res, err := w.client(w.readTimeout()).DescribeConfigs(ctx, &DescribeConfigsRequest{
+		Resources: []DescribeConfigRequestResource{{
+			ResourceType: ResourceTypeTopic,
+			ResourceName: topic,
+			ConfigNames:  []string{minInsyncReplicasConfig},
+		}},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, r := range res.Resources {
+		if r.Error != nil {
+			return 0, r.Error
+		}
+		for _, e := range r.ConfigEntries {
+			if e.ConfigName == minInsyncReplicasConfig {
+				n, err := strconv.Atoi(e.ConfigValue)
+				if err != nil {
+					return 0, fmt.Errorf("kafka.(*Writer).WriteMessages: invalid %s of topic %q: %w", minInsyncReplicasConfig, topic, err)
+				}
+				return n, nil
+			}
+		}
+	}
+	return 1, nil
+}
@@ -0,0 +1,98 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func TestWriterInsyncCheck(t *testing.T) {
+	tests := []struct {
+		scenario string
+		check    kafka.InsyncCheck
+		acks     kafka.RequiredAcks
+		// When non-zero, min.insync.replicas is lowered to 1 after this delay.
+		recover time.Duration
+		failed  bool
+	}{
+		{
+			scenario: "no check",
+			check:    kafka.InsyncCheckNone,
+		},
+		{
+			scenario: "fail fast",
+			check:    kafka.InsyncCheckFail,
+			failed:   true,
+		},
+		{
+			scenario: "skip the check when not all replicas are required",
+			check:    kafka.InsyncCheckFail,
+			acks:     kafka.RequireOne,
+		},
+		{
+			scenario: "wait until the replicas are in sync",
+			check:    kafka.InsyncCheckWait,
+			recover:  200 * time.Millisecond,
+		},
+		{
+			scenario: "wait until the context expires",
+			check:    kafka.InsyncCheckWait,
+			failed:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+			defer cancel()
+
+			b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A", Partitions: 2})
+			// The broker is the only replica of the partitions.
+			if err := b.SetTopicConfig("topic-A", "min.insync.replicas", "2"); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.recover != 0 {
+				timer := time.AfterFunc(test.recover, func() {
+					b.SetTopicConfig("topic-A", "min.insync.replicas", "1")
+				})
+				defer timer.Stop()
+			}
+
+			acks := test.acks
+			if acks == 0 {
+				acks = kafka.RequireAll
+			}
+
+			w := &kafka.Writer{
+				Addr:         b.Addr(),
+				Topic:        "topic-A",
+				BatchTimeout: 10 * time.Millisecond,
+				RequiredAcks: acks,
+				InsyncCheck:  test.check,
+			}
+			defer w.Close()
+
+			err := w.WriteMessages(ctx, kafka.Message{Value: []byte("A")})
+			if test.failed {
+				if !errors.Is(err, kafka.NotEnoughReplicas) {
+					t.Fatalf("expected NotEnoughReplicas, got %v", err)
+				}
+				if n := len(b.Records("topic-A", 0)) + len(b.Records("topic-A", 1)); n != 0 {
+					t.Errorf("%d records were written", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := len(b.Records("topic-A", 0)) + len(b.Records("topic-A", 1)); n != 1 {
+				t.Errorf("%d records were written, want 1", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func TestWriterRouter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A", Partitions: 3})
+
+	var seen []kafka.Partition
+	w := &kafka.Writer{
+		Addr:         b.Addr(),
+		Topic:        "topic-A",
+		BatchTimeout: 10 * time.Millisecond,
+		RequiredAcks: kafka.RequireOne,
+		Router: func(msg kafka.Message, partitions []kafka.Partition) (int, error) {
+			seen = partitions
+			if string(msg.Key) == "pinned" {
+				return 2, nil
+			}
+			return 0, nil
+		},
+	}
+	defer w.Close()
+
+	err := w.WriteMessages(ctx,
+		kafka.Message{Key: []byte("pinned"), Value: []byte("A")},
+		kafka.Message{Key: []byte("other"), Value: []byte("B")},
+		kafka.Message{Key: []byte("pinned"), Value: []byte("C")},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != 3 {
+		t.Fatalf("router saw %d partitions, want 3", len(seen))
+	}
+	for i, p := range seen {
+		if p.Topic != "topic-A" || p.ID != i || p.Leader.Port == 0 || len(p.Isr) == 0 {
+			t.Errorf("wrong partition metadata passed to the router: %+v", p)
+		}
+	}
+
+	for partition, want := range map[int][]string{0: {"B"}, 1: nil, 2: {"A", "C"}} {
+		records := b.Records("topic-A", partition)
+		if len(records) != len(want) {
+			t.Errorf("partition %d has %d records, want %d", partition, len(records), len(want))
+			continue
+		}
+		for i, r := range records {
+			if string(r.Value) != want[i] {
+				t.Errorf("partition %d record %d: got %q, want %q", partition, i, r.Value, want[i])
+			}
+		}
+	}
+}
+
+func TestWriterRouterError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A", Partitions: 2})
+
+	errNoRoute := errors.New("no route")
+	tests := []struct {
+		scenario string
+		router   func(kafka.Message, []kafka.Partition) (int, error)
+		check    func(error) bool
+	}{
+		{
+			scenario: "router error",
+			router: func(kafka.Message, []kafka.Partition) (int, error) {
+				return 0, errNoRoute
+			},
+			check: func(err error) bool { return errors.Is(err, errNoRoute) },
+		},
+		{
+			scenario: "unknown partition",
+			router: func(kafka.Message, []kafka.Partition) (int, error) {
+				return 5, nil
+			},
+			check: func(err error) bool { return err != nil },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.scenario, func(t *testing.T) {
+			w := &kafka.Writer{
+				Addr:   b.Addr(),
+				Topic:  "topic-A",
+				Router: test.router,
+			}
+			defer w.Close()
+
+			err := w.WriteMessages(ctx, kafka.Message{Value: []byte("A")})
+			if !test.check(err) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/segmentio/kafka-go/protocol/writetxnmarkers"
+)
+
+// ErrUnsafeAdmin is returned by the methods of Client which send requests that
+// may corrupt the state of the cluster when Client.UnsafeAdmin is not set.
+var ErrUnsafeAdmin = errors.New("unsafe admin requests are not enabled on the client")
+
+// WriteTxnMarker represents a transaction marker to write to partitions.
+type WriteTxnMarker struct {
+	// The id and epoch of the producer whose transaction is completed by the
+	// marker. The epoch is usually the one reported by DescribeProducers.
+	ProducerID    int
+	ProducerEpoch int
+
+	// When true, the marker commits the transaction, otherwise the records of
+	// the transaction are aborted.
+	Commit bool
+
+	// A mapping of topic names to the list of partitions to write the marker
+	// to.
+	Topics map[string][]int
+
+	// The epoch of the transaction coordinator writing the marker, or -1 to
+	// bypass the check of coordinator epochs, which operators writing markers
+	// for dead producers usually need to do.
+	CoordinatorEpoch int
+}
+
+// WriteTxnMarkersRequest represents a request sent to kafka brokers to write
+// transaction markers.
+type WriteTxnMarkersRequest struct {
+	// Address of the kafka broker to send the request to.
+	Addr net.Addr
+
+	// The markers to write, the requests are sent to the leaders of their
+	// partitions.
+	//
+	// Brokers report the results of writing markers by producer id, so each
+	// producer may only appear once; the client returns an error when two
+	// markers have the same producer id.
+	Markers []WriteTxnMarker
+}
+
+// WriteTxnMarkersResponse represents a response from kafka brokers to a
+// WriteTxnMarkersRequest.
+type WriteTxnMarkersResponse struct {
+	// Mappings of producer ids to the results of writing their markers, there
+	// will be one entry for each producer in the request.
+	Markers map[int]WriteTxnMarkerResult
+}
+
+// WriteTxnMarkerResult carries the results of writing the marker of a producer.
+type WriteTxnMarkerResult struct {
+	// Mappings of topic names to the results of writing the marker to their
+	// partitions.
+	Topics map[string][]WriteTxnMarkerPartition
+}
+
+// WriteTxnMarkerPartition carries the result of writing a transaction marker to
+// a partition.
+type WriteTxnMarkerPartition struct {
+	// The partition that the marker was written to.
+	Partition int
+
+	// An error that may have occurred while writing the marker, for example
+	// InvalidProducerEpoch if the producer epoch is lower than the one of the
+	// partition leader.
+	Error error
+}
+
+// WriteTxnMarkers sends requests to the partition leaders to write transaction
+// markers, which complete the transactions of producers on the partitions.
+//
+// Transaction coordinators write markers when transactions are committed or
+// aborted. Operators use this API to abort hanging transactions, which a
+// producer left open on a partition without its coordinator knowing about it
+// (see KIP-664), and which block read_committed consumers. Since writing
+// markers bypasses the transaction coordinators, it requires setting
+// UnsafeAdmin on the client, and ErrUnsafeAdmin is returned otherwise.
+func (c *Client) WriteTxnMarkers(ctx context.Context, req *WriteTxnMarkersRequest) (*WriteTxnMarkersResponse, error) {
+	if !c.UnsafeAdmin {
+		return nil, fmt.Errorf("kafka.(*Client).WriteTxnMarkers: %w", ErrUnsafeAdmin)
+	}
+
+	markers := make([]writetxnmarkers.RequestMarker, len(req.Markers))
+	producers := make(map[int]struct{}, len(req.Markers))
+
+	for i, m := range req.Markers {
+		if _, ok := producers[m.ProducerID]; ok {
+			return nil, fmt.Errorf("kafka.(*Client).WriteTxnMarkers: duplicate markers for producer id %d", m.ProducerID)
+		}
+		producers[m.ProducerID] = struct{}{}
+
+		topics := make([]writetxnmarkers.RequestTopic, 0, len(m.Topics))
+
+		for topicName, partitions := range m.Topics {
+			indexes := make([]int32, len(partitions))
+
+			for j, p := range partitions {
+				indexes[j] = int32(p)
+			}
+
+			topics = append(topics, writetxnmarkers.RequestTopic{
+				Name:             topicName,
+				PartitionIndexes: indexes,
+			})
+		}
+
+		markers[i] = writetxnmarkers.RequestMarker{
+			ProducerID:        int64(m.ProducerID),
+			ProducerEpoch:     int16(m.ProducerEpoch),
+			TransactionResult: m.Commit,
+			Topics:            topics,
+			CoordinatorEpoch:  int32(m.CoordinatorEpoch),
+		}
+	}
+
+	m, err := c.roundTrip(ctx, req.Addr, &writetxnmarkers.Request{
+		Markers: markers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kafka.(*Client).WriteTxnMarkers: %w", err)
+	}
+
+	res := m.(*writetxnmarkers.Response)
+	ret := &WriteTxnMarkersResponse{
+		Markers: make(map[int]WriteTxnMarkerResult, len(res.Markers)),
+	}
+
+	for _, marker := range res.Markers {
+		result := WriteTxnMarkerResult{
+			Topics: make(map[string][]WriteTxnMarkerPartition, len(marker.Topics)),
+		}
+
+		for _, t := range marker.Topics {
+			partitions := make([]WriteTxnMarkerPartition, len(t.Partitions))
+
+			for i, p := range t.Partitions {
+				partitions[i] = WriteTxnMarkerPartition{
+					Partition: int(p.PartitionIndex),
+					Error:     makeError(p.ErrorCode, ""),
+				}
+			}
+
+			result.Topics[t.Name] = partitions
+		}
+
+		ret.Markers[int(marker.ProducerID)] = result
+	}
+
+	return ret, nil
+}
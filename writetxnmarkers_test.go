@@ -0,0 +1,167 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/kafkatest"
+)
+
+func TestClientWriteTxnMarkersUnsafeAdmin(t *testing.T) {
+	client := &kafka.Client{Addr: kafka.TCP("localhost:9092")}
+
+	_, err := client.WriteTxnMarkers(context.Background(), &kafka.WriteTxnMarkersRequest{
+		Markers: []kafka.WriteTxnMarker{{
+			ProducerID: 1,
+			Topics:     map[string][]int{"topic-A": {0}},
+		}},
+	})
+	if !errors.Is(err, kafka.ErrUnsafeAdmin) {
+		t.Errorf("expected ErrUnsafeAdmin, got %v", err)
+	}
+}
+
+func TestClientWriteTxnMarkersDuplicateProducer(t *testing.T) {
+	client := &kafka.Client{Addr: kafka.TCP("localhost:9092"), UnsafeAdmin: true}
+
+	_, err := client.WriteTxnMarkers(context.Background(), &kafka.WriteTxnMarkersRequest{
+		Markers: []kafka.WriteTxnMarker{
+			{ProducerID: 1, Commit: true, Topics: map[string][]int{"topic-A": {0}}},
+			{ProducerID: 1, Commit: false, Topics: map[string][]int{"topic-A": {1}}},
+		},
+	})
+	if err == nil {
+		t.Error("expected an error for markers with the same producer id")
+	}
+}
+
+func TestClientWriteTxnMarkers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	b := kafkatest.NewTestBroker(t, kafkatest.Topic{Name: "topic-A", Partitions: 2})
+
+	transport := &kafka.Transport{}
+	defer transport.CloseIdleConnections()
+	client := &kafka.Client{Addr: b.Addr(), Transport: transport, UnsafeAdmin: true}
+
+	// Leave a transaction open on both partitions, with a record on each.
+	init, err := client.InitProducerID(ctx, &kafka.InitProducerIDRequest{
+		TransactionalID:      "txn-A",
+		TransactionTimeoutMs: 30000,
+		ProducerID:           -1,
+		ProducerEpoch:        -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if init.Error != nil {
+		t.Fatal(init.Error)
+	}
+	producer := init.Producer
+
+	add, err := client.AddPartitionsToTxn(ctx, &kafka.AddPartitionsToTxnRequest{
+		TransactionalID: "txn-A",
+		ProducerID:      producer.ProducerID,
+		ProducerEpoch:   producer.ProducerEpoch,
+		Topics:          map[string][]kafka.AddPartitionToTxn{"topic-A": {{Partition: 0}, {Partition: 1}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range add.Topics["topic-A"] {
+		if p.Error != nil {
+			t.Fatal(p.Error)
+		}
+	}
+
+	for partition := 0; partition < 2; partition++ {
+		res, err := client.Produce(ctx, &kafka.ProduceRequest{
+			Topic:           "topic-A",
+			Partition:       partition,
+			RequiredAcks:    kafka.RequireAll,
+			TransactionalID: "txn-A",
+			Producer:        producer,
+			Records:         kafka.NewRecordReader(kafka.Record{Value: kafka.NewBytes([]byte("hello"))}),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+	}
+
+	// Markers with a stale producer epoch are rejected.
+	stale, err := client.WriteTxnMarkers(ctx, &kafka.WriteTxnMarkersRequest{
+		Markers: []kafka.WriteTxnMarker{{
+			ProducerID:       producer.ProducerID,
+			ProducerEpoch:    producer.ProducerEpoch - 1,
+			Topics:           map[string][]int{"topic-A": {0}},
+			CoordinatorEpoch: -1,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := stale.Markers[producer.ProducerID].Topics["topic-A"]; len(p) != 1 || !errors.Is(p[0].Error, kafka.InvalidProducerEpoch) {
+		t.Errorf("expected InvalidProducerEpoch, got %+v", p)
+	}
+
+	res, err := client.WriteTxnMarkers(ctx, &kafka.WriteTxnMarkersRequest{
+		Markers: []kafka.WriteTxnMarker{
+			{
+				ProducerID:       producer.ProducerID,
+				ProducerEpoch:    producer.ProducerEpoch,
+				Commit:           true,
+				Topics:           map[string][]int{"topic-A": {0}},
+				CoordinatorEpoch: -1,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := res.Markers[producer.ProducerID].Topics["topic-A"]; len(p) != 1 || p[0].Partition != 0 || p[0].Error != nil {
+		t.Errorf("wrong commit results: %+v", p)
+	}
+
+	res, err = client.WriteTxnMarkers(ctx, &kafka.WriteTxnMarkersRequest{
+		Markers: []kafka.WriteTxnMarker{
+			{
+				ProducerID:       producer.ProducerID,
+				ProducerEpoch:    producer.ProducerEpoch,
+				Topics:           map[string][]int{"topic-A": {1}},
+				CoordinatorEpoch: -1,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := res.Markers[producer.ProducerID].Topics["topic-A"]; len(p) != 1 || p[0].Partition != 1 || p[0].Error != nil {
+		t.Errorf("wrong abort results: %+v", p)
+	}
+
+	if records := b.Records("topic-A", 0); len(records) != 1 || string(records[0].Value) != "hello" {
+		t.Errorf("the committed record was not written: %+v", records)
+	}
+	if records := b.Records("topic-A", 1); len(records) != 0 {
+		t.Errorf("the aborted record was written: %+v", records)
+	}
+
+	producers, err := client.DescribeProducers(ctx, &kafka.DescribeProducersRequest{
+		Topics: map[string][]int{"topic-A": {0, 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range producers.Topics["topic-A"] {
+		if p.Error != nil || len(p.ActiveProducers) != 0 {
+			t.Errorf("partition %d still has an open transaction: %+v", p.Partition, p)
+		}
+	}
+}